@@ -18,6 +18,13 @@ type Profile struct {
 	Points          int    `json:"points"`
 }
 
+// profileUpdate is the PUT /profile body. Points is a pointer so an echoed
+// value can be told apart from an attempt to change it.
+type profileUpdate struct {
+	Profile
+	Points *int `json:"points"`
+}
+
 var (
 	mu      sync.RWMutex
 	profile = Profile{
@@ -34,7 +41,7 @@ var (
 
 // setupApp builds the Fiber app. Separated so tests can reuse it.
 func setupApp() *fiber.App {
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: problemErrorHandler})
 
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.SendString("Hello world")
@@ -48,13 +55,23 @@ func setupApp() *fiber.App {
 	})
 
 	// PUT accepts a full or partial profile JSON and updates server-side store.
+	// Points are derived from the points ledger, so they may be echoed back
+	// unchanged but never set by the client.
 	app.Put("/profile", func(c *fiber.Ctx) error {
-		var in Profile
+		var in profileUpdate
 		if err := c.BodyParser(&in); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+			return validationProblem("invalid json")
 		}
 
 		mu.Lock()
+		if in.Points != nil && *in.Points != profile.Points {
+			mu.Unlock()
+			return validationProblem("points are read-only", FieldError{
+				Field:   "points",
+				Message: "points cannot be changed; they are derived from the points ledger",
+			})
+		}
+
 		// update only non-empty / non-zero fields so clients can PATCH-like behaviour
 		if in.MembershipLevel != "" {
 			profile.MembershipLevel = in.MembershipLevel
@@ -77,9 +94,6 @@ func setupApp() *fiber.App {
 		if in.JoinedDate != "" {
 			profile.JoinedDate = in.JoinedDate
 		}
-		if in.Points != 0 {
-			profile.Points = in.Points
-		}
 		p := profile
 		mu.Unlock()
 
@@ -162,12 +176,49 @@ func setupApp() *fiber.App {
 									},
 								},
 							},
+							"400": map[string]interface{}{
+								"$ref": "#/components/responses/Problem",
+							},
 						},
 					},
 				},
 			},
 			"components": map[string]interface{}{
+				"responses": map[string]interface{}{
+					"Problem": map[string]interface{}{
+						"description": "Error described as RFC 7807 problem details",
+						"content": map[string]interface{}{
+							"application/problem+json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"$ref": "#/components/schemas/Problem",
+								},
+							},
+						},
+					},
+				},
 				"schemas": map[string]interface{}{
+					"Problem": map[string]interface{}{
+						"type":     "object",
+						"required": []string{"type", "title", "status"},
+						"properties": map[string]interface{}{
+							"type":     map[string]interface{}{"type": "string", "format": "uri-reference"},
+							"title":    map[string]interface{}{"type": "string"},
+							"status":   map[string]interface{}{"type": "integer"},
+							"detail":   map[string]interface{}{"type": "string"},
+							"instance": map[string]interface{}{"type": "string", "format": "uri-reference"},
+							"errors": map[string]interface{}{
+								"type":  "array",
+								"items": map[string]interface{}{"$ref": "#/components/schemas/FieldError"},
+							},
+						},
+					},
+					"FieldError": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"field":   map[string]interface{}{"type": "string"},
+							"message": map[string]interface{}{"type": "string"},
+						},
+					},
 					"Profile": map[string]interface{}{
 						"type": "object",
 						"properties": map[string]interface{}{
@@ -178,7 +229,7 @@ func setupApp() *fiber.App {
 							"phone":            map[string]interface{}{"type": "string"},
 							"email":            map[string]interface{}{"type": "string"},
 							"joined_date":      map[string]interface{}{"type": "string", "format": "date"},
-							"points":           map[string]interface{}{"type": "integer", "format": "int32"},
+							"points":           map[string]interface{}{"type": "integer", "format": "int32", "readOnly": true, "description": "Balance derived from the points ledger; PUT rejects changes"},
 						},
 					},
 				},
@@ -35,7 +35,7 @@ func TestPutProfileUpdatesFields(t *testing.T) {
 
 	update := map[string]interface{}{
 		"first_name": "สมชาย-updated",
-		"points":     20000,
+		"points":     15420, // echoing the current balance is allowed
 	}
 	b, _ := json.Marshal(update)
 	req := httptest.NewRequest("PUT", "/profile", bytes.NewReader(b))
@@ -55,7 +55,65 @@ func TestPutProfileUpdatesFields(t *testing.T) {
 	if p.FirstName != "สมชาย-updated" {
 		t.Fatalf("first name not updated, got %q", p.FirstName)
 	}
-	if p.Points != 20000 {
-		t.Fatalf("points not updated, got %d", p.Points)
+	if p.Points != 15420 {
+		t.Fatalf("points changed, got %d", p.Points)
+	}
+}
+
+func TestPutProfileRejectsPointsChange(t *testing.T) {
+	app := setupApp()
+
+	b, _ := json.Marshal(map[string]interface{}{"first_name": "Hacker", "points": 999999})
+	req := httptest.NewRequest("PUT", "/profile", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", resp.StatusCode)
+	}
+
+	var p Problem
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(p.Errors) != 1 || p.Errors[0].Field != "points" {
+		t.Fatalf("expected a points field error, got %+v", p.Errors)
+	}
+
+	// Nothing from the rejected request may be applied
+	resp, _ = app.Test(httptest.NewRequest("GET", "/profile", nil))
+	var current Profile
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if current.Points != 15420 || current.FirstName == "Hacker" {
+		t.Fatalf("rejected update was applied: %+v", current)
+	}
+}
+
+func TestPutProfileInvalidJSONReturnsProblem(t *testing.T) {
+	app := setupApp()
+
+	req := httptest.NewRequest("PUT", "/profile", bytes.NewReader([]byte("{not json")))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != mimeProblemJSON {
+		t.Fatalf("expected Content-Type %q, got %q", mimeProblemJSON, ct)
+	}
+
+	var p Problem
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if p.Status != fiber.StatusBadRequest || p.Type == "" || p.Title == "" || p.Instance != "/profile" {
+		t.Fatalf("unexpected problem body: %+v", p)
 	}
 }
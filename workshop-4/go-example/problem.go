@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// mimeProblemJSON is the media type of RFC 7807 problem details
+const mimeProblemJSON = "application/problem+json"
+
+// FieldError describes a problem with a single input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details response body.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Error lets handlers return a Problem directly.
+func (p *Problem) Error() string {
+	return p.Title + ": " + p.Detail
+}
+
+// validationProblem builds a 400 problem with optional per-field details.
+func validationProblem(detail string, fields ...FieldError) *Problem {
+	return &Problem{
+		Type:   "/problems/validation-error",
+		Title:  "Validation Error",
+		Status: fiber.StatusBadRequest,
+		Detail: detail,
+		Errors: fields,
+	}
+}
+
+// problemErrorHandler renders every error returned by a handler as
+// application/problem+json.
+func problemErrorHandler(c *fiber.Ctx, err error) error {
+	var problem *Problem
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &problem):
+		p := *problem
+		problem = &p
+	case errors.As(err, &fiberErr):
+		problem = &Problem{
+			Type:   "about:blank",
+			Title:  fiberErr.Message,
+			Status: fiberErr.Code,
+		}
+	default:
+		log.Printf("%s %s: %v", c.Method(), c.Path(), err)
+		problem = &Problem{
+			Type:   "/problems/internal-error",
+			Title:  "Internal Server Error",
+			Status: fiber.StatusInternalServerError,
+			Detail: "An unexpected error occurred",
+		}
+	}
+	problem.Instance = c.Path()
+
+	c.Status(problem.Status)
+	return c.JSON(problem, mimeProblemJSON)
+}
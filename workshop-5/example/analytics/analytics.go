@@ -0,0 +1,63 @@
+// Package analytics records product analytics events. Events are facts
+// about what happened, named in snake_case (e.g. sms_sent), with a flat set
+// of string properties. LogEmitter writes them as JSON lines, for a log
+// shipper to forward to the analytics pipeline.
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event is a single analytics event
+type Event struct {
+	Name       string            `json:"event"`
+	Properties map[string]string `json:"properties,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+// NewEvent creates an event that occurred now
+func NewEvent(name string, properties map[string]string) Event {
+	return Event{Name: name, Properties: properties, OccurredAt: time.Now().UTC()}
+}
+
+// LogEmitter writes every event to w as a JSON line and keeps them for
+// tests to inspect
+type LogEmitter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	events []Event
+}
+
+// NewLogEmitter creates an emitter writing to w
+func NewLogEmitter(w io.Writer) *LogEmitter {
+	return &LogEmitter{w: w}
+}
+
+// Emit writes event to the log
+func (e *LogEmitter) Emit(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode analytics event: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, err := e.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write analytics event: %w", err)
+	}
+	e.events = append(e.events, event)
+	return nil
+}
+
+// Events returns the events emitted so far, oldest first
+func (e *LogEmitter) Events() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]Event(nil), e.events...)
+}
@@ -0,0 +1,34 @@
+package analytics_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"example.com/mike/analytics"
+)
+
+func TestLogEmitter(t *testing.T) {
+	var buf bytes.Buffer
+	emitter := analytics.NewLogEmitter(&buf)
+
+	event := analytics.NewEvent("sms_sent", map[string]string{"order_id": "o1"})
+	if err := emitter.Emit(event); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	var line struct {
+		Event      string            `json:"event"`
+		Properties map[string]string `json:"properties"`
+		OccurredAt string            `json:"occurred_at"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("event line is not JSON: %v", err)
+	}
+	if line.Event != "sms_sent" || line.Properties["order_id"] != "o1" || line.OccurredAt == "" {
+		t.Fatalf("unexpected event line: %+v", line)
+	}
+	if events := emitter.Events(); len(events) != 1 || events[0].Name != "sms_sent" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
@@ -0,0 +1,229 @@
+// Package apperror defines the domain error taxonomy shared by the
+// repository, usecase and handler layers.
+//
+// Every error carries a Kind (not found, conflict, validation,
+// unprocessable, unauthorized, forbidden, too many requests, unavailable or
+// internal).
+// Callers test the kind with errors.Is against the package sentinels and
+// read details with errors.As:
+//
+//	if errors.Is(err, apperror.ErrNotFound) { ... }
+//
+//	var appErr *apperror.Error
+//	if errors.As(err, &appErr) { fmt.Println(appErr.Fields) }
+package apperror
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kind classifies an error so outer layers can react without inspecting
+// messages
+type Kind int
+
+const (
+	// KindInternal is an unexpected failure; details must not reach clients
+	KindInternal Kind = iota
+
+	// KindNotFound means the requested resource does not exist
+	KindNotFound
+
+	// KindConflict means the request clashes with existing state,
+	// e.g. a uniqueness violation
+	KindConflict
+
+	// KindValidation means the input is malformed or breaks a business rule
+	KindValidation
+
+	// KindUnprocessable means the input is well-formed but cannot be
+	// processed as sent, e.g. an idempotency key reused for another request
+	KindUnprocessable
+
+	// KindUnauthorized means the caller could not be authenticated, e.g. a
+	// missing or invalid signature
+	KindUnauthorized
+
+	// KindForbidden means the caller is known but not allowed to perform
+	// the operation
+	KindForbidden
+
+	// KindTooManyRequests means the caller went over its rate limit and
+	// should retry later
+	KindTooManyRequests
+
+	// KindUnavailable means the operation was abandoned because its
+	// request was cancelled or ran out of time; it may succeed if retried
+	KindUnavailable
+)
+
+// String returns the name of the kind
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindConflict:
+		return "conflict"
+	case KindValidation:
+		return "validation"
+	case KindUnprocessable:
+		return "unprocessable"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindForbidden:
+		return "forbidden"
+	case KindTooManyRequests:
+		return "too many requests"
+	case KindUnavailable:
+		return "unavailable"
+	default:
+		return "internal"
+	}
+}
+
+// Sentinels matching every error of the corresponding kind via errors.Is
+var (
+	ErrInternal   = errors.New("internal error")
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+
+	ErrUnprocessable = errors.New("unprocessable")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrForbidden     = errors.New("forbidden")
+
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrUnavailable     = errors.New("unavailable")
+)
+
+// FieldError describes a problem with a single input field
+type FieldError struct {
+	Field   string `json:"field" example:"email"`
+	Message string `json:"message" example:"email is required"`
+}
+
+// Error is a classified domain error
+type Error struct {
+	// Kind classifies the error
+	Kind Kind
+
+	// Message is safe to show to API clients, except for internal errors
+	// where handlers replace it with a generic message
+	Message string
+
+	// Fields lists per-field problems for validation errors
+	Fields []FieldError
+
+	// Err is the underlying cause, if any. It is never shown to clients.
+	Err error
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	msg := e.Message
+	if len(e.Fields) > 0 {
+		parts := make([]string, len(e.Fields))
+		for i, f := range e.Fields {
+			parts[i] = f.Message
+		}
+		msg += ": " + strings.Join(parts, "; ")
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel for this error's kind
+func (e *Error) Is(target error) bool {
+	return target == e.Kind.sentinel()
+}
+
+// sentinel returns the package sentinel for the kind
+func (k Kind) sentinel() error {
+	switch k {
+	case KindNotFound:
+		return ErrNotFound
+	case KindConflict:
+		return ErrConflict
+	case KindValidation:
+		return ErrValidation
+	case KindUnprocessable:
+		return ErrUnprocessable
+	case KindUnauthorized:
+		return ErrUnauthorized
+	case KindForbidden:
+		return ErrForbidden
+	case KindTooManyRequests:
+		return ErrTooManyRequests
+	case KindUnavailable:
+		return ErrUnavailable
+	default:
+		return ErrInternal
+	}
+}
+
+// NotFound creates a not-found error
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+// Conflict creates a conflict error
+func Conflict(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+// Validation creates a validation error with optional per-field details
+func Validation(message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Message: message, Fields: fields}
+}
+
+// Unprocessable creates an error for well-formed input that cannot be
+// processed as sent
+func Unprocessable(message string) *Error {
+	return &Error{Kind: KindUnprocessable, Message: message}
+}
+
+// Unauthorized creates an error for callers that could not be
+// authenticated
+func Unauthorized(message string) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message}
+}
+
+// Forbidden creates an error for authenticated callers that may not
+// perform an operation
+func Forbidden(message string) *Error {
+	return &Error{Kind: KindForbidden, Message: message}
+}
+
+// TooManyRequests creates an error for callers over their rate limit
+func TooManyRequests(message string) *Error {
+	return &Error{Kind: KindTooManyRequests, Message: message}
+}
+
+// Unavailable wraps the error of an operation abandoned because its request
+// was cancelled or timed out. The message is shown to clients; the cause is
+// only logged.
+func Unavailable(message string, cause error) *Error {
+	return &Error{Kind: KindUnavailable, Message: message, Err: cause}
+}
+
+// Internal wraps an unexpected failure. Neither the message nor the cause
+// is shown to clients; both are only logged.
+func Internal(message string, cause error) *Error {
+	return &Error{Kind: KindInternal, Message: message, Err: cause}
+}
+
+// KindOf returns the kind of err. Errors outside the taxonomy are internal.
+func KindOf(err error) Kind {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Kind
+	}
+	return KindInternal
+}
@@ -0,0 +1,82 @@
+package apperror_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"example.com/mike/apperror"
+)
+
+func TestErrorsIsMatchesKindSentinel(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		sentinel error
+		kind     apperror.Kind
+	}{
+		{"not found", apperror.NotFound("user not found"), apperror.ErrNotFound, apperror.KindNotFound},
+		{"conflict", apperror.Conflict("email taken"), apperror.ErrConflict, apperror.KindConflict},
+		{"validation", apperror.Validation("bad input"), apperror.ErrValidation, apperror.KindValidation},
+		{"unprocessable", apperror.Unprocessable("key reused"), apperror.ErrUnprocessable, apperror.KindUnprocessable},
+		{"unauthorized", apperror.Unauthorized("bad signature"), apperror.ErrUnauthorized, apperror.KindUnauthorized},
+		{"forbidden", apperror.Forbidden("staff only"), apperror.ErrForbidden, apperror.KindForbidden},
+		{"too many requests", apperror.TooManyRequests("slow down"), apperror.ErrTooManyRequests, apperror.KindTooManyRequests},
+		{"unavailable", apperror.Unavailable("timed out", context.DeadlineExceeded), apperror.ErrUnavailable, apperror.KindUnavailable},
+		{"internal", apperror.Internal("boom", errors.New("disk full")), apperror.ErrInternal, apperror.KindInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapped := fmt.Errorf("context: %w", tt.err)
+			if !errors.Is(wrapped, tt.sentinel) {
+				t.Fatalf("errors.Is(%v, %v) = false", wrapped, tt.sentinel)
+			}
+			if got := apperror.KindOf(wrapped); got != tt.kind {
+				t.Fatalf("KindOf = %v, want %v", got, tt.kind)
+			}
+			for _, other := range []error{apperror.ErrNotFound, apperror.ErrConflict, apperror.ErrValidation, apperror.ErrUnprocessable, apperror.ErrUnauthorized, apperror.ErrForbidden, apperror.ErrTooManyRequests, apperror.ErrUnavailable, apperror.ErrInternal} {
+				if other != tt.sentinel && errors.Is(wrapped, other) {
+					t.Fatalf("errors.Is(%v, %v) = true", wrapped, other)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorsAsExposesFields(t *testing.T) {
+	err := fmt.Errorf("register: %w", apperror.Validation("Invalid registration data",
+		apperror.FieldError{Field: "email", Message: "email is required"},
+		apperror.FieldError{Field: "phone", Message: "phone number is required"},
+	))
+
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("errors.As failed for %v", err)
+	}
+	if len(appErr.Fields) != 2 || appErr.Fields[0].Field != "email" {
+		t.Fatalf("unexpected fields: %+v", appErr.Fields)
+	}
+}
+
+func TestInternalUnwrapsCause(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := apperror.Internal("Failed to create user", cause)
+	if !errors.Is(err, cause) {
+		t.Fatal("expected internal error to unwrap to its cause")
+	}
+}
+
+func TestUnavailableUnwrapsCause(t *testing.T) {
+	err := apperror.Unavailable("Request timed out", fmt.Errorf("get user: %w", context.DeadlineExceeded))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected unavailable error to unwrap to its cause")
+	}
+}
+
+func TestKindOfUnclassifiedError(t *testing.T) {
+	if got := apperror.KindOf(errors.New("plain")); got != apperror.KindInternal {
+		t.Fatalf("KindOf(plain error) = %v, want internal", got)
+	}
+}
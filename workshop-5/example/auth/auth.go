@@ -0,0 +1,31 @@
+// Package auth signs the access tokens handed out at login, delivers the
+// one-time codes users log in with and signs partner API requests.
+//
+// Access tokens are JSON Web Tokens (RFC 7519) signed with HMAC-SHA256:
+//
+//	<base64url header>.<base64url claims>.<base64url signature>
+//
+// Only the HS256 algorithm is accepted, whatever the header says, so a
+// token cannot downgrade itself to "none" or to another key type. Tokens
+// are short-lived and not stored: a valid signature and an unexpired "exp"
+// claim are all Verify checks.
+//
+// Partner requests are not tokens: each one carries an HMAC-SHA256 of its
+// method, path, body, timestamp and nonce made with the partner's API key
+// secret, see SignRequest.
+//
+// Two code senders are provided: LogCodeSender, a fake that writes each
+// code as a JSON line for local development and tests, and SMSCodeSender,
+// which texts codes to phone numbers through an sms sender.
+package auth
+
+import "errors"
+
+// Errors returned by the code senders
+var (
+	// ErrInvalidCode is returned for codes without a destination or value
+	ErrInvalidCode = errors.New("login code needs a destination and a value")
+
+	// ErrUnsupportedChannel is returned for channels a sender cannot reach
+	ErrUnsupportedChannel = errors.New("login code channel is not supported")
+)
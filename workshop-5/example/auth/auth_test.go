@@ -0,0 +1,213 @@
+package auth_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/mike/auth"
+	"example.com/mike/entity"
+	"example.com/mike/sms"
+)
+
+func newSigner(t *testing.T, fill byte) *auth.Signer {
+	t.Helper()
+	signer, err := auth.NewSigner(bytes.Repeat([]byte{fill}, auth.MinSecretLength))
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	return signer
+}
+
+func newClaims() auth.Claims {
+	now := time.Now()
+	return auth.Claims{
+		Subject:   "550e8400-e29b-41d4-a716-446655440000",
+		ID:        "c0a8012e-5d4b-4e6f-8a9b-1c2d3e4f5a6b",
+		IssuedAt:  now,
+		ExpiresAt: now.Add(15 * time.Minute),
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer := newSigner(t, 1)
+	claims := newClaims()
+
+	token, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if strings.Count(token, ".") != 2 {
+		t.Fatalf("token %q is not a JWT", token)
+	}
+
+	got, err := signer.Verify(token, time.Now())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.Subject != claims.Subject || got.ID != claims.ID ||
+		got.IssuedAt.Unix() != claims.IssuedAt.Unix() || got.ExpiresAt.Unix() != claims.ExpiresAt.Unix() {
+		t.Fatalf("claims mismatch\n got: %+v\nwant: %+v", got, claims)
+	}
+
+	if _, err := signer.Verify(token, claims.ExpiresAt.Add(time.Second)); !errors.Is(err, auth.ErrExpired) {
+		t.Fatalf("Verify after expiry: expected %v, got %v", auth.ErrExpired, err)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	signer := newSigner(t, 1)
+	token, _ := signer.Sign(newClaims())
+	forged, _ := newSigner(t, 2).Sign(newClaims())
+	parts := strings.Split(token, ".")
+
+	// A payload naming another user, kept with the original signature
+	other := newClaims()
+	other.Subject = "someone-else"
+	payload, _ := json.Marshal(map[string]any{"sub": other.Subject, "jti": other.ID, "exp": other.ExpiresAt.Unix()})
+	swapped := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"empty", "", auth.ErrMalformed},
+		{"two segments", parts[0] + "." + parts[1], auth.ErrMalformed},
+		{"header not base64", "!!." + parts[1] + "." + parts[2], auth.ErrMalformed},
+		{"alg none", none + "." + parts[1] + ".", auth.ErrUnsupportedAlgorithm},
+		{"other secret", forged, auth.ErrBadSignature},
+		{"swapped payload", swapped, auth.ErrBadSignature},
+		{"no signature", parts[0] + "." + parts[1] + ".", auth.ErrBadSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.Verify(tt.token, time.Now()); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSignRejectsInvalidClaims(t *testing.T) {
+	signer := newSigner(t, 1)
+
+	noSubject := newClaims()
+	noSubject.Subject = ""
+	backwards := newClaims()
+	backwards.ExpiresAt = backwards.IssuedAt
+
+	for _, claims := range []auth.Claims{noSubject, backwards} {
+		if _, err := signer.Sign(claims); err == nil {
+			t.Fatalf("Sign(%+v): expected an error", claims)
+		}
+	}
+}
+
+func TestNewSignerRejectsShortSecret(t *testing.T) {
+	if _, err := auth.NewSigner(make([]byte, auth.MinSecretLength-1)); err == nil {
+		t.Fatal("expected an error for a short secret")
+	}
+}
+
+func TestLogCodeSender(t *testing.T) {
+	var out bytes.Buffer
+	sender := auth.NewLogCodeSender(&out)
+
+	if err := sender.SendCode(entity.OTPChannelEmail, "alice@example.com", "123456"); err != nil {
+		t.Fatalf("SendCode: %v", err)
+	}
+	if err := sender.SendCode(entity.OTPChannelPhone, "", "123456"); !errors.Is(err, auth.ErrInvalidCode) {
+		t.Fatalf("SendCode without destination: expected %v, got %v", auth.ErrInvalidCode, err)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not JSON: %v\n%s", err, out.String())
+	}
+	if line["channel"] != "email" || line["to"] != "alice@example.com" || line["code"] != "123456" || line["sent_at"] == nil {
+		t.Fatalf("unexpected log line: %v", line)
+	}
+	if sent := sender.Sent(); len(sent) != 1 || sent[0].Code != "123456" {
+		t.Fatalf("unexpected sent codes: %+v", sent)
+	}
+}
+
+func TestSMSCodeSender(t *testing.T) {
+	texts := sms.NewLogSender(&bytes.Buffer{})
+	sender := auth.NewSMSCodeSender(texts)
+
+	if err := sender.SendCode(entity.OTPChannelPhone, "+66812345678", "654321"); err != nil {
+		t.Fatalf("SendCode: %v", err)
+	}
+	sent := texts.Sent()
+	if len(sent) != 1 || sent[0].To != "+66812345678" || !strings.Contains(sent[0].Body, "654321") {
+		t.Fatalf("unexpected messages: %+v", sent)
+	}
+	if count := sms.CountSegments(sent[0].Body); count.Encoding != sms.GSM7 || count.Segments != 1 {
+		t.Fatalf("code message bills as %+v", count)
+	}
+
+	if sender.Supports(entity.OTPChannelEmail) {
+		t.Fatal("SMS sender claims to support email")
+	}
+	if err := sender.SendCode(entity.OTPChannelEmail, "alice@example.com", "654321"); !errors.Is(err, auth.ErrUnsupportedChannel) {
+		t.Fatalf("SendCode by email: expected %v, got %v", auth.ErrUnsupportedChannel, err)
+	}
+}
+
+func TestSignAndVerifyRequest(t *testing.T) {
+	secret := []byte("partner-secret")
+	now := time.Now()
+	req := auth.SignedRequest{
+		Method:    "POST",
+		Path:      "/partner/users/u1/points",
+		Timestamp: strconv.FormatInt(now.Unix(), 10),
+		Nonce:     "3f9a1c2e4b6d8f0a",
+		Body:      []byte(`{"amount":100}`),
+	}
+	signature := auth.SignRequest(secret, req)
+	if !strings.HasPrefix(signature, "sha256=") {
+		t.Fatalf("signature %q lacks its sha256= prefix", signature)
+	}
+
+	fresh, err := auth.VerifyRequest(secret, req, signature, now)
+	if err != nil {
+		t.Fatalf("VerifyRequest: %v", err)
+	}
+	if want := time.Unix(now.Unix(), 0).Add(auth.MaxRequestAge); !fresh.Equal(want) {
+		t.Fatalf("fresh until %v, want %v", fresh, want)
+	}
+
+	// Every part of the request is covered by the signature
+	for name, tampered := range map[string]auth.SignedRequest{
+		"method":    {Method: "PUT", Path: req.Path, Timestamp: req.Timestamp, Nonce: req.Nonce, Body: req.Body},
+		"path":      {Method: req.Method, Path: "/partner/users/u2/points", Timestamp: req.Timestamp, Nonce: req.Nonce, Body: req.Body},
+		"timestamp": {Method: req.Method, Path: req.Path, Timestamp: strconv.FormatInt(now.Unix()+1, 10), Nonce: req.Nonce, Body: req.Body},
+		"nonce":     {Method: req.Method, Path: req.Path, Timestamp: req.Timestamp, Nonce: "other", Body: req.Body},
+		"body":      {Method: req.Method, Path: req.Path, Timestamp: req.Timestamp, Nonce: req.Nonce, Body: []byte(`{"amount":1000}`)},
+	} {
+		if _, err := auth.VerifyRequest(secret, tampered, signature, now); !errors.Is(err, auth.ErrBadRequestSignature) {
+			t.Fatalf("tampered %s: expected %v, got %v", name, auth.ErrBadRequestSignature, err)
+		}
+	}
+	for _, bad := range []string{"", strings.TrimPrefix(signature, "sha256="), "sha256=zz", auth.SignRequest([]byte("guessed"), req)} {
+		if _, err := auth.VerifyRequest(secret, req, bad, now); !errors.Is(err, auth.ErrBadRequestSignature) {
+			t.Fatalf("signature %q: expected %v, got %v", bad, auth.ErrBadRequestSignature, err)
+		}
+	}
+
+	if _, err := auth.VerifyRequest(secret, req, signature, now.Add(auth.MaxRequestAge+time.Second)); !errors.Is(err, auth.ErrStaleRequest) {
+		t.Fatalf("late request: expected %v, got %v", auth.ErrStaleRequest, err)
+	}
+	if _, err := auth.VerifyRequest(secret, req, signature, now.Add(-auth.MaxRequestAge-time.Second)); !errors.Is(err, auth.ErrStaleRequest) {
+		t.Fatalf("request from the future: expected %v, got %v", auth.ErrStaleRequest, err)
+	}
+}
@@ -0,0 +1,67 @@
+package entity
+
+import "time"
+
+// CartItem is a product and quantity in a member's cart
+type CartItem struct {
+	ProductID string `json:"product_id" example:"3e4f5a6b-7c8d-4e9f-a0b1-c2d3e4f5a6b7"`
+	Quantity  int    `json:"quantity" example:"2"`
+}
+
+// Cart holds the items a member intends to buy. Every member has at most
+// one cart; prices are not stored and always come from the catalog.
+type Cart struct {
+	UserID        string     `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Items         []CartItem `json:"items"`
+	CustomerName  string     `json:"customer_name,omitempty" example:"Somchai Jaidee"`
+	CustomerPhone string     `json:"customer_phone,omitempty" example:"+66812345678"`
+	UpdatedAt     time.Time  `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// NewCart creates an empty cart for a member
+func NewCart(userID string) *Cart {
+	return &Cart{
+		UserID:    userID,
+		Items:     []CartItem{},
+		UpdatedAt: time.Now(),
+	}
+}
+
+// Item returns the cart item for productID, or nil if it is not in the cart
+func (c *Cart) Item(productID string) *CartItem {
+	for i := range c.Items {
+		if c.Items[i].ProductID == productID {
+			return &c.Items[i]
+		}
+	}
+	return nil
+}
+
+// SetQuantity sets the quantity of a product, adding it to the end of the
+// cart if needed. A quantity of zero or less removes the product.
+func (c *Cart) SetQuantity(productID string, quantity int) {
+	for i := range c.Items {
+		if c.Items[i].ProductID != productID {
+			continue
+		}
+		if quantity <= 0 {
+			c.Items = append(c.Items[:i], c.Items[i+1:]...)
+		} else {
+			c.Items[i].Quantity = quantity
+		}
+		return
+	}
+	if quantity > 0 {
+		c.Items = append(c.Items, CartItem{ProductID: productID, Quantity: quantity})
+	}
+}
+
+// Clone returns a deep copy of the cart
+func (c *Cart) Clone() *Cart {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.Items = append([]CartItem{}, c.Items...)
+	return &clone
+}
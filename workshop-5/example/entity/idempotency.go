@@ -0,0 +1,39 @@
+package entity
+
+import "time"
+
+// IdempotencyRecord is the outcome of the first request made with an
+// Idempotency-Key. Retries with the same key replay the stored response.
+type IdempotencyRecord struct {
+	// Key identifies the caller and the key they sent
+	Key string
+
+	// RequestHash fingerprints the method, URL and body of the first request
+	RequestHash string
+
+	// Completed is false while the first request is still being processed
+	Completed bool
+
+	// StatusCode, ContentType and Body hold the stored response
+	StatusCode  int
+	ContentType string
+	Body        []byte
+
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the record no longer applies at now
+func (r *IdempotencyRecord) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// Clone returns a deep copy of the record
+func (r *IdempotencyRecord) Clone() *IdempotencyRecord {
+	if r == nil {
+		return nil
+	}
+	clone := *r
+	clone.Body = append([]byte(nil), r.Body...)
+	return &clone
+}
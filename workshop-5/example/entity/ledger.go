@@ -0,0 +1,164 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EntryType classifies a ledger entry from the account holder's point of view
+type EntryType string
+
+// Ledger entry types
+const (
+	EntryEarn        EntryType = "earn"
+	EntrySpend       EntryType = "spend"
+	EntryTransferIn  EntryType = "transfer_in"
+	EntryTransferOut EntryType = "transfer_out"
+
+	// EntryReversal undoes an entry of another type; see Posting.Reversal
+	EntryReversal EntryType = "reversal"
+)
+
+// Valid reports whether t is a known entry type
+func (t EntryType) Valid() bool {
+	switch t {
+	case EntryEarn, EntrySpend, EntryTransferIn, EntryTransferOut, EntryReversal:
+		return true
+	}
+	return false
+}
+
+// System accounts are the other side of postings that create or consume
+// points. Their balances may go negative; member balances may not.
+const (
+	SystemAccountPrefix = "system:"
+
+	// AccountRewards issues earned points
+	AccountRewards = SystemAccountPrefix + "rewards"
+
+	// AccountRedemptions receives spent points
+	AccountRedemptions = SystemAccountPrefix + "redemptions"
+)
+
+// IsSystemAccount reports whether accountID is a system account rather
+// than a user ID
+func IsSystemAccount(accountID string) bool {
+	return strings.HasPrefix(accountID, SystemAccountPrefix)
+}
+
+// LedgerEntry is one immutable leg of a posting. Amount is signed: positive
+// amounts credit the account, negative amounts debit it.
+type LedgerEntry struct {
+	ID             string    `json:"id" example:"7d9f7a4e-0c1b-4f0e-9a53-3b6f2d1c8e90:0"`
+	PostingID      string    `json:"posting_id" example:"7d9f7a4e-0c1b-4f0e-9a53-3b6f2d1c8e90"`
+	AccountID      string    `json:"account_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Type           EntryType `json:"type" example:"earn"`
+	Amount         int       `json:"amount" example:"100"`
+	CounterpartyID string    `json:"counterparty_id,omitempty" example:"system:rewards"`
+	OrderID        string    `json:"order_id,omitempty"`
+	TransferID     string    `json:"transfer_id,omitempty"`
+	Memo           string    `json:"memo,omitempty" example:"Welcome bonus"`
+	CreatedAt      time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// Posting is a balanced group of entries that is written atomically: the
+// amounts of its entries sum to zero, so points are only ever moved between
+// accounts, never created or destroyed.
+type Posting struct {
+	ID        string        `json:"id"`
+	Entries   []LedgerEntry `json:"entries"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// NewPosting creates a posting from its legs. Each leg gets an entry ID
+// derived from the posting ID, the posting timestamp, and the other leg's
+// account as counterparty when there are exactly two legs.
+func NewPosting(id string, legs ...LedgerEntry) *Posting {
+	p := &Posting{ID: id, CreatedAt: time.Now()}
+	for i, leg := range legs {
+		leg.ID = fmt.Sprintf("%s:%d", id, i)
+		leg.PostingID = id
+		leg.CreatedAt = p.CreatedAt
+		if len(legs) == 2 && leg.CounterpartyID == "" {
+			leg.CounterpartyID = legs[1-i].AccountID
+		}
+		p.Entries = append(p.Entries, leg)
+	}
+	return p
+}
+
+// NewEarnPosting credits accountID with amount points issued by the rewards
+// account
+func NewEarnPosting(id, accountID string, amount int, memo string) *Posting {
+	return NewPosting(id,
+		LedgerEntry{AccountID: accountID, Type: EntryEarn, Amount: amount, Memo: memo},
+		LedgerEntry{AccountID: AccountRewards, Type: EntryEarn, Amount: -amount, Memo: memo},
+	)
+}
+
+// NewSpendPosting debits amount points from accountID into the redemptions
+// account. orderID is optional.
+func NewSpendPosting(id, accountID string, amount int, memo, orderID string) *Posting {
+	return NewPosting(id,
+		LedgerEntry{AccountID: accountID, Type: EntrySpend, Amount: -amount, Memo: memo, OrderID: orderID},
+		LedgerEntry{AccountID: AccountRedemptions, Type: EntrySpend, Amount: amount, Memo: memo, OrderID: orderID},
+	)
+}
+
+// NewTransferPosting moves amount points from one member to another
+func NewTransferPosting(id, fromAccountID, toAccountID string, amount int, memo, transferID string) *Posting {
+	return NewPosting(id,
+		LedgerEntry{AccountID: fromAccountID, Type: EntryTransferOut, Amount: -amount, Memo: memo, TransferID: transferID},
+		LedgerEntry{AccountID: toAccountID, Type: EntryTransferIn, Amount: amount, Memo: memo, TransferID: transferID},
+	)
+}
+
+// Reversal returns a posting with the given ID and memo that undoes p:
+// every entry is repeated with the opposite amount and type EntryReversal,
+// so history filters do not mistake it for a new earn, spend or transfer.
+// Order and transfer IDs are kept to link it to what it undoes.
+func (p *Posting) Reversal(id, memo string) *Posting {
+	legs := make([]LedgerEntry, len(p.Entries))
+	for i, e := range p.Entries {
+		legs[i] = LedgerEntry{
+			AccountID:      e.AccountID,
+			Type:           EntryReversal,
+			Amount:         -e.Amount,
+			CounterpartyID: e.CounterpartyID,
+			OrderID:        e.OrderID,
+			TransferID:     e.TransferID,
+			Memo:           memo,
+		}
+	}
+	return NewPosting(id, legs...)
+}
+
+// Balanced reports whether the entry amounts sum to zero
+func (p *Posting) Balanced() bool {
+	sum := 0
+	for _, e := range p.Entries {
+		sum += e.Amount
+	}
+	return sum == 0
+}
+
+// EntryFor returns the posting's entry for accountID, or nil
+func (p *Posting) EntryFor(accountID string) *LedgerEntry {
+	for i := range p.Entries {
+		if p.Entries[i].AccountID == accountID {
+			return &p.Entries[i]
+		}
+	}
+	return nil
+}
+
+// Clone returns a copy of the posting that shares no state with the original
+func (p *Posting) Clone() *Posting {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	clone.Entries = append([]LedgerEntry(nil), p.Entries...)
+	return &clone
+}
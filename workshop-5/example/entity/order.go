@@ -0,0 +1,165 @@
+package entity
+
+import "time"
+
+// OrderStatus is the lifecycle state of an order
+type OrderStatus string
+
+// Order statuses. An order is created, then paid and finally fulfilled when
+// the goods are handed over. A created or paid order can be voided and a
+// fulfilled one refunded, which returns the points and the stock.
+const (
+	OrderCreated   OrderStatus = "created"
+	OrderPaid      OrderStatus = "paid"
+	OrderFulfilled OrderStatus = "fulfilled"
+	OrderVoided    OrderStatus = "voided"
+	OrderRefunded  OrderStatus = "refunded"
+)
+
+// OrderEvent records a status change of an order, who made it and why
+type OrderEvent struct {
+	From      OrderStatus `json:"from,omitempty" example:"paid"`
+	To        OrderStatus `json:"to" example:"fulfilled"`
+	Actor     string      `json:"actor" example:"staff-042"`
+	Reason    string      `json:"reason,omitempty" example:"Picked up at the counter"`
+	CreatedAt time.Time   `json:"created_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// OrderItem is a product bought in an order, priced when the order was
+// placed
+type OrderItem struct {
+	ProductID string `json:"product_id" example:"3e4f5a6b-7c8d-4e9f-a0b1-c2d3e4f5a6b7"`
+	Name      string `json:"name" example:"Iced Latte"`
+	UnitPrice int    `json:"unit_price" example:"120"`
+	Quantity  int    `json:"quantity" example:"2"`
+	LineTotal int    `json:"line_total" example:"240"`
+}
+
+// Order is a member's purchase of catalog products with points
+type Order struct {
+	ID            string       `json:"id" example:"8a7b6c5d-4e3f-4a2b-9c1d-0e9f8a7b6c5d"`
+	UserID        string       `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Items         []OrderItem  `json:"items"`
+	Total         int          `json:"total" example:"240"`
+	CustomerName  string       `json:"customer_name,omitempty" example:"Somchai Jaidee"`
+	CustomerPhone string       `json:"customer_phone,omitempty" example:"+66812345678"`
+	Status        OrderStatus  `json:"status" example:"paid"`
+	Events        []OrderEvent `json:"events"`
+	CreatedAt     time.Time    `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt     time.Time    `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// NewOrder creates an order for items, totalling their line totals. The
+// member placing it is recorded as the actor of its creation.
+func NewOrder(id, userID string, items []OrderItem, customerName, customerPhone string) *Order {
+	now := time.Now()
+	total := 0
+	for _, item := range items {
+		total += item.LineTotal
+	}
+	return &Order{
+		ID:            id,
+		UserID:        userID,
+		Items:         items,
+		Total:         total,
+		CustomerName:  customerName,
+		CustomerPhone: customerPhone,
+		Status:        OrderCreated,
+		Events:        []OrderEvent{{To: OrderCreated, Actor: userID, CreatedAt: now}},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Apply moves the order to status to and records the change. It does not
+// check that the change is allowed; that is up to the caller.
+func (o *Order) Apply(to OrderStatus, actor, reason string) OrderEvent {
+	event := OrderEvent{From: o.Status, To: to, Actor: actor, Reason: reason, CreatedAt: time.Now()}
+	o.Status = to
+	o.Events = append(o.Events, event)
+	o.UpdatedAt = event.CreatedAt
+	return event
+}
+
+// Quantities returns the quantity ordered of each product
+func (o *Order) Quantities() map[string]int {
+	quantities := make(map[string]int, len(o.Items))
+	for _, item := range o.Items {
+		quantities[item.ProductID] += item.Quantity
+	}
+	return quantities
+}
+
+// Clone returns a deep copy of the order
+func (o *Order) Clone() *Order {
+	if o == nil {
+		return nil
+	}
+	clone := *o
+	clone.Items = append([]OrderItem{}, o.Items...)
+	clone.Events = append([]OrderEvent{}, o.Events...)
+	return &clone
+}
+
+// PaymentMethod is how an order was paid
+type PaymentMethod string
+
+// Payment methods
+const (
+	PaymentPoints PaymentMethod = "points"
+)
+
+// PaymentStatus is the state of a payment
+type PaymentStatus string
+
+// Payment statuses. A refunded payment has been reversed in the ledger.
+const (
+	PaymentCaptured PaymentStatus = "captured"
+	PaymentRefunded PaymentStatus = "refunded"
+)
+
+// Payment records how an order was paid. A points payment is settled by the
+// ledger posting with the same ID.
+type Payment struct {
+	ID        string        `json:"id" example:"1b2c3d4e-5f6a-4b7c-8d9e-0f1a2b3c4d5e"`
+	OrderID   string        `json:"order_id" example:"8a7b6c5d-4e3f-4a2b-9c1d-0e9f8a7b6c5d"`
+	UserID    string        `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Amount    int           `json:"amount" example:"240"`
+	Method    PaymentMethod `json:"method" example:"points"`
+	Status    PaymentStatus `json:"status" example:"captured"`
+	CreatedAt time.Time     `json:"created_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// NewPointsPayment creates a captured points payment for the whole order
+func NewPointsPayment(id string, order *Order) *Payment {
+	return &Payment{
+		ID:        id,
+		OrderID:   order.ID,
+		UserID:    order.UserID,
+		Amount:    order.Total,
+		Method:    PaymentPoints,
+		Status:    PaymentCaptured,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Posting returns the ledger posting that settles a points payment,
+// spending the amount from the member's account
+func (p *Payment) Posting() *Posting {
+	return NewSpendPosting(p.ID, p.UserID, p.Amount, "Order "+p.OrderID, p.OrderID)
+}
+
+// Reversal returns the ledger posting that refunds a points payment. Its ID
+// is derived from the payment ID, so a payment can never be refunded twice.
+func (p *Payment) Reversal() *Posting {
+	return p.Posting().Reversal("reversal-"+p.ID, "Refund of order "+p.OrderID)
+}
+
+// Clone returns a copy of the payment
+func (p *Payment) Clone() *Payment {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	return &clone
+}
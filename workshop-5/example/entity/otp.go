@@ -0,0 +1,61 @@
+package entity
+
+import "time"
+
+// OTPChannel is where a one-time login code is sent
+type OTPChannel string
+
+// OTP channels
+const (
+	OTPChannelPhone OTPChannel = "phone"
+	OTPChannelEmail OTPChannel = "email"
+)
+
+// OTPChallenge is a one-time login code sent to a user. Only a hash of the
+// code is kept; a challenge is spent by the first correct code, and gives
+// out after a few wrong ones or at its expiry.
+type OTPChallenge struct {
+	ID          string     `json:"id" example:"c0a8012e-5d4b-4e6f-8a9b-1c2d3e4f5a6b"`
+	UserID      string     `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Channel     OTPChannel `json:"channel" example:"phone"`
+	Destination string     `json:"destination" example:"+66812345678"`
+	CodeHash    string     `json:"-"`
+	Attempts    int        `json:"attempts" example:"0"`
+	ExpiresAt   time.Time  `json:"expires_at" example:"2024-01-01T00:05:00Z"`
+	ConsumedAt  *time.Time `json:"consumed_at,omitempty" example:"2024-01-01T00:01:00Z"`
+	CreatedAt   time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// NewOTPChallenge creates a challenge for the code hashed as codeHash that
+// expires after ttl
+func NewOTPChallenge(id, userID string, channel OTPChannel, destination, codeHash string, ttl time.Duration) *OTPChallenge {
+	now := time.Now()
+	return &OTPChallenge{
+		ID:          id,
+		UserID:      userID,
+		Channel:     channel,
+		Destination: destination,
+		CodeHash:    codeHash,
+		ExpiresAt:   now.Add(ttl),
+		CreatedAt:   now,
+	}
+}
+
+// Expired reports whether the challenge has passed its expiry at now
+func (c *OTPChallenge) Expired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// Clone returns a copy of the challenge that shares no state with the
+// original
+func (c *OTPChallenge) Clone() *OTPChallenge {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	if c.ConsumedAt != nil {
+		consumedAt := *c.ConsumedAt
+		clone.ConsumedAt = &consumedAt
+	}
+	return &clone
+}
@@ -0,0 +1,93 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// OutboxStatus is the dispatch state of an outbox message
+type OutboxStatus string
+
+// Outbox message statuses. A message is pending until a handler delivers
+// it; a message that keeps failing, or fails in a way retrying cannot fix,
+// is dead until it is replayed.
+const (
+	OutboxPending   OutboxStatus = "pending"
+	OutboxDelivered OutboxStatus = "delivered"
+	OutboxDead      OutboxStatus = "dead"
+)
+
+// OutboxMessage is a notification or event written in the same transaction
+// as the change it reports and delivered by the dispatcher afterwards, so
+// it is never lost once the change is committed
+type OutboxMessage struct {
+	ID            string          `json:"id" example:"9b2d4f6a-1c3e-4a5b-8d7f-0e1a2b3c4d5e"`
+	Topic         string          `json:"topic" example:"sms.receipt"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	Status        OutboxStatus    `json:"status" example:"pending"`
+	Attempts      int             `json:"attempts" example:"1"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" example:"2024-01-01T00:00:02Z"`
+	LastError     string          `json:"last_error,omitempty" example:"SMS provider unreachable"`
+	CreatedAt     time.Time       `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt     time.Time       `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// NewOutboxMessage creates a pending message with payload encoded as JSON,
+// due at once
+func NewOutboxMessage(id, topic string, payload any) (*OutboxMessage, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s payload: %w", topic, err)
+	}
+	now := time.Now()
+	return &OutboxMessage{
+		ID:            id,
+		Topic:         topic,
+		Payload:       data,
+		Status:        OutboxPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}, nil
+}
+
+// MarkDelivered records that a handler delivered the message at now
+func (m *OutboxMessage) MarkDelivered(now time.Time) {
+	m.Status = OutboxDelivered
+	m.LastError = ""
+	m.UpdatedAt = now
+}
+
+// MarkRetry records a failed attempt and schedules the next one at next
+func (m *OutboxMessage) MarkRetry(reason string, next, now time.Time) {
+	m.Status = OutboxPending
+	m.LastError = reason
+	m.NextAttemptAt = next
+	m.UpdatedAt = now
+}
+
+// MarkDead records the failure that stopped the message from being retried
+func (m *OutboxMessage) MarkDead(reason string, now time.Time) {
+	m.Status = OutboxDead
+	m.LastError = reason
+	m.UpdatedAt = now
+}
+
+// Decode decodes the payload into v
+func (m *OutboxMessage) Decode(v any) error {
+	if err := json.Unmarshal(m.Payload, v); err != nil {
+		return fmt.Errorf("decode %s payload: %w", m.Topic, err)
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the message
+func (m *OutboxMessage) Clone() *OutboxMessage {
+	if m == nil {
+		return nil
+	}
+	clone := *m
+	clone.Payload = append(json.RawMessage(nil), m.Payload...)
+	return &clone
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// Product is an item members can buy with points
+type Product struct {
+	ID          string    `json:"id" example:"3e4f5a6b-7c8d-4e9f-a0b1-c2d3e4f5a6b7"`
+	Name        string    `json:"name" example:"Iced Latte"`
+	Description string    `json:"description,omitempty" example:"Double shot, oat milk"`
+	Category    string    `json:"category" example:"drinks"`
+	PricePoints int       `json:"price_points" example:"120"`
+	Active      bool      `json:"active" example:"true"`
+	Stock       int       `json:"stock" example:"40"`
+	CreatedAt   time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt   time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// NewProduct creates a product
+func NewProduct(id, name, description, category string, pricePoints, stock int, active bool) *Product {
+	now := time.Now()
+	return &Product{
+		ID:          id,
+		Name:        name,
+		Description: description,
+		Category:    category,
+		PricePoints: pricePoints,
+		Active:      active,
+		Stock:       stock,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// Clone returns a copy of the product
+func (p *Product) Clone() *Product {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	return &clone
+}
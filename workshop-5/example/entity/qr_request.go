@@ -0,0 +1,58 @@
+package entity
+
+import "time"
+
+// QRRequestStatus is the lifecycle state of a QR payment request
+type QRRequestStatus string
+
+// QR payment request statuses. A request starts pending and ends either
+// paid, once a payer settled it with a transfer, or expired.
+const (
+	QRRequestPending QRRequestStatus = "pending"
+	QRRequestPaid    QRRequestStatus = "paid"
+	QRRequestExpired QRRequestStatus = "expired"
+)
+
+// QRRequest asks for a payment of points to the recipient, shared as a
+// QR code that another member scans and pays
+type QRRequest struct {
+	ID          string          `json:"id" example:"8d3c2b1a-4e5f-4a6b-9c7d-0e1f2a3b4c5d"`
+	RecipientID string          `json:"recipient_id" example:"6ba7b810-9dad-11d1-80b4-00c04fd430c8"`
+	Amount      int             `json:"amount" example:"250"`
+	Memo        string          `json:"memo,omitempty" example:"Coffee"`
+	Status      QRRequestStatus `json:"status" example:"pending"`
+	PayerID     string          `json:"payer_id,omitempty" example:""`
+	TransferID  string          `json:"transfer_id,omitempty" example:""`
+	ExpiresAt   time.Time       `json:"expires_at" example:"2024-01-01T00:15:00Z"`
+	CreatedAt   time.Time       `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt   time.Time       `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// NewQRRequest creates a pending request that expires after ttl
+func NewQRRequest(id, recipientID string, amount int, memo string, ttl time.Duration) *QRRequest {
+	now := time.Now()
+	return &QRRequest{
+		ID:          id,
+		RecipientID: recipientID,
+		Amount:      amount,
+		Memo:        memo,
+		Status:      QRRequestPending,
+		ExpiresAt:   now.Add(ttl),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// Due reports whether a pending request has passed its expiry at now
+func (r *QRRequest) Due(now time.Time) bool {
+	return r.Status == QRRequestPending && !now.Before(r.ExpiresAt)
+}
+
+// Clone returns a copy of the request
+func (r *QRRequest) Clone() *QRRequest {
+	if r == nil {
+		return nil
+	}
+	clone := *r
+	return &clone
+}
@@ -0,0 +1,87 @@
+package entity
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimit is a token-bucket policy: each caller's bucket holds up to
+// Limit tokens and refills evenly at Limit tokens per Period. A request
+// takes one token, so a caller may burst Limit requests and then make one
+// every Period/Limit.
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+}
+
+// Enabled reports whether the policy limits anything. The zero RateLimit
+// turns limiting off.
+func (l RateLimit) Enabled() bool {
+	return l.Limit > 0 && l.Period > 0
+}
+
+// refill is the time it takes a bucket to gain one token
+func (l RateLimit) refill() time.Duration {
+	return l.Period / time.Duration(l.Limit)
+}
+
+// RateLimitDecision is the outcome of taking a token from a bucket
+type RateLimitDecision struct {
+	// Allowed is false when the bucket was empty
+	Allowed bool
+
+	// Limit is the bucket's capacity and Remaining the whole tokens left
+	Limit     int
+	Remaining int
+
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+
+	// RetryAfter is how long until the next token when the request was
+	// refused, zero otherwise
+	RetryAfter time.Duration
+}
+
+// TokenBucket is the state of one caller's bucket under a RateLimit
+type TokenBucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+
+	// FullAt is when the bucket will be full again. From then on it
+	// behaves like a new bucket and may be deleted.
+	FullAt time.Time
+}
+
+// NewTokenBucket creates a full bucket
+func NewTokenBucket(key string, limit RateLimit, now time.Time) *TokenBucket {
+	return &TokenBucket{
+		Key:       key,
+		Tokens:    float64(limit.Limit),
+		UpdatedAt: now,
+		FullAt:    now,
+	}
+}
+
+// Take refills the bucket for the time since it was last used and takes
+// a token from it if one is left
+func (b *TokenBucket) Take(limit RateLimit, now time.Time) RateLimitDecision {
+	refill := limit.refill()
+	if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Limit), b.Tokens+float64(elapsed)/float64(refill))
+		b.UpdatedAt = now
+	}
+
+	decision := RateLimitDecision{Limit: limit.Limit}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = time.Duration((1 - b.Tokens) * float64(refill))
+	}
+
+	decision.Remaining = int(b.Tokens)
+	decision.Reset = time.Duration((float64(limit.Limit) - b.Tokens) * float64(refill))
+	b.FullAt = b.UpdatedAt.Add(decision.Reset)
+	return decision
+}
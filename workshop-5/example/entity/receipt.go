@@ -0,0 +1,73 @@
+package entity
+
+import "time"
+
+// ReceiptStatus is the delivery state of an SMS receipt
+type ReceiptStatus string
+
+// SMS receipt statuses. A receipt is pending until the provider accepts or
+// rejects it; an accepted receipt is sent until the provider reports it
+// delivered or failed.
+const (
+	ReceiptPending   ReceiptStatus = "pending"
+	ReceiptSent      ReceiptStatus = "sent"
+	ReceiptDelivered ReceiptStatus = "delivered"
+	ReceiptFailed    ReceiptStatus = "failed"
+)
+
+// Receipt is the SMS sent to a customer after checkout
+type Receipt struct {
+	ID          string        `json:"id" example:"2f1e0d9c-8b7a-4c6d-9e5f-4a3b2c1d0e9f"`
+	OrderID     string        `json:"order_id" example:"7c9e6679-7425-40de-944b-e07fc1f90ae7"`
+	UserID      string        `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Phone       string        `json:"phone" example:"+66812345678"`
+	Language    string        `json:"language" example:"th"`
+	Body        string        `json:"body" example:"ขอบคุณที่ใช้บริการ คำสั่งซื้อ #7C9E6679 ชำระ 320 พอยต์ คงเหลือ 14920 พอยต์"`
+	Encoding    string        `json:"encoding" example:"UCS-2"`
+	Segments    int           `json:"segments" example:"2"`
+	ProviderRef string        `json:"provider_ref,omitempty" example:"msg-8f14e45f"`
+	Status      ReceiptStatus `json:"status" example:"sent"`
+	Error       string        `json:"error,omitempty" example:""`
+	CreatedAt   time.Time     `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt   time.Time     `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// NewReceipt creates a pending receipt for an order
+func NewReceipt(id, orderID, userID, phone, language, body string) *Receipt {
+	now := time.Now()
+	return &Receipt{
+		ID:        id,
+		OrderID:   orderID,
+		UserID:    userID,
+		Phone:     phone,
+		Language:  language,
+		Body:      body,
+		Status:    ReceiptPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// MarkSent records that the provider accepted the receipt as providerRef
+func (r *Receipt) MarkSent(providerRef string) {
+	r.ProviderRef = providerRef
+	r.Status = ReceiptSent
+	r.Error = ""
+	r.UpdatedAt = time.Now()
+}
+
+// MarkFailed records why the receipt could not be sent or delivered
+func (r *Receipt) MarkFailed(reason string) {
+	r.Status = ReceiptFailed
+	r.Error = reason
+	r.UpdatedAt = time.Now()
+}
+
+// Clone returns a copy of the receipt
+func (r *Receipt) Clone() *Receipt {
+	if r == nil {
+		return nil
+	}
+	clone := *r
+	return &clone
+}
@@ -0,0 +1,59 @@
+package entity
+
+import "time"
+
+// RefreshToken is a long-lived credential exchanged for new access tokens.
+// Each token is used once: refreshing rotates it to a successor in the same
+// family. Presenting a rotated token again means it was stolen, so the
+// whole family is revoked. Only a hash of the token secret is kept.
+type RefreshToken struct {
+	ID         string     `json:"id"`
+	FamilyID   string     `json:"family_id"`
+	UserID     string     `json:"user_id"`
+	SecretHash string     `json:"-"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// NewRefreshToken creates a token in family for the secret hashed as
+// secretHash that expires after ttl. The first token of a family uses its
+// own ID as the family ID.
+func NewRefreshToken(id, familyID, userID, secretHash string, ttl time.Duration) *RefreshToken {
+	now := time.Now()
+	if familyID == "" {
+		familyID = id
+	}
+	return &RefreshToken{
+		ID:         id,
+		FamilyID:   familyID,
+		UserID:     userID,
+		SecretHash: secretHash,
+		ExpiresAt:  now.Add(ttl),
+		CreatedAt:  now,
+	}
+}
+
+// Active reports whether the token can still be used at now: it has not
+// expired, been rotated or been revoked
+func (t *RefreshToken) Active(now time.Time) bool {
+	return now.Before(t.ExpiresAt) && t.RotatedAt == nil && t.RevokedAt == nil
+}
+
+// Clone returns a copy of the token that shares no state with the original
+func (t *RefreshToken) Clone() *RefreshToken {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	if t.RotatedAt != nil {
+		rotatedAt := *t.RotatedAt
+		clone.RotatedAt = &rotatedAt
+	}
+	if t.RevokedAt != nil {
+		revokedAt := *t.RevokedAt
+		clone.RevokedAt = &revokedAt
+	}
+	return &clone
+}
@@ -0,0 +1,67 @@
+package entity
+
+import "time"
+
+// TransferStatus is the lifecycle state of a transfer
+type TransferStatus string
+
+// Transfer statuses. A transfer starts pending and ends either posted, when
+// its ledger posting was written, or failed, when nothing was written.
+const (
+	TransferPending TransferStatus = "pending"
+	TransferPosted  TransferStatus = "posted"
+	TransferFailed  TransferStatus = "failed"
+)
+
+// Transfer moves points from one member to another. A transfer that pays a
+// QR payment request names it in QRRequestID, and the request is marked
+// paid when the transfer is posted.
+type Transfer struct {
+	ID            string         `json:"id" example:"2f1c6b9e-8d4a-4f7b-9c3e-5a6d7e8f9a0b"`
+	FromUserID    string         `json:"from_user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ToUserID      string         `json:"to_user_id" example:"6ba7b810-9dad-11d1-80b4-00c04fd430c8"`
+	Amount        int            `json:"amount" example:"500"`
+	Memo          string         `json:"memo,omitempty" example:"Lunch"`
+	QRRequestID   string         `json:"qr_request_id,omitempty" example:""`
+	Status        TransferStatus `json:"status" example:"posted"`
+	FailureReason string         `json:"failure_reason,omitempty" example:""`
+	CreatedAt     time.Time      `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt     time.Time      `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// NewTransfer creates a pending transfer
+func NewTransfer(id, fromUserID, toUserID string, amount int, memo string) *Transfer {
+	now := time.Now()
+	return &Transfer{
+		ID:         id,
+		FromUserID: fromUserID,
+		ToUserID:   toUserID,
+		Amount:     amount,
+		Memo:       memo,
+		Status:     TransferPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// Posting returns the ledger posting that settles the transfer. It reuses
+// the transfer ID, so a transfer can never be posted twice.
+func (t *Transfer) Posting() *Posting {
+	return NewTransferPosting(t.ID, t.FromUserID, t.ToUserID, t.Amount, t.Memo, t.ID)
+}
+
+// Reversal returns the ledger posting that undoes the transfer's posting.
+// Its ID is derived from the transfer ID, so a transfer can never be
+// reversed twice.
+func (t *Transfer) Reversal() *Posting {
+	return t.Posting().Reversal("reversal-"+t.ID, "Reversal of transfer "+t.ID)
+}
+
+// Clone returns a copy of the transfer
+func (t *Transfer) Clone() *Transfer {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	return &clone
+}
@@ -1,6 +1,67 @@
 package entity
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// MemberIDPrefix is the prefix shared by every member ID, e.g. LBK000001
+const MemberIDPrefix = "LBK"
+
+// Membership levels, from highest to lowest tier
+const (
+	MembershipGold   = "Gold"
+	MembershipSilver = "Silver"
+	MembershipBronze = "Bronze"
+)
+
+// ValidMembershipLevel reports whether level is a known membership tier
+func ValidMembershipLevel(level string) bool {
+	switch level {
+	case MembershipGold, MembershipSilver, MembershipBronze:
+		return true
+	}
+	return false
+}
+
+// Role decides what a user may do beyond their own account
+type Role string
+
+// Roles, from least to most privileged
+const (
+	// RoleMember is a customer who may act only on their own account
+	RoleMember Role = "member"
+
+	// RoleStaff works the counter: they serve customers and fulfil orders
+	RoleStaff Role = "staff"
+
+	// RoleAdmin runs the programme: users, roles, refunds and operations
+	RoleAdmin Role = "admin"
+)
+
+// roleRanks orders roles by privilege
+var roleRanks = map[Role]int{
+	RoleMember: 1,
+	RoleStaff:  2,
+	RoleAdmin:  3,
+}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role Role) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// AtLeast reports whether r is as privileged as other. Unknown roles are
+// below every known one.
+func (r Role) AtLeast(other Role) bool {
+	return roleRanks[r] > 0 && roleRanks[r] >= roleRanks[other]
+}
+
+// FormatMemberID formats a member sequence number as a member ID
+func FormatMemberID(seq int64) string {
+	return fmt.Sprintf("%s%06d", MemberIDPrefix, seq)
+}
 
 // User represents a registered user in the domain
 type User struct {
@@ -11,7 +72,8 @@ type User struct {
 	Phone           string    `json:"phone" example:"+66812345678"`
 	Email           string    `json:"email" example:"john.doe@example.com"`
 	MembershipLevel string    `json:"membership_level" example:"Gold"`
-	Points          int       `json:"points" example:"0"`
+	Role            Role      `json:"role" example:"member"`
+	Points          int       `json:"points" example:"0"` // projection of the points ledger
 	RegisteredAt    time.Time `json:"registered_at" example:"2024-01-01T00:00:00Z"`
 }
 
@@ -24,12 +86,21 @@ func NewUser(id, memberID, firstName, lastName, phone, email string) *User {
 		LastName:        lastName,
 		Phone:           phone,
 		Email:           email,
-		MembershipLevel: "Gold", // Default membership level
-		Points:          0,      // Start with 0 points
+		MembershipLevel: MembershipGold, // Default membership level
+		Role:            RoleMember,
 		RegisteredAt:    time.Now(),
 	}
 }
 
+// Clone returns a copy of the user that shares no state with the original
+func (u *User) Clone() *User {
+	if u == nil {
+		return nil
+	}
+	clone := *u
+	return &clone
+}
+
 // GetFullName returns the full name of the user
 func (u *User) GetFullName() string {
 	return u.FirstName + " " + u.LastName
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"time"
+
+	"example.com/mike/auth"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiKeyLocal is the fiber.Ctx Locals key of the authenticated API key
+const apiKeyLocal = "auth.api_key"
+
+// RequireAPIKey authenticates partner requests signed with an API key, see
+// auth.SignRequest, and puts the key on the request for CurrentAPIKey. It
+// is separate from Authenticate: partner routes accept no access tokens
+// and user routes accept no API keys. Each key may make its RateLimit
+// requests per minute, counted in a token bucket in limits, with the same
+// headers and 429 responses as RateLimit.
+//
+// The middleware must run before Idempotency, so retries are scoped to
+// the key. A retry needs a new nonce and signature, since a resent nonce
+// is refused as a replay.
+func RequireAPIKey(apiKeyUsecase usecase.APIKeyUsecase, limits repository.RateLimitRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := auth.SignedRequest{
+			Method:    c.Method(),
+			Path:      c.OriginalURL(),
+			Timestamp: c.Get(auth.RequestTimestampHeader),
+			Nonce:     c.Get(auth.RequestNonceHeader),
+			Body:      c.Body(),
+		}
+		key, err := apiKeyUsecase.Authenticate(c.UserContext(), c.Get(auth.APIKeyHeader), req, c.Get(auth.RequestSignatureHeader))
+		if err != nil {
+			return err
+		}
+
+		limit := entity.RateLimit{Limit: key.RateLimit, Period: time.Minute}
+		if err := takeToken(c, limits, "partner:api-key:"+key.ID, limit); err != nil {
+			return err
+		}
+
+		c.Locals(apiKeyLocal, key)
+		return c.Next()
+	}
+}
+
+// CurrentAPIKey returns the API key that signed the request, or nil for
+// requests outside the partner routes
+func CurrentAPIKey(c *fiber.Ctx) *entity.APIKey {
+	key, _ := c.Locals(apiKeyLocal).(*entity.APIKey)
+	return key
+}
+
+// RequireScope returns a route guard that lets a partner request through
+// only when its API key was granted scope
+func RequireScope(scope entity.APIScope) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := CurrentAPIKey(c)
+		if key == nil {
+			return usecase.ErrInvalidAPIKeySignature
+		}
+		if !key.HasScope(scope) {
+			return usecase.ErrMissingScope
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"example.com/mike/apperror"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyHandler handles the admin endpoints for managing partner API keys
+type APIKeyHandler struct {
+	apiKeyUsecase usecase.APIKeyUsecase
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyUsecase usecase.APIKeyUsecase) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyUsecase: apiKeyUsecase,
+	}
+}
+
+// RegisterRoutes sets up the admin API key routes
+func (h *APIKeyHandler) RegisterRoutes(app *fiber.App) {
+	manageKeys := Authorize(usecase.OpManageAPIKeys, "")
+	app.Post("/admin/api-keys", manageKeys, h.CreateKey)
+	app.Get("/admin/api-keys", manageKeys, h.ListKeys)
+	app.Post("/admin/api-keys/:id/revoke", manageKeys, h.RevokeKey)
+	app.Post("/admin/api-keys/:id/rotate", manageKeys, h.RotateKey)
+}
+
+// CreateKey handles creating a partner API key
+// @Summary      Create a partner API key
+// @Description  Create an API key for a partner such as a merchant's POS. The response carries the signing secret, which is not stored and cannot be shown again. Rate limit is in requests per minute; 0 uses the default of 60.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        key  body      usecase.CreateAPIKeyRequest  true  "API key"
+// @Success      201  {object}  usecase.APIKeySecretResponse  "API key created"
+// @Failure      400  {object}  handler.Problem  "Invalid request format or validation failed"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Only admins may manage API keys"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /admin/api-keys [post]
+func (h *APIKeyHandler) CreateKey(c *fiber.Ctx) error {
+	var req usecase.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+
+	response, err := h.apiKeyUsecase.CreateKey(c.UserContext(), CurrentUser(c).ID, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// ListKeys handles listing partner API keys
+// @Summary      List partner API keys
+// @Description  Retrieve every API key oldest first, revoked ones included. Secrets are never listed.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  usecase.ListAPIKeysResponse  "API keys"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Only admins may manage API keys"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /admin/api-keys [get]
+func (h *APIKeyHandler) ListKeys(c *fiber.Ctx) error {
+	response, err := h.apiKeyUsecase.ListKeys(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// RevokeKey handles revoking a partner API key
+// @Summary      Revoke a partner API key
+// @Description  Revoke an API key; requests signed with it are refused from then on. Revoking a revoked key changes nothing.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "API key ID"
+// @Success      200  {object}  usecase.APIKeyResponse  "API key revoked"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Only admins may manage API keys"
+// @Failure      404  {object}  handler.Problem  "API key not found"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /admin/api-keys/{id}/revoke [post]
+func (h *APIKeyHandler) RevokeKey(c *fiber.Ctx) error {
+	response, err := h.apiKeyUsecase.RevokeKey(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// RotateKey handles rotating the secret of a partner API key
+// @Summary      Rotate a partner API key
+// @Description  Give an API key a new signing secret, returned once. The previous secret keeps working for 24 hours so the partner can deploy the new one.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "API key ID"
+// @Success      200  {object}  usecase.APIKeySecretResponse  "API key rotated"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Only admins may manage API keys"
+// @Failure      404  {object}  handler.Problem  "API key not found"
+// @Failure      409  {object}  handler.Problem  "API key is revoked"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /admin/api-keys/{id}/rotate [post]
+func (h *APIKeyHandler) RotateKey(c *fiber.Ctx) error {
+	response, err := h.apiKeyUsecase.RotateKey(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
@@ -0,0 +1,78 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// createAPIKey creates a partner API key through the admin endpoint
+func createAPIKey(t *testing.T, app *fiber.App, admin []string, req usecase.CreateAPIKeyRequest) *usecase.APIKeySecretResponse {
+	t.Helper()
+	resp := do(t, app, "POST", "/admin/api-keys", req, admin...)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("create API key: expected 201, got %d", resp.StatusCode)
+	}
+	var created usecase.APIKeySecretResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	return &created
+}
+
+func TestAPIKeyManagement(t *testing.T) {
+	app, users := setupAppWithUsers()
+	adminID, admin := createUser(t, users, entity.RoleAdmin, "+66822222222", "admin@example.com")
+	_, staff := createUser(t, users, entity.RoleStaff, "+66811111111", "staff@example.com")
+
+	created := createAPIKey(t, app, admin, usecase.CreateAPIKeyRequest{Name: "Lumpini POS", Scopes: []entity.APIScope{entity.ScopePointsAward}})
+	if created.Secret == "" || created.Key.CreatedBy != adminID || created.Key.RateLimit != 60 {
+		t.Fatalf("unexpected key: %+v", created)
+	}
+	id := created.Key.ID
+
+	// Only admins manage keys
+	expectProblem(t, do(t, app, "GET", "/admin/api-keys", nil, staff...), fiber.StatusForbidden)
+	expectProblem(t, do(t, app, "POST", "/admin/api-keys", nil), fiber.StatusUnauthorized)
+
+	problem := expectProblem(t, do(t, app, "POST", "/admin/api-keys", usecase.CreateAPIKeyRequest{Name: "POS"}, admin...), fiber.StatusBadRequest)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "scopes" {
+		t.Fatalf("expected a scopes field error, got %+v", problem)
+	}
+	expectProblem(t, do(t, app, "POST", "/admin/api-keys", "{", admin...), fiber.StatusBadRequest)
+
+	var rotated usecase.APIKeySecretResponse
+	resp := do(t, app, "POST", "/admin/api-keys/"+id+"/rotate", nil, admin...)
+	if err := json.NewDecoder(resp.Body).Decode(&rotated); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || rotated.Secret == created.Secret || rotated.Key.PreviousExpiresAt == nil {
+		t.Fatalf("unexpected rotation: %d %+v", resp.StatusCode, rotated)
+	}
+
+	if resp := do(t, app, "POST", "/admin/api-keys/"+id+"/revoke", nil, admin...); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("revoke: expected 200, got %d", resp.StatusCode)
+	}
+	expectProblem(t, do(t, app, "POST", "/admin/api-keys/"+id+"/rotate", nil, admin...), fiber.StatusConflict)
+	expectProblem(t, do(t, app, "POST", "/admin/api-keys/pk_missing/revoke", nil, admin...), fiber.StatusNotFound)
+
+	// Listings never show secrets
+	resp = do(t, app, "GET", "/admin/api-keys", nil, admin...)
+	body := readBody(t, resp)
+	var list usecase.ListAPIKeysResponse
+	if err := json.Unmarshal([]byte(body), &list); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if list.Count != 1 || list.Keys[0].RevokedAt == nil {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	for _, secret := range []string{created.Secret, rotated.Secret, "salt"} {
+		if strings.Contains(body, secret) {
+			t.Fatalf("listing leaks %q: %s", secret, body)
+		}
+	}
+}
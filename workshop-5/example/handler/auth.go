@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"strings"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// userLocal is the fiber.Ctx Locals key of the authenticated user
+const userLocal = "auth.user"
+
+// Errors returned by the authentication middleware
+var (
+	// ErrInvalidAuthorization is returned for Authorization headers that
+	// are not "Bearer <token>"
+	ErrInvalidAuthorization = apperror.Unauthorized("Authorization header must be \"Bearer <access token>\"")
+
+	// ErrAuthenticationRequired is returned when a protected route is
+	// called without an access token
+	ErrAuthenticationRequired = apperror.Unauthorized("Authentication required")
+)
+
+// Authenticate verifies the access token in the Authorization header and
+// puts its user on the request for CurrentUser. Requests without the
+// header pass through anonymously, so public routes keep working; routes
+// that need a user add RequireUser. A header with an invalid or expired
+// token is always rejected with 401.
+func Authenticate(authUsecase usecase.AuthUsecase) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := c.Get(fiber.HeaderAuthorization)
+		if header == "" {
+			return c.Next()
+		}
+
+		scheme, token, ok := strings.Cut(header, " ")
+		token = strings.TrimSpace(token)
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_request"`)
+			return ErrInvalidAuthorization
+		}
+
+		user, err := authUsecase.Authenticate(c.UserContext(), token)
+		if err != nil {
+			if apperror.KindOf(err) == apperror.KindUnauthorized {
+				c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			}
+			return err
+		}
+
+		c.Locals(userLocal, user)
+		return c.Next()
+	}
+}
+
+// RequireUser rejects requests that Authenticate did not attach a user to
+func RequireUser(c *fiber.Ctx) error {
+	if CurrentUser(c) == nil {
+		c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+		return ErrAuthenticationRequired
+	}
+	return c.Next()
+}
+
+// CurrentUser returns the user authenticated by the request's access
+// token, or nil for anonymous requests
+func CurrentUser(c *fiber.Ctx) *entity.User {
+	user, _ := c.Locals(userLocal).(*entity.User)
+	return user
+}
+
+// Authorize returns a route guard that lets a request through only when
+// usecase.DefaultAccessPolicy allows its user to perform op. ownerParam
+// names the path parameter holding the ID of the user owning the resource,
+// e.g. "id" for /user/:id, or is empty for operations without an owner.
+// Anonymous requests are rejected with 401 and denied ones with 403.
+func Authorize(op usecase.Operation, ownerParam string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var ownerIDs []string
+		if ownerParam != "" {
+			ownerIDs = append(ownerIDs, c.Params(ownerParam))
+		}
+		if err := authorize(c, op, ownerIDs...); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
+
+// authorize checks op for handlers that only learn the owners of a
+// resource from the request body or by loading the resource
+func authorize(c *fiber.Ctx, op usecase.Operation, ownerIDs ...string) error {
+	user := CurrentUser(c)
+	if user == nil {
+		c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+		return ErrAuthenticationRequired
+	}
+	return usecase.DefaultAccessPolicy.Authorize(user, op, ownerIDs...)
+}
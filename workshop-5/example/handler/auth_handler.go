@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"example.com/mike/apperror"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuthHandler handles the login endpoints
+type AuthHandler struct {
+	authUsecase usecase.AuthUsecase
+}
+
+// NewAuthHandler creates a new login handler
+func NewAuthHandler(authUsecase usecase.AuthUsecase) *AuthHandler {
+	return &AuthHandler{
+		authUsecase: authUsecase,
+	}
+}
+
+// RegisterRoutes sets up the login routes
+func (h *AuthHandler) RegisterRoutes(app *fiber.App) {
+	app.Post("/auth/otp", h.RequestCode)
+	app.Post("/auth/otp/verify", h.VerifyCode)
+	app.Post("/auth/refresh", h.Refresh)
+	app.Post("/auth/logout", h.Logout)
+}
+
+// RequestCode handles sending a login code
+// @Summary      Request a login code
+// @Description  Send a six-digit one-time login code to the phone number or email of a registered user. Exactly one of phone or email must be set. The response is the same whether or not the account exists; the code is only sent if it does.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      usecase.RequestCodeRequest  true  "Phone number or email"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      202      {object}  usecase.RequestCodeResponse  "Login code sent if the account exists"
+// @Failure      400      {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      422      {object}  handler.Problem  "Codes cannot be sent to this kind of destination, or Idempotency-Key reused for a different request"
+// @Failure      429      {object}  handler.Problem  "Too many login codes from this client or to this destination; see Retry-After"
+// @Failure      500      {object}  handler.Problem  "Internal server error"
+// @Router       /auth/otp [post]
+func (h *AuthHandler) RequestCode(c *fiber.Ctx) error {
+	var req usecase.RequestCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+
+	response, err := h.authUsecase.RequestCode(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(response)
+}
+
+// VerifyCode handles logging in with a login code
+// @Summary      Log in with a login code
+// @Description  Exchange a login code for a short-lived access token and a refresh token. Each challenge allows five tries and expires after five minutes.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      usecase.VerifyCodeRequest  true  "Challenge ID and code"
+// @Success      200      {object}  usecase.TokenResponse  "Logged in"
+// @Failure      400      {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401      {object}  handler.Problem  "Invalid or expired login code"
+// @Failure      429      {object}  handler.Problem  "Too many login attempts from this client; see Retry-After"
+// @Failure      500      {object}  handler.Problem  "Internal server error"
+// @Router       /auth/otp/verify [post]
+func (h *AuthHandler) VerifyCode(c *fiber.Ctx) error {
+	var req usecase.VerifyCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+
+	response, err := h.authUsecase.VerifyCode(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// Refresh handles rotating a refresh token
+// @Summary      Refresh tokens
+// @Description  Exchange a refresh token for a new access token and a new refresh token. Each refresh token works once; presenting it again logs out every session started from the same login.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      usecase.RefreshRequest  true  "Refresh token"
+// @Success      200      {object}  usecase.TokenResponse  "Tokens refreshed"
+// @Failure      400      {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401      {object}  handler.Problem  "Invalid, expired, revoked or reused refresh token"
+// @Failure      500      {object}  handler.Problem  "Internal server error"
+// @Router       /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req usecase.RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+
+	response, err := h.authUsecase.Refresh(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// Logout handles revoking a refresh token
+// @Summary      Log out
+// @Description  Revoke a refresh token and every refresh token rotated from the same login. Access tokens already issued stay valid until they expire.
+// @Tags         auth
+// @Accept       json
+// @Param        request  body  usecase.RefreshRequest  true  "Refresh token"
+// @Success      204  "Logged out"
+// @Failure      400  {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401  {object}  handler.Problem  "Invalid refresh token"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /auth/logout [post]
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req usecase.RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+
+	if err := h.authUsecase.Logout(c.UserContext(), req); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
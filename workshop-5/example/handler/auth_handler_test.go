@@ -0,0 +1,72 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"example.com/mike/auth"
+	"example.com/mike/handler"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLoginEndpoints(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler, Immutable: true})
+	userRepo, ledgerRepo := repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository()
+	codes := auth.NewLogCodeSender(io.Discard)
+	authUsecase := usecase.NewAuthUsecase(userRepo, repository.NewMemoryOTPRepository(), repository.NewMemoryRefreshTokenRepository(),
+		codes, testSigner, usecase.DefaultAuthPolicy)
+	app.Use(handler.Authenticate(authUsecase))
+	app.Use(handler.Idempotency(handler.IdempotencyConfig{Store: repository.NewMemoryIdempotencyRepository()}))
+	handler.NewAuthHandler(authUsecase).RegisterRoutes(app)
+	handler.NewHTTPHandler(usecase.NewUserUsecase(userRepo, ledgerRepo)).RegisterRoutes(app)
+
+	do(t, app, "POST", "/register", usecase.RegisterRequest{FirstName: "John", LastName: "Doe", Phone: "+66812345678", Email: "john.doe@example.com"})
+
+	var challenge usecase.RequestCodeResponse
+	resp := do(t, app, "POST", "/auth/otp", usecase.RequestCodeRequest{Phone: "081-234-5678"})
+	if err := json.NewDecoder(resp.Body).Decode(&challenge); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusAccepted || challenge.ChallengeID == "" || len(codes.Sent()) != 1 {
+		t.Fatalf("request code: unexpected response %d %+v", resp.StatusCode, challenge)
+	}
+	expectProblem(t, do(t, app, "POST", "/auth/otp", usecase.RequestCodeRequest{}), fiber.StatusBadRequest)
+	if resp := do(t, app, "POST", "/auth/otp", usecase.RequestCodeRequest{Email: "nobody@example.com"}); resp.StatusCode != fiber.StatusAccepted {
+		t.Fatalf("unknown account: expected 202, got %d", resp.StatusCode)
+	}
+
+	expectProblem(t, do(t, app, "POST", "/auth/otp/verify", usecase.VerifyCodeRequest{ChallengeID: challenge.ChallengeID, Code: "wrong"}), fiber.StatusUnauthorized)
+
+	var tokens usecase.TokenResponse
+	resp = do(t, app, "POST", "/auth/otp/verify", usecase.VerifyCodeRequest{ChallengeID: challenge.ChallengeID, Code: codes.Sent()[0].Code})
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("verify: unexpected response %d %+v", resp.StatusCode, tokens)
+	}
+
+	// The access token opens the protected routes
+	if resp := do(t, app, "GET", "/user/"+tokens.UserID, nil, fiber.HeaderAuthorization, "Bearer "+tokens.AccessToken); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("GET user with token: expected 200, got %d", resp.StatusCode)
+	}
+
+	var refreshed usecase.TokenResponse
+	resp = do(t, app, "POST", "/auth/refresh", usecase.RefreshRequest{RefreshToken: tokens.RefreshToken})
+	if err := json.NewDecoder(resp.Body).Decode(&refreshed); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || refreshed.RefreshToken == tokens.RefreshToken {
+		t.Fatalf("refresh: unexpected response %d %+v", resp.StatusCode, refreshed)
+	}
+	expectProblem(t, do(t, app, "POST", "/auth/refresh", usecase.RefreshRequest{RefreshToken: tokens.RefreshToken}), fiber.StatusUnauthorized)
+	expectProblem(t, do(t, app, "POST", "/auth/refresh", "{not json"), fiber.StatusBadRequest)
+
+	if resp := do(t, app, "POST", "/auth/logout", usecase.RefreshRequest{RefreshToken: refreshed.RefreshToken}); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("logout: expected 204, got %d", resp.StatusCode)
+	}
+	expectProblem(t, do(t, app, "POST", "/auth/logout", usecase.RefreshRequest{RefreshToken: "garbage"}), fiber.StatusUnauthorized)
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"example.com/mike/apperror"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CartHandler handles cart and checkout HTTP requests
+type CartHandler struct {
+	cartUsecase usecase.CartUsecase
+}
+
+// NewCartHandler creates a new cart handler
+func NewCartHandler(cartUsecase usecase.CartUsecase) *CartHandler {
+	return &CartHandler{
+		cartUsecase: cartUsecase,
+	}
+}
+
+// RegisterRoutes sets up the cart and checkout routes. Members use their
+// own cart; staff may fill and check out carts for customers.
+func (h *CartHandler) RegisterRoutes(app *fiber.App) {
+	manageCart := Authorize(usecase.OpManageCart, "id")
+	app.Get("/user/:id/cart", manageCart, h.GetCart)
+	app.Post("/user/:id/cart/items", manageCart, h.AddItem)
+	app.Put("/user/:id/cart/items/:productId", manageCart, h.UpdateItem)
+	app.Delete("/user/:id/cart/items/:productId", manageCart, h.RemoveItem)
+	app.Put("/user/:id/cart/customer", manageCart, h.SetCustomer)
+	app.Post("/checkout", RequireUser, h.Checkout)
+}
+
+// GetCart handles getting a user's cart
+// @Summary      Get cart
+// @Description  Retrieve the user's cart priced at current catalog prices, with the subtotal in points. Lines that cannot be bought as they are have available set to false.
+// @Tags         cart
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  usecase.CartResponse  "Cart found"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Cart belongs to another member"
+// @Failure      404  {object}  handler.Problem  "User not found"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /user/{id}/cart [get]
+func (h *CartHandler) GetCart(c *fiber.Ctx) error {
+	response, err := h.cartUsecase.GetCart(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// AddItem handles adding a product to a user's cart
+// @Summary      Add a cart item
+// @Description  Add a product to the user's cart. Adding a product already in the cart increases its quantity, up to 99.
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string                   true   "User ID"
+// @Param        request          body      usecase.CartItemRequest  true   "Product and quantity"
+// @Param        Idempotency-Key  header    string                   false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.CartResponse  "Item added"
+// @Failure      400              {object}  handler.Problem  "Invalid request format, validation error or unknown product"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Cart belongs to another member"
+// @Failure      404              {object}  handler.Problem  "User not found"
+// @Failure      409              {object}  handler.Problem  "Product no longer available or not enough stock"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /user/{id}/cart/items [post]
+func (h *CartHandler) AddItem(c *fiber.Ctx) error {
+	var req usecase.CartItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+
+	response, err := h.cartUsecase.AddItem(c.UserContext(), c.Params("id"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// UpdateItem handles changing the quantity of a cart item
+// @Summary      Update a cart item
+// @Description  Set the quantity of a product already in the user's cart
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string                         true   "User ID"
+// @Param        productId        path      string                         true   "Product ID"
+// @Param        request          body      usecase.UpdateCartItemRequest  true   "New quantity"
+// @Param        Idempotency-Key  header    string                         false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.CartResponse  "Item updated"
+// @Failure      400              {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Cart belongs to another member"
+// @Failure      404              {object}  handler.Problem  "User not found or product not in the cart"
+// @Failure      409              {object}  handler.Problem  "Product no longer available or not enough stock"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /user/{id}/cart/items/{productId} [put]
+func (h *CartHandler) UpdateItem(c *fiber.Ctx) error {
+	var req usecase.UpdateCartItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+
+	response, err := h.cartUsecase.UpdateItem(c.UserContext(), c.Params("id"), c.Params("productId"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// RemoveItem handles removing a product from a user's cart
+// @Summary      Remove a cart item
+// @Description  Remove a product from the user's cart
+// @Tags         cart
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string  true   "User ID"
+// @Param        productId        path      string  true   "Product ID"
+// @Param        Idempotency-Key  header    string  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.CartResponse  "Item removed"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Cart belongs to another member"
+// @Failure      404              {object}  handler.Problem  "User not found or product not in the cart"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /user/{id}/cart/items/{productId} [delete]
+func (h *CartHandler) RemoveItem(c *fiber.Ctx) error {
+	response, err := h.cartUsecase.RemoveItem(c.UserContext(), c.Params("id"), c.Params("productId"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// SetCustomer handles attaching customer details to a user's cart
+// @Summary      Set cart customer
+// @Description  Attach the customer's name and phone number to the user's cart. They are copied onto the order at checkout; the phone number is stored in E.164.
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string                       true   "User ID"
+// @Param        request          body      usecase.CartCustomerRequest  true   "Customer details"
+// @Param        Idempotency-Key  header    string                       false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.CartResponse  "Customer updated"
+// @Failure      400              {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Cart belongs to another member"
+// @Failure      404              {object}  handler.Problem  "User not found"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /user/{id}/cart/customer [put]
+func (h *CartHandler) SetCustomer(c *fiber.Ctx) error {
+	var req usecase.CartCustomerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+
+	response, err := h.cartUsecase.SetCustomer(c.UserContext(), c.Params("id"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// Checkout handles buying everything in a user's cart
+// @Summary      Check out
+// @Description  Buy everything in the user's cart with points at current catalog prices. Stock is reserved, the points are debited, the order and its payment are recorded and the cart is emptied in one transaction; on any failure nothing changes.
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request          body      usecase.CheckoutRequest  true   "Whose cart to check out"
+// @Param        Idempotency-Key  header    string                   false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      201              {object}  usecase.OrderResponse  "Order placed"
+// @Failure      400              {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Cart belongs to another member"
+// @Failure      404              {object}  handler.Problem  "User not found"
+// @Failure      409              {object}  handler.Problem  "Cart is empty, insufficient balance, not enough stock or product no longer available"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /checkout [post]
+func (h *CartHandler) Checkout(c *fiber.Ctx) error {
+	var req usecase.CheckoutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+	if err := authorize(c, usecase.OpCheckout, req.UserID); err != nil {
+		return err
+	}
+	req.Actor = CurrentUser(c).ID
+
+	response, err := h.cartUsecase.Checkout(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
@@ -0,0 +1,98 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCartAndCheckoutEndpoints(t *testing.T) {
+	app, users := setupAppWithUsers()
+
+	member, asMember := registerAndAuthorize(t, app, "+66810000031", "cart@example.com")
+	_, asOther := registerAndAuthorize(t, app, "+66810000032", "other@example.com")
+	staffID, staff := createUser(t, users, entity.RoleStaff, "+66811111111", "staff@example.com")
+	_, admin := createUser(t, users, entity.RoleAdmin, "+66822222222", "admin@example.com")
+	do(t, app, "POST", "/user/"+member+"/ledger", usecase.PostEntryRequest{Type: "earn", Amount: 500}, staff...)
+
+	var product usecase.ProductResponse
+	resp := do(t, app, "POST", "/admin/products", usecase.ProductRequest{Name: "Iced Latte", Category: "drinks", PricePoints: 120, Stock: 3}, admin...)
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	latte := product.Product.ID
+	cart := "/user/" + member + "/cart"
+
+	expectProblem(t, do(t, app, "POST", "/checkout", usecase.CheckoutRequest{UserID: member}, asMember...), fiber.StatusConflict)
+	expectProblem(t, do(t, app, "POST", cart+"/items", usecase.CartItemRequest{ProductID: "missing", Quantity: 1}, asMember...), fiber.StatusBadRequest)
+	expectProblem(t, do(t, app, "POST", cart+"/items", usecase.CartItemRequest{ProductID: latte, Quantity: 4}, asMember...), fiber.StatusConflict)
+	expectProblem(t, do(t, app, "PUT", cart+"/items/missing", usecase.UpdateCartItemRequest{Quantity: 1}, asMember...), fiber.StatusNotFound)
+	expectProblem(t, do(t, app, "GET", "/user/missing/cart", nil, staff...), fiber.StatusNotFound)
+
+	// Other members can neither fill the cart nor check it out
+	expectProblem(t, do(t, app, "GET", cart, nil), fiber.StatusUnauthorized)
+	expectProblem(t, do(t, app, "POST", cart+"/items", usecase.CartItemRequest{ProductID: latte, Quantity: 1}, asOther...), fiber.StatusForbidden)
+	expectProblem(t, do(t, app, "POST", "/checkout", usecase.CheckoutRequest{UserID: member}, asOther...), fiber.StatusForbidden)
+
+	do(t, app, "POST", cart+"/items", usecase.CartItemRequest{ProductID: latte, Quantity: 1}, asMember...)
+	resp = do(t, app, "PUT", cart+"/items/"+latte, usecase.UpdateCartItemRequest{Quantity: 3}, asMember...)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("update item: expected 200, got %d", resp.StatusCode)
+	}
+	resp = do(t, app, "PUT", cart+"/customer", usecase.CartCustomerRequest{Name: "Somchai Jaidee", Phone: "081-234-5678"}, staff...)
+	var got usecase.CartResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if got.Subtotal != 360 || len(got.Items) != 1 || got.CustomerPhone != "+66812345678" {
+		t.Fatalf("unexpected cart: %+v", got)
+	}
+
+	// Staff check out for the customer; 360 of the 500 points are spent
+	resp = do(t, app, "POST", "/checkout", usecase.CheckoutRequest{UserID: member}, staff...)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("checkout: expected 201, got %d", resp.StatusCode)
+	}
+	var placed usecase.OrderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&placed); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if placed.Order.Status != entity.OrderPaid || placed.Order.Total != 360 || placed.Payment.Amount != 360 || placed.Balance != 140 {
+		t.Fatalf("unexpected checkout response: %+v", placed)
+	}
+	for _, event := range placed.Order.Events {
+		if event.Actor != staffID {
+			t.Fatalf("expected staff %s as actor, got %+v", staffID, event)
+		}
+	}
+
+	expectProblem(t, do(t, app, "GET", "/orders/"+placed.Order.ID, nil, asOther...), fiber.StatusForbidden)
+	resp = do(t, app, "GET", "/orders/"+placed.Order.ID, nil, asMember...)
+	var order usecase.OrderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if order.Order.ID != placed.Order.ID || order.Payment.ID != placed.Payment.ID {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+	expectProblem(t, do(t, app, "GET", "/orders/missing", nil, asMember...), fiber.StatusNotFound)
+
+	// The failure modes are told apart by their detail
+	do(t, app, "PATCH", "/admin/products/"+latte, `{"stock": 1, "price_points": 200}`, admin...)
+	do(t, app, "POST", cart+"/items", usecase.CartItemRequest{ProductID: latte, Quantity: 1}, asMember...)
+	if problem := expectProblem(t, do(t, app, "POST", "/checkout", usecase.CheckoutRequest{UserID: member}, asMember...), fiber.StatusConflict); problem.Detail != usecase.ErrInsufficientBalance.Message {
+		t.Fatalf("expected an insufficient balance problem, got %+v", problem)
+	}
+	do(t, app, "PATCH", "/admin/products/"+latte, `{"active": false}`, admin...)
+	if problem := expectProblem(t, do(t, app, "POST", "/checkout", usecase.CheckoutRequest{UserID: member}, asMember...), fiber.StatusConflict); problem.Detail != usecase.ErrProductInactive.Message {
+		t.Fatalf("expected a product unavailable problem, got %+v", problem)
+	}
+
+	resp = do(t, app, "DELETE", cart+"/items/"+latte, nil, asMember...)
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil || len(got.Items) != 0 {
+		t.Fatalf("remove item: %+v, %v", got, err)
+	}
+}
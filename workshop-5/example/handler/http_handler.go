@@ -1,13 +1,16 @@
 package handler
 
 import (
+	"strings"
+
+	"example.com/mike/apperror"
 	"example.com/mike/usecase"
 	"github.com/gofiber/fiber/v2"
 )
 
 // @title           User Management API
 // @version         1.0
-// @description     A simple user management API with registration and retrieval functionality
+// @description     A simple user management API with registration and retrieval functionality. Errors are returned as RFC 7807 application/problem+json bodies (see handler.Problem).
 // @termsOfService  http://swagger.io/terms/
 
 // @contact.name   API Support
@@ -20,6 +23,19 @@ import (
 // @host      localhost:3000
 // @BasePath  /
 
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 Access token from POST /auth/otp/verify, sent as "Bearer <access token>"
+
+// @securityDefinitions.apikey  PartnerAPIKey
+// @in                          header
+// @name                        X-API-Key
+// @description                 Partner API key ID from POST /admin/api-keys. Every request also carries X-API-Timestamp (Unix seconds), X-API-Nonce (16-128 of A-Z a-z 0-9 - _, never reused) and X-API-Signature: "sha256=" + hex HMAC-SHA256 with the key's secret of "<timestamp>\n<nonce>\n<METHOD>\n<path and query>\n<hex SHA-256 of the body>"
+
+// MIMEMergePatchJSON is the media type of RFC 7396 JSON Merge Patch documents
+const MIMEMergePatchJSON = "application/merge-patch+json"
+
 // HTTPHandler handles HTTP requests
 type HTTPHandler struct {
 	userUsecase usecase.UserUsecase
@@ -32,15 +48,21 @@ func NewHTTPHandler(userUsecase usecase.UserUsecase) *HTTPHandler {
 	}
 }
 
-// RegisterRoutes sets up all the routes
+// RegisterRoutes sets up all the routes. Errors returned by the handlers
+// are rendered by ErrorHandler, which must be set in fiber.Config.
 func (h *HTTPHandler) RegisterRoutes(app *fiber.App) {
 	// Health check endpoint
 	app.Get("/health", h.HealthCheck)
 
-	// User endpoints
+	// User endpoints. Registration is open; members manage their own
+	// account, staff may look customers up and admins manage everyone.
 	app.Post("/register", h.Register)
-	app.Get("/user/:id", h.GetUser)
-	app.Get("/users", h.GetAllUsers)
+	app.Get("/user/:id", Authorize(usecase.OpGetUser, "id"), h.GetUser)
+	app.Put("/user/:id", Authorize(usecase.OpUpdateUser, "id"), h.UpdateUser)
+	app.Patch("/user/:id", Authorize(usecase.OpUpdateUser, "id"), h.PatchUser)
+	app.Delete("/user/:id", Authorize(usecase.OpDeleteUser, "id"), h.DeleteUser)
+	app.Get("/users", Authorize(usecase.OpListUsers, ""), h.ListUsers)
+	app.Put("/admin/users/:id/role", Authorize(usecase.OpSetUserRole, ""), h.SetRole)
 }
 
 // HealthCheck handles health check requests
@@ -65,42 +87,30 @@ func (h *HTTPHandler) HealthCheck(c *fiber.Ctx) error {
 // @Accept       json
 // @Produce      json
 // @Param        request  body      usecase.RegisterRequest  true  "User registration data"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: repeats within 24h replay the first response"
 // @Success      201      {object}  usecase.RegisterResponse  "User registered successfully"
-// @Failure      400      {object}  usecase.RegisterResponse  "Invalid request format or validation error"
-// @Failure      409      {object}  usecase.RegisterResponse  "Email already registered"
-// @Failure      500      {object}  usecase.RegisterResponse  "Internal server error"
+// @Failure      400      {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      409      {object}  handler.Problem  "Email or phone already registered"
+// @Failure      422      {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      429      {object}  handler.Problem  "Too many registrations from this IP; see Retry-After"
+// @Failure      500      {object}  handler.Problem  "Internal server error"
+// @Failure      503      {object}  handler.Problem  "Request timed out; retry later"
 // @Router       /register [post]
 func (h *HTTPHandler) Register(c *fiber.Ctx) error {
 	var req usecase.RegisterRequest
 
 	// Parse request body
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(usecase.RegisterResponse{
-			Success: false,
-			Message: "Invalid request format",
-		})
+		return apperror.Validation("Invalid request format")
 	}
 
 	// Call usecase
-	response, err := h.userUsecase.Register(req)
+	response, err := h.userUsecase.Register(c.UserContext(), req)
 	if err != nil {
-		return c.Status(500).JSON(usecase.RegisterResponse{
-			Success: false,
-			Message: "Internal server error",
-		})
+		return err
 	}
 
-	// Return appropriate status code
-	if response.Success {
-		return c.Status(201).JSON(response)
-	}
-
-	// Check if it's a conflict (email already exists)
-	if response.Message == "Email already registered" {
-		return c.Status(409).JSON(response)
-	}
-
-	return c.Status(400).JSON(response)
+	return c.Status(fiber.StatusCreated).JSON(response)
 }
 
 // GetUser handles getting a user by ID
@@ -109,50 +119,185 @@ func (h *HTTPHandler) Register(c *fiber.Ctx) error {
 // @Tags         users
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        id   path      string  true  "User ID"
 // @Success      200  {object}  usecase.RegisterResponse  "User found"
-// @Failure      404  {object}  usecase.RegisterResponse  "User not found"
-// @Failure      500  {object}  usecase.RegisterResponse  "Internal server error"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Members may only read their own account"
+// @Failure      404  {object}  handler.Problem  "User not found"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Failure      503  {object}  handler.Problem  "Request timed out; retry later"
 // @Router       /user/{id} [get]
 func (h *HTTPHandler) GetUser(c *fiber.Ctx) error {
 	userID := c.Params("id")
 
-	response, err := h.userUsecase.GetUser(userID)
+	response, err := h.userUsecase.GetUser(c.UserContext(), userID)
 	if err != nil {
-		return c.Status(500).JSON(usecase.RegisterResponse{
-			Success: false,
-			Message: "Internal server error",
-		})
+		return err
 	}
 
-	if !response.Success {
-		return c.Status(404).JSON(response)
+	return c.JSON(response)
+}
+
+// UpdateUser handles replacing a user's editable fields
+// @Summary      Update user
+// @Description  Replace first name, last name, phone and email of a user. member_id, registered_at and the other read-only fields may be sent back unchanged but cannot be modified.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string                     true  "User ID"
+// @Param        request  body      usecase.UpdateUserRequest  true  "User data"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200      {object}  usecase.RegisterResponse  "User updated successfully"
+// @Failure      400      {object}  handler.Problem  "Invalid request format, validation error or read-only field changed"
+// @Failure      401      {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403      {object}  handler.Problem  "Members may only change their own account"
+// @Failure      404      {object}  handler.Problem  "User not found"
+// @Failure      409      {object}  handler.Problem  "Email or phone already registered"
+// @Failure      422      {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500      {object}  handler.Problem  "Internal server error"
+// @Failure      503      {object}  handler.Problem  "Request timed out; retry later"
+// @Router       /user/{id} [put]
+func (h *HTTPHandler) UpdateUser(c *fiber.Ctx) error {
+	req, err := usecase.DecodeUpdateUserRequest(c.Body())
+	if err != nil {
+		return err
+	}
+
+	response, err := h.userUsecase.UpdateUser(c.UserContext(), c.Params("id"), req)
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(response)
 }
 
-// GetAllUsers handles getting all users
-// @Summary      Get all users
-// @Description  Retrieve all registered users
+// PatchUser handles partial user updates
+// @Summary      Partially update user
+// @Description  Apply an RFC 7396 JSON Merge Patch to a user. The same validation, uniqueness and read-only rules as PUT apply.
+// @Tags         users
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path      string                  true  "User ID"
+// @Param        patch  body      map[string]interface{}  true  "Merge patch, e.g. {\"phone\": \"+66898765432\"}"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200    {object}  usecase.RegisterResponse  "User updated successfully"
+// @Failure      400    {object}  handler.Problem  "Invalid patch, validation error or read-only field changed"
+// @Failure      401    {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403    {object}  handler.Problem  "Members may only change their own account"
+// @Failure      404    {object}  handler.Problem  "User not found"
+// @Failure      409    {object}  handler.Problem  "Email or phone already registered"
+// @Failure      415    {object}  handler.Problem  "Unsupported content type"
+// @Failure      422    {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500    {object}  handler.Problem  "Internal server error"
+// @Failure      503    {object}  handler.Problem  "Request timed out; retry later"
+// @Router       /user/{id} [patch]
+func (h *HTTPHandler) PatchUser(c *fiber.Ctx) error {
+	// Plain JSON is accepted as well since many clients cannot set a
+	// custom content type
+	if !c.Is("json") && !strings.HasPrefix(c.Get(fiber.HeaderContentType), MIMEMergePatchJSON) {
+		return fiber.ErrUnsupportedMediaType
+	}
+
+	response, err := h.userUsecase.PatchUser(c.UserContext(), c.Params("id"), c.Body())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// DeleteUser handles deleting a user
+// @Summary      Delete user
+// @Description  Delete a user by ID. Their member ID is never reassigned.
+// @Tags         users
+// @Security     BearerAuth
+// @Param        id   path  string  true  "User ID"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      204  "User deleted"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Members may only change their own account"
+// @Failure      404  {object}  handler.Problem  "User not found"
+// @Failure      422  {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Failure      503  {object}  handler.Problem  "Request timed out; retry later"
+// @Router       /user/{id} [delete]
+func (h *HTTPHandler) DeleteUser(c *fiber.Ctx) error {
+	if err := h.userUsecase.DeleteUser(c.UserContext(), c.Params("id")); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListUsers handles listing users page by page
+// @Summary      List users
+// @Description  Retrieve a page of users, filtered and sorted. Pass next_cursor from the previous page as cursor to continue; the sort must stay the same.
 // @Tags         users
 // @Accept       json
 // @Produce      json
-// @Success      200  {object}  map[string]interface{}  "List of all users"
-// @Failure      500  {object}  map[string]interface{}  "Internal server error"
+// @Security     BearerAuth
+// @Param        membership_level  query     string  false  "Membership level"  Enums(Gold, Silver, Bronze)
+// @Param        registered_from   query     string  false  "Registered at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param        registered_to     query     string  false  "Registered before (RFC 3339), or on or before (YYYY-MM-DD)"
+// @Param        name              query     string  false  "First or last name prefix"
+// @Param        email             query     string  false  "Email prefix"
+// @Param        phone             query     string  false  "Phone prefix"
+// @Param        sort              query     string  false  "Sort field, - prefix for descending"  Enums(registered_at, -registered_at, member_id, -member_id, first_name, -first_name, last_name, -last_name, email, -email)
+// @Param        limit             query     int     false  "Page size (1-100, default 20)"
+// @Param        cursor            query     string  false  "Cursor from the previous page"
+// @Success      200  {object}  usecase.ListUsersResponse  "Page of users"
+// @Failure      400  {object}  handler.Problem  "Invalid list parameters"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Only admins may list users"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Failure      503  {object}  handler.Problem  "Request timed out; retry later"
 // @Router       /users [get]
-func (h *HTTPHandler) GetAllUsers(c *fiber.Ctx) error {
-	users, err := h.userUsecase.GetAllUsers()
+func (h *HTTPHandler) ListUsers(c *fiber.Ctx) error {
+	var req usecase.ListUsersRequest
+	if err := c.QueryParser(&req); err != nil {
+		return apperror.Validation("Invalid query parameters")
+	}
+
+	response, err := h.userUsecase.ListUsers(c.UserContext(), req)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"success": false,
-			"message": "Internal server error",
-		})
+		return err
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"users":   users,
-		"count":   len(users),
-	})
+	return c.JSON(response)
+}
+
+// SetRole handles changing a user's role
+// @Summary      Set a user's role
+// @Description  Make a user a member, staff or admin. Roles cannot be changed through PUT or PATCH /user/{id}. Admins only.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string                  true   "User ID"
+// @Param        request          body      usecase.SetRoleRequest  true   "New role"
+// @Param        Idempotency-Key  header    string                  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.RegisterResponse  "Role updated"
+// @Failure      400              {object}  handler.Problem  "Invalid request format or unknown role"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Only admins may change roles"
+// @Failure      404              {object}  handler.Problem  "User not found"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Failure      503              {object}  handler.Problem  "Request timed out; retry later"
+// @Router       /admin/users/{id}/role [put]
+func (h *HTTPHandler) SetRole(c *fiber.Ctx) error {
+	var req usecase.SetRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+
+	response, err := h.userUsecase.SetRole(c.UserContext(), c.Params("id"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
 }
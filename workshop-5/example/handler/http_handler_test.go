@@ -0,0 +1,549 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/mike/analytics"
+	"example.com/mike/auth"
+	"example.com/mike/entity"
+	"example.com/mike/handler"
+	"example.com/mike/qrpayload"
+	"example.com/mike/repository"
+	"example.com/mike/sms"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func setupApp() *fiber.App {
+	app, _ := setupAppWithUsers()
+	return app
+}
+
+// setupAppWithUsers is setupApp, also returning the app's user repository
+// so tests can add staff and admins
+func setupAppWithUsers() (*fiber.App, repository.UserRepository) {
+	return setupAppWithLimits(handler.DefaultRateLimits)
+}
+
+// setupAppWithLimits is setupAppWithUsers with the given rate limits
+func setupAppWithLimits(limits handler.RateLimits) (*fiber.App, repository.UserRepository) {
+	app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler, Immutable: true})
+	app.Use(handler.Timeout(handler.DefaultRequestTimeout))
+	userRepo, ledgerRepo := repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository()
+	authUsecase := usecase.NewAuthUsecase(userRepo, repository.NewMemoryOTPRepository(), repository.NewMemoryRefreshTokenRepository(),
+		auth.NewLogCodeSender(io.Discard), testSigner, usecase.DefaultAuthPolicy)
+	app.Use(handler.Authenticate(authUsecase))
+	apiKeys := usecase.NewAPIKeyUsecase(repository.NewMemoryAPIKeyRepository(), testSigner, usecase.DefaultAPIKeyPolicy)
+	rateLimits := repository.NewMemoryRateLimitRepository()
+	app.Use("/partner", handler.RequireAPIKey(apiKeys, rateLimits))
+	limits.Mount(app, rateLimits)
+	app.Use(handler.Idempotency(handler.IdempotencyConfig{Store: repository.NewMemoryIdempotencyRepository()}))
+	handler.NewAuthHandler(authUsecase).RegisterRoutes(app)
+	handler.NewHTTPHandler(usecase.NewUserUsecase(userRepo, ledgerRepo)).RegisterRoutes(app)
+	ledger := usecase.NewLedgerUsecase(userRepo, ledgerRepo)
+	handler.NewLedgerHandler(ledger).RegisterRoutes(app)
+	handler.NewAPIKeyHandler(apiKeys).RegisterRoutes(app)
+	handler.NewPartnerHandler(ledger).RegisterRoutes(app)
+	qrRepo := repository.NewMemoryQRRequestRepository()
+	transferRepo := repository.NewMemoryTransferRepository(ledgerRepo, qrRepo)
+	transfers := usecase.NewTransferUsecase(userRepo, transferRepo, ledgerRepo, usecase.DefaultTransferPolicy)
+	handler.NewTransferHandler(transfers).RegisterRoutes(app)
+	handler.NewQRHandler(usecase.NewQRUsecase(userRepo, qrRepo, transfers, testKeyring, usecase.DefaultQRPolicy)).RegisterRoutes(app)
+	productRepo, cartRepo := repository.NewMemoryProductRepository(), repository.NewMemoryCartRepository()
+	handler.NewProductHandler(usecase.NewProductUsecase(productRepo)).RegisterRoutes(app)
+	outboxRepo := repository.NewMemoryOutboxRepository()
+	orderRepo := repository.NewMemoryOrderRepository(productRepo, ledgerRepo, cartRepo, outboxRepo)
+	receipts := usecase.NewReceiptUsecase(userRepo, orderRepo, ledgerRepo, repository.NewMemoryReceiptRepository(outboxRepo),
+		sms.NewLogSender(io.Discard), testWebhookSecret)
+	outbox := usecase.NewOutboxUsecase(outboxRepo, map[string]usecase.OutboxHandler{
+		usecase.TopicSMSReceipt:     usecase.ReceiptOutboxHandler(receipts),
+		usecase.TopicAnalyticsEvent: usecase.AnalyticsOutboxHandler(analytics.NewLogEmitter(io.Discard)),
+	}, usecase.DefaultOutboxPolicy)
+	handler.NewCartHandler(usecase.NewCartUsecase(userRepo, productRepo, cartRepo, orderRepo, ledgerRepo, receipts, outbox)).RegisterRoutes(app)
+	handler.NewOrderHandler(usecase.NewOrderUsecase(orderRepo)).RegisterRoutes(app)
+	handler.NewReceiptHandler(receipts).RegisterRoutes(app)
+	handler.NewOutboxHandler(outbox).RegisterRoutes(app)
+	return app, userRepo
+}
+
+// testWebhookSecret signs the SMS delivery reports of every test app
+var testWebhookSecret = []byte("test-sms-webhook-secret")
+
+// testSigner signs the access tokens of every test app
+var testSigner = func() *auth.Signer {
+	signer, err := auth.NewSigner([]byte("test-token-secret-test-token-secret"))
+	if err != nil {
+		panic(err)
+	}
+	return signer
+}()
+
+// bearer returns an Authorization header pair for an access token of
+// userID, to pass to do
+func bearer(t *testing.T, userID string) []string {
+	t.Helper()
+	now := time.Now()
+	token, err := testSigner.Sign(auth.Claims{Subject: userID, ID: "test", IssuedAt: now, ExpiresAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return []string{fiber.HeaderAuthorization, "Bearer " + token}
+}
+
+// authenticate returns the Authenticate middleware for hand-wired test
+// apps whose users live in userRepo
+func authenticate(userRepo repository.UserRepository) fiber.Handler {
+	return handler.Authenticate(usecase.NewAuthUsecase(userRepo, repository.NewMemoryOTPRepository(), repository.NewMemoryRefreshTokenRepository(),
+		auth.NewLogCodeSender(io.Discard), testSigner, usecase.DefaultAuthPolicy))
+}
+
+// createUser stores a user with role straight in userRepo and returns
+// their ID with an Authorization header pair for them
+func createUser(t *testing.T, userRepo repository.UserRepository, role entity.Role, phone, email string) (string, []string) {
+	t.Helper()
+	memberID, err := userRepo.NextMemberID(context.Background())
+	if err != nil {
+		t.Fatalf("NextMemberID: %v", err)
+	}
+	user := entity.NewUser(uuid.New().String(), memberID, "Staff", "Test", phone, email)
+	user.Role = role
+	if err := userRepo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	return user.ID, bearer(t, user.ID)
+}
+
+// setRole changes the role of a stored user
+func setRole(t *testing.T, userRepo repository.UserRepository, id string, role entity.Role) {
+	t.Helper()
+	user, err := userRepo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	user.Role = role
+	if err := userRepo.Update(context.Background(), user); err != nil {
+		t.Fatalf("Update user: %v", err)
+	}
+}
+
+// testKeyring signs the QR payloads of every test app
+var testKeyring = func() *qrpayload.Keyring {
+	keyring, err := qrpayload.ParseKeyring("test:dGVzdC1zaWduaW5nLWtleS10ZXN0LXNpZ25pbmcta2V5")
+	if err != nil {
+		panic(err)
+	}
+	return keyring
+}()
+
+// do sends a JSON request with optional header name and value pairs
+func do(t *testing.T, app *fiber.App, method, path string, body interface{}, headers ...string) *http.Response {
+	t.Helper()
+
+	var reader *bytes.Reader
+	switch b := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case string:
+		reader = bytes.NewReader([]byte(b))
+	default:
+		raw, _ := json.Marshal(b)
+		reader = bytes.NewReader(raw)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	return resp
+}
+
+// expectProblem asserts resp is a problem+json body with the given status
+func expectProblem(t *testing.T, resp *http.Response, status int) handler.Problem {
+	t.Helper()
+
+	if resp.StatusCode != status {
+		t.Fatalf("expected status %d, got %d", status, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != handler.MIMEProblemJSON {
+		t.Fatalf("expected Content-Type %q, got %q", handler.MIMEProblemJSON, ct)
+	}
+
+	var problem handler.Problem
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if problem.Status != status || problem.Type == "" || problem.Title == "" {
+		t.Fatalf("incomplete problem: %+v", problem)
+	}
+	return problem
+}
+
+func TestRegisterStatusCodes(t *testing.T) {
+	app := setupApp()
+	valid := usecase.RegisterRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Phone:     "+66812345678",
+		Email:     "john.doe@example.com",
+	}
+
+	if resp := do(t, app, "POST", "/register", valid); resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("first registration: expected 201, got %d", resp.StatusCode)
+	}
+
+	problem := expectProblem(t, do(t, app, "POST", "/register", valid), fiber.StatusConflict)
+	if problem.Detail != "Email already registered" {
+		t.Fatalf("duplicate registration: unexpected detail %q", problem.Detail)
+	}
+
+	problem = expectProblem(t, do(t, app, "POST", "/register", usecase.RegisterRequest{FirstName: "John"}), fiber.StatusBadRequest)
+	if len(problem.Errors) != 3 {
+		t.Fatalf("missing fields: expected 3 field errors, got %+v", problem.Errors)
+	}
+	if problem.Instance != "/register" {
+		t.Fatalf("missing fields: unexpected instance %q", problem.Instance)
+	}
+
+	expectProblem(t, do(t, app, "POST", "/register", "{not json"), fiber.StatusBadRequest)
+}
+
+// registerAndAuthorize registers a user and returns their ID with an
+// Authorization header pair for them
+func registerAndAuthorize(t *testing.T, app *fiber.App, phone, email string) (string, []string) {
+	t.Helper()
+	resp := do(t, app, "POST", "/register", usecase.RegisterRequest{FirstName: "John", LastName: "Doe", Phone: phone, Email: email})
+	var registered usecase.RegisterResponse
+	if err := json.NewDecoder(resp.Body).Decode(&registered); err != nil || registered.User == nil {
+		t.Fatalf("register: status %d, %v", resp.StatusCode, err)
+	}
+	return registered.User.ID, bearer(t, registered.User.ID)
+}
+
+func TestGetUserNotFoundReturns404(t *testing.T) {
+	app, users := setupAppWithUsers()
+	_, authorization := createUser(t, users, entity.RoleStaff, "+66812345678", "john.doe@example.com")
+
+	problem := expectProblem(t, do(t, app, "GET", "/user/does-not-exist", nil, authorization...), fiber.StatusNotFound)
+	if problem.Detail != "User not found" {
+		t.Fatalf("unexpected detail %q", problem.Detail)
+	}
+}
+
+func TestUserRoutesRequireAuthentication(t *testing.T) {
+	app := setupApp()
+	id, authorization := registerAndAuthorize(t, app, "+66812345678", "john.doe@example.com")
+
+	for _, route := range [][2]string{{"GET", "/users"}, {"GET", "/user/" + id}, {"PUT", "/user/" + id}, {"PATCH", "/user/" + id}, {"DELETE", "/user/" + id}} {
+		resp := do(t, app, route[0], route[1], nil)
+		if problem := expectProblem(t, resp, fiber.StatusUnauthorized); problem.Detail != "Authentication required" {
+			t.Fatalf("%s %s: unexpected detail %q", route[0], route[1], problem.Detail)
+		}
+		if got := resp.Header.Get(fiber.HeaderWWWAuthenticate); got != "Bearer" {
+			t.Fatalf("%s %s: WWW-Authenticate = %q", route[0], route[1], got)
+		}
+	}
+
+	// A bad token is rejected even on public routes
+	for _, header := range []string{"Bearer not-a-token", "Basic dXNlcjpwYXNz", "Bearer"} {
+		resp := do(t, app, "GET", "/health", nil, fiber.HeaderAuthorization, header)
+		expectProblem(t, resp, fiber.StatusUnauthorized)
+		if got := resp.Header.Get(fiber.HeaderWWWAuthenticate); !strings.HasPrefix(got, "Bearer error=") {
+			t.Fatalf("%q: WWW-Authenticate = %q", header, got)
+		}
+	}
+
+	if resp := do(t, app, "GET", "/user/"+id, nil, authorization...); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("authenticated GET: expected 200, got %d", resp.StatusCode)
+	}
+	if resp := do(t, app, "GET", "/health", nil); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("anonymous GET /health: expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestUnknownRouteReturnsProblem(t *testing.T) {
+	app := setupApp()
+
+	expectProblem(t, do(t, app, "GET", "/does-not-exist", nil), fiber.StatusNotFound)
+}
+
+func TestUserLifecycleEndpoints(t *testing.T) {
+	app, users := setupAppWithUsers()
+	id, authorization := registerAndAuthorize(t, app, "+66812345678", "john.doe@example.com")
+	path := "/user/" + id
+
+	resp := do(t, app, "PUT", path, map[string]string{
+		"first_name": "Jane", "last_name": "Doe", "phone": "+66812345678", "email": "jane@example.com",
+	}, authorization...)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("PUT: expected 200, got %d", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest("PATCH", path, bytes.NewReader([]byte(`{"phone":"+66898765432"}`)))
+	req.Header.Set("Content-Type", handler.MIMEMergePatchJSON)
+	req.Header.Set(authorization[0], authorization[1])
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var patched usecase.RegisterResponse
+	if err := json.NewDecoder(resp.Body).Decode(&patched); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || patched.User.Phone != "+66898765432" || patched.User.Email != "jane@example.com" {
+		t.Fatalf("PATCH: unexpected response %d %+v", resp.StatusCode, patched.User)
+	}
+
+	problem := expectProblem(t, do(t, app, "PATCH", path, map[string]string{"member_id": "LBK999999"}, authorization...), fiber.StatusBadRequest)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "member_id" {
+		t.Fatalf("PATCH member_id: unexpected errors %+v", problem.Errors)
+	}
+
+	req = httptest.NewRequest("PATCH", path, bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set(authorization[0], authorization[1])
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	expectProblem(t, resp, fiber.StatusUnsupportedMediaType)
+
+	if resp := do(t, app, "DELETE", path, nil, authorization...); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("DELETE: expected 204, got %d", resp.StatusCode)
+	}
+	_, admin := createUser(t, users, entity.RoleAdmin, "+66811111111", "admin@example.com")
+	expectProblem(t, do(t, app, "DELETE", path, nil, admin...), fiber.StatusNotFound)
+
+	// The deleted user's access token stops working
+	expectProblem(t, do(t, app, "GET", "/users", nil, authorization...), fiber.StatusUnauthorized)
+}
+
+func TestListUsersQueryParameters(t *testing.T) {
+	app, users := setupAppWithUsers()
+	var id string
+	var authorization []string
+	for i, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		id, authorization = registerAndAuthorize(t, app, fmt.Sprintf("+6681000000%d", i), email)
+	}
+	expectProblem(t, do(t, app, "GET", "/users", nil, authorization...), fiber.StatusForbidden)
+	setRole(t, users, id, entity.RoleAdmin)
+
+	resp := do(t, app, "GET", "/users?limit=2&sort=-email", nil, authorization...)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var page usecase.ListUsersResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if page.Count != 2 || page.Users[0].Email != "c@example.com" || page.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+
+	resp = do(t, app, "GET", "/users?limit=2&sort=-email&cursor="+page.NextCursor, nil, authorization...)
+	page = usecase.ListUsersResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if page.Count != 1 || page.Users[0].Email != "a@example.com" || page.NextCursor != "" {
+		t.Fatalf("unexpected last page: %+v", page)
+	}
+
+	expectProblem(t, do(t, app, "GET", "/users?limit=abc", nil, authorization...), fiber.StatusBadRequest)
+}
+
+func TestLedgerEndpoints(t *testing.T) {
+	app, users := setupAppWithUsers()
+
+	id, authorization := registerAndAuthorize(t, app, "+66812345678", "john.doe@example.com")
+	_, staff := createUser(t, users, entity.RoleStaff, "+66811111111", "staff@example.com")
+	_, admin := createUser(t, users, entity.RoleAdmin, "+66822222222", "admin@example.com")
+	base := "/user/" + id
+
+	// Members read their ledger but only staff post to it
+	expectProblem(t, do(t, app, "POST", base+"/ledger", usecase.PostEntryRequest{Type: "earn", Amount: 300}, authorization...), fiber.StatusForbidden)
+	resp := do(t, app, "POST", base+"/ledger", usecase.PostEntryRequest{Type: "earn", Amount: 300}, staff...)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var posted usecase.LedgerEntryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&posted); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+
+	expectProblem(t, do(t, app, "POST", base+"/ledger", usecase.PostEntryRequest{Type: "spend", Amount: 301}, staff...), fiber.StatusConflict)
+	expectProblem(t, do(t, app, "POST", base+"/ledger", usecase.PostEntryRequest{Type: "earn"}, staff...), fiber.StatusBadRequest)
+	expectProblem(t, do(t, app, "GET", "/user/missing/balance", nil, staff...), fiber.StatusNotFound)
+
+	resp = do(t, app, "GET", base+"/balance", nil, authorization...)
+	var balance usecase.BalanceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&balance); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if balance.Balance != 300 {
+		t.Fatalf("expected balance 300, got %d", balance.Balance)
+	}
+
+	resp = do(t, app, "GET", base+"/ledger/"+posted.Entry.ID, nil, authorization...)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("GET entry: expected 200, got %d", resp.StatusCode)
+	}
+
+	resp = do(t, app, "GET", base+"/ledger?type=earn", nil, authorization...)
+	var list usecase.ListEntriesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if list.Count != 1 || list.Entries[0].ID != posted.Entry.ID {
+		t.Fatalf("unexpected history: %+v", list)
+	}
+
+	// The user resource shows the ledger balance and refuses to change it
+	resp = do(t, app, "GET", base, nil, authorization...)
+	var user usecase.RegisterResponse
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if user.User.Points != 300 {
+		t.Fatalf("expected points 300, got %d", user.User.Points)
+	}
+	expectProblem(t, do(t, app, "PATCH", base, `{"points": 1000000}`, authorization...), fiber.StatusBadRequest)
+
+	// Only admins reverse entries, and each posting only once
+	reverse := base + "/ledger/" + posted.Entry.ID + "/reverse"
+	expectProblem(t, do(t, app, "POST", reverse, usecase.ReverseEntryRequest{Reason: "Credited twice"}, staff...), fiber.StatusForbidden)
+	expectProblem(t, do(t, app, "POST", reverse, usecase.ReverseEntryRequest{}, admin...), fiber.StatusBadRequest)
+	resp = do(t, app, "POST", reverse, usecase.ReverseEntryRequest{Reason: "Credited twice"}, admin...)
+	var reversed usecase.LedgerEntryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reversed); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusCreated || reversed.Entry.Amount != -300 || reversed.Balance != 0 {
+		t.Fatalf("reverse: unexpected response %d %+v", resp.StatusCode, reversed)
+	}
+	expectProblem(t, do(t, app, "POST", reverse, usecase.ReverseEntryRequest{Reason: "Again"}, admin...), fiber.StatusConflict)
+	expectProblem(t, do(t, app, "POST", base+"/ledger/"+reversed.Entry.ID+"/reverse", usecase.ReverseEntryRequest{Reason: "Undo"}, admin...), fiber.StatusUnprocessableEntity)
+	expectProblem(t, do(t, app, "POST", base+"/ledger/missing/reverse", usecase.ReverseEntryRequest{Reason: "Undo"}, admin...), fiber.StatusNotFound)
+}
+
+func TestTransferEndpoints(t *testing.T) {
+	app, users := setupAppWithUsers()
+
+	alice, asAlice := registerAndAuthorize(t, app, "+66810000010", "alice@example.com")
+	bob, asBob := registerAndAuthorize(t, app, "+66810000011", "bob@example.com")
+	_, asCarol := registerAndAuthorize(t, app, "+66810000012", "carol@example.com")
+	_, staff := createUser(t, users, entity.RoleStaff, "+66811111111", "staff@example.com")
+	if resp := do(t, app, "POST", "/user/"+alice+"/ledger", usecase.PostEntryRequest{Type: "earn", Amount: 1000}, staff...); resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("seed: expected 201, got %d", resp.StatusCode)
+	}
+
+	transfer := usecase.TransferRequest{FromUserID: alice, ToUserID: bob, Amount: 400, Memo: "Lunch"}
+	expectProblem(t, do(t, app, "POST", "/transfers", transfer), fiber.StatusUnauthorized)
+	expectProblem(t, do(t, app, "POST", "/transfers", transfer, asBob...), fiber.StatusForbidden)
+	expectProblem(t, do(t, app, "POST", "/transfers", transfer, staff...), fiber.StatusForbidden)
+
+	resp := do(t, app, "POST", "/transfers", transfer, asAlice...)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var created usecase.TransferResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if created.Transfer.Status != entity.TransferPosted || created.Balance != 600 {
+		t.Fatalf("unexpected transfer response: %+v %+v", created, created.Transfer)
+	}
+
+	// Both parties may read the transfer; to other members it does not exist
+	for _, authorization := range [][]string{asAlice, asBob, staff} {
+		if resp := do(t, app, "GET", "/transfers/"+created.Transfer.ID, nil, authorization...); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("GET transfer: expected 200, got %d", resp.StatusCode)
+		}
+	}
+	hidden := expectProblem(t, do(t, app, "GET", "/transfers/"+created.Transfer.ID, nil, asCarol...), fiber.StatusNotFound)
+	missing := expectProblem(t, do(t, app, "GET", "/transfers/missing", nil, asCarol...), fiber.StatusNotFound)
+	if hidden.Type != missing.Type || hidden.Detail != missing.Detail {
+		t.Fatalf("another member's transfer is told apart from a missing one: %+v, %+v", hidden, missing)
+	}
+
+	expectProblem(t, do(t, app, "POST", "/transfers", usecase.TransferRequest{FromUserID: alice, ToUserID: bob, Amount: 601}, asAlice...), fiber.StatusConflict)
+	expectProblem(t, do(t, app, "POST", "/transfers", usecase.TransferRequest{FromUserID: alice, ToUserID: alice, Amount: 1}, asAlice...), fiber.StatusBadRequest)
+	expectProblem(t, do(t, app, "POST", "/transfers", usecase.TransferRequest{FromUserID: alice, ToUserID: "missing", Amount: 1}, asAlice...), fiber.StatusBadRequest)
+	expectProblem(t, do(t, app, "GET", "/transfers/missing", nil, asAlice...), fiber.StatusNotFound)
+
+	resp = do(t, app, "GET", "/user/"+bob+"/balance", nil, asBob...)
+	var balance usecase.BalanceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&balance); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if balance.Balance != 400 {
+		t.Fatalf("expected recipient balance 400, got %d", balance.Balance)
+	}
+}
+
+func TestRoleBasedAccess(t *testing.T) {
+	app, users := setupAppWithUsers()
+	member, asMember := registerAndAuthorize(t, app, "+66812345678", "john.doe@example.com")
+	other, asOther := registerAndAuthorize(t, app, "+66898765432", "jane.doe@example.com")
+	_, staff := createUser(t, users, entity.RoleStaff, "+66811111111", "staff@example.com")
+	_, admin := createUser(t, users, entity.RoleAdmin, "+66822222222", "admin@example.com")
+
+	// Members only read their own account; staff and admins read anyone's
+	problem := expectProblem(t, do(t, app, "GET", "/user/"+member, nil, asOther...), fiber.StatusForbidden)
+	if problem.Type != "/problems/forbidden" || problem.Detail != usecase.ErrForbidden.Message {
+		t.Fatalf("unexpected problem: %+v", problem)
+	}
+	for _, authorization := range [][]string{asMember, staff, admin} {
+		if resp := do(t, app, "GET", "/user/"+member, nil, authorization...); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("GET /user/%s: expected 200, got %d", member, resp.StatusCode)
+		}
+	}
+
+	// Staff may not change accounts, list users or hand out roles
+	update := map[string]string{"first_name": "Jim", "last_name": "Doe", "phone": "+66812345678", "email": "john.doe@example.com"}
+	expectProblem(t, do(t, app, "PUT", "/user/"+member, update, staff...), fiber.StatusForbidden)
+	expectProblem(t, do(t, app, "DELETE", "/user/"+member, nil, asOther...), fiber.StatusForbidden)
+	expectProblem(t, do(t, app, "GET", "/users", nil, staff...), fiber.StatusForbidden)
+	expectProblem(t, do(t, app, "PUT", "/admin/users/"+other+"/role", usecase.SetRoleRequest{Role: "admin"}, asOther...), fiber.StatusForbidden)
+	if resp := do(t, app, "PUT", "/user/"+member, update, admin...); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("admin PUT: expected 200, got %d", resp.StatusCode)
+	}
+
+	// Roles are read-only on the user resource and changed by admins
+	expectProblem(t, do(t, app, "PATCH", "/user/"+other, `{"role": "admin"}`, asOther...), fiber.StatusBadRequest)
+	expectProblem(t, do(t, app, "PUT", "/admin/users/"+other+"/role", usecase.SetRoleRequest{Role: "owner"}, admin...), fiber.StatusBadRequest)
+	expectProblem(t, do(t, app, "PUT", "/admin/users/missing/role", usecase.SetRoleRequest{Role: "staff"}, admin...), fiber.StatusNotFound)
+	resp := do(t, app, "PUT", "/admin/users/"+other+"/role", usecase.SetRoleRequest{Role: "staff"}, admin...)
+	var promoted usecase.RegisterResponse
+	if err := json.NewDecoder(resp.Body).Decode(&promoted); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || promoted.User.Role != entity.RoleStaff {
+		t.Fatalf("set role: unexpected response %d %+v", resp.StatusCode, promoted.User)
+	}
+	if resp := do(t, app, "GET", "/user/"+member, nil, asOther...); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("GET as promoted staff: expected 200, got %d", resp.StatusCode)
+	}
+
+	// Admin routes turn members away before looking at the request
+	for _, route := range [][2]string{
+		{"GET", "/admin/products"}, {"POST", "/admin/orders/any/fulfill"}, {"POST", "/admin/orders/any/refund"},
+		{"GET", "/admin/outbox"}, {"POST", "/user/" + member + "/ledger"},
+	} {
+		expectProblem(t, do(t, app, route[0], route[1], nil, asMember...), fiber.StatusForbidden)
+	}
+}
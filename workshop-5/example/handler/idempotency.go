@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Headers used by the idempotency middleware
+const (
+	// HeaderIdempotencyKey carries the client-chosen key, e.g. a UUID
+	HeaderIdempotencyKey = "Idempotency-Key"
+
+	// HeaderIdempotentReplayed is set on responses replayed from storage
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// MaxIdempotencyKeyLength bounds the Idempotency-Key header
+const MaxIdempotencyKeyLength = 255
+
+// DefaultIdempotencyTTL is how long keys are kept unless configured
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// Errors returned by the idempotency middleware
+var (
+	// ErrInvalidIdempotencyKey is returned for empty-looking, overlong or
+	// non-printable keys
+	ErrInvalidIdempotencyKey = apperror.Validation("Invalid Idempotency-Key header",
+		apperror.FieldError{Field: HeaderIdempotencyKey, Message: "must be 1-255 printable ASCII characters"})
+
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a
+	// different method, URL or body
+	ErrIdempotencyKeyReused = apperror.Unprocessable("Idempotency-Key was already used for a different request")
+
+	// ErrIdempotentRequestInProgress is returned when a retry arrives while
+	// the first request with the same key is still being processed
+	ErrIdempotentRequestInProgress = apperror.Conflict("A request with this Idempotency-Key is still being processed")
+)
+
+// IdempotencyConfig configures the idempotency middleware
+type IdempotencyConfig struct {
+	// Store keeps the reservations and stored responses
+	Store repository.IdempotencyRepository
+
+	// TTL is how long a key is remembered, DefaultIdempotencyTTL if zero
+	TTL time.Duration
+
+	// Caller identifies who sent the request, so two callers may use the
+	// same key. Defaults to the authenticated user, or the client IP for
+	// anonymous requests; Authenticate must run first.
+	Caller func(c *fiber.Ctx) string
+}
+
+// Idempotency makes POST, PUT, PATCH and DELETE requests that carry an
+// Idempotency-Key header safe to retry. The first request with a key is
+// processed and its response stored under the caller, the key and a hash of
+// the request; retries get the stored response back with an
+// Idempotent-Replayed header. Reusing a key for a different request is
+// rejected with 422, and a retry of a request still in flight with 409.
+// Server errors are not stored, so those requests can be retried.
+func Idempotency(config IdempotencyConfig) fiber.Handler {
+	if config.TTL <= 0 {
+		config.TTL = DefaultIdempotencyTTL
+	}
+	if config.Caller == nil {
+		config.Caller = defaultCaller
+	}
+
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderIdempotencyKey)
+		if key == "" || !isMutatingMethod(c.Method()) {
+			return c.Next()
+		}
+		if !validIdempotencyKey(key) {
+			return ErrInvalidIdempotencyKey
+		}
+
+		now := time.Now()
+		record := &entity.IdempotencyRecord{
+			Key:         hashParts(config.Caller(c), key),
+			RequestHash: hashParts(c.Method(), c.OriginalURL(), string(c.Body())),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(config.TTL),
+		}
+
+		existing, err := config.Store.Reserve(c.UserContext(), record)
+		if errors.Is(err, repository.ErrIdempotencyKeyExists) {
+			return replay(c, record, existing)
+		}
+		if err != nil {
+			return apperror.Internal("Failed to reserve idempotency key", err)
+		}
+
+		// Render errors here rather than in the app error handler so the
+		// problem response is stored like any other
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				release(c, config.Store, record.Key)
+				return err
+			}
+		}
+
+		response := c.Response()
+		if response.StatusCode() >= fiber.StatusInternalServerError {
+			release(c, config.Store, record.Key)
+			return nil
+		}
+
+		record.StatusCode = response.StatusCode()
+		record.ContentType = string(response.Header.ContentType())
+		record.Body = append([]byte(nil), response.Body()...)
+		// The request has run, so its response is stored even if it
+		// outlived its deadline; otherwise retries would find the key
+		// reserved until it expires
+		if err := config.Store.Complete(context.WithoutCancel(c.UserContext()), record); err != nil {
+			log.Printf("%s %s: store idempotent response: %v", c.Method(), c.Path(), err)
+		}
+		return nil
+	}
+}
+
+// defaultCaller identifies the caller by user ID or partner API key, or
+// by client IP for anonymous requests
+func defaultCaller(c *fiber.Ctx) string {
+	if user := CurrentUser(c); user != nil {
+		return "user:" + user.ID
+	}
+	if key := CurrentAPIKey(c); key != nil {
+		return "api-key:" + key.ID
+	}
+	return "ip:" + c.IP()
+}
+
+// replay answers a retry from the stored record
+func replay(c *fiber.Ctx, record, existing *entity.IdempotencyRecord) error {
+	if existing.RequestHash != record.RequestHash {
+		return ErrIdempotencyKeyReused
+	}
+	if !existing.Completed {
+		return ErrIdempotentRequestInProgress
+	}
+
+	c.Set(HeaderIdempotentReplayed, "true")
+	if existing.ContentType != "" {
+		c.Set(fiber.HeaderContentType, existing.ContentType)
+	}
+	return c.Status(existing.StatusCode).Send(existing.Body)
+}
+
+// release drops a reservation whose request failed, so it can be retried.
+// Timed out requests are released too, hence the context is not cancelled.
+func release(c *fiber.Ctx, store repository.IdempotencyRepository, key string) {
+	if err := store.Release(context.WithoutCancel(c.UserContext()), key); err != nil {
+		log.Printf("%s %s: release idempotency key: %v", c.Method(), c.Path(), err)
+	}
+}
+
+// isMutatingMethod reports whether requests with method change state
+func isMutatingMethod(method string) bool {
+	switch method {
+	case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// validIdempotencyKey reports whether key is 1-255 printable ASCII characters
+func validIdempotencyKey(key string) bool {
+	if len(key) > MaxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// hashParts returns the hex SHA-256 of parts, separated so that
+// ("ab", "c") and ("a", "bc") differ
+func hashParts(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
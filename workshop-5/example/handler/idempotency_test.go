@@ -0,0 +1,220 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/handler"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// doIdempotent sends a JSON request with an Idempotency-Key header
+func doIdempotent(t *testing.T, app *fiber.App, method, path, key string, body interface{}, headers ...string) *http.Response {
+	t.Helper()
+
+	raw, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(handler.HeaderIdempotencyKey, key)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	return resp
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return string(body)
+}
+
+func TestIdempotencyReplaysFirstResponse(t *testing.T) {
+	app, users := setupAppWithUsers()
+	req := usecase.RegisterRequest{FirstName: "John", LastName: "Doe", Phone: "+66812345678", Email: "john.doe@example.com"}
+
+	first := doIdempotent(t, app, "POST", "/register", "key-1", req)
+	if first.StatusCode != fiber.StatusCreated || first.Header.Get(handler.HeaderIdempotentReplayed) != "" {
+		t.Fatalf("first request: status %d, replayed %q", first.StatusCode, first.Header.Get(handler.HeaderIdempotentReplayed))
+	}
+	firstBody := readBody(t, first)
+
+	retry := doIdempotent(t, app, "POST", "/register", "key-1", req)
+	if retry.StatusCode != fiber.StatusCreated || retry.Header.Get(handler.HeaderIdempotentReplayed) != "true" {
+		t.Fatalf("retry: status %d, replayed %q", retry.StatusCode, retry.Header.Get(handler.HeaderIdempotentReplayed))
+	}
+	if ct := retry.Header.Get("Content-Type"); ct != fiber.MIMEApplicationJSON {
+		t.Fatalf("retry Content-Type = %q", ct)
+	}
+	if body := readBody(t, retry); body != firstBody {
+		t.Fatalf("retry body differs\n got: %s\nwant: %s", body, firstBody)
+	}
+
+	_, admin := createUser(t, users, entity.RoleAdmin, "+66811111111", "admin@example.com")
+	var list usecase.ListUsersResponse
+	if err := json.NewDecoder(do(t, app, "GET", "/users?email=john.doe", nil, admin...).Body).Decode(&list); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if list.Count != 1 {
+		t.Fatalf("expected 1 user after the retry, got %d", list.Count)
+	}
+
+	// Without a key the same request runs again and conflicts
+	expectProblem(t, do(t, app, "POST", "/register", req), fiber.StatusConflict)
+}
+
+func TestIdempotencyRejectsReuseForAnotherRequest(t *testing.T) {
+	app := setupApp()
+	req := usecase.RegisterRequest{FirstName: "John", LastName: "Doe", Phone: "+66812345678", Email: "john.doe@example.com"}
+	doIdempotent(t, app, "POST", "/register", "key-1", req)
+
+	req.Email = "jane.doe@example.com"
+	expectProblem(t, doIdempotent(t, app, "POST", "/register", "key-1", req), fiber.StatusUnprocessableEntity)
+
+	// Keys are scoped to the caller
+	app = fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler})
+	app.Use(handler.Idempotency(handler.IdempotencyConfig{
+		Store:  repository.NewMemoryIdempotencyRepository(),
+		Caller: func(c *fiber.Ctx) string { return c.Get("X-Caller") },
+	}))
+	app.Post("/echo", func(c *fiber.Ctx) error { return c.Send(c.Body()) })
+
+	if resp := doIdempotent(t, app, "POST", "/echo", "shared", "a", "X-Caller", "alice"); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("alice: expected 200, got %d", resp.StatusCode)
+	}
+	resp := doIdempotent(t, app, "POST", "/echo", "shared", "b", "X-Caller", "bob")
+	if resp.StatusCode != fiber.StatusOK || readBody(t, resp) != `"b"` {
+		t.Fatalf("bob: expected a separate response, got %d", resp.StatusCode)
+	}
+}
+
+func TestIdempotencyStoresProblemResponses(t *testing.T) {
+	app := setupApp()
+	req := usecase.RegisterRequest{FirstName: "John"}
+
+	expectProblem(t, doIdempotent(t, app, "POST", "/register", "key-1", req), fiber.StatusBadRequest)
+
+	retry := doIdempotent(t, app, "POST", "/register", "key-1", req)
+	if retry.Header.Get(handler.HeaderIdempotentReplayed) != "true" {
+		t.Fatal("expected the validation problem to be replayed")
+	}
+	expectProblem(t, retry, fiber.StatusBadRequest)
+}
+
+func TestIdempotencyKeyValidation(t *testing.T) {
+	app := setupApp()
+
+	for _, key := range []string{strings.Repeat("k", handler.MaxIdempotencyKeyLength+1), "has space", "ไทย"} {
+		expectProblem(t, doIdempotent(t, app, "POST", "/register", key, usecase.RegisterRequest{}), fiber.StatusBadRequest)
+	}
+
+	// Safe methods ignore the header
+	if resp := doIdempotent(t, app, "GET", "/health", "has space", nil); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("GET with key: expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestIdempotencyRetriesAfterServerErrorOrExpiry(t *testing.T) {
+	calls := 0
+	newApp := func(ttl time.Duration) *fiber.App {
+		app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler})
+		app.Use(handler.Idempotency(handler.IdempotencyConfig{Store: repository.NewMemoryIdempotencyRepository(), TTL: ttl}))
+		app.Post("/flaky", func(c *fiber.Ctx) error {
+			calls++
+			if calls == 1 {
+				return apperror.Internal("boom", nil)
+			}
+			return c.SendStatus(fiber.StatusCreated)
+		})
+		return app
+	}
+
+	app := newApp(0)
+	expectProblem(t, doIdempotent(t, app, "POST", "/flaky", "key-1", nil), fiber.StatusInternalServerError)
+	if resp := doIdempotent(t, app, "POST", "/flaky", "key-1", nil); resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("retry after 500: expected 201, got %d", resp.StatusCode)
+	}
+	if resp := doIdempotent(t, app, "POST", "/flaky", "key-1", nil); resp.Header.Get(handler.HeaderIdempotentReplayed) != "true" {
+		t.Fatal("expected the 201 to be replayed")
+	}
+
+	calls = 1
+	app = newApp(time.Nanosecond)
+	doIdempotent(t, app, "POST", "/flaky", "key-1", nil)
+	doIdempotent(t, app, "POST", "/flaky", "key-1", nil)
+	if calls != 3 {
+		t.Fatalf("expected expired keys to run the handler again, got %d calls", calls)
+	}
+}
+
+func TestIdempotencyRejectsRetryWhileInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler})
+	app.Use(handler.Idempotency(handler.IdempotencyConfig{Store: repository.NewMemoryIdempotencyRepository()}))
+	app.Post("/slow", func(c *fiber.Ctx) error {
+		close(started)
+		<-release
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	done := make(chan int)
+	go func() {
+		req := httptest.NewRequest("POST", "/slow", nil)
+		req.Header.Set(handler.HeaderIdempotencyKey, "key-1")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Errorf("request failed: %v", err)
+			done <- 0
+			return
+		}
+		done <- resp.StatusCode
+	}()
+
+	<-started
+	retry := httptest.NewRequest("POST", "/slow", nil)
+	retry.Header.Set(handler.HeaderIdempotencyKey, "key-1")
+	resp, err := app.Test(retry, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	expectProblem(t, resp, fiber.StatusConflict)
+	close(release)
+	if status := <-done; status != fiber.StatusCreated {
+		t.Fatalf("first request: expected 201, got %d", status)
+	}
+}
+
+func TestIdempotencyKeysArePerUser(t *testing.T) {
+	app, users := setupAppWithUsers()
+	alice, aliceAuth := registerAndAuthorize(t, app, "+66812345678", "alice@example.com")
+	_, bobAuth := createUser(t, users, entity.RoleAdmin, "+66898765432", "bob@example.com")
+	path := "/user/" + alice
+	update := map[string]string{"first_name": "Alice", "last_name": "Doe", "phone": "+66812345678", "email": "alice@example.com"}
+
+	first := doIdempotent(t, app, "PUT", path, "shared", update, aliceAuth...)
+	if first.StatusCode != fiber.StatusOK {
+		t.Fatalf("alice: expected 200, got %d", first.StatusCode)
+	}
+
+	// The same key from another user is a new request, not a replay
+	second := doIdempotent(t, app, "PUT", path, "shared", update, bobAuth...)
+	if second.StatusCode != fiber.StatusOK || second.Header.Get(handler.HeaderIdempotentReplayed) != "" {
+		t.Fatalf("bob: status %d, replayed %q", second.StatusCode, second.Header.Get(handler.HeaderIdempotentReplayed))
+	}
+}
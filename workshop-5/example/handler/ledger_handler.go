@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"example.com/mike/apperror"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LedgerHandler handles points ledger HTTP requests
+type LedgerHandler struct {
+	ledgerUsecase usecase.LedgerUsecase
+}
+
+// NewLedgerHandler creates a new ledger handler
+func NewLedgerHandler(ledgerUsecase usecase.LedgerUsecase) *LedgerHandler {
+	return &LedgerHandler{
+		ledgerUsecase: ledgerUsecase,
+	}
+}
+
+// RegisterRoutes sets up the ledger routes. Members read their own
+// ledger; staff post entries and admins reverse them.
+func (h *LedgerHandler) RegisterRoutes(app *fiber.App) {
+	readLedger := Authorize(usecase.OpReadLedger, "id")
+	app.Get("/user/:id/balance", readLedger, h.GetBalance)
+	app.Get("/user/:id/ledger", readLedger, h.ListEntries)
+	app.Post("/user/:id/ledger", Authorize(usecase.OpPostLedgerEntry, ""), h.PostEntry)
+	app.Get("/user/:id/ledger/:entryId", readLedger, h.GetEntry)
+	app.Post("/user/:id/ledger/:entryId/reverse", Authorize(usecase.OpReverseLedgerEntry, ""), h.ReverseEntry)
+}
+
+// GetBalance handles getting a user's points balance
+// @Summary      Get points balance
+// @Description  Return the user's balance, derived from their ledger entries
+// @Tags         ledger
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  usecase.BalanceResponse  "Current balance"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Ledger belongs to another member"
+// @Failure      404  {object}  handler.Problem  "User not found"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /user/{id}/balance [get]
+func (h *LedgerHandler) GetBalance(c *fiber.Ctx) error {
+	response, err := h.ledgerUsecase.GetBalance(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// ListEntries handles listing a user's ledger history
+// @Summary      List ledger entries
+// @Description  Retrieve a page of the user's ledger entries, newest first. Pass next_cursor from the previous page as cursor to continue.
+// @Tags         ledger
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      string  true   "User ID"
+// @Param        type    query     string  false  "Entry type filter"  Enums(earn, spend, transfer, transfer_in, transfer_out, reversal)
+// @Param        limit   query     int     false  "Page size (1-100, default 20)"
+// @Param        cursor  query     string  false  "Cursor from the previous page"
+// @Success      200     {object}  usecase.ListEntriesResponse  "Page of ledger entries"
+// @Failure      400     {object}  handler.Problem  "Invalid list parameters"
+// @Failure      401     {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403     {object}  handler.Problem  "Ledger belongs to another member"
+// @Failure      404     {object}  handler.Problem  "User not found"
+// @Failure      500     {object}  handler.Problem  "Internal server error"
+// @Router       /user/{id}/ledger [get]
+func (h *LedgerHandler) ListEntries(c *fiber.Ctx) error {
+	var req usecase.ListEntriesRequest
+	if err := c.QueryParser(&req); err != nil {
+		return apperror.Validation("Invalid query parameters")
+	}
+
+	response, err := h.ledgerUsecase.ListEntries(c.UserContext(), c.Params("id"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// PostEntry handles recording earned or spent points
+// @Summary      Post a ledger entry
+// @Description  Record points earned or spent by the user. Entries are immutable; spending more than the balance is rejected. Staff only.
+// @Tags         ledger
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string                    true  "User ID"
+// @Param        request  body      usecase.PostEntryRequest  true  "Entry to post"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      201      {object}  usecase.LedgerEntryResponse  "Entry posted"
+// @Failure      400      {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401      {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403      {object}  handler.Problem  "Only staff may post ledger entries"
+// @Failure      404      {object}  handler.Problem  "User not found"
+// @Failure      409      {object}  handler.Problem  "Insufficient balance"
+// @Failure      422      {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500      {object}  handler.Problem  "Internal server error"
+// @Router       /user/{id}/ledger [post]
+func (h *LedgerHandler) PostEntry(c *fiber.Ctx) error {
+	var req usecase.PostEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+
+	response, err := h.ledgerUsecase.PostEntry(c.UserContext(), c.Params("id"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetEntry handles getting a single ledger entry
+// @Summary      Get a ledger entry
+// @Description  Retrieve one of the user's ledger entries
+// @Tags         ledger
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string  true  "User ID"
+// @Param        entryId  path      string  true  "Ledger entry ID"
+// @Success      200      {object}  usecase.LedgerEntryResponse  "Ledger entry"
+// @Failure      401      {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403      {object}  handler.Problem  "Ledger belongs to another member"
+// @Failure      404      {object}  handler.Problem  "User or entry not found"
+// @Failure      500      {object}  handler.Problem  "Internal server error"
+// @Router       /user/{id}/ledger/{entryId} [get]
+func (h *LedgerHandler) GetEntry(c *fiber.Ctx) error {
+	response, err := h.ledgerUsecase.GetEntry(c.UserContext(), c.Params("id"), c.Params("entryId"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// ReverseEntry handles undoing a ledger entry
+// @Summary      Reverse a ledger entry
+// @Description  Undo the posting a ledger entry belongs to with a new posting of opposite amounts; the original entries are kept. A posting can be reversed once. Order payments are refunded through the order instead. Admins only.
+// @Tags         ledger
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string                       true   "User ID"
+// @Param        entryId          path      string                       true   "Ledger entry ID"
+// @Param        request          body      usecase.ReverseEntryRequest  true   "Reason for the reversal"
+// @Param        Idempotency-Key  header    string                       false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      201              {object}  usecase.LedgerEntryResponse  "Reversal posted; entry is the user's reversing entry"
+// @Failure      400              {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Only admins may reverse ledger entries"
+// @Failure      404              {object}  handler.Problem  "User or entry not found"
+// @Failure      409              {object}  handler.Problem  "Entry already reversed or insufficient balance"
+// @Failure      422              {object}  handler.Problem  "Entry cannot be reversed, or Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /user/{id}/ledger/{entryId}/reverse [post]
+func (h *LedgerHandler) ReverseEntry(c *fiber.Ctx) error {
+	var req usecase.ReverseEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+
+	response, err := h.ledgerUsecase.ReverseEntry(c.UserContext(), c.Params("id"), c.Params("entryId"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
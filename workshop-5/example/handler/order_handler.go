@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+
+	"example.com/mike/apperror"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// OrderHandler handles order HTTP requests
+type OrderHandler struct {
+	orderUsecase usecase.OrderUsecase
+}
+
+// NewOrderHandler creates a new order handler
+func NewOrderHandler(orderUsecase usecase.OrderUsecase) *OrderHandler {
+	return &OrderHandler{
+		orderUsecase: orderUsecase,
+	}
+}
+
+// RegisterRoutes sets up the order routes and the staff order actions
+func (h *OrderHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/orders/:id", RequireUser, h.GetOrder)
+
+	app.Post("/admin/orders/:id/fulfill", Authorize(usecase.OpFulfillOrder, ""), h.FulfillOrder)
+	app.Post("/admin/orders/:id/void", Authorize(usecase.OpVoidOrder, ""), h.VoidOrder)
+	app.Post("/admin/orders/:id/refund", Authorize(usecase.OpRefundOrder, ""), h.RefundOrder)
+}
+
+// GetOrder handles getting an order by ID
+// @Summary      Get an order
+// @Description  Retrieve an order with its items, priced when it was placed, and its payment. Members may only read their own orders.
+// @Tags         orders
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Order ID"
+// @Success      200  {object}  usecase.OrderResponse  "Order found"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Order belongs to another member"
+// @Failure      404  {object}  handler.Problem  "Order not found"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /orders/{id} [get]
+func (h *OrderHandler) GetOrder(c *fiber.Ctx) error {
+	response, err := h.orderUsecase.GetOrder(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	if err := authorize(c, usecase.OpGetOrder, response.Order.UserID); err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// FulfillOrder handles marking an order as fulfilled
+// @Summary      Fulfil an order
+// @Description  Mark a paid order as handed over to the customer. Staff only; the signed-in staff member is recorded as the actor.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string                      true   "Order ID"
+// @Param        request          body      usecase.OrderActionRequest  false  "Optional reason"
+// @Param        Idempotency-Key  header    string                      false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.OrderResponse  "Order fulfilled"
+// @Failure      400              {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Role not allowed to perform this action"
+// @Failure      404              {object}  handler.Problem  "Order not found"
+// @Failure      409              {object}  handler.Problem  "Order cannot move to the requested status"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /admin/orders/{id}/fulfill [post]
+func (h *OrderHandler) FulfillOrder(c *fiber.Ctx) error {
+	return h.orderAction(c, h.orderUsecase.FulfillOrder)
+}
+
+// VoidOrder handles voiding an order
+// @Summary      Void an order
+// @Description  Cancel an order that has not been fulfilled. The payment is reversed in the ledger and the items are restocked. Staff only.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string                      true   "Order ID"
+// @Param        request          body      usecase.OrderActionRequest  true   "Reason"
+// @Param        Idempotency-Key  header    string                      false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.OrderResponse  "Order voided"
+// @Failure      400              {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Role not allowed to perform this action"
+// @Failure      404              {object}  handler.Problem  "Order not found"
+// @Failure      409              {object}  handler.Problem  "Order cannot move to the requested status"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /admin/orders/{id}/void [post]
+func (h *OrderHandler) VoidOrder(c *fiber.Ctx) error {
+	return h.orderAction(c, h.orderUsecase.VoidOrder)
+}
+
+// RefundOrder handles refunding a fulfilled order
+// @Summary      Refund an order
+// @Description  Reverse a fulfilled order. The payment is reversed in the ledger and the items are restocked. Staff only.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string                      true   "Order ID"
+// @Param        request          body      usecase.OrderActionRequest  true   "Reason"
+// @Param        Idempotency-Key  header    string                      false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.OrderResponse  "Order refunded"
+// @Failure      400              {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Role not allowed to perform this action"
+// @Failure      404              {object}  handler.Problem  "Order not found"
+// @Failure      409              {object}  handler.Problem  "Order cannot move to the requested status"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /admin/orders/{id}/refund [post]
+func (h *OrderHandler) RefundOrder(c *fiber.Ctx) error {
+	return h.orderAction(c, h.orderUsecase.RefundOrder)
+}
+
+// orderAction parses a staff action and applies it to the order in the
+// path on behalf of the authenticated staff member. The body is optional
+// for actions that need no reason.
+func (h *OrderHandler) orderAction(c *fiber.Ctx, action func(context.Context, string, usecase.OrderActionRequest) (*usecase.OrderResponse, error)) error {
+	var req usecase.OrderActionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return apperror.Validation("Invalid request format")
+		}
+	}
+	req.Actor = CurrentUser(c).ID
+
+	response, err := action(c.UserContext(), c.Params("id"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
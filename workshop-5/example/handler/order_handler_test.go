@@ -0,0 +1,81 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOrderActionEndpoints(t *testing.T) {
+	app, users := setupAppWithUsers()
+
+	member, asMember := registerAndAuthorize(t, app, "+66810000041", "orders@example.com")
+	staffID, staff := createUser(t, users, entity.RoleStaff, "+66811111111", "staff@example.com")
+	_, admin := createUser(t, users, entity.RoleAdmin, "+66822222222", "admin@example.com")
+	do(t, app, "POST", "/user/"+member+"/ledger", usecase.PostEntryRequest{Type: "earn", Amount: 500}, staff...)
+
+	var product usecase.ProductResponse
+	resp := do(t, app, "POST", "/admin/products", usecase.ProductRequest{Name: "Iced Latte", Category: "drinks", PricePoints: 120, Stock: 3}, admin...)
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	do(t, app, "POST", "/user/"+member+"/cart/items", usecase.CartItemRequest{ProductID: product.Product.ID, Quantity: 2}, asMember...)
+
+	var placed usecase.OrderResponse
+	resp = do(t, app, "POST", "/checkout", usecase.CheckoutRequest{UserID: member}, asMember...)
+	if err := json.NewDecoder(resp.Body).Decode(&placed); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	base := "/admin/orders/" + placed.Order.ID
+
+	resp = do(t, app, "GET", "/orders/"+placed.Order.ID+"/receipt", nil, asMember...)
+	var receipt usecase.ReceiptResponse
+	if err := json.NewDecoder(resp.Body).Decode(&receipt); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || receipt.Receipt.Status != entity.ReceiptSent || receipt.Receipt.Phone != "+66810000041" {
+		t.Fatalf("unexpected receipt: %d %+v", resp.StatusCode, receipt.Receipt)
+	}
+	expectProblem(t, do(t, app, "GET", "/orders/missing/receipt", nil, asMember...), fiber.StatusNotFound)
+
+	// Members take no order actions
+	for _, action := range []string{"/fulfill", "/void", "/refund"} {
+		expectProblem(t, do(t, app, "POST", base+action, usecase.OrderActionRequest{Reason: "Out of milk"}, asMember...), fiber.StatusForbidden)
+	}
+
+	problem := expectProblem(t, do(t, app, "POST", base+"/void", usecase.OrderActionRequest{}, staff...), fiber.StatusBadRequest)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "reason" {
+		t.Fatalf("expected a reason field error, got %+v", problem)
+	}
+	expectProblem(t, do(t, app, "POST", base+"/refund", usecase.OrderActionRequest{Reason: "Spilled"}, staff...), fiber.StatusConflict)
+	expectProblem(t, do(t, app, "POST", "/admin/orders/missing/fulfill", nil, staff...), fiber.StatusNotFound)
+	expectProblem(t, do(t, app, "POST", base+"/fulfill", "{", staff...), fiber.StatusBadRequest)
+
+	// The actor is the signed-in staff member, whatever the body claims
+	resp = do(t, app, "POST", base+"/void", `{"reason": "Out of milk", "actor": "someone-else"}`, staff...)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("void: expected 200, got %d", resp.StatusCode)
+	}
+	var voided usecase.OrderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&voided); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	last := voided.Order.Events[len(voided.Order.Events)-1]
+	if voided.Order.Status != entity.OrderVoided || voided.Payment.Status != entity.PaymentRefunded ||
+		last.Actor != staffID || last.Reason != "Out of milk" {
+		t.Fatalf("unexpected voided order: %+v", voided)
+	}
+	expectProblem(t, do(t, app, "POST", base+"/fulfill", nil, staff...), fiber.StatusConflict)
+
+	var balance usecase.BalanceResponse
+	resp = do(t, app, "GET", "/user/"+member+"/balance", nil, asMember...)
+	if err := json.NewDecoder(resp.Body).Decode(&balance); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if balance.Balance != 500 {
+		t.Fatalf("balance = %d, want 500", balance.Balance)
+	}
+}
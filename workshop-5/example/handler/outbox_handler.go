@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"example.com/mike/apperror"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// OutboxHandler handles the admin endpoints for inspecting and replaying
+// outbox messages
+type OutboxHandler struct {
+	outboxUsecase usecase.OutboxUsecase
+}
+
+// NewOutboxHandler creates a new outbox handler
+func NewOutboxHandler(outboxUsecase usecase.OutboxUsecase) *OutboxHandler {
+	return &OutboxHandler{
+		outboxUsecase: outboxUsecase,
+	}
+}
+
+// RegisterRoutes sets up the admin outbox routes
+func (h *OutboxHandler) RegisterRoutes(app *fiber.App) {
+	manageOutbox := Authorize(usecase.OpManageOutbox, "")
+	app.Get("/admin/outbox", manageOutbox, h.ListMessages)
+	app.Get("/admin/outbox/:id", manageOutbox, h.GetMessage)
+	app.Post("/admin/outbox/:id/replay", manageOutbox, h.ReplayMessage)
+}
+
+// ListMessages handles listing outbox messages
+// @Summary      List outbox messages
+// @Description  Retrieve outbox messages oldest first, e.g. the dead ones that ran out of attempts or could not be delivered
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        status  query     string  false  "Status filter"  Enums(pending, delivered, dead)
+// @Param        limit   query     int     false  "Page size (1-100, default 20)"
+// @Success      200     {object}  usecase.ListOutboxResponse  "Outbox messages"
+// @Failure      400     {object}  handler.Problem  "Invalid list parameters"
+// @Failure      401     {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403     {object}  handler.Problem  "Only admins may manage the outbox"
+// @Failure      500     {object}  handler.Problem  "Internal server error"
+// @Router       /admin/outbox [get]
+func (h *OutboxHandler) ListMessages(c *fiber.Ctx) error {
+	var req usecase.ListOutboxRequest
+	if err := c.QueryParser(&req); err != nil {
+		return apperror.Validation("Invalid query parameters")
+	}
+
+	response, err := h.outboxUsecase.ListMessages(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// GetMessage handles getting an outbox message by ID
+// @Summary      Get an outbox message
+// @Description  Retrieve an outbox message with its payload, attempts and last error
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Outbox message ID"
+// @Success      200  {object}  usecase.OutboxMessageResponse  "Outbox message found"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Only admins may manage the outbox"
+// @Failure      404  {object}  handler.Problem  "Outbox message not found"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /admin/outbox/{id} [get]
+func (h *OutboxHandler) GetMessage(c *fiber.Ctx) error {
+	response, err := h.outboxUsecase.GetMessage(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// ReplayMessage handles replaying a stuck outbox message
+// @Summary      Replay an outbox message
+// @Description  Reset the attempts of a pending or dead message and deliver it at once. The response shows the outcome; a message that fails again is retried by the dispatcher.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string  true   "Outbox message ID"
+// @Param        Idempotency-Key  header    string  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.OutboxMessageResponse  "Outbox message replayed"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Only admins may manage the outbox"
+// @Failure      404              {object}  handler.Problem  "Outbox message not found"
+// @Failure      409              {object}  handler.Problem  "Outbox message was already delivered"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /admin/outbox/{id}/replay [post]
+func (h *OutboxHandler) ReplayMessage(c *fiber.Ctx) error {
+	response, err := h.outboxUsecase.ReplayMessage(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
@@ -0,0 +1,75 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/handler"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOutboxEndpoints(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler, Immutable: true})
+	userRepo := repository.NewMemoryUserRepository()
+	app.Use(authenticate(userRepo))
+	app.Use(handler.Idempotency(handler.IdempotencyConfig{Store: repository.NewMemoryIdempotencyRepository()}))
+	outboxRepo := repository.NewMemoryOutboxRepository()
+	var failure error = apperror.Unprocessable("Malformed payload")
+	outbox := usecase.NewOutboxUsecase(outboxRepo, map[string]usecase.OutboxHandler{
+		"test": func(context.Context, *entity.OutboxMessage) error { return failure },
+	}, usecase.DefaultOutboxPolicy)
+	handler.NewOutboxHandler(outbox).RegisterRoutes(app)
+
+	message, err := entity.NewOutboxMessage("m1", "test", map[string]string{"order_id": "o1"})
+	if err != nil {
+		t.Fatalf("NewOutboxMessage: %v", err)
+	}
+	if err := outboxRepo.Enqueue(context.Background(), message); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err := outbox.DispatchDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+
+	_, admin := createUser(t, userRepo, entity.RoleAdmin, "+66822222222", "admin@example.com")
+	_, staff := createUser(t, userRepo, entity.RoleStaff, "+66811111111", "staff@example.com")
+	expectProblem(t, do(t, app, "GET", "/admin/outbox", nil), fiber.StatusUnauthorized)
+	expectProblem(t, do(t, app, "POST", "/admin/outbox/m1/replay", nil, staff...), fiber.StatusForbidden)
+
+	var list usecase.ListOutboxResponse
+	resp := do(t, app, "GET", "/admin/outbox?status=dead", nil, admin...)
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || list.Count != 1 || list.Messages[0].ID != "m1" || list.Messages[0].LastError == "" {
+		t.Fatalf("unexpected list: %d %+v", resp.StatusCode, list)
+	}
+	expectProblem(t, do(t, app, "GET", "/admin/outbox?status=stuck", nil, admin...), fiber.StatusBadRequest)
+
+	var got usecase.OutboxMessageResponse
+	resp = do(t, app, "GET", "/admin/outbox/m1", nil, admin...)
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if got.OutboxMessage.Status != entity.OutboxDead || string(got.OutboxMessage.Payload) != `{"order_id":"o1"}` {
+		t.Fatalf("unexpected message: %+v", got.OutboxMessage)
+	}
+	expectProblem(t, do(t, app, "GET", "/admin/outbox/missing", nil, admin...), fiber.StatusNotFound)
+
+	failure = nil
+	resp = do(t, app, "POST", "/admin/outbox/m1/replay", nil, admin...)
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || got.OutboxMessage.Status != entity.OutboxDelivered {
+		t.Fatalf("replay: unexpected response %d %+v", resp.StatusCode, got.OutboxMessage)
+	}
+	expectProblem(t, do(t, app, "POST", "/admin/outbox/m1/replay", nil, admin...), fiber.StatusConflict)
+	expectProblem(t, do(t, app, "POST", "/admin/outbox/missing/replay", nil, admin...), fiber.StatusNotFound)
+}
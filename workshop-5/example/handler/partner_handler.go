@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// PartnerHandler handles the server-to-server endpoints partners such as
+// merchants' POS systems call with a signed API key request. The routes
+// must sit behind RequireAPIKey.
+type PartnerHandler struct {
+	ledgerUsecase usecase.LedgerUsecase
+}
+
+// NewPartnerHandler creates a new partner handler
+func NewPartnerHandler(ledgerUsecase usecase.LedgerUsecase) *PartnerHandler {
+	return &PartnerHandler{
+		ledgerUsecase: ledgerUsecase,
+	}
+}
+
+// RegisterRoutes sets up the partner routes, each guarded by the scope it
+// needs
+func (h *PartnerHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/partner/users/:id/balance", RequireScope(entity.ScopePointsRead), h.GetBalance)
+	app.Post("/partner/users/:id/points", RequireScope(entity.ScopePointsAward), h.AwardPoints)
+}
+
+// GetBalance handles a partner reading a member's balance
+// @Summary      Get a member's balance as a partner
+// @Description  Return the member's points balance. Needs an API key with the points:read scope.
+// @Tags         partner
+// @Produce      json
+// @Security     PartnerAPIKey
+// @Param        id               path    string  true  "User ID"
+// @Param        X-API-Timestamp  header  string  true  "Unix seconds, within 5 minutes of the server clock"
+// @Param        X-API-Nonce      header  string  true  "Random string, never reused with the key"
+// @Param        X-API-Signature  header  string  true  "sha256=<hex HMAC-SHA256 of the canonical request>"
+// @Success      200  {object}  usecase.BalanceResponse  "Current balance"
+// @Failure      401  {object}  handler.Problem  "Invalid API key or signature, stale timestamp or reused nonce"
+// @Failure      403  {object}  handler.Problem  "API key lacks the points:read scope"
+// @Failure      404  {object}  handler.Problem  "User not found"
+// @Failure      429  {object}  handler.Problem  "API key rate limit exceeded; see Retry-After"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /partner/users/{id}/balance [get]
+func (h *PartnerHandler) GetBalance(c *fiber.Ctx) error {
+	response, err := h.ledgerUsecase.GetBalance(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// AwardPoints handles a partner awarding points to a member
+// @Summary      Award points as a partner
+// @Description  Post an earn entry for the member. Without a memo the entry is labelled with the API key's name. Needs an API key with the points:award scope. Send an Idempotency-Key so a retry, signed again with a new nonce, cannot award twice.
+// @Tags         partner
+// @Accept       json
+// @Produce      json
+// @Security     PartnerAPIKey
+// @Param        id               path    string  true   "User ID"
+// @Param        request          body    usecase.AwardPointsRequest  true  "Points to award"
+// @Param        X-API-Timestamp  header  string  true   "Unix seconds, within 5 minutes of the server clock"
+// @Param        X-API-Nonce      header  string  true   "Random string, never reused with the key"
+// @Param        X-API-Signature  header  string  true   "sha256=<hex HMAC-SHA256 of the canonical request>"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      201  {object}  usecase.LedgerEntryResponse  "Points awarded"
+// @Failure      400  {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401  {object}  handler.Problem  "Invalid API key or signature, stale timestamp or reused nonce"
+// @Failure      403  {object}  handler.Problem  "API key lacks the points:award scope"
+// @Failure      404  {object}  handler.Problem  "User not found"
+// @Failure      422  {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      429  {object}  handler.Problem  "API key rate limit exceeded; see Retry-After"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /partner/users/{id}/points [post]
+func (h *PartnerHandler) AwardPoints(c *fiber.Ctx) error {
+	var req usecase.AwardPointsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+	if req.Memo == "" {
+		req.Memo = CurrentAPIKey(c).Name
+	}
+
+	response, err := h.ledgerUsecase.PostEntry(c.UserContext(), c.Params("id"), usecase.PostEntryRequest{
+		Type:   string(entity.EntryEarn),
+		Amount: req.Amount,
+		Memo:   req.Memo,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
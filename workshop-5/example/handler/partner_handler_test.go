@@ -0,0 +1,157 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"example.com/mike/auth"
+	"example.com/mike/entity"
+	"example.com/mike/handler"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// signed returns the header pairs of a partner request signed with key
+// at, using a new nonce
+func signed(t *testing.T, key *usecase.APIKeySecretResponse, method, path string, body interface{}, at time.Time) []string {
+	t.Helper()
+	var raw []byte
+	if body != nil {
+		raw, _ = json.Marshal(body)
+	}
+	req := auth.SignedRequest{
+		Method:    method,
+		Path:      path,
+		Timestamp: strconv.FormatInt(at.Unix(), 10),
+		Nonce:     uuid.New().String(),
+		Body:      raw,
+	}
+	return []string{
+		auth.APIKeyHeader, key.Key.ID,
+		auth.RequestTimestampHeader, req.Timestamp,
+		auth.RequestNonceHeader, req.Nonce,
+		auth.RequestSignatureHeader, auth.SignRequest([]byte(key.Secret), req),
+	}
+}
+
+// doSigned sends a partner request signed with key
+func doSigned(t *testing.T, app *fiber.App, key *usecase.APIKeySecretResponse, method, path string, body interface{}, headers ...string) *http.Response {
+	t.Helper()
+	return do(t, app, method, path, body, append(signed(t, key, method, path, body, time.Now()), headers...)...)
+}
+
+func TestPartnerAwardsPoints(t *testing.T) {
+	app, users := setupAppWithUsers()
+	_, admin := createUser(t, users, entity.RoleAdmin, "+66822222222", "admin@example.com")
+	member, asMember := registerAndAuthorize(t, app, "+66810000051", "partner@example.com")
+	pos := createAPIKey(t, app, admin, usecase.CreateAPIKeyRequest{
+		Name:   "Lumpini POS",
+		Scopes: []entity.APIScope{entity.ScopePointsRead, entity.ScopePointsAward},
+	})
+	path := "/partner/users/" + member + "/points"
+
+	resp := doSigned(t, app, pos, "POST", path, usecase.AwardPointsRequest{Amount: 120})
+	var awarded usecase.LedgerEntryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&awarded); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusCreated || awarded.Balance != 120 ||
+		awarded.Entry.Type != entity.EntryEarn || awarded.Entry.Memo != "Lumpini POS" {
+		t.Fatalf("unexpected award: %d %+v", resp.StatusCode, awarded)
+	}
+
+	var balance usecase.BalanceResponse
+	resp = doSigned(t, app, pos, "GET", "/partner/users/"+member+"/balance", nil)
+	if err := json.NewDecoder(resp.Body).Decode(&balance); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || balance.Balance != 120 {
+		t.Fatalf("unexpected balance: %d %+v", resp.StatusCode, balance)
+	}
+
+	expectProblem(t, doSigned(t, app, pos, "POST", path, usecase.AwardPointsRequest{}), fiber.StatusBadRequest)
+	expectProblem(t, doSigned(t, app, pos, "POST", "/partner/users/missing/points", usecase.AwardPointsRequest{Amount: 1}), fiber.StatusNotFound)
+
+	// A retry signed again with the same Idempotency-Key awards once
+	idempotent := []string{handler.HeaderIdempotencyKey, "sale-1"}
+	doSigned(t, app, pos, "POST", path, usecase.AwardPointsRequest{Amount: 10}, idempotent...)
+	retry := doSigned(t, app, pos, "POST", path, usecase.AwardPointsRequest{Amount: 10}, idempotent...)
+	if retry.Header.Get(handler.HeaderIdempotentReplayed) != "true" {
+		t.Fatal("expected the retried award to be replayed")
+	}
+
+	// Access tokens do not work on partner routes
+	expectProblem(t, do(t, app, "GET", "/partner/users/"+member+"/balance", nil, asMember...), fiber.StatusUnauthorized)
+}
+
+func TestPartnerRequestSigning(t *testing.T) {
+	app, users := setupAppWithUsers()
+	_, admin := createUser(t, users, entity.RoleAdmin, "+66822222222", "admin@example.com")
+	member, _ := registerAndAuthorize(t, app, "+66810000052", "signing@example.com")
+	reader := createAPIKey(t, app, admin, usecase.CreateAPIKeyRequest{Name: "Reader", Scopes: []entity.APIScope{entity.ScopePointsRead}})
+	path := "/partner/users/" + member + "/points"
+	body := usecase.AwardPointsRequest{Amount: 100}
+
+	// Replaying a captured request is refused
+	headers := signed(t, reader, "GET", "/partner/users/"+member+"/balance", nil, time.Now())
+	if resp := do(t, app, "GET", "/partner/users/"+member+"/balance", nil, headers...); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("first request: expected 200, got %d", resp.StatusCode)
+	}
+	expectProblem(t, do(t, app, "GET", "/partner/users/"+member+"/balance", nil, headers...), fiber.StatusUnauthorized)
+
+	// Missing scopes are forbidden
+	expectProblem(t, doSigned(t, app, reader, "POST", path, body), fiber.StatusForbidden)
+
+	writer := createAPIKey(t, app, admin, usecase.CreateAPIKeyRequest{Name: "Writer", Scopes: []entity.APIScope{entity.ScopePointsAward}})
+	tampered := signed(t, writer, "POST", path, usecase.AwardPointsRequest{Amount: 1}, time.Now())
+	stale := signed(t, writer, "POST", path, body, time.Now().Add(-auth.MaxRequestAge-time.Minute))
+	otherPath := signed(t, writer, "POST", "/partner/users/other/points", body, time.Now())
+
+	tests := []struct {
+		name    string
+		headers []string
+	}{
+		{"unsigned", nil},
+		{"tampered body", tampered},
+		{"stale timestamp", stale},
+		{"signed for another path", otherPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectProblem(t, do(t, app, "POST", path, body, tt.headers...), fiber.StatusUnauthorized)
+		})
+	}
+
+	// Revoked keys stop working at once
+	do(t, app, "POST", "/admin/api-keys/"+writer.Key.ID+"/revoke", nil, admin...)
+	expectProblem(t, doSigned(t, app, writer, "POST", path, body), fiber.StatusUnauthorized)
+}
+
+func TestPartnerRateLimit(t *testing.T) {
+	app, users := setupAppWithUsers()
+	_, admin := createUser(t, users, entity.RoleAdmin, "+66822222222", "admin@example.com")
+	member, _ := registerAndAuthorize(t, app, "+66810000053", "limit@example.com")
+	limited := createAPIKey(t, app, admin, usecase.CreateAPIKeyRequest{Name: "POS", Scopes: []entity.APIScope{entity.ScopePointsRead}, RateLimit: 2})
+	other := createAPIKey(t, app, admin, usecase.CreateAPIKeyRequest{Name: "Other", Scopes: []entity.APIScope{entity.ScopePointsRead}, RateLimit: 2})
+	path := "/partner/users/" + member + "/balance"
+
+	for i := 0; i < 2; i++ {
+		if resp := doSigned(t, app, limited, "GET", path, nil); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, resp.StatusCode)
+		}
+	}
+	resp := doSigned(t, app, limited, "GET", path, nil)
+	expectProblem(t, resp, fiber.StatusTooManyRequests)
+	if resp.Header.Get(fiber.HeaderRetryAfter) != "30" || resp.Header.Get(handler.HeaderRateLimitLimit) != "2" {
+		t.Fatalf("refused request headers: %v", resp.Header)
+	}
+
+	// Each key has its own limit
+	if resp := doSigned(t, app, other, "GET", path, nil); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("other key: expected 200, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"log"
+
+	"example.com/mike/apperror"
+	"github.com/gofiber/fiber/v2"
+)
+
+// MIMEProblemJSON is the media type of RFC 7807 problem details
+const MIMEProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details response body
+type Problem struct {
+	// Type is a URI reference identifying the problem type
+	Type string `json:"type" example:"/problems/validation-error"`
+
+	// Title is a short, human-readable summary of the problem type
+	Title string `json:"title" example:"Validation Error"`
+
+	// Status is the HTTP status code
+	Status int `json:"status" example:"400"`
+
+	// Detail explains this occurrence of the problem
+	Detail string `json:"detail,omitempty" example:"Invalid registration data"`
+
+	// Instance is the request path that produced the problem
+	Instance string `json:"instance,omitempty" example:"/register"`
+
+	// Errors lists per-field validation problems
+	Errors []apperror.FieldError `json:"errors,omitempty"`
+}
+
+// problemTypes maps each error kind to its problem type URI and title
+var problemTypes = map[apperror.Kind]struct {
+	status int
+	uri    string
+	title  string
+}{
+	apperror.KindNotFound:        {fiber.StatusNotFound, "/problems/not-found", "Not Found"},
+	apperror.KindConflict:        {fiber.StatusConflict, "/problems/conflict", "Conflict"},
+	apperror.KindValidation:      {fiber.StatusBadRequest, "/problems/validation-error", "Validation Error"},
+	apperror.KindUnprocessable:   {fiber.StatusUnprocessableEntity, "/problems/unprocessable", "Unprocessable Entity"},
+	apperror.KindUnauthorized:    {fiber.StatusUnauthorized, "/problems/unauthorized", "Unauthorized"},
+	apperror.KindForbidden:       {fiber.StatusForbidden, "/problems/forbidden", "Forbidden"},
+	apperror.KindTooManyRequests: {fiber.StatusTooManyRequests, "/problems/too-many-requests", "Too Many Requests"},
+	apperror.KindUnavailable:     {fiber.StatusServiceUnavailable, "/problems/unavailable", "Service Unavailable"},
+	apperror.KindInternal:        {fiber.StatusInternalServerError, "/problems/internal-error", "Internal Server Error"},
+}
+
+// NewProblem converts err into problem details for the current request.
+// Internal errors are hidden behind a generic detail message.
+func NewProblem(c *fiber.Ctx, err error) Problem {
+	// Errors raised by Fiber itself, e.g. unknown routes or bad methods
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return Problem{
+			Type:     "about:blank",
+			Title:    fiberErr.Message,
+			Status:   fiberErr.Code,
+			Instance: c.Path(),
+		}
+	}
+
+	kind := apperror.KindOf(err)
+	pt := problemTypes[kind]
+	problem := Problem{
+		Type:     pt.uri,
+		Title:    pt.title,
+		Status:   pt.status,
+		Detail:   "An unexpected error occurred",
+		Instance: c.Path(),
+	}
+
+	var appErr *apperror.Error
+	if kind != apperror.KindInternal && errors.As(err, &appErr) {
+		problem.Detail = appErr.Message
+		problem.Errors = appErr.Fields
+	}
+	return problem
+}
+
+// ErrorHandler is the Fiber error handler shared by every route. Handlers
+// return domain errors and this writes them as application/problem+json.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	problem := NewProblem(c, err)
+	if problem.Status >= fiber.StatusInternalServerError {
+		log.Printf("%s %s: %v", c.Method(), c.Path(), err)
+	}
+
+	c.Status(problem.Status)
+	return c.JSON(problem, MIMEProblemJSON)
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"strings"
+
+	"example.com/mike/apperror"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ProductHandler handles product catalog HTTP requests
+type ProductHandler struct {
+	productUsecase usecase.ProductUsecase
+}
+
+// NewProductHandler creates a new product catalog handler
+func NewProductHandler(productUsecase usecase.ProductUsecase) *ProductHandler {
+	return &ProductHandler{
+		productUsecase: productUsecase,
+	}
+}
+
+// RegisterRoutes sets up the public catalog and the admin product routes,
+// which admins use to keep the catalog and stock up to date
+func (h *ProductHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/products", h.ListProducts)
+	app.Get("/products/:id", h.GetProduct)
+
+	manageProducts := Authorize(usecase.OpManageProducts, "")
+	app.Post("/admin/products", manageProducts, h.CreateProduct)
+	app.Get("/admin/products", manageProducts, h.AdminListProducts)
+	app.Get("/admin/products/:id", manageProducts, h.AdminGetProduct)
+	app.Put("/admin/products/:id", manageProducts, h.UpdateProduct)
+	app.Patch("/admin/products/:id", manageProducts, h.PatchProduct)
+	app.Delete("/admin/products/:id", manageProducts, h.DeleteProduct)
+}
+
+// ListProducts handles listing the public catalog
+// @Summary      List products
+// @Description  Retrieve a page of active products ordered by name. Pass next_cursor from the previous page as cursor to continue.
+// @Tags         products
+// @Produce      json
+// @Param        category  query     string  false  "Category"
+// @Param        q         query     string  false  "Search the name and description"
+// @Param        limit     query     int     false  "Page size (1-100, default 20)"
+// @Param        cursor    query     string  false  "Cursor from the previous page"
+// @Success      200       {object}  usecase.ListProductsResponse  "Page of products"
+// @Failure      400       {object}  handler.Problem  "Invalid list parameters"
+// @Failure      500       {object}  handler.Problem  "Internal server error"
+// @Router       /products [get]
+func (h *ProductHandler) ListProducts(c *fiber.Ctx) error {
+	return h.listProducts(c, false)
+}
+
+// GetProduct handles getting a product from the public catalog
+// @Summary      Get a product
+// @Description  Retrieve an active product by ID
+// @Tags         products
+// @Produce      json
+// @Param        id   path      string  true  "Product ID"
+// @Success      200  {object}  usecase.ProductResponse  "Product found"
+// @Failure      404  {object}  handler.Problem  "Product not found or inactive"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /products/{id} [get]
+func (h *ProductHandler) GetProduct(c *fiber.Ctx) error {
+	return h.getProduct(c, false)
+}
+
+// CreateProduct handles adding a product
+// @Summary      Create a product
+// @Description  Add a product to the catalog. The category is stored in lower case; active defaults to true.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request          body      usecase.ProductRequest  true   "Product data"
+// @Param        Idempotency-Key  header    string                  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      201              {object}  usecase.ProductResponse  "Product created"
+// @Failure      400              {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Only admins may manage products"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /admin/products [post]
+func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
+	req, err := usecase.DecodeProductRequest(c.Body())
+	if err != nil {
+		return err
+	}
+
+	response, err := h.productUsecase.CreateProduct(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// AdminListProducts handles listing every product
+// @Summary      List all products
+// @Description  Retrieve a page of products ordered by name, inactive ones included
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        category  query     string  false  "Category"
+// @Param        q         query     string  false  "Search the name and description"
+// @Param        limit     query     int     false  "Page size (1-100, default 20)"
+// @Param        cursor    query     string  false  "Cursor from the previous page"
+// @Success      200       {object}  usecase.ListProductsResponse  "Page of products"
+// @Failure      400       {object}  handler.Problem  "Invalid list parameters"
+// @Failure      401       {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403       {object}  handler.Problem  "Only admins may manage products"
+// @Failure      500       {object}  handler.Problem  "Internal server error"
+// @Router       /admin/products [get]
+func (h *ProductHandler) AdminListProducts(c *fiber.Ctx) error {
+	return h.listProducts(c, true)
+}
+
+// AdminGetProduct handles getting any product
+// @Summary      Get any product
+// @Description  Retrieve a product by ID, active or not
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Product ID"
+// @Success      200  {object}  usecase.ProductResponse  "Product found"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Only admins may manage products"
+// @Failure      404  {object}  handler.Problem  "Product not found"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /admin/products/{id} [get]
+func (h *ProductHandler) AdminGetProduct(c *fiber.Ctx) error {
+	return h.getProduct(c, true)
+}
+
+// UpdateProduct handles replacing a product's fields
+// @Summary      Update a product
+// @Description  Replace the name, description, category, price and stock of a product. active is left unchanged when omitted.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string                  true   "Product ID"
+// @Param        request          body      usecase.ProductRequest  true   "Product data"
+// @Param        Idempotency-Key  header    string                  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.ProductResponse  "Product updated"
+// @Failure      400              {object}  handler.Problem  "Invalid request format or validation error"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Only admins may manage products"
+// @Failure      404              {object}  handler.Problem  "Product not found"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /admin/products/{id} [put]
+func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
+	req, err := usecase.DecodeProductRequest(c.Body())
+	if err != nil {
+		return err
+	}
+
+	response, err := h.productUsecase.UpdateProduct(c.UserContext(), c.Params("id"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// PatchProduct handles partial product updates
+// @Summary      Partially update a product
+// @Description  Apply an RFC 7396 JSON Merge Patch to a product, e.g. to restock it or take it off sale. The same validation as PUT applies.
+// @Tags         admin
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id               path      string                  true   "Product ID"
+// @Param        patch            body      map[string]interface{}  true   "Merge patch, e.g. {\"stock\": 25, \"active\": false}"
+// @Param        Idempotency-Key  header    string                  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.ProductResponse  "Product updated"
+// @Failure      400              {object}  handler.Problem  "Invalid patch or validation error"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Only admins may manage products"
+// @Failure      404              {object}  handler.Problem  "Product not found"
+// @Failure      415              {object}  handler.Problem  "Unsupported content type"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /admin/products/{id} [patch]
+func (h *ProductHandler) PatchProduct(c *fiber.Ctx) error {
+	if !c.Is("json") && !strings.HasPrefix(c.Get(fiber.HeaderContentType), MIMEMergePatchJSON) {
+		return fiber.ErrUnsupportedMediaType
+	}
+
+	response, err := h.productUsecase.PatchProduct(c.UserContext(), c.Params("id"), c.Body())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// DeleteProduct handles removing a product
+// @Summary      Delete a product
+// @Description  Remove a product from the catalog. To take it off sale but keep it, set active to false instead.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        id               path  string  true   "Product ID"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      204  "Product deleted"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Only admins may manage products"
+// @Failure      404  {object}  handler.Problem  "Product not found"
+// @Failure      422  {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /admin/products/{id} [delete]
+func (h *ProductHandler) DeleteProduct(c *fiber.Ctx) error {
+	if err := h.productUsecase.DeleteProduct(c.UserContext(), c.Params("id")); err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// listProducts serves a product listing, with or without inactive products
+func (h *ProductHandler) listProducts(c *fiber.Ctx, includeInactive bool) error {
+	var req usecase.ListProductsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return apperror.Validation("Invalid query parameters")
+	}
+
+	response, err := h.productUsecase.ListProducts(c.UserContext(), req, includeInactive)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// getProduct serves a single product, with or without inactive products
+func (h *ProductHandler) getProduct(c *fiber.Ctx, includeInactive bool) error {
+	response, err := h.productUsecase.GetProduct(c.UserContext(), c.Params("id"), includeInactive)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
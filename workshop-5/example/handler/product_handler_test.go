@@ -0,0 +1,82 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestProductEndpoints(t *testing.T) {
+	app, users := setupAppWithUsers()
+	_, admin := createUser(t, users, entity.RoleAdmin, "+66811111110", "admin@example.com")
+	_, staff := createUser(t, users, entity.RoleStaff, "+66811111111", "staff@example.com")
+	_, asMember := registerAndAuthorize(t, app, "+66812345678", "john.doe@example.com")
+
+	resp := do(t, app, "POST", "/admin/products", usecase.ProductRequest{Name: "Iced Latte", Category: "Drinks", PricePoints: 120, Stock: 40}, admin...)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("create: expected 201, got %d", resp.StatusCode)
+	}
+	var created usecase.ProductResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	id := created.Product.ID
+	do(t, app, "POST", "/admin/products", usecase.ProductRequest{Name: "Chocolate Cake", Category: "bakery", PricePoints: 90, Stock: 5}, admin...)
+
+	expectProblem(t, do(t, app, "POST", "/admin/products", `{"name": "X", "category": "x", "price_points": 1, "colour": "red"}`, admin...), fiber.StatusBadRequest)
+	expectProblem(t, do(t, app, "POST", "/admin/products", usecase.ProductRequest{Name: "Free"}, admin...), fiber.StatusBadRequest)
+
+	// Only admins manage the catalog; staff sell from it
+	for _, caller := range [][]string{asMember, staff} {
+		expectProblem(t, do(t, app, "POST", "/admin/products", usecase.ProductRequest{Name: "Free", Category: "x", PricePoints: 1}, caller...), fiber.StatusForbidden)
+	}
+	expectProblem(t, do(t, app, "PATCH", "/admin/products/"+id, usecase.ProductRequest{Stock: 99}, staff...), fiber.StatusForbidden)
+	expectProblem(t, do(t, app, "DELETE", "/admin/products/"+id, nil, staff...), fiber.StatusForbidden)
+
+	var list usecase.ListProductsResponse
+	if err := json.NewDecoder(do(t, app, "GET", "/products?category=drinks&q=latte", nil).Body).Decode(&list); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if list.Count != 1 || list.Products[0].ID != id {
+		t.Fatalf("unexpected listing: %+v", list)
+	}
+
+	// Taking the product off sale hides it from the public catalog only
+	req := httptest.NewRequest("PATCH", "/admin/products/"+id, strings.NewReader(`{"active": false}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set(admin[0], admin[1])
+	if resp, err := app.Test(req); err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("patch: %v, %v", resp, err)
+	}
+	expectProblem(t, do(t, app, "GET", "/products/"+id, nil), fiber.StatusNotFound)
+	if resp := do(t, app, "GET", "/admin/products/"+id, nil, admin...); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("admin get: expected 200, got %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(do(t, app, "GET", "/products", nil).Body).Decode(&list); err != nil || list.Count != 1 {
+		t.Fatalf("public listing after deactivation = %+v, %v", list, err)
+	}
+	if err := json.NewDecoder(do(t, app, "GET", "/admin/products?limit=1", nil, admin...).Body).Decode(&list); err != nil || list.Count != 1 || list.NextCursor == "" {
+		t.Fatalf("admin listing = %+v, %v", list, err)
+	}
+	expectProblem(t, do(t, app, "GET", "/products?limit=0&cursor=bad", nil), fiber.StatusBadRequest)
+
+	resp = do(t, app, "PUT", "/admin/products/"+id, usecase.ProductRequest{Name: "Iced Latte", Category: "drinks", PricePoints: 150, Stock: 10}, admin...)
+	var updated usecase.ProductResponse
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if updated.Product.PricePoints != 150 || updated.Product.Active {
+		t.Fatalf("unexpected product after PUT: %+v", updated.Product)
+	}
+
+	if resp := do(t, app, "DELETE", "/admin/products/"+id, nil, admin...); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", resp.StatusCode)
+	}
+	expectProblem(t, do(t, app, "GET", "/admin/products/"+id, nil, admin...), fiber.StatusNotFound)
+	expectProblem(t, do(t, app, "DELETE", "/admin/products/"+id, nil, admin...), fiber.StatusNotFound)
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// QR code image sizes in pixels
+const (
+	DefaultQRCodeSize = 256
+	MinQRCodeSize     = 128
+	MaxQRCodeSize     = 1024
+)
+
+// ErrQRRequestNotPayable is returned when asking for the QR code of a
+// request that is already paid or expired
+var ErrQRRequestNotPayable = apperror.Conflict("QR request is no longer payable")
+
+// QRHandler handles QR payment request HTTP requests
+type QRHandler struct {
+	qrUsecase usecase.QRUsecase
+}
+
+// NewQRHandler creates a new QR payment request handler
+func NewQRHandler(qrUsecase usecase.QRUsecase) *QRHandler {
+	return &QRHandler{
+		qrUsecase: qrUsecase,
+	}
+}
+
+// RegisterRoutes sets up the QR payment request routes. Members request
+// payments to, and pay from, their own account; any signed-in user may
+// look up a request to pay it.
+func (h *QRHandler) RegisterRoutes(app *fiber.App) {
+	app.Post("/qr-requests", RequireUser, h.CreateRequest)
+	app.Post("/qr-requests/pay", RequireUser, h.PayRequest)
+	app.Get("/qr-requests/:id", Authorize(usecase.OpGetQRRequest, ""), h.GetRequest)
+	app.Get("/qr-requests/:id/qr.png", Authorize(usecase.OpGetQRRequest, ""), h.GetQRCode)
+}
+
+// CreateRequest handles creating a QR payment request
+// @Summary      Create a QR payment request
+// @Description  Request a payment of points to the recipient. The response carries the payload to show as a QR code; the PNG is served at /qr-requests/{id}/qr.png. Requests expire after expires_in seconds (default 900, at most 86400).
+// @Tags         qr
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request          body      usecase.CreateQRRequest  true   "Payment request"
+// @Param        Idempotency-Key  header    string                   false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      201              {object}  usecase.QRRequestResponse  "QR request created"
+// @Failure      400              {object}  handler.Problem  "Invalid request format, validation error or unknown recipient"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Payments can only be requested to your own account"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      429              {object}  handler.Problem  "Too many QR requests; see Retry-After"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /qr-requests [post]
+func (h *QRHandler) CreateRequest(c *fiber.Ctx) error {
+	var req usecase.CreateQRRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+	if err := authorize(c, usecase.OpCreateQRRequest, req.RecipientID); err != nil {
+		return err
+	}
+
+	response, err := h.qrUsecase.CreateRequest(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetRequest handles getting a QR payment request
+// @Summary      Get a QR payment request
+// @Description  Retrieve a payment request and its status (pending, paid or expired). The payload is only returned while the request is pending.
+// @Tags         qr
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "QR request ID"
+// @Success      200  {object}  usecase.QRRequestResponse  "QR request found"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      404  {object}  handler.Problem  "QR request not found"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /qr-requests/{id} [get]
+func (h *QRHandler) GetRequest(c *fiber.Ctx) error {
+	response, err := h.qrUsecase.GetRequest(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// GetQRCode handles rendering a pending request as a QR code
+// @Summary      Get the QR code of a payment request
+// @Description  Render the payload of a pending payment request as a PNG QR code
+// @Tags         qr
+// @Produce      png
+// @Security     BearerAuth
+// @Param        id    path      string  true   "QR request ID"
+// @Param        size  query     int     false  "Image size in pixels (128-1024, default 256)"
+// @Success      200   {file}    binary  "PNG image"
+// @Failure      400   {object}  handler.Problem  "Invalid size"
+// @Failure      401   {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      404   {object}  handler.Problem  "QR request not found"
+// @Failure      409   {object}  handler.Problem  "QR request already paid or expired"
+// @Failure      429   {object}  handler.Problem  "Too many QR codes; see Retry-After"
+// @Failure      500   {object}  handler.Problem  "Internal server error"
+// @Router       /qr-requests/{id}/qr.png [get]
+func (h *QRHandler) GetQRCode(c *fiber.Ctx) error {
+	size := c.QueryInt("size", DefaultQRCodeSize)
+	if size < MinQRCodeSize || size > MaxQRCodeSize {
+		return apperror.Validation("Invalid query parameters",
+			apperror.FieldError{Field: "size", Message: "size must be between 128 and 1024"})
+	}
+
+	response, err := h.qrUsecase.GetRequest(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	if response.Request.Status != entity.QRRequestPending {
+		return ErrQRRequestNotPayable
+	}
+
+	png, err := qrcode.Encode(response.Payload, qrcode.Medium, size)
+	if err != nil {
+		return apperror.Internal("Failed to render QR code", err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Type("png")
+	return c.Send(png)
+}
+
+// PayRequest handles paying a QR payment request from a scanned payload
+// @Summary      Pay a QR payment request
+// @Description  Settle a pending payment request from the payload scanned off its QR code. The payload's signature and expiry are verified and it must match the stored request; the requested points are then transferred from the payer to the recipient. A payload pays at most once; transfer limits and the payer's balance apply.
+// @Tags         qr
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request          body      usecase.PayQRRequest  true   "Scanned payload and payer"
+// @Param        Idempotency-Key  header    string                false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      200              {object}  usecase.QRRequestResponse  "QR request paid"
+// @Failure      400              {object}  handler.Problem  "Invalid request format, validation error, or a malformed, forged or mismatched payload"
+// @Failure      401              {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403              {object}  handler.Problem  "Payments can only be made from your own account"
+// @Failure      404              {object}  handler.Problem  "QR request or payer not found"
+// @Failure      409              {object}  handler.Problem  "Already paid (replayed payload), expired, insufficient balance or daily limit exceeded"
+// @Failure      422              {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      429              {object}  handler.Problem  "Too many transfers; see Retry-After"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /qr-requests/pay [post]
+func (h *QRHandler) PayRequest(c *fiber.Ctx) error {
+	var req usecase.PayQRRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+	if err := authorize(c, usecase.OpPayQRRequest, req.PayerID); err != nil {
+		return err
+	}
+
+	response, err := h.qrUsecase.PayRequest(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
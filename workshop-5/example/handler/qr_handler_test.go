@@ -0,0 +1,83 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"image/png"
+	"strings"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestQREndpoints(t *testing.T) {
+	app, users := setupAppWithUsers()
+
+	shop, asShop := registerAndAuthorize(t, app, "+66810000020", "shop@example.com")
+	buyer, asBuyer := registerAndAuthorize(t, app, "+66810000021", "buyer@example.com")
+	_, staff := createUser(t, users, entity.RoleStaff, "+66811111111", "staff@example.com")
+	do(t, app, "POST", "/user/"+buyer+"/ledger", usecase.PostEntryRequest{Type: "earn", Amount: 1000}, staff...)
+
+	// Members only request points for themselves and pay from their own account
+	expectProblem(t, do(t, app, "POST", "/qr-requests", usecase.CreateQRRequest{RecipientID: shop, Amount: 250}), fiber.StatusUnauthorized)
+	expectProblem(t, do(t, app, "POST", "/qr-requests", usecase.CreateQRRequest{RecipientID: shop, Amount: 250}, asBuyer...), fiber.StatusForbidden)
+
+	resp := do(t, app, "POST", "/qr-requests", usecase.CreateQRRequest{RecipientID: shop, Amount: 250, Memo: "Coffee"}, asShop...)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var created usecase.QRRequestResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	base := "/qr-requests/" + created.Request.ID
+
+	resp = do(t, app, "GET", base+"/qr.png?size=200", nil, asBuyer...)
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("QR code: status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	img, err := png.Decode(resp.Body)
+	if err != nil {
+		t.Fatalf("QR code is not a PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 200 {
+		t.Fatalf("QR code size = %v, want 200x200", b)
+	}
+	expectProblem(t, do(t, app, "GET", base+"/qr.png?size=10", nil, asBuyer...), fiber.StatusBadRequest)
+
+	// A payload edited to lower the amount fails verification
+	tampered := strings.Replace(created.Payload, ".6y.", ".1.", 1)
+	problem := expectProblem(t, do(t, app, "POST", "/qr-requests/pay", usecase.PayQRRequest{Payload: tampered, PayerID: buyer}, asBuyer...), fiber.StatusBadRequest)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "payload" {
+		t.Fatalf("expected a payload field error, got %+v", problem)
+	}
+
+	pay := usecase.PayQRRequest{Payload: created.Payload, PayerID: buyer}
+	expectProblem(t, do(t, app, "POST", "/qr-requests/pay", pay, asShop...), fiber.StatusForbidden)
+	resp = do(t, app, "POST", "/qr-requests/pay", pay, asBuyer...)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("pay: expected 200, got %d", resp.StatusCode)
+	}
+	var paid usecase.QRRequestResponse
+	if err := json.NewDecoder(resp.Body).Decode(&paid); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if paid.Request.Status != entity.QRRequestPaid || paid.Transfer == nil || paid.Balance != 750 {
+		t.Fatalf("unexpected payment response: %+v", paid)
+	}
+
+	expectProblem(t, do(t, app, "POST", "/qr-requests/pay", pay, asBuyer...), fiber.StatusConflict)
+	expectProblem(t, do(t, app, "GET", base+"/qr.png", nil, asShop...), fiber.StatusConflict)
+	expectProblem(t, do(t, app, "POST", "/qr-requests", usecase.CreateQRRequest{RecipientID: "missing", Amount: 1}, staff...), fiber.StatusForbidden)
+	expectProblem(t, do(t, app, "GET", "/qr-requests/missing", nil, asShop...), fiber.StatusNotFound)
+
+	resp = do(t, app, "GET", base, nil, asShop...)
+	var got usecase.QRRequestResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if got.Request.Status != entity.QRRequestPaid || got.Request.TransferID != paid.Transfer.ID {
+		t.Fatalf("unexpected request: %+v", got.Request)
+	}
+}
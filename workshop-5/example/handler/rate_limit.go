@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+	"example.com/mike/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Rate limit response headers, from the IETF RateLimit header fields
+// draft. RateLimit-Reset and Retry-After are in seconds.
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+)
+
+// ErrRateLimited is returned when a caller's bucket is empty
+var ErrRateLimited = apperror.TooManyRequests("Too many requests; retry after the number of seconds in Retry-After")
+
+// RateLimitConfig configures the rate limit of a route
+type RateLimitConfig struct {
+	// Store keeps the token buckets
+	Store repository.RateLimitRepository
+
+	// Name separates the buckets of this route from other routes limited
+	// by the same caller
+	Name string
+
+	// Limit is the route's policy; the zero RateLimit lets every request
+	// through
+	Limit entity.RateLimit
+
+	// Caller picks the bucket of a request. Defaults to the authenticated
+	// user or partner API key, or the client IP for anonymous requests;
+	// see ClientIP to limit by IP only.
+	Caller func(c *fiber.Ctx) string
+}
+
+// RateLimit limits a route with a token bucket per caller. Every response
+// carries the RateLimit-* headers; requests finding the bucket empty are
+// rejected with 429 and a Retry-After header. Mount it after Authenticate
+// and before Idempotency, so retries count towards the limit and refused
+// requests are not stored as the key's response.
+func RateLimit(config RateLimitConfig) fiber.Handler {
+	if config.Caller == nil {
+		config.Caller = defaultCaller
+	}
+
+	return func(c *fiber.Ctx) error {
+		if !config.Limit.Enabled() {
+			return c.Next()
+		}
+		if err := takeToken(c, config.Store, config.Name+":"+config.Caller(c), config.Limit); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
+
+// ClientIP identifies the caller by client IP, for routes such as
+// registration where everyone is anonymous
+func ClientIP(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// otpDestination identifies the caller of POST /auth/otp by the phone
+// number or email the code is sent to, so one inbox cannot be flooded from
+// many addresses. Requests without a destination fall back to ClientIP;
+// they fail validation anyway.
+func otpDestination(c *fiber.Ctx) string {
+	var req usecase.RequestCodeRequest
+	if err := c.BodyParser(&req); err == nil {
+		if phone, err := validation.NormalizePhone(strings.TrimSpace(req.Phone)); err == nil {
+			return "phone:" + phone
+		}
+		if email := strings.TrimSpace(req.Email); email != "" {
+			return "email:" + strings.ToLower(email)
+		}
+	}
+	return ClientIP(c)
+}
+
+// RateLimits holds the policy of every rate-limited user route
+type RateLimits struct {
+	// Register limits POST /register per client IP
+	Register entity.RateLimit
+
+	// LoginCodes limits sending login codes, POST /auth/otp, per client
+	// IP
+	LoginCodes entity.RateLimit
+
+	// LoginCodeDestinations limits POST /auth/otp per phone number or
+	// email, wherever the requests come from
+	LoginCodeDestinations entity.RateLimit
+
+	// LoginAttempts limits POST /auth/otp/verify per client IP. Every
+	// challenge allows a few tries, so this bounds guessing across the
+	// challenges a caller keeps requesting.
+	LoginAttempts entity.RateLimit
+
+	// Transfers limits POST /transfers and QR payments, POST
+	// /qr-requests/pay, per user. Both post a transfer, so they share one
+	// bucket.
+	Transfers entity.RateLimit
+
+	// QRRequests limits QR generation, POST /qr-requests, per user
+	QRRequests entity.RateLimit
+
+	// QRCodes limits rendering QR code images, GET
+	// /qr-requests/:id/qr.png, per user
+	QRCodes entity.RateLimit
+}
+
+// DefaultRateLimits are the rate limits used by the server
+var DefaultRateLimits = RateLimits{
+	Register:              entity.RateLimit{Limit: 20, Period: time.Hour},
+	LoginCodes:            entity.RateLimit{Limit: 20, Period: time.Hour},
+	LoginCodeDestinations: entity.RateLimit{Limit: 5, Period: time.Hour},
+	LoginAttempts:         entity.RateLimit{Limit: 30, Period: time.Hour},
+	Transfers:             entity.RateLimit{Limit: 10, Period: time.Minute},
+	QRRequests:            entity.RateLimit{Limit: 20, Period: time.Minute},
+	QRCodes:               entity.RateLimit{Limit: 60, Period: time.Minute},
+}
+
+// Mount registers the limits on their routes. Fiber runs the handlers of
+// a route in registration order, so Mount must be called after
+// Authenticate and before Idempotency and the routes themselves.
+func (l RateLimits) Mount(app *fiber.App, store repository.RateLimitRepository) {
+	app.Post("/register", RateLimit(RateLimitConfig{Store: store, Name: "register", Limit: l.Register, Caller: ClientIP}))
+	app.Post("/auth/otp",
+		RateLimit(RateLimitConfig{Store: store, Name: "login-codes", Limit: l.LoginCodes, Caller: ClientIP}),
+		RateLimit(RateLimitConfig{Store: store, Name: "login-code-destinations", Limit: l.LoginCodeDestinations, Caller: otpDestination}))
+	app.Post("/auth/otp/verify", RateLimit(RateLimitConfig{Store: store, Name: "login-attempts", Limit: l.LoginAttempts, Caller: ClientIP}))
+	transfers := RateLimit(RateLimitConfig{Store: store, Name: "transfers", Limit: l.Transfers})
+	app.Post("/transfers", transfers)
+	app.Post("/qr-requests/pay", transfers)
+	app.Post("/qr-requests", RateLimit(RateLimitConfig{Store: store, Name: "qr-requests", Limit: l.QRRequests}))
+	app.Get("/qr-requests/:id/qr.png", RateLimit(RateLimitConfig{Store: store, Name: "qr-codes", Limit: l.QRCodes}))
+}
+
+// takeToken takes a token from bucket key and sets the rate limit
+// headers, returning ErrRateLimited when the bucket is empty
+func takeToken(c *fiber.Ctx, store repository.RateLimitRepository, key string, limit entity.RateLimit) error {
+	decision, err := store.Take(key, limit, time.Now())
+	if err != nil {
+		return apperror.Internal("Failed to check rate limit", err)
+	}
+
+	c.Set(HeaderRateLimitLimit, strconv.Itoa(decision.Limit))
+	c.Set(HeaderRateLimitRemaining, strconv.Itoa(decision.Remaining))
+	c.Set(HeaderRateLimitReset, strconv.Itoa(seconds(decision.Reset)))
+	c.Set(HeaderRateLimitPolicy, strconv.Itoa(limit.Limit)+";w="+strconv.Itoa(seconds(limit.Period)))
+	if !decision.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds(decision.RetryAfter)))
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// seconds rounds d up to whole seconds
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
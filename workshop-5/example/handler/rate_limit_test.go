@@ -0,0 +1,198 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"example.com/mike/entity"
+	"example.com/mike/handler"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimitHeaders(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler})
+	app.Use(handler.RateLimit(handler.RateLimitConfig{
+		Store:  repository.NewMemoryRateLimitRepository(),
+		Name:   "echo",
+		Limit:  entity.RateLimit{Limit: 2, Period: time.Minute},
+		Caller: func(c *fiber.Ctx) string { return c.Get("X-Caller") },
+	}))
+	app.Post("/echo", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		resp := do(t, app, "POST", "/echo", nil, "X-Caller", "alice")
+		if resp.StatusCode != fiber.StatusNoContent ||
+			resp.Header.Get(handler.HeaderRateLimitLimit) != "2" ||
+			resp.Header.Get(handler.HeaderRateLimitRemaining) != strconv.Itoa(remaining) ||
+			resp.Header.Get(handler.HeaderRateLimitPolicy) != "2;w=60" {
+			t.Fatalf("request with %d left: %d %v", remaining, resp.StatusCode, resp.Header)
+		}
+	}
+
+	resp := do(t, app, "POST", "/echo", nil, "X-Caller", "alice")
+	expectProblem(t, resp, fiber.StatusTooManyRequests)
+	if resp.Header.Get(fiber.HeaderRetryAfter) != "30" || resp.Header.Get(handler.HeaderRateLimitReset) != "60" {
+		t.Fatalf("refused request headers: %v", resp.Header)
+	}
+
+	// Callers have separate buckets
+	if resp := do(t, app, "POST", "/echo", nil, "X-Caller", "bob"); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("bob: expected 204, got %d", resp.StatusCode)
+	}
+
+	// The zero limit lets everything through
+	app = fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler})
+	app.Use(handler.RateLimit(handler.RateLimitConfig{Store: repository.NewMemoryRateLimitRepository(), Name: "off"}))
+	app.Post("/echo", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+	for i := 0; i < 5; i++ {
+		if resp := do(t, app, "POST", "/echo", nil); resp.StatusCode != fiber.StatusNoContent || resp.Header.Get(handler.HeaderRateLimitLimit) != "" {
+			t.Fatalf("disabled limit: %d %v", resp.StatusCode, resp.Header)
+		}
+	}
+}
+
+func TestRateLimitedRoutes(t *testing.T) {
+	app, users := setupAppWithUsers()
+	limits := handler.DefaultRateLimits
+	alice, asAlice := createUser(t, users, entity.RoleMember, "+66810000061", "alice@example.com")
+	_, asBob := createUser(t, users, entity.RoleMember, "+66810000062", "bob@example.com")
+
+	tests := []struct {
+		name    string
+		path    string
+		body    interface{}
+		limit   entity.RateLimit
+		caller  []string
+		other   []string
+		allowed int
+	}{
+		{"register per IP", "/register", usecase.RegisterRequest{}, limits.Register, nil, nil, fiber.StatusBadRequest},
+		{"transfers per user", "/transfers", usecase.TransferRequest{FromUserID: alice}, limits.Transfers, asAlice, asBob, fiber.StatusBadRequest},
+		{"QR generation per user", "/qr-requests", usecase.CreateQRRequest{RecipientID: alice, Amount: 1}, limits.QRRequests, asAlice, asBob, fiber.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Refused requests count too, so validation errors use up the bucket
+			for i := 0; i < tt.limit.Limit; i++ {
+				if resp := do(t, app, "POST", tt.path, tt.body, tt.caller...); resp.StatusCode != tt.allowed {
+					t.Fatalf("request %d: expected %d, got %d", i, tt.allowed, resp.StatusCode)
+				}
+			}
+			expectProblem(t, do(t, app, "POST", tt.path, tt.body, tt.caller...), fiber.StatusTooManyRequests)
+
+			if tt.other != nil {
+				if resp := do(t, app, "POST", tt.path, tt.body, tt.other...); resp.StatusCode == fiber.StatusTooManyRequests {
+					t.Fatal("another user shares the bucket")
+				}
+			}
+		})
+	}
+}
+
+func TestQRPaymentsAndCodesAreRateLimited(t *testing.T) {
+	app, users := setupAppWithUsers()
+	limits := handler.DefaultRateLimits
+	alice, asAlice := createUser(t, users, entity.RoleMember, "+66810000063", "alice@example.com")
+	bob, asBob := createUser(t, users, entity.RoleMember, "+66810000064", "bob@example.com")
+
+	t.Run("QR payments share the transfer bucket", func(t *testing.T) {
+		for i := 0; i < limits.Transfers.Limit-1; i++ {
+			if resp := do(t, app, "POST", "/transfers", usecase.TransferRequest{FromUserID: alice}, asAlice...); resp.StatusCode != fiber.StatusBadRequest {
+				t.Fatalf("transfer %d: expected 400, got %d", i, resp.StatusCode)
+			}
+		}
+		pay := usecase.PayQRRequest{PayerID: alice}
+		if resp := do(t, app, "POST", "/qr-requests/pay", pay, asAlice...); resp.StatusCode != fiber.StatusBadRequest {
+			t.Fatalf("last payment: expected 400, got %d", resp.StatusCode)
+		}
+		expectRetryAfter(t, do(t, app, "POST", "/qr-requests/pay", pay, asAlice...))
+		expectRetryAfter(t, do(t, app, "POST", "/transfers", usecase.TransferRequest{FromUserID: alice}, asAlice...))
+
+		if resp := do(t, app, "POST", "/qr-requests/pay", usecase.PayQRRequest{PayerID: bob}, asBob...); resp.StatusCode == fiber.StatusTooManyRequests {
+			t.Fatal("another user shares the bucket")
+		}
+	})
+
+	t.Run("QR codes per user", func(t *testing.T) {
+		// The default bucket refills once a second, faster than slow
+		// renders use it up, so count against an hourly one
+		limits := handler.DefaultRateLimits
+		limits.QRCodes = entity.RateLimit{Limit: 5, Period: time.Hour}
+		app, users := setupAppWithLimits(limits)
+		_, asAlice := createUser(t, users, entity.RoleMember, "+66810000065", "alice@example.com")
+		bob, asBob := createUser(t, users, entity.RoleMember, "+66810000066", "bob@example.com")
+
+		resp := do(t, app, "POST", "/qr-requests", usecase.CreateQRRequest{RecipientID: bob, Amount: 1}, asBob...)
+		var created usecase.QRRequestResponse
+		if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		image := "/qr-requests/" + created.Request.ID + "/qr.png"
+
+		for i := 0; i < limits.QRCodes.Limit; i++ {
+			if resp := do(t, app, "GET", image, nil, asAlice...); resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("QR code %d: expected 200, got %d", i, resp.StatusCode)
+			}
+		}
+		expectRetryAfter(t, do(t, app, "GET", image, nil, asAlice...))
+
+		if resp := do(t, app, "GET", image, nil, asBob...); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("another user: expected 200, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestLoginRoutesAreRateLimited(t *testing.T) {
+	limits := handler.DefaultRateLimits
+
+	t.Run("codes per destination", func(t *testing.T) {
+		app := setupApp()
+		for i := 0; i < limits.LoginCodeDestinations.Limit; i++ {
+			if resp := do(t, app, "POST", "/auth/otp", usecase.RequestCodeRequest{Phone: "+66812345678"}); resp.StatusCode != fiber.StatusAccepted {
+				t.Fatalf("code %d: expected 202, got %d", i, resp.StatusCode)
+			}
+		}
+		// The same number written differently is the same destination
+		expectRetryAfter(t, do(t, app, "POST", "/auth/otp", usecase.RequestCodeRequest{Phone: "081-234-5678"}))
+
+		if resp := do(t, app, "POST", "/auth/otp", usecase.RequestCodeRequest{Email: "john@example.com"}); resp.StatusCode != fiber.StatusAccepted {
+			t.Fatalf("another destination: expected 202, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("codes per client IP", func(t *testing.T) {
+		app := setupApp()
+		for i := 0; i < limits.LoginCodes.Limit; i++ {
+			req := usecase.RequestCodeRequest{Email: "user" + strconv.Itoa(i) + "@example.com"}
+			if resp := do(t, app, "POST", "/auth/otp", req); resp.StatusCode != fiber.StatusAccepted {
+				t.Fatalf("code %d: expected 202, got %d", i, resp.StatusCode)
+			}
+		}
+		expectRetryAfter(t, do(t, app, "POST", "/auth/otp", usecase.RequestCodeRequest{Email: "fresh@example.com"}))
+	})
+
+	t.Run("attempts per client IP", func(t *testing.T) {
+		app := setupApp()
+		guess := usecase.VerifyCodeRequest{ChallengeID: "c1", Code: "000000"}
+		for i := 0; i < limits.LoginAttempts.Limit; i++ {
+			if resp := do(t, app, "POST", "/auth/otp/verify", guess); resp.StatusCode != fiber.StatusUnauthorized {
+				t.Fatalf("attempt %d: expected 401, got %d", i, resp.StatusCode)
+			}
+		}
+		expectRetryAfter(t, do(t, app, "POST", "/auth/otp/verify", guess))
+	})
+}
+
+// expectRetryAfter checks that resp was refused by a rate limit
+func expectRetryAfter(t *testing.T, resp *http.Response) {
+	t.Helper()
+	expectProblem(t, resp, fiber.StatusTooManyRequests)
+	if resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+		t.Fatalf("429 without Retry-After: %v", resp.Header)
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"example.com/mike/sms"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReceiptHandler handles SMS receipt HTTP requests
+type ReceiptHandler struct {
+	receiptUsecase usecase.ReceiptUsecase
+}
+
+// NewReceiptHandler creates a new SMS receipt handler
+func NewReceiptHandler(receiptUsecase usecase.ReceiptUsecase) *ReceiptHandler {
+	return &ReceiptHandler{
+		receiptUsecase: receiptUsecase,
+	}
+}
+
+// RegisterRoutes sets up the receipt routes
+func (h *ReceiptHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/orders/:id/receipt", RequireUser, h.GetReceipt)
+
+	app.Post("/webhooks/sms/delivery", h.ReceiveDeliveryReport)
+}
+
+// GetReceipt handles getting the SMS receipt of an order
+// @Summary      Get an order's receipt
+// @Description  Retrieve the SMS receipt sent after checkout, with its text, segment count, provider reference and delivery status. Members may only read receipts of their own orders.
+// @Tags         orders
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Order ID"
+// @Success      200  {object}  usecase.ReceiptResponse  "Receipt found"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403  {object}  handler.Problem  "Order belongs to another member"
+// @Failure      404  {object}  handler.Problem  "Receipt not found"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /orders/{id}/receipt [get]
+func (h *ReceiptHandler) GetReceipt(c *fiber.Ctx) error {
+	response, err := h.receiptUsecase.GetReceipt(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	if err := authorize(c, usecase.OpGetOrder, response.Receipt.UserID); err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// ReceiveDeliveryReport handles the SMS provider's delivery callback
+// @Summary      Receive an SMS delivery report
+// @Description  Called by the SMS provider when a receipt is delivered or fails. The raw body must be signed: X-SMS-Signature is "sha256=" and the hex HMAC-SHA256 of "<X-SMS-Timestamp>.<body>" with the shared secret, and the timestamp must be within 5 minutes. Reports are idempotent on message_id; repeats return 200 with duplicate set.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        request          body      sms.DeliveryReport  true  "Delivery report"
+// @Param        X-SMS-Timestamp  header    string              true  "Unix seconds when the report was signed"
+// @Param        X-SMS-Signature  header    string              true  "sha256=<hex HMAC-SHA256>"
+// @Success      200              {object}  usecase.DeliveryReportResponse  "Report applied, or already applied"
+// @Failure      400              {object}  handler.Problem  "Invalid delivery report"
+// @Failure      401              {object}  handler.Problem  "Invalid webhook signature"
+// @Failure      404              {object}  handler.Problem  "No receipt was sent with this message ID"
+// @Failure      500              {object}  handler.Problem  "Internal server error"
+// @Router       /webhooks/sms/delivery [post]
+func (h *ReceiptHandler) ReceiveDeliveryReport(c *fiber.Ctx) error {
+	response, err := h.receiptUsecase.ReceiveDeliveryReport(c.UserContext(), c.Body(), c.Get(sms.TimestampHeader), c.Get(sms.SignatureHeader))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
@@ -0,0 +1,125 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/mike/analytics"
+	"example.com/mike/entity"
+	"example.com/mike/handler"
+	"example.com/mike/repository"
+	"example.com/mike/sms"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// appTransport delivers HTTP requests straight to a Fiber app, so the
+// stand-in SMS provider can call the webhook without a listening server
+type appTransport struct {
+	app *fiber.App
+}
+
+func (t appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.app.Test(req, -1)
+}
+
+func TestSMSDeliveryWebhook(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler, Immutable: true})
+
+	provider := sms.NewStandInProvider(sms.StandInConfig{
+		CallbackURL: "http://app.test/webhooks/sms/delivery",
+		Secret:      testWebhookSecret,
+		Client:      &http.Client{Transport: appTransport{app}},
+	})
+	server := httptest.NewServer(provider)
+	defer server.Close()
+
+	userRepo, ledgerRepo := repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository()
+	productRepo, outboxRepo := repository.NewMemoryProductRepository(), repository.NewMemoryOutboxRepository()
+	orderRepo := repository.NewMemoryOrderRepository(productRepo, ledgerRepo, repository.NewMemoryCartRepository(), outboxRepo)
+	events := analytics.NewLogEmitter(io.Discard)
+	receipts := usecase.NewReceiptUsecase(userRepo, orderRepo, ledgerRepo, repository.NewMemoryReceiptRepository(outboxRepo),
+		sms.NewHTTPSender(sms.HTTPConfig{URL: server.URL}), testWebhookSecret)
+	outbox := usecase.NewOutboxUsecase(outboxRepo, map[string]usecase.OutboxHandler{
+		usecase.TopicSMSReceipt:     usecase.ReceiptOutboxHandler(receipts),
+		usecase.TopicAnalyticsEvent: usecase.AnalyticsOutboxHandler(events),
+	}, usecase.DefaultOutboxPolicy)
+	app.Use(authenticate(userRepo))
+	handler.NewReceiptHandler(receipts).RegisterRoutes(app)
+	_, staff := createUser(t, userRepo, entity.RoleStaff, "+66811111111", "staff@example.com")
+
+	// Place an order with its receipt message, as checkout does
+	if err := productRepo.Create(context.Background(), entity.NewProduct("p1", "Tea", "", "drinks", 50, 1, true)); err != nil {
+		t.Fatalf("Create product: %v", err)
+	}
+	if err := ledgerRepo.Post(context.Background(), entity.NewEarnPosting("e1", "u1", 1000, "")); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	order := entity.NewOrder("o1", "u1", []entity.OrderItem{{ProductID: "p1", Name: "Tea", UnitPrice: 50, Quantity: 1, LineTotal: 50}}, "Somchai Jaidee", "+66812345678")
+	order.Apply(entity.OrderPaid, "u1", "Paid with points")
+	message, err := entity.NewOutboxMessage("m1", usecase.TopicSMSReceipt, usecase.ReceiptMessage{OrderID: "o1", Language: usecase.LanguageEnglish})
+	if err != nil {
+		t.Fatalf("NewOutboxMessage: %v", err)
+	}
+	if err := orderRepo.Place(context.Background(), order, entity.NewPointsPayment("pay1", order), message); err != nil {
+		t.Fatalf("Place: %v", err)
+	}
+	if _, err := outbox.DispatchDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+	sent, err := receipts.GetReceipt(context.Background(), "o1")
+	if err != nil {
+		t.Fatalf("GetReceipt: %v", err)
+	}
+	receipt := sent.Receipt
+	if receipt.Status != entity.ReceiptSent || provider.Messages()[receipt.ProviderRef].To != "+66812345678" ||
+		!strings.Contains(receipt.Body, "950 points left") {
+		t.Fatalf("receipt not sent through the provider: %+v", receipt)
+	}
+
+	for i := 0; i < 2; i++ {
+		status, err := provider.Report(receipt.ProviderRef, sms.Delivered, "")
+		if err != nil || status != fiber.StatusOK {
+			t.Fatalf("report %d: status %d, err %v", i, status, err)
+		}
+	}
+	resp := do(t, app, "GET", "/orders/o1/receipt", nil, staff...)
+	var got usecase.ReceiptResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if got.Receipt.Status != entity.ReceiptDelivered {
+		t.Fatalf("receipt not delivered: %+v", got.Receipt)
+	}
+	if _, err := outbox.DispatchDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+	if emitted := events.Events(); len(emitted) != 1 || emitted[0].Name != usecase.EventSMSSent {
+		t.Fatalf("expected a single sms_sent event, got %+v", emitted)
+	}
+
+	// Reports signed with another secret, or for unknown messages, are refused
+	body := `{"message_id":"` + receipt.ProviderRef + `","status":"failed"}`
+	now := time.Now()
+	signed := func(secret []byte, body string) *http.Response {
+		req := httptest.NewRequest("POST", "/webhooks/sms/delivery", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(sms.TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		req.Header.Set(sms.SignatureHeader, sms.SignCallback(secret, now, []byte(body)))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+	expectProblem(t, signed([]byte("guessed"), body), fiber.StatusUnauthorized)
+	expectProblem(t, signed(testWebhookSecret, `{"message_id":"unknown","status":"failed"}`), fiber.StatusNotFound)
+	expectProblem(t, signed(testWebhookSecret, `{"message_id":"x","status":"queued"}`), fiber.StatusBadRequest)
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultRequestTimeout is how long a request may run before its context
+// is cancelled
+const DefaultRequestTimeout = 10 * time.Second
+
+// Timeout gives every request a context that is cancelled once d has
+// passed. Handlers pass it on with c.UserContext(), so usecases and
+// repositories stop working on requests nobody will wait for; the
+// usecases report such requests as apperror.KindUnavailable, rendered as
+// 503. Mount it before every other middleware so they run under the same
+// deadline.
+func Timeout(d time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), d)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
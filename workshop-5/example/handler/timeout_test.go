@@ -0,0 +1,51 @@
+package handler_test
+
+import (
+	"testing"
+	"time"
+
+	"example.com/mike/handler"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestTimeoutSetsRequestDeadline(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler})
+	app.Use(handler.Timeout(time.Minute))
+	app.Get("/deadline", func(c *fiber.Ctx) error {
+		deadline, ok := c.UserContext().Deadline()
+		if !ok || time.Until(deadline) > time.Minute {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	if resp := do(t, app, "GET", "/deadline", nil); resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+}
+
+func TestTimedOutRequestIsUnavailable(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: handler.ErrorHandler})
+	app.Use(handler.Timeout(10 * time.Millisecond))
+
+	// A slow middleware uses up the whole timeout before the handler runs
+	app.Use(func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		return c.Next()
+	})
+	userUsecase := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	handler.NewHTTPHandler(userUsecase).RegisterRoutes(app)
+
+	resp := do(t, app, "POST", "/register", usecase.RegisterRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Phone:     "+66812345678",
+		Email:     "john@example.com",
+	})
+	problem := expectProblem(t, resp, fiber.StatusServiceUnavailable)
+	if problem.Type != "/problems/unavailable" || problem.Detail == "" {
+		t.Fatalf("unexpected problem: %+v", problem)
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+
+	"example.com/mike/apperror"
+	"example.com/mike/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TransferHandler handles peer-to-peer transfer HTTP requests
+type TransferHandler struct {
+	transferUsecase usecase.TransferUsecase
+}
+
+// NewTransferHandler creates a new transfer handler
+func NewTransferHandler(transferUsecase usecase.TransferUsecase) *TransferHandler {
+	return &TransferHandler{
+		transferUsecase: transferUsecase,
+	}
+}
+
+// RegisterRoutes sets up the transfer routes. Members send points from
+// their own account and read the transfers they are a party to.
+func (h *TransferHandler) RegisterRoutes(app *fiber.App) {
+	app.Post("/transfers", RequireUser, h.CreateTransfer)
+	app.Get("/transfers/:id", RequireUser, h.GetTransfer)
+}
+
+// CreateTransfer handles sending points to another member
+// @Summary      Transfer points
+// @Description  Send points to another member. The amount must be within the per-transfer limits, the sender's balance and the daily cap. The debit and credit are posted atomically; a rejected transfer is recorded as failed and leaves both balances unchanged.
+// @Tags         transfers
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      usecase.TransferRequest  true  "Transfer details"
+// @Param        Idempotency-Key  header  string  false  "Makes retries safe: repeats within 24h replay the first response"
+// @Success      201      {object}  usecase.TransferResponse  "Transfer posted"
+// @Failure      400      {object}  handler.Problem  "Invalid request format, validation error or unknown recipient"
+// @Failure      401      {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      403      {object}  handler.Problem  "Points can only be sent from your own account"
+// @Failure      404      {object}  handler.Problem  "Sender not found"
+// @Failure      409      {object}  handler.Problem  "Insufficient balance or daily limit exceeded"
+// @Failure      422      {object}  handler.Problem  "Idempotency-Key reused for a different request"
+// @Failure      429      {object}  handler.Problem  "Too many transfers; see Retry-After"
+// @Failure      500      {object}  handler.Problem  "Internal server error"
+// @Router       /transfers [post]
+func (h *TransferHandler) CreateTransfer(c *fiber.Ctx) error {
+	var req usecase.TransferRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperror.Validation("Invalid request format")
+	}
+	if err := authorize(c, usecase.OpCreateTransfer, req.FromUserID); err != nil {
+		return err
+	}
+
+	response, err := h.transferUsecase.Transfer(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetTransfer handles getting a transfer by ID
+// @Summary      Get a transfer
+// @Description  Retrieve a transfer and its status (pending, posted or failed). Members may only read transfers they sent or received; other transfers are reported as not found.
+// @Tags         transfers
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Transfer ID"
+// @Success      200  {object}  usecase.TransferResponse  "Transfer found"
+// @Failure      401  {object}  handler.Problem  "Missing, invalid or expired access token"
+// @Failure      404  {object}  handler.Problem  "Transfer not found, or between other members"
+// @Failure      500  {object}  handler.Problem  "Internal server error"
+// @Router       /transfers/{id} [get]
+func (h *TransferHandler) GetTransfer(c *fiber.Ctx) error {
+	response, err := h.transferUsecase.GetTransfer(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	// Callers who may not read the transfer are told it does not exist, so
+	// they cannot probe for transfer IDs between other members
+	transfer := response.Transfer
+	if err := authorize(c, usecase.OpGetTransfer, transfer.FromUserID, transfer.ToUserID); err != nil {
+		if errors.Is(err, apperror.ErrForbidden) {
+			return usecase.ErrTransferNotFound
+		}
+		return err
+	}
+
+	return c.JSON(response)
+}
@@ -1,10 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"example.com/mike/analytics"
+	"example.com/mike/auth"
+	_ "example.com/mike/docs"
+	"example.com/mike/entity"
 	"example.com/mike/handler"
+	"example.com/mike/qrpayload"
 	"example.com/mike/repository"
+	"example.com/mike/sms"
 	"example.com/mike/usecase"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,247 +26,487 @@ import (
 )
 
 func main() {
-	// Create a new Fiber instance
-	app := fiber.New()
+	// Background jobs stop and the server drains its requests on SIGINT or
+	// SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Create a new Fiber instance. Every error is rendered as RFC 7807
+	// application/problem+json by the shared error handler. Immutable keeps
+	// path parameters valid after the request, since the in-memory
+	// repositories hold on to them as IDs.
+	app := fiber.New(fiber.Config{
+		ErrorHandler: handler.ErrorHandler,
+		Immutable:    true,
+	})
 
 	// Initialize dependencies (Dependency Injection)
-	userRepo := repository.NewMemoryUserRepository()
-	userUsecase := usecase.NewUserUsecase(userRepo)
+	repos := newRepositories()
+	smsSender := newSMSSender()
+	authUsecase := usecase.NewAuthUsecase(repos.users, repos.otp, repos.refreshTokens, newCodeSender(smsSender), newTokenSigner(), newAuthPolicy())
+	userUsecase := usecase.NewUserUsecase(repos.users, repos.ledger)
+	ledgerUsecase := usecase.NewLedgerUsecase(repos.users, repos.ledger)
+	transferUsecase := usecase.NewTransferUsecase(repos.users, repos.transfers, repos.ledger, usecase.DefaultTransferPolicy)
+	qrUsecase := usecase.NewQRUsecase(repos.users, repos.qrRequests, transferUsecase, newQRKeyring(), usecase.DefaultQRPolicy)
 	httpHandler := handler.NewHTTPHandler(userUsecase)
+	ledgerHandler := handler.NewLedgerHandler(ledgerUsecase)
+	transferHandler := handler.NewTransferHandler(transferUsecase)
+	productUsecase := usecase.NewProductUsecase(repos.products)
+	events := analytics.NewLogEmitter(os.Stdout)
+	receiptUsecase := usecase.NewReceiptUsecase(repos.users, repos.orders, repos.ledger, repos.receipts, smsSender, newSMSWebhookSecret())
+	outboxUsecase := usecase.NewOutboxUsecase(repos.outbox, map[string]usecase.OutboxHandler{
+		usecase.TopicSMSReceipt:     usecase.ReceiptOutboxHandler(receiptUsecase),
+		usecase.TopicAnalyticsEvent: usecase.AnalyticsOutboxHandler(events),
+	}, usecase.DefaultOutboxPolicy)
+	cartUsecase := usecase.NewCartUsecase(repos.users, repos.products, repos.carts, repos.orders, repos.ledger, receiptUsecase, outboxUsecase)
+	orderUsecase := usecase.NewOrderUsecase(repos.orders)
+	qrHandler := handler.NewQRHandler(qrUsecase)
+	productHandler := handler.NewProductHandler(productUsecase)
+	cartHandler := handler.NewCartHandler(cartUsecase)
+	orderHandler := handler.NewOrderHandler(orderUsecase)
+	receiptHandler := handler.NewReceiptHandler(receiptUsecase)
+	outboxHandler := handler.NewOutboxHandler(outboxUsecase)
+	authHandler := handler.NewAuthHandler(authUsecase)
+	apiKeyUsecase := usecase.NewAPIKeyUsecase(repos.apiKeys, newAPIKeySigner(), usecase.DefaultAPIKeyPolicy)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyUsecase)
+	partnerHandler := handler.NewPartnerHandler(ledgerUsecase)
+
+	// Every request runs under a deadline; user routes pass it down to
+	// storage and give up with 503 once it passes
+	app.Use(handler.Timeout(durationEnv("REQUEST_TIMEOUT", handler.DefaultRequestTimeout)))
+
+	// Requests with an access token act as its user; routes that need one
+	// reject anonymous requests
+	app.Use(handler.Authenticate(authUsecase))
+
+	// Partner routes are called server to server with signed API key
+	// requests instead of access tokens
+	app.Use("/partner", handler.RequireAPIKey(apiKeyUsecase, repos.rateLimits))
+
+	// Registration, logins, transfers, QR generation, QR payments and QR
+	// code images are rate limited per client IP, destination or user;
+	// refused requests get 429 with Retry-After
+	newRateLimits().Mount(app, repos.rateLimits)
+	go purgeRateLimits(ctx, repos.rateLimits, time.Minute)
+
+	// Retried POST, PUT, PATCH and DELETE requests carrying an
+	// Idempotency-Key header replay the first response
+	app.Use(handler.Idempotency(handler.IdempotencyConfig{
+		Store: repos.idempotency,
+		TTL:   durationEnv("IDEMPOTENCY_TTL", handler.DefaultIdempotencyTTL),
+	}))
+	go purgeIdempotencyKeys(ctx, repos.idempotency, time.Hour)
 
 	// Register routes
+	authHandler.RegisterRoutes(app)
 	httpHandler.RegisterRoutes(app)
+	ledgerHandler.RegisterRoutes(app)
+	transferHandler.RegisterRoutes(app)
+	qrHandler.RegisterRoutes(app)
+	productHandler.RegisterRoutes(app)
+	cartHandler.RegisterRoutes(app)
+	orderHandler.RegisterRoutes(app)
+	receiptHandler.RegisterRoutes(app)
+	outboxHandler.RegisterRoutes(app)
+	apiKeyHandler.RegisterRoutes(app)
+	partnerHandler.RegisterRoutes(app)
 
-	// Swagger documentation
-	app.Get("/swagger/doc.json", func(c *fiber.Ctx) error {
-		c.Set("Content-Type", "application/json")
-		return c.JSON(map[string]interface{}{
-			"swagger": "2.0",
-			"info": map[string]interface{}{
-				"title":       "User Management API",
-				"version":     "1.0",
-				"description": "A simple user management API with registration and retrieval functionality",
-				"contact": map[string]interface{}{
-					"name":  "API Support",
-					"url":   "http://www.swagger.io/support",
-					"email": "support@swagger.io",
-				},
-				"license": map[string]interface{}{
-					"name": "MIT",
-					"url":  "https://opensource.org/licenses/MIT",
-				},
-			},
-			"host":     "127.0.0.1:3000",
-			"basePath": "/",
-			"schemes":  []string{"http"},
-			"paths": map[string]interface{}{
-				"/health": map[string]interface{}{
-					"get": map[string]interface{}{
-						"summary":     "Health Check",
-						"description": "Check if the service is running",
-						"tags":        []string{"health"},
-						"responses": map[string]interface{}{
-							"200": map[string]interface{}{
-								"description": "Service is healthy",
-								"schema": map[string]interface{}{
-									"type": "object",
-								},
-							},
-						},
-					},
-				},
-				"/register": map[string]interface{}{
-					"post": map[string]interface{}{
-						"summary":     "Register a new user",
-						"description": "Register a new user with first name, last name, phone, and email",
-						"tags":        []string{"users"},
-						"parameters": []map[string]interface{}{
-							{
-								"name":        "request",
-								"in":          "body",
-								"required":    true,
-								"description": "User registration data",
-								"schema": map[string]interface{}{
-									"$ref": "#/definitions/RegisterRequest",
-								},
-							},
-						},
-						"responses": map[string]interface{}{
-							"201": map[string]interface{}{
-								"description": "User registered successfully",
-								"schema": map[string]interface{}{
-									"$ref": "#/definitions/RegisterResponse",
-								},
-							},
-							"400": map[string]interface{}{
-								"description": "Invalid request format or validation error",
-								"schema": map[string]interface{}{
-									"$ref": "#/definitions/RegisterResponse",
-								},
-							},
-							"409": map[string]interface{}{
-								"description": "Email already registered",
-								"schema": map[string]interface{}{
-									"$ref": "#/definitions/RegisterResponse",
-								},
-							},
-							"500": map[string]interface{}{
-								"description": "Internal server error",
-								"schema": map[string]interface{}{
-									"$ref": "#/definitions/RegisterResponse",
-								},
-							},
-						},
-					},
-				},
-				"/user/{id}": map[string]interface{}{
-					"get": map[string]interface{}{
-						"summary":     "Get user by ID",
-						"description": "Retrieve a user by their unique ID",
-						"tags":        []string{"users"},
-						"parameters": []map[string]interface{}{
-							{
-								"name":        "id",
-								"in":          "path",
-								"required":    true,
-								"type":        "string",
-								"description": "User ID",
-							},
-						},
-						"responses": map[string]interface{}{
-							"200": map[string]interface{}{
-								"description": "User found",
-								"schema": map[string]interface{}{
-									"$ref": "#/definitions/RegisterResponse",
-								},
-							},
-							"404": map[string]interface{}{
-								"description": "User not found",
-								"schema": map[string]interface{}{
-									"$ref": "#/definitions/RegisterResponse",
-								},
-							},
-							"500": map[string]interface{}{
-								"description": "Internal server error",
-								"schema": map[string]interface{}{
-									"$ref": "#/definitions/RegisterResponse",
-								},
-							},
-						},
-					},
-				},
-				"/users": map[string]interface{}{
-					"get": map[string]interface{}{
-						"summary":     "Get all users",
-						"description": "Retrieve all registered users",
-						"tags":        []string{"users"},
-						"responses": map[string]interface{}{
-							"200": map[string]interface{}{
-								"description": "List of all users",
-								"schema": map[string]interface{}{
-									"type": "object",
-								},
-							},
-							"500": map[string]interface{}{
-								"description": "Internal server error",
-								"schema": map[string]interface{}{
-									"type": "object",
-								},
-							},
-						},
-					},
-				},
-			},
-			"definitions": map[string]interface{}{
-				"User": map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"id": map[string]interface{}{
-							"type":    "string",
-							"example": "550e8400-e29b-41d4-a716-446655440000",
-						},
-						"member_id": map[string]interface{}{
-							"type":    "string",
-							"example": "LBK000001",
-						},
-						"first_name": map[string]interface{}{
-							"type":    "string",
-							"example": "John",
-						},
-						"last_name": map[string]interface{}{
-							"type":    "string",
-							"example": "Doe",
-						},
-						"phone": map[string]interface{}{
-							"type":    "string",
-							"example": "+66812345678",
-						},
-						"email": map[string]interface{}{
-							"type":    "string",
-							"example": "john.doe@example.com",
-						},
-						"membership_level": map[string]interface{}{
-							"type":    "string",
-							"example": "Gold",
-						},
-						"points": map[string]interface{}{
-							"type":    "integer",
-							"example": 0,
-						},
-						"registered_at": map[string]interface{}{
-							"type":    "string",
-							"example": "2024-01-01T00:00:00Z",
-						},
-					},
-				},
-				"RegisterRequest": map[string]interface{}{
-					"type":     "object",
-					"required": []string{"first_name", "last_name", "phone", "email"},
-					"properties": map[string]interface{}{
-						"first_name": map[string]interface{}{
-							"type":    "string",
-							"example": "John",
-						},
-						"last_name": map[string]interface{}{
-							"type":    "string",
-							"example": "Doe",
-						},
-						"phone": map[string]interface{}{
-							"type":    "string",
-							"example": "+66812345678",
-						},
-						"email": map[string]interface{}{
-							"type":    "string",
-							"example": "john.doe@example.com",
-						},
-					},
-				},
-				"RegisterResponse": map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"success": map[string]interface{}{
-							"type":    "boolean",
-							"example": true,
-						},
-						"message": map[string]interface{}{
-							"type":    "string",
-							"example": "User registered successfully",
-						},
-						"user": map[string]interface{}{
-							"$ref": "#/definitions/User",
-						},
-					},
-				},
-			},
-		})
-	})
+	// Delete login codes and refresh tokens once they expire
+	go purgeLogins(ctx, authUsecase, time.Hour)
+
+	// Forget the nonces of partner requests once their timestamps are stale
+	go purgeNonces(ctx, apiKeyUsecase, time.Minute)
+
+	// Expire QR payment requests once they pass their expiry
+	go sweepQRRequests(ctx, qrUsecase, time.Minute)
+
+	// Deliver outbox messages that were not dispatched right away or are
+	// due for a retry
+	go dispatchOutbox(ctx, outboxUsecase, durationEnv("OUTBOX_INTERVAL", time.Second))
+
+	// Swagger documentation, generated from the handler annotations with
+	// swag init -g handler/http_handler.go --outputTypes go
 	app.Get("/swagger/*", swagger.New(swagger.Config{
-		URL:         "http://127.0.0.1:3000/swagger/doc.json",
+		URL:         "/swagger/doc.json",
 		DeepLinking: false,
 	}))
 
 	// Start server
 	log.Println("Starting server on port 3000...")
 	log.Println("Swagger documentation available at: http://localhost:3000/swagger/")
-	log.Fatal(app.Listen(":3000"))
+	go func() {
+		<-ctx.Done()
+		log.Println("Shutting down...")
+		if err := app.Shutdown(); err != nil {
+			log.Printf("Failed to shut down: %v", err)
+		}
+	}()
+	if err := app.Listen(":3000"); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// repositories groups the storage used by the usecases
+type repositories struct {
+	users      repository.UserRepository
+	ledger     repository.LedgerRepository
+	transfers  repository.TransferRepository
+	qrRequests repository.QRRequestRepository
+	products   repository.ProductRepository
+	carts      repository.CartRepository
+	orders     repository.OrderRepository
+	receipts   repository.ReceiptRepository
+	outbox     repository.OutboxRepository
+
+	otp           repository.OTPRepository
+	refreshTokens repository.RefreshTokenRepository
+	idempotency   repository.IdempotencyRepository
+	apiKeys       repository.APIKeyRepository
+	rateLimits    repository.RateLimitRepository
+}
+
+// newRepositories selects the storage backend from the STORAGE_DRIVER
+// environment variable ("memory" by default, or "sqlite"). The SQLite file
+// location is read from SQLITE_PATH and defaults to users.db.
+func newRepositories() repositories {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "memory":
+		log.Println("Using in-memory storage")
+		ledger := repository.NewMemoryLedgerRepository()
+		products, carts := repository.NewMemoryProductRepository(), repository.NewMemoryCartRepository()
+		outbox, qrRequests := repository.NewMemoryOutboxRepository(), repository.NewMemoryQRRequestRepository()
+		return repositories{
+			users:      repository.NewMemoryUserRepository(),
+			ledger:     ledger,
+			transfers:  repository.NewMemoryTransferRepository(ledger, qrRequests),
+			qrRequests: qrRequests,
+			products:   products,
+			carts:      carts,
+			orders:     repository.NewMemoryOrderRepository(products, ledger, carts, outbox),
+			receipts:   repository.NewMemoryReceiptRepository(outbox),
+			outbox:     outbox,
+
+			otp:           repository.NewMemoryOTPRepository(),
+			refreshTokens: repository.NewMemoryRefreshTokenRepository(),
+			idempotency:   repository.NewMemoryIdempotencyRepository(),
+			apiKeys:       repository.NewMemoryAPIKeyRepository(),
+			rateLimits:    repository.NewMemoryRateLimitRepository(),
+		}
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "users.db"
+		}
+
+		db, err := repository.OpenSQLite(path)
+		if err != nil {
+			log.Fatalf("Failed to open SQLite database: %v", err)
+		}
+
+		log.Printf("Using SQLite storage at %s", path)
+		return repositories{
+			users:      repository.NewSQLiteUserRepository(db),
+			ledger:     repository.NewSQLiteLedgerRepository(db),
+			transfers:  repository.NewSQLiteTransferRepository(db),
+			qrRequests: repository.NewSQLiteQRRequestRepository(db),
+			products:   repository.NewSQLiteProductRepository(db),
+			carts:      repository.NewSQLiteCartRepository(db),
+			orders:     repository.NewSQLiteOrderRepository(db),
+			receipts:   repository.NewSQLiteReceiptRepository(db),
+			outbox:     repository.NewSQLiteOutboxRepository(db),
+
+			otp:           repository.NewSQLiteOTPRepository(db),
+			refreshTokens: repository.NewSQLiteRefreshTokenRepository(db),
+			idempotency:   repository.NewSQLiteIdempotencyRepository(db),
+			apiKeys:       repository.NewSQLiteAPIKeyRepository(db),
+
+			// Rate limit buckets are short-lived and per process
+			rateLimits: repository.NewMemoryRateLimitRepository(),
+		}
+	default:
+		log.Fatalf("Unknown STORAGE_DRIVER %q (expected \"memory\" or \"sqlite\")", driver)
+		return repositories{}
+	}
+}
+
+// newQRKeyring reads the QR payload signing keys from QR_SIGNING_KEYS, a
+// comma-separated list of <key id>:<base64 secret> pairs with the active key
+// first. Without it a random key is generated, so payloads handed out stop
+// verifying when the server restarts.
+func newQRKeyring() *qrpayload.Keyring {
+	if spec := os.Getenv("QR_SIGNING_KEYS"); spec != "" {
+		keyring, err := qrpayload.ParseKeyring(spec)
+		if err != nil {
+			log.Fatalf("Invalid QR_SIGNING_KEYS: %v", err)
+		}
+		return keyring
+	}
+
+	log.Println("QR_SIGNING_KEYS is not set; signing QR payloads with a random key that will not survive a restart")
+	key, err := qrpayload.GenerateKey("dev")
+	if err != nil {
+		log.Fatalf("Failed to generate QR signing key: %v", err)
+	}
+	keyring, err := qrpayload.NewKeyring(key)
+	if err != nil {
+		log.Fatalf("Failed to create QR keyring: %v", err)
+	}
+	return keyring
+}
+
+// newSMSSender selects the SMS provider from SMS_PROVIDER. "log" (the
+// default) writes messages as JSON lines to SMS_LOG_PATH, or to standard
+// output, instead of sending them. "http" posts them to SMS_HTTP_URL with
+// SMS_HTTP_TOKEN as a bearer token and SMS_FROM as the sender name.
+func newSMSSender() usecase.SMSSender {
+	switch provider := os.Getenv("SMS_PROVIDER"); provider {
+	case "", "log":
+		path := os.Getenv("SMS_LOG_PATH")
+		if path == "" {
+			log.Println("Writing SMS messages to standard output")
+			return sms.NewLogSender(os.Stdout)
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			log.Fatalf("Failed to open SMS_LOG_PATH: %v", err)
+		}
+		log.Printf("Writing SMS messages to %s", path)
+		return sms.NewLogSender(file)
+	case "http":
+		url := os.Getenv("SMS_HTTP_URL")
+		if url == "" {
+			log.Fatal("SMS_HTTP_URL is required when SMS_PROVIDER is \"http\"")
+		}
+		return sms.NewHTTPSender(sms.HTTPConfig{
+			URL:   url,
+			Token: os.Getenv("SMS_HTTP_TOKEN"),
+			From:  os.Getenv("SMS_FROM"),
+		})
+	default:
+		log.Fatalf("Unknown SMS_PROVIDER %q (expected \"log\" or \"http\")", provider)
+		return nil
+	}
+}
+
+// newSMSWebhookSecret reads the secret shared with the SMS provider to sign
+// delivery reports from SMS_WEBHOOK_SECRET. Without it a random secret is
+// used, so every delivery report is rejected.
+func newSMSWebhookSecret() []byte {
+	if secret := os.Getenv("SMS_WEBHOOK_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+
+	log.Println("SMS_WEBHOOK_SECRET is not set; SMS delivery reports will be rejected")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Failed to generate SMS webhook secret: %v", err)
+	}
+	return secret
+}
+
+// newTokenSigner reads the access token signing secret from
+// AUTH_TOKEN_SECRET, at least 32 bytes. Without it a random secret is used,
+// so everyone is logged out when the server restarts.
+func newTokenSigner() *auth.Signer {
+	secret := []byte(os.Getenv("AUTH_TOKEN_SECRET"))
+	if len(secret) == 0 {
+		log.Println("AUTH_TOKEN_SECRET is not set; signing access tokens with a random secret that will not survive a restart")
+		secret = make([]byte, auth.MinSecretLength)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("Failed to generate access token secret: %v", err)
+		}
+	}
+
+	signer, err := auth.NewSigner(secret)
+	if err != nil {
+		log.Fatalf("Invalid AUTH_TOKEN_SECRET: %v", err)
+	}
+	return signer
+}
+
+// newAPIKeySigner reads API_KEY_SECRET, at least 32 bytes, from which the
+// signing secrets of partner API keys are derived. Without it a random
+// secret is used, so every partner key stops working when the server
+// restarts.
+func newAPIKeySigner() *auth.Signer {
+	secret := []byte(os.Getenv("API_KEY_SECRET"))
+	if len(secret) == 0 {
+		log.Println("API_KEY_SECRET is not set; deriving partner API key secrets from a random secret that will not survive a restart")
+		secret = make([]byte, auth.MinSecretLength)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("Failed to generate API key secret: %v", err)
+		}
+	}
+
+	signer, err := auth.NewSigner(secret)
+	if err != nil {
+		log.Fatalf("Invalid API_KEY_SECRET: %v", err)
+	}
+	return signer
+}
+
+// newAuthPolicy returns the default login policy with the admins listed
+// in ADMIN_EMAILS, separated by commas. Until someone is listed nobody can
+// change roles.
+func newAuthPolicy() usecase.AuthPolicy {
+	policy := usecase.DefaultAuthPolicy
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			policy.AdminEmails = append(policy.AdminEmails, email)
+		}
+	}
+	if len(policy.AdminEmails) == 0 {
+		log.Println("ADMIN_EMAILS is not set; no user will be made an admin at login")
+	}
+	return policy
+}
+
+// newCodeSender selects how login codes are delivered from OTP_SENDER.
+// "log" (the default) writes codes as JSON lines to standard output instead
+// of sending them. "sms" texts them through smsSender; it cannot send
+// email, so email logins are refused.
+func newCodeSender(smsSender usecase.SMSSender) usecase.CodeSender {
+	switch sender := os.Getenv("OTP_SENDER"); sender {
+	case "", "log":
+		log.Println("Writing login codes to standard output")
+		return auth.NewLogCodeSender(os.Stdout)
+	case "sms":
+		return auth.NewSMSCodeSender(smsSender)
+	default:
+		log.Fatalf("Unknown OTP_SENDER %q (expected \"log\" or \"sms\")", sender)
+		return nil
+	}
+}
+
+// durationEnv reads a duration such as "24h" from the environment variable
+// name, falling back to def when it is unset
+func durationEnv(name string, def time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		log.Fatalf("Invalid %s %q (expected a positive duration such as \"24h\")", name, raw)
+	}
+	return d
+}
+
+// newRateLimits returns the default rate limits, overridden per route by
+// RATE_LIMIT_REGISTER, RATE_LIMIT_LOGIN_CODES,
+// RATE_LIMIT_LOGIN_CODE_DESTINATIONS, RATE_LIMIT_LOGIN_ATTEMPTS,
+// RATE_LIMIT_TRANSFERS, RATE_LIMIT_QR_REQUESTS and RATE_LIMIT_QR_CODES
+func newRateLimits() handler.RateLimits {
+	limits := handler.DefaultRateLimits
+	limits.Register = rateLimitEnv("RATE_LIMIT_REGISTER", limits.Register)
+	limits.LoginCodes = rateLimitEnv("RATE_LIMIT_LOGIN_CODES", limits.LoginCodes)
+	limits.LoginCodeDestinations = rateLimitEnv("RATE_LIMIT_LOGIN_CODE_DESTINATIONS", limits.LoginCodeDestinations)
+	limits.LoginAttempts = rateLimitEnv("RATE_LIMIT_LOGIN_ATTEMPTS", limits.LoginAttempts)
+	limits.Transfers = rateLimitEnv("RATE_LIMIT_TRANSFERS", limits.Transfers)
+	limits.QRRequests = rateLimitEnv("RATE_LIMIT_QR_REQUESTS", limits.QRRequests)
+	limits.QRCodes = rateLimitEnv("RATE_LIMIT_QR_CODES", limits.QRCodes)
+	return limits
+}
+
+// rateLimitEnv reads a rate limit such as "10/1m" (10 requests a minute)
+// from the environment variable name, falling back to def when it is
+// unset. "off" turns the limit off.
+func rateLimitEnv(name string, def entity.RateLimit) entity.RateLimit {
+	raw := os.Getenv(name)
+	switch raw {
+	case "":
+		return def
+	case "off":
+		return entity.RateLimit{}
+	}
+
+	count, period, _ := strings.Cut(raw, "/")
+	limit, err := strconv.Atoi(count)
+	if err != nil || limit <= 0 {
+		log.Fatalf("Invalid %s %q (expected <requests>/<period> such as \"10/1m\", or \"off\")", name, raw)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		log.Fatalf("Invalid %s %q (expected <requests>/<period> such as \"10/1m\", or \"off\")", name, raw)
+	}
+	return entity.RateLimit{Limit: limit, Period: d}
+}
+
+// purgeIdempotencyKeys deletes expired idempotency keys every interval
+func purgeIdempotencyKeys(ctx context.Context, store repository.IdempotencyRepository, interval time.Duration) {
+	for now := range ticks(ctx, interval) {
+		if _, err := store.DeleteExpired(ctx, now); err != nil {
+			log.Printf("Failed to purge idempotency keys: %v", err)
+		}
+	}
+}
+
+// purgeRateLimits forgets the rate limit buckets that have refilled every
+// interval
+func purgeRateLimits(ctx context.Context, store repository.RateLimitRepository, interval time.Duration) {
+	for now := range ticks(ctx, interval) {
+		if _, err := store.DeleteExpired(now); err != nil {
+			log.Printf("Failed to purge rate limits: %v", err)
+		}
+	}
+}
+
+// purgeLogins deletes expired login codes and refresh tokens every interval
+func purgeLogins(ctx context.Context, authUsecase usecase.AuthUsecase, interval time.Duration) {
+	for now := range ticks(ctx, interval) {
+		if _, err := authUsecase.PurgeExpired(ctx, now); err != nil {
+			log.Printf("Failed to purge expired logins: %v", err)
+		}
+	}
+}
+
+// purgeNonces deletes the nonces of stale partner requests every interval
+func purgeNonces(ctx context.Context, apiKeyUsecase usecase.APIKeyUsecase, interval time.Duration) {
+	for now := range ticks(ctx, interval) {
+		if _, err := apiKeyUsecase.PurgeExpiredNonces(ctx, now); err != nil {
+			log.Printf("Failed to purge API key nonces: %v", err)
+		}
+	}
+}
+
+// sweepQRRequests expires due QR payment requests every interval
+func sweepQRRequests(ctx context.Context, qrUsecase usecase.QRUsecase, interval time.Duration) {
+	for now := range ticks(ctx, interval) {
+		if _, err := qrUsecase.ExpireDue(ctx, now); err != nil {
+			log.Printf("Failed to expire QR requests: %v", err)
+		}
+	}
+}
+
+// dispatchOutbox delivers due outbox messages every interval
+func dispatchOutbox(ctx context.Context, outboxUsecase usecase.OutboxUsecase, interval time.Duration) {
+	for now := range ticks(ctx, interval) {
+		if _, err := outboxUsecase.DispatchDue(ctx, now); err != nil {
+			log.Printf("Failed to dispatch outbox messages: %v", err)
+		}
+	}
+}
+
+// ticks delivers the time every interval until ctx is done, then closes
+// the channel so the job ranging over it returns
+func ticks(ctx context.Context, interval time.Duration) <-chan time.Time {
+	ch := make(chan time.Time)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				select {
+				case ch <- now:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch
 }
@@ -0,0 +1,196 @@
+// Package qrpayload signs and verifies the payloads encoded in QR payment
+// requests, so a QR code cannot be edited to change who gets paid or how
+// much.
+//
+// A payload is a single line of dot-separated fields:
+//
+//	LBK1.<key id>.<request id>.<recipient id>.<amount>.<expiry>.<signature>
+//
+// The amount and the expiry (Unix seconds) are base 36. The signature is
+// the base64url HMAC-SHA256 of everything before it, truncated to 128 bits,
+// made with the key named by the key ID. Keys rotate through a Keyring: the
+// active key signs new payloads while retired keys keep verifying the
+// payloads already handed out.
+package qrpayload
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version prefixes every payload in the current format
+const Version = "LBK1"
+
+// MinSecretLength is the shortest accepted signing secret in bytes
+const MinSecretLength = 32
+
+// signatureLength is the number of HMAC bytes kept in a payload
+const signatureLength = 16
+
+// Errors returned by Verify, from the cheapest check to the last
+var (
+	ErrMalformed          = errors.New("malformed QR payload")
+	ErrUnsupportedVersion = errors.New("unsupported QR payload version")
+	ErrUnknownKey         = errors.New("unknown QR signing key")
+	ErrBadSignature       = errors.New("invalid QR payload signature")
+	ErrExpired            = errors.New("QR payload has expired")
+)
+
+// Claims are the signed contents of a payload
+type Claims struct {
+	RequestID   string
+	RecipientID string
+	Amount      int
+	ExpiresAt   time.Time
+}
+
+// Key is a named signing secret
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring signs with its active key and verifies with any of its keys
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// NewKeyring creates a keyring that signs with active and also verifies
+// payloads signed with the retired keys
+func NewKeyring(active Key, retired ...Key) (*Keyring, error) {
+	k := &Keyring{active: active.ID, keys: make(map[string][]byte)}
+	for _, key := range append([]Key{active}, retired...) {
+		if !validField(key.ID) || len(key.ID) > 16 {
+			return nil, fmt.Errorf("key ID %q: must be 1-16 letters, digits, '-' or '_'", key.ID)
+		}
+		if len(key.Secret) < MinSecretLength {
+			return nil, fmt.Errorf("key %q: secret must be at least %d bytes", key.ID, MinSecretLength)
+		}
+		if _, dup := k.keys[key.ID]; dup {
+			return nil, fmt.Errorf("key %q: duplicate key ID", key.ID)
+		}
+		k.keys[key.ID] = append([]byte(nil), key.Secret...)
+	}
+	return k, nil
+}
+
+// ParseKeyring reads a keyring from a comma-separated list of
+// <key id>:<base64 secret> pairs. The first key is active; the others are
+// retired and only verify.
+func ParseKeyring(spec string) (*Keyring, error) {
+	var keys []Key
+	for _, pair := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("key %q: expected <key id>:<base64 secret>", pair)
+		}
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: secret is not base64: %w", id, err)
+		}
+		keys = append(keys, Key{ID: id, Secret: secret})
+	}
+	return NewKeyring(keys[0], keys[1:]...)
+}
+
+// GenerateKey creates a key with a random secret
+func GenerateKey(id string) (Key, error) {
+	secret := make([]byte, MinSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, fmt.Errorf("generate key: %w", err)
+	}
+	return Key{ID: id, Secret: secret}, nil
+}
+
+// Sign encodes and signs claims with the active key
+func (k *Keyring) Sign(c Claims) (string, error) {
+	if !validField(c.RequestID) || !validField(c.RecipientID) || c.Amount <= 0 || c.ExpiresAt.IsZero() {
+		return "", fmt.Errorf("sign QR payload: invalid claims %+v", c)
+	}
+
+	unsigned := strings.Join([]string{
+		Version,
+		k.active,
+		c.RequestID,
+		c.RecipientID,
+		strconv.FormatInt(int64(c.Amount), 36),
+		strconv.FormatInt(c.ExpiresAt.Unix(), 36),
+	}, ".")
+	return unsigned + "." + sign(k.keys[k.active], unsigned), nil
+}
+
+// Verify checks a payload's version, key and signature, then its expiry at
+// now, and returns its claims
+func (k *Keyring) Verify(payload string, now time.Time) (Claims, error) {
+	parts := strings.Split(strings.TrimSpace(payload), ".")
+	switch {
+	case parts[0] == Version:
+	case strings.HasPrefix(parts[0], "LBK"):
+		return Claims{}, ErrUnsupportedVersion
+	default:
+		return Claims{}, ErrMalformed
+	}
+	if len(parts) != 7 {
+		return Claims{}, ErrMalformed
+	}
+
+	secret, ok := k.keys[parts[1]]
+	if !ok {
+		return Claims{}, ErrUnknownKey
+	}
+	unsigned := strings.Join(parts[:6], ".")
+	if !hmac.Equal([]byte(parts[6]), []byte(sign(secret, unsigned))) {
+		return Claims{}, ErrBadSignature
+	}
+
+	amount, err := strconv.ParseInt(parts[4], 36, 64)
+	if err != nil || amount <= 0 {
+		return Claims{}, ErrMalformed
+	}
+	expiry, err := strconv.ParseInt(parts[5], 36, 64)
+	if err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	claims := Claims{
+		RequestID:   parts[2],
+		RecipientID: parts[3],
+		Amount:      int(amount),
+		ExpiresAt:   time.Unix(expiry, 0),
+	}
+	if !now.Before(claims.ExpiresAt) {
+		return claims, ErrExpired
+	}
+	return claims, nil
+}
+
+// sign returns the truncated base64url HMAC-SHA256 of message
+func sign(secret []byte, message string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(message))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:signatureLength])
+}
+
+// validField reports whether s can be a payload field: non-empty letters,
+// digits, '-' and '_'
+func validField(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package qrpayload_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/mike/qrpayload"
+)
+
+func newKey(id string, fill byte) qrpayload.Key {
+	return qrpayload.Key{ID: id, Secret: bytes.Repeat([]byte{fill}, qrpayload.MinSecretLength)}
+}
+
+func newClaims() qrpayload.Claims {
+	return qrpayload.Claims{
+		RequestID:   "8d3c2b1a-4e5f-4a6b-9c7d-0e1f2a3b4c5d",
+		RecipientID: "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		Amount:      250,
+		ExpiresAt:   time.Now().Add(15 * time.Minute),
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	keyring, err := qrpayload.NewKeyring(newKey("k1", 1))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	claims := newClaims()
+
+	payload, err := keyring.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if !strings.HasPrefix(payload, qrpayload.Version+".k1.") || len(payload) > 140 {
+		t.Fatalf("unexpected payload %q (%d bytes)", payload, len(payload))
+	}
+
+	got, err := keyring.Verify(payload, time.Now())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.RequestID != claims.RequestID || got.RecipientID != claims.RecipientID ||
+		got.Amount != claims.Amount || got.ExpiresAt.Unix() != claims.ExpiresAt.Unix() {
+		t.Fatalf("claims mismatch\n got: %+v\nwant: %+v", got, claims)
+	}
+
+	if _, err := keyring.Verify(payload, claims.ExpiresAt.Add(time.Second)); !errors.Is(err, qrpayload.ErrExpired) {
+		t.Fatalf("Verify after expiry: expected %v, got %v", qrpayload.ErrExpired, err)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	keyring, _ := qrpayload.NewKeyring(newKey("k1", 1))
+	other, _ := qrpayload.NewKeyring(newKey("k1", 2))
+	payload, _ := keyring.Sign(newClaims())
+	forged, _ := other.Sign(newClaims())
+	parts := strings.Split(payload, ".")
+
+	replace := func(i int, value string) string {
+		edited := append([]string(nil), parts...)
+		edited[i] = value
+		return strings.Join(edited, ".")
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    error
+	}{
+		{"amount changed", replace(4, "zz"), qrpayload.ErrBadSignature},
+		{"recipient changed", replace(3, "attacker"), qrpayload.ErrBadSignature},
+		{"expiry extended", replace(5, "zzzzzzz"), qrpayload.ErrBadSignature},
+		{"signature changed", replace(6, "AAAAAAAAAAAAAAAAAAAAAA"), qrpayload.ErrBadSignature},
+		{"signed with another secret", forged, qrpayload.ErrBadSignature},
+		{"unknown key", replace(1, "k9"), qrpayload.ErrUnknownKey},
+		{"future version", replace(0, "LBK2"), qrpayload.ErrUnsupportedVersion},
+		{"plain request ID", "8d3c2b1a-4e5f-4a6b-9c7d-0e1f2a3b4c5d", qrpayload.ErrMalformed},
+		{"missing fields", strings.Join(parts[:5], "."), qrpayload.ErrMalformed},
+		{"empty", "", qrpayload.ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := keyring.Verify(tt.payload, time.Now()); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify(%q): expected %v, got %v", tt.payload, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, _ := qrpayload.NewKeyring(newKey("k1", 1))
+	issued, _ := old.Sign(newClaims())
+
+	rotated, err := qrpayload.NewKeyring(newKey("k2", 2), newKey("k1", 1))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	if _, err := rotated.Verify(issued, time.Now()); err != nil {
+		t.Fatalf("payload signed with the retired key: %v", err)
+	}
+	fresh, _ := rotated.Sign(newClaims())
+	if !strings.HasPrefix(fresh, qrpayload.Version+".k2.") {
+		t.Fatalf("expected the active key to sign, got %q", fresh)
+	}
+
+	// Once the old key is removed its payloads stop verifying
+	dropped, _ := qrpayload.NewKeyring(newKey("k2", 2))
+	if _, err := dropped.Verify(issued, time.Now()); !errors.Is(err, qrpayload.ErrUnknownKey) {
+		t.Fatalf("expected %v, got %v", qrpayload.ErrUnknownKey, err)
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	secret := func(fill byte) string {
+		return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{fill}, qrpayload.MinSecretLength))
+	}
+
+	keyring, err := qrpayload.ParseKeyring("k2:" + secret(2) + ", k1:" + secret(1))
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	payload, _ := keyring.Sign(newClaims())
+	if !strings.HasPrefix(payload, qrpayload.Version+".k2.") {
+		t.Fatalf("expected the first key to be active, got %q", payload)
+	}
+
+	for _, spec := range []string{
+		"",
+		"k1",
+		"k1:not base64!",
+		"k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"bad.id:" + secret(1),
+		"k1:" + secret(1) + ",k1:" + secret(2),
+	} {
+		if _, err := qrpayload.ParseKeyring(spec); err == nil {
+			t.Errorf("ParseKeyring(%q): expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every APIKeyRepository implementation
+var (
+	// ErrNilAPIKey is returned when Create receives a nil key
+	ErrNilAPIKey = apperror.Validation("API key cannot be nil")
+
+	// ErrInvalidAPIKey is returned for keys without ID, name, scopes,
+	// rate limit or salt, or already rotated or revoked
+	ErrInvalidAPIKey = apperror.Validation("invalid API key")
+
+	// ErrAPIKeyExists is returned when a key with the same ID exists
+	ErrAPIKeyExists = apperror.Conflict("API key already exists")
+
+	// ErrAPIKeyNotFound is returned when no key matches the lookup
+	ErrAPIKeyNotFound = apperror.NotFound("API key not found")
+
+	// ErrAPIKeyRevoked is returned when rotating a revoked key
+	ErrAPIKeyRevoked = apperror.Conflict("API key is revoked")
+
+	// ErrNonceUsed is returned when a key's nonce was recorded before
+	ErrNonceUsed = apperror.Conflict("nonce already used")
+)
+
+// APIKeyRepository stores partner API keys and the nonces of their signed
+// requests
+type APIKeyRepository interface {
+	// Create stores a new key
+	Create(ctx context.Context, key *entity.APIKey) error
+
+	// GetByID retrieves a key by ID
+	GetByID(ctx context.Context, id string) (*entity.APIKey, error)
+
+	// List returns every key, oldest first
+	List(ctx context.Context) ([]*entity.APIKey, error)
+
+	// Rotate gives an active key a new salt at now, keeping the current
+	// salt as the previous one until previousExpiresAt, and returns the
+	// rotated key. Revoked keys cannot be rotated.
+	Rotate(ctx context.Context, id, salt string, previousExpiresAt, now time.Time) (*entity.APIKey, error)
+
+	// Revoke revokes a key at now and returns it. Revoking a revoked key
+	// changes nothing.
+	Revoke(ctx context.Context, id string, now time.Time) (*entity.APIKey, error)
+
+	// UseNonce records that key keyID used nonce, which must be kept until
+	// expiresAt. Recording the same nonce twice fails with ErrNonceUsed,
+	// so of two requests with the same nonce only one is accepted.
+	UseNonce(ctx context.Context, keyID, nonce string, expiresAt time.Time) error
+
+	// DeleteExpiredNonces deletes the nonces expired at or before now and
+	// returns how many were deleted
+	DeleteExpiredNonces(ctx context.Context, now time.Time) (int, error)
+}
+
+// validateAPIKey checks the invariants shared by every implementation
+func validateAPIKey(key *entity.APIKey) error {
+	if key == nil {
+		return ErrNilAPIKey
+	}
+	if key.ID == "" || key.Name == "" || len(key.Scopes) == 0 || key.RateLimit <= 0 || key.Salt == "" ||
+		key.PreviousSalt != "" || key.RotatedAt != nil || key.RevokedAt != nil {
+		return ErrInvalidAPIKey
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every CartRepository implementation
+var (
+	// ErrNilCart is returned when Save receives a nil cart
+	ErrNilCart = apperror.Validation("cart cannot be nil")
+
+	// ErrInvalidCart is returned for carts without a user ID, or with items
+	// lacking a product ID, with a non-positive quantity or listed twice
+	ErrInvalidCart = apperror.Validation("invalid cart")
+)
+
+// CartRepository stores one cart per member
+type CartRepository interface {
+	// Get retrieves a member's cart. A member without a stored cart gets
+	// an empty one.
+	Get(ctx context.Context, userID string) (*entity.Cart, error)
+
+	// Save replaces a member's cart
+	Save(ctx context.Context, cart *entity.Cart) error
+
+	// Delete removes a member's cart. Deleting a cart that does not exist
+	// is not an error.
+	Delete(ctx context.Context, userID string) error
+}
+
+// validateCart checks the invariants shared by every implementation
+func validateCart(cart *entity.Cart) error {
+	if cart == nil {
+		return ErrNilCart
+	}
+	if cart.UserID == "" {
+		return ErrInvalidCart
+	}
+	seen := make(map[string]bool, len(cart.Items))
+	for _, item := range cart.Items {
+		if item.ProductID == "" || item.Quantity <= 0 || seen[item.ProductID] {
+			return ErrInvalidCart
+		}
+		seen[item.ProductID] = true
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every IdempotencyRepository implementation
+var (
+	// ErrNilIdempotencyRecord is returned when a nil record is passed
+	ErrNilIdempotencyRecord = apperror.Validation("idempotency record cannot be nil")
+
+	// ErrInvalidIdempotencyRecord is returned for records without a key,
+	// request hash or expiry
+	ErrInvalidIdempotencyRecord = apperror.Validation("invalid idempotency record")
+
+	// ErrIdempotencyKeyExists is returned by Reserve, together with the
+	// stored record, when the key is already in use
+	ErrIdempotencyKeyExists = apperror.Conflict("idempotency key already exists")
+
+	// ErrIdempotencyKeyNotFound is returned when no reservation matches
+	ErrIdempotencyKeyNotFound = apperror.NotFound("idempotency key not found")
+)
+
+// IdempotencyRepository stores the responses of idempotent requests
+type IdempotencyRepository interface {
+	// Reserve stores a new, uncompleted record unless an unexpired record
+	// with the same key exists, in which case that record is returned with
+	// ErrIdempotencyKeyExists. Expired records are replaced. The check and
+	// the write are atomic, so only one of several concurrent requests wins.
+	Reserve(ctx context.Context, record *entity.IdempotencyRecord) (*entity.IdempotencyRecord, error)
+
+	// Complete stores the response of a reserved record. The reservation
+	// must exist, be uncompleted and have the same request hash.
+	Complete(ctx context.Context, record *entity.IdempotencyRecord) error
+
+	// Release removes an uncompleted reservation so the request can be
+	// retried
+	Release(ctx context.Context, key string) error
+
+	// DeleteExpired removes every record expired at now and returns how
+	// many were removed
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// validateIdempotencyRecord checks the invariants shared by every
+// implementation
+func validateIdempotencyRecord(record *entity.IdempotencyRecord) error {
+	if record == nil {
+		return ErrNilIdempotencyRecord
+	}
+	if record.Key == "" || record.RequestHash == "" || record.ExpiresAt.IsZero() {
+		return ErrInvalidIdempotencyRecord
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every LedgerRepository implementation
+var (
+	// ErrNilPosting is returned when Post receives a nil posting
+	ErrNilPosting = apperror.Validation("posting cannot be nil")
+
+	// ErrInvalidPosting is returned for postings with a missing ID, fewer
+	// than two entries, zero amounts, unknown entry types or an account
+	// appearing twice
+	ErrInvalidPosting = apperror.Validation("invalid posting")
+
+	// ErrUnbalancedPosting is returned when the entry amounts do not sum to zero
+	ErrUnbalancedPosting = apperror.Validation("posting does not balance")
+
+	// ErrPostingExists is returned when a posting with the same ID was
+	// already written
+	ErrPostingExists = apperror.Conflict("posting already exists")
+
+	// ErrInsufficientBalance is returned when a posting would leave a member
+	// account with a negative balance. Implementations check balances
+	// atomically with the write.
+	ErrInsufficientBalance = apperror.Conflict("insufficient balance")
+
+	// ErrPostingNotFound is returned when no posting matches the lookup
+	ErrPostingNotFound = apperror.NotFound("posting not found")
+
+	// ErrEntryNotFound is returned when no ledger entry matches the lookup
+	ErrEntryNotFound = apperror.NotFound("ledger entry not found")
+)
+
+// LedgerRepository stores the append-only points ledger. Entries are never
+// updated or deleted; corrections are new postings.
+//
+// Every method takes the caller's context and gives up with ctx.Err(),
+// possibly wrapped, once it is done. A posting is written in full or not at
+// all.
+type LedgerRepository interface {
+	// Post writes every entry of a balanced posting atomically
+	Post(ctx context.Context, posting *entity.Posting) error
+
+	// GetPosting retrieves a posting with all of its entries
+	GetPosting(ctx context.Context, id string) (*entity.Posting, error)
+
+	// GetEntry retrieves a single ledger entry by ID
+	GetEntry(ctx context.Context, id string) (*entity.LedgerEntry, error)
+
+	// Balance returns the sum of an account's entries. Accounts without
+	// entries have a zero balance.
+	Balance(ctx context.Context, accountID string) (int, error)
+
+	// Balances returns the balances of several accounts at once
+	Balances(ctx context.Context, accountIDs []string) (map[string]int, error)
+
+	// Entries retrieves a page of an account's entries, newest first
+	Entries(ctx context.Context, q LedgerQuery) (*LedgerPage, error)
+}
+
+// LedgerQuery describes a page of an account's ledger entries
+type LedgerQuery struct {
+	// AccountID is required
+	AccountID string
+
+	// Types restricts the entry types; empty means all types
+	Types []entity.EntryType
+
+	// Limit is the maximum number of entries returned; it must be positive
+	Limit int
+
+	// After resumes the listing right after the given position
+	After *LedgerCursor
+}
+
+// LedgerCursor is a keyset position in an account's entries: the creation
+// time and ID of the last entry on the previous page
+type LedgerCursor struct {
+	CreatedAt string `json:"t"`
+	ID        string `json:"id"`
+}
+
+// LedgerPage is one page of ledger entries
+type LedgerPage struct {
+	Entries []*entity.LedgerEntry
+
+	// Next is the cursor for the following page, nil on the last page
+	Next *LedgerCursor
+}
+
+// LedgerCursorFor returns the cursor positioned at entry
+func LedgerCursorFor(entry *entity.LedgerEntry) *LedgerCursor {
+	return &LedgerCursor{CreatedAt: entry.CreatedAt.UTC().Format(cursorTimeFormat), ID: entry.ID}
+}
+
+// normalize rejects malformed queries
+func (q LedgerQuery) normalize() (LedgerQuery, error) {
+	if q.AccountID == "" {
+		return q, apperror.Validation("account ID is required")
+	}
+	if q.Limit <= 0 {
+		return q, apperror.Validation(fmt.Sprintf("limit must be positive, got %d", q.Limit))
+	}
+	for _, t := range q.Types {
+		if !t.Valid() {
+			return q, apperror.Validation(fmt.Sprintf("unknown entry type %q", t))
+		}
+	}
+	if q.After != nil {
+		if _, err := time.Parse(cursorTimeFormat, q.After.CreatedAt); err != nil {
+			return q, apperror.Validation("invalid cursor")
+		}
+	}
+	return q, nil
+}
+
+// matches reports whether entry passes the query filters
+func (q LedgerQuery) matches(entry *entity.LedgerEntry) bool {
+	if entry.AccountID != q.AccountID {
+		return false
+	}
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, t := range q.Types {
+		if entry.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// afterCursor reports whether entry sorts strictly after the cursor in
+// newest-first order
+func (q LedgerQuery) afterCursor(entry *entity.LedgerEntry) bool {
+	if q.After == nil {
+		return true
+	}
+	created := entry.CreatedAt.UTC().Format(cursorTimeFormat)
+	if created != q.After.CreatedAt {
+		return created < q.After.CreatedAt
+	}
+	return entry.ID < q.After.ID
+}
+
+// validatePosting checks the invariants shared by every implementation
+func validatePosting(posting *entity.Posting) error {
+	if posting == nil {
+		return ErrNilPosting
+	}
+	if posting.ID == "" || len(posting.Entries) < 2 {
+		return ErrInvalidPosting
+	}
+
+	accounts := make(map[string]bool, len(posting.Entries))
+	for _, e := range posting.Entries {
+		if e.ID == "" || e.AccountID == "" || e.Amount == 0 || !e.Type.Valid() || accounts[e.AccountID] {
+			return ErrInvalidPosting
+		}
+		accounts[e.AccountID] = true
+	}
+
+	if !posting.Balanced() {
+		return ErrUnbalancedPosting
+	}
+	return nil
+}
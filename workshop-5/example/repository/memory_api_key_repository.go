@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+// memoryAPIKeyRepository implements APIKeyRepository using in-memory
+// storage
+type memoryAPIKeyRepository struct {
+	mu     sync.Mutex
+	keys   map[string]*entity.APIKey
+	nonces map[apiKeyNonce]time.Time
+}
+
+// apiKeyNonce identifies a nonce used by a key
+type apiKeyNonce struct {
+	keyID string
+	nonce string
+}
+
+// NewMemoryAPIKeyRepository creates a new in-memory API key repository
+func NewMemoryAPIKeyRepository() APIKeyRepository {
+	return &memoryAPIKeyRepository{
+		keys:   make(map[string]*entity.APIKey),
+		nonces: make(map[apiKeyNonce]time.Time),
+	}
+}
+
+// Create stores a new key
+func (r *memoryAPIKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateAPIKey(key); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.keys[key.ID]; exists {
+		return ErrAPIKeyExists
+	}
+	r.keys[key.ID] = key.Clone()
+	return nil
+}
+
+// GetByID retrieves a key by ID
+func (r *memoryAPIKeyRepository) GetByID(ctx context.Context, id string) (*entity.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, exists := r.keys[id]
+	if !exists {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key.Clone(), nil
+}
+
+// List returns every key, oldest first
+func (r *memoryAPIKeyRepository) List(ctx context.Context) ([]*entity.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]*entity.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key.Clone())
+	}
+	slices.SortFunc(keys, func(a, b *entity.APIKey) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return keys, nil
+}
+
+// Rotate replaces the salt of an active key under the lock
+func (r *memoryAPIKeyRepository) Rotate(ctx context.Context, id, salt string, previousExpiresAt, now time.Time) (*entity.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, exists := r.keys[id]
+	if !exists {
+		return nil, ErrAPIKeyNotFound
+	}
+	if !key.Active() {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	key.PreviousSalt = key.Salt
+	key.PreviousExpiresAt = &previousExpiresAt
+	key.Salt = salt
+	key.RotatedAt = &now
+	return key.Clone(), nil
+}
+
+// Revoke revokes a key unless it already is
+func (r *memoryAPIKeyRepository) Revoke(ctx context.Context, id string, now time.Time) (*entity.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, exists := r.keys[id]
+	if !exists {
+		return nil, ErrAPIKeyNotFound
+	}
+	if key.Active() {
+		key.RevokedAt = &now
+	}
+	return key.Clone(), nil
+}
+
+// UseNonce records a nonce unless the key used it before
+func (r *memoryAPIKeyRepository) UseNonce(ctx context.Context, keyID, nonce string, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := apiKeyNonce{keyID: keyID, nonce: nonce}
+	if _, used := r.nonces[id]; used {
+		return ErrNonceUsed
+	}
+	r.nonces[id] = expiresAt
+	return nil
+}
+
+// DeleteExpiredNonces deletes nonces expired at now
+func (r *memoryAPIKeyRepository) DeleteExpiredNonces(ctx context.Context, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for id, expiresAt := range r.nonces {
+		if !now.Before(expiresAt) {
+			delete(r.nonces, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
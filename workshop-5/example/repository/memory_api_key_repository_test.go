@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryAPIKeyRepository(t *testing.T) {
+	repositorytest.RunAPIKeyRepositoryTests(t, func(t *testing.T) repository.APIKeyRepository {
+		return repository.NewMemoryAPIKeyRepository()
+	})
+}
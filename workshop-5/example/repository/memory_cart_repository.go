@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"example.com/mike/entity"
+)
+
+// memoryCartRepository implements CartRepository using in-memory storage
+type memoryCartRepository struct {
+	mu    sync.RWMutex
+	carts map[string]*entity.Cart
+}
+
+// NewMemoryCartRepository creates a new in-memory cart repository
+func NewMemoryCartRepository() CartRepository {
+	return &memoryCartRepository{
+		carts: make(map[string]*entity.Cart),
+	}
+}
+
+// Get retrieves a member's cart, or an empty one
+func (r *memoryCartRepository) Get(ctx context.Context, userID string) (*entity.Cart, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cart, ok := r.carts[userID]
+	if !ok {
+		return entity.NewCart(userID), nil
+	}
+	return cart.Clone(), nil
+}
+
+// Save replaces a member's cart
+func (r *memoryCartRepository) Save(ctx context.Context, cart *entity.Cart) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateCart(cart); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.carts[cart.UserID] = cart.Clone()
+	return nil
+}
+
+// Delete removes a member's cart
+func (r *memoryCartRepository) Delete(ctx context.Context, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.carts, userID)
+	return nil
+}
@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryCartRepository(t *testing.T) {
+	repositorytest.RunCartRepositoryTests(t, func(t *testing.T) repository.CartRepository {
+		return repository.NewMemoryCartRepository()
+	})
+}
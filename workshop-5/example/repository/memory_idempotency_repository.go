@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+// memoryIdempotencyRepository implements IdempotencyRepository using
+// in-memory storage
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]*entity.IdempotencyRecord
+}
+
+// NewMemoryIdempotencyRepository creates a new in-memory idempotency
+// repository
+func NewMemoryIdempotencyRepository() IdempotencyRepository {
+	return &memoryIdempotencyRepository{
+		records: make(map[string]*entity.IdempotencyRecord),
+	}
+}
+
+// Reserve stores a new record unless an unexpired one exists for its key
+func (r *memoryIdempotencyRepository) Reserve(ctx context.Context, record *entity.IdempotencyRecord) (*entity.IdempotencyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := validateIdempotencyRecord(record); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.records[record.Key]; ok && !existing.Expired(record.CreatedAt) {
+		return existing.Clone(), ErrIdempotencyKeyExists
+	}
+
+	stored := record.Clone()
+	stored.Completed = false
+	r.records[stored.Key] = stored
+	return nil, nil
+}
+
+// Complete stores the response of a reserved record
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, record *entity.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateIdempotencyRecord(record); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.records[record.Key]
+	if !ok || existing.Completed || existing.RequestHash != record.RequestHash {
+		return ErrIdempotencyKeyNotFound
+	}
+
+	stored := record.Clone()
+	stored.Completed = true
+	stored.CreatedAt, stored.ExpiresAt = existing.CreatedAt, existing.ExpiresAt
+	r.records[stored.Key] = stored
+	return nil
+}
+
+// Release removes an uncompleted reservation
+func (r *memoryIdempotencyRepository) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.records[key]
+	if !ok || existing.Completed {
+		return ErrIdempotencyKeyNotFound
+	}
+	delete(r.records, key)
+	return nil
+}
+
+// DeleteExpired removes every record expired at now
+func (r *memoryIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := 0
+	for key, record := range r.records {
+		if record.Expired(now) {
+			delete(r.records, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryIdempotencyRepository(t *testing.T) {
+	repositorytest.RunIdempotencyRepositoryTests(t, func(t *testing.T) repository.IdempotencyRepository {
+		return repository.NewMemoryIdempotencyRepository()
+	})
+}
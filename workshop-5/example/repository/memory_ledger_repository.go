@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"example.com/mike/entity"
+)
+
+// memoryLedgerRepository implements LedgerRepository using in-memory
+// storage. It is safe for concurrent use and copies entries in and out.
+type memoryLedgerRepository struct {
+	mu       sync.RWMutex
+	postings map[string]*entity.Posting
+	entries  map[string]*entity.LedgerEntry
+
+	// byAccount lists each account's entries in posting order
+	byAccount map[string][]*entity.LedgerEntry
+
+	// balances caches the sum of each account's entries
+	balances map[string]int
+}
+
+// NewMemoryLedgerRepository creates a new in-memory ledger repository
+func NewMemoryLedgerRepository() LedgerRepository {
+	return &memoryLedgerRepository{
+		postings:  make(map[string]*entity.Posting),
+		entries:   make(map[string]*entity.LedgerEntry),
+		byAccount: make(map[string][]*entity.LedgerEntry),
+		balances:  make(map[string]int),
+	}
+}
+
+// Post writes every entry of a balanced posting atomically
+func (r *memoryLedgerRepository) Post(ctx context.Context, posting *entity.Posting) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validatePosting(posting); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.postings[posting.ID]; exists {
+		return ErrPostingExists
+	}
+	for _, e := range posting.Entries {
+		if _, exists := r.entries[e.ID]; exists {
+			return ErrPostingExists
+		}
+		if e.Amount < 0 && !entity.IsSystemAccount(e.AccountID) && r.balances[e.AccountID]+e.Amount < 0 {
+			return ErrInsufficientBalance
+		}
+	}
+
+	stored := posting.Clone()
+	r.postings[stored.ID] = stored
+	for i := range stored.Entries {
+		entry := &stored.Entries[i]
+		r.entries[entry.ID] = entry
+		r.byAccount[entry.AccountID] = append(r.byAccount[entry.AccountID], entry)
+		r.balances[entry.AccountID] += entry.Amount
+	}
+	return nil
+}
+
+// GetPosting retrieves a posting with all of its entries
+func (r *memoryLedgerRepository) GetPosting(ctx context.Context, id string) (*entity.Posting, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	posting, exists := r.postings[id]
+	if !exists {
+		return nil, ErrPostingNotFound
+	}
+	return posting.Clone(), nil
+}
+
+// GetEntry retrieves a single ledger entry by ID
+func (r *memoryLedgerRepository) GetEntry(ctx context.Context, id string) (*entity.LedgerEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, exists := r.entries[id]
+	if !exists {
+		return nil, ErrEntryNotFound
+	}
+	clone := *entry
+	return &clone, nil
+}
+
+// Balance returns the sum of an account's entries
+func (r *memoryLedgerRepository) Balance(ctx context.Context, accountID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.balances[accountID], nil
+}
+
+// Balances returns the balances of several accounts at once
+func (r *memoryLedgerRepository) Balances(ctx context.Context, accountIDs []string) (map[string]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	balances := make(map[string]int, len(accountIDs))
+	for _, id := range accountIDs {
+		balances[id] = r.balances[id]
+	}
+	return balances, nil
+}
+
+// Entries retrieves a page of an account's entries, newest first
+func (r *memoryLedgerRepository) Entries(ctx context.Context, q LedgerQuery) (*LedgerPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	q, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var matched []*entity.LedgerEntry
+	for _, entry := range r.byAccount[q.AccountID] {
+		if q.matches(entry) && q.afterCursor(entry) {
+			clone := *entry
+			matched = append(matched, &clone)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	page := &LedgerPage{Entries: matched}
+	if len(matched) > q.Limit {
+		page.Entries = matched[:q.Limit]
+		page.Next = LedgerCursorFor(page.Entries[q.Limit-1])
+	}
+	if page.Entries == nil {
+		page.Entries = []*entity.LedgerEntry{}
+	}
+	return page, nil
+}
@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryLedgerRepository(t *testing.T) {
+	repositorytest.RunLedgerRepositoryTests(t, func(t *testing.T) repository.LedgerRepository {
+		return repository.NewMemoryLedgerRepository()
+	})
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"example.com/mike/entity"
+)
+
+// memoryOrderRepository implements OrderRepository using in-memory
+// storage, settling orders against the given product, ledger and cart
+// repositories and enqueuing messages in the given outbox
+type memoryOrderRepository struct {
+	mu       sync.RWMutex
+	orders   map[string]*entity.Order
+	payments map[string]*entity.Payment // by order ID
+	products ProductRepository
+	ledger   LedgerRepository
+	carts    CartRepository
+	outbox   OutboxRepository
+}
+
+// NewMemoryOrderRepository creates a new in-memory order repository that
+// reserves stock from products, posts payments to ledger, empties carts and
+// enqueues messages in outbox
+func NewMemoryOrderRepository(
+	products ProductRepository,
+	ledger LedgerRepository,
+	carts CartRepository,
+	outbox OutboxRepository,
+) OrderRepository {
+	return &memoryOrderRepository{
+		orders:   make(map[string]*entity.Order),
+		payments: make(map[string]*entity.Payment),
+		products: products,
+		ledger:   ledger,
+		carts:    carts,
+		outbox:   outbox,
+	}
+}
+
+// Place reserves stock, posts the payment, enqueues the messages and
+// stores the order. The write lock is held throughout; if a later step
+// fails the earlier ones are undone, so an order is never stored without
+// its stock, payment and messages or vice versa.
+func (r *memoryOrderRepository) Place(ctx context.Context, order *entity.Order, payment *entity.Payment, messages ...*entity.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateOrder(order, payment, messages); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.orders[order.ID]; exists {
+		return ErrOrderExists
+	}
+
+	// Like a SQL transaction, the steps below either all happen or are all
+	// undone, so they run to completion once started
+	ctx = context.WithoutCancel(ctx)
+	for _, message := range messages {
+		if _, err := r.outbox.GetByID(ctx, message.ID); err == nil {
+			return ErrOutboxMessageExists
+		}
+	}
+
+	quantities := order.Quantities()
+	if err := r.products.Reserve(ctx, quantities); err != nil {
+		return err
+	}
+	if err := r.ledger.Post(ctx, payment.Posting()); err != nil {
+		if restockErr := r.products.Restock(ctx, quantities); restockErr != nil {
+			return restockErr
+		}
+		return err
+	}
+	if err := r.outbox.Enqueue(ctx, messages...); err != nil {
+		if reverseErr := r.ledger.Post(ctx, payment.Reversal()); reverseErr != nil {
+			return reverseErr
+		}
+		if restockErr := r.products.Restock(ctx, quantities); restockErr != nil {
+			return restockErr
+		}
+		return err
+	}
+
+	r.orders[order.ID] = order.Clone()
+	r.payments[order.ID] = payment.Clone()
+	return r.carts.Delete(ctx, order.UserID)
+}
+
+// Transition changes the order status under the write lock. The payment
+// reversal is posted first since it is the only step that can fail.
+func (r *memoryOrderRepository) Transition(ctx context.Context, t OrderTransition) (*entity.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := validateOrderTransition(t); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, exists := r.orders[t.OrderID]
+	if !exists {
+		return nil, ErrOrderNotFound
+	}
+	if order.Status != t.Event.From {
+		return nil, ErrOrderStatusChanged
+	}
+
+	// Once the refund is posted the restock must follow, as in Place
+	ctx = context.WithoutCancel(ctx)
+
+	if t.RefundPayment {
+		payment := r.payments[order.ID]
+		if err := r.ledger.Post(ctx, payment.Reversal()); err != nil {
+			return nil, err
+		}
+		payment.Status = entity.PaymentRefunded
+	}
+	if t.Restock {
+		// Stored orders only hold positive quantities, so this cannot fail
+		if err := r.products.Restock(ctx, order.Quantities()); err != nil {
+			return nil, err
+		}
+	}
+
+	order.Status = t.Event.To
+	order.Events = append(order.Events, t.Event)
+	order.UpdatedAt = t.Event.CreatedAt
+	return order.Clone(), nil
+}
+
+// GetByID retrieves an order by ID
+func (r *memoryOrderRepository) GetByID(ctx context.Context, id string) (*entity.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	order, exists := r.orders[id]
+	if !exists {
+		return nil, ErrOrderNotFound
+	}
+	return order.Clone(), nil
+}
+
+// GetPayment retrieves the payment of an order
+func (r *memoryOrderRepository) GetPayment(ctx context.Context, orderID string) (*entity.Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	payment, exists := r.payments[orderID]
+	if !exists {
+		return nil, ErrPaymentNotFound
+	}
+	return payment.Clone(), nil
+}
@@ -0,0 +1,24 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryOrderRepository(t *testing.T) {
+	repositorytest.RunOrderRepositoryTests(t, func(t *testing.T) repositorytest.OrderRepositories {
+		products := repository.NewMemoryProductRepository()
+		ledger := repository.NewMemoryLedgerRepository()
+		carts := repository.NewMemoryCartRepository()
+		outbox := repository.NewMemoryOutboxRepository()
+		return repositorytest.OrderRepositories{
+			Orders:   repository.NewMemoryOrderRepository(products, ledger, carts, outbox),
+			Products: products,
+			Ledger:   ledger,
+			Carts:    carts,
+			Outbox:   outbox,
+		}
+	})
+}
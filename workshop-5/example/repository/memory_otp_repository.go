@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+// memoryOTPRepository implements OTPRepository using in-memory storage
+type memoryOTPRepository struct {
+	mu         sync.Mutex
+	challenges map[string]*entity.OTPChallenge
+}
+
+// NewMemoryOTPRepository creates a new in-memory OTP challenge repository
+func NewMemoryOTPRepository() OTPRepository {
+	return &memoryOTPRepository{
+		challenges: make(map[string]*entity.OTPChallenge),
+	}
+}
+
+// Create stores a new challenge
+func (r *memoryOTPRepository) Create(ctx context.Context, challenge *entity.OTPChallenge) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateOTPChallenge(challenge); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.challenges[challenge.ID]; exists {
+		return ErrOTPChallengeExists
+	}
+	r.challenges[challenge.ID] = challenge.Clone()
+	return nil
+}
+
+// GetByID retrieves a challenge by ID
+func (r *memoryOTPRepository) GetByID(ctx context.Context, id string) (*entity.OTPChallenge, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, exists := r.challenges[id]
+	if !exists {
+		return nil, ErrOTPChallengeNotFound
+	}
+	return challenge.Clone(), nil
+}
+
+// Attempt counts a code attempt against an open challenge
+func (r *memoryOTPRepository) Attempt(ctx context.Context, id string, now time.Time, maxAttempts int) (*entity.OTPChallenge, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, exists := r.challenges[id]
+	if !exists {
+		return nil, ErrOTPChallengeNotFound
+	}
+	if challenge.ConsumedAt != nil || challenge.Expired(now) || challenge.Attempts >= maxAttempts {
+		return nil, ErrOTPChallengeClosed
+	}
+	challenge.Attempts++
+	return challenge.Clone(), nil
+}
+
+// Consume marks a challenge as used
+func (r *memoryOTPRepository) Consume(ctx context.Context, id string, now time.Time) (*entity.OTPChallenge, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, exists := r.challenges[id]
+	if !exists {
+		return nil, ErrOTPChallengeNotFound
+	}
+	if challenge.ConsumedAt != nil {
+		return nil, ErrOTPChallengeClosed
+	}
+	challenge.ConsumedAt = &now
+	return challenge.Clone(), nil
+}
+
+// DeleteExpired deletes challenges expired at now
+func (r *memoryOTPRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for id, challenge := range r.challenges {
+		if challenge.Expired(now) {
+			delete(r.challenges, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
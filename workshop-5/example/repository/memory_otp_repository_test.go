@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryOTPRepository(t *testing.T) {
+	repositorytest.RunOTPRepositoryTests(t, func(t *testing.T) repository.OTPRepository {
+		return repository.NewMemoryOTPRepository()
+	})
+}
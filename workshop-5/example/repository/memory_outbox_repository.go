@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+// memoryOutboxRepository implements OutboxRepository using in-memory
+// storage
+type memoryOutboxRepository struct {
+	mu       sync.RWMutex
+	messages map[string]*entity.OutboxMessage
+}
+
+// NewMemoryOutboxRepository creates a new in-memory outbox repository
+func NewMemoryOutboxRepository() OutboxRepository {
+	return &memoryOutboxRepository{
+		messages: make(map[string]*entity.OutboxMessage),
+	}
+}
+
+// Enqueue stores new messages, checking the whole batch before storing any
+func (r *memoryOutboxRepository) Enqueue(ctx context.Context, messages ...*entity.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateOutboxMessages(messages); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make(map[string]bool, len(messages))
+	for _, message := range messages {
+		if _, exists := r.messages[message.ID]; exists || ids[message.ID] {
+			return ErrOutboxMessageExists
+		}
+		ids[message.ID] = true
+	}
+	for _, message := range messages {
+		r.messages[message.ID] = message.Clone()
+	}
+	return nil
+}
+
+// ClaimDue claims the earliest due pending messages
+func (r *memoryOutboxRepository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*entity.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := r.filter(func(m *entity.OutboxMessage) bool {
+		return m.Status == entity.OutboxPending && !m.NextAttemptAt.After(now)
+	})
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*entity.OutboxMessage, len(due))
+	for i, message := range due {
+		claimed[i] = r.claim(message, now, lease)
+	}
+	return claimed, nil
+}
+
+// Claim claims a single pending message if it is due
+func (r *memoryOutboxRepository) Claim(ctx context.Context, id string, now time.Time, lease time.Duration) (*entity.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	message, exists := r.messages[id]
+	if !exists {
+		return nil, ErrOutboxMessageNotFound
+	}
+	if message.Status != entity.OutboxPending || message.NextAttemptAt.After(now) {
+		return nil, ErrOutboxMessageNotDue
+	}
+	return r.claim(message, now, lease), nil
+}
+
+// Update stores the dispatch fields of an existing message
+func (r *memoryOutboxRepository) Update(ctx context.Context, message *entity.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateOutboxMessage(message); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.messages[message.ID]
+	if !exists {
+		return ErrOutboxMessageNotFound
+	}
+	stored.Status = message.Status
+	stored.Attempts = message.Attempts
+	stored.NextAttemptAt = message.NextAttemptAt
+	stored.LastError = message.LastError
+	stored.UpdatedAt = message.UpdatedAt
+	return nil
+}
+
+// Replay moves an undelivered message back to pending
+func (r *memoryOutboxRepository) Replay(ctx context.Context, id string, now time.Time) (*entity.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	message, exists := r.messages[id]
+	if !exists {
+		return nil, ErrOutboxMessageNotFound
+	}
+	if message.Status == entity.OutboxDelivered {
+		return nil, ErrOutboxMessageDelivered
+	}
+
+	message.Status = entity.OutboxPending
+	message.Attempts = 0
+	message.NextAttemptAt = now
+	message.UpdatedAt = now
+	return message.Clone(), nil
+}
+
+// GetByID retrieves a message by ID
+func (r *memoryOutboxRepository) GetByID(ctx context.Context, id string) (*entity.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	message, exists := r.messages[id]
+	if !exists {
+		return nil, ErrOutboxMessageNotFound
+	}
+	return message.Clone(), nil
+}
+
+// List returns messages with status, oldest first
+func (r *memoryOutboxRepository) List(ctx context.Context, status entity.OutboxStatus, limit int) ([]*entity.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := r.filter(func(m *entity.OutboxMessage) bool {
+		return status == "" || m.Status == status
+	})
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.Before(matches[j].CreatedAt)
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	messages := make([]*entity.OutboxMessage, len(matches))
+	for i, message := range matches {
+		messages[i] = message.Clone()
+	}
+	return messages, nil
+}
+
+// filter returns the stored messages matching keep; callers hold the lock
+func (r *memoryOutboxRepository) filter(keep func(*entity.OutboxMessage) bool) []*entity.OutboxMessage {
+	var matches []*entity.OutboxMessage
+	for _, message := range r.messages {
+		if keep(message) {
+			matches = append(matches, message)
+		}
+	}
+	return matches
+}
+
+// claim counts an attempt and leases the message; callers hold the lock
+func (r *memoryOutboxRepository) claim(message *entity.OutboxMessage, now time.Time, lease time.Duration) *entity.OutboxMessage {
+	message.Attempts++
+	message.NextAttemptAt = now.Add(lease)
+	message.UpdatedAt = now
+	return message.Clone()
+}
@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryOutboxRepository(t *testing.T) {
+	repositorytest.RunOutboxRepositoryTests(t, func(t *testing.T) repository.OutboxRepository {
+		return repository.NewMemoryOutboxRepository()
+	})
+}
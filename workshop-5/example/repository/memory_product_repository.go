@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+// memoryProductRepository implements ProductRepository using in-memory
+// storage
+type memoryProductRepository struct {
+	mu       sync.RWMutex
+	products map[string]*entity.Product
+}
+
+// NewMemoryProductRepository creates a new in-memory product repository
+func NewMemoryProductRepository() ProductRepository {
+	return &memoryProductRepository{
+		products: make(map[string]*entity.Product),
+	}
+}
+
+// Create stores a new product
+func (r *memoryProductRepository) Create(ctx context.Context, product *entity.Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateProduct(product); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.products[product.ID]; exists {
+		return ErrProductExists
+	}
+	r.products[product.ID] = product.Clone()
+	return nil
+}
+
+// GetByID retrieves a product by ID
+func (r *memoryProductRepository) GetByID(ctx context.Context, id string) (*entity.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	product, ok := r.products[id]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	return product.Clone(), nil
+}
+
+// Update replaces an existing product, keeping its creation time
+func (r *memoryProductRepository) Update(ctx context.Context, product *entity.Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateProduct(product); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.products[product.ID]
+	if !ok {
+		return ErrProductNotFound
+	}
+
+	product.CreatedAt = existing.CreatedAt
+	product.UpdatedAt = time.Now()
+	r.products[product.ID] = product.Clone()
+	return nil
+}
+
+// Delete deletes a product by ID
+func (r *memoryProductRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.products[id]; !ok {
+		return ErrProductNotFound
+	}
+	delete(r.products, id)
+	return nil
+}
+
+// Query retrieves a filtered page of products ordered by name
+func (r *memoryProductRepository) Query(ctx context.Context, q ProductQuery) (*ProductPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	q, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	matched := make([]*entity.Product, 0)
+	for _, product := range r.products {
+		if q.matches(product) {
+			matched = append(matched, product.Clone())
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return compareProducts(matched[i].Name, matched[i].ID, matched[j].Name, matched[j].ID) < 0
+	})
+
+	page := &ProductPage{Products: matched}
+	if len(matched) > q.Limit {
+		page.Products = matched[:q.Limit]
+		last := page.Products[q.Limit-1]
+		page.Next = &ProductCursor{Name: last.Name, ID: last.ID}
+	}
+	return page, nil
+}
+
+// Reserve takes quantities out of stock, checking every product before
+// changing any
+func (r *memoryProductRepository) Reserve(ctx context.Context, quantities map[string]int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateQuantities(quantities); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := sortedProductIDs(quantities)
+	for _, id := range ids {
+		product, ok := r.products[id]
+		switch {
+		case !ok:
+			return ErrProductNotFound
+		case !product.Active:
+			return ErrProductInactive
+		case product.Stock < quantities[id]:
+			return ErrOutOfStock
+		}
+	}
+
+	now := time.Now()
+	for _, id := range ids {
+		r.products[id].Stock -= quantities[id]
+		r.products[id].UpdatedAt = now
+	}
+	return nil
+}
+
+// Restock puts quantities back into stock
+func (r *memoryProductRepository) Restock(ctx context.Context, quantities map[string]int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateQuantities(quantities); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, quantity := range quantities {
+		if product, ok := r.products[id]; ok {
+			product.Stock += quantity
+			product.UpdatedAt = now
+		}
+	}
+	return nil
+}
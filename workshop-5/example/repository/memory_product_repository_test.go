@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryProductRepository(t *testing.T) {
+	repositorytest.RunProductRepositoryTests(t, func(t *testing.T) repository.ProductRepository {
+		return repository.NewMemoryProductRepository()
+	})
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+// memoryQRRequestRepository implements QRRequestRepository using in-memory
+// storage
+type memoryQRRequestRepository struct {
+	mu       sync.RWMutex
+	requests map[string]*entity.QRRequest
+}
+
+// NewMemoryQRRequestRepository creates a new in-memory QR request repository
+func NewMemoryQRRequestRepository() QRRequestRepository {
+	return &memoryQRRequestRepository{
+		requests: make(map[string]*entity.QRRequest),
+	}
+}
+
+// Create stores a new pending request
+func (r *memoryQRRequestRepository) Create(ctx context.Context, request *entity.QRRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateQRRequest(request); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.requests[request.ID]; exists {
+		return ErrQRRequestExists
+	}
+	r.requests[request.ID] = request.Clone()
+	return nil
+}
+
+// GetByID retrieves a request by ID
+func (r *memoryQRRequestRepository) GetByID(ctx context.Context, id string) (*entity.QRRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	request, ok := r.requests[id]
+	if !ok {
+		return nil, ErrQRRequestNotFound
+	}
+	return request.Clone(), nil
+}
+
+// MarkPaid moves a pending request to paid
+func (r *memoryQRRequestRepository) MarkPaid(ctx context.Context, id, payerID, transferID string) (*entity.QRRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return r.transition(id, paidBy(payerID, transferID))
+}
+
+// MarkExpired moves a pending request to expired
+func (r *memoryQRRequestRepository) MarkExpired(ctx context.Context, id string) (*entity.QRRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return r.transition(id, func(request *entity.QRRequest) {
+		request.Status = entity.QRRequestExpired
+	})
+}
+
+// ListDue returns pending requests expired at now, earliest first
+func (r *memoryQRRequestRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*entity.QRRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	due := make([]*entity.QRRequest, 0)
+	for _, request := range r.requests {
+		if request.Due(now) {
+			due = append(due, request.Clone())
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].ExpiresAt.Equal(due[j].ExpiresAt) {
+			return due[i].ExpiresAt.Before(due[j].ExpiresAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// transition applies change to a pending request under the lock
+func (r *memoryQRRequestRepository) transition(id string, change func(*entity.QRRequest)) (*entity.QRRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	request, ok := r.requests[id]
+	if !ok {
+		return nil, ErrQRRequestNotFound
+	}
+	if request.Status != entity.QRRequestPending {
+		return nil, ErrQRRequestNotPending
+	}
+
+	change(request)
+	request.UpdatedAt = time.Now()
+	return request.Clone(), nil
+}
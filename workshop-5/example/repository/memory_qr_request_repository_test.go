@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryQRRequestRepository(t *testing.T) {
+	repositorytest.RunQRRequestRepositoryTests(t, func(t *testing.T) repository.QRRequestRepository {
+		return repository.NewMemoryQRRequestRepository()
+	})
+}
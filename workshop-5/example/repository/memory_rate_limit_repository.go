@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+// memoryRateLimitRepository implements RateLimitRepository using in-memory
+// storage. Buckets are per process, so replicas behind a load balancer
+// each allow the full limit.
+type memoryRateLimitRepository struct {
+	mu      sync.Mutex
+	buckets map[string]*entity.TokenBucket
+}
+
+// NewMemoryRateLimitRepository creates a new in-memory rate limit
+// repository
+func NewMemoryRateLimitRepository() RateLimitRepository {
+	return &memoryRateLimitRepository{
+		buckets: make(map[string]*entity.TokenBucket),
+	}
+}
+
+// Take takes a token under the lock
+func (r *memoryRateLimitRepository) Take(key string, limit entity.RateLimit, now time.Time) (entity.RateLimitDecision, error) {
+	if key == "" || !limit.Enabled() {
+		return entity.RateLimitDecision{}, ErrInvalidRateLimit
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bucket, exists := r.buckets[key]
+	if !exists {
+		bucket = entity.NewTokenBucket(key, limit, now)
+		r.buckets[key] = bucket
+	}
+	return bucket.Take(limit, now), nil
+}
+
+// DeleteExpired deletes buckets full again at now
+func (r *memoryRateLimitRepository) DeleteExpired(now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for key, bucket := range r.buckets {
+		if !now.Before(bucket.FullAt) {
+			delete(r.buckets, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
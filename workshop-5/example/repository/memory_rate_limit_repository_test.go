@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryRateLimitRepository(t *testing.T) {
+	repositorytest.RunRateLimitRepositoryTests(t, func(t *testing.T) repository.RateLimitRepository {
+		return repository.NewMemoryRateLimitRepository()
+	})
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+// memoryReceiptRepository implements ReceiptRepository using in-memory
+// storage, enqueuing messages in the given outbox
+type memoryReceiptRepository struct {
+	mu       sync.RWMutex
+	receipts map[string]*entity.Receipt // by order ID
+	ids      map[string]bool
+	outbox   OutboxRepository
+}
+
+// NewMemoryReceiptRepository creates a new in-memory receipt repository
+// that enqueues messages in outbox
+func NewMemoryReceiptRepository(outbox OutboxRepository) ReceiptRepository {
+	return &memoryReceiptRepository{
+		receipts: make(map[string]*entity.Receipt),
+		ids:      make(map[string]bool),
+		outbox:   outbox,
+	}
+}
+
+// Create stores a new receipt
+func (r *memoryReceiptRepository) Create(ctx context.Context, receipt *entity.Receipt) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateReceipt(receipt, nil); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.receipts[receipt.OrderID]; exists || r.ids[receipt.ID] {
+		return ErrReceiptExists
+	}
+	r.receipts[receipt.OrderID] = receipt.Clone()
+	r.ids[receipt.ID] = true
+	return nil
+}
+
+// Update stores the delivery fields of an existing receipt. The messages
+// are enqueued first, since that is the only step that can fail.
+func (r *memoryReceiptRepository) Update(ctx context.Context, receipt *entity.Receipt, messages ...*entity.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateReceipt(receipt, messages); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.receipts[receipt.OrderID]
+	if !ok || stored.ID != receipt.ID {
+		return ErrReceiptNotFound
+	}
+	if err := r.outbox.Enqueue(ctx, messages...); err != nil {
+		return err
+	}
+	stored.ProviderRef = receipt.ProviderRef
+	stored.Status = receipt.Status
+	stored.Error = receipt.Error
+	stored.UpdatedAt = receipt.UpdatedAt
+	return nil
+}
+
+// GetByOrderID retrieves the receipt of an order
+func (r *memoryReceiptRepository) GetByOrderID(ctx context.Context, orderID string) (*entity.Receipt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	receipt, ok := r.receipts[orderID]
+	if !ok {
+		return nil, ErrReceiptNotFound
+	}
+	return receipt.Clone(), nil
+}
+
+// GetByProviderRef retrieves a receipt by the provider's message ID
+func (r *memoryReceiptRepository) GetByProviderRef(ctx context.Context, providerRef string) (*entity.Receipt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	receipt := r.byProviderRef(providerRef)
+	if receipt == nil {
+		return nil, ErrReceiptNotFound
+	}
+	return receipt.Clone(), nil
+}
+
+// MarkDelivery moves a sent receipt to delivered or failed, enqueuing the
+// messages first
+func (r *memoryReceiptRepository) MarkDelivery(
+	ctx context.Context, providerRef string, status entity.ReceiptStatus, reason string, messages ...*entity.OutboxMessage,
+) (*entity.Receipt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := validateDelivery(providerRef, status, messages); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	receipt := r.byProviderRef(providerRef)
+	if receipt == nil {
+		return nil, ErrReceiptNotFound
+	}
+	if receipt.Status != entity.ReceiptSent {
+		return nil, ErrReceiptNotSent
+	}
+	if err := r.outbox.Enqueue(ctx, messages...); err != nil {
+		return nil, err
+	}
+
+	receipt.Status = status
+	receipt.Error = reason
+	receipt.UpdatedAt = time.Now()
+	return receipt.Clone(), nil
+}
+
+// byProviderRef finds a receipt by provider reference; callers hold the lock
+func (r *memoryReceiptRepository) byProviderRef(providerRef string) *entity.Receipt {
+	if providerRef == "" {
+		return nil
+	}
+	for _, receipt := range r.receipts {
+		if receipt.ProviderRef == providerRef {
+			return receipt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryReceiptRepository(t *testing.T) {
+	repositorytest.RunReceiptRepositoryTests(t, func(t *testing.T) repositorytest.ReceiptRepositories {
+		outbox := repository.NewMemoryOutboxRepository()
+		return repositorytest.ReceiptRepositories{
+			Receipts: repository.NewMemoryReceiptRepository(outbox),
+			Outbox:   outbox,
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+// memoryRefreshTokenRepository implements RefreshTokenRepository using
+// in-memory storage
+type memoryRefreshTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]*entity.RefreshToken
+}
+
+// NewMemoryRefreshTokenRepository creates a new in-memory refresh token
+// repository
+func NewMemoryRefreshTokenRepository() RefreshTokenRepository {
+	return &memoryRefreshTokenRepository{
+		tokens: make(map[string]*entity.RefreshToken),
+	}
+}
+
+// Create stores the first token of a new family
+func (r *memoryRefreshTokenRepository) Create(ctx context.Context, token *entity.RefreshToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateRefreshToken(token); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tokens[token.ID]; exists {
+		return ErrRefreshTokenExists
+	}
+	r.tokens[token.ID] = token.Clone()
+	return nil
+}
+
+// GetByID retrieves a token by ID
+func (r *memoryRefreshTokenRepository) GetByID(ctx context.Context, id string) (*entity.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, exists := r.tokens[id]
+	if !exists {
+		return nil, ErrRefreshTokenNotFound
+	}
+	return token.Clone(), nil
+}
+
+// Rotate replaces an active token with its successor under the lock
+func (r *memoryRefreshTokenRepository) Rotate(ctx context.Context, id string, next *entity.RefreshToken, now time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateRefreshToken(next); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.tokens[id]
+	if !exists {
+		return ErrRefreshTokenNotFound
+	}
+	if next.FamilyID != current.FamilyID || next.UserID != current.UserID {
+		return ErrInvalidRefreshToken
+	}
+	if !current.Active(now) {
+		return ErrRefreshTokenInactive
+	}
+	if _, exists := r.tokens[next.ID]; exists {
+		return ErrRefreshTokenExists
+	}
+
+	current.RotatedAt = &now
+	r.tokens[next.ID] = next.Clone()
+	return nil
+}
+
+// RevokeFamily revokes every unrevoked token of a family
+func (r *memoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revoked := 0
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// DeleteExpired deletes tokens expired at now
+func (r *memoryRefreshTokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for id, token := range r.tokens {
+		if !now.Before(token.ExpiresAt) {
+			delete(r.tokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryRefreshTokenRepository(t *testing.T) {
+	repositorytest.RunRefreshTokenRepositoryTests(t, func(t *testing.T) repository.RefreshTokenRepository {
+		return repository.NewMemoryRefreshTokenRepository()
+	})
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+// memoryTransferRepository implements TransferRepository using in-memory
+// storage, settling transfers against the given ledger and QR requests
+type memoryTransferRepository struct {
+	mu         sync.RWMutex
+	transfers  map[string]*entity.Transfer
+	ledger     LedgerRepository
+	qrRequests QRRequestRepository
+}
+
+// NewMemoryTransferRepository creates a new in-memory transfer repository
+// that posts to ledger and marks the QR requests transfers pay in
+// qrRequests
+func NewMemoryTransferRepository(ledger LedgerRepository, qrRequests QRRequestRepository) TransferRepository {
+	return &memoryTransferRepository{
+		transfers:  make(map[string]*entity.Transfer),
+		ledger:     ledger,
+		qrRequests: qrRequests,
+	}
+}
+
+// Create stores a new pending transfer
+func (r *memoryTransferRepository) Create(ctx context.Context, transfer *entity.Transfer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateTransfer(transfer); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.transfers[transfer.ID]; exists {
+		return ErrTransferExists
+	}
+	r.transfers[transfer.ID] = transfer.Clone()
+	return nil
+}
+
+// GetByID retrieves a transfer by ID
+func (r *memoryTransferRepository) GetByID(ctx context.Context, id string) (*entity.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transfer, exists := r.transfers[id]
+	if !exists {
+		return nil, ErrTransferNotFound
+	}
+	return transfer.Clone(), nil
+}
+
+// Complete posts the transfer to the ledger, marks the QR request it pays,
+// if any, paid and marks the transfer posted. The write lock is held
+// throughout; if marking the request fails the posting is reversed, so a
+// transfer is never posted without its entries and request or vice versa.
+func (r *memoryTransferRepository) Complete(ctx context.Context, id string) (*entity.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transfer, err := r.pending(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The posting is undone if the request cannot be paid, so neither step
+	// may be cancelled once started
+	ctx = context.WithoutCancel(ctx)
+	if transfer.QRRequestID != "" {
+		request, err := r.qrRequests.GetByID(ctx, transfer.QRRequestID)
+		if err != nil {
+			return nil, err
+		}
+		if request.Status != entity.QRRequestPending {
+			return nil, ErrQRRequestNotPending
+		}
+	}
+
+	if err := r.ledger.Post(ctx, transfer.Posting()); err != nil {
+		return nil, err
+	}
+	if transfer.QRRequestID != "" {
+		if _, err := r.qrRequests.MarkPaid(ctx, transfer.QRRequestID, transfer.FromUserID, transfer.ID); err != nil {
+			if reverseErr := r.ledger.Post(ctx, transfer.Reversal()); reverseErr != nil {
+				return nil, reverseErr
+			}
+			return nil, err
+		}
+	}
+
+	transfer.Status = entity.TransferPosted
+	transfer.UpdatedAt = time.Now()
+	return transfer.Clone(), nil
+}
+
+// Fail marks a pending transfer as failed
+func (r *memoryTransferRepository) Fail(ctx context.Context, id, reason string) (*entity.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transfer, err := r.pending(id)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.Status = entity.TransferFailed
+	transfer.FailureReason = reason
+	transfer.UpdatedAt = time.Now()
+	return transfer.Clone(), nil
+}
+
+// PostedTotalSince sums a member's posted outgoing transfers since a time
+func (r *memoryTransferRepository) PostedTotalSince(ctx context.Context, fromUserID string, since time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	total := 0
+	for _, transfer := range r.transfers {
+		if transfer.FromUserID == fromUserID && transfer.Status == entity.TransferPosted &&
+			!transfer.CreatedAt.Before(since) {
+			total += transfer.Amount
+		}
+	}
+	return total, nil
+}
+
+// pending returns the stored transfer if it is pending. Callers must hold
+// the write lock.
+func (r *memoryTransferRepository) pending(id string) (*entity.Transfer, error) {
+	transfer, exists := r.transfers[id]
+	if !exists {
+		return nil, ErrTransferNotFound
+	}
+	if transfer.Status != entity.TransferPending {
+		return nil, ErrTransferNotPending
+	}
+	return transfer, nil
+}
@@ -0,0 +1,15 @@
+package repository_test
+
+import (
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestMemoryTransferRepository(t *testing.T) {
+	repositorytest.RunTransferRepositoryTests(t, func(t *testing.T) (repository.TransferRepository, repository.LedgerRepository, repository.QRRequestRepository) {
+		ledger, qrRequests := repository.NewMemoryLedgerRepository(), repository.NewMemoryQRRequestRepository()
+		return repository.NewMemoryTransferRepository(ledger, qrRequests), ledger, qrRequests
+	})
+}
@@ -1,89 +1,255 @@
 package repository
 
 import (
-	"errors"
+	"context"
+	"sort"
+	"sync"
 
 	"example.com/mike/entity"
 )
 
-// memoryUserRepository implements UserRepository using in-memory storage
+// memoryUserRepository implements UserRepository using in-memory storage.
+// It is safe for concurrent use; stored users are copied on the way in and
+// on the way out so callers can never mutate the repository's state.
 type memoryUserRepository struct {
+	mu    sync.RWMutex
 	users map[string]*entity.User
+
+	// Secondary indexes mapping a unique field to the user ID
+	byEmail    map[string]string
+	byPhone    map[string]string
+	byMemberID map[string]string
+
+	// memberSeq is the last member sequence number handed out
+	memberSeq int64
 }
 
 // NewMemoryUserRepository creates a new in-memory user repository
 func NewMemoryUserRepository() UserRepository {
 	return &memoryUserRepository{
-		users: make(map[string]*entity.User),
+		users:      make(map[string]*entity.User),
+		byEmail:    make(map[string]string),
+		byPhone:    make(map[string]string),
+		byMemberID: make(map[string]string),
 	}
 }
 
 // Create creates a new user
-func (r *memoryUserRepository) Create(user *entity.User) error {
+func (r *memoryUserRepository) Create(ctx context.Context, user *entity.User) error {
 	if user == nil {
-		return errors.New("user cannot be nil")
+		return ErrNilUser
 	}
 
 	if user.ID == "" {
-		return errors.New("user ID cannot be empty")
+		return ErrEmptyUserID
 	}
 
-	r.users[user.ID] = user
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[user.ID]; exists {
+		return ErrUserExists
+	}
+
+	if err := r.checkUnique(user); err != nil {
+		return err
+	}
+
+	r.put(user.Clone())
 	return nil
 }
 
 // GetByID retrieves a user by ID
-func (r *memoryUserRepository) GetByID(id string) (*entity.User, error) {
+func (r *memoryUserRepository) GetByID(ctx context.Context, id string) (*entity.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	user, exists := r.users[id]
 	if !exists {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
-	return user, nil
+	return user.Clone(), nil
 }
 
 // GetByEmail retrieves a user by email
-func (r *memoryUserRepository) GetByEmail(email string) (*entity.User, error) {
-	for _, user := range r.users {
-		if user.Email == email {
-			return user, nil
-		}
+func (r *memoryUserRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, exists := r.byEmail[email]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	return r.users[id].Clone(), nil
+}
+
+// GetByPhone retrieves a user by phone number
+func (r *memoryUserRepository) GetByPhone(ctx context.Context, phone string) (*entity.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, exists := r.byPhone[phone]
+	if !exists {
+		return nil, ErrUserNotFound
 	}
-	return nil, errors.New("user not found")
+	return r.users[id].Clone(), nil
 }
 
 // GetAll retrieves all users
-func (r *memoryUserRepository) GetAll() ([]*entity.User, error) {
+func (r *memoryUserRepository) GetAll(ctx context.Context) ([]*entity.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	userList := make([]*entity.User, 0, len(r.users))
 	for _, user := range r.users {
-		userList = append(userList, user)
+		userList = append(userList, user.Clone())
 	}
 	return userList, nil
 }
 
 // Update updates an existing user
-func (r *memoryUserRepository) Update(user *entity.User) error {
+func (r *memoryUserRepository) Update(ctx context.Context, user *entity.User) error {
 	if user == nil {
-		return errors.New("user cannot be nil")
+		return ErrNilUser
 	}
 
 	if user.ID == "" {
-		return errors.New("user ID cannot be empty")
+		return ErrEmptyUserID
 	}
 
-	if _, exists := r.users[user.ID]; !exists {
-		return errors.New("user not found")
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	r.users[user.ID] = user
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.users[user.ID]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	if err := r.checkUnique(user); err != nil {
+		return err
+	}
+
+	r.remove(existing)
+	r.put(user.Clone())
 	return nil
 }
 
 // Delete deletes a user by ID
-func (r *memoryUserRepository) Delete(id string) error {
-	if _, exists := r.users[id]; !exists {
-		return errors.New("user not found")
+func (r *memoryUserRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	r.remove(user)
+	return nil
+}
+
+// Query retrieves a filtered, sorted page of users. Every user is scanned,
+// so the scan stops early once ctx is done.
+func (r *memoryUserRepository) Query(ctx context.Context, q UserQuery) (*UserPage, error) {
+	q, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	matched := make([]*entity.User, 0)
+	for _, user := range r.users {
+		if err := ctx.Err(); err != nil {
+			r.mu.RUnlock()
+			return nil, err
+		}
+		if q.matches(user) && q.afterCursor(user) {
+			matched = append(matched, user.Clone())
+		}
 	}
+	r.mu.RUnlock()
 
-	delete(r.users, id)
+	sort.Slice(matched, func(i, j int) bool {
+		return q.less(matched[i], matched[j])
+	})
+
+	page := &UserPage{Users: matched}
+	if len(matched) > q.Limit {
+		page.Users = matched[:q.Limit]
+		page.Next = CursorFor(page.Users[q.Limit-1], q.SortBy)
+	}
+	return page, nil
+}
+
+// NextMemberID reserves the next member ID
+func (r *memoryUserRepository) NextMemberID(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.memberSeq++
+	return entity.FormatMemberID(r.memberSeq), nil
+}
+
+// checkUnique reports whether the email, phone or member ID of user is
+// already taken by a different user. Callers must hold the write lock.
+func (r *memoryUserRepository) checkUnique(user *entity.User) error {
+	if id, taken := r.byEmail[user.Email]; taken && id != user.ID {
+		return ErrEmailTaken
+	}
+	if id, taken := r.byPhone[user.Phone]; taken && id != user.ID {
+		return ErrPhoneTaken
+	}
+	if id, taken := r.byMemberID[user.MemberID]; taken && id != user.ID {
+		return ErrMemberIDTaken
+	}
 	return nil
 }
+
+// put stores user and its index entries. Callers must hold the write lock.
+func (r *memoryUserRepository) put(user *entity.User) {
+	user.Points = 0 // a ledger projection, never stored
+	r.users[user.ID] = user
+	r.byEmail[user.Email] = user.ID
+	r.byPhone[user.Phone] = user.ID
+	r.byMemberID[user.MemberID] = user.ID
+}
+
+// remove drops user and its index entries. Callers must hold the write lock.
+func (r *memoryUserRepository) remove(user *entity.User) {
+	delete(r.users, user.ID)
+	delete(r.byEmail, user.Email)
+	delete(r.byPhone, user.Phone)
+	delete(r.byMemberID, user.MemberID)
+}
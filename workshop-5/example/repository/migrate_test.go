@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+func TestLedgerMigrationCarriesOverPoints(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "users.db")+"?_time_format=sqlite")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	// Bring the schema to the version before the ledger existed
+	if _, err := db.Exec(`CREATE TABLE schema_migrations (
+		version INTEGER PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		t.Fatalf("create schema_migrations: %v", err)
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for _, m := range migrations {
+		if m.Name == "create_ledger_entries_table" {
+			break
+		}
+		if err := applyMigration(db, m); err != nil {
+			t.Fatalf("applyMigration: %v", err)
+		}
+	}
+
+	if _, err := db.Exec(
+		`INSERT INTO users (id, member_id, first_name, last_name, phone, email, membership_level, points, registered_at)
+		VALUES ('u1', 'LBK000001', 'John', 'Doe', '+66812345678', 'john@example.com', 'Gold', 15420, ?),
+		       ('u2', 'LBK000002', 'Jane', 'Doe', '+66812345679', 'jane@example.com', 'Gold', 0, ?)`,
+		time.Now().UTC(), time.Now().UTC(),
+	); err != nil {
+		t.Fatalf("insert users: %v", err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	ledger := NewSQLiteLedgerRepository(db)
+	balances, err := ledger.Balances(context.Background(), []string{"u1", "u2", "system:rewards"})
+	if err != nil {
+		t.Fatalf("Balances: %v", err)
+	}
+	if balances["u1"] != 15420 || balances["u2"] != 0 || balances["system:rewards"] != -15420 {
+		t.Fatalf("unexpected balances after migration: %v", balances)
+	}
+
+	users := NewSQLiteUserRepository(db)
+	if _, err := users.GetByID(context.Background(), "u1"); err != nil {
+		t.Fatalf("GetByID after dropping points: %v", err)
+	}
+}
+
+func TestLegacyTimestampsAreNormalized(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+
+	// Databases created before _time_format=sqlite hold timestamps in Go's
+	// time.Time.String() format
+	legacy, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := legacy.Exec(`CREATE TABLE schema_migrations (
+		version INTEGER PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		t.Fatalf("create schema_migrations: %v", err)
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for _, m := range migrations[:2] {
+		if err := applyMigration(legacy, m); err != nil {
+			t.Fatalf("applyMigration: %v", err)
+		}
+	}
+	registered := time.Date(2024, 1, 1, 10, 0, 0, 250_000_000, time.UTC)
+	if _, err := legacy.Exec(
+		`INSERT INTO users (id, member_id, first_name, last_name, phone, email, membership_level, points, registered_at)
+		VALUES ('u1', 'LBK000001', 'John', 'Doe', '+66812345678', 'john@example.com', 'Gold', 100, ?)`,
+		registered,
+	); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	legacy.Close()
+
+	db, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	defer db.Close()
+
+	for _, column := range []string{"users.registered_at", "ledger_entries.created_at", "schema_migrations.applied_at"} {
+		table, name, _ := strings.Cut(column, ".")
+		var legacyRows int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table + ` WHERE ` + name + ` LIKE '% UTC%'`).Scan(&legacyRows); err != nil {
+			t.Fatalf("count %s: %v", column, err)
+		}
+		if legacyRows != 0 {
+			t.Fatalf("%s: %d rows still in the legacy format", column, legacyRows)
+		}
+	}
+
+	// Range filters compare the stored text with timestamps in the new format
+	users := NewSQLiteUserRepository(db)
+	tests := []struct {
+		name  string
+		query UserQuery
+		want  int
+	}{
+		{"from is inclusive", UserQuery{RegisteredFrom: registered, Limit: 10}, 1},
+		{"to is exclusive", UserQuery{RegisteredTo: registered, Limit: 10}, 0},
+	}
+	for _, tt := range tests {
+		page, err := users.Query(context.Background(), tt.query)
+		if err != nil {
+			t.Fatalf("%s: Query: %v", tt.name, err)
+		}
+		if len(page.Users) != tt.want {
+			t.Fatalf("%s: got %d users, want %d", tt.name, len(page.Users), tt.want)
+		}
+	}
+
+	user, err := users.GetByID(context.Background(), "u1")
+	if err != nil || !user.RegisteredAt.Equal(registered) {
+		t.Fatalf("GetByID = %+v, %v; want registered at %v", user, err, registered)
+	}
+}
+
+func TestReversalMigrationRetypesReversals(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "users.db")+"?_time_format=sqlite")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	// Bring the schema to the version before reversals had their own type
+	if _, err := db.Exec(`CREATE TABLE schema_migrations (
+		version INTEGER PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		t.Fatalf("create schema_migrations: %v", err)
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for _, m := range migrations {
+		if m.Name == "add_reversal_entry_type" {
+			break
+		}
+		if err := applyMigration(db, m); err != nil {
+			t.Fatalf("applyMigration: %v", err)
+		}
+	}
+
+	// Reversals used to repeat the type of the entries they undo
+	if _, err := db.Exec(
+		`INSERT INTO ledger_entries (id, posting_id, account_id, type, amount, counterparty_id, memo, created_at)
+		VALUES ('p1:0', 'p1', 'u1', 'earn', 100, 'system:rewards', 'Bonus', ?),
+		       ('p1:1', 'p1', 'system:rewards', 'earn', -100, 'u1', 'Bonus', ?),
+		       ('reversal-p1:0', 'reversal-p1', 'u1', 'earn', -100, 'system:rewards', 'Credited twice', ?),
+		       ('reversal-p1:1', 'reversal-p1', 'system:rewards', 'earn', 100, 'u1', 'Credited twice', ?)`,
+		time.Now().UTC(), time.Now().UTC(), time.Now().UTC(), time.Now().UTC(),
+	); err != nil {
+		t.Fatalf("insert postings: %v", err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	ledger := NewSQLiteLedgerRepository(db)
+	for id, want := range map[string]entity.EntryType{"p1": entity.EntryEarn, "reversal-p1": entity.EntryReversal} {
+		posting, err := ledger.GetPosting(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetPosting(%s): %v", id, err)
+		}
+		for _, e := range posting.Entries {
+			if e.Type != want {
+				t.Fatalf("%s: entry %s has type %s, want %s", id, e.ID, e.Type, want)
+			}
+		}
+	}
+
+	// The rebuilt table is still append-only
+	if _, err := db.Exec(`UPDATE ledger_entries SET amount = 1 WHERE id = 'p1:0'`); err == nil {
+		t.Fatal("expected ledger entries to stay immutable")
+	}
+	if _, err := db.Exec(`DELETE FROM ledger_entries WHERE id = 'p1:0'`); err == nil {
+		t.Fatal("expected ledger entries to stay undeletable")
+	}
+}
@@ -0,0 +1,19 @@
+-- Create users table
+CREATE TABLE users (
+    id VARCHAR(36) PRIMARY KEY,
+    member_id VARCHAR(20) UNIQUE NOT NULL,
+    first_name VARCHAR(100) NOT NULL,
+    last_name VARCHAR(100) NOT NULL,
+    phone VARCHAR(20) UNIQUE NOT NULL,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    membership_level VARCHAR(20) NOT NULL DEFAULT 'Gold',
+    points INTEGER NOT NULL DEFAULT 0,
+    registered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX idx_users_member_id ON users(member_id);
+CREATE UNIQUE INDEX idx_users_email ON users(email);
+CREATE UNIQUE INDEX idx_users_phone ON users(phone);
+CREATE INDEX idx_users_membership_level ON users(membership_level);
+CREATE INDEX idx_users_registered_at ON users(registered_at);
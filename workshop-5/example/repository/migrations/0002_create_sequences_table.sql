@@ -0,0 +1,10 @@
+-- Monotonic counters owned by the repository layer, e.g. member IDs
+CREATE TABLE sequences (
+    name VARCHAR(50) PRIMARY KEY,
+    value INTEGER NOT NULL
+);
+
+-- Continue the member ID sequence after any existing members
+INSERT INTO sequences (name, value)
+SELECT 'member_id', COALESCE(MAX(CAST(SUBSTR(member_id, 4) AS INTEGER)), 0)
+FROM users;
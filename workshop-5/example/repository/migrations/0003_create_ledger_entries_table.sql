@@ -0,0 +1,39 @@
+-- Immutable points ledger. Entries are grouped into postings whose amounts
+-- sum to zero; balances are derived by summing an account's entries.
+CREATE TABLE ledger_entries (
+    id VARCHAR(80) PRIMARY KEY,
+    posting_id VARCHAR(64) NOT NULL,
+    account_id VARCHAR(64) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('earn', 'spend', 'transfer_in', 'transfer_out')),
+    amount INTEGER NOT NULL CHECK (amount <> 0),
+    counterparty_id VARCHAR(64) NOT NULL DEFAULT '',
+    order_id VARCHAR(64) NOT NULL DEFAULT '',
+    transfer_id VARCHAR(64) NOT NULL DEFAULT '',
+    memo VARCHAR(200) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    UNIQUE (posting_id, account_id)
+);
+
+CREATE INDEX idx_ledger_entries_account ON ledger_entries(account_id, created_at, id);
+
+CREATE TRIGGER ledger_entries_no_update BEFORE UPDATE ON ledger_entries
+BEGIN
+    SELECT RAISE(ABORT, 'ledger entries are immutable');
+END;
+
+CREATE TRIGGER ledger_entries_no_delete BEFORE DELETE ON ledger_entries
+BEGIN
+    SELECT RAISE(ABORT, 'ledger entries are immutable');
+END;
+
+-- Carry existing balances over as opening postings issued by the rewards account
+INSERT INTO ledger_entries (id, posting_id, account_id, type, amount, counterparty_id, memo, created_at)
+SELECT 'opening-' || id || ':0', 'opening-' || id, id, 'earn', points, 'system:rewards', 'Opening balance', registered_at
+FROM users WHERE points > 0;
+
+INSERT INTO ledger_entries (id, posting_id, account_id, type, amount, counterparty_id, memo, created_at)
+SELECT 'opening-' || id || ':1', 'opening-' || id, 'system:rewards', 'earn', -points, id, 'Opening balance', registered_at
+FROM users WHERE points > 0;
+
+-- Points are now a projection of the ledger
+ALTER TABLE users DROP COLUMN points;
@@ -0,0 +1,17 @@
+-- Point transfers between members. A posted transfer has exactly one ledger
+-- posting whose ID is the transfer ID.
+CREATE TABLE transfers (
+    id VARCHAR(64) PRIMARY KEY,
+    from_user_id VARCHAR(36) NOT NULL,
+    to_user_id VARCHAR(36) NOT NULL,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    memo VARCHAR(200) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'posted', 'failed')),
+    failure_reason VARCHAR(200) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    CHECK (from_user_id <> to_user_id)
+);
+
+-- Daily cap lookups: a member's posted transfers since the start of the day
+CREATE INDEX idx_transfers_from_user ON transfers(from_user_id, status, created_at);
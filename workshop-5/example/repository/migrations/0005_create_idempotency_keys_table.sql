@@ -0,0 +1,16 @@
+-- Responses stored for requests sent with an Idempotency-Key header. A row
+-- is inserted uncompleted when the first request starts and completed with
+-- its response; retries within the TTL replay it.
+CREATE TABLE idempotency_keys (
+    key VARCHAR(64) PRIMARY KEY,
+    request_hash VARCHAR(64) NOT NULL,
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    body BLOB,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+-- Sweeping expired keys
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
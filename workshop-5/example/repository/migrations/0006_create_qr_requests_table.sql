@@ -0,0 +1,16 @@
+-- QR payment requests. A paid request points at the transfer that settled it.
+CREATE TABLE qr_requests (
+    id VARCHAR(64) PRIMARY KEY,
+    recipient_id VARCHAR(36) NOT NULL,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    memo VARCHAR(200) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'paid', 'expired')),
+    payer_id VARCHAR(36) NOT NULL DEFAULT '',
+    transfer_id VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- The expiry sweeper scans pending requests by expiry
+CREATE INDEX idx_qr_requests_status_expires_at ON qr_requests(status, expires_at);
@@ -0,0 +1,16 @@
+-- Product catalog. Stock counts the units still available to sell.
+CREATE TABLE products (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(1000) NOT NULL DEFAULT '',
+    category VARCHAR(50) NOT NULL,
+    price_points INTEGER NOT NULL CHECK (price_points > 0),
+    active BOOLEAN NOT NULL DEFAULT 1,
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- The public listing filters by category and pages by name
+CREATE INDEX idx_products_category_name ON products(category, name, id);
+CREATE INDEX idx_products_name ON products(name, id);
@@ -0,0 +1,15 @@
+-- Server-side carts, one per member. Items keep the order they were added in.
+CREATE TABLE carts (
+    user_id VARCHAR(36) PRIMARY KEY,
+    customer_name VARCHAR(100) NOT NULL DEFAULT '',
+    customer_phone VARCHAR(20) NOT NULL DEFAULT '',
+    updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE cart_items (
+    user_id VARCHAR(36) NOT NULL,
+    product_id VARCHAR(64) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    position INTEGER NOT NULL,
+    PRIMARY KEY (user_id, product_id)
+);
@@ -0,0 +1,37 @@
+-- Orders placed at checkout. Items copy the product name and price at the
+-- time of purchase so later catalog edits do not change past orders.
+CREATE TABLE orders (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    total INTEGER NOT NULL CHECK (total > 0),
+    customer_name VARCHAR(100) NOT NULL DEFAULT '',
+    customer_phone VARCHAR(20) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL CHECK (status IN ('paid')),
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_orders_user ON orders(user_id, created_at);
+
+CREATE TABLE order_items (
+    order_id VARCHAR(64) NOT NULL,
+    position INTEGER NOT NULL,
+    product_id VARCHAR(64) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    unit_price INTEGER NOT NULL CHECK (unit_price > 0),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    line_total INTEGER NOT NULL CHECK (line_total > 0),
+    PRIMARY KEY (order_id, position)
+);
+
+-- Payments for orders. A points payment is settled by the ledger posting
+-- with the same ID.
+CREATE TABLE payments (
+    id VARCHAR(64) PRIMARY KEY,
+    order_id VARCHAR(64) NOT NULL UNIQUE,
+    user_id VARCHAR(36) NOT NULL,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    method VARCHAR(20) NOT NULL CHECK (method IN ('points')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('captured')),
+    created_at DATETIME NOT NULL
+);
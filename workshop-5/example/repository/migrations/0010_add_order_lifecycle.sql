@@ -0,0 +1,49 @@
+-- Orders move through created, paid, fulfilled, voided and refunded, and
+-- payments can be refunded. SQLite cannot alter CHECK constraints, so both
+-- tables are rebuilt.
+CREATE TABLE orders_new (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    total INTEGER NOT NULL CHECK (total > 0),
+    customer_name VARCHAR(100) NOT NULL DEFAULT '',
+    customer_phone VARCHAR(20) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL CHECK (status IN ('created', 'paid', 'fulfilled', 'voided', 'refunded')),
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+INSERT INTO orders_new SELECT * FROM orders;
+DROP TABLE orders;
+ALTER TABLE orders_new RENAME TO orders;
+CREATE INDEX idx_orders_user ON orders(user_id, created_at);
+
+CREATE TABLE payments_new (
+    id VARCHAR(64) PRIMARY KEY,
+    order_id VARCHAR(64) NOT NULL UNIQUE,
+    user_id VARCHAR(36) NOT NULL,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    method VARCHAR(20) NOT NULL CHECK (method IN ('points')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('captured', 'refunded')),
+    created_at DATETIME NOT NULL
+);
+INSERT INTO payments_new SELECT * FROM payments;
+DROP TABLE payments;
+ALTER TABLE payments_new RENAME TO payments;
+
+-- Every status change of an order, with who made it and why
+CREATE TABLE order_events (
+    order_id VARCHAR(64) NOT NULL,
+    position INTEGER NOT NULL,
+    from_status VARCHAR(20) NOT NULL DEFAULT '',
+    to_status VARCHAR(20) NOT NULL,
+    actor VARCHAR(64) NOT NULL,
+    reason VARCHAR(200) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (order_id, position)
+);
+
+-- Orders placed so far were created and paid by their member at checkout
+INSERT INTO order_events (order_id, position, from_status, to_status, actor, created_at)
+SELECT id, 0, '', 'created', user_id, created_at FROM orders;
+
+INSERT INTO order_events (order_id, position, from_status, to_status, actor, reason, created_at)
+SELECT id, 1, 'created', 'paid', user_id, 'Paid with points', created_at FROM orders;
@@ -0,0 +1,17 @@
+-- SMS receipts sent after checkout, one per order. provider_ref is the
+-- provider's message ID once it accepted the message.
+CREATE TABLE receipts (
+    id VARCHAR(64) PRIMARY KEY,
+    order_id VARCHAR(64) NOT NULL UNIQUE,
+    user_id VARCHAR(36) NOT NULL,
+    phone VARCHAR(20) NOT NULL,
+    language VARCHAR(5) NOT NULL,
+    body TEXT NOT NULL,
+    encoding VARCHAR(10) NOT NULL,
+    segments INTEGER NOT NULL CHECK (segments > 0),
+    provider_ref VARCHAR(100) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'sent', 'delivered', 'failed')),
+    error VARCHAR(500) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
@@ -0,0 +1,3 @@
+-- Delivery callbacks look receipts up by the provider's message ID.
+-- Receipts the provider never accepted have no reference.
+CREATE INDEX idx_receipts_provider_ref ON receipts(provider_ref) WHERE provider_ref != '';
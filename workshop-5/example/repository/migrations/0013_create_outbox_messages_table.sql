@@ -0,0 +1,18 @@
+-- Notifications and events written in the same transaction as the change
+-- they report and delivered by the outbox dispatcher. next_attempt_at is
+-- pushed forward when a dispatcher claims a message, so an interrupted
+-- delivery is retried once the claim lapses.
+CREATE TABLE outbox_messages (
+    id VARCHAR(64) PRIMARY KEY,
+    topic VARCHAR(100) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0 CHECK (attempts >= 0),
+    next_attempt_at DATETIME NOT NULL,
+    last_error VARCHAR(500) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- Claiming due messages and listing messages by status
+CREATE INDEX idx_outbox_messages_status ON outbox_messages(status, next_attempt_at);
@@ -0,0 +1,31 @@
+-- One-time login codes. Only an HMAC of the code is stored; a challenge is
+-- spent once consumed_at is set, expired or out of attempts.
+CREATE TABLE otp_challenges (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    channel VARCHAR(10) NOT NULL CHECK (channel IN ('phone', 'email')),
+    destination VARCHAR(255) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0 CHECK (attempts >= 0),
+    expires_at DATETIME NOT NULL,
+    consumed_at DATETIME,
+    created_at DATETIME NOT NULL
+);
+
+-- Refresh tokens, rotated on every use. Tokens issued from the same login
+-- share a family_id so a reused token revokes the whole family.
+CREATE TABLE refresh_tokens (
+    id VARCHAR(64) PRIMARY KEY,
+    family_id VARCHAR(64) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL,
+    rotated_at DATETIME,
+    revoked_at DATETIME
+);
+
+-- Purging expired rows and revoking a family
+CREATE INDEX idx_otp_challenges_expires_at ON otp_challenges(expires_at);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
@@ -0,0 +1,4 @@
+-- Roles decide what a user may do beyond their own account. Everyone
+-- registered so far is a member; admins are promoted explicitly.
+ALTER TABLE users ADD COLUMN role VARCHAR(10) NOT NULL DEFAULT 'member'
+    CHECK (role IN ('member', 'staff', 'admin'));
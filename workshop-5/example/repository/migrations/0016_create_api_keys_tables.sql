@@ -0,0 +1,27 @@
+-- Partner API keys. Request signing secrets are derived from the salt and
+-- a server secret, so they are never stored. After a rotation the previous
+-- salt keeps verifying until previous_expires_at.
+CREATE TABLE api_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    scopes TEXT NOT NULL, -- space-separated
+    rate_limit INTEGER NOT NULL CHECK (rate_limit > 0),
+    salt VARCHAR(64) NOT NULL,
+    previous_salt VARCHAR(64) NOT NULL DEFAULT '',
+    previous_expires_at DATETIME,
+    created_by VARCHAR(36) NOT NULL,
+    created_at DATETIME NOT NULL,
+    rotated_at DATETIME,
+    revoked_at DATETIME
+);
+
+-- Nonces of signed requests, kept while their timestamp is fresh so a
+-- captured request cannot be replayed
+CREATE TABLE api_key_nonces (
+    key_id VARCHAR(64) NOT NULL,
+    nonce VARCHAR(128) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (key_id, nonce)
+);
+
+CREATE INDEX idx_api_key_nonces_expires_at ON api_key_nonces(expires_at);
@@ -0,0 +1,3 @@
+-- A transfer that pays a QR request names it, so posting the transfer and
+-- marking the request paid happen in the same transaction
+ALTER TABLE transfers ADD COLUMN qr_request_id VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,22 @@
+-- Timestamps written before _time_format=sqlite use Go's time.Time.String()
+-- format, always in UTC: "2024-01-01 10:00:00.25 +0000 UTC". Rewrite them as
+-- "2024-01-01 10:00:00.25+00:00" so they compare correctly with later ones.
+-- Only users and the opening ledger postings copied from them are affected.
+UPDATE users
+SET registered_at = SUBSTR(registered_at, 1, INSTR(registered_at, ' +0000 UTC') - 1) || '+00:00'
+WHERE INSTR(registered_at, ' +0000 UTC') > 0;
+
+DROP TRIGGER ledger_entries_no_update;
+
+UPDATE ledger_entries
+SET created_at = SUBSTR(created_at, 1, INSTR(created_at, ' +0000 UTC') - 1) || '+00:00'
+WHERE INSTR(created_at, ' +0000 UTC') > 0;
+
+CREATE TRIGGER ledger_entries_no_update BEFORE UPDATE ON ledger_entries
+BEGIN
+    SELECT RAISE(ABORT, 'ledger entries are immutable');
+END;
+
+UPDATE schema_migrations
+SET applied_at = SUBSTR(applied_at, 1, INSTR(applied_at, ' +0000 UTC') - 1) || '+00:00'
+WHERE INSTR(applied_at, ' +0000 UTC') > 0;
@@ -0,0 +1,37 @@
+-- Reversal postings get their own entry type instead of repeating the type
+-- of the entries they undo. SQLite cannot alter a CHECK constraint, so the
+-- table is rebuilt; existing reversals are recognised by their posting ID.
+CREATE TABLE ledger_entries_new (
+    id VARCHAR(80) PRIMARY KEY,
+    posting_id VARCHAR(64) NOT NULL,
+    account_id VARCHAR(64) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('earn', 'spend', 'transfer_in', 'transfer_out', 'reversal')),
+    amount INTEGER NOT NULL CHECK (amount <> 0),
+    counterparty_id VARCHAR(64) NOT NULL DEFAULT '',
+    order_id VARCHAR(64) NOT NULL DEFAULT '',
+    transfer_id VARCHAR(64) NOT NULL DEFAULT '',
+    memo VARCHAR(200) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    UNIQUE (posting_id, account_id)
+);
+
+INSERT INTO ledger_entries_new (id, posting_id, account_id, type, amount, counterparty_id, order_id, transfer_id, memo, created_at)
+SELECT id, posting_id, account_id,
+       CASE WHEN posting_id LIKE 'reversal-%' THEN 'reversal' ELSE type END,
+       amount, counterparty_id, order_id, transfer_id, memo, created_at
+FROM ledger_entries;
+
+DROP TABLE ledger_entries;
+ALTER TABLE ledger_entries_new RENAME TO ledger_entries;
+
+CREATE INDEX idx_ledger_entries_account ON ledger_entries(account_id, created_at, id);
+
+CREATE TRIGGER ledger_entries_no_update BEFORE UPDATE ON ledger_entries
+BEGIN
+    SELECT RAISE(ABORT, 'ledger entries are immutable');
+END;
+
+CREATE TRIGGER ledger_entries_no_delete BEFORE DELETE ON ledger_entries
+BEGIN
+    SELECT RAISE(ABORT, 'ledger entries are immutable');
+END;
@@ -0,0 +1,6 @@
+-- member_id, phone and email are declared UNIQUE on the users table, which
+-- already indexes each of them. The unique indexes created next to the table
+-- duplicated those, so every insert and update maintained two of each.
+DROP INDEX IF EXISTS idx_users_member_id;
+DROP INDEX IF EXISTS idx_users_email;
+DROP INDEX IF EXISTS idx_users_phone;
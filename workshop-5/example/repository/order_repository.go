@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every OrderRepository implementation
+var (
+	// ErrNilOrder is returned when Place receives a nil order or payment
+	ErrNilOrder = apperror.Validation("order and payment cannot be nil")
+
+	// ErrInvalidOrder is returned for orders that are not paid, without ID,
+	// member, items or events, with items that do not add up to the total,
+	// or with a payment that does not cover the order
+	ErrInvalidOrder = apperror.Validation("invalid order")
+
+	// ErrOrderExists is returned when an order with the same ID exists
+	ErrOrderExists = apperror.Conflict("order already exists")
+
+	// ErrOrderNotFound is returned when no order matches the lookup
+	ErrOrderNotFound = apperror.NotFound("order not found")
+
+	// ErrPaymentNotFound is returned when no payment matches the lookup
+	ErrPaymentNotFound = apperror.NotFound("payment not found")
+
+	// ErrInvalidOrderTransition is returned for transitions without an
+	// order ID or actor, or that do not change the status
+	ErrInvalidOrderTransition = apperror.Validation("invalid order transition")
+
+	// ErrOrderStatusChanged is returned when the order is no longer in the
+	// status a transition starts from
+	ErrOrderStatusChanged = apperror.Conflict("order status has changed")
+)
+
+// OrderRepository stores orders and their payments, settling payments
+// against the ledger and the product stock
+type OrderRepository interface {
+	// Place reserves stock for every item, posts the payment to the ledger,
+	// stores the paid order with its events and the payment, empties the
+	// member's cart and enqueues the outbox messages announcing the order
+	// in one atomic step. On error nothing is written; stock and ledger
+	// errors such as ErrOutOfStock and ErrInsufficientBalance are returned
+	// as is.
+	Place(ctx context.Context, order *entity.Order, payment *entity.Payment, messages ...*entity.OutboxMessage) error
+
+	// Transition changes the status of an order and applies the side
+	// effects of the change in one atomic step, returning the updated
+	// order. It fails with ErrOrderStatusChanged unless the order is in
+	// the transition's From status. Which changes are allowed is decided
+	// by the caller.
+	Transition(ctx context.Context, t OrderTransition) (*entity.Order, error)
+
+	// GetByID retrieves an order by ID
+	GetByID(ctx context.Context, id string) (*entity.Order, error)
+
+	// GetPayment retrieves the payment of an order
+	GetPayment(ctx context.Context, orderID string) (*entity.Payment, error)
+}
+
+// OrderTransition is a status change of an order and its side effects
+type OrderTransition struct {
+	OrderID string
+
+	// Event is recorded on the order; its To status becomes the order's
+	// status
+	Event entity.OrderEvent
+
+	// RefundPayment posts the payment's reversal to the ledger and marks
+	// the payment refunded
+	RefundPayment bool
+
+	// Restock puts the ordered quantities back into stock
+	Restock bool
+}
+
+// validateOrderTransition checks the invariants shared by every
+// implementation
+func validateOrderTransition(t OrderTransition) error {
+	if t.OrderID == "" || t.Event.Actor == "" || t.Event.From == t.Event.To {
+		return ErrInvalidOrderTransition
+	}
+	return nil
+}
+
+// validateOrder checks the invariants shared by every implementation
+func validateOrder(order *entity.Order, payment *entity.Payment, messages []*entity.OutboxMessage) error {
+	if order == nil || payment == nil {
+		return ErrNilOrder
+	}
+	if order.ID == "" || order.UserID == "" || len(order.Items) == 0 || len(order.Events) == 0 ||
+		order.Status != entity.OrderPaid {
+		return ErrInvalidOrder
+	}
+	total := 0
+	for _, item := range order.Items {
+		if item.ProductID == "" || item.UnitPrice <= 0 || item.Quantity <= 0 ||
+			item.LineTotal != item.UnitPrice*item.Quantity {
+			return ErrInvalidOrder
+		}
+		total += item.LineTotal
+	}
+	if total != order.Total || payment.ID == "" || payment.OrderID != order.ID ||
+		payment.UserID != order.UserID || payment.Amount != order.Total {
+		return ErrInvalidOrder
+	}
+	return validateOutboxMessages(messages)
+}
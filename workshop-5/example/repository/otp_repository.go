@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every OTPRepository implementation
+var (
+	// ErrNilOTPChallenge is returned when Create receives a nil challenge
+	ErrNilOTPChallenge = apperror.Validation("OTP challenge cannot be nil")
+
+	// ErrInvalidOTPChallenge is returned for challenges without ID, user,
+	// channel, destination, code hash or expiry, or already used
+	ErrInvalidOTPChallenge = apperror.Validation("invalid OTP challenge")
+
+	// ErrOTPChallengeExists is returned when a challenge with the same ID
+	// exists
+	ErrOTPChallengeExists = apperror.Conflict("OTP challenge already exists")
+
+	// ErrOTPChallengeNotFound is returned when no challenge matches the
+	// lookup
+	ErrOTPChallengeNotFound = apperror.NotFound("OTP challenge not found")
+
+	// ErrOTPChallengeClosed is returned when trying a code against a
+	// challenge that is consumed, expired or out of attempts
+	ErrOTPChallengeClosed = apperror.Conflict("OTP challenge is consumed, expired or out of attempts")
+)
+
+// OTPRepository stores one-time login code challenges
+type OTPRepository interface {
+	// Create stores a new challenge
+	Create(ctx context.Context, challenge *entity.OTPChallenge) error
+
+	// GetByID retrieves a challenge by ID
+	GetByID(ctx context.Context, id string) (*entity.OTPChallenge, error)
+
+	// Attempt counts a code attempt against a challenge that is not
+	// consumed, has not expired at now and has fewer than maxAttempts
+	// attempts, and returns it with the attempt counted. Counting first
+	// means concurrent guesses cannot exceed maxAttempts between them.
+	Attempt(ctx context.Context, id string, now time.Time, maxAttempts int) (*entity.OTPChallenge, error)
+
+	// Consume marks a challenge as used at now, once
+	Consume(ctx context.Context, id string, now time.Time) (*entity.OTPChallenge, error)
+
+	// DeleteExpired deletes challenges that expired at or before now and
+	// returns how many were deleted
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// validateOTPChallenge checks the invariants shared by every implementation
+func validateOTPChallenge(challenge *entity.OTPChallenge) error {
+	if challenge == nil {
+		return ErrNilOTPChallenge
+	}
+	if challenge.ID == "" || challenge.UserID == "" || challenge.Destination == "" ||
+		challenge.CodeHash == "" || challenge.ExpiresAt.IsZero() ||
+		challenge.Attempts != 0 || challenge.ConsumedAt != nil {
+		return ErrInvalidOTPChallenge
+	}
+	switch challenge.Channel {
+	case entity.OTPChannelPhone, entity.OTPChannelEmail:
+		return nil
+	}
+	return ErrInvalidOTPChallenge
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every OutboxRepository implementation
+var (
+	// ErrNilOutboxMessage is returned when a nil message is enqueued or
+	// updated
+	ErrNilOutboxMessage = apperror.Validation("outbox message cannot be nil")
+
+	// ErrInvalidOutboxMessage is returned for messages without ID, topic or
+	// payload, with negative attempts or an unknown status
+	ErrInvalidOutboxMessage = apperror.Validation("invalid outbox message")
+
+	// ErrOutboxMessageExists is returned when a message with the same ID
+	// exists
+	ErrOutboxMessageExists = apperror.Conflict("outbox message already exists")
+
+	// ErrOutboxMessageNotFound is returned when no message matches the
+	// lookup
+	ErrOutboxMessageNotFound = apperror.NotFound("outbox message not found")
+
+	// ErrOutboxMessageNotDue is returned when claiming a message that is
+	// not pending or not due, e.g. because another dispatcher claimed it
+	ErrOutboxMessageNotDue = apperror.Conflict("outbox message is not due")
+
+	// ErrOutboxMessageDelivered is returned when replaying a message that
+	// was already delivered
+	ErrOutboxMessageDelivered = apperror.Conflict("outbox message was already delivered")
+)
+
+// OutboxRepository stores outbox messages. Repositories whose changes must
+// be announced take the messages along and write them in the same atomic
+// step; the dispatcher then claims and delivers them.
+type OutboxRepository interface {
+	// Enqueue stores new messages, all or none
+	Enqueue(ctx context.Context, messages ...*entity.OutboxMessage) error
+
+	// ClaimDue claims up to limit pending messages due at now, earliest
+	// first. Claiming counts an attempt and pushes the next attempt lease
+	// into the future, so a dispatcher that dies while delivering leaves
+	// the message to be retried rather than lost, and no other dispatcher
+	// picks it up in the meantime.
+	ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*entity.OutboxMessage, error)
+
+	// Claim claims a single message like ClaimDue. It fails with
+	// ErrOutboxMessageNotDue unless the message is pending and due.
+	Claim(ctx context.Context, id string, now time.Time, lease time.Duration) (*entity.OutboxMessage, error)
+
+	// Update stores the status, attempts, next attempt time, last error and
+	// update time of an existing message
+	Update(ctx context.Context, message *entity.OutboxMessage) error
+
+	// Replay moves a pending or dead message back to pending with no
+	// attempts, due at now. Delivered messages fail with
+	// ErrOutboxMessageDelivered.
+	Replay(ctx context.Context, id string, now time.Time) (*entity.OutboxMessage, error)
+
+	// GetByID retrieves a message by ID
+	GetByID(ctx context.Context, id string) (*entity.OutboxMessage, error)
+
+	// List returns up to limit messages with status, or with any status
+	// when it is empty, oldest first
+	List(ctx context.Context, status entity.OutboxStatus, limit int) ([]*entity.OutboxMessage, error)
+}
+
+// validateOutboxMessage checks the invariants shared by every
+// implementation
+func validateOutboxMessage(message *entity.OutboxMessage) error {
+	if message == nil {
+		return ErrNilOutboxMessage
+	}
+	if message.ID == "" || message.Topic == "" || len(message.Payload) == 0 || message.Attempts < 0 {
+		return ErrInvalidOutboxMessage
+	}
+	switch message.Status {
+	case entity.OutboxPending, entity.OutboxDelivered, entity.OutboxDead:
+		return nil
+	default:
+		return ErrInvalidOutboxMessage
+	}
+}
+
+// validateOutboxMessages checks every message of a batch
+func validateOutboxMessages(messages []*entity.OutboxMessage) error {
+	for _, message := range messages {
+		if err := validateOutboxMessage(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
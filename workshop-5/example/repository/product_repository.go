@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every ProductRepository implementation
+var (
+	// ErrNilProduct is returned when Create or Update receives a nil product
+	ErrNilProduct = apperror.Validation("product cannot be nil")
+
+	// ErrInvalidProduct is returned for products without ID, name or
+	// category, or with a non-positive price or negative stock
+	ErrInvalidProduct = apperror.Validation("invalid product")
+
+	// ErrProductExists is returned when a product with the same ID exists
+	ErrProductExists = apperror.Conflict("product already exists")
+
+	// ErrProductNotFound is returned when no product matches the lookup
+	ErrProductNotFound = apperror.NotFound("product not found")
+
+	// ErrProductInactive is returned when reserving stock of a product that
+	// is not for sale
+	ErrProductInactive = apperror.Conflict("product is inactive")
+
+	// ErrOutOfStock is returned when reserving more units than are in stock
+	ErrOutOfStock = apperror.Conflict("product is out of stock")
+)
+
+// ProductRepository stores the product catalog
+type ProductRepository interface {
+	// Create stores a new product
+	Create(ctx context.Context, product *entity.Product) error
+
+	// GetByID retrieves a product by ID
+	GetByID(ctx context.Context, id string) (*entity.Product, error)
+
+	// Update replaces an existing product
+	Update(ctx context.Context, product *entity.Product) error
+
+	// Delete deletes a product by ID
+	Delete(ctx context.Context, id string) error
+
+	// Query retrieves a filtered page of products ordered by name, using
+	// keyset pagination; see ProductQuery
+	Query(ctx context.Context, q ProductQuery) (*ProductPage, error)
+
+	// Reserve takes the given quantity of each product, keyed by product
+	// ID, out of stock. Either every quantity is reserved or, on error,
+	// none is; missing, inactive and understocked products fail with
+	// ErrProductNotFound, ErrProductInactive and ErrOutOfStock.
+	Reserve(ctx context.Context, quantities map[string]int) error
+
+	// Restock puts the given quantity of each product back into stock.
+	// Products deleted in the meantime are skipped.
+	Restock(ctx context.Context, quantities map[string]int) error
+}
+
+// ProductQuery describes a filtered page of products. Zero values mean
+// "no filter".
+type ProductQuery struct {
+	// Category matches the category exactly
+	Category string
+
+	// Search matches a substring of the name or description, ignoring case
+	Search string
+
+	// ActiveOnly leaves out inactive products
+	ActiveOnly bool
+
+	// Limit is the maximum number of products returned; it must be positive
+	Limit int
+
+	// After resumes the listing right after the given position
+	After *ProductCursor
+}
+
+// ProductCursor is a keyset position in the product listing: the name and
+// ID of the last product on the previous page
+type ProductCursor struct {
+	Name string `json:"n"`
+	ID   string `json:"id"`
+}
+
+// ProductPage is one page of a product listing
+type ProductPage struct {
+	Products []*entity.Product
+
+	// Next is the cursor for the following page, nil on the last page
+	Next *ProductCursor
+}
+
+// validateProduct checks the invariants shared by every implementation
+func validateProduct(product *entity.Product) error {
+	if product == nil {
+		return ErrNilProduct
+	}
+	if product.ID == "" || product.Name == "" || product.Category == "" ||
+		product.PricePoints <= 0 || product.Stock < 0 {
+		return ErrInvalidProduct
+	}
+	return nil
+}
+
+// validateQuantities checks that every quantity to reserve or restock is
+// positive
+func validateQuantities(quantities map[string]int) error {
+	for productID, quantity := range quantities {
+		if productID == "" || quantity <= 0 {
+			return apperror.Validation(fmt.Sprintf("invalid quantity %d of product %q", quantity, productID))
+		}
+	}
+	return nil
+}
+
+// sortedProductIDs returns the product IDs of quantities in order, so
+// multi-product updates always visit rows in the same order
+func sortedProductIDs(quantities map[string]int) []string {
+	ids := make([]string, 0, len(quantities))
+	for id := range quantities {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// normalize rejects malformed queries
+func (q ProductQuery) normalize() (ProductQuery, error) {
+	if q.Limit <= 0 {
+		return q, apperror.Validation(fmt.Sprintf("limit must be positive, got %d", q.Limit))
+	}
+	return q, nil
+}
+
+// matches reports whether product passes every filter of q
+func (q ProductQuery) matches(product *entity.Product) bool {
+	if q.ActiveOnly && !product.Active {
+		return false
+	}
+	if q.Category != "" && product.Category != q.Category {
+		return false
+	}
+	if q.Search != "" &&
+		!containsFold(product.Name, q.Search) &&
+		!containsFold(product.Description, q.Search) {
+		return false
+	}
+	return q.After == nil || compareProducts(product.Name, product.ID, q.After.Name, q.After.ID) > 0
+}
+
+// compareProducts orders (name, id) pairs
+func compareProducts(aName, aID, bName, bID string) int {
+	if c := strings.Compare(aName, bName); c != 0 {
+		return c
+	}
+	return strings.Compare(aID, bID)
+}
+
+// containsFold is a case-insensitive strings.Contains
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every QRRequestRepository implementation
+var (
+	// ErrNilQRRequest is returned when Create receives a nil request
+	ErrNilQRRequest = apperror.Validation("QR request cannot be nil")
+
+	// ErrInvalidQRRequest is returned for requests without ID, recipient,
+	// a positive amount or an expiry
+	ErrInvalidQRRequest = apperror.Validation("invalid QR request")
+
+	// ErrQRRequestExists is returned when a request with the same ID exists
+	ErrQRRequestExists = apperror.Conflict("QR request already exists")
+
+	// ErrQRRequestNotFound is returned when no request matches the lookup
+	ErrQRRequestNotFound = apperror.NotFound("QR request not found")
+
+	// ErrQRRequestNotPending is returned when paying or expiring a request
+	// that is already paid or expired
+	ErrQRRequestNotPending = apperror.Conflict("QR request is not pending")
+)
+
+// QRRequestRepository stores QR payment requests
+type QRRequestRepository interface {
+	// Create stores a new pending request
+	Create(ctx context.Context, request *entity.QRRequest) error
+
+	// GetByID retrieves a request by ID
+	GetByID(ctx context.Context, id string) (*entity.QRRequest, error)
+
+	// MarkPaid moves a pending request to paid, recording the payer and the
+	// transfer that settled it
+	MarkPaid(ctx context.Context, id, payerID, transferID string) (*entity.QRRequest, error)
+
+	// MarkExpired moves a pending request to expired
+	MarkExpired(ctx context.Context, id string) (*entity.QRRequest, error)
+
+	// ListDue returns up to limit pending requests whose expiry is at or
+	// before now, earliest first
+	ListDue(ctx context.Context, now time.Time, limit int) ([]*entity.QRRequest, error)
+}
+
+// validateQRRequest checks the invariants shared by every implementation
+func validateQRRequest(request *entity.QRRequest) error {
+	if request == nil {
+		return ErrNilQRRequest
+	}
+	if request.ID == "" || request.RecipientID == "" || request.Amount <= 0 ||
+		request.ExpiresAt.IsZero() || request.Status != entity.QRRequestPending {
+		return ErrInvalidQRRequest
+	}
+	return nil
+}
+
+// paidBy returns the change that marks a request paid by a transfer
+func paidBy(payerID, transferID string) func(*entity.QRRequest) {
+	return func(request *entity.QRRequest) {
+		request.Status = entity.QRRequestPaid
+		request.PayerID = payerID
+		request.TransferID = transferID
+	}
+}
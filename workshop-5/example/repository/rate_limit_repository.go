@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// ErrInvalidRateLimit is returned when taking from a bucket without a key
+// or under a disabled policy
+var ErrInvalidRateLimit = apperror.Validation("invalid rate limit")
+
+// RateLimitRepository stores the token buckets of rate-limited callers.
+// Buckets are created full on first use, so a store may forget a bucket
+// once it has refilled without changing any decision.
+type RateLimitRepository interface {
+	// Take takes a token from bucket key under limit at now and returns
+	// the decision. Concurrent takes from the same bucket are applied one
+	// at a time, so a bucket never hands out more tokens than it holds.
+	Take(key string, limit entity.RateLimit, now time.Time) (entity.RateLimitDecision, error)
+
+	// DeleteExpired deletes the buckets full again at or before now and
+	// returns how many were deleted
+	DeleteExpired(now time.Time) (int, error)
+}
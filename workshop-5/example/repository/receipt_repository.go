@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every ReceiptRepository implementation
+var (
+	// ErrNilReceipt is returned when Create or Update receives a nil receipt
+	ErrNilReceipt = apperror.Validation("receipt cannot be nil")
+
+	// ErrInvalidReceipt is returned for receipts without ID, order, phone
+	// number, body or a known status
+	ErrInvalidReceipt = apperror.Validation("invalid receipt")
+
+	// ErrReceiptExists is returned when a receipt with the same ID or for
+	// the same order exists
+	ErrReceiptExists = apperror.Conflict("receipt already exists")
+
+	// ErrReceiptNotFound is returned when no receipt matches the lookup
+	ErrReceiptNotFound = apperror.NotFound("receipt not found")
+
+	// ErrReceiptNotSent is returned when recording the delivery of a
+	// receipt that is not waiting for one
+	ErrReceiptNotSent = apperror.Conflict("receipt is not awaiting delivery")
+
+	// ErrInvalidDelivery is returned for deliveries without a provider
+	// reference or a final status
+	ErrInvalidDelivery = apperror.Validation("invalid receipt delivery")
+)
+
+// ReceiptRepository stores SMS receipts. Every order has at most one
+// receipt. Changes that are announced take their outbox messages along and
+// enqueue them in the same atomic step.
+type ReceiptRepository interface {
+	// Create stores a new receipt
+	Create(ctx context.Context, receipt *entity.Receipt) error
+
+	// Update stores the provider reference, status, error and update time
+	// of an existing receipt and enqueues messages
+	Update(ctx context.Context, receipt *entity.Receipt, messages ...*entity.OutboxMessage) error
+
+	// GetByOrderID retrieves the receipt of an order
+	GetByOrderID(ctx context.Context, orderID string) (*entity.Receipt, error)
+
+	// GetByProviderRef retrieves a receipt by the provider's message ID
+	GetByProviderRef(ctx context.Context, providerRef string) (*entity.Receipt, error)
+
+	// MarkDelivery moves a sent receipt to delivered or failed, recording
+	// the provider's reason, and enqueues messages. It fails with
+	// ErrReceiptNotSent once the receipt has left sent, so each delivery,
+	// and the messages announcing it, are only applied once.
+	MarkDelivery(
+		ctx context.Context, providerRef string, status entity.ReceiptStatus, reason string, messages ...*entity.OutboxMessage,
+	) (*entity.Receipt, error)
+}
+
+// validateReceipt checks the invariants shared by every implementation
+func validateReceipt(receipt *entity.Receipt, messages []*entity.OutboxMessage) error {
+	if receipt == nil {
+		return ErrNilReceipt
+	}
+	if receipt.ID == "" || receipt.OrderID == "" || receipt.Phone == "" || receipt.Body == "" {
+		return ErrInvalidReceipt
+	}
+	switch receipt.Status {
+	case entity.ReceiptPending, entity.ReceiptSent, entity.ReceiptDelivered, entity.ReceiptFailed:
+		return validateOutboxMessages(messages)
+	default:
+		return ErrInvalidReceipt
+	}
+}
+
+// validateDelivery checks a delivery outcome
+func validateDelivery(providerRef string, status entity.ReceiptStatus, messages []*entity.OutboxMessage) error {
+	if providerRef == "" || (status != entity.ReceiptDelivered && status != entity.ReceiptFailed) {
+		return ErrInvalidDelivery
+	}
+	return validateOutboxMessages(messages)
+}
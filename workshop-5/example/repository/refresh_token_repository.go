@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every RefreshTokenRepository implementation
+var (
+	// ErrNilRefreshToken is returned when Create or Rotate receives a nil
+	// token
+	ErrNilRefreshToken = apperror.Validation("refresh token cannot be nil")
+
+	// ErrInvalidRefreshToken is returned for tokens without ID, family,
+	// user, secret hash or expiry, or already rotated or revoked
+	ErrInvalidRefreshToken = apperror.Validation("invalid refresh token")
+
+	// ErrRefreshTokenExists is returned when a token with the same ID
+	// exists
+	ErrRefreshTokenExists = apperror.Conflict("refresh token already exists")
+
+	// ErrRefreshTokenNotFound is returned when no token matches the lookup
+	ErrRefreshTokenNotFound = apperror.NotFound("refresh token not found")
+
+	// ErrRefreshTokenInactive is returned when rotating a token that is
+	// expired, already rotated or revoked
+	ErrRefreshTokenInactive = apperror.Conflict("refresh token is no longer active")
+)
+
+// RefreshTokenRepository stores refresh tokens and their rotation
+type RefreshTokenRepository interface {
+	// Create stores the first token of a new family
+	Create(ctx context.Context, token *entity.RefreshToken) error
+
+	// GetByID retrieves a token by ID
+	GetByID(ctx context.Context, id string) (*entity.RefreshToken, error)
+
+	// Rotate marks the token id as rotated at now and stores next, its
+	// successor in the same family, atomically. Only a token that is
+	// active at now can be rotated, so of two concurrent refreshes with the
+	// same token only one succeeds.
+	Rotate(ctx context.Context, id string, next *entity.RefreshToken, now time.Time) error
+
+	// RevokeFamily revokes every token of a family that is not revoked
+	// yet and returns how many were revoked
+	RevokeFamily(ctx context.Context, familyID string, now time.Time) (int, error)
+
+	// DeleteExpired deletes tokens that expired at or before now and
+	// returns how many were deleted
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// validateRefreshToken checks the invariants shared by every
+// implementation
+func validateRefreshToken(token *entity.RefreshToken) error {
+	if token == nil {
+		return ErrNilRefreshToken
+	}
+	if token.ID == "" || token.FamilyID == "" || token.UserID == "" || token.SecretHash == "" ||
+		token.ExpiresAt.IsZero() || token.RotatedAt != nil || token.RevokedAt != nil {
+		return ErrInvalidRefreshToken
+	}
+	return nil
+}
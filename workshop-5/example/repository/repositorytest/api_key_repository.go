@@ -0,0 +1,285 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// APIKeyFactory returns a new, empty API key repository for a single test
+type APIKeyFactory func(t *testing.T) repository.APIKeyRepository
+
+// RunAPIKeyRepositoryTests runs the conformance suite against the API key
+// repositories returned by newRepo
+func RunAPIKeyRepositoryTests(t *testing.T, newRepo APIKeyFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.APIKeyRepository)
+	}{
+		{"CreateAndGetByID", testAPIKeyCreateAndGetByID},
+		{"CreateRejectsInvalid", testAPIKeyCreateRejectsInvalid},
+		{"List", testAPIKeyList},
+		{"Rotate", testAPIKeyRotate},
+		{"Revoke", testAPIKeyRevoke},
+		{"UseNonce", testAPIKeyUseNonce},
+		{"DeleteExpiredNonces", testAPIKeyDeleteExpiredNonces},
+		{"ConcurrentNoncesApplyOnce", testAPIKeyConcurrentNonces},
+		{"HonoursCancelledContext", testAPIKeyHonoursCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+func mustCreateAPIKey(t *testing.T, repo repository.APIKeyRepository, id string) *entity.APIKey {
+	t.Helper()
+	key := entity.NewAPIKey(id, "POS "+id, []entity.APIScope{entity.ScopePointsRead, entity.ScopePointsAward}, 60, "salt-"+id, "admin")
+	if err := repo.Create(context.Background(), key); err != nil {
+		t.Fatalf("Create(%s): unexpected error: %v", id, err)
+	}
+	return key
+}
+
+func testAPIKeyCreateAndGetByID(t *testing.T, repo repository.APIKeyRepository) {
+	want := mustCreateAPIKey(t, repo, "k1")
+
+	got, err := repo.GetByID(context.Background(), "k1")
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if got.ID != "k1" || got.Name != want.Name || len(got.Scopes) != 2 || !got.HasScope(entity.ScopePointsAward) ||
+		got.RateLimit != 60 || got.Salt != want.Salt || got.PreviousSalt != "" || got.CreatedBy != "admin" ||
+		got.PreviousExpiresAt != nil || got.RotatedAt != nil || got.RevokedAt != nil || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("API key mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+
+	if err := repo.Create(context.Background(), entity.NewAPIKey("k1", "Other", []entity.APIScope{entity.ScopePointsRead}, 10, "salt", "admin")); !errors.Is(err, repository.ErrAPIKeyExists) {
+		t.Fatalf("Create duplicate: expected %v, got %v", repository.ErrAPIKeyExists, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "missing"); !errors.Is(err, repository.ErrAPIKeyNotFound) {
+		t.Fatalf("GetByID missing: expected %v, got %v", repository.ErrAPIKeyNotFound, err)
+	}
+}
+
+func testAPIKeyCreateRejectsInvalid(t *testing.T, repo repository.APIKeyRepository) {
+	scopes := []entity.APIScope{entity.ScopePointsRead}
+	revoked := entity.NewAPIKey("revoked", "POS", scopes, 60, "salt", "admin")
+	revoked.RevokedAt = &revoked.CreatedAt
+
+	tests := []struct {
+		name string
+		key  *entity.APIKey
+		want error
+	}{
+		{"nil", nil, repository.ErrNilAPIKey},
+		{"empty ID", entity.NewAPIKey("", "POS", scopes, 60, "salt", "admin"), repository.ErrInvalidAPIKey},
+		{"no name", entity.NewAPIKey("k1", "", scopes, 60, "salt", "admin"), repository.ErrInvalidAPIKey},
+		{"no scopes", entity.NewAPIKey("k1", "POS", nil, 60, "salt", "admin"), repository.ErrInvalidAPIKey},
+		{"no rate limit", entity.NewAPIKey("k1", "POS", scopes, 0, "salt", "admin"), repository.ErrInvalidAPIKey},
+		{"no salt", entity.NewAPIKey("k1", "POS", scopes, 60, "", "admin"), repository.ErrInvalidAPIKey},
+		{"revoked", revoked, repository.ErrInvalidAPIKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Create(context.Background(), tt.key); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testAPIKeyList(t *testing.T, repo repository.APIKeyRepository) {
+	if keys, err := repo.List(context.Background()); err != nil || len(keys) != 0 {
+		t.Fatalf("List empty = %v, %v", keys, err)
+	}
+
+	first := mustCreateAPIKey(t, repo, "b")
+	second := entity.NewAPIKey("a", "POS a", []entity.APIScope{entity.ScopePointsRead}, 60, "salt-a", "admin")
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	if err := repo.Create(context.Background(), second); err != nil {
+		t.Fatalf("Create: unexpected error: %v", err)
+	}
+
+	keys, err := repo.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: unexpected error: %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != "b" || keys[1].ID != "a" {
+		t.Fatalf("List = %+v, want b then a", keys)
+	}
+}
+
+func testAPIKeyRotate(t *testing.T, repo repository.APIKeyRepository) {
+	key := mustCreateAPIKey(t, repo, "k1")
+	now := key.CreatedAt.Add(time.Hour)
+	grace := now.Add(24 * time.Hour)
+
+	rotated, err := repo.Rotate(context.Background(), "k1", "salt-2", grace, now)
+	if err != nil {
+		t.Fatalf("Rotate: unexpected error: %v", err)
+	}
+	if rotated.Salt != "salt-2" || rotated.PreviousSalt != key.Salt ||
+		rotated.PreviousExpiresAt == nil || !rotated.PreviousExpiresAt.Equal(grace) ||
+		rotated.RotatedAt == nil || !rotated.RotatedAt.Equal(now) {
+		t.Fatalf("key not rotated: %+v", rotated)
+	}
+	if !rotated.PreviousSaltActive(now) || rotated.PreviousSaltActive(grace) {
+		t.Fatalf("previous salt should be active until %v: %+v", grace, rotated)
+	}
+	if got, _ := repo.GetByID(context.Background(), "k1"); got.Salt != "salt-2" || got.PreviousSalt != key.Salt {
+		t.Fatalf("rotation not stored: %+v", got)
+	}
+
+	// Only the salt just replaced stays valid
+	if rotated, _ = repo.Rotate(context.Background(), "k1", "salt-3", grace, now); rotated.PreviousSalt != "salt-2" {
+		t.Fatalf("second rotation kept %q as previous salt", rotated.PreviousSalt)
+	}
+
+	if _, err := repo.Rotate(context.Background(), "missing", "salt", grace, now); !errors.Is(err, repository.ErrAPIKeyNotFound) {
+		t.Fatalf("Rotate missing: expected %v, got %v", repository.ErrAPIKeyNotFound, err)
+	}
+	if _, err := repo.Revoke(context.Background(), "k1", now); err != nil {
+		t.Fatalf("Revoke: unexpected error: %v", err)
+	}
+	if _, err := repo.Rotate(context.Background(), "k1", "salt-4", grace, now); !errors.Is(err, repository.ErrAPIKeyRevoked) {
+		t.Fatalf("Rotate revoked: expected %v, got %v", repository.ErrAPIKeyRevoked, err)
+	}
+	if got, _ := repo.GetByID(context.Background(), "k1"); got.Salt != "salt-3" {
+		t.Fatalf("refused rotation changed the key: %+v", got)
+	}
+}
+
+func testAPIKeyRevoke(t *testing.T, repo repository.APIKeyRepository) {
+	key := mustCreateAPIKey(t, repo, "k1")
+	mustCreateAPIKey(t, repo, "other")
+	now := key.CreatedAt.Add(time.Hour)
+
+	revoked, err := repo.Revoke(context.Background(), "k1", now)
+	if err != nil {
+		t.Fatalf("Revoke: unexpected error: %v", err)
+	}
+	if revoked.Active() || !revoked.RevokedAt.Equal(now) {
+		t.Fatalf("key not revoked: %+v", revoked)
+	}
+	if got, _ := repo.GetByID(context.Background(), "other"); !got.Active() {
+		t.Fatalf("another key revoked: %+v", got)
+	}
+
+	// Revoking again keeps the first revocation time
+	again, err := repo.Revoke(context.Background(), "k1", now.Add(time.Hour))
+	if err != nil || !again.RevokedAt.Equal(now) {
+		t.Fatalf("Revoke again = %+v, %v", again, err)
+	}
+	if _, err := repo.Revoke(context.Background(), "missing", now); !errors.Is(err, repository.ErrAPIKeyNotFound) {
+		t.Fatalf("Revoke missing: expected %v, got %v", repository.ErrAPIKeyNotFound, err)
+	}
+}
+
+func testAPIKeyUseNonce(t *testing.T, repo repository.APIKeyRepository) {
+	expiresAt := time.Now().Add(time.Minute)
+
+	if err := repo.UseNonce(context.Background(), "k1", "n1", expiresAt); err != nil {
+		t.Fatalf("UseNonce: unexpected error: %v", err)
+	}
+	if err := repo.UseNonce(context.Background(), "k1", "n1", expiresAt); !errors.Is(err, repository.ErrNonceUsed) {
+		t.Fatalf("UseNonce again: expected %v, got %v", repository.ErrNonceUsed, err)
+	}
+
+	// Nonces are per key
+	if err := repo.UseNonce(context.Background(), "k2", "n1", expiresAt); err != nil {
+		t.Fatalf("UseNonce for another key: unexpected error: %v", err)
+	}
+}
+
+func testAPIKeyDeleteExpiredNonces(t *testing.T, repo repository.APIKeyRepository) {
+	now := time.Now()
+	if err := repo.UseNonce(context.Background(), "k1", "old", now); err != nil {
+		t.Fatalf("UseNonce: unexpected error: %v", err)
+	}
+	if err := repo.UseNonce(context.Background(), "k1", "new", now.Add(time.Minute)); err != nil {
+		t.Fatalf("UseNonce: unexpected error: %v", err)
+	}
+
+	deleted, err := repo.DeleteExpiredNonces(context.Background(), now)
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteExpiredNonces = %d, %v, want 1", deleted, err)
+	}
+	if err := repo.UseNonce(context.Background(), "k1", "old", now.Add(time.Minute)); err != nil {
+		t.Fatalf("expired nonce kept: %v", err)
+	}
+	if err := repo.UseNonce(context.Background(), "k1", "new", now.Add(time.Minute)); !errors.Is(err, repository.ErrNonceUsed) {
+		t.Fatalf("unexpired nonce deleted: %v", err)
+	}
+}
+
+func testAPIKeyConcurrentNonces(t *testing.T, repo repository.APIKeyRepository) {
+	expiresAt := time.Now().Add(time.Minute)
+
+	const workers = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	used := 0
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.UseNonce(context.Background(), "k1", "n1", expiresAt)
+			switch {
+			case err == nil:
+				mu.Lock()
+				used++
+				mu.Unlock()
+			case !errors.Is(err, repository.ErrNonceUsed):
+				errs <- fmt.Errorf("UseNonce: %w", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if used != 1 {
+		t.Fatalf("%d concurrent uses of a nonce succeeded, want 1", used)
+	}
+}
+
+func testAPIKeyHonoursCancelledContext(t *testing.T, repo repository.APIKeyRepository) {
+	mustCreateAPIKey(t, repo, "k1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	now := time.Now()
+	assertCancelled(t, []cancelledCall{
+		{"Create", repo.Create(ctx, entity.NewAPIKey("k2", "POS k2", []entity.APIScope{entity.ScopePointsRead}, 60, "salt-k2", "admin"))},
+		{"GetByID", errOf(repo.GetByID(ctx, "k1"))},
+		{"List", errOf(repo.List(ctx))},
+		{"Rotate", errOf(repo.Rotate(ctx, "k1", "salt-next", now.Add(time.Hour), now))},
+		{"Revoke", errOf(repo.Revoke(ctx, "k1", now))},
+		{"UseNonce", repo.UseNonce(ctx, "k1", "n1", now.Add(time.Minute))},
+		{"DeleteExpiredNonces", errOf(repo.DeleteExpiredNonces(ctx, now))},
+	})
+
+	// None of the writes took effect
+	if got, err := repo.GetByID(context.Background(), "k1"); err != nil || got.Salt != "salt-k1" || got.RevokedAt != nil {
+		t.Fatalf("GetByID(k1) = %+v, %v", got, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "k2"); !errors.Is(err, repository.ErrAPIKeyNotFound) {
+		t.Fatalf("GetByID(k2): expected %v, got %v", repository.ErrAPIKeyNotFound, err)
+	}
+	if err := repo.UseNonce(context.Background(), "k1", "n1", now.Add(time.Minute)); err != nil {
+		t.Fatalf("UseNonce after cancelled use: unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// CartFactory returns a new, empty cart repository for a single test
+type CartFactory func(t *testing.T) repository.CartRepository
+
+// RunCartRepositoryTests runs the conformance suite against the cart
+// repositories returned by newRepo
+func RunCartRepositoryTests(t *testing.T, newRepo CartFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.CartRepository)
+	}{
+		{"GetWithoutCartIsEmpty", testCartGetWithoutCart},
+		{"SaveAndGet", testCartSaveAndGet},
+		{"SaveRejectsInvalid", testCartSaveRejectsInvalid},
+		{"Delete", testCartDelete},
+		{"HonoursCancelledContext", testCartHonoursCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+func mustSaveCart(t *testing.T, repo repository.CartRepository, cart *entity.Cart) {
+	t.Helper()
+	if err := repo.Save(context.Background(), cart); err != nil {
+		t.Fatalf("Save(%s): unexpected error: %v", cart.UserID, err)
+	}
+}
+
+func testCartGetWithoutCart(t *testing.T, repo repository.CartRepository) {
+	cart, err := repo.Get(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	if cart.UserID != "alice" || len(cart.Items) != 0 || cart.CustomerName != "" {
+		t.Fatalf("expected an empty cart, got %+v", cart)
+	}
+}
+
+func testCartSaveAndGet(t *testing.T, repo repository.CartRepository) {
+	cart := entity.NewCart("alice")
+	cart.SetQuantity("mocha", 1)
+	cart.SetQuantity("latte", 2)
+	cart.CustomerName = "Somchai Jaidee"
+	cart.CustomerPhone = "+66812345678"
+	mustSaveCart(t, repo, cart)
+
+	got, err := repo.Get(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	if len(got.Items) != 2 || got.Items[0] != (entity.CartItem{ProductID: "mocha", Quantity: 1}) ||
+		got.Items[1] != (entity.CartItem{ProductID: "latte", Quantity: 2}) ||
+		got.CustomerName != "Somchai Jaidee" || got.CustomerPhone != "+66812345678" ||
+		!got.UpdatedAt.Equal(cart.UpdatedAt) {
+		t.Fatalf("cart mismatch\n got: %+v\nwant: %+v", got, cart)
+	}
+
+	// Saving replaces the items
+	got.SetQuantity("mocha", 0)
+	got.Items[0].Quantity = 5
+	mustSaveCart(t, repo, got)
+	again, err := repo.Get(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	if len(again.Items) != 1 || again.Items[0] != (entity.CartItem{ProductID: "latte", Quantity: 5}) {
+		t.Fatalf("unexpected items after save: %+v", again.Items)
+	}
+
+	// Other members' carts are separate
+	if bob, _ := repo.Get(context.Background(), "bob"); len(bob.Items) != 0 {
+		t.Fatalf("expected bob's cart to be empty, got %+v", bob.Items)
+	}
+}
+
+func testCartSaveRejectsInvalid(t *testing.T, repo repository.CartRepository) {
+	tests := []struct {
+		name string
+		cart *entity.Cart
+		want error
+	}{
+		{"nil", nil, repository.ErrNilCart},
+		{"no user", entity.NewCart(""), repository.ErrInvalidCart},
+		{"no product", &entity.Cart{UserID: "alice", Items: []entity.CartItem{{Quantity: 1}}}, repository.ErrInvalidCart},
+		{"zero quantity", &entity.Cart{UserID: "alice", Items: []entity.CartItem{{ProductID: "latte"}}}, repository.ErrInvalidCart},
+		{"duplicate product", &entity.Cart{UserID: "alice", Items: []entity.CartItem{
+			{ProductID: "latte", Quantity: 1}, {ProductID: "latte", Quantity: 2},
+		}}, repository.ErrInvalidCart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Save(context.Background(), tt.cart); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testCartDelete(t *testing.T, repo repository.CartRepository) {
+	cart := entity.NewCart("alice")
+	cart.SetQuantity("latte", 2)
+	mustSaveCart(t, repo, cart)
+
+	if err := repo.Delete(context.Background(), "alice"); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	got, err := repo.Get(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	if len(got.Items) != 0 {
+		t.Fatalf("expected an empty cart after delete, got %+v", got.Items)
+	}
+	if err := repo.Delete(context.Background(), "alice"); err != nil {
+		t.Fatalf("Delete twice: unexpected error: %v", err)
+	}
+}
+
+func testCartHonoursCancelledContext(t *testing.T, repo repository.CartRepository) {
+	cart := entity.NewCart("alice")
+	cart.SetQuantity("latte", 2)
+	mustSaveCart(t, repo, cart)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	changed := entity.NewCart("alice")
+	changed.SetQuantity("mocha", 1)
+	assertCancelled(t, []cancelledCall{
+		{"Get", errOf(repo.Get(ctx, "alice"))},
+		{"Save", repo.Save(ctx, changed)},
+		{"Delete", repo.Delete(ctx, "alice")},
+	})
+
+	// None of the writes took effect
+	got, err := repo.Get(context.Background(), "alice")
+	if err != nil || len(got.Items) != 1 || got.Items[0].ProductID != "latte" {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+}
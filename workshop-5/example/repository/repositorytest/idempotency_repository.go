@@ -0,0 +1,243 @@
+package repositorytest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// IdempotencyFactory returns a new, empty idempotency repository for a
+// single test
+type IdempotencyFactory func(t *testing.T) repository.IdempotencyRepository
+
+// RunIdempotencyRepositoryTests runs the conformance suite against the
+// idempotency repositories returned by newRepo
+func RunIdempotencyRepositoryTests(t *testing.T, newRepo IdempotencyFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.IdempotencyRepository)
+	}{
+		{"ReserveAndComplete", testIdempotencyReserveAndComplete},
+		{"ReserveRejectsInvalid", testIdempotencyReserveRejectsInvalid},
+		{"ReserveReplacesExpired", testIdempotencyReserveReplacesExpired},
+		{"CompleteRequiresReservation", testIdempotencyCompleteRequiresReservation},
+		{"Release", testIdempotencyRelease},
+		{"DeleteExpired", testIdempotencyDeleteExpired},
+		{"ConcurrentReserveOnlyOneWins", testIdempotencyConcurrentReserve},
+		{"HonoursCancelledContext", testIdempotencyHonoursCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+func newIdempotencyRecord(key string, now time.Time, ttl time.Duration) *entity.IdempotencyRecord {
+	return &entity.IdempotencyRecord{
+		Key:         key,
+		RequestHash: "hash-" + key,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+}
+
+func mustReserve(t *testing.T, repo repository.IdempotencyRepository, record *entity.IdempotencyRecord) {
+	t.Helper()
+	if existing, err := repo.Reserve(context.Background(), record); err != nil {
+		t.Fatalf("Reserve(%s): unexpected error: %v (existing %+v)", record.Key, err, existing)
+	}
+}
+
+func testIdempotencyReserveAndComplete(t *testing.T, repo repository.IdempotencyRepository) {
+	now := time.Now()
+	record := newIdempotencyRecord("k1", now, time.Hour)
+	mustReserve(t, repo, record)
+
+	existing, err := repo.Reserve(context.Background(), newIdempotencyRecord("k1", now.Add(time.Minute), time.Hour))
+	if !errors.Is(err, repository.ErrIdempotencyKeyExists) {
+		t.Fatalf("second Reserve: expected %v, got %v", repository.ErrIdempotencyKeyExists, err)
+	}
+	if existing == nil || existing.Completed || existing.RequestHash != record.RequestHash {
+		t.Fatalf("second Reserve returned %+v, want the uncompleted reservation", existing)
+	}
+
+	record.StatusCode = 201
+	record.ContentType = "application/json"
+	record.Body = []byte(`{"success":true}`)
+	if err := repo.Complete(context.Background(), record); err != nil {
+		t.Fatalf("Complete: unexpected error: %v", err)
+	}
+
+	existing, err = repo.Reserve(context.Background(), newIdempotencyRecord("k1", now.Add(time.Minute), time.Hour))
+	if !errors.Is(err, repository.ErrIdempotencyKeyExists) {
+		t.Fatalf("Reserve after Complete: expected %v, got %v", repository.ErrIdempotencyKeyExists, err)
+	}
+	if !existing.Completed || existing.StatusCode != 201 || existing.ContentType != "application/json" ||
+		!bytes.Equal(existing.Body, record.Body) || !existing.ExpiresAt.Equal(record.ExpiresAt) {
+		t.Fatalf("stored record mismatch\n got: %+v\nwant: %+v", existing, record)
+	}
+
+	if err := repo.Complete(context.Background(), record); !errors.Is(err, repository.ErrIdempotencyKeyNotFound) {
+		t.Fatalf("second Complete: expected %v, got %v", repository.ErrIdempotencyKeyNotFound, err)
+	}
+}
+
+func testIdempotencyReserveRejectsInvalid(t *testing.T, repo repository.IdempotencyRepository) {
+	now := time.Now()
+	noHash := newIdempotencyRecord("k1", now, time.Hour)
+	noHash.RequestHash = ""
+
+	tests := []struct {
+		name   string
+		record *entity.IdempotencyRecord
+		want   error
+	}{
+		{"nil", nil, repository.ErrNilIdempotencyRecord},
+		{"empty key", newIdempotencyRecord("", now, time.Hour), repository.ErrInvalidIdempotencyRecord},
+		{"empty hash", noHash, repository.ErrInvalidIdempotencyRecord},
+		{"no expiry", &entity.IdempotencyRecord{Key: "k2", RequestHash: "h"}, repository.ErrInvalidIdempotencyRecord},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repo.Reserve(context.Background(), tt.record); !errors.Is(err, tt.want) {
+				t.Fatalf("Reserve: expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testIdempotencyReserveReplacesExpired(t *testing.T, repo repository.IdempotencyRepository) {
+	now := time.Now()
+	old := newIdempotencyRecord("k1", now, time.Minute)
+	mustReserve(t, repo, old)
+	old.StatusCode = 200
+	if err := repo.Complete(context.Background(), old); err != nil {
+		t.Fatalf("Complete: unexpected error: %v", err)
+	}
+
+	fresh := newIdempotencyRecord("k1", now.Add(time.Minute), time.Minute)
+	fresh.RequestHash = "other"
+	mustReserve(t, repo, fresh)
+
+	existing, err := repo.Reserve(context.Background(), newIdempotencyRecord("k1", now.Add(90*time.Second), time.Minute))
+	if !errors.Is(err, repository.ErrIdempotencyKeyExists) || existing.RequestHash != "other" || existing.Completed {
+		t.Fatalf("Reserve = %+v, %v; want the fresh reservation", existing, err)
+	}
+}
+
+func testIdempotencyCompleteRequiresReservation(t *testing.T, repo repository.IdempotencyRepository) {
+	now := time.Now()
+	if err := repo.Complete(context.Background(), newIdempotencyRecord("missing", now, time.Hour)); !errors.Is(err, repository.ErrIdempotencyKeyNotFound) {
+		t.Fatalf("Complete missing: expected %v, got %v", repository.ErrIdempotencyKeyNotFound, err)
+	}
+
+	mustReserve(t, repo, newIdempotencyRecord("k1", now, time.Hour))
+	other := newIdempotencyRecord("k1", now, time.Hour)
+	other.RequestHash = "other"
+	if err := repo.Complete(context.Background(), other); !errors.Is(err, repository.ErrIdempotencyKeyNotFound) {
+		t.Fatalf("Complete with another hash: expected %v, got %v", repository.ErrIdempotencyKeyNotFound, err)
+	}
+}
+
+func testIdempotencyRelease(t *testing.T, repo repository.IdempotencyRepository) {
+	now := time.Now()
+	mustReserve(t, repo, newIdempotencyRecord("k1", now, time.Hour))
+
+	if err := repo.Release(context.Background(), "k1"); err != nil {
+		t.Fatalf("Release: unexpected error: %v", err)
+	}
+	mustReserve(t, repo, newIdempotencyRecord("k1", now, time.Hour))
+
+	completed := newIdempotencyRecord("k1", now, time.Hour)
+	if err := repo.Complete(context.Background(), completed); err != nil {
+		t.Fatalf("Complete: unexpected error: %v", err)
+	}
+	if err := repo.Release(context.Background(), "k1"); !errors.Is(err, repository.ErrIdempotencyKeyNotFound) {
+		t.Fatalf("Release completed: expected %v, got %v", repository.ErrIdempotencyKeyNotFound, err)
+	}
+	if err := repo.Release(context.Background(), "missing"); !errors.Is(err, repository.ErrIdempotencyKeyNotFound) {
+		t.Fatalf("Release missing: expected %v, got %v", repository.ErrIdempotencyKeyNotFound, err)
+	}
+}
+
+func testIdempotencyDeleteExpired(t *testing.T, repo repository.IdempotencyRepository) {
+	now := time.Now()
+	mustReserve(t, repo, newIdempotencyRecord("short", now, time.Minute))
+	mustReserve(t, repo, newIdempotencyRecord("long", now, time.Hour))
+
+	removed, err := repo.DeleteExpired(context.Background(), now.Add(time.Minute))
+	if err != nil || removed != 1 {
+		t.Fatalf("DeleteExpired = %d, %v; want 1", removed, err)
+	}
+	if err := repo.Release(context.Background(), "short"); !errors.Is(err, repository.ErrIdempotencyKeyNotFound) {
+		t.Fatalf("expired record still stored: %v", err)
+	}
+	if err := repo.Release(context.Background(), "long"); err != nil {
+		t.Fatalf("unexpired record removed: %v", err)
+	}
+}
+
+func testIdempotencyConcurrentReserve(t *testing.T, repo repository.IdempotencyRepository) {
+	now := time.Now()
+
+	const attempts = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	won := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			record := newIdempotencyRecord("k1", now, time.Hour)
+			record.RequestHash = fmt.Sprintf("hash-%d", i)
+			_, err := repo.Reserve(context.Background(), record)
+			if err != nil && !errors.Is(err, repository.ErrIdempotencyKeyExists) {
+				t.Errorf("Reserve: unexpected error: %v", err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				won++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if won != 1 {
+		t.Fatalf("expected exactly one reservation to win, got %d", won)
+	}
+}
+
+func testIdempotencyHonoursCancelledContext(t *testing.T, repo repository.IdempotencyRepository) {
+	now := time.Now()
+	record := newIdempotencyRecord("k1", now, time.Hour)
+	mustReserve(t, repo, record)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	completed := *record
+	completed.StatusCode = 201
+	assertCancelled(t, []cancelledCall{
+		{"Reserve", errOf(repo.Reserve(ctx, newIdempotencyRecord("k2", now, time.Hour)))},
+		{"Complete", repo.Complete(ctx, &completed)},
+		{"Release", repo.Release(ctx, "k1")},
+		{"DeleteExpired", errOf(repo.DeleteExpired(ctx, now.Add(2*time.Hour)))},
+	})
+
+	// None of the writes took effect: k1 is still reserved and k2 is free
+	if _, err := repo.Reserve(context.Background(), newIdempotencyRecord("k1", now, time.Hour)); !errors.Is(err, repository.ErrIdempotencyKeyExists) {
+		t.Fatalf("Reserve(k1): expected %v, got %v", repository.ErrIdempotencyKeyExists, err)
+	}
+	mustReserve(t, repo, newIdempotencyRecord("k2", now, time.Hour))
+}
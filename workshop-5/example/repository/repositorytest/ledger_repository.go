@@ -0,0 +1,338 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// LedgerFactory returns a new, empty ledger repository for a single test
+type LedgerFactory func(t *testing.T) repository.LedgerRepository
+
+// RunLedgerRepositoryTests runs the conformance suite against the ledger
+// repositories returned by newRepo
+func RunLedgerRepositoryTests(t *testing.T, newRepo LedgerFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.LedgerRepository)
+	}{
+		{"PostAndBalance", testPostAndBalance},
+		{"PostRejectsInvalid", testPostRejectsInvalid},
+		{"PostRejectsDuplicate", testPostRejectsDuplicate},
+		{"PostRejectsOverdraft", testPostRejectsOverdraft},
+		{"GetPostingAndEntry", testGetPostingAndEntry},
+		{"Balances", testBalances},
+		{"HonoursCancelledContext", testLedgerHonoursCancelledContext},
+		{"EntriesNewestFirst", testEntriesNewestFirst},
+		{"EntriesFilterByType", testEntriesFilterByType},
+		{"EntriesPaginate", testEntriesPaginate},
+		{"ConcurrentSpendsNeverOverdraw", testConcurrentSpendsNeverOverdraw},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+func mustPost(t *testing.T, repo repository.LedgerRepository, posting *entity.Posting) {
+	t.Helper()
+	if err := repo.Post(context.Background(), posting); err != nil {
+		t.Fatalf("Post(%s): unexpected error: %v", posting.ID, err)
+	}
+}
+
+func assertBalance(t *testing.T, repo repository.LedgerRepository, accountID string, want int) {
+	t.Helper()
+	got, err := repo.Balance(context.Background(), accountID)
+	if err != nil {
+		t.Fatalf("Balance(%s): unexpected error: %v", accountID, err)
+	}
+	if got != want {
+		t.Fatalf("Balance(%s) = %d, want %d", accountID, got, want)
+	}
+}
+
+func testPostAndBalance(t *testing.T, repo repository.LedgerRepository) {
+	assertBalance(t, repo, "alice", 0)
+
+	mustPost(t, repo, entity.NewEarnPosting("p1", "alice", 500, "welcome"))
+	mustPost(t, repo, entity.NewSpendPosting("p2", "alice", 200, "coffee", "order-1"))
+	mustPost(t, repo, entity.NewTransferPosting("p3", "alice", "bob", 100, "lunch", "transfer-1"))
+
+	assertBalance(t, repo, "alice", 200)
+	assertBalance(t, repo, "bob", 100)
+	assertBalance(t, repo, entity.AccountRewards, -500)
+	assertBalance(t, repo, entity.AccountRedemptions, 200)
+}
+
+func testPostRejectsInvalid(t *testing.T, repo repository.LedgerRepository) {
+	unbalanced := entity.NewEarnPosting("unbalanced", "alice", 100, "")
+	unbalanced.Entries[1].Amount = -99
+
+	single := entity.NewPosting("single", entity.LedgerEntry{AccountID: "alice", Type: entity.EntryEarn, Amount: 100})
+
+	zero := entity.NewEarnPosting("zero", "alice", 0, "")
+
+	sameAccount := entity.NewTransferPosting("same", "alice", "alice", 100, "", "")
+
+	unknownType := entity.NewEarnPosting("unknown", "alice", 100, "")
+	unknownType.Entries[0].Type = "bonus"
+
+	noID := entity.NewEarnPosting("", "alice", 100, "")
+
+	tests := []struct {
+		name    string
+		posting *entity.Posting
+		want    error
+	}{
+		{"nil", nil, repository.ErrNilPosting},
+		{"unbalanced", unbalanced, repository.ErrUnbalancedPosting},
+		{"single entry", single, repository.ErrInvalidPosting},
+		{"zero amount", zero, repository.ErrInvalidPosting},
+		{"same account twice", sameAccount, repository.ErrInvalidPosting},
+		{"unknown type", unknownType, repository.ErrInvalidPosting},
+		{"empty ID", noID, repository.ErrInvalidPosting},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Post(context.Background(), tt.posting); !errors.Is(err, tt.want) {
+				t.Fatalf("Post: expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+	assertBalance(t, repo, "alice", 0)
+}
+
+func testPostRejectsDuplicate(t *testing.T, repo repository.LedgerRepository) {
+	mustPost(t, repo, entity.NewEarnPosting("p1", "alice", 100, ""))
+
+	if err := repo.Post(context.Background(), entity.NewEarnPosting("p1", "alice", 100, "")); !errors.Is(err, repository.ErrPostingExists) {
+		t.Fatalf("Post duplicate: expected %v, got %v", repository.ErrPostingExists, err)
+	}
+	assertBalance(t, repo, "alice", 100)
+}
+
+func testPostRejectsOverdraft(t *testing.T, repo repository.LedgerRepository) {
+	mustPost(t, repo, entity.NewEarnPosting("p1", "alice", 100, ""))
+
+	err := repo.Post(context.Background(), entity.NewTransferPosting("p2", "alice", "bob", 101, "", ""))
+	if !errors.Is(err, repository.ErrInsufficientBalance) {
+		t.Fatalf("Post overdraft: expected %v, got %v", repository.ErrInsufficientBalance, err)
+	}
+
+	// Neither leg may be written
+	assertBalance(t, repo, "alice", 100)
+	assertBalance(t, repo, "bob", 0)
+	if _, err := repo.GetPosting(context.Background(), "p2"); !errors.Is(err, repository.ErrPostingNotFound) {
+		t.Fatalf("GetPosting after rejected post: expected %v, got %v", repository.ErrPostingNotFound, err)
+	}
+
+	// Spending the exact balance is allowed
+	mustPost(t, repo, entity.NewSpendPosting("p3", "alice", 100, "", ""))
+	assertBalance(t, repo, "alice", 0)
+}
+
+func testGetPostingAndEntry(t *testing.T, repo repository.LedgerRepository) {
+	want := entity.NewTransferPosting("p1", entity.AccountRewards, "bob", 250, "gift", "transfer-1")
+	mustPost(t, repo, want)
+
+	got, err := repo.GetPosting(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetPosting: unexpected error: %v", err)
+	}
+	if len(got.Entries) != 2 || !got.Balanced() {
+		t.Fatalf("GetPosting returned %+v", got)
+	}
+
+	entry, err := repo.GetEntry(context.Background(), want.Entries[1].ID)
+	if err != nil {
+		t.Fatalf("GetEntry: unexpected error: %v", err)
+	}
+	w := want.Entries[1]
+	if entry.ID != w.ID || entry.PostingID != "p1" || entry.AccountID != "bob" ||
+		entry.Type != entity.EntryTransferIn || entry.Amount != 250 ||
+		entry.CounterpartyID != entity.AccountRewards || entry.TransferID != "transfer-1" ||
+		entry.Memo != "gift" || !entry.CreatedAt.Equal(w.CreatedAt) {
+		t.Fatalf("entry mismatch\n got: %+v\nwant: %+v", entry, w)
+	}
+
+	if _, err := repo.GetPosting(context.Background(), "missing"); !errors.Is(err, repository.ErrPostingNotFound) {
+		t.Fatalf("GetPosting missing: expected %v, got %v", repository.ErrPostingNotFound, err)
+	}
+	if _, err := repo.GetEntry(context.Background(), "missing"); !errors.Is(err, repository.ErrEntryNotFound) {
+		t.Fatalf("GetEntry missing: expected %v, got %v", repository.ErrEntryNotFound, err)
+	}
+}
+
+func testBalances(t *testing.T, repo repository.LedgerRepository) {
+	mustPost(t, repo, entity.NewEarnPosting("p1", "alice", 100, ""))
+	mustPost(t, repo, entity.NewEarnPosting("p2", "bob", 300, ""))
+	mustPost(t, repo, entity.NewEarnPosting("p3", "alice", 50, ""))
+
+	got, err := repo.Balances(context.Background(), []string{"alice", "bob", "carol"})
+	if err != nil {
+		t.Fatalf("Balances: unexpected error: %v", err)
+	}
+	want := map[string]int{"alice": 150, "bob": 300, "carol": 0}
+	if len(got) != len(want) {
+		t.Fatalf("Balances = %v, want %v", got, want)
+	}
+	for id, balance := range want {
+		if got[id] != balance {
+			t.Fatalf("Balances = %v, want %v", got, want)
+		}
+	}
+
+	if got, err := repo.Balances(context.Background(), nil); err != nil || len(got) != 0 {
+		t.Fatalf("Balances(nil) = %v, %v", got, err)
+	}
+}
+
+func testLedgerHonoursCancelledContext(t *testing.T, repo repository.LedgerRepository) {
+	mustPost(t, repo, entity.NewEarnPosting("p1", "alice", 100, ""))
+	posting, err := repo.GetPosting(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetPosting: unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assertCancelled(t, []cancelledCall{
+		{"Post", repo.Post(ctx, entity.NewEarnPosting("p2", "alice", 50, ""))},
+		{"GetPosting", errOf(repo.GetPosting(ctx, "p1"))},
+		{"GetEntry", errOf(repo.GetEntry(ctx, posting.Entries[0].ID))},
+		{"Balance", errOf(repo.Balance(ctx, "alice"))},
+		{"Balances", errOf(repo.Balances(ctx, []string{"alice"}))},
+		{"Entries", errOf(repo.Entries(ctx, repository.LedgerQuery{AccountID: "alice", Limit: 10}))},
+	})
+
+	// The cancelled posting was not recorded
+	assertBalance(t, repo, "alice", 100)
+}
+
+// seedLedger posts n earn postings for alice, plus one posting for bob
+func seedLedger(t *testing.T, repo repository.LedgerRepository, n int) {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		mustPost(t, repo, entity.NewEarnPosting(fmt.Sprintf("p%02d", i), "alice", i, ""))
+	}
+	mustPost(t, repo, entity.NewEarnPosting("bob-1", "bob", 1, ""))
+}
+
+func testEntriesNewestFirst(t *testing.T, repo repository.LedgerRepository) {
+	seedLedger(t, repo, 3)
+
+	page, err := repo.Entries(context.Background(), repository.LedgerQuery{AccountID: "alice", Limit: 10})
+	if err != nil {
+		t.Fatalf("Entries: unexpected error: %v", err)
+	}
+	if len(page.Entries) != 3 || page.Next != nil {
+		t.Fatalf("expected 3 entries on a single page, got %d (next %v)", len(page.Entries), page.Next)
+	}
+	for i, entry := range page.Entries {
+		if entry.AccountID != "alice" {
+			t.Fatalf("entry for another account: %+v", entry)
+		}
+		if i > 0 && entry.CreatedAt.After(page.Entries[i-1].CreatedAt) {
+			t.Fatalf("entries not newest first: %v after %v", entry.CreatedAt, page.Entries[i-1].CreatedAt)
+		}
+	}
+}
+
+func testEntriesFilterByType(t *testing.T, repo repository.LedgerRepository) {
+	mustPost(t, repo, entity.NewEarnPosting("p1", "alice", 500, ""))
+	mustPost(t, repo, entity.NewSpendPosting("p2", "alice", 100, "", ""))
+	mustPost(t, repo, entity.NewTransferPosting("p3", "alice", "bob", 100, "", ""))
+	mustPost(t, repo, entity.NewTransferPosting("p4", "bob", "alice", 50, "", ""))
+
+	page, err := repo.Entries(context.Background(), repository.LedgerQuery{
+		AccountID: "alice",
+		Types:     []entity.EntryType{entity.EntryTransferIn, entity.EntryTransferOut},
+		Limit:     10,
+	})
+	if err != nil {
+		t.Fatalf("Entries: unexpected error: %v", err)
+	}
+	if len(page.Entries) != 2 {
+		t.Fatalf("expected 2 transfer entries, got %d", len(page.Entries))
+	}
+	for _, entry := range page.Entries {
+		if entry.Type != entity.EntryTransferIn && entry.Type != entity.EntryTransferOut {
+			t.Fatalf("unexpected entry type %q", entry.Type)
+		}
+	}
+
+	if _, err := repo.Entries(context.Background(), repository.LedgerQuery{AccountID: "alice", Types: []entity.EntryType{"bonus"}, Limit: 10}); err == nil {
+		t.Fatal("Entries with unknown type: expected an error")
+	}
+	if _, err := repo.Entries(context.Background(), repository.LedgerQuery{Limit: 10}); err == nil {
+		t.Fatal("Entries without account: expected an error")
+	}
+}
+
+func testEntriesPaginate(t *testing.T, repo repository.LedgerRepository) {
+	seedLedger(t, repo, 7)
+
+	seen := map[string]bool{}
+	q := repository.LedgerQuery{AccountID: "alice", Limit: 3}
+	for pages := 1; ; pages++ {
+		page, err := repo.Entries(context.Background(), q)
+		if err != nil {
+			t.Fatalf("Entries: unexpected error: %v", err)
+		}
+		for _, entry := range page.Entries {
+			if seen[entry.ID] {
+				t.Fatalf("entry %s listed twice", entry.ID)
+			}
+			seen[entry.ID] = true
+		}
+		if page.Next == nil {
+			if pages != 3 {
+				t.Fatalf("expected 3 pages, got %d", pages)
+			}
+			break
+		}
+		q.After = page.Next
+	}
+	if len(seen) != 7 {
+		t.Fatalf("expected 7 entries, got %d", len(seen))
+	}
+}
+
+func testConcurrentSpendsNeverOverdraw(t *testing.T, repo repository.LedgerRepository) {
+	mustPost(t, repo, entity.NewEarnPosting("seed", "alice", 1000, ""))
+
+	const attempts = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := repo.Post(context.Background(), entity.NewSpendPosting(fmt.Sprintf("spend-%d", i), "alice", 100, "", ""))
+			switch {
+			case err == nil:
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			case !errors.Is(err, repository.ErrInsufficientBalance):
+				t.Errorf("Post: expected nil or %v, got %v", repository.ErrInsufficientBalance, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if succeeded != 10 {
+		t.Fatalf("expected exactly 10 spends to succeed, got %d", succeeded)
+	}
+	assertBalance(t, repo, "alice", 0)
+}
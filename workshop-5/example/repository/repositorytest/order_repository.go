@@ -0,0 +1,434 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// OrderRepositories is an order repository together with the repositories
+// it settles against
+type OrderRepositories struct {
+	Orders   repository.OrderRepository
+	Products repository.ProductRepository
+	Ledger   repository.LedgerRepository
+	Carts    repository.CartRepository
+	Outbox   repository.OutboxRepository
+}
+
+// OrderFactory returns new, empty order, product, ledger, cart and outbox
+// repositories that share storage
+type OrderFactory func(t *testing.T) OrderRepositories
+
+// RunOrderRepositoryTests runs the conformance suite against the order
+// repositories returned by newRepos
+func RunOrderRepositoryTests(t *testing.T, newRepos OrderFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repos OrderRepositories)
+	}{
+		{"PlaceSettlesOrder", testOrderPlaceSettles},
+		{"PlaceRejectsInvalid", testOrderPlaceRejectsInvalid},
+		{"PlaceRollsBackOnFailure", testOrderPlaceRollsBack},
+		{"PlaceOnlyOnce", testOrderPlaceOnlyOnce},
+		{"ConcurrentPlacesNeverOversell", testOrderConcurrentPlaces},
+		{"Transition", testOrderTransition},
+		{"TransitionRefundsAndRestocks", testOrderTransitionRefunds},
+		{"TransitionRejectsInvalid", testOrderTransitionRejectsInvalid},
+		{"ConcurrentTransitionsApplyOnce", testOrderConcurrentTransitions},
+		{"HonoursCancelledContext", testOrderHonoursCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepos(t))
+		})
+	}
+}
+
+// newOrder builds a paid order for quantity units of an Iced Latte at 100
+// points each, with its points payment
+func newOrder(id, userID string, quantity int) (*entity.Order, *entity.Payment) {
+	order := entity.NewOrder(id, userID, []entity.OrderItem{{
+		ProductID: "latte", Name: "Iced Latte", UnitPrice: 100, Quantity: quantity, LineTotal: 100 * quantity,
+	}}, "Somchai Jaidee", "+66812345678")
+	order.Apply(entity.OrderPaid, userID, "Paid with points")
+	return order, entity.NewPointsPayment("pay-"+id, order)
+}
+
+func assertStock(t *testing.T, repo repository.ProductRepository, id string, want int) {
+	t.Helper()
+	product, err := repo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID(%s): unexpected error: %v", id, err)
+	}
+	if product.Stock != want {
+		t.Fatalf("stock of %s = %d, want %d", id, product.Stock, want)
+	}
+}
+
+func testOrderPlaceSettles(t *testing.T, repos OrderRepositories) {
+	mustCreateProduct(t, repos.Products, entity.NewProduct("latte", "Iced Latte", "", "drinks", 100, 5, true))
+	mustPost(t, repos.Ledger, entity.NewEarnPosting("seed", "alice", 500, ""))
+	cart := entity.NewCart("alice")
+	cart.SetQuantity("latte", 2)
+	mustSaveCart(t, repos.Carts, cart)
+
+	order, payment := newOrder("o1", "alice", 2)
+	if err := repos.Orders.Place(context.Background(), order, payment, newOutboxMessage(t, "receipt-o1", 0)); err != nil {
+		t.Fatalf("Place: unexpected error: %v", err)
+	}
+
+	assertStock(t, repos.Products, "latte", 3)
+	assertOutboxStatus(t, repos.Outbox, "receipt-o1", entity.OutboxPending, 0)
+	assertBalance(t, repos.Ledger, "alice", 300)
+	assertBalance(t, repos.Ledger, entity.AccountRedemptions, 200)
+
+	posting, err := repos.Ledger.GetPosting(context.Background(), payment.ID)
+	if err != nil {
+		t.Fatalf("GetPosting: unexpected error: %v", err)
+	}
+	if entry := posting.EntryFor("alice"); entry == nil || entry.Type != entity.EntrySpend || entry.OrderID != "o1" {
+		t.Fatalf("unexpected payment posting: %+v", posting)
+	}
+
+	got, err := repos.Orders.GetByID(context.Background(), "o1")
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if got.UserID != "alice" || got.Total != 200 || got.Status != entity.OrderPaid || len(got.Items) != 1 ||
+		got.Items[0] != order.Items[0] || got.CustomerName != "Somchai Jaidee" || got.CustomerPhone != "+66812345678" ||
+		!got.CreatedAt.Equal(order.CreatedAt) {
+		t.Fatalf("order mismatch\n got: %+v\nwant: %+v", got, order)
+	}
+	if len(got.Events) != 2 || got.Events[1].From != entity.OrderCreated || got.Events[1].To != entity.OrderPaid ||
+		got.Events[1].Actor != "alice" || got.Events[1].Reason != "Paid with points" ||
+		!got.Events[1].CreatedAt.Equal(order.Events[1].CreatedAt) {
+		t.Fatalf("unexpected events: %+v", got.Events)
+	}
+
+	gotPayment, err := repos.Orders.GetPayment(context.Background(), "o1")
+	if err != nil {
+		t.Fatalf("GetPayment: unexpected error: %v", err)
+	}
+	if gotPayment.ID != payment.ID || gotPayment.Amount != 200 || gotPayment.Method != entity.PaymentPoints ||
+		gotPayment.Status != entity.PaymentCaptured || !gotPayment.CreatedAt.Equal(payment.CreatedAt) {
+		t.Fatalf("payment mismatch\n got: %+v\nwant: %+v", gotPayment, payment)
+	}
+
+	if cart, _ := repos.Carts.Get(context.Background(), "alice"); len(cart.Items) != 0 {
+		t.Fatalf("expected the cart to be emptied, got %+v", cart.Items)
+	}
+
+	if _, err := repos.Orders.GetByID(context.Background(), "missing"); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Fatalf("GetByID missing: expected %v, got %v", repository.ErrOrderNotFound, err)
+	}
+	if _, err := repos.Orders.GetPayment(context.Background(), "missing"); !errors.Is(err, repository.ErrPaymentNotFound) {
+		t.Fatalf("GetPayment missing: expected %v, got %v", repository.ErrPaymentNotFound, err)
+	}
+}
+
+func testOrderPlaceRejectsInvalid(t *testing.T, repos OrderRepositories) {
+	order, payment := newOrder("o1", "alice", 1)
+	empty := entity.NewOrder("o2", "alice", nil, "", "")
+	empty.Apply(entity.OrderPaid, "alice", "")
+	mismatched := order.Clone()
+	mismatched.Total = 50
+	unpaid := entity.NewOrder("o3", "alice", order.Items, "", "")
+
+	tests := []struct {
+		name    string
+		order   *entity.Order
+		payment *entity.Payment
+		want    error
+	}{
+		{"nil order", nil, payment, repository.ErrNilOrder},
+		{"nil payment", order, nil, repository.ErrNilOrder},
+		{"no items", empty, entity.NewPointsPayment("pay-o2", empty), repository.ErrInvalidOrder},
+		{"wrong total", mismatched, payment, repository.ErrInvalidOrder},
+		{"not paid", unpaid, entity.NewPointsPayment("pay-o3", unpaid), repository.ErrInvalidOrder},
+		{"payment for another order", order, entity.NewPointsPayment("pay-o2", empty), repository.ErrInvalidOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repos.Orders.Place(context.Background(), tt.order, tt.payment); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testOrderPlaceRollsBack(t *testing.T, repos OrderRepositories) {
+	mustCreateProduct(t, repos.Products, entity.NewProduct("latte", "Iced Latte", "", "drinks", 100, 2, true))
+	mustPost(t, repos.Ledger, entity.NewEarnPosting("seed", "alice", 150, ""))
+	cart := entity.NewCart("alice")
+	cart.SetQuantity("latte", 2)
+	mustSaveCart(t, repos.Carts, cart)
+
+	assertUnchanged := func() {
+		t.Helper()
+		assertStock(t, repos.Products, "latte", 2)
+		assertBalance(t, repos.Ledger, "alice", 150)
+		if _, err := repos.Orders.GetByID(context.Background(), "o1"); !errors.Is(err, repository.ErrOrderNotFound) {
+			t.Fatalf("expected no order to be stored, got %v", err)
+		}
+		if cart, _ := repos.Carts.Get(context.Background(), "alice"); len(cart.Items) != 1 {
+			t.Fatalf("expected the cart to be kept, got %+v", cart.Items)
+		}
+		if _, err := repos.Outbox.GetByID(context.Background(), "receipt-o1"); !errors.Is(err, repository.ErrOutboxMessageNotFound) {
+			t.Fatalf("expected no outbox message to be stored, got %v", err)
+		}
+	}
+
+	order, payment := newOrder("o1", "alice", 2)
+	if err := repos.Orders.Place(context.Background(), order, payment, newOutboxMessage(t, "receipt-o1", 0)); !errors.Is(err, repository.ErrInsufficientBalance) {
+		t.Fatalf("expected %v, got %v", repository.ErrInsufficientBalance, err)
+	}
+	assertUnchanged()
+
+	order, payment = newOrder("o1", "alice", 3)
+	if err := repos.Orders.Place(context.Background(), order, payment, newOutboxMessage(t, "receipt-o1", 0)); !errors.Is(err, repository.ErrOutOfStock) {
+		t.Fatalf("expected %v, got %v", repository.ErrOutOfStock, err)
+	}
+	assertUnchanged()
+
+	mustEnqueue(t, repos.Outbox, newOutboxMessage(t, "taken", 0))
+	order, payment = newOrder("o1", "alice", 1)
+	err := repos.Orders.Place(context.Background(), order, payment, newOutboxMessage(t, "receipt-o1", 0), newOutboxMessage(t, "taken", 0))
+	if !errors.Is(err, repository.ErrOutboxMessageExists) {
+		t.Fatalf("expected %v, got %v", repository.ErrOutboxMessageExists, err)
+	}
+	assertUnchanged()
+
+	latte, _ := repos.Products.GetByID(context.Background(), "latte")
+	latte.Active = false
+	if err := repos.Products.Update(context.Background(), latte); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	order, payment = newOrder("o1", "alice", 1)
+	if err := repos.Orders.Place(context.Background(), order, payment); !errors.Is(err, repository.ErrProductInactive) {
+		t.Fatalf("expected %v, got %v", repository.ErrProductInactive, err)
+	}
+	assertUnchanged()
+}
+
+func testOrderPlaceOnlyOnce(t *testing.T, repos OrderRepositories) {
+	mustCreateProduct(t, repos.Products, entity.NewProduct("latte", "Iced Latte", "", "drinks", 100, 5, true))
+	mustPost(t, repos.Ledger, entity.NewEarnPosting("seed", "alice", 500, ""))
+
+	order, payment := newOrder("o1", "alice", 1)
+	if err := repos.Orders.Place(context.Background(), order, payment); err != nil {
+		t.Fatalf("Place: unexpected error: %v", err)
+	}
+
+	// Same order with a new payment, and a new order reusing the payment
+	again, _ := newOrder("o1", "alice", 1)
+	if err := repos.Orders.Place(context.Background(), again, entity.NewPointsPayment("pay-other", again)); !errors.Is(err, repository.ErrOrderExists) {
+		t.Fatalf("Place same order: expected %v, got %v", repository.ErrOrderExists, err)
+	}
+	other, _ := newOrder("o2", "alice", 1)
+	reused := entity.NewPointsPayment(payment.ID, other)
+	if err := repos.Orders.Place(context.Background(), other, reused); !errors.Is(err, repository.ErrPostingExists) {
+		t.Fatalf("Place reusing payment: expected %v, got %v", repository.ErrPostingExists, err)
+	}
+
+	assertStock(t, repos.Products, "latte", 4)
+	assertBalance(t, repos.Ledger, "alice", 400)
+}
+
+func testOrderConcurrentPlaces(t *testing.T, repos OrderRepositories) {
+	mustCreateProduct(t, repos.Products, entity.NewProduct("latte", "Iced Latte", "", "drinks", 100, 3, true))
+
+	const buyers = 10
+	for i := 0; i < buyers; i++ {
+		mustPost(t, repos.Ledger, entity.NewEarnPosting(fmt.Sprintf("seed-%d", i), fmt.Sprintf("buyer-%d", i), 100, ""))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			order, payment := newOrder(fmt.Sprintf("o%d", i), fmt.Sprintf("buyer-%d", i), 1)
+			if err := repos.Orders.Place(context.Background(), order, payment); err != nil && !errors.Is(err, repository.ErrOutOfStock) {
+				t.Errorf("Place(%s): unexpected error: %v", order.ID, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assertStock(t, repos.Products, "latte", 0)
+	assertBalance(t, repos.Ledger, entity.AccountRedemptions, 300)
+}
+
+// mustPlace places a paid order for quantity lattes, seeding the member's
+// balance and the latte stock
+func mustPlace(t *testing.T, repos OrderRepositories, id string, quantity int) *entity.Order {
+	t.Helper()
+	if _, err := repos.Products.GetByID(context.Background(), "latte"); errors.Is(err, repository.ErrProductNotFound) {
+		mustCreateProduct(t, repos.Products, entity.NewProduct("latte", "Iced Latte", "", "drinks", 100, 5, true))
+	}
+	mustPost(t, repos.Ledger, entity.NewEarnPosting("seed-"+id, "alice", 100*quantity, ""))
+
+	order, payment := newOrder(id, "alice", quantity)
+	if err := repos.Orders.Place(context.Background(), order, payment); err != nil {
+		t.Fatalf("Place(%s): unexpected error: %v", id, err)
+	}
+	return order
+}
+
+// transition builds the transition of order from its current status
+func transition(order *entity.Order, to entity.OrderStatus, refund, restock bool) repository.OrderTransition {
+	return repository.OrderTransition{
+		OrderID:       order.ID,
+		Event:         order.Clone().Apply(to, "staff-1", "reason "+string(to)),
+		RefundPayment: refund,
+		Restock:       restock,
+	}
+}
+
+func testOrderTransition(t *testing.T, repos OrderRepositories) {
+	order := mustPlace(t, repos, "o1", 2)
+
+	fulfill := transition(order, entity.OrderFulfilled, false, false)
+	got, err := repos.Orders.Transition(context.Background(), fulfill)
+	if err != nil {
+		t.Fatalf("Transition: unexpected error: %v", err)
+	}
+	if got.Status != entity.OrderFulfilled || len(got.Events) != 3 || got.Events[2] != fulfill.Event ||
+		!got.UpdatedAt.Equal(fulfill.Event.CreatedAt) {
+		t.Fatalf("unexpected order after transition: %+v", got)
+	}
+
+	stored, err := repos.Orders.GetByID(context.Background(), "o1")
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if stored.Status != entity.OrderFulfilled || len(stored.Events) != 3 || stored.Events[2].Actor != "staff-1" ||
+		stored.Events[2].Reason != "reason fulfilled" || !stored.Events[2].CreatedAt.Equal(fulfill.Event.CreatedAt) {
+		t.Fatalf("unexpected stored order: %+v", stored)
+	}
+
+	// Nothing else changes without side effects
+	assertStock(t, repos.Products, "latte", 3)
+	assertBalance(t, repos.Ledger, "alice", 0)
+}
+
+func testOrderTransitionRefunds(t *testing.T, repos OrderRepositories) {
+	order := mustPlace(t, repos, "o1", 2)
+
+	if _, err := repos.Orders.Transition(context.Background(), transition(order, entity.OrderVoided, true, true)); err != nil {
+		t.Fatalf("Transition: unexpected error: %v", err)
+	}
+
+	assertStock(t, repos.Products, "latte", 5)
+	assertBalance(t, repos.Ledger, "alice", 200)
+	assertBalance(t, repos.Ledger, entity.AccountRedemptions, 0)
+
+	payment, err := repos.Orders.GetPayment(context.Background(), "o1")
+	if err != nil {
+		t.Fatalf("GetPayment: unexpected error: %v", err)
+	}
+	if payment.Status != entity.PaymentRefunded {
+		t.Fatalf("payment status = %s, want %s", payment.Status, entity.PaymentRefunded)
+	}
+
+	reversal, err := repos.Ledger.GetPosting(context.Background(), "reversal-"+payment.ID)
+	if err != nil {
+		t.Fatalf("GetPosting: unexpected error: %v", err)
+	}
+	if entry := reversal.EntryFor("alice"); entry == nil || entry.Type != entity.EntryReversal || entry.Amount != 200 || entry.OrderID != "o1" {
+		t.Fatalf("unexpected reversal: %+v", reversal)
+	}
+}
+
+func testOrderTransitionRejectsInvalid(t *testing.T, repos OrderRepositories) {
+	order := mustPlace(t, repos, "o1", 1)
+
+	stale := transition(order, entity.OrderFulfilled, false, false)
+	stale.Event.From = entity.OrderCreated
+	noActor := transition(order, entity.OrderFulfilled, false, false)
+	noActor.Event.Actor = ""
+	missing := transition(order, entity.OrderFulfilled, false, false)
+	missing.OrderID = "missing"
+
+	tests := []struct {
+		name       string
+		transition repository.OrderTransition
+		want       error
+	}{
+		{"stale status", stale, repository.ErrOrderStatusChanged},
+		{"no actor", noActor, repository.ErrInvalidOrderTransition},
+		{"same status", transition(order, entity.OrderPaid, false, false), repository.ErrInvalidOrderTransition},
+		{"missing order", missing, repository.ErrOrderNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repos.Orders.Transition(context.Background(), tt.transition); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if got, _ := repos.Orders.GetByID(context.Background(), "o1"); got.Status != entity.OrderPaid || len(got.Events) != 2 {
+		t.Fatalf("rejected transitions changed the order: %+v", got)
+	}
+}
+
+func testOrderConcurrentTransitions(t *testing.T, repos OrderRepositories) {
+	order := mustPlace(t, repos, "o1", 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repos.Orders.Transition(context.Background(), transition(order, entity.OrderVoided, true, true))
+			if err != nil && !errors.Is(err, repository.ErrOrderStatusChanged) {
+				t.Errorf("Transition: unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The points and stock come back exactly once
+	assertStock(t, repos.Products, "latte", 5)
+	assertBalance(t, repos.Ledger, "alice", 200)
+	if got, _ := repos.Orders.GetByID(context.Background(), "o1"); len(got.Events) != 3 {
+		t.Fatalf("expected a single void event, got %+v", got.Events)
+	}
+}
+
+func testOrderHonoursCancelledContext(t *testing.T, repos OrderRepositories) {
+	order := mustPlace(t, repos, "o1", 1)
+	mustPost(t, repos.Ledger, entity.NewEarnPosting("seed-o2", "alice", 100, ""))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	next, payment := newOrder("o2", "alice", 1)
+	assertCancelled(t, []cancelledCall{
+		{"Place", repos.Orders.Place(ctx, next, payment, newOutboxMessage(t, "receipt-o2", 0))},
+		{"Transition", errOf(repos.Orders.Transition(ctx, transition(order, entity.OrderVoided, true, true)))},
+		{"GetByID", errOf(repos.Orders.GetByID(ctx, "o1"))},
+		{"GetPayment", errOf(repos.Orders.GetPayment(ctx, "o1"))},
+	})
+
+	// None of the writes took effect
+	if got, err := repos.Orders.GetByID(context.Background(), "o1"); err != nil || got.Status != entity.OrderPaid {
+		t.Fatalf("GetByID(o1) = %+v, %v", got, err)
+	}
+	if _, err := repos.Orders.GetByID(context.Background(), "o2"); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Fatalf("GetByID(o2): expected %v, got %v", repository.ErrOrderNotFound, err)
+	}
+	if _, err := repos.Outbox.GetByID(context.Background(), "receipt-o2"); !errors.Is(err, repository.ErrOutboxMessageNotFound) {
+		t.Fatalf("GetByID(receipt-o2): expected %v, got %v", repository.ErrOutboxMessageNotFound, err)
+	}
+	assertStock(t, repos.Products, "latte", 4)
+	assertBalance(t, repos.Ledger, "alice", 100)
+}
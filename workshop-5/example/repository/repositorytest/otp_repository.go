@@ -0,0 +1,221 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// OTPFactory returns a new, empty OTP challenge repository for a single
+// test
+type OTPFactory func(t *testing.T) repository.OTPRepository
+
+// RunOTPRepositoryTests runs the conformance suite against the OTP
+// challenge repositories returned by newRepo
+func RunOTPRepositoryTests(t *testing.T, newRepo OTPFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.OTPRepository)
+	}{
+		{"CreateAndGetByID", testOTPCreateAndGetByID},
+		{"CreateRejectsInvalid", testOTPCreateRejectsInvalid},
+		{"AttemptCountsUntilClosed", testOTPAttempt},
+		{"ConsumeOnce", testOTPConsume},
+		{"DeleteExpired", testOTPDeleteExpired},
+		{"ConcurrentAttemptsStopAtMax", testOTPConcurrentAttempts},
+		{"HonoursCancelledContext", testOTPHonoursCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+func mustCreateOTPChallenge(t *testing.T, repo repository.OTPRepository, id string, ttl time.Duration) *entity.OTPChallenge {
+	t.Helper()
+	challenge := entity.NewOTPChallenge(id, "alice", entity.OTPChannelPhone, "+66812345678", "hash-"+id, ttl)
+	if err := repo.Create(context.Background(), challenge); err != nil {
+		t.Fatalf("Create(%s): unexpected error: %v", id, err)
+	}
+	return challenge
+}
+
+func testOTPCreateAndGetByID(t *testing.T, repo repository.OTPRepository) {
+	want := mustCreateOTPChallenge(t, repo, "c1", time.Minute)
+
+	got, err := repo.GetByID(context.Background(), "c1")
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if got.ID != want.ID || got.UserID != "alice" || got.Channel != entity.OTPChannelPhone ||
+		got.Destination != want.Destination || got.CodeHash != want.CodeHash || got.Attempts != 0 ||
+		got.ConsumedAt != nil || !got.ExpiresAt.Equal(want.ExpiresAt) || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("OTP challenge mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+
+	duplicate := entity.NewOTPChallenge("c1", "bob", entity.OTPChannelEmail, "bob@example.com", "hash", time.Minute)
+	if err := repo.Create(context.Background(), duplicate); !errors.Is(err, repository.ErrOTPChallengeExists) {
+		t.Fatalf("Create duplicate: expected %v, got %v", repository.ErrOTPChallengeExists, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "missing"); !errors.Is(err, repository.ErrOTPChallengeNotFound) {
+		t.Fatalf("GetByID missing: expected %v, got %v", repository.ErrOTPChallengeNotFound, err)
+	}
+}
+
+func testOTPCreateRejectsInvalid(t *testing.T, repo repository.OTPRepository) {
+	consumed := entity.NewOTPChallenge("consumed", "alice", entity.OTPChannelPhone, "+66812345678", "hash", time.Minute)
+	consumed.ConsumedAt = &consumed.CreatedAt
+
+	tests := []struct {
+		name      string
+		challenge *entity.OTPChallenge
+		want      error
+	}{
+		{"nil", nil, repository.ErrNilOTPChallenge},
+		{"empty ID", entity.NewOTPChallenge("", "alice", entity.OTPChannelPhone, "+66812345678", "hash", time.Minute), repository.ErrInvalidOTPChallenge},
+		{"no user", entity.NewOTPChallenge("c1", "", entity.OTPChannelPhone, "+66812345678", "hash", time.Minute), repository.ErrInvalidOTPChallenge},
+		{"unknown channel", entity.NewOTPChallenge("c2", "alice", "fax", "+66812345678", "hash", time.Minute), repository.ErrInvalidOTPChallenge},
+		{"no code", entity.NewOTPChallenge("c3", "alice", entity.OTPChannelEmail, "alice@example.com", "", time.Minute), repository.ErrInvalidOTPChallenge},
+		{"consumed", consumed, repository.ErrInvalidOTPChallenge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Create(context.Background(), tt.challenge); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testOTPAttempt(t *testing.T, repo repository.OTPRepository) {
+	challenge := mustCreateOTPChallenge(t, repo, "c1", time.Minute)
+	now := challenge.CreatedAt
+
+	for want := 1; want <= 3; want++ {
+		got, err := repo.Attempt(context.Background(), "c1", now, 3)
+		if err != nil {
+			t.Fatalf("Attempt %d: unexpected error: %v", want, err)
+		}
+		if got.Attempts != want {
+			t.Fatalf("Attempt %d: attempts = %d", want, got.Attempts)
+		}
+	}
+	if _, err := repo.Attempt(context.Background(), "c1", now, 3); !errors.Is(err, repository.ErrOTPChallengeClosed) {
+		t.Fatalf("Attempt past the limit: expected %v, got %v", repository.ErrOTPChallengeClosed, err)
+	}
+	if got, _ := repo.GetByID(context.Background(), "c1"); got.Attempts != 3 {
+		t.Fatalf("refused attempt was counted: %d", got.Attempts)
+	}
+
+	expired := mustCreateOTPChallenge(t, repo, "c2", time.Minute)
+	if _, err := repo.Attempt(context.Background(), "c2", expired.ExpiresAt, 3); !errors.Is(err, repository.ErrOTPChallengeClosed) {
+		t.Fatalf("Attempt after expiry: expected %v, got %v", repository.ErrOTPChallengeClosed, err)
+	}
+	if _, err := repo.Attempt(context.Background(), "missing", now, 3); !errors.Is(err, repository.ErrOTPChallengeNotFound) {
+		t.Fatalf("Attempt missing: expected %v, got %v", repository.ErrOTPChallengeNotFound, err)
+	}
+}
+
+func testOTPConsume(t *testing.T, repo repository.OTPRepository) {
+	challenge := mustCreateOTPChallenge(t, repo, "c1", time.Minute)
+	now := challenge.CreatedAt.Add(time.Second)
+
+	got, err := repo.Consume(context.Background(), "c1", now)
+	if err != nil {
+		t.Fatalf("Consume: unexpected error: %v", err)
+	}
+	if got.ConsumedAt == nil || !got.ConsumedAt.Equal(now) {
+		t.Fatalf("ConsumedAt = %v, want %v", got.ConsumedAt, now)
+	}
+	if _, err := repo.Consume(context.Background(), "c1", now); !errors.Is(err, repository.ErrOTPChallengeClosed) {
+		t.Fatalf("Consume twice: expected %v, got %v", repository.ErrOTPChallengeClosed, err)
+	}
+	if _, err := repo.Attempt(context.Background(), "c1", now, 3); !errors.Is(err, repository.ErrOTPChallengeClosed) {
+		t.Fatalf("Attempt after Consume: expected %v, got %v", repository.ErrOTPChallengeClosed, err)
+	}
+	if _, err := repo.Consume(context.Background(), "missing", now); !errors.Is(err, repository.ErrOTPChallengeNotFound) {
+		t.Fatalf("Consume missing: expected %v, got %v", repository.ErrOTPChallengeNotFound, err)
+	}
+}
+
+func testOTPDeleteExpired(t *testing.T, repo repository.OTPRepository) {
+	old := mustCreateOTPChallenge(t, repo, "old", time.Minute)
+	mustCreateOTPChallenge(t, repo, "new", time.Hour)
+
+	deleted, err := repo.DeleteExpired(context.Background(), old.ExpiresAt)
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteExpired = %d, %v, want 1", deleted, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "old"); !errors.Is(err, repository.ErrOTPChallengeNotFound) {
+		t.Fatalf("expired challenge kept: %v", err)
+	}
+	if _, err := repo.GetByID(context.Background(), "new"); err != nil {
+		t.Fatalf("unexpired challenge deleted: %v", err)
+	}
+}
+
+func testOTPConcurrentAttempts(t *testing.T, repo repository.OTPRepository) {
+	challenge := mustCreateOTPChallenge(t, repo, "c1", time.Minute)
+
+	const workers, maxAttempts = 20, 5
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	counted := 0
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Attempt(context.Background(), "c1", challenge.CreatedAt, maxAttempts)
+			switch {
+			case err == nil:
+				mu.Lock()
+				counted++
+				mu.Unlock()
+			case !errors.Is(err, repository.ErrOTPChallengeClosed):
+				errs <- fmt.Errorf("Attempt: %w", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if got, _ := repo.GetByID(context.Background(), "c1"); counted != maxAttempts || got.Attempts != maxAttempts {
+		t.Fatalf("%d attempts counted, %d stored, want %d", counted, got.Attempts, maxAttempts)
+	}
+}
+
+func testOTPHonoursCancelledContext(t *testing.T, repo repository.OTPRepository) {
+	mustCreateOTPChallenge(t, repo, "c1", -time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	now := time.Now()
+	assertCancelled(t, []cancelledCall{
+		{"Create", repo.Create(ctx, entity.NewOTPChallenge("c2", "alice", entity.OTPChannelPhone, "+66812345678", "hash-c2", time.Minute))},
+		{"GetByID", errOf(repo.GetByID(ctx, "c1"))},
+		{"Attempt", errOf(repo.Attempt(ctx, "c1", now, 5))},
+		{"Consume", errOf(repo.Consume(ctx, "c1", now))},
+		{"DeleteExpired", errOf(repo.DeleteExpired(ctx, now))},
+	})
+
+	// None of the writes took effect
+	if got, err := repo.GetByID(context.Background(), "c1"); err != nil || got.Attempts != 0 || got.ConsumedAt != nil {
+		t.Fatalf("GetByID(c1) = %+v, %v", got, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "c2"); !errors.Is(err, repository.ErrOTPChallengeNotFound) {
+		t.Fatalf("GetByID(c2): expected %v, got %v", repository.ErrOTPChallengeNotFound, err)
+	}
+}
@@ -0,0 +1,350 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// OutboxFactory returns a new, empty outbox repository for a single test
+type OutboxFactory func(t *testing.T) repository.OutboxRepository
+
+// RunOutboxRepositoryTests runs the conformance suite against the outbox
+// repositories returned by newRepo
+func RunOutboxRepositoryTests(t *testing.T, newRepo OutboxFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.OutboxRepository)
+	}{
+		{"EnqueueAndGetByID", testOutboxEnqueueAndGet},
+		{"EnqueueRejectsInvalid", testOutboxEnqueueRejectsInvalid},
+		{"ClaimDue", testOutboxClaimDue},
+		{"Claim", testOutboxClaim},
+		{"UpdateAndReplay", testOutboxUpdateAndReplay},
+		{"List", testOutboxList},
+		{"ConcurrentClaimsApplyOnce", testOutboxConcurrentClaims},
+		{"HonoursCancelledContext", testOutboxHonoursCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+// outboxEpoch is the creation time of the messages built by newOutboxMessage
+var outboxEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newOutboxMessage builds a pending message created and due at
+// outboxEpoch plus offset
+func newOutboxMessage(t *testing.T, id string, offset time.Duration) *entity.OutboxMessage {
+	t.Helper()
+	message, err := entity.NewOutboxMessage(id, "sms.receipt", map[string]string{"order_id": "o-" + id})
+	if err != nil {
+		t.Fatalf("NewOutboxMessage: unexpected error: %v", err)
+	}
+	at := outboxEpoch.Add(offset)
+	message.NextAttemptAt, message.CreatedAt, message.UpdatedAt = at, at, at
+	return message
+}
+
+func mustEnqueue(t *testing.T, repo repository.OutboxRepository, messages ...*entity.OutboxMessage) {
+	t.Helper()
+	if err := repo.Enqueue(context.Background(), messages...); err != nil {
+		t.Fatalf("Enqueue: unexpected error: %v", err)
+	}
+}
+
+func assertOutboxStatus(t *testing.T, repo repository.OutboxRepository, id string, want entity.OutboxStatus, attempts int) {
+	t.Helper()
+	got, err := repo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID(%s): unexpected error: %v", id, err)
+	}
+	if got.Status != want || got.Attempts != attempts {
+		t.Fatalf("message %s is %s after %d attempts, want %s after %d", id, got.Status, got.Attempts, want, attempts)
+	}
+}
+
+func testOutboxEnqueueAndGet(t *testing.T, repo repository.OutboxRepository) {
+	want := newOutboxMessage(t, "m1", 0)
+	mustEnqueue(t, repo, want, newOutboxMessage(t, "m2", 0))
+
+	got, err := repo.GetByID(context.Background(), "m1")
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if got.Topic != "sms.receipt" || string(got.Payload) != `{"order_id":"o-m1"}` || got.Status != entity.OutboxPending ||
+		got.Attempts != 0 || !got.NextAttemptAt.Equal(want.NextAttemptAt) || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("message mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+
+	// A batch with one existing message is rejected as a whole
+	if err := repo.Enqueue(context.Background(), newOutboxMessage(t, "m3", 0), newOutboxMessage(t, "m1", 0)); !errors.Is(err, repository.ErrOutboxMessageExists) {
+		t.Fatalf("Enqueue duplicate: expected %v, got %v", repository.ErrOutboxMessageExists, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "m3"); !errors.Is(err, repository.ErrOutboxMessageNotFound) {
+		t.Fatalf("expected the rejected batch to store nothing, got %v", err)
+	}
+}
+
+func testOutboxEnqueueRejectsInvalid(t *testing.T, repo repository.OutboxRepository) {
+	noTopic := newOutboxMessage(t, "m2", 0)
+	noTopic.Topic = ""
+	noPayload := newOutboxMessage(t, "m3", 0)
+	noPayload.Payload = nil
+	unknownStatus := newOutboxMessage(t, "m4", 0)
+	unknownStatus.Status = "queued"
+
+	tests := []struct {
+		name    string
+		message *entity.OutboxMessage
+		want    error
+	}{
+		{"nil", nil, repository.ErrNilOutboxMessage},
+		{"empty ID", newOutboxMessage(t, "", 0), repository.ErrInvalidOutboxMessage},
+		{"no topic", noTopic, repository.ErrInvalidOutboxMessage},
+		{"no payload", noPayload, repository.ErrInvalidOutboxMessage},
+		{"unknown status", unknownStatus, repository.ErrInvalidOutboxMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Enqueue(context.Background(), tt.message); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testOutboxClaimDue(t *testing.T, repo repository.OutboxRepository) {
+	delivered := newOutboxMessage(t, "delivered", 0)
+	delivered.MarkDelivered(outboxEpoch)
+	mustEnqueue(t, repo,
+		newOutboxMessage(t, "later", 2*time.Second),
+		newOutboxMessage(t, "first", 0),
+		newOutboxMessage(t, "second", time.Second),
+		newOutboxMessage(t, "future", time.Hour),
+		delivered,
+	)
+
+	now := outboxEpoch.Add(5 * time.Second)
+	claimed, err := repo.ClaimDue(context.Background(), now, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDue: unexpected error: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != "first" || claimed[1].ID != "second" {
+		t.Fatalf("expected the two earliest due messages, got %+v", claimed)
+	}
+	if claimed[0].Attempts != 1 || !claimed[0].NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("claim not counted or leased: %+v", claimed[0])
+	}
+	assertOutboxStatus(t, repo, "first", entity.OutboxPending, 1)
+
+	// Claimed messages are leased, so only the rest is due
+	claimed, err = repo.ClaimDue(context.Background(), now, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDue: unexpected error: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != "later" {
+		t.Fatalf("expected only the unclaimed due message, got %+v", claimed)
+	}
+
+	// Once the lease lapses the messages are due again
+	claimed, err = repo.ClaimDue(context.Background(), now.Add(time.Minute), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDue: unexpected error: %v", err)
+	}
+	if len(claimed) != 3 || claimed[0].ID != "first" || claimed[0].Attempts != 2 {
+		t.Fatalf("expected the lapsed claims to be due again, got %+v", claimed)
+	}
+}
+
+func testOutboxClaim(t *testing.T, repo repository.OutboxRepository) {
+	mustEnqueue(t, repo, newOutboxMessage(t, "m1", 0), newOutboxMessage(t, "future", time.Hour))
+
+	claimed, err := repo.Claim(context.Background(), "m1", outboxEpoch, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: unexpected error: %v", err)
+	}
+	if claimed.Attempts != 1 || !claimed.NextAttemptAt.Equal(outboxEpoch.Add(time.Minute)) {
+		t.Fatalf("claim not counted or leased: %+v", claimed)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want error
+	}{
+		{"already claimed", "m1", repository.ErrOutboxMessageNotDue},
+		{"not due", "future", repository.ErrOutboxMessageNotDue},
+		{"missing", "missing", repository.ErrOutboxMessageNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repo.Claim(context.Background(), tt.id, outboxEpoch, time.Minute); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testOutboxUpdateAndReplay(t *testing.T, repo repository.OutboxRepository) {
+	mustEnqueue(t, repo, newOutboxMessage(t, "m1", 0), newOutboxMessage(t, "m2", 0))
+
+	message, err := repo.Claim(context.Background(), "m1", outboxEpoch, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: unexpected error: %v", err)
+	}
+	message.MarkRetry("provider unreachable", outboxEpoch.Add(2*time.Second), outboxEpoch)
+	message.Topic = "ignored"
+	if err := repo.Update(context.Background(), message); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	got, _ := repo.GetByID(context.Background(), "m1")
+	if got.Status != entity.OutboxPending || got.Attempts != 1 || got.LastError != "provider unreachable" ||
+		!got.NextAttemptAt.Equal(outboxEpoch.Add(2*time.Second)) || got.Topic != "sms.receipt" {
+		t.Fatalf("unexpected message after retry: %+v", got)
+	}
+
+	message.MarkDead("provider unreachable", outboxEpoch.Add(time.Second))
+	if err := repo.Update(context.Background(), message); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if _, err := repo.ClaimDue(context.Background(), outboxEpoch.Add(time.Hour), 10, time.Minute); err != nil {
+		t.Fatalf("ClaimDue: unexpected error: %v", err)
+	}
+	assertOutboxStatus(t, repo, "m1", entity.OutboxDead, 1)
+
+	replayAt := outboxEpoch.Add(2 * time.Hour)
+	replayed, err := repo.Replay(context.Background(), "m1", replayAt)
+	if err != nil {
+		t.Fatalf("Replay: unexpected error: %v", err)
+	}
+	if replayed.Status != entity.OutboxPending || replayed.Attempts != 0 || !replayed.NextAttemptAt.Equal(replayAt) ||
+		replayed.LastError != "provider unreachable" {
+		t.Fatalf("unexpected replayed message: %+v", replayed)
+	}
+	if _, err := repo.Claim(context.Background(), "m1", replayAt, time.Minute); err != nil {
+		t.Fatalf("Claim replayed: unexpected error: %v", err)
+	}
+
+	delivered, _ := repo.GetByID(context.Background(), "m2")
+	delivered.MarkDelivered(outboxEpoch)
+	if err := repo.Update(context.Background(), delivered); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if _, err := repo.Replay(context.Background(), "m2", replayAt); !errors.Is(err, repository.ErrOutboxMessageDelivered) {
+		t.Fatalf("Replay delivered: expected %v, got %v", repository.ErrOutboxMessageDelivered, err)
+	}
+	if _, err := repo.Replay(context.Background(), "missing", replayAt); !errors.Is(err, repository.ErrOutboxMessageNotFound) {
+		t.Fatalf("Replay missing: expected %v, got %v", repository.ErrOutboxMessageNotFound, err)
+	}
+	if err := repo.Update(context.Background(), newOutboxMessage(t, "missing", 0)); !errors.Is(err, repository.ErrOutboxMessageNotFound) {
+		t.Fatalf("Update missing: expected %v, got %v", repository.ErrOutboxMessageNotFound, err)
+	}
+}
+
+func testOutboxList(t *testing.T, repo repository.OutboxRepository) {
+	dead := newOutboxMessage(t, "dead", time.Second)
+	dead.MarkDead("no handler", outboxEpoch)
+	mustEnqueue(t, repo, newOutboxMessage(t, "newest", 2*time.Second), dead, newOutboxMessage(t, "oldest", 0))
+
+	ids := func(messages []*entity.OutboxMessage) []string {
+		ids := make([]string, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+		}
+		return ids
+	}
+	tests := []struct {
+		name   string
+		status entity.OutboxStatus
+		limit  int
+		want   []string
+	}{
+		{"any status", "", 10, []string{"oldest", "dead", "newest"}},
+		{"limited", "", 2, []string{"oldest", "dead"}},
+		{"pending", entity.OutboxPending, 10, []string{"oldest", "newest"}},
+		{"dead", entity.OutboxDead, 10, []string{"dead"}},
+		{"none", entity.OutboxDelivered, 10, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.List(context.Background(), tt.status, tt.limit)
+			if err != nil {
+				t.Fatalf("List: unexpected error: %v", err)
+			}
+			if gotIDs := ids(got); !slices.Equal(gotIDs, tt.want) {
+				t.Fatalf("List = %v, want %v", gotIDs, tt.want)
+			}
+		})
+	}
+}
+
+func testOutboxConcurrentClaims(t *testing.T, repo repository.OutboxRepository) {
+	for i := 0; i < 10; i++ {
+		mustEnqueue(t, repo, newOutboxMessage(t, string(rune('a'+i)), time.Duration(i)*time.Millisecond))
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claimedBy := make(map[string]int)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := repo.ClaimDue(context.Background(), outboxEpoch.Add(time.Second), 3, time.Minute)
+			if err != nil {
+				t.Errorf("ClaimDue: unexpected error: %v", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, message := range claimed {
+				claimedBy[message.ID]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimedBy) != 10 {
+		t.Fatalf("claimed %d distinct messages, want 10", len(claimedBy))
+	}
+	for id, claims := range claimedBy {
+		if claims != 1 {
+			t.Fatalf("message %s claimed %d times, want once", id, claims)
+		}
+	}
+}
+
+func testOutboxHonoursCancelledContext(t *testing.T, repo repository.OutboxRepository) {
+	message := newOutboxMessage(t, "m1", 0)
+	mustEnqueue(t, repo, message)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	now := time.Now()
+	assertCancelled(t, []cancelledCall{
+		{"Enqueue", repo.Enqueue(ctx, newOutboxMessage(t, "m2", 0))},
+		{"ClaimDue", errOf(repo.ClaimDue(ctx, now, 10, time.Minute))},
+		{"Claim", errOf(repo.Claim(ctx, "m1", now, time.Minute))},
+		{"Update", repo.Update(ctx, message)},
+		{"Replay", errOf(repo.Replay(ctx, "m1", now))},
+		{"GetByID", errOf(repo.GetByID(ctx, "m1"))},
+		{"List", errOf(repo.List(ctx, "", 10))},
+	})
+
+	// None of the writes took effect
+	assertOutboxStatus(t, repo, "m1", entity.OutboxPending, 0)
+	if _, err := repo.GetByID(context.Background(), "m2"); !errors.Is(err, repository.ErrOutboxMessageNotFound) {
+		t.Fatalf("GetByID(m2): expected %v, got %v", repository.ErrOutboxMessageNotFound, err)
+	}
+}
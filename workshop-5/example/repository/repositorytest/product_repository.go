@@ -0,0 +1,290 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// ProductFactory returns a new, empty product repository for a single test
+type ProductFactory func(t *testing.T) repository.ProductRepository
+
+// RunProductRepositoryTests runs the conformance suite against the product
+// repositories returned by newRepo
+func RunProductRepositoryTests(t *testing.T, newRepo ProductFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.ProductRepository)
+	}{
+		{"CreateAndGetByID", testProductCreateAndGetByID},
+		{"CreateRejectsInvalid", testProductCreateRejectsInvalid},
+		{"Update", testProductUpdate},
+		{"Delete", testProductDelete},
+		{"QueryFilters", testProductQueryFilters},
+		{"QueryPagination", testProductQueryPagination},
+		{"ReserveAndRestock", testProductReserveAndRestock},
+		{"HonoursCancelledContext", testProductHonoursCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+func mustCreateProduct(t *testing.T, repo repository.ProductRepository, product *entity.Product) *entity.Product {
+	t.Helper()
+	if err := repo.Create(context.Background(), product); err != nil {
+		t.Fatalf("Create(%s): unexpected error: %v", product.ID, err)
+	}
+	return product
+}
+
+func productIDs(products []*entity.Product) []string {
+	ids := make([]string, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+	return ids
+}
+
+func testProductCreateAndGetByID(t *testing.T, repo repository.ProductRepository) {
+	want := mustCreateProduct(t, repo, entity.NewProduct("p1", "Iced Latte", "Oat milk", "drinks", 120, 40, true))
+
+	got, err := repo.GetByID(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if got.ID != want.ID || got.Name != want.Name || got.Description != want.Description || got.Category != want.Category ||
+		got.PricePoints != 120 || !got.Active || got.Stock != 40 || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("product mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+
+	// The stored product is a copy
+	got.Stock = 0
+	if again, _ := repo.GetByID(context.Background(), "p1"); again.Stock != 40 {
+		t.Fatal("mutating a returned product changed the stored one")
+	}
+
+	if err := repo.Create(context.Background(), entity.NewProduct("p1", "Other", "", "drinks", 1, 0, true)); !errors.Is(err, repository.ErrProductExists) {
+		t.Fatalf("Create duplicate: expected %v, got %v", repository.ErrProductExists, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "missing"); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Fatalf("GetByID missing: expected %v, got %v", repository.ErrProductNotFound, err)
+	}
+}
+
+func testProductCreateRejectsInvalid(t *testing.T, repo repository.ProductRepository) {
+	tests := []struct {
+		name    string
+		product *entity.Product
+		want    error
+	}{
+		{"nil", nil, repository.ErrNilProduct},
+		{"empty ID", entity.NewProduct("", "Latte", "", "drinks", 1, 0, true), repository.ErrInvalidProduct},
+		{"no name", entity.NewProduct("p1", "", "", "drinks", 1, 0, true), repository.ErrInvalidProduct},
+		{"no category", entity.NewProduct("p2", "Latte", "", "", 1, 0, true), repository.ErrInvalidProduct},
+		{"zero price", entity.NewProduct("p3", "Latte", "", "drinks", 0, 0, true), repository.ErrInvalidProduct},
+		{"negative stock", entity.NewProduct("p4", "Latte", "", "drinks", 1, -1, true), repository.ErrInvalidProduct},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Create(context.Background(), tt.product); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testProductUpdate(t *testing.T, repo repository.ProductRepository) {
+	created := mustCreateProduct(t, repo, entity.NewProduct("p1", "Latte", "", "drinks", 120, 40, true))
+
+	update := entity.NewProduct("p1", "Iced Latte", "Oat milk", "cold drinks", 150, 0, false)
+	if err := repo.Update(context.Background(), update); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if !update.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("Update should keep the creation time: got %v, want %v", update.CreatedAt, created.CreatedAt)
+	}
+
+	got, err := repo.GetByID(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if got.Name != "Iced Latte" || got.Description != "Oat milk" || got.Category != "cold drinks" ||
+		got.PricePoints != 150 || got.Active || got.Stock != 0 || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("unexpected product after update: %+v", got)
+	}
+
+	if err := repo.Update(context.Background(), entity.NewProduct("missing", "Latte", "", "drinks", 1, 0, true)); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Fatalf("Update missing: expected %v, got %v", repository.ErrProductNotFound, err)
+	}
+	if err := repo.Update(context.Background(), entity.NewProduct("p1", "Latte", "", "drinks", 0, 0, true)); !errors.Is(err, repository.ErrInvalidProduct) {
+		t.Fatalf("Update invalid: expected %v, got %v", repository.ErrInvalidProduct, err)
+	}
+}
+
+func testProductDelete(t *testing.T, repo repository.ProductRepository) {
+	mustCreateProduct(t, repo, entity.NewProduct("p1", "Latte", "", "drinks", 120, 40, true))
+
+	if err := repo.Delete(context.Background(), "p1"); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+	if _, err := repo.GetByID(context.Background(), "p1"); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Fatalf("GetByID after delete: expected %v, got %v", repository.ErrProductNotFound, err)
+	}
+	if err := repo.Delete(context.Background(), "p1"); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Fatalf("Delete twice: expected %v, got %v", repository.ErrProductNotFound, err)
+	}
+}
+
+func testProductQueryFilters(t *testing.T, repo repository.ProductRepository) {
+	mustCreateProduct(t, repo, entity.NewProduct("latte", "Iced Latte", "Oat milk", "drinks", 120, 40, true))
+	mustCreateProduct(t, repo, entity.NewProduct("mocha", "Mocha", "Dark chocolate and espresso", "drinks", 130, 10, true))
+	mustCreateProduct(t, repo, entity.NewProduct("cake", "Chocolate Cake", "", "bakery", 90, 5, true))
+	mustCreateProduct(t, repo, entity.NewProduct("old", "Old Latte", "", "drinks", 100, 0, false))
+	mustCreateProduct(t, repo, entity.NewProduct("pct", "100% Juice", "", "drinks", 80, 3, true))
+
+	tests := []struct {
+		name  string
+		query repository.ProductQuery
+		want  []string
+	}{
+		{"all, by name", repository.ProductQuery{}, []string{"pct", "cake", "latte", "mocha", "old"}},
+		{"active only", repository.ProductQuery{ActiveOnly: true}, []string{"pct", "cake", "latte", "mocha"}},
+		{"category", repository.ProductQuery{Category: "bakery"}, []string{"cake"}},
+		{"search name, any case", repository.ProductQuery{Search: "LATTE"}, []string{"latte", "old"}},
+		{"search description", repository.ProductQuery{Search: "chocolate"}, []string{"cake", "mocha"}},
+		{"search escapes wildcards", repository.ProductQuery{Search: "0%"}, []string{"pct"}},
+		{"combined", repository.ProductQuery{Category: "drinks", Search: "latte", ActiveOnly: true}, []string{"latte"}},
+		{"no match", repository.ProductQuery{Category: "toys"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Limit = 10
+			page, err := repo.Query(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("Query: unexpected error: %v", err)
+			}
+			if got := productIDs(page.Products); fmt.Sprint(got) != fmt.Sprint(tt.want) || page.Next != nil {
+				t.Fatalf("Query = %v (next %v), want %v", got, page.Next, tt.want)
+			}
+		})
+	}
+
+	if _, err := repo.Query(context.Background(), repository.ProductQuery{}); err == nil {
+		t.Fatal("Query without a limit: expected an error")
+	}
+}
+
+func testProductQueryPagination(t *testing.T, repo repository.ProductRepository) {
+	// Two products share a name so the ID breaks the tie
+	for _, p := range []struct{ id, name string }{{"b2", "Bagel"}, {"b1", "Bagel"}, {"a", "Americano"}, {"c", "Croissant"}, {"d", "Donut"}} {
+		mustCreateProduct(t, repo, entity.NewProduct(p.id, p.name, "", "bakery", 50, 1, true))
+	}
+
+	var got []string
+	query := repository.ProductQuery{Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		page, err := repo.Query(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Query: unexpected error: %v", err)
+		}
+		got = append(got, productIDs(page.Products)...)
+		if page.Next == nil {
+			break
+		}
+		query.After = page.Next
+	}
+
+	if want := []string{"a", "b1", "b2", "c", "d"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("pages = %v, want %v", got, want)
+	}
+}
+
+func testProductReserveAndRestock(t *testing.T, repo repository.ProductRepository) {
+	mustCreateProduct(t, repo, entity.NewProduct("latte", "Iced Latte", "", "drinks", 120, 5, true))
+	mustCreateProduct(t, repo, entity.NewProduct("mocha", "Mocha", "", "drinks", 130, 2, true))
+	mustCreateProduct(t, repo, entity.NewProduct("retired", "Retired", "", "drinks", 90, 10, false))
+
+	assertStock := func(id string, want int) {
+		t.Helper()
+		product, err := repo.GetByID(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetByID(%s): unexpected error: %v", id, err)
+		}
+		if product.Stock != want {
+			t.Fatalf("stock of %s = %d, want %d", id, product.Stock, want)
+		}
+	}
+
+	if err := repo.Reserve(context.Background(), map[string]int{"latte": 3, "mocha": 2}); err != nil {
+		t.Fatalf("Reserve: unexpected error: %v", err)
+	}
+	assertStock("latte", 2)
+	assertStock("mocha", 0)
+
+	// A failing reservation leaves every product untouched
+	failures := []struct {
+		name       string
+		quantities map[string]int
+		want       error
+	}{
+		{"out of stock", map[string]int{"latte": 1, "mocha": 1}, repository.ErrOutOfStock},
+		{"inactive", map[string]int{"latte": 1, "retired": 1}, repository.ErrProductInactive},
+		{"missing", map[string]int{"latte": 1, "missing": 1}, repository.ErrProductNotFound},
+	}
+	for _, tt := range failures {
+		if err := repo.Reserve(context.Background(), tt.quantities); !errors.Is(err, tt.want) {
+			t.Fatalf("Reserve %s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+	assertStock("latte", 2)
+	assertStock("retired", 10)
+
+	if err := repo.Reserve(context.Background(), map[string]int{"latte": 0}); !errors.Is(err, apperror.ErrValidation) {
+		t.Fatalf("Reserve zero: expected a validation error, got %v", err)
+	}
+
+	if err := repo.Restock(context.Background(), map[string]int{"mocha": 2, "deleted": 1}); err != nil {
+		t.Fatalf("Restock: unexpected error: %v", err)
+	}
+	assertStock("mocha", 2)
+}
+
+func testProductHonoursCancelledContext(t *testing.T, repo repository.ProductRepository) {
+	product := mustCreateProduct(t, repo, entity.NewProduct("latte", "Iced Latte", "", "drinks", 100, 5, true))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	updated := *product
+	updated.PricePoints = 120
+	assertCancelled(t, []cancelledCall{
+		{"Create", repo.Create(ctx, entity.NewProduct("mocha", "Mocha", "", "drinks", 90, 5, true))},
+		{"GetByID", errOf(repo.GetByID(ctx, "latte"))},
+		{"Update", repo.Update(ctx, &updated)},
+		{"Query", errOf(repo.Query(ctx, repository.ProductQuery{Limit: 10}))},
+		{"Reserve", repo.Reserve(ctx, map[string]int{"latte": 1})},
+		{"Restock", repo.Restock(ctx, map[string]int{"latte": 1})},
+		{"Delete", repo.Delete(ctx, "latte")},
+	})
+
+	// None of the writes took effect
+	got, err := repo.GetByID(context.Background(), "latte")
+	if err != nil || got.PricePoints != 100 || got.Stock != 5 {
+		t.Fatalf("GetByID(latte) = %+v, %v", got, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "mocha"); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Fatalf("GetByID(mocha): expected %v, got %v", repository.ErrProductNotFound, err)
+	}
+}
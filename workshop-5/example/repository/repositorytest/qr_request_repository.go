@@ -0,0 +1,193 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// QRRequestFactory returns a new, empty QR request repository for a single
+// test
+type QRRequestFactory func(t *testing.T) repository.QRRequestRepository
+
+// RunQRRequestRepositoryTests runs the conformance suite against the QR
+// request repositories returned by newRepo
+func RunQRRequestRepositoryTests(t *testing.T, newRepo QRRequestFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.QRRequestRepository)
+	}{
+		{"CreateAndGetByID", testQRRequestCreateAndGetByID},
+		{"CreateRejectsInvalid", testQRRequestCreateRejectsInvalid},
+		{"MarkPaid", testQRRequestMarkPaid},
+		{"MarkExpired", testQRRequestMarkExpired},
+		{"ListDue", testQRRequestListDue},
+		{"HonoursCancelledContext", testQRRequestHonoursCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+func mustCreateQRRequest(t *testing.T, repo repository.QRRequestRepository, id string, ttl time.Duration) *entity.QRRequest {
+	t.Helper()
+	request := entity.NewQRRequest(id, "alice", 250, "memo "+id, ttl)
+	if err := repo.Create(context.Background(), request); err != nil {
+		t.Fatalf("Create(%s): unexpected error: %v", id, err)
+	}
+	return request
+}
+
+func testQRRequestCreateAndGetByID(t *testing.T, repo repository.QRRequestRepository) {
+	want := mustCreateQRRequest(t, repo, "q1", time.Minute)
+
+	got, err := repo.GetByID(context.Background(), "q1")
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if got.ID != want.ID || got.RecipientID != "alice" || got.Amount != 250 || got.Memo != want.Memo ||
+		got.Status != entity.QRRequestPending || !got.ExpiresAt.Equal(want.ExpiresAt) || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("QR request mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+
+	if err := repo.Create(context.Background(), entity.NewQRRequest("q1", "bob", 1, "", time.Minute)); !errors.Is(err, repository.ErrQRRequestExists) {
+		t.Fatalf("Create duplicate: expected %v, got %v", repository.ErrQRRequestExists, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "missing"); !errors.Is(err, repository.ErrQRRequestNotFound) {
+		t.Fatalf("GetByID missing: expected %v, got %v", repository.ErrQRRequestNotFound, err)
+	}
+}
+
+func testQRRequestCreateRejectsInvalid(t *testing.T, repo repository.QRRequestRepository) {
+	paid := entity.NewQRRequest("paid", "alice", 1, "", time.Minute)
+	paid.Status = entity.QRRequestPaid
+
+	tests := []struct {
+		name    string
+		request *entity.QRRequest
+		want    error
+	}{
+		{"nil", nil, repository.ErrNilQRRequest},
+		{"empty ID", entity.NewQRRequest("", "alice", 1, "", time.Minute), repository.ErrInvalidQRRequest},
+		{"no recipient", entity.NewQRRequest("q1", "", 1, "", time.Minute), repository.ErrInvalidQRRequest},
+		{"zero amount", entity.NewQRRequest("q2", "alice", 0, "", time.Minute), repository.ErrInvalidQRRequest},
+		{"no expiry", &entity.QRRequest{ID: "q3", RecipientID: "alice", Amount: 1, Status: entity.QRRequestPending}, repository.ErrInvalidQRRequest},
+		{"not pending", paid, repository.ErrInvalidQRRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Create(context.Background(), tt.request); !errors.Is(err, tt.want) {
+				t.Fatalf("Create: expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testQRRequestMarkPaid(t *testing.T, repo repository.QRRequestRepository) {
+	mustCreateQRRequest(t, repo, "q1", time.Minute)
+
+	got, err := repo.MarkPaid(context.Background(), "q1", "bob", "t1")
+	if err != nil {
+		t.Fatalf("MarkPaid: unexpected error: %v", err)
+	}
+	if got.Status != entity.QRRequestPaid || got.PayerID != "bob" || got.TransferID != "t1" {
+		t.Fatalf("MarkPaid returned %+v", got)
+	}
+
+	stored, err := repo.GetByID(context.Background(), "q1")
+	if err != nil || stored.Status != entity.QRRequestPaid || stored.PayerID != "bob" || stored.TransferID != "t1" {
+		t.Fatalf("GetByID after MarkPaid = %+v, %v", stored, err)
+	}
+
+	if _, err := repo.MarkPaid(context.Background(), "q1", "carol", "t2"); !errors.Is(err, repository.ErrQRRequestNotPending) {
+		t.Fatalf("second MarkPaid: expected %v, got %v", repository.ErrQRRequestNotPending, err)
+	}
+	if _, err := repo.MarkExpired(context.Background(), "q1"); !errors.Is(err, repository.ErrQRRequestNotPending) {
+		t.Fatalf("MarkExpired after MarkPaid: expected %v, got %v", repository.ErrQRRequestNotPending, err)
+	}
+	if _, err := repo.MarkPaid(context.Background(), "missing", "bob", "t3"); !errors.Is(err, repository.ErrQRRequestNotFound) {
+		t.Fatalf("MarkPaid missing: expected %v, got %v", repository.ErrQRRequestNotFound, err)
+	}
+}
+
+func testQRRequestMarkExpired(t *testing.T, repo repository.QRRequestRepository) {
+	mustCreateQRRequest(t, repo, "q1", time.Minute)
+
+	got, err := repo.MarkExpired(context.Background(), "q1")
+	if err != nil || got.Status != entity.QRRequestExpired {
+		t.Fatalf("MarkExpired = %+v, %v", got, err)
+	}
+	if _, err := repo.MarkPaid(context.Background(), "q1", "bob", "t1"); !errors.Is(err, repository.ErrQRRequestNotPending) {
+		t.Fatalf("MarkPaid after MarkExpired: expected %v, got %v", repository.ErrQRRequestNotPending, err)
+	}
+	if _, err := repo.MarkExpired(context.Background(), "missing"); !errors.Is(err, repository.ErrQRRequestNotFound) {
+		t.Fatalf("MarkExpired missing: expected %v, got %v", repository.ErrQRRequestNotFound, err)
+	}
+}
+
+func testQRRequestListDue(t *testing.T, repo repository.QRRequestRepository) {
+	mustCreateQRRequest(t, repo, "later", 2*time.Minute)
+	mustCreateQRRequest(t, repo, "sooner", time.Minute)
+	mustCreateQRRequest(t, repo, "paid", time.Minute)
+	mustCreateQRRequest(t, repo, "fresh", time.Hour)
+	if _, err := repo.MarkPaid(context.Background(), "paid", "bob", "t1"); err != nil {
+		t.Fatalf("MarkPaid: unexpected error: %v", err)
+	}
+
+	now := time.Now().Add(5 * time.Minute)
+	due, err := repo.ListDue(context.Background(), now, 0)
+	if err != nil {
+		t.Fatalf("ListDue: unexpected error: %v", err)
+	}
+	if len(due) != 2 || due[0].ID != "sooner" || due[1].ID != "later" {
+		t.Fatalf("ListDue returned %v, want [sooner later]", qrRequestIDs(due))
+	}
+
+	due, err = repo.ListDue(context.Background(), now, 1)
+	if err != nil || len(due) != 1 || due[0].ID != "sooner" {
+		t.Fatalf("ListDue(limit 1) = %v, %v", qrRequestIDs(due), err)
+	}
+
+	due, err = repo.ListDue(context.Background(), time.Now(), 0)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("ListDue(now) = %v, %v; want none", qrRequestIDs(due), err)
+	}
+}
+
+func qrRequestIDs(requests []*entity.QRRequest) []string {
+	ids := make([]string, len(requests))
+	for i, request := range requests {
+		ids[i] = request.ID
+	}
+	return ids
+}
+
+func testQRRequestHonoursCancelledContext(t *testing.T, repo repository.QRRequestRepository) {
+	mustCreateQRRequest(t, repo, "q1", -time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assertCancelled(t, []cancelledCall{
+		{"Create", repo.Create(ctx, entity.NewQRRequest("q2", "alice", 1, "", time.Minute))},
+		{"GetByID", errOf(repo.GetByID(ctx, "q1"))},
+		{"MarkPaid", errOf(repo.MarkPaid(ctx, "q1", "bob", "t1"))},
+		{"MarkExpired", errOf(repo.MarkExpired(ctx, "q1"))},
+		{"ListDue", errOf(repo.ListDue(ctx, time.Now(), 10))},
+	})
+
+	// None of the writes took effect
+	if got, err := repo.GetByID(context.Background(), "q1"); err != nil || got.Status != entity.QRRequestPending {
+		t.Fatalf("GetByID(q1) = %+v, %v", got, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "q2"); !errors.Is(err, repository.ErrQRRequestNotFound) {
+		t.Fatalf("GetByID(q2): expected %v, got %v", repository.ErrQRRequestNotFound, err)
+	}
+}
@@ -0,0 +1,169 @@
+package repositorytest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// RateLimitFactory returns a new, empty rate limit repository for a
+// single test
+type RateLimitFactory func(t *testing.T) repository.RateLimitRepository
+
+// RunRateLimitRepositoryTests runs the conformance suite against the rate
+// limit repositories returned by newRepo
+func RunRateLimitRepositoryTests(t *testing.T, newRepo RateLimitFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.RateLimitRepository)
+	}{
+		{"TakeUntilEmpty", testRateLimitTakeUntilEmpty},
+		{"Refill", testRateLimitRefill},
+		{"BucketsAreSeparate", testRateLimitBucketsAreSeparate},
+		{"RejectsInvalid", testRateLimitRejectsInvalid},
+		{"DeleteExpired", testRateLimitDeleteExpired},
+		{"ConcurrentTakesNeverOverdraw", testRateLimitConcurrentTakes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+// threePerMinute refills a token every 20 seconds
+var threePerMinute = entity.RateLimit{Limit: 3, Period: time.Minute}
+
+func mustTake(t *testing.T, repo repository.RateLimitRepository, key string, now time.Time) entity.RateLimitDecision {
+	t.Helper()
+	decision, err := repo.Take(key, threePerMinute, now)
+	if err != nil {
+		t.Fatalf("Take(%s): unexpected error: %v", key, err)
+	}
+	return decision
+}
+
+func testRateLimitTakeUntilEmpty(t *testing.T, repo repository.RateLimitRepository) {
+	now := time.Now()
+
+	for want := 2; want >= 0; want-- {
+		decision := mustTake(t, repo, "alice", now)
+		if !decision.Allowed || decision.Limit != 3 || decision.Remaining != want || decision.RetryAfter != 0 {
+			t.Fatalf("take with %d left: %+v", want, decision)
+		}
+	}
+	if reset := mustTake(t, repo, "alice", now).Reset; reset != time.Minute {
+		t.Fatalf("Reset of an empty bucket = %v, want 1m", reset)
+	}
+
+	decision := mustTake(t, repo, "alice", now.Add(5*time.Second))
+	if decision.Allowed || decision.Remaining != 0 || decision.RetryAfter != 15*time.Second {
+		t.Fatalf("take from an empty bucket: %+v", decision)
+	}
+}
+
+func testRateLimitRefill(t *testing.T, repo repository.RateLimitRepository) {
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		mustTake(t, repo, "alice", now)
+	}
+
+	// One token comes back every 20 seconds
+	if decision := mustTake(t, repo, "alice", now.Add(20*time.Second)); !decision.Allowed || decision.Remaining != 0 {
+		t.Fatalf("take after one refill: %+v", decision)
+	}
+
+	// The bucket never holds more than its limit
+	decision := mustTake(t, repo, "alice", now.Add(time.Hour))
+	if !decision.Allowed || decision.Remaining != 2 || decision.Reset != 20*time.Second {
+		t.Fatalf("take after a long pause: %+v", decision)
+	}
+}
+
+func testRateLimitBucketsAreSeparate(t *testing.T, repo repository.RateLimitRepository) {
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		mustTake(t, repo, "alice", now)
+	}
+
+	if decision := mustTake(t, repo, "bob", now); !decision.Allowed || decision.Remaining != 2 {
+		t.Fatalf("another key shares the bucket: %+v", decision)
+	}
+}
+
+func testRateLimitRejectsInvalid(t *testing.T, repo repository.RateLimitRepository) {
+	tests := []struct {
+		name  string
+		key   string
+		limit entity.RateLimit
+	}{
+		{"empty key", "", threePerMinute},
+		{"no limit", "alice", entity.RateLimit{Period: time.Minute}},
+		{"no period", "alice", entity.RateLimit{Limit: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repo.Take(tt.key, tt.limit, time.Now()); !errors.Is(err, repository.ErrInvalidRateLimit) {
+				t.Fatalf("expected %v, got %v", repository.ErrInvalidRateLimit, err)
+			}
+		})
+	}
+}
+
+func testRateLimitDeleteExpired(t *testing.T, repo repository.RateLimitRepository) {
+	now := time.Now()
+	mustTake(t, repo, "alice", now)
+	for i := 0; i < 3; i++ {
+		mustTake(t, repo, "bob", now)
+	}
+
+	// Alice's bucket is full again after 20 seconds, Bob's after a minute
+	deleted, err := repo.DeleteExpired(now.Add(20 * time.Second))
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteExpired = %d, %v, want 1", deleted, err)
+	}
+	if decision := mustTake(t, repo, "bob", now.Add(20*time.Second)); !decision.Allowed || decision.Remaining != 0 {
+		t.Fatalf("unexpired bucket deleted: %+v", decision)
+	}
+}
+
+func testRateLimitConcurrentTakes(t *testing.T, repo repository.RateLimitRepository) {
+	now := time.Now()
+
+	const workers = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decision, err := repo.Take("alice", threePerMinute, now)
+			if err != nil {
+				errs <- fmt.Errorf("Take: %w", err)
+				return
+			}
+			if decision.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if allowed != 3 {
+		t.Fatalf("%d concurrent takes allowed, want 3", allowed)
+	}
+}
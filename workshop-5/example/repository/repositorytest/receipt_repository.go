@@ -0,0 +1,303 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// ReceiptRepositories is a receipt repository together with the outbox it
+// enqueues messages in
+type ReceiptRepositories struct {
+	Receipts repository.ReceiptRepository
+	Outbox   repository.OutboxRepository
+}
+
+// ReceiptFactory returns new, empty receipt and outbox repositories that
+// share storage
+type ReceiptFactory func(t *testing.T) ReceiptRepositories
+
+// RunReceiptRepositoryTests runs the conformance suite against the receipt
+// repositories returned by newRepos
+func RunReceiptRepositoryTests(t *testing.T, newRepos ReceiptFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repos ReceiptRepositories)
+	}{
+		{"CreateAndGetByOrderID", testReceiptCreateAndGet},
+		{"CreateRejectsInvalid", testReceiptCreateRejectsInvalid},
+		{"Update", testReceiptUpdate},
+		{"MarkDelivery", testReceiptMarkDelivery},
+		{"ConcurrentDeliveriesApplyOnce", testReceiptConcurrentDeliveries},
+		{"ChangesEnqueueMessages", testReceiptEnqueuesMessages},
+		{"HonoursCancelledContext", testReceiptHonoursCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepos(t))
+		})
+	}
+}
+
+func newReceipt(id, orderID string) *entity.Receipt {
+	receipt := entity.NewReceipt(id, orderID, "alice", "+66812345678", "th", "ขอบคุณที่ใช้บริการ")
+	receipt.Encoding = "UCS-2"
+	receipt.Segments = 1
+	return receipt
+}
+
+func testReceiptCreateAndGet(t *testing.T, repos ReceiptRepositories) {
+	repo := repos.Receipts
+	want := newReceipt("r1", "o1")
+	if err := repo.Create(context.Background(), want); err != nil {
+		t.Fatalf("Create: unexpected error: %v", err)
+	}
+
+	got, err := repo.GetByOrderID(context.Background(), "o1")
+	if err != nil {
+		t.Fatalf("GetByOrderID: unexpected error: %v", err)
+	}
+	if got.ID != "r1" || got.UserID != "alice" || got.Phone != want.Phone || got.Language != "th" || got.Body != want.Body ||
+		got.Encoding != "UCS-2" || got.Segments != 1 || got.Status != entity.ReceiptPending || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("receipt mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+
+	for name, duplicate := range map[string]*entity.Receipt{"same ID": newReceipt("r1", "o2"), "same order": newReceipt("r2", "o1")} {
+		if err := repo.Create(context.Background(), duplicate); !errors.Is(err, repository.ErrReceiptExists) {
+			t.Fatalf("Create %s: expected %v, got %v", name, repository.ErrReceiptExists, err)
+		}
+	}
+	if _, err := repo.GetByOrderID(context.Background(), "missing"); !errors.Is(err, repository.ErrReceiptNotFound) {
+		t.Fatalf("GetByOrderID missing: expected %v, got %v", repository.ErrReceiptNotFound, err)
+	}
+}
+
+func testReceiptCreateRejectsInvalid(t *testing.T, repos ReceiptRepositories) {
+	repo := repos.Receipts
+	unknownStatus := newReceipt("r4", "o4")
+	unknownStatus.Status = "queued"
+
+	tests := []struct {
+		name    string
+		receipt *entity.Receipt
+		want    error
+	}{
+		{"nil", nil, repository.ErrNilReceipt},
+		{"empty ID", newReceipt("", "o1"), repository.ErrInvalidReceipt},
+		{"no order", newReceipt("r2", ""), repository.ErrInvalidReceipt},
+		{"no phone", entity.NewReceipt("r3", "o3", "alice", "", "th", "Hi"), repository.ErrInvalidReceipt},
+		{"unknown status", unknownStatus, repository.ErrInvalidReceipt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Create(context.Background(), tt.receipt); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testReceiptUpdate(t *testing.T, repos ReceiptRepositories) {
+	repo := repos.Receipts
+	receipt := newReceipt("r1", "o1")
+	if err := repo.Create(context.Background(), receipt); err != nil {
+		t.Fatalf("Create: unexpected error: %v", err)
+	}
+
+	receipt.MarkSent("msg-42")
+	receipt.Body = "ignored"
+	if err := repo.Update(context.Background(), receipt); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	got, err := repo.GetByOrderID(context.Background(), "o1")
+	if err != nil {
+		t.Fatalf("GetByOrderID: unexpected error: %v", err)
+	}
+	if got.Status != entity.ReceiptSent || got.ProviderRef != "msg-42" || got.Body != "ขอบคุณที่ใช้บริการ" ||
+		!got.UpdatedAt.Equal(receipt.UpdatedAt) {
+		t.Fatalf("unexpected receipt after update: %+v", got)
+	}
+
+	receipt.MarkFailed("number unreachable")
+	if err := repo.Update(context.Background(), receipt); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	if got, _ := repo.GetByOrderID(context.Background(), "o1"); got.Status != entity.ReceiptFailed || got.Error != "number unreachable" {
+		t.Fatalf("unexpected receipt after failure: %+v", got)
+	}
+
+	if err := repo.Update(context.Background(), newReceipt("missing", "o1")); !errors.Is(err, repository.ErrReceiptNotFound) {
+		t.Fatalf("Update missing: expected %v, got %v", repository.ErrReceiptNotFound, err)
+	}
+}
+
+// mustCreateSent stores a receipt the provider accepted as providerRef
+func mustCreateSent(t *testing.T, repo repository.ReceiptRepository, id, orderID, providerRef string) *entity.Receipt {
+	t.Helper()
+	receipt := newReceipt(id, orderID)
+	if err := repo.Create(context.Background(), receipt); err != nil {
+		t.Fatalf("Create(%s): unexpected error: %v", id, err)
+	}
+	receipt.MarkSent(providerRef)
+	if err := repo.Update(context.Background(), receipt); err != nil {
+		t.Fatalf("Update(%s): unexpected error: %v", id, err)
+	}
+	return receipt
+}
+
+func testReceiptMarkDelivery(t *testing.T, repos ReceiptRepositories) {
+	repo := repos.Receipts
+	mustCreateSent(t, repo, "r1", "o1", "msg-1")
+	mustCreateSent(t, repo, "r2", "o2", "msg-2")
+
+	got, err := repo.GetByProviderRef(context.Background(), "msg-2")
+	if err != nil {
+		t.Fatalf("GetByProviderRef: unexpected error: %v", err)
+	}
+	if got.ID != "r2" || got.Status != entity.ReceiptSent {
+		t.Fatalf("unexpected receipt: %+v", got)
+	}
+
+	delivered, err := repo.MarkDelivery(context.Background(), "msg-1", entity.ReceiptDelivered, "")
+	if err != nil {
+		t.Fatalf("MarkDelivery: unexpected error: %v", err)
+	}
+	if delivered.ID != "r1" || delivered.Status != entity.ReceiptDelivered {
+		t.Fatalf("unexpected delivered receipt: %+v", delivered)
+	}
+	failed, err := repo.MarkDelivery(context.Background(), "msg-2", entity.ReceiptFailed, "handset off")
+	if err != nil {
+		t.Fatalf("MarkDelivery: unexpected error: %v", err)
+	}
+	if stored, _ := repo.GetByOrderID(context.Background(), "o2"); stored.Status != entity.ReceiptFailed || stored.Error != "handset off" ||
+		!stored.UpdatedAt.Equal(failed.UpdatedAt) {
+		t.Fatalf("unexpected stored receipt: %+v", stored)
+	}
+
+	tests := []struct {
+		name        string
+		providerRef string
+		status      entity.ReceiptStatus
+		want        error
+	}{
+		{"already delivered", "msg-1", entity.ReceiptFailed, repository.ErrReceiptNotSent},
+		{"unknown reference", "msg-3", entity.ReceiptDelivered, repository.ErrReceiptNotFound},
+		{"no reference", "", entity.ReceiptDelivered, repository.ErrInvalidDelivery},
+		{"not a final status", "msg-1", entity.ReceiptSent, repository.ErrInvalidDelivery},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repo.MarkDelivery(context.Background(), tt.providerRef, tt.status, ""); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+	if _, err := repo.GetByProviderRef(context.Background(), ""); !errors.Is(err, repository.ErrReceiptNotFound) {
+		t.Fatalf("GetByProviderRef empty: expected %v, got %v", repository.ErrReceiptNotFound, err)
+	}
+}
+
+func testReceiptConcurrentDeliveries(t *testing.T, repos ReceiptRepositories) {
+	repo := repos.Receipts
+	mustCreateSent(t, repo, "r1", "o1", "msg-1")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	applied := 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.MarkDelivery(context.Background(), "msg-1", entity.ReceiptDelivered, "")
+			if err != nil && !errors.Is(err, repository.ErrReceiptNotSent) {
+				t.Errorf("MarkDelivery: unexpected error: %v", err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				applied++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if applied != 1 {
+		t.Fatalf("delivery applied %d times, want once", applied)
+	}
+}
+
+func testReceiptEnqueuesMessages(t *testing.T, repos ReceiptRepositories) {
+	receipt := mustCreateSent(t, repos.Receipts, "r1", "o1", "msg-1")
+	mustEnqueue(t, repos.Outbox, newOutboxMessage(t, "taken", 0))
+
+	// A message that cannot be enqueued rolls the change back
+	receipt.MarkFailed("rejected")
+	if err := repos.Receipts.Update(context.Background(), receipt, newOutboxMessage(t, "taken", 0)); !errors.Is(err, repository.ErrOutboxMessageExists) {
+		t.Fatalf("Update: expected %v, got %v", repository.ErrOutboxMessageExists, err)
+	}
+	if _, err := repos.Receipts.MarkDelivery(context.Background(), "msg-1", entity.ReceiptDelivered, "", newOutboxMessage(t, "taken", 0)); !errors.Is(err, repository.ErrOutboxMessageExists) {
+		t.Fatalf("MarkDelivery: expected %v, got %v", repository.ErrOutboxMessageExists, err)
+	}
+	if got, _ := repos.Receipts.GetByOrderID(context.Background(), "o1"); got.Status != entity.ReceiptSent {
+		t.Fatalf("expected the receipt to stay sent, got %+v", got)
+	}
+
+	if _, err := repos.Receipts.MarkDelivery(context.Background(), "msg-1", entity.ReceiptDelivered, "", newOutboxMessage(t, "sms_sent", 0)); err != nil {
+		t.Fatalf("MarkDelivery: unexpected error: %v", err)
+	}
+	assertOutboxStatus(t, repos.Outbox, "sms_sent", entity.OutboxPending, 0)
+
+	// A repeated delivery enqueues nothing
+	if _, err := repos.Receipts.MarkDelivery(context.Background(), "msg-1", entity.ReceiptFailed, "", newOutboxMessage(t, "repeat", 0)); !errors.Is(err, repository.ErrReceiptNotSent) {
+		t.Fatalf("MarkDelivery again: expected %v, got %v", repository.ErrReceiptNotSent, err)
+	}
+	if _, err := repos.Outbox.GetByID(context.Background(), "repeat"); !errors.Is(err, repository.ErrOutboxMessageNotFound) {
+		t.Fatalf("expected no message for the repeated delivery, got %v", err)
+	}
+
+	failed := newReceipt("r2", "o2")
+	if err := repos.Receipts.Create(context.Background(), failed); err != nil {
+		t.Fatalf("Create: unexpected error: %v", err)
+	}
+	failed.MarkFailed("rejected")
+	if err := repos.Receipts.Update(context.Background(), failed, newOutboxMessage(t, "sms_failed", 0)); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	assertOutboxStatus(t, repos.Outbox, "sms_failed", entity.OutboxPending, 0)
+}
+
+func testReceiptHonoursCancelledContext(t *testing.T, repos ReceiptRepositories) {
+	receipt := mustCreateSent(t, repos.Receipts, "r1", "o1", "msg-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	failed := *receipt
+	failed.MarkFailed("Provider unavailable")
+	assertCancelled(t, []cancelledCall{
+		{"Create", repos.Receipts.Create(ctx, newReceipt("r2", "o2"))},
+		{"Update", repos.Receipts.Update(ctx, &failed, newOutboxMessage(t, "m1", 0))},
+		{"GetByOrderID", errOf(repos.Receipts.GetByOrderID(ctx, "o1"))},
+		{"GetByProviderRef", errOf(repos.Receipts.GetByProviderRef(ctx, "msg-1"))},
+		{"MarkDelivery", errOf(repos.Receipts.MarkDelivery(ctx, "msg-1", entity.ReceiptDelivered, "", newOutboxMessage(t, "m2", 0)))},
+	})
+
+	// None of the writes took effect
+	if got, err := repos.Receipts.GetByOrderID(context.Background(), "o1"); err != nil || got.Status != receipt.Status {
+		t.Fatalf("GetByOrderID(o1) = %+v, %v", got, err)
+	}
+	if _, err := repos.Receipts.GetByOrderID(context.Background(), "o2"); !errors.Is(err, repository.ErrReceiptNotFound) {
+		t.Fatalf("GetByOrderID(o2): expected %v, got %v", repository.ErrReceiptNotFound, err)
+	}
+	for _, id := range []string{"m1", "m2"} {
+		if _, err := repos.Outbox.GetByID(context.Background(), id); !errors.Is(err, repository.ErrOutboxMessageNotFound) {
+			t.Fatalf("GetByID(%s): expected %v, got %v", id, repository.ErrOutboxMessageNotFound, err)
+		}
+	}
+}
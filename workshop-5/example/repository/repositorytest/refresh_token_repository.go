@@ -0,0 +1,278 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// RefreshTokenFactory returns a new, empty refresh token repository for a
+// single test
+type RefreshTokenFactory func(t *testing.T) repository.RefreshTokenRepository
+
+// RunRefreshTokenRepositoryTests runs the conformance suite against the
+// refresh token repositories returned by newRepo
+func RunRefreshTokenRepositoryTests(t *testing.T, newRepo RefreshTokenFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.RefreshTokenRepository)
+	}{
+		{"CreateAndGetByID", testRefreshTokenCreateAndGetByID},
+		{"CreateRejectsInvalid", testRefreshTokenCreateRejectsInvalid},
+		{"Rotate", testRefreshTokenRotate},
+		{"RotateRejectsInactive", testRefreshTokenRotateRejectsInactive},
+		{"RevokeFamily", testRefreshTokenRevokeFamily},
+		{"DeleteExpired", testRefreshTokenDeleteExpired},
+		{"ConcurrentRotationsApplyOnce", testRefreshTokenConcurrentRotations},
+		{"HonoursCancelledContext", testRefreshTokenHonoursCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+func mustCreateRefreshToken(t *testing.T, repo repository.RefreshTokenRepository, id string, ttl time.Duration) *entity.RefreshToken {
+	t.Helper()
+	token := entity.NewRefreshToken(id, "", "alice", "hash-"+id, ttl)
+	if err := repo.Create(context.Background(), token); err != nil {
+		t.Fatalf("Create(%s): unexpected error: %v", id, err)
+	}
+	return token
+}
+
+func testRefreshTokenCreateAndGetByID(t *testing.T, repo repository.RefreshTokenRepository) {
+	want := mustCreateRefreshToken(t, repo, "t1", time.Hour)
+
+	got, err := repo.GetByID(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if got.ID != "t1" || got.FamilyID != "t1" || got.UserID != "alice" || got.SecretHash != want.SecretHash ||
+		got.RotatedAt != nil || got.RevokedAt != nil ||
+		!got.ExpiresAt.Equal(want.ExpiresAt) || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("refresh token mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+
+	if err := repo.Create(context.Background(), entity.NewRefreshToken("t1", "", "bob", "hash", time.Hour)); !errors.Is(err, repository.ErrRefreshTokenExists) {
+		t.Fatalf("Create duplicate: expected %v, got %v", repository.ErrRefreshTokenExists, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "missing"); !errors.Is(err, repository.ErrRefreshTokenNotFound) {
+		t.Fatalf("GetByID missing: expected %v, got %v", repository.ErrRefreshTokenNotFound, err)
+	}
+}
+
+func testRefreshTokenCreateRejectsInvalid(t *testing.T, repo repository.RefreshTokenRepository) {
+	revoked := entity.NewRefreshToken("revoked", "", "alice", "hash", time.Hour)
+	revoked.RevokedAt = &revoked.CreatedAt
+
+	tests := []struct {
+		name  string
+		token *entity.RefreshToken
+		want  error
+	}{
+		{"nil", nil, repository.ErrNilRefreshToken},
+		{"empty ID", entity.NewRefreshToken("", "f1", "alice", "hash", time.Hour), repository.ErrInvalidRefreshToken},
+		{"no user", entity.NewRefreshToken("t1", "", "", "hash", time.Hour), repository.ErrInvalidRefreshToken},
+		{"no secret", entity.NewRefreshToken("t2", "", "alice", "", time.Hour), repository.ErrInvalidRefreshToken},
+		{"revoked", revoked, repository.ErrInvalidRefreshToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Create(context.Background(), tt.token); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testRefreshTokenRotate(t *testing.T, repo repository.RefreshTokenRepository) {
+	first := mustCreateRefreshToken(t, repo, "t1", time.Hour)
+	now := first.CreatedAt.Add(time.Second)
+
+	next := entity.NewRefreshToken("t2", "t1", "alice", "hash-t2", time.Hour)
+	if err := repo.Rotate(context.Background(), "t1", next, now); err != nil {
+		t.Fatalf("Rotate: unexpected error: %v", err)
+	}
+	rotated, err := repo.GetByID(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if rotated.RotatedAt == nil || !rotated.RotatedAt.Equal(now) || rotated.Active(now) {
+		t.Fatalf("token not rotated: %+v", rotated)
+	}
+	if got, err := repo.GetByID(context.Background(), "t2"); err != nil || got.FamilyID != "t1" || !got.Active(now) {
+		t.Fatalf("successor not stored: %+v, %v", got, err)
+	}
+
+	// The successor must stay in the family and belong to the same user
+	other := mustCreateRefreshToken(t, repo, "other", time.Hour)
+	tests := []struct {
+		name string
+		id   string
+		next *entity.RefreshToken
+		want error
+	}{
+		{"other family", "t2", entity.NewRefreshToken("t3", "other", "alice", "hash", time.Hour), repository.ErrInvalidRefreshToken},
+		{"other user", "t2", entity.NewRefreshToken("t3", "t1", "bob", "hash", time.Hour), repository.ErrInvalidRefreshToken},
+		{"existing ID", "t2", entity.NewRefreshToken(other.ID, "t1", "alice", "hash", time.Hour), repository.ErrRefreshTokenExists},
+		{"missing", "missing", entity.NewRefreshToken("t3", "t1", "alice", "hash", time.Hour), repository.ErrRefreshTokenNotFound},
+		{"nil", "t2", nil, repository.ErrNilRefreshToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Rotate(context.Background(), tt.id, tt.next, now); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+	if got, _ := repo.GetByID(context.Background(), "t2"); !got.Active(now) {
+		t.Fatalf("refused rotation changed the token: %+v", got)
+	}
+}
+
+func testRefreshTokenRotateRejectsInactive(t *testing.T, repo repository.RefreshTokenRepository) {
+	first := mustCreateRefreshToken(t, repo, "t1", time.Hour)
+	now := first.CreatedAt.Add(time.Second)
+	if err := repo.Rotate(context.Background(), "t1", entity.NewRefreshToken("t2", "t1", "alice", "hash", time.Hour), now); err != nil {
+		t.Fatalf("Rotate: unexpected error: %v", err)
+	}
+
+	expired := mustCreateRefreshToken(t, repo, "expired", time.Minute)
+	revoked := mustCreateRefreshToken(t, repo, "revoked", time.Hour)
+	if _, err := repo.RevokeFamily(context.Background(), revoked.FamilyID, now); err != nil {
+		t.Fatalf("RevokeFamily: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		at   time.Time
+	}{
+		{"rotated", "t1", now},
+		{"expired", expired.ID, expired.ExpiresAt},
+		{"revoked", revoked.ID, now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := entity.NewRefreshToken("next-"+tt.id, tt.id, "alice", "hash", time.Hour)
+			if err := repo.Rotate(context.Background(), tt.id, next, tt.at); !errors.Is(err, repository.ErrRefreshTokenInactive) {
+				t.Fatalf("expected %v, got %v", repository.ErrRefreshTokenInactive, err)
+			}
+			if _, err := repo.GetByID(context.Background(), next.ID); !errors.Is(err, repository.ErrRefreshTokenNotFound) {
+				t.Fatalf("successor of an inactive token was stored: %v", err)
+			}
+		})
+	}
+}
+
+func testRefreshTokenRevokeFamily(t *testing.T, repo repository.RefreshTokenRepository) {
+	first := mustCreateRefreshToken(t, repo, "t1", time.Hour)
+	mustCreateRefreshToken(t, repo, "other", time.Hour)
+	now := first.CreatedAt.Add(time.Second)
+	if err := repo.Rotate(context.Background(), "t1", entity.NewRefreshToken("t2", "t1", "alice", "hash", time.Hour), now); err != nil {
+		t.Fatalf("Rotate: unexpected error: %v", err)
+	}
+
+	revoked, err := repo.RevokeFamily(context.Background(), "t1", now)
+	if err != nil || revoked != 2 {
+		t.Fatalf("RevokeFamily = %d, %v, want 2", revoked, err)
+	}
+	for _, id := range []string{"t1", "t2"} {
+		if got, _ := repo.GetByID(context.Background(), id); got.RevokedAt == nil || !got.RevokedAt.Equal(now) {
+			t.Fatalf("%s not revoked: %+v", id, got)
+		}
+	}
+	if got, _ := repo.GetByID(context.Background(), "other"); got.RevokedAt != nil {
+		t.Fatalf("token of another family revoked: %+v", got)
+	}
+	if revoked, _ := repo.RevokeFamily(context.Background(), "t1", now.Add(time.Second)); revoked != 0 {
+		t.Fatalf("revoking again revoked %d tokens", revoked)
+	}
+}
+
+func testRefreshTokenDeleteExpired(t *testing.T, repo repository.RefreshTokenRepository) {
+	old := mustCreateRefreshToken(t, repo, "old", time.Minute)
+	mustCreateRefreshToken(t, repo, "new", time.Hour)
+
+	deleted, err := repo.DeleteExpired(context.Background(), old.ExpiresAt)
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteExpired = %d, %v, want 1", deleted, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "old"); !errors.Is(err, repository.ErrRefreshTokenNotFound) {
+		t.Fatalf("expired token kept: %v", err)
+	}
+	if _, err := repo.GetByID(context.Background(), "new"); err != nil {
+		t.Fatalf("unexpired token deleted: %v", err)
+	}
+}
+
+func testRefreshTokenConcurrentRotations(t *testing.T, repo repository.RefreshTokenRepository) {
+	first := mustCreateRefreshToken(t, repo, "t1", time.Hour)
+
+	const workers = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	rotated := 0
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			next := entity.NewRefreshToken(fmt.Sprintf("next-%d", i), "t1", "alice", "hash", time.Hour)
+			err := repo.Rotate(context.Background(), "t1", next, first.CreatedAt)
+			switch {
+			case err == nil:
+				mu.Lock()
+				rotated++
+				mu.Unlock()
+			case !errors.Is(err, repository.ErrRefreshTokenInactive):
+				errs <- fmt.Errorf("Rotate: %w", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if rotated != 1 {
+		t.Fatalf("%d concurrent rotations succeeded, want 1", rotated)
+	}
+}
+
+func testRefreshTokenHonoursCancelledContext(t *testing.T, repo repository.RefreshTokenRepository) {
+	token := mustCreateRefreshToken(t, repo, "t1", -time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	now := time.Now()
+	next := entity.NewRefreshToken("t2", token.FamilyID, "alice", "hash-t2", time.Hour)
+	assertCancelled(t, []cancelledCall{
+		{"Create", repo.Create(ctx, entity.NewRefreshToken("t3", "", "alice", "hash-t3", time.Hour))},
+		{"GetByID", errOf(repo.GetByID(ctx, "t1"))},
+		{"Rotate", repo.Rotate(ctx, "t1", next, now)},
+		{"RevokeFamily", errOf(repo.RevokeFamily(ctx, token.FamilyID, now))},
+		{"DeleteExpired", errOf(repo.DeleteExpired(ctx, now))},
+	})
+
+	// None of the writes took effect
+	if got, err := repo.GetByID(context.Background(), "t1"); err != nil || got.RotatedAt != nil || got.RevokedAt != nil {
+		t.Fatalf("GetByID(t1) = %+v, %v", got, err)
+	}
+	for _, id := range []string{"t2", "t3"} {
+		if _, err := repo.GetByID(context.Background(), id); !errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			t.Fatalf("GetByID(%s): expected %v, got %v", id, repository.ErrRefreshTokenNotFound, err)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// TransferFactory returns a new, empty transfer repository together with
+// the ledger and QR requests it settles against
+type TransferFactory func(t *testing.T) (repository.TransferRepository, repository.LedgerRepository, repository.QRRequestRepository)
+
+// RunTransferRepositoryTests runs the conformance suite against the
+// transfer repositories returned by newRepo
+func RunTransferRepositoryTests(t *testing.T, newRepo TransferFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.TransferRepository, ledger repository.LedgerRepository, qrRequests repository.QRRequestRepository)
+	}{
+		{"CreateAndGetByID", testTransferCreateAndGetByID},
+		{"CreateRejectsInvalid", testTransferCreateRejectsInvalid},
+		{"CompletePostsLedger", testTransferCompletePostsLedger},
+		{"CompleteRollsBackOnInsufficientBalance", testTransferCompleteRollsBack},
+		{"CompleteOnlyOnce", testTransferCompleteOnlyOnce},
+		{"CompletePaysQRRequest", testTransferCompletePaysQRRequest},
+		{"CompleteRollsBackUnlessQRRequestPending", testTransferCompleteRollsBackUnlessQRRequestPending},
+		{"Fail", testTransferFail},
+		{"PostedTotalSince", testTransferPostedTotalSince},
+		{"ConcurrentCompletesNeverOverdraw", testTransferConcurrentCompletes},
+		{"HonoursCancelledContext", testTransferHonoursCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, ledger, qrRequests := newRepo(t)
+			tt.run(t, repo, ledger, qrRequests)
+		})
+	}
+}
+
+func mustCreateTransfer(t *testing.T, repo repository.TransferRepository, id, from, to string, amount int) *entity.Transfer {
+	t.Helper()
+	transfer := entity.NewTransfer(id, from, to, amount, "memo "+id)
+	if err := repo.Create(context.Background(), transfer); err != nil {
+		t.Fatalf("Create(%s): unexpected error: %v", id, err)
+	}
+	return transfer
+}
+
+func testTransferCreateAndGetByID(t *testing.T, repo repository.TransferRepository, _ repository.LedgerRepository, _ repository.QRRequestRepository) {
+	want := mustCreateTransfer(t, repo, "t1", "alice", "bob", 100)
+
+	got, err := repo.GetByID(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if got.ID != want.ID || got.FromUserID != "alice" || got.ToUserID != "bob" || got.Amount != 100 ||
+		got.Memo != want.Memo || got.Status != entity.TransferPending || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("transfer mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+
+	if err := repo.Create(context.Background(), entity.NewTransfer("t1", "alice", "bob", 100, "")); !errors.Is(err, repository.ErrTransferExists) {
+		t.Fatalf("Create duplicate: expected %v, got %v", repository.ErrTransferExists, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "missing"); !errors.Is(err, repository.ErrTransferNotFound) {
+		t.Fatalf("GetByID missing: expected %v, got %v", repository.ErrTransferNotFound, err)
+	}
+}
+
+func testTransferCreateRejectsInvalid(t *testing.T, repo repository.TransferRepository, _ repository.LedgerRepository, _ repository.QRRequestRepository) {
+	posted := entity.NewTransfer("posted", "alice", "bob", 1, "")
+	posted.Status = entity.TransferPosted
+
+	tests := []struct {
+		name     string
+		transfer *entity.Transfer
+		want     error
+	}{
+		{"nil", nil, repository.ErrNilTransfer},
+		{"empty ID", entity.NewTransfer("", "alice", "bob", 1, ""), repository.ErrInvalidTransfer},
+		{"to self", entity.NewTransfer("self", "alice", "alice", 1, ""), repository.ErrInvalidTransfer},
+		{"zero amount", entity.NewTransfer("zero", "alice", "bob", 0, ""), repository.ErrInvalidTransfer},
+		{"not pending", posted, repository.ErrInvalidTransfer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.Create(context.Background(), tt.transfer); !errors.Is(err, tt.want) {
+				t.Fatalf("Create: expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func testTransferCompletePostsLedger(t *testing.T, repo repository.TransferRepository, ledger repository.LedgerRepository, _ repository.QRRequestRepository) {
+	mustPost(t, ledger, entity.NewEarnPosting("seed", "alice", 500, ""))
+	mustCreateTransfer(t, repo, "t1", "alice", "bob", 200)
+
+	got, err := repo.Complete(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("Complete: unexpected error: %v", err)
+	}
+	if got.Status != entity.TransferPosted {
+		t.Fatalf("status = %s, want %s", got.Status, entity.TransferPosted)
+	}
+	assertBalance(t, ledger, "alice", 300)
+	assertBalance(t, ledger, "bob", 200)
+
+	posting, err := ledger.GetPosting(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("GetPosting: unexpected error: %v", err)
+	}
+	for _, entry := range posting.Entries {
+		if entry.TransferID != "t1" {
+			t.Fatalf("entry not linked to the transfer: %+v", entry)
+		}
+	}
+
+	stored, err := repo.GetByID(context.Background(), "t1")
+	if err != nil || stored.Status != entity.TransferPosted {
+		t.Fatalf("GetByID after Complete = %+v, %v", stored, err)
+	}
+}
+
+func testTransferCompleteRollsBack(t *testing.T, repo repository.TransferRepository, ledger repository.LedgerRepository, _ repository.QRRequestRepository) {
+	mustPost(t, ledger, entity.NewEarnPosting("seed", "alice", 100, ""))
+	mustCreateTransfer(t, repo, "t1", "alice", "bob", 101)
+
+	if _, err := repo.Complete(context.Background(), "t1"); !errors.Is(err, repository.ErrInsufficientBalance) {
+		t.Fatalf("Complete: expected %v, got %v", repository.ErrInsufficientBalance, err)
+	}
+
+	assertBalance(t, ledger, "alice", 100)
+	assertBalance(t, ledger, "bob", 0)
+	stored, err := repo.GetByID(context.Background(), "t1")
+	if err != nil || stored.Status != entity.TransferPending {
+		t.Fatalf("transfer after rolled back Complete = %+v, %v; want pending", stored, err)
+	}
+}
+
+func testTransferCompleteOnlyOnce(t *testing.T, repo repository.TransferRepository, ledger repository.LedgerRepository, _ repository.QRRequestRepository) {
+	mustPost(t, ledger, entity.NewEarnPosting("seed", "alice", 500, ""))
+	mustCreateTransfer(t, repo, "t1", "alice", "bob", 100)
+
+	if _, err := repo.Complete(context.Background(), "t1"); err != nil {
+		t.Fatalf("Complete: unexpected error: %v", err)
+	}
+	if _, err := repo.Complete(context.Background(), "t1"); !errors.Is(err, repository.ErrTransferNotPending) {
+		t.Fatalf("second Complete: expected %v, got %v", repository.ErrTransferNotPending, err)
+	}
+	if _, err := repo.Fail(context.Background(), "t1", "too late"); !errors.Is(err, repository.ErrTransferNotPending) {
+		t.Fatalf("Fail after Complete: expected %v, got %v", repository.ErrTransferNotPending, err)
+	}
+	assertBalance(t, ledger, "bob", 100)
+
+	if _, err := repo.Complete(context.Background(), "missing"); !errors.Is(err, repository.ErrTransferNotFound) {
+		t.Fatalf("Complete missing: expected %v, got %v", repository.ErrTransferNotFound, err)
+	}
+}
+
+func mustCreateQRTransfer(t *testing.T, repo repository.TransferRepository, qrRequests repository.QRRequestRepository, id string) *entity.QRRequest {
+	t.Helper()
+	request := entity.NewQRRequest("qr-"+id, "bob", 100, "", time.Hour)
+	if err := qrRequests.Create(context.Background(), request); err != nil {
+		t.Fatalf("Create QR request: unexpected error: %v", err)
+	}
+	transfer := entity.NewTransfer(id, "alice", "bob", 100, "")
+	transfer.QRRequestID = request.ID
+	if err := repo.Create(context.Background(), transfer); err != nil {
+		t.Fatalf("Create(%s): unexpected error: %v", id, err)
+	}
+	return request
+}
+
+func testTransferCompletePaysQRRequest(t *testing.T, repo repository.TransferRepository, ledger repository.LedgerRepository, qrRequests repository.QRRequestRepository) {
+	mustPost(t, ledger, entity.NewEarnPosting("seed", "alice", 500, ""))
+	request := mustCreateQRTransfer(t, repo, qrRequests, "t1")
+
+	if _, err := repo.Complete(context.Background(), "t1"); err != nil {
+		t.Fatalf("Complete: unexpected error: %v", err)
+	}
+	stored, err := repo.GetByID(context.Background(), "t1")
+	if err != nil || stored.Status != entity.TransferPosted || stored.QRRequestID != request.ID {
+		t.Fatalf("GetByID after Complete = %+v, %v", stored, err)
+	}
+	paid, err := qrRequests.GetByID(context.Background(), request.ID)
+	if err != nil || paid.Status != entity.QRRequestPaid || paid.PayerID != "alice" || paid.TransferID != "t1" {
+		t.Fatalf("QR request after Complete = %+v, %v", paid, err)
+	}
+	assertBalance(t, ledger, "bob", 100)
+}
+
+func testTransferCompleteRollsBackUnlessQRRequestPending(t *testing.T, repo repository.TransferRepository, ledger repository.LedgerRepository, qrRequests repository.QRRequestRepository) {
+	mustPost(t, ledger, entity.NewEarnPosting("seed", "alice", 500, ""))
+
+	// Paid by another transfer since this one was created
+	paid := mustCreateQRTransfer(t, repo, qrRequests, "t1")
+	if _, err := qrRequests.MarkPaid(context.Background(), paid.ID, "carol", "other"); err != nil {
+		t.Fatalf("MarkPaid: unexpected error: %v", err)
+	}
+	if _, err := repo.Complete(context.Background(), "t1"); !errors.Is(err, repository.ErrQRRequestNotPending) {
+		t.Fatalf("Complete paid: expected %v, got %v", repository.ErrQRRequestNotPending, err)
+	}
+
+	// Expired since this one was created
+	expired := mustCreateQRTransfer(t, repo, qrRequests, "t2")
+	if _, err := qrRequests.MarkExpired(context.Background(), expired.ID); err != nil {
+		t.Fatalf("MarkExpired: unexpected error: %v", err)
+	}
+	if _, err := repo.Complete(context.Background(), "t2"); !errors.Is(err, repository.ErrQRRequestNotPending) {
+		t.Fatalf("Complete expired: expected %v, got %v", repository.ErrQRRequestNotPending, err)
+	}
+
+	assertBalance(t, ledger, "alice", 500)
+	assertBalance(t, ledger, "bob", 0)
+	for _, id := range []string{"t1", "t2"} {
+		stored, err := repo.GetByID(context.Background(), id)
+		if err != nil || stored.Status != entity.TransferPending {
+			t.Fatalf("transfer %s after rolled back Complete = %+v, %v; want pending", id, stored, err)
+		}
+	}
+	if request, err := qrRequests.GetByID(context.Background(), paid.ID); err != nil || request.TransferID != "other" {
+		t.Fatalf("QR request after rolled back Complete = %+v, %v", request, err)
+	}
+}
+
+func testTransferFail(t *testing.T, repo repository.TransferRepository, ledger repository.LedgerRepository, _ repository.QRRequestRepository) {
+	mustCreateTransfer(t, repo, "t1", "alice", "bob", 100)
+
+	got, err := repo.Fail(context.Background(), "t1", "insufficient balance")
+	if err != nil {
+		t.Fatalf("Fail: unexpected error: %v", err)
+	}
+	if got.Status != entity.TransferFailed || got.FailureReason != "insufficient balance" {
+		t.Fatalf("Fail returned %+v", got)
+	}
+
+	stored, err := repo.GetByID(context.Background(), "t1")
+	if err != nil || stored.Status != entity.TransferFailed || stored.FailureReason != "insufficient balance" {
+		t.Fatalf("GetByID after Fail = %+v, %v", stored, err)
+	}
+	if _, err := repo.Complete(context.Background(), "t1"); !errors.Is(err, repository.ErrTransferNotPending) {
+		t.Fatalf("Complete after Fail: expected %v, got %v", repository.ErrTransferNotPending, err)
+	}
+	assertBalance(t, ledger, "bob", 0)
+}
+
+func testTransferPostedTotalSince(t *testing.T, repo repository.TransferRepository, ledger repository.LedgerRepository, _ repository.QRRequestRepository) {
+	start := time.Now()
+	mustPost(t, ledger, entity.NewEarnPosting("seed", "alice", 1000, ""))
+
+	mustCreateTransfer(t, repo, "t1", "alice", "bob", 100)
+	mustCreateTransfer(t, repo, "t2", "alice", "carol", 250)
+	mustCreateTransfer(t, repo, "t3", "alice", "bob", 400) // stays pending
+	mustCreateTransfer(t, repo, "t4", "alice", "bob", 50)  // fails
+	for _, id := range []string{"t1", "t2"} {
+		if _, err := repo.Complete(context.Background(), id); err != nil {
+			t.Fatalf("Complete(%s): unexpected error: %v", id, err)
+		}
+	}
+	if _, err := repo.Fail(context.Background(), "t4", "test"); err != nil {
+		t.Fatalf("Fail: unexpected error: %v", err)
+	}
+
+	total, err := repo.PostedTotalSince(context.Background(), "alice", start)
+	if err != nil {
+		t.Fatalf("PostedTotalSince: unexpected error: %v", err)
+	}
+	if total != 350 {
+		t.Fatalf("PostedTotalSince = %d, want 350", total)
+	}
+
+	total, err = repo.PostedTotalSince(context.Background(), "alice", time.Now().Add(time.Hour))
+	if err != nil || total != 0 {
+		t.Fatalf("PostedTotalSince(future) = %d, %v; want 0", total, err)
+	}
+	total, err = repo.PostedTotalSince(context.Background(), "bob", start)
+	if err != nil || total != 0 {
+		t.Fatalf("PostedTotalSince(bob) = %d, %v; want 0", total, err)
+	}
+}
+
+func testTransferConcurrentCompletes(t *testing.T, repo repository.TransferRepository, ledger repository.LedgerRepository, _ repository.QRRequestRepository) {
+	mustPost(t, ledger, entity.NewEarnPosting("seed", "alice", 500, ""))
+
+	const attempts = 10
+	ids := make([]string, attempts)
+	for i := range ids {
+		ids[i] = string(rune('a'+i)) + "-transfer"
+		mustCreateTransfer(t, repo, ids[i], "alice", "bob", 100)
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if _, err := repo.Complete(context.Background(), id); err != nil && !errors.Is(err, repository.ErrInsufficientBalance) {
+				t.Errorf("Complete(%s): unexpected error: %v", id, err)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	assertBalance(t, ledger, "alice", 0)
+	assertBalance(t, ledger, "bob", 500)
+
+	posted := 0
+	for _, id := range ids {
+		transfer, err := repo.GetByID(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetByID: unexpected error: %v", err)
+		}
+		if transfer.Status == entity.TransferPosted {
+			posted++
+		}
+	}
+	if posted != 5 {
+		t.Fatalf("expected 5 posted transfers, got %d", posted)
+	}
+}
+
+func testTransferHonoursCancelledContext(t *testing.T, repo repository.TransferRepository, ledger repository.LedgerRepository, _ repository.QRRequestRepository) {
+	mustPost(t, ledger, entity.NewEarnPosting("seed", "alice", 500, ""))
+	mustCreateTransfer(t, repo, "t1", "alice", "bob", 200)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assertCancelled(t, []cancelledCall{
+		{"Create", repo.Create(ctx, entity.NewTransfer("t2", "alice", "bob", 100, ""))},
+		{"GetByID", errOf(repo.GetByID(ctx, "t1"))},
+		{"Complete", errOf(repo.Complete(ctx, "t1"))},
+		{"Fail", errOf(repo.Fail(ctx, "t1", "Cancelled"))},
+		{"PostedTotalSince", errOf(repo.PostedTotalSince(ctx, "alice", time.Time{}))},
+	})
+
+	// None of the writes took effect
+	if got, err := repo.GetByID(context.Background(), "t1"); err != nil || got.Status != entity.TransferPending {
+		t.Fatalf("GetByID(t1) = %+v, %v", got, err)
+	}
+	if _, err := repo.GetByID(context.Background(), "t2"); !errors.Is(err, repository.ErrTransferNotFound) {
+		t.Fatalf("GetByID(t2): expected %v, got %v", repository.ErrTransferNotFound, err)
+	}
+	assertBalance(t, ledger, "alice", 500)
+	assertBalance(t, ledger, "bob", 0)
+}
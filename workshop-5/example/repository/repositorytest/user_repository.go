@@ -0,0 +1,957 @@
+// Package repositorytest provides a conformance test suite that every
+// repository.UserRepository implementation must pass.
+//
+// Implementations plug in from their own _test.go file:
+//
+//	func TestMemoryUserRepository(t *testing.T) {
+//		repositorytest.RunUserRepositoryTests(t, func(t *testing.T) repository.UserRepository {
+//			return repository.NewMemoryUserRepository()
+//		})
+//	}
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+)
+
+// Factory returns a new, empty repository for a single test. Use t.Cleanup
+// to release any resources the repository holds.
+type Factory func(t *testing.T) repository.UserRepository
+
+// RunUserRepositoryTests runs the full conformance suite against the
+// repositories returned by newRepo
+func RunUserRepositoryTests(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.UserRepository)
+	}{
+		{"CreateAndGetByID", testCreateAndGetByID},
+		{"CreateRejectsNil", testCreateRejectsNil},
+		{"CreateRejectsEmptyID", testCreateRejectsEmptyID},
+		{"CreateRejectsDuplicateID", testCreateRejectsDuplicateID},
+		{"CreateRejectsDuplicateEmail", testCreateRejectsDuplicateEmail},
+		{"CreateRejectsDuplicatePhone", testCreateRejectsDuplicatePhone},
+		{"CreateRejectsDuplicateMemberID", testCreateRejectsDuplicateMemberID},
+		{"CreateCopiesInput", testCreateCopiesInput},
+		{"GetReturnsCopies", testGetReturnsCopies},
+		{"GetByIDNotFound", testGetByIDNotFound},
+		{"GetByEmail", testGetByEmail},
+		{"GetByEmailNotFound", testGetByEmailNotFound},
+		{"GetByPhone", testGetByPhone},
+		{"GetByPhoneNotFound", testGetByPhoneNotFound},
+		{"GetAllEmpty", testGetAllEmpty},
+		{"GetAll", testGetAll},
+		{"Update", testUpdate},
+		{"UpdateNotFound", testUpdateNotFound},
+		{"UpdateRejectsTakenEmail", testUpdateRejectsTakenEmail},
+		{"UpdateRejectsTakenPhone", testUpdateRejectsTakenPhone},
+		{"UpdateRejectsNil", testUpdateRejectsNil},
+		{"UpdateRejectsEmptyID", testUpdateRejectsEmptyID},
+		{"Delete", testDelete},
+		{"DeleteNotFound", testDeleteNotFound},
+		{"PointsAreNotStored", testPointsAreNotStored},
+		{"ConcurrentAccess", testConcurrentAccess},
+		{"QueryFilters", testQueryFilters},
+		{"QueryNonASCIINamePrefix", testQueryNonASCIINamePrefix},
+		{"QuerySortOrder", testQuerySortOrder},
+		{"QueryPaginatesWithoutGapsOrDuplicates", testQueryPaginates},
+		{"QueryCursorStableAcrossInserts", testQueryCursorStableAcrossInserts},
+		{"QueryRejectsInvalid", testQueryRejectsInvalid},
+		{"ConcurrentCreateSameEmail", testConcurrentCreateSameEmail},
+		{"NextMemberIDIsMonotonic", testNextMemberIDIsMonotonic},
+		{"NextMemberIDNotReusedAfterDelete", testNextMemberIDNotReusedAfterDelete},
+		{"ConcurrentNextMemberID", testConcurrentNextMemberID},
+		{"CancelledContext", testCancelledContext},
+		{"ExpiredDeadline", testExpiredDeadline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+// newTestUser builds a valid user whose unique fields are derived from n
+func newTestUser(n int) *entity.User {
+	user := entity.NewUser(
+		fmt.Sprintf("00000000-0000-0000-0000-%012d", n),
+		fmt.Sprintf("LBK%06d", n),
+		"John",
+		"Doe",
+		fmt.Sprintf("+6681%07d", n),
+		fmt.Sprintf("user%d@example.com", n),
+	)
+	// Storage backends are not required to keep sub-second precision or
+	// the monotonic clock reading.
+	user.RegisteredAt = user.RegisteredAt.Truncate(time.Second).UTC()
+	return user
+}
+
+// mustCreate stores user or fails the test
+func mustCreate(t *testing.T, repo repository.UserRepository, user *entity.User) {
+	t.Helper()
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create(%s): unexpected error: %v", user.ID, err)
+	}
+}
+
+// assertUserEqual compares every persisted field of two users. Points is
+// a ledger projection and is checked separately.
+func assertUserEqual(t *testing.T, got, want *entity.User) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("got nil user, want %+v", want)
+	}
+	if got.ID != want.ID ||
+		got.MemberID != want.MemberID ||
+		got.FirstName != want.FirstName ||
+		got.LastName != want.LastName ||
+		got.Phone != want.Phone ||
+		got.Email != want.Email ||
+		got.MembershipLevel != want.MembershipLevel ||
+		got.Role != want.Role ||
+		!got.RegisteredAt.Equal(want.RegisteredAt) {
+		t.Fatalf("user mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+}
+
+func testCreateAndGetByID(t *testing.T, repo repository.UserRepository) {
+	want := newTestUser(1)
+	mustCreate(t, repo, want)
+
+	got, err := repo.GetByID(context.Background(), want.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	assertUserEqual(t, got, want)
+}
+
+func testCreateRejectsNil(t *testing.T, repo repository.UserRepository) {
+	if err := repo.Create(context.Background(), nil); !errors.Is(err, repository.ErrNilUser) {
+		t.Fatalf("Create(nil): expected %v, got %v", repository.ErrNilUser, err)
+	}
+}
+
+func testCreateRejectsEmptyID(t *testing.T, repo repository.UserRepository) {
+	user := newTestUser(1)
+	user.ID = ""
+	if err := repo.Create(context.Background(), user); !errors.Is(err, repository.ErrEmptyUserID) {
+		t.Fatalf("Create with empty ID: expected %v, got %v", repository.ErrEmptyUserID, err)
+	}
+}
+
+func testCreateRejectsDuplicateID(t *testing.T, repo repository.UserRepository) {
+	first := newTestUser(1)
+	mustCreate(t, repo, first)
+
+	dup := newTestUser(2)
+	dup.ID = first.ID
+	if err := repo.Create(context.Background(), dup); !errors.Is(err, repository.ErrUserExists) {
+		t.Fatalf("Create with duplicate ID: expected %v, got %v", repository.ErrUserExists, err)
+	}
+
+	got, err := repo.GetByID(context.Background(), first.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	assertUserEqual(t, got, first)
+}
+
+func testCreateRejectsDuplicateEmail(t *testing.T, repo repository.UserRepository) {
+	first := newTestUser(1)
+	mustCreate(t, repo, first)
+
+	dup := newTestUser(2)
+	dup.Email = first.Email
+	err := repo.Create(context.Background(), dup)
+	if !errors.Is(err, repository.ErrEmailTaken) {
+		t.Fatalf("Create with duplicate email: expected %v, got %v", repository.ErrEmailTaken, err)
+	}
+	if !errors.Is(err, apperror.ErrConflict) {
+		t.Fatalf("Create with duplicate email: expected a conflict error, got %v", err)
+	}
+
+	if _, err := repo.GetByID(context.Background(), dup.ID); err == nil {
+		t.Fatal("rejected user must not be stored")
+	}
+}
+
+func testCreateRejectsDuplicatePhone(t *testing.T, repo repository.UserRepository) {
+	first := newTestUser(1)
+	mustCreate(t, repo, first)
+
+	dup := newTestUser(2)
+	dup.Phone = first.Phone
+	if err := repo.Create(context.Background(), dup); !errors.Is(err, repository.ErrPhoneTaken) {
+		t.Fatalf("Create with duplicate phone: expected %v, got %v", repository.ErrPhoneTaken, err)
+	}
+
+	if _, err := repo.GetByID(context.Background(), dup.ID); err == nil {
+		t.Fatal("rejected user must not be stored")
+	}
+}
+
+func testCreateRejectsDuplicateMemberID(t *testing.T, repo repository.UserRepository) {
+	first := newTestUser(1)
+	mustCreate(t, repo, first)
+
+	dup := newTestUser(2)
+	dup.MemberID = first.MemberID
+	if err := repo.Create(context.Background(), dup); !errors.Is(err, repository.ErrMemberIDTaken) {
+		t.Fatalf("Create with duplicate member ID: expected %v, got %v", repository.ErrMemberIDTaken, err)
+	}
+
+	if _, err := repo.GetByID(context.Background(), dup.ID); err == nil {
+		t.Fatal("rejected user must not be stored")
+	}
+}
+
+func testCreateCopiesInput(t *testing.T, repo repository.UserRepository) {
+	user := newTestUser(1)
+	want := *user
+	mustCreate(t, repo, user)
+
+	// Mutating the caller's value after Create must not leak into storage
+	user.FirstName = "Mutated"
+	user.Email = "mutated@example.com"
+	user.Points = 999
+
+	got, err := repo.GetByID(context.Background(), want.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	assertUserEqual(t, got, &want)
+}
+
+func testGetReturnsCopies(t *testing.T, repo repository.UserRepository) {
+	want := newTestUser(1)
+	mustCreate(t, repo, want)
+
+	byID, err := repo.GetByID(context.Background(), want.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	byID.FirstName = "Mutated"
+
+	byEmail, err := repo.GetByEmail(context.Background(), want.Email)
+	if err != nil {
+		t.Fatalf("GetByEmail: unexpected error: %v", err)
+	}
+	assertUserEqual(t, byEmail, want)
+	byEmail.Points = 999
+
+	all, err := repo.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	all[0].Email = "mutated@example.com"
+
+	got, err := repo.GetByID(context.Background(), want.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	assertUserEqual(t, got, want)
+}
+
+func testGetByIDNotFound(t *testing.T, repo repository.UserRepository) {
+	user, err := repo.GetByID(context.Background(), "does-not-exist")
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		t.Fatalf("GetByID on missing user: expected %v, got %v", repository.ErrUserNotFound, err)
+	}
+	if !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("GetByID on missing user: expected a not-found error, got %v", err)
+	}
+	if user != nil {
+		t.Fatalf("GetByID on missing user: expected nil user, got %+v", user)
+	}
+}
+
+func testGetByEmail(t *testing.T, repo repository.UserRepository) {
+	want := newTestUser(1)
+	mustCreate(t, repo, want)
+	mustCreate(t, repo, newTestUser(2))
+
+	got, err := repo.GetByEmail(context.Background(), want.Email)
+	if err != nil {
+		t.Fatalf("GetByEmail: unexpected error: %v", err)
+	}
+	assertUserEqual(t, got, want)
+}
+
+func testGetByEmailNotFound(t *testing.T, repo repository.UserRepository) {
+	mustCreate(t, repo, newTestUser(1))
+
+	user, err := repo.GetByEmail(context.Background(), "nobody@example.com")
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		t.Fatalf("GetByEmail on missing user: expected %v, got %v", repository.ErrUserNotFound, err)
+	}
+	if user != nil {
+		t.Fatalf("GetByEmail on missing user: expected nil user, got %+v", user)
+	}
+}
+
+func testGetByPhone(t *testing.T, repo repository.UserRepository) {
+	want := newTestUser(1)
+	mustCreate(t, repo, want)
+	mustCreate(t, repo, newTestUser(2))
+
+	got, err := repo.GetByPhone(context.Background(), want.Phone)
+	if err != nil {
+		t.Fatalf("GetByPhone: unexpected error: %v", err)
+	}
+	assertUserEqual(t, got, want)
+}
+
+func testGetByPhoneNotFound(t *testing.T, repo repository.UserRepository) {
+	mustCreate(t, repo, newTestUser(1))
+
+	user, err := repo.GetByPhone(context.Background(), "+66899999999")
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		t.Fatalf("GetByPhone on missing user: expected %v, got %v", repository.ErrUserNotFound, err)
+	}
+	if user != nil {
+		t.Fatalf("GetByPhone on missing user: expected nil user, got %+v", user)
+	}
+}
+
+func testGetAllEmpty(t *testing.T, repo repository.UserRepository) {
+	users, err := repo.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	if users == nil {
+		t.Fatal("GetAll on empty repository: expected empty slice, got nil")
+	}
+	if len(users) != 0 {
+		t.Fatalf("GetAll on empty repository: expected 0 users, got %d", len(users))
+	}
+}
+
+func testGetAll(t *testing.T, repo repository.UserRepository) {
+	want := map[string]*entity.User{}
+	for i := 1; i <= 5; i++ {
+		user := newTestUser(i)
+		mustCreate(t, repo, user)
+		want[user.ID] = user
+	}
+
+	users, err := repo.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	if len(users) != len(want) {
+		t.Fatalf("GetAll: expected %d users, got %d", len(want), len(users))
+	}
+	for _, got := range users {
+		expected, ok := want[got.ID]
+		if !ok {
+			t.Fatalf("GetAll: unexpected user %s", got.ID)
+		}
+		assertUserEqual(t, got, expected)
+		delete(want, got.ID)
+	}
+}
+
+func testPointsAreNotStored(t *testing.T, repo repository.UserRepository) {
+	user := newTestUser(1)
+	user.Points = 500
+	mustCreate(t, repo, user)
+
+	got, err := repo.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if got.Points != 0 {
+		t.Fatalf("Create stored points: got %d, want 0", got.Points)
+	}
+
+	got.Points = 700
+	if err := repo.Update(context.Background(), got); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	got, err = repo.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	if got.Points != 0 {
+		t.Fatalf("Update stored points: got %d, want 0", got.Points)
+	}
+}
+
+func testUpdate(t *testing.T, repo repository.UserRepository) {
+	user := newTestUser(1)
+	mustCreate(t, repo, user)
+
+	updated := *user
+	updated.FirstName = "Jane"
+	updated.Phone = "+66899999999"
+	updated.Email = "jane@example.com"
+	updated.MembershipLevel = "Silver"
+	updated.Role = entity.RoleStaff
+	updated.Points = 150
+	if err := repo.Update(context.Background(), &updated); err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+
+	got, err := repo.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	assertUserEqual(t, got, &updated)
+
+	if _, err := repo.GetByEmail(context.Background(), user.Email); err == nil {
+		t.Fatal("GetByEmail with old email: expected error after update, got nil")
+	}
+	got, err = repo.GetByEmail(context.Background(), updated.Email)
+	if err != nil {
+		t.Fatalf("GetByEmail with new email: unexpected error: %v", err)
+	}
+	assertUserEqual(t, got, &updated)
+}
+
+func testUpdateNotFound(t *testing.T, repo repository.UserRepository) {
+	if err := repo.Update(context.Background(), newTestUser(1)); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Fatalf("Update on missing user: expected %v, got %v", repository.ErrUserNotFound, err)
+	}
+
+	if _, err := repo.GetByID(context.Background(), newTestUser(1).ID); err == nil {
+		t.Fatal("Update must not create missing users")
+	}
+}
+
+func testUpdateRejectsTakenEmail(t *testing.T, repo repository.UserRepository) {
+	first := newTestUser(1)
+	second := newTestUser(2)
+	mustCreate(t, repo, first)
+	mustCreate(t, repo, second)
+
+	updated := *second
+	updated.Email = first.Email
+	if err := repo.Update(context.Background(), &updated); !errors.Is(err, repository.ErrEmailTaken) {
+		t.Fatalf("Update to an email owned by another user: expected %v, got %v", repository.ErrEmailTaken, err)
+	}
+
+	got, err := repo.GetByID(context.Background(), second.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	assertUserEqual(t, got, second)
+}
+
+func testUpdateRejectsTakenPhone(t *testing.T, repo repository.UserRepository) {
+	first := newTestUser(1)
+	second := newTestUser(2)
+	mustCreate(t, repo, first)
+	mustCreate(t, repo, second)
+
+	updated := *second
+	updated.Phone = first.Phone
+	if err := repo.Update(context.Background(), &updated); !errors.Is(err, repository.ErrPhoneTaken) {
+		t.Fatalf("Update to a phone owned by another user: expected %v, got %v", repository.ErrPhoneTaken, err)
+	}
+
+	got, err := repo.GetByID(context.Background(), second.ID)
+	if err != nil {
+		t.Fatalf("GetByID: unexpected error: %v", err)
+	}
+	assertUserEqual(t, got, second)
+}
+
+func testUpdateRejectsNil(t *testing.T, repo repository.UserRepository) {
+	if err := repo.Update(context.Background(), nil); !errors.Is(err, repository.ErrNilUser) {
+		t.Fatalf("Update(nil): expected %v, got %v", repository.ErrNilUser, err)
+	}
+}
+
+func testUpdateRejectsEmptyID(t *testing.T, repo repository.UserRepository) {
+	user := newTestUser(1)
+	mustCreate(t, repo, user)
+
+	user.ID = ""
+	if err := repo.Update(context.Background(), user); !errors.Is(err, repository.ErrEmptyUserID) {
+		t.Fatalf("Update with empty ID: expected %v, got %v", repository.ErrEmptyUserID, err)
+	}
+}
+
+func testDelete(t *testing.T, repo repository.UserRepository) {
+	user := newTestUser(1)
+	other := newTestUser(2)
+	mustCreate(t, repo, user)
+	mustCreate(t, repo, other)
+
+	if err := repo.Delete(context.Background(), user.ID); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+
+	if _, err := repo.GetByID(context.Background(), user.ID); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Fatalf("GetByID after Delete: expected %v, got %v", repository.ErrUserNotFound, err)
+	}
+	if _, err := repo.GetByEmail(context.Background(), user.Email); err == nil {
+		t.Fatal("GetByEmail after Delete: expected error, got nil")
+	}
+
+	users, err := repo.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != other.ID {
+		t.Fatalf("GetAll after Delete: expected only %s, got %+v", other.ID, users)
+	}
+
+	// The email of a deleted user can be registered again
+	reused := newTestUser(3)
+	reused.Email = user.Email
+	mustCreate(t, repo, reused)
+}
+
+func testDeleteNotFound(t *testing.T, repo repository.UserRepository) {
+	if err := repo.Delete(context.Background(), "does-not-exist"); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Fatalf("Delete on missing user: expected %v, got %v", repository.ErrUserNotFound, err)
+	}
+}
+
+func testConcurrentAccess(t *testing.T, repo repository.UserRepository) {
+	const workers = 8
+	const perWorker = 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker*4)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				user := newTestUser(w*perWorker + i + 1)
+				if err := repo.Create(context.Background(), user); err != nil {
+					errs <- fmt.Errorf("Create(%s): %w", user.ID, err)
+					continue
+				}
+				got, err := repo.GetByID(context.Background(), user.ID)
+				if err != nil {
+					errs <- fmt.Errorf("GetByID(%s): %w", user.ID, err)
+					continue
+				}
+				// Writing to a returned user must not race with other readers
+				got.Points = -1
+				if _, err := repo.GetByEmail(context.Background(), user.Email); err != nil {
+					errs <- fmt.Errorf("GetByEmail(%s): %w", user.Email, err)
+				}
+				if _, err := repo.GetAll(context.Background()); err != nil {
+					errs <- fmt.Errorf("GetAll: %w", err)
+				}
+				user.Points = i
+				if err := repo.Update(context.Background(), user); err != nil {
+					errs <- fmt.Errorf("Update(%s): %w", user.ID, err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	users, err := repo.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	if len(users) != workers*perWorker {
+		t.Fatalf("GetAll: expected %d users, got %d", workers*perWorker, len(users))
+	}
+}
+
+func testConcurrentCreateSameEmail(t *testing.T, repo repository.UserRepository) {
+	const attempts = 20
+
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := newTestUser(i + 1)
+			user.Email = "same@example.com"
+			results <- repo.Create(context.Background(), user)
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	created := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, repository.ErrEmailTaken):
+			t.Errorf("Create: expected nil or %v, got %v", repository.ErrEmailTaken, err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("expected exactly one user with the shared email, got %d", created)
+	}
+}
+
+func testNextMemberIDIsMonotonic(t *testing.T, repo repository.UserRepository) {
+	previous := ""
+	for i := 0; i < 5; i++ {
+		memberID, err := repo.NextMemberID(context.Background())
+		if err != nil {
+			t.Fatalf("NextMemberID: unexpected error: %v", err)
+		}
+		if memberID <= previous {
+			t.Fatalf("NextMemberID: %q is not greater than previous %q", memberID, previous)
+		}
+		previous = memberID
+	}
+}
+
+func testNextMemberIDNotReusedAfterDelete(t *testing.T, repo repository.UserRepository) {
+	memberID, err := repo.NextMemberID(context.Background())
+	if err != nil {
+		t.Fatalf("NextMemberID: unexpected error: %v", err)
+	}
+
+	user := newTestUser(1)
+	user.MemberID = memberID
+	mustCreate(t, repo, user)
+	if err := repo.Delete(context.Background(), user.ID); err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+
+	next, err := repo.NextMemberID(context.Background())
+	if err != nil {
+		t.Fatalf("NextMemberID: unexpected error: %v", err)
+	}
+	if next == memberID {
+		t.Fatalf("NextMemberID reused %q after delete", memberID)
+	}
+}
+
+func testConcurrentNextMemberID(t *testing.T, repo repository.UserRepository) {
+	const workers = 8
+	const perWorker = 25
+
+	var wg sync.WaitGroup
+	ids := make(chan string, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				memberID, err := repo.NextMemberID(context.Background())
+				if err != nil {
+					t.Errorf("NextMemberID: unexpected error: %v", err)
+					return
+				}
+				ids <- memberID
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool)
+	for memberID := range ids {
+		if seen[memberID] {
+			t.Fatalf("NextMemberID handed out %q twice", memberID)
+		}
+		seen[memberID] = true
+	}
+}
+
+func testCancelledContext(t *testing.T, repo repository.UserRepository) {
+	user := newTestUser(1)
+	mustCreate(t, repo, user)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	updated := *user
+	updated.FirstName = "Jane"
+	assertCancelled(t, []cancelledCall{
+		{"Create", repo.Create(ctx, newTestUser(2))},
+		{"GetByID", errOf(repo.GetByID(ctx, user.ID))},
+		{"GetByEmail", errOf(repo.GetByEmail(ctx, user.Email))},
+		{"GetByPhone", errOf(repo.GetByPhone(ctx, user.Phone))},
+		{"GetAll", errOf(repo.GetAll(ctx))},
+		{"Query", errOf(repo.Query(ctx, repository.UserQuery{Limit: 10}))},
+		{"Update", repo.Update(ctx, &updated)},
+		{"Delete", repo.Delete(ctx, user.ID)},
+		{"NextMemberID", errOf(repo.NextMemberID(ctx))},
+	})
+
+	// None of the writes took effect
+	users, err := repo.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("GetAll: unexpected error: %v", err)
+	}
+	if len(users) != 1 {
+		t.Fatalf("expected 1 user, got %d", len(users))
+	}
+	assertUserEqual(t, users[0], user)
+}
+
+func testExpiredDeadline(t *testing.T, repo repository.UserRepository) {
+	user := newTestUser(1)
+	mustCreate(t, repo, user)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetByID: expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if _, err := repo.Query(ctx, repository.UserQuery{Limit: 10}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Query: expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+// errOf returns the error of a call returning a value and an error
+func errOf[T any](_ T, err error) error {
+	return err
+}
+
+// cancelledCall is the outcome of a repository call made with a cancelled
+// context
+type cancelledCall struct {
+	name string
+	err  error
+}
+
+// assertCancelled checks that every call failed with context.Canceled
+func assertCancelled(t *testing.T, calls []cancelledCall) {
+	t.Helper()
+	for _, call := range calls {
+		if !errors.Is(call.err, context.Canceled) {
+			t.Errorf("%s: expected %v, got %v", call.name, context.Canceled, call.err)
+		}
+	}
+}
+
+// seedQueryUsers stores users with distinct names, levels and registration
+// times one hour apart, starting at base
+func seedQueryUsers(t *testing.T, repo repository.UserRepository, base time.Time) []*entity.User {
+	t.Helper()
+
+	seed := []struct {
+		first, last, level, email, phone string
+	}{
+		{"Somchai", "Jaidee", "Gold", "somchai@example.com", "+66811111111"},
+		{"Somsak", "Rakthai", "Silver", "somsak@example.com", "+66812222222"},
+		{"Anong", "Srisuk", "Gold", "anong@corp.example", "+66823333333"},
+		{"Malee", "Somboon", "Bronze", "malee@corp.example", "+66824444444"},
+		{"John", "Doe", "Silver", "John.Doe@example.com", "+14155550100"},
+	}
+
+	users := make([]*entity.User, 0, len(seed))
+	for i, s := range seed {
+		user := newTestUser(i + 1)
+		user.FirstName, user.LastName = s.first, s.last
+		user.MembershipLevel, user.Email, user.Phone = s.level, s.email, s.phone
+		user.RegisteredAt = base.Add(time.Duration(i) * time.Hour)
+		mustCreate(t, repo, user)
+		users = append(users, user)
+	}
+	return users
+}
+
+// queryIDs runs q and returns the IDs of the page in order
+func queryIDs(t *testing.T, repo repository.UserRepository, q repository.UserQuery) []string {
+	t.Helper()
+	page, err := repo.Query(context.Background(), q)
+	if err != nil {
+		t.Fatalf("Query(%+v): unexpected error: %v", q, err)
+	}
+	ids := make([]string, len(page.Users))
+	for i, user := range page.Users {
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+// assertIDs compares two ordered ID lists
+func assertIDs(t *testing.T, got []string, want ...*entity.User) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d users, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i].ID {
+			t.Fatalf("position %d: expected %s, got %s (all: %v)", i, want[i].ID, got[i], got)
+		}
+	}
+}
+
+func testQueryFilters(t *testing.T, repo repository.UserRepository) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	u := seedQueryUsers(t, repo, base)
+
+	tests := []struct {
+		name  string
+		query repository.UserQuery
+		want  []*entity.User
+	}{
+		{"no filter", repository.UserQuery{}, u},
+		{"membership level", repository.UserQuery{MembershipLevel: "Gold"}, []*entity.User{u[0], u[2]}},
+		{"registered from is inclusive", repository.UserQuery{RegisteredFrom: base.Add(3 * time.Hour)}, []*entity.User{u[3], u[4]}},
+		{"registered to is exclusive", repository.UserQuery{RegisteredTo: base.Add(2 * time.Hour)}, []*entity.User{u[0], u[1]}},
+		{"registered range", repository.UserQuery{RegisteredFrom: base.Add(time.Hour), RegisteredTo: base.Add(3 * time.Hour)}, []*entity.User{u[1], u[2]}},
+		{"first or last name prefix", repository.UserQuery{NamePrefix: "som"}, []*entity.User{u[0], u[1], u[3]}},
+		{"email prefix ignores case", repository.UserQuery{EmailPrefix: "john.d"}, []*entity.User{u[4]}},
+		{"phone prefix", repository.UserQuery{PhonePrefix: "+6682"}, []*entity.User{u[2], u[3]}},
+		{"wildcards are literal", repository.UserQuery{NamePrefix: "%"}, nil},
+		{"combined", repository.UserQuery{MembershipLevel: "Silver", NamePrefix: "so"}, []*entity.User{u[1]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Limit = 100
+			assertIDs(t, queryIDs(t, repo, tt.query), tt.want...)
+		})
+	}
+}
+
+func testQueryNonASCIINamePrefix(t *testing.T, repo repository.UserRepository) {
+	seed := [][2]string{{"สมชาย", "ใจดี"}, {"Émile", "Durand"}, {"émilie", "Martin"}}
+	u := make([]*entity.User, len(seed))
+	for i, names := range seed {
+		u[i] = newTestUser(i + 1)
+		u[i].FirstName, u[i].LastName = names[0], names[1]
+		u[i].RegisteredAt = time.Date(2024, 1, 1, i, 0, 0, 0, time.UTC)
+		mustCreate(t, repo, u[i])
+	}
+
+	// Only ASCII letters are folded, as by SQL LIKE, so every backend
+	// matches the same users
+	tests := []struct {
+		name   string
+		prefix string
+		want   []*entity.User
+	}{
+		{"thai first name", "สม", []*entity.User{u[0]}},
+		{"thai last name", "ใจ", []*entity.User{u[0]}},
+		{"single thai character", "ส", []*entity.User{u[0]}},
+		{"upper case non-ASCII letter", "Émi", []*entity.User{u[1]}},
+		{"lower case non-ASCII letter", "émi", []*entity.User{u[2]}},
+		{"ASCII letters after a non-ASCII one are folded", "ÉMI", []*entity.User{u[1]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertIDs(t, queryIDs(t, repo, repository.UserQuery{NamePrefix: tt.prefix, Limit: 100}), tt.want...)
+		})
+	}
+}
+
+func testQuerySortOrder(t *testing.T, repo repository.UserRepository) {
+	u := seedQueryUsers(t, repo, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	assertIDs(t, queryIDs(t, repo, repository.UserQuery{Limit: 100, Descending: true}),
+		u[4], u[3], u[2], u[1], u[0])
+	assertIDs(t, queryIDs(t, repo, repository.UserQuery{Limit: 100, SortBy: repository.SortByFirstName}),
+		u[2], u[4], u[3], u[0], u[1])
+	assertIDs(t, queryIDs(t, repo, repository.UserQuery{Limit: 100, SortBy: repository.SortByLastName, Descending: true}),
+		u[2], u[3], u[1], u[0], u[4])
+}
+
+func testQueryPaginates(t *testing.T, repo repository.UserRepository) {
+	// Identical registration times force the ID tie-breaker to keep the
+	// order stable across pages
+	registeredAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := make(map[string]bool)
+	for i := 1; i <= 23; i++ {
+		user := newTestUser(i)
+		if i%2 == 0 {
+			user.RegisteredAt = registeredAt
+		}
+		mustCreate(t, repo, user)
+		want[user.ID] = true
+	}
+
+	for _, descending := range []bool{false, true} {
+		seen := make(map[string]bool)
+		q := repository.UserQuery{Limit: 5, Descending: descending}
+		var previous *entity.User
+		for pages := 0; ; pages++ {
+			if pages > 10 {
+				t.Fatal("pagination did not terminate")
+			}
+
+			page, err := repo.Query(context.Background(), q)
+			if err != nil {
+				t.Fatalf("Query: unexpected error: %v", err)
+			}
+			for _, user := range page.Users {
+				if seen[user.ID] {
+					t.Fatalf("user %s returned twice", user.ID)
+				}
+				seen[user.ID] = true
+				if previous != nil && !inOrder(previous, user, descending) {
+					t.Fatalf("users out of order: %s then %s", previous.ID, user.ID)
+				}
+				previous = user
+			}
+			if page.Next == nil {
+				break
+			}
+			if len(page.Users) != q.Limit {
+				t.Fatalf("non-final page has %d users, want %d", len(page.Users), q.Limit)
+			}
+			q.After = page.Next
+		}
+
+		if len(seen) != len(want) {
+			t.Fatalf("descending=%v: paged through %d users, want %d", descending, len(seen), len(want))
+		}
+	}
+}
+
+// inOrder reports whether b may follow a when sorting by registration time
+func inOrder(a, b *entity.User, descending bool) bool {
+	if a.RegisteredAt.Equal(b.RegisteredAt) {
+		return (a.ID < b.ID) != descending
+	}
+	return a.RegisteredAt.Before(b.RegisteredAt) != descending
+}
+
+func testQueryCursorStableAcrossInserts(t *testing.T, repo repository.UserRepository) {
+	u := seedQueryUsers(t, repo, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	q := repository.UserQuery{Limit: 2}
+	page, err := repo.Query(context.Background(), q)
+	if err != nil {
+		t.Fatalf("Query: unexpected error: %v", err)
+	}
+
+	// A user registered before the cursor must not shift the next page
+	early := newTestUser(99)
+	early.RegisteredAt = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	mustCreate(t, repo, early)
+
+	q.After = page.Next
+	assertIDs(t, queryIDs(t, repo, q), u[2], u[3])
+}
+
+func testQueryRejectsInvalid(t *testing.T, repo repository.UserRepository) {
+	invalid := []repository.UserQuery{
+		{Limit: 0},
+		{Limit: 10, SortBy: "password"},
+		{Limit: 10, After: &repository.UserCursor{Value: "not-a-time", ID: "x"}},
+	}
+	for _, q := range invalid {
+		if _, err := repo.Query(context.Background(), q); !errors.Is(err, apperror.ErrValidation) {
+			t.Errorf("Query(%+v): expected a validation error, got %v", q, err)
+		}
+	}
+}
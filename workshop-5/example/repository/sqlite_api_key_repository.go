@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/mike/entity"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteAPIKeyRepository implements APIKeyRepository using a SQLite
+// database
+type sqliteAPIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteAPIKeyRepository creates a new SQLite-backed API key repository.
+// The database must already be migrated, see OpenSQLite.
+func NewSQLiteAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &sqliteAPIKeyRepository{
+		db: db,
+	}
+}
+
+const apiKeyColumns = `id, name, scopes, rate_limit, salt, previous_salt, previous_expires_at,
+	created_by, created_at, rotated_at, revoked_at`
+
+// Create stores a new key
+func (r *sqliteAPIKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	if err := validateAPIKey(key); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO api_keys (`+apiKeyColumns+`) VALUES (?, ?, ?, ?, ?, '', NULL, ?, ?, NULL, NULL)`,
+		key.ID, key.Name, joinScopes(key.Scopes), key.RateLimit, key.Salt, key.CreatedBy, key.CreatedAt.UTC(),
+	)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return ErrAPIKeyExists
+		}
+		return fmt.Errorf("create API key: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a key by ID
+func (r *sqliteAPIKeyRepository) GetByID(ctx context.Context, id string) (*entity.APIKey, error) {
+	return scanAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id))
+}
+
+// List returns every key, oldest first
+func (r *sqliteAPIKeyRepository) List(ctx context.Context) ([]*entity.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*entity.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Rotate replaces the salt of an active key with a conditional update
+func (r *sqliteAPIKeyRepository) Rotate(ctx context.Context, id, salt string, previousExpiresAt, now time.Time) (*entity.APIKey, error) {
+	return r.update(ctx, id, ErrAPIKeyRevoked,
+		`UPDATE api_keys SET previous_salt = salt, previous_expires_at = ?, salt = ?, rotated_at = ?
+		WHERE id = ? AND revoked_at IS NULL`,
+		previousExpiresAt.UTC(), salt, now.UTC(), id,
+	)
+}
+
+// Revoke revokes a key unless it already is
+func (r *sqliteAPIKeyRepository) Revoke(ctx context.Context, id string, now time.Time) (*entity.APIKey, error) {
+	return r.update(ctx, id, nil,
+		`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`,
+		now.UTC(), id,
+	)
+}
+
+// UseNonce records a nonce, relying on the primary key to refuse repeats
+func (r *sqliteAPIKeyRepository) UseNonce(ctx context.Context, keyID, nonce string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO api_key_nonces (key_id, nonce, expires_at) VALUES (?, ?, ?)`,
+		keyID, nonce, expiresAt.UTC(),
+	)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return ErrNonceUsed
+		}
+		return fmt.Errorf("use API key nonce: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredNonces deletes nonces expired at now
+func (r *sqliteAPIKeyRepository) DeleteExpiredNonces(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM api_key_nonces WHERE expires_at <= ?`, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("delete expired API key nonces: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete expired API key nonces: %w", err)
+	}
+	return int(deleted), nil
+}
+
+// update runs a conditional update of key id and returns the key. When
+// the condition excludes the key, excluded is returned, or the unchanged
+// key if excluded is nil.
+func (r *sqliteAPIKeyRepository) update(ctx context.Context, id string, excluded error, query string, args ...any) (*entity.APIKey, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("update API key: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("update API key: %w", err)
+	}
+	key, err := scanAPIKey(tx.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+	if excluded != nil {
+		if err := requireAffected(result, excluded); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("update API key: %w", err)
+	}
+	return key, nil
+}
+
+// scanAPIKey reads a single key from row
+func scanAPIKey(row rowScanner) (*entity.APIKey, error) {
+	var key entity.APIKey
+	var scopes string
+	var previousExpiresAt, rotatedAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&key.ID, &key.Name, &scopes, &key.RateLimit, &key.Salt, &key.PreviousSalt, &previousExpiresAt,
+		&key.CreatedBy, &key.CreatedAt, &rotatedAt, &revokedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan API key: %w", err)
+	}
+	for _, scope := range strings.Fields(scopes) {
+		key.Scopes = append(key.Scopes, entity.APIScope(scope))
+	}
+	if previousExpiresAt.Valid {
+		key.PreviousExpiresAt = &previousExpiresAt.Time
+	}
+	if rotatedAt.Valid {
+		key.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
+
+// joinScopes stores scopes space-separated
+func joinScopes(scopes []entity.APIScope) string {
+	parts := make([]string, len(scopes))
+	for i, scope := range scopes {
+		parts[i] = string(scope)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,21 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteAPIKeyRepository(t *testing.T) {
+	repositorytest.RunAPIKeyRepositoryTests(t, func(t *testing.T) repository.APIKeyRepository {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "api_keys.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repository.NewSQLiteAPIKeyRepository(db)
+	})
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"example.com/mike/entity"
+)
+
+// sqliteCartRepository implements CartRepository using a SQLite database
+type sqliteCartRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteCartRepository creates a new SQLite-backed cart repository. The
+// database must already be migrated, see OpenSQLite.
+func NewSQLiteCartRepository(db *sql.DB) CartRepository {
+	return &sqliteCartRepository{
+		db: db,
+	}
+}
+
+// Get retrieves a member's cart, or an empty one
+func (r *sqliteCartRepository) Get(ctx context.Context, userID string) (*entity.Cart, error) {
+	cart := entity.NewCart(userID)
+	err := r.db.QueryRowContext(ctx,
+		`SELECT customer_name, customer_phone, updated_at FROM carts WHERE user_id = ?`, userID,
+	).Scan(&cart.CustomerName, &cart.CustomerPhone, &cart.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cart, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get cart: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT product_id, quantity FROM cart_items WHERE user_id = ? ORDER BY position`, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get cart items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item entity.CartItem
+		if err := rows.Scan(&item.ProductID, &item.Quantity); err != nil {
+			return nil, fmt.Errorf("get cart items: %w", err)
+		}
+		cart.Items = append(cart.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get cart items: %w", err)
+	}
+	return cart, nil
+}
+
+// Save replaces a member's cart and its items in one transaction
+func (r *sqliteCartRepository) Save(ctx context.Context, cart *entity.Cart) error {
+	if err := validateCart(cart); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("save cart: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO carts (user_id, customer_name, customer_phone, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			customer_name = excluded.customer_name,
+			customer_phone = excluded.customer_phone,
+			updated_at = excluded.updated_at`,
+		cart.UserID, cart.CustomerName, cart.CustomerPhone, cart.UpdatedAt.UTC(),
+	); err != nil {
+		return fmt.Errorf("save cart: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE user_id = ?`, cart.UserID); err != nil {
+		return fmt.Errorf("save cart items: %w", err)
+	}
+	for i, item := range cart.Items {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO cart_items (user_id, product_id, quantity, position) VALUES (?, ?, ?, ?)`,
+			cart.UserID, item.ProductID, item.Quantity, i,
+		); err != nil {
+			return fmt.Errorf("save cart items: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("save cart: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a member's cart and its items
+func (r *sqliteCartRepository) Delete(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("delete cart: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteCart(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete cart: %w", err)
+	}
+	return nil
+}
+
+// deleteCart removes a member's cart and its items inside tx
+func deleteCart(ctx context.Context, tx *sql.Tx, userID string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("delete cart items: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM carts WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("delete cart: %w", err)
+	}
+	return nil
+}
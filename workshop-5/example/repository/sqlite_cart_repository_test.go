@@ -0,0 +1,21 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteCartRepository(t *testing.T) {
+	repositorytest.RunCartRepositoryTests(t, func(t *testing.T) repository.CartRepository {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "carts.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repository.NewSQLiteCartRepository(db)
+	})
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/mike/entity"
+)
+
+// sqliteIdempotencyRepository implements IdempotencyRepository using a
+// SQLite database
+type sqliteIdempotencyRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteIdempotencyRepository creates a new SQLite-backed idempotency
+// repository. The database must already be migrated, see OpenSQLite.
+func NewSQLiteIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &sqliteIdempotencyRepository{
+		db: db,
+	}
+}
+
+const idempotencyColumns = `key, request_hash, completed, status_code, content_type, body, created_at, expires_at`
+
+// Reserve stores a new record unless an unexpired one exists for its key
+func (r *sqliteIdempotencyRepository) Reserve(ctx context.Context, record *entity.IdempotencyRecord) (*entity.IdempotencyRecord, error) {
+	if err := validateIdempotencyRecord(record); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+	defer tx.Rollback()
+
+	existing, err := getIdempotencyRecord(ctx, tx, record.Key)
+	switch {
+	case err == nil && !existing.Expired(record.CreatedAt):
+		return existing, ErrIdempotencyKeyExists
+	case err != nil && !errors.Is(err, ErrIdempotencyKeyNotFound):
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT OR REPLACE INTO idempotency_keys (`+idempotencyColumns+`) VALUES (?, ?, FALSE, 0, '', NULL, ?, ?)`,
+		record.Key, record.RequestHash, record.CreatedAt.UTC(), record.ExpiresAt.UTC(),
+	); err != nil {
+		return nil, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+	return nil, nil
+}
+
+// Complete stores the response of a reserved record
+func (r *sqliteIdempotencyRepository) Complete(ctx context.Context, record *entity.IdempotencyRecord) error {
+	if err := validateIdempotencyRecord(record); err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET completed = TRUE, status_code = ?, content_type = ?, body = ?
+		WHERE key = ? AND request_hash = ? AND NOT completed`,
+		record.StatusCode, record.ContentType, record.Body, record.Key, record.RequestHash,
+	)
+	if err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return requireAffected(result, ErrIdempotencyKeyNotFound)
+}
+
+// Release removes an uncompleted reservation
+func (r *sqliteIdempotencyRepository) Release(ctx context.Context, key string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = ? AND NOT completed`, key)
+	if err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return requireAffected(result, ErrIdempotencyKeyNotFound)
+}
+
+// DeleteExpired removes every record expired at now
+func (r *sqliteIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= ?`, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", err)
+	}
+	return int(removed), nil
+}
+
+// getIdempotencyRecord reads a single record
+func getIdempotencyRecord(ctx context.Context, q querier, key string) (*entity.IdempotencyRecord, error) {
+	var record entity.IdempotencyRecord
+	err := q.QueryRowContext(ctx, `SELECT `+idempotencyColumns+` FROM idempotency_keys WHERE key = ?`, key).Scan(
+		&record.Key, &record.RequestHash, &record.Completed, &record.StatusCode, &record.ContentType,
+		&record.Body, &record.CreatedAt, &record.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get idempotency key: %w", err)
+	}
+	return &record, nil
+}
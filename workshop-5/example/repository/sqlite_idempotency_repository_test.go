@@ -0,0 +1,21 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteIdempotencyRepository(t *testing.T) {
+	repositorytest.RunIdempotencyRepositoryTests(t, func(t *testing.T) repository.IdempotencyRepository {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "idempotency.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repository.NewSQLiteIdempotencyRepository(db)
+	})
+}
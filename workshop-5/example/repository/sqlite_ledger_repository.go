@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/mike/entity"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteLedgerRepository implements LedgerRepository using a SQLite
+// database. Immutability is enforced by triggers on ledger_entries.
+type sqliteLedgerRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteLedgerRepository creates a new SQLite-backed ledger repository.
+// The database must already be migrated, see OpenSQLite.
+func NewSQLiteLedgerRepository(db *sql.DB) LedgerRepository {
+	return &sqliteLedgerRepository{
+		db: db,
+	}
+}
+
+const ledgerEntryColumns = `id, posting_id, account_id, type, amount, counterparty_id, order_id, transfer_id, memo, created_at`
+
+// Post writes every entry of a balanced posting in one transaction
+func (r *sqliteLedgerRepository) Post(ctx context.Context, posting *entity.Posting) error {
+	if err := validatePosting(posting); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("post ledger entries: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := postEntries(ctx, tx, posting); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("post ledger entries: %w", err)
+	}
+	return nil
+}
+
+// postEntries checks balances and inserts the entries of a validated
+// posting inside tx. The balance check and the inserts share the
+// transaction, so concurrent debits cannot overdraw an account.
+func postEntries(ctx context.Context, tx *sql.Tx, posting *entity.Posting) error {
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM ledger_entries WHERE posting_id = ?)`, posting.ID,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("post ledger entries: %w", err)
+	}
+	if exists {
+		return ErrPostingExists
+	}
+
+	for _, e := range posting.Entries {
+		if e.Amount > 0 || entity.IsSystemAccount(e.AccountID) {
+			continue
+		}
+		balance, err := balance(ctx, tx, e.AccountID)
+		if err != nil {
+			return err
+		}
+		if balance+e.Amount < 0 {
+			return ErrInsufficientBalance
+		}
+	}
+
+	for _, e := range posting.Entries {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO ledger_entries (`+ledgerEntryColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.ID, e.PostingID, e.AccountID, string(e.Type), e.Amount,
+			e.CounterpartyID, e.OrderID, e.TransferID, e.Memo, e.CreatedAt.UTC(),
+		); err != nil {
+			return fmt.Errorf("post ledger entries: %w", translateLedgerConstraintError(err))
+		}
+	}
+	return nil
+}
+
+// GetPosting retrieves a posting with all of its entries
+func (r *sqliteLedgerRepository) GetPosting(ctx context.Context, id string) (*entity.Posting, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+ledgerEntryColumns+` FROM ledger_entries WHERE posting_id = ? ORDER BY id`, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get posting: %w", err)
+	}
+	defer rows.Close()
+
+	posting := &entity.Posting{ID: id}
+	for rows.Next() {
+		entry, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		posting.Entries = append(posting.Entries, *entry)
+		posting.CreatedAt = entry.CreatedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get posting: %w", err)
+	}
+	if len(posting.Entries) == 0 {
+		return nil, ErrPostingNotFound
+	}
+	return posting, nil
+}
+
+// GetEntry retrieves a single ledger entry by ID
+func (r *sqliteLedgerRepository) GetEntry(ctx context.Context, id string) (*entity.LedgerEntry, error) {
+	entry, err := scanLedgerEntry(r.db.QueryRowContext(ctx,
+		`SELECT `+ledgerEntryColumns+` FROM ledger_entries WHERE id = ?`, id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrEntryNotFound
+	}
+	return entry, err
+}
+
+// Balance returns the sum of an account's entries
+func (r *sqliteLedgerRepository) Balance(ctx context.Context, accountID string) (int, error) {
+	var sum int
+	if err := r.db.QueryRowContext(ctx, balanceQuery, accountID).Scan(&sum); err != nil {
+		return 0, fmt.Errorf("get balance: %w", err)
+	}
+	return sum, nil
+}
+
+// Balances returns the balances of several accounts at once
+func (r *sqliteLedgerRepository) Balances(ctx context.Context, accountIDs []string) (map[string]int, error) {
+	balances := make(map[string]int, len(accountIDs))
+	if len(accountIDs) == 0 {
+		return balances, nil
+	}
+
+	args := make([]any, len(accountIDs))
+	for i, id := range accountIDs {
+		args[i] = id
+		balances[id] = 0
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT account_id, SUM(amount) FROM ledger_entries
+		WHERE account_id IN (?`+strings.Repeat(", ?", len(accountIDs)-1)+`)
+		GROUP BY account_id`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get balances: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var sum int
+		if err := rows.Scan(&id, &sum); err != nil {
+			return nil, fmt.Errorf("get balances: %w", err)
+		}
+		balances[id] = sum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get balances: %w", err)
+	}
+	return balances, nil
+}
+
+// Entries retrieves a page of an account's entries, newest first, using
+// the (account_id, created_at, id) index
+func (r *sqliteLedgerRepository) Entries(ctx context.Context, q LedgerQuery) (*LedgerPage, error) {
+	q, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	where := []string{"account_id = ?"}
+	args := []any{q.AccountID}
+	if len(q.Types) > 0 {
+		where = append(where, "type IN (?"+strings.Repeat(", ?", len(q.Types)-1)+")")
+		for _, t := range q.Types {
+			args = append(args, string(t))
+		}
+	}
+	if q.After != nil {
+		// Already validated by LedgerQuery.normalize
+		createdAt, _ := time.Parse(cursorTimeFormat, q.After.CreatedAt)
+		where = append(where, "(created_at < ? OR (created_at = ? AND id < ?))")
+		args = append(args, createdAt.UTC(), createdAt.UTC(), q.After.ID)
+	}
+	args = append(args, q.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+ledgerEntryColumns+` FROM ledger_entries
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC, id DESC LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*entity.LedgerEntry, 0, q.Limit)
+	for rows.Next() {
+		entry, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query ledger entries: %w", err)
+	}
+
+	page := &LedgerPage{Entries: entries}
+	if len(entries) > q.Limit {
+		page.Entries = entries[:q.Limit]
+		page.Next = LedgerCursorFor(page.Entries[q.Limit-1])
+	}
+	return page, nil
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// balanceQuery sums an account's entries
+const balanceQuery = `SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE account_id = ?`
+
+// balance sums an account's entries
+func balance(ctx context.Context, q querier, accountID string) (int, error) {
+	var sum int
+	if err := q.QueryRowContext(ctx, balanceQuery, accountID).Scan(&sum); err != nil {
+		return 0, fmt.Errorf("get balance: %w", err)
+	}
+	return sum, nil
+}
+
+// scanLedgerEntry reads a single ledger_entries row into an entity. It
+// returns sql.ErrNoRows unwrapped so callers can pick the not-found error.
+func scanLedgerEntry(row rowScanner) (*entity.LedgerEntry, error) {
+	var entry entity.LedgerEntry
+	var entryType string
+	err := row.Scan(
+		&entry.ID, &entry.PostingID, &entry.AccountID, &entryType, &entry.Amount,
+		&entry.CounterpartyID, &entry.OrderID, &entry.TransferID, &entry.Memo, &entry.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan ledger entry: %w", err)
+	}
+	entry.Type = entity.EntryType(entryType)
+	return &entry, nil
+}
+
+// translateLedgerConstraintError maps duplicate entry IDs to ErrPostingExists
+func translateLedgerConstraintError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+			return ErrPostingExists
+		}
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteLedgerRepository(t *testing.T) {
+	repositorytest.RunLedgerRepositoryTests(t, func(t *testing.T) repository.LedgerRepository {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "ledger.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repository.NewSQLiteLedgerRepository(db)
+	})
+}
+
+func TestSQLiteLedgerEntriesAreImmutable(t *testing.T) {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "ledger.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewSQLiteLedgerRepository(db)
+	if err := repo.Post(context.Background(), entity.NewEarnPosting("p1", "alice", 100, "")); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	if _, err := db.Exec(`UPDATE ledger_entries SET amount = 1000000`); err == nil {
+		t.Fatal("UPDATE ledger_entries: expected the trigger to abort")
+	}
+	if _, err := db.Exec(`DELETE FROM ledger_entries`); err == nil {
+		t.Fatal("DELETE FROM ledger_entries: expected the trigger to abort")
+	}
+}
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"example.com/mike/entity"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteOrderRepository implements OrderRepository using a SQLite database
+// shared with the products, ledger, carts and outbox, so an order, its
+// stock, its payment posting, the cart and the outbox messages announcing
+// the order are written in the same transaction
+type sqliteOrderRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteOrderRepository creates a new SQLite-backed order repository.
+// The database must already be migrated, see OpenSQLite.
+func NewSQLiteOrderRepository(db *sql.DB) OrderRepository {
+	return &sqliteOrderRepository{
+		db: db,
+	}
+}
+
+const (
+	orderColumns   = `id, user_id, total, customer_name, customer_phone, status, created_at, updated_at`
+	paymentColumns = `id, order_id, user_id, amount, method, status, created_at`
+)
+
+// Place reserves stock, posts the payment, stores the order, its events and
+// the payment, deletes the cart and enqueues the messages in one
+// transaction
+func (r *sqliteOrderRepository) Place(ctx context.Context, order *entity.Order, payment *entity.Payment, messages ...*entity.OutboxMessage) error {
+	if err := validateOrder(order, payment, messages); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("place order: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := reserveStock(ctx, tx, order.Quantities()); err != nil {
+		return err
+	}
+	if err := postEntries(ctx, tx, payment.Posting()); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO orders (`+orderColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		order.ID, order.UserID, order.Total, order.CustomerName, order.CustomerPhone,
+		string(order.Status), order.CreatedAt.UTC(), order.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return ErrOrderExists
+		}
+		return fmt.Errorf("create order: %w", err)
+	}
+	for i, item := range order.Items {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO order_items (order_id, position, product_id, name, unit_price, quantity, line_total)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			order.ID, i, item.ProductID, item.Name, item.UnitPrice, item.Quantity, item.LineTotal,
+		); err != nil {
+			return fmt.Errorf("create order items: %w", err)
+		}
+	}
+	for i, event := range order.Events {
+		if err := insertOrderEvent(ctx, tx, order.ID, i, event); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO payments (`+paymentColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		payment.ID, payment.OrderID, payment.UserID, payment.Amount,
+		string(payment.Method), string(payment.Status), payment.CreatedAt.UTC(),
+	); err != nil {
+		return fmt.Errorf("create payment: %w", err)
+	}
+	if err := deleteCart(ctx, tx, order.UserID); err != nil {
+		return err
+	}
+	if err := insertOutboxMessages(ctx, tx, messages); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("place order: %w", err)
+	}
+	return nil
+}
+
+// Transition changes the order status, posts the payment reversal and
+// restocks as requested, all in one transaction
+func (r *sqliteOrderRepository) Transition(ctx context.Context, t OrderTransition) (*entity.Order, error) {
+	if err := validateOrderTransition(t); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("transition order: %w", err)
+	}
+	defer tx.Rollback()
+
+	order, err := getOrder(ctx, tx, t.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != t.Event.From {
+		return nil, ErrOrderStatusChanged
+	}
+
+	if t.RefundPayment {
+		payment, err := getPayment(ctx, tx, order.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := postEntries(ctx, tx, payment.Reversal()); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE payments SET status = ? WHERE id = ?`, string(entity.PaymentRefunded), payment.ID,
+		); err != nil {
+			return nil, fmt.Errorf("refund payment: %w", err)
+		}
+	}
+	if t.Restock {
+		if err := restock(ctx, tx, order.Quantities()); err != nil {
+			return nil, err
+		}
+	}
+
+	order.Status = t.Event.To
+	order.UpdatedAt = t.Event.CreatedAt
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE orders SET status = ?, updated_at = ? WHERE id = ?`,
+		string(order.Status), order.UpdatedAt.UTC(), order.ID,
+	); err != nil {
+		return nil, fmt.Errorf("transition order: %w", err)
+	}
+	if err := insertOrderEvent(ctx, tx, order.ID, len(order.Events), t.Event); err != nil {
+		return nil, err
+	}
+	order.Events = append(order.Events, t.Event)
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transition order: %w", err)
+	}
+	return order, nil
+}
+
+// GetByID retrieves an order with its items and events
+func (r *sqliteOrderRepository) GetByID(ctx context.Context, id string) (*entity.Order, error) {
+	return getOrder(ctx, r.db, id)
+}
+
+// GetPayment retrieves the payment of an order
+func (r *sqliteOrderRepository) GetPayment(ctx context.Context, orderID string) (*entity.Payment, error) {
+	return getPayment(ctx, r.db, orderID)
+}
+
+// rowsQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowsQuerier interface {
+	querier
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// getOrder reads an order with its items and events
+func getOrder(ctx context.Context, q rowsQuerier, id string) (*entity.Order, error) {
+	var order entity.Order
+	var status string
+	err := q.QueryRowContext(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = ?`, id).Scan(
+		&order.ID, &order.UserID, &order.Total, &order.CustomerName, &order.CustomerPhone,
+		&status, &order.CreatedAt, &order.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get order: %w", err)
+	}
+	order.Status = entity.OrderStatus(status)
+
+	if order.Items, err = getOrderItems(ctx, q, id); err != nil {
+		return nil, err
+	}
+	if order.Events, err = getOrderEvents(ctx, q, id); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// getOrderItems reads the items of an order in order
+func getOrderItems(ctx context.Context, q rowsQuerier, orderID string) ([]entity.OrderItem, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT product_id, name, unit_price, quantity, line_total FROM order_items
+		WHERE order_id = ? ORDER BY position`, orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get order items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []entity.OrderItem{}
+	for rows.Next() {
+		var item entity.OrderItem
+		if err := rows.Scan(&item.ProductID, &item.Name, &item.UnitPrice, &item.Quantity, &item.LineTotal); err != nil {
+			return nil, fmt.Errorf("get order items: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get order items: %w", err)
+	}
+	return items, nil
+}
+
+// getOrderEvents reads the status changes of an order, oldest first
+func getOrderEvents(ctx context.Context, q rowsQuerier, orderID string) ([]entity.OrderEvent, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT from_status, to_status, actor, reason, created_at FROM order_events
+		WHERE order_id = ? ORDER BY position`, orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get order events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []entity.OrderEvent{}
+	for rows.Next() {
+		var event entity.OrderEvent
+		var from, to string
+		if err := rows.Scan(&from, &to, &event.Actor, &event.Reason, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("get order events: %w", err)
+		}
+		event.From, event.To = entity.OrderStatus(from), entity.OrderStatus(to)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get order events: %w", err)
+	}
+	return events, nil
+}
+
+// insertOrderEvent stores the event at position in the order's history
+// inside tx
+func insertOrderEvent(ctx context.Context, tx *sql.Tx, orderID string, position int, event entity.OrderEvent) error {
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO order_events (order_id, position, from_status, to_status, actor, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		orderID, position, string(event.From), string(event.To), event.Actor, event.Reason, event.CreatedAt.UTC(),
+	); err != nil {
+		return fmt.Errorf("create order event: %w", err)
+	}
+	return nil
+}
+
+// getPayment reads the payment of an order
+func getPayment(ctx context.Context, q querier, orderID string) (*entity.Payment, error) {
+	var payment entity.Payment
+	var method, status string
+	err := q.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM payments WHERE order_id = ?`, orderID).Scan(
+		&payment.ID, &payment.OrderID, &payment.UserID, &payment.Amount, &method, &status, &payment.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get payment: %w", err)
+	}
+	payment.Method = entity.PaymentMethod(method)
+	payment.Status = entity.PaymentStatus(status)
+	return &payment, nil
+}
@@ -0,0 +1,27 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteOrderRepository(t *testing.T) {
+	repositorytest.RunOrderRepositoryTests(t, func(t *testing.T) repositorytest.OrderRepositories {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "orders.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repositorytest.OrderRepositories{
+			Orders:   repository.NewSQLiteOrderRepository(db),
+			Products: repository.NewSQLiteProductRepository(db),
+			Ledger:   repository.NewSQLiteLedgerRepository(db),
+			Carts:    repository.NewSQLiteCartRepository(db),
+			Outbox:   repository.NewSQLiteOutboxRepository(db),
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/mike/entity"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteOTPRepository implements OTPRepository using a SQLite database
+type sqliteOTPRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteOTPRepository creates a new SQLite-backed OTP challenge
+// repository. The database must already be migrated, see OpenSQLite.
+func NewSQLiteOTPRepository(db *sql.DB) OTPRepository {
+	return &sqliteOTPRepository{
+		db: db,
+	}
+}
+
+const otpChallengeColumns = `id, user_id, channel, destination, code_hash, attempts, expires_at, consumed_at, created_at`
+
+// Create stores a new challenge
+func (r *sqliteOTPRepository) Create(ctx context.Context, challenge *entity.OTPChallenge) error {
+	if err := validateOTPChallenge(challenge); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO otp_challenges (`+otpChallengeColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, NULL, ?)`,
+		challenge.ID, challenge.UserID, string(challenge.Channel), challenge.Destination, challenge.CodeHash,
+		challenge.Attempts, challenge.ExpiresAt.UTC(), challenge.CreatedAt.UTC(),
+	)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return ErrOTPChallengeExists
+		}
+		return fmt.Errorf("create OTP challenge: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a challenge by ID
+func (r *sqliteOTPRepository) GetByID(ctx context.Context, id string) (*entity.OTPChallenge, error) {
+	return scanOTPChallenge(r.db.QueryRowContext(ctx, `SELECT `+otpChallengeColumns+` FROM otp_challenges WHERE id = ?`, id))
+}
+
+// Attempt counts a code attempt with a conditional update, so the checks
+// and the increment happen in one statement
+func (r *sqliteOTPRepository) Attempt(ctx context.Context, id string, now time.Time, maxAttempts int) (*entity.OTPChallenge, error) {
+	return r.update(ctx, id,
+		`UPDATE otp_challenges SET attempts = attempts + 1
+		WHERE id = ? AND consumed_at IS NULL AND expires_at > ? AND attempts < ?`,
+		id, now.UTC(), maxAttempts,
+	)
+}
+
+// Consume marks a challenge as used with a conditional update
+func (r *sqliteOTPRepository) Consume(ctx context.Context, id string, now time.Time) (*entity.OTPChallenge, error) {
+	return r.update(ctx, id,
+		`UPDATE otp_challenges SET consumed_at = ? WHERE id = ? AND consumed_at IS NULL`,
+		now.UTC(), id,
+	)
+}
+
+// DeleteExpired deletes challenges expired at now
+func (r *sqliteOTPRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM otp_challenges WHERE expires_at <= ?`, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("delete expired OTP challenges: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete expired OTP challenges: %w", err)
+	}
+	return int(deleted), nil
+}
+
+// update runs a conditional update of challenge id and returns the
+// challenge, telling a missing challenge from one the condition excluded
+func (r *sqliteOTPRepository) update(ctx context.Context, id, query string, args ...any) (*entity.OTPChallenge, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("update OTP challenge: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("update OTP challenge: %w", err)
+	}
+	challenge, err := scanOTPChallenge(tx.QueryRowContext(ctx, `SELECT `+otpChallengeColumns+` FROM otp_challenges WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := requireAffected(result, ErrOTPChallengeClosed); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("update OTP challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// scanOTPChallenge reads a single challenge from row
+func scanOTPChallenge(row rowScanner) (*entity.OTPChallenge, error) {
+	var challenge entity.OTPChallenge
+	var channel string
+	var consumedAt sql.NullTime
+	err := row.Scan(
+		&challenge.ID, &challenge.UserID, &channel, &challenge.Destination, &challenge.CodeHash,
+		&challenge.Attempts, &challenge.ExpiresAt, &consumedAt, &challenge.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOTPChallengeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan OTP challenge: %w", err)
+	}
+	challenge.Channel = entity.OTPChannel(channel)
+	if consumedAt.Valid {
+		challenge.ConsumedAt = &consumedAt.Time
+	}
+	return &challenge, nil
+}
@@ -0,0 +1,21 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteOTPRepository(t *testing.T) {
+	repositorytest.RunOTPRepositoryTests(t, func(t *testing.T) repository.OTPRepository {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "otp_challenges.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repository.NewSQLiteOTPRepository(db)
+	})
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/mike/entity"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteOutboxRepository implements OutboxRepository using a SQLite
+// database shared with the repositories that write messages, so a message
+// is stored in the same transaction as the change it reports
+type sqliteOutboxRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteOutboxRepository creates a new SQLite-backed outbox repository.
+// The database must already be migrated, see OpenSQLite.
+func NewSQLiteOutboxRepository(db *sql.DB) OutboxRepository {
+	return &sqliteOutboxRepository{
+		db: db,
+	}
+}
+
+const outboxColumns = `id, topic, payload, status, attempts, next_attempt_at, last_error, created_at, updated_at`
+
+// Enqueue stores new messages in one transaction
+func (r *sqliteOutboxRepository) Enqueue(ctx context.Context, messages ...*entity.OutboxMessage) error {
+	if err := validateOutboxMessages(messages); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("enqueue outbox messages: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertOutboxMessages(ctx, tx, messages); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("enqueue outbox messages: %w", err)
+	}
+	return nil
+}
+
+// ClaimDue claims the earliest due pending messages in one transaction
+func (r *sqliteOutboxRepository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*entity.OutboxMessage, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox messages: %w", err)
+	}
+	defer tx.Rollback()
+
+	messages, err := queryOutboxMessages(ctx, tx,
+		`SELECT `+outboxColumns+` FROM outbox_messages WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id LIMIT ?`,
+		string(entity.OutboxPending), now.UTC(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		if err := claimOutboxMessage(ctx, tx, message, now, lease); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("claim outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+// Claim claims a single pending message if it is due
+func (r *sqliteOutboxRepository) Claim(ctx context.Context, id string, now time.Time, lease time.Duration) (*entity.OutboxMessage, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox message: %w", err)
+	}
+	defer tx.Rollback()
+
+	message, err := scanOutboxMessage(tx.QueryRowContext(ctx, `SELECT `+outboxColumns+` FROM outbox_messages WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+	if message.Status != entity.OutboxPending || message.NextAttemptAt.After(now) {
+		return nil, ErrOutboxMessageNotDue
+	}
+	if err := claimOutboxMessage(ctx, tx, message, now, lease); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("claim outbox message: %w", err)
+	}
+	return message, nil
+}
+
+// Update stores the dispatch fields of an existing message
+func (r *sqliteOutboxRepository) Update(ctx context.Context, message *entity.OutboxMessage) error {
+	if err := validateOutboxMessage(message); err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE outbox_messages SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, updated_at = ?
+		WHERE id = ?`,
+		string(message.Status), message.Attempts, message.NextAttemptAt.UTC(), message.LastError,
+		message.UpdatedAt.UTC(), message.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update outbox message: %w", err)
+	}
+	return requireAffected(result, ErrOutboxMessageNotFound)
+}
+
+// Replay moves an undelivered message back to pending in one transaction
+func (r *sqliteOutboxRepository) Replay(ctx context.Context, id string, now time.Time) (*entity.OutboxMessage, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("replay outbox message: %w", err)
+	}
+	defer tx.Rollback()
+
+	message, err := scanOutboxMessage(tx.QueryRowContext(ctx, `SELECT `+outboxColumns+` FROM outbox_messages WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+	if message.Status == entity.OutboxDelivered {
+		return nil, ErrOutboxMessageDelivered
+	}
+
+	message.Status = entity.OutboxPending
+	message.Attempts = 0
+	message.NextAttemptAt = now
+	message.UpdatedAt = now
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE outbox_messages SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ? WHERE id = ?`,
+		string(message.Status), now.UTC(), now.UTC(), message.ID,
+	); err != nil {
+		return nil, fmt.Errorf("replay outbox message: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("replay outbox message: %w", err)
+	}
+	return message, nil
+}
+
+// GetByID retrieves a message by ID
+func (r *sqliteOutboxRepository) GetByID(ctx context.Context, id string) (*entity.OutboxMessage, error) {
+	return scanOutboxMessage(r.db.QueryRowContext(ctx, `SELECT `+outboxColumns+` FROM outbox_messages WHERE id = ?`, id))
+}
+
+// List returns messages with status, oldest first
+func (r *sqliteOutboxRepository) List(ctx context.Context, status entity.OutboxStatus, limit int) ([]*entity.OutboxMessage, error) {
+	return queryOutboxMessages(ctx, r.db,
+		`SELECT `+outboxColumns+` FROM outbox_messages WHERE ? = '' OR status = ?
+		ORDER BY created_at, id LIMIT ?`,
+		string(status), string(status), limit,
+	)
+}
+
+// insertOutboxMessages stores new messages inside tx
+func insertOutboxMessages(ctx context.Context, tx *sql.Tx, messages []*entity.OutboxMessage) error {
+	for _, message := range messages {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO outbox_messages (`+outboxColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			message.ID, message.Topic, string(message.Payload), string(message.Status), message.Attempts,
+			message.NextAttemptAt.UTC(), message.LastError, message.CreatedAt.UTC(), message.UpdatedAt.UTC(),
+		)
+		if err != nil {
+			var sqliteErr *sqlite.Error
+			if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+				return ErrOutboxMessageExists
+			}
+			return fmt.Errorf("create outbox message: %w", err)
+		}
+	}
+	return nil
+}
+
+// claimOutboxMessage counts an attempt and leases the message inside tx
+func claimOutboxMessage(ctx context.Context, tx *sql.Tx, message *entity.OutboxMessage, now time.Time, lease time.Duration) error {
+	message.Attempts++
+	message.NextAttemptAt = now.Add(lease)
+	message.UpdatedAt = now
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE outbox_messages SET attempts = ?, next_attempt_at = ?, updated_at = ? WHERE id = ?`,
+		message.Attempts, message.NextAttemptAt.UTC(), message.UpdatedAt.UTC(), message.ID,
+	); err != nil {
+		return fmt.Errorf("claim outbox message: %w", err)
+	}
+	return nil
+}
+
+// queryOutboxMessages reads every message matched by query
+func queryOutboxMessages(ctx context.Context, q rowsQuerier, query string, args ...any) ([]*entity.OutboxMessage, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*entity.OutboxMessage{}
+	for rows.Next() {
+		message, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+// scanOutboxMessage reads a single message from row
+func scanOutboxMessage(row rowScanner) (*entity.OutboxMessage, error) {
+	var message entity.OutboxMessage
+	var payload, status string
+	err := row.Scan(
+		&message.ID, &message.Topic, &payload, &status, &message.Attempts,
+		&message.NextAttemptAt, &message.LastError, &message.CreatedAt, &message.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOutboxMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan outbox message: %w", err)
+	}
+	message.Payload = []byte(payload)
+	message.Status = entity.OutboxStatus(status)
+	return &message, nil
+}
@@ -0,0 +1,21 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteOutboxRepository(t *testing.T) {
+	repositorytest.RunOutboxRepositoryTests(t, func(t *testing.T) repository.OutboxRepository {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "outbox.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repository.NewSQLiteOutboxRepository(db)
+	})
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/mike/entity"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteProductRepository implements ProductRepository using a SQLite
+// database
+type sqliteProductRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteProductRepository creates a new SQLite-backed product
+// repository. The database must already be migrated, see OpenSQLite.
+func NewSQLiteProductRepository(db *sql.DB) ProductRepository {
+	return &sqliteProductRepository{
+		db: db,
+	}
+}
+
+const productColumns = `id, name, description, category, price_points, active, stock, created_at, updated_at`
+
+// Create stores a new product
+func (r *sqliteProductRepository) Create(ctx context.Context, product *entity.Product) error {
+	if err := validateProduct(product); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO products (`+productColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		product.ID, product.Name, product.Description, product.Category, product.PricePoints,
+		product.Active, product.Stock, product.CreatedAt.UTC(), product.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return ErrProductExists
+		}
+		return fmt.Errorf("create product: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a product by ID
+func (r *sqliteProductRepository) GetByID(ctx context.Context, id string) (*entity.Product, error) {
+	return scanProduct(r.db.QueryRowContext(ctx, `SELECT `+productColumns+` FROM products WHERE id = ?`, id))
+}
+
+// Update replaces an existing product, keeping its creation time
+func (r *sqliteProductRepository) Update(ctx context.Context, product *entity.Product) error {
+	if err := validateProduct(product); err != nil {
+		return err
+	}
+
+	updatedAt := time.Now()
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE products SET name = ?, description = ?, category = ?, price_points = ?, active = ?,
+			stock = ?, updated_at = ?
+		WHERE id = ? RETURNING created_at`,
+		product.Name, product.Description, product.Category, product.PricePoints, product.Active,
+		product.Stock, updatedAt.UTC(), product.ID,
+	).Scan(&product.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("update product: %w", err)
+	}
+	product.UpdatedAt = updatedAt
+	return nil
+}
+
+// Delete deletes a product by ID
+func (r *sqliteProductRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM products WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete product: %w", err)
+	}
+
+	return requireAffected(result, ErrProductNotFound)
+}
+
+// Query retrieves a filtered page of products ordered by name. SQLite's
+// LIKE ignores case for ASCII letters only; Thai has no case.
+func (r *sqliteProductRepository) Query(ctx context.Context, q ProductQuery) (*ProductPage, error) {
+	q, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	var where []string
+	var args []any
+	if q.ActiveOnly {
+		where = append(where, "active = 1")
+	}
+	if q.Category != "" {
+		where = append(where, "category = ?")
+		args = append(args, q.Category)
+	}
+	if q.Search != "" {
+		pattern := "%" + likePrefix(q.Search) // a prefix of anything: contains
+		where = append(where, `(name LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+	if q.After != nil {
+		where = append(where, "(name > ? OR (name = ? AND id > ?))")
+		args = append(args, q.After.Name, q.After.Name, q.After.ID)
+	}
+
+	query := `SELECT ` + productColumns + ` FROM products`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY name, id LIMIT ?`
+	args = append(args, q.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query products: %w", err)
+	}
+	defer rows.Close()
+
+	products := make([]*entity.Product, 0, q.Limit)
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query products: %w", err)
+	}
+
+	page := &ProductPage{Products: products}
+	if len(products) > q.Limit {
+		page.Products = products[:q.Limit]
+		last := page.Products[q.Limit-1]
+		page.Next = &ProductCursor{Name: last.Name, ID: last.ID}
+	}
+	return page, nil
+}
+
+// Reserve takes quantities out of stock in one transaction
+func (r *sqliteProductRepository) Reserve(ctx context.Context, quantities map[string]int) error {
+	if err := validateQuantities(quantities); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("reserve stock: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := reserveStock(ctx, tx, quantities); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("reserve stock: %w", err)
+	}
+	return nil
+}
+
+// Restock puts quantities back into stock in one transaction
+func (r *sqliteProductRepository) Restock(ctx context.Context, quantities map[string]int) error {
+	if err := validateQuantities(quantities); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("restock: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := restock(ctx, tx, quantities); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("restock: %w", err)
+	}
+	return nil
+}
+
+// reserveStock takes quantities out of stock inside tx. The caller rolls
+// back on error, so a partial reservation is never kept.
+func reserveStock(ctx context.Context, tx *sql.Tx, quantities map[string]int) error {
+	now := time.Now().UTC()
+	for _, id := range sortedProductIDs(quantities) {
+		var active bool
+		var stock int
+		err := tx.QueryRowContext(ctx, `SELECT active, stock FROM products WHERE id = ?`, id).Scan(&active, &stock)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrProductNotFound
+		case err != nil:
+			return fmt.Errorf("reserve stock: %w", err)
+		case !active:
+			return ErrProductInactive
+		case stock < quantities[id]:
+			return ErrOutOfStock
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE products SET stock = stock - ?, updated_at = ? WHERE id = ?`, quantities[id], now, id,
+		); err != nil {
+			return fmt.Errorf("reserve stock: %w", err)
+		}
+	}
+	return nil
+}
+
+// restock puts quantities back into stock inside tx, skipping deleted
+// products
+func restock(ctx context.Context, tx *sql.Tx, quantities map[string]int) error {
+	now := time.Now().UTC()
+	for _, id := range sortedProductIDs(quantities) {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE products SET stock = stock + ?, updated_at = ? WHERE id = ?`, quantities[id], now, id,
+		); err != nil {
+			return fmt.Errorf("restock: %w", err)
+		}
+	}
+	return nil
+}
+
+// scanProduct reads a single product from row
+func scanProduct(row rowScanner) (*entity.Product, error) {
+	var product entity.Product
+	err := row.Scan(
+		&product.ID, &product.Name, &product.Description, &product.Category, &product.PricePoints,
+		&product.Active, &product.Stock, &product.CreatedAt, &product.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan product: %w", err)
+	}
+	return &product, nil
+}
@@ -0,0 +1,21 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteProductRepository(t *testing.T) {
+	repositorytest.RunProductRepositoryTests(t, func(t *testing.T) repository.ProductRepository {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "products.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repository.NewSQLiteProductRepository(db)
+	})
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/mike/entity"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteQRRequestRepository implements QRRequestRepository using a SQLite
+// database
+type sqliteQRRequestRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteQRRequestRepository creates a new SQLite-backed QR request
+// repository. The database must already be migrated, see OpenSQLite.
+func NewSQLiteQRRequestRepository(db *sql.DB) QRRequestRepository {
+	return &sqliteQRRequestRepository{
+		db: db,
+	}
+}
+
+const qrRequestColumns = `id, recipient_id, amount, memo, status, payer_id, transfer_id, expires_at, created_at, updated_at`
+
+// Create stores a new pending request
+func (r *sqliteQRRequestRepository) Create(ctx context.Context, request *entity.QRRequest) error {
+	if err := validateQRRequest(request); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO qr_requests (`+qrRequestColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		request.ID, request.RecipientID, request.Amount, request.Memo, string(request.Status),
+		request.PayerID, request.TransferID,
+		request.ExpiresAt.UTC(), request.CreatedAt.UTC(), request.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return ErrQRRequestExists
+		}
+		return fmt.Errorf("create QR request: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a request by ID
+func (r *sqliteQRRequestRepository) GetByID(ctx context.Context, id string) (*entity.QRRequest, error) {
+	return scanQRRequest(r.db.QueryRowContext(ctx, `SELECT `+qrRequestColumns+` FROM qr_requests WHERE id = ?`, id))
+}
+
+// MarkPaid moves a pending request to paid
+func (r *sqliteQRRequestRepository) MarkPaid(ctx context.Context, id, payerID, transferID string) (*entity.QRRequest, error) {
+	return r.transition(ctx, id, paidBy(payerID, transferID))
+}
+
+// MarkExpired moves a pending request to expired
+func (r *sqliteQRRequestRepository) MarkExpired(ctx context.Context, id string) (*entity.QRRequest, error) {
+	return r.transition(ctx, id, func(request *entity.QRRequest) {
+		request.Status = entity.QRRequestExpired
+	})
+}
+
+// ListDue returns pending requests expired at now, earliest first
+func (r *sqliteQRRequestRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*entity.QRRequest, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+qrRequestColumns+` FROM qr_requests
+		WHERE status = 'pending' AND expires_at <= ?
+		ORDER BY expires_at, id LIMIT ?`,
+		now.UTC(), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list due QR requests: %w", err)
+	}
+	defer rows.Close()
+
+	due := make([]*entity.QRRequest, 0)
+	for rows.Next() {
+		request, err := scanQRRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		due = append(due, request)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list due QR requests: %w", err)
+	}
+	return due, nil
+}
+
+// transition applies change to a pending request in one transaction
+func (r *sqliteQRRequestRepository) transition(ctx context.Context, id string, change func(*entity.QRRequest)) (*entity.QRRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("update QR request: %w", err)
+	}
+	defer tx.Rollback()
+
+	request, err := transitionQRRequest(ctx, tx, id, change)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("update QR request: %w", err)
+	}
+	return request, nil
+}
+
+// transitionQRRequest applies change to a pending request inside tx
+func transitionQRRequest(ctx context.Context, tx *sql.Tx, id string, change func(*entity.QRRequest)) (*entity.QRRequest, error) {
+	request, err := scanQRRequest(tx.QueryRowContext(ctx, `SELECT `+qrRequestColumns+` FROM qr_requests WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != entity.QRRequestPending {
+		return nil, ErrQRRequestNotPending
+	}
+
+	change(request)
+	request.UpdatedAt = time.Now()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE qr_requests SET status = ?, payer_id = ?, transfer_id = ?, updated_at = ? WHERE id = ?`,
+		string(request.Status), request.PayerID, request.TransferID, request.UpdatedAt.UTC(), request.ID,
+	); err != nil {
+		return nil, fmt.Errorf("update QR request: %w", err)
+	}
+	return request, nil
+}
+
+// scanQRRequest reads a single request from row
+func scanQRRequest(row rowScanner) (*entity.QRRequest, error) {
+	var request entity.QRRequest
+	var status string
+	err := row.Scan(
+		&request.ID, &request.RecipientID, &request.Amount, &request.Memo, &status,
+		&request.PayerID, &request.TransferID, &request.ExpiresAt, &request.CreatedAt, &request.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrQRRequestNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan QR request: %w", err)
+	}
+	request.Status = entity.QRRequestStatus(status)
+	return &request, nil
+}
@@ -0,0 +1,21 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteQRRequestRepository(t *testing.T) {
+	repositorytest.RunQRRequestRepositoryTests(t, func(t *testing.T) repository.QRRequestRepository {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "qr_requests.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repository.NewSQLiteQRRequestRepository(db)
+	})
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/mike/entity"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteReceiptRepository implements ReceiptRepository using a SQLite
+// database shared with the outbox
+type sqliteReceiptRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteReceiptRepository creates a new SQLite-backed receipt
+// repository. The database must already be migrated, see OpenSQLite.
+func NewSQLiteReceiptRepository(db *sql.DB) ReceiptRepository {
+	return &sqliteReceiptRepository{
+		db: db,
+	}
+}
+
+const receiptColumns = `id, order_id, user_id, phone, language, body, encoding, segments, provider_ref, status, error, created_at, updated_at`
+
+// Create stores a new receipt
+func (r *sqliteReceiptRepository) Create(ctx context.Context, receipt *entity.Receipt) error {
+	if err := validateReceipt(receipt, nil); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO receipts (`+receiptColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		receipt.ID, receipt.OrderID, receipt.UserID, receipt.Phone, receipt.Language, receipt.Body,
+		receipt.Encoding, receipt.Segments, receipt.ProviderRef, string(receipt.Status), receipt.Error,
+		receipt.CreatedAt.UTC(), receipt.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) {
+			switch sqliteErr.Code() {
+			case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+				return ErrReceiptExists
+			}
+		}
+		return fmt.Errorf("create receipt: %w", err)
+	}
+	return nil
+}
+
+// Update stores the delivery fields of an existing receipt and enqueues
+// the messages in one transaction
+func (r *sqliteReceiptRepository) Update(ctx context.Context, receipt *entity.Receipt, messages ...*entity.OutboxMessage) error {
+	if err := validateReceipt(receipt, messages); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("update receipt: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE receipts SET provider_ref = ?, status = ?, error = ?, updated_at = ? WHERE id = ? AND order_id = ?`,
+		receipt.ProviderRef, string(receipt.Status), receipt.Error, receipt.UpdatedAt.UTC(), receipt.ID, receipt.OrderID,
+	)
+	if err != nil {
+		return fmt.Errorf("update receipt: %w", err)
+	}
+	if err := requireAffected(result, ErrReceiptNotFound); err != nil {
+		return err
+	}
+	if err := insertOutboxMessages(ctx, tx, messages); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("update receipt: %w", err)
+	}
+	return nil
+}
+
+// GetByOrderID retrieves the receipt of an order
+func (r *sqliteReceiptRepository) GetByOrderID(ctx context.Context, orderID string) (*entity.Receipt, error) {
+	return scanReceipt(r.db.QueryRowContext(ctx, `SELECT `+receiptColumns+` FROM receipts WHERE order_id = ?`, orderID))
+}
+
+// GetByProviderRef retrieves a receipt by the provider's message ID
+func (r *sqliteReceiptRepository) GetByProviderRef(ctx context.Context, providerRef string) (*entity.Receipt, error) {
+	if providerRef == "" {
+		return nil, ErrReceiptNotFound
+	}
+	return scanReceipt(r.db.QueryRowContext(ctx, `SELECT `+receiptColumns+` FROM receipts WHERE provider_ref = ?`, providerRef))
+}
+
+// MarkDelivery moves a sent receipt to delivered or failed and enqueues
+// the messages in one transaction
+func (r *sqliteReceiptRepository) MarkDelivery(
+	ctx context.Context, providerRef string, status entity.ReceiptStatus, reason string, messages ...*entity.OutboxMessage,
+) (*entity.Receipt, error) {
+	if err := validateDelivery(providerRef, status, messages); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("mark receipt delivery: %w", err)
+	}
+	defer tx.Rollback()
+
+	receipt, err := scanReceipt(tx.QueryRowContext(ctx, `SELECT `+receiptColumns+` FROM receipts WHERE provider_ref = ?`, providerRef))
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != entity.ReceiptSent {
+		return nil, ErrReceiptNotSent
+	}
+
+	receipt.Status = status
+	receipt.Error = reason
+	receipt.UpdatedAt = time.Now()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE receipts SET status = ?, error = ?, updated_at = ? WHERE id = ?`,
+		string(receipt.Status), receipt.Error, receipt.UpdatedAt.UTC(), receipt.ID,
+	); err != nil {
+		return nil, fmt.Errorf("mark receipt delivery: %w", err)
+	}
+	if err := insertOutboxMessages(ctx, tx, messages); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("mark receipt delivery: %w", err)
+	}
+	return receipt, nil
+}
+
+// scanReceipt reads a single receipt from row
+func scanReceipt(row rowScanner) (*entity.Receipt, error) {
+	var receipt entity.Receipt
+	var status string
+	err := row.Scan(
+		&receipt.ID, &receipt.OrderID, &receipt.UserID, &receipt.Phone, &receipt.Language, &receipt.Body,
+		&receipt.Encoding, &receipt.Segments, &receipt.ProviderRef, &status, &receipt.Error,
+		&receipt.CreatedAt, &receipt.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReceiptNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan receipt: %w", err)
+	}
+	receipt.Status = entity.ReceiptStatus(status)
+	return &receipt, nil
+}
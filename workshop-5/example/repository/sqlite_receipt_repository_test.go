@@ -0,0 +1,24 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteReceiptRepository(t *testing.T) {
+	repositorytest.RunReceiptRepositoryTests(t, func(t *testing.T) repositorytest.ReceiptRepositories {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "receipts.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repositorytest.ReceiptRepositories{
+			Receipts: repository.NewSQLiteReceiptRepository(db),
+			Outbox:   repository.NewSQLiteOutboxRepository(db),
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/mike/entity"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteRefreshTokenRepository implements RefreshTokenRepository using a
+// SQLite database
+type sqliteRefreshTokenRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRefreshTokenRepository creates a new SQLite-backed refresh token
+// repository. The database must already be migrated, see OpenSQLite.
+func NewSQLiteRefreshTokenRepository(db *sql.DB) RefreshTokenRepository {
+	return &sqliteRefreshTokenRepository{
+		db: db,
+	}
+}
+
+const refreshTokenColumns = `id, family_id, user_id, secret_hash, expires_at, created_at, rotated_at, revoked_at`
+
+// Create stores the first token of a new family
+func (r *sqliteRefreshTokenRepository) Create(ctx context.Context, token *entity.RefreshToken) error {
+	if err := validateRefreshToken(token); err != nil {
+		return err
+	}
+	return insertRefreshToken(ctx, r.db, token)
+}
+
+// GetByID retrieves a token by ID
+func (r *sqliteRefreshTokenRepository) GetByID(ctx context.Context, id string) (*entity.RefreshToken, error) {
+	return scanRefreshToken(r.db.QueryRowContext(ctx, `SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE id = ?`, id))
+}
+
+// Rotate marks an active token rotated and inserts its successor in one
+// transaction
+func (r *sqliteRefreshTokenRepository) Rotate(ctx context.Context, id string, next *entity.RefreshToken, now time.Time) error {
+	if err := validateRefreshToken(next); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("rotate refresh token: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := scanRefreshToken(tx.QueryRowContext(ctx, `SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE id = ?`, id))
+	if err != nil {
+		return err
+	}
+	if next.FamilyID != current.FamilyID || next.UserID != current.UserID {
+		return ErrInvalidRefreshToken
+	}
+	result, err := tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET rotated_at = ?
+		WHERE id = ? AND rotated_at IS NULL AND revoked_at IS NULL AND expires_at > ?`,
+		now.UTC(), id, now.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("rotate refresh token: %w", err)
+	}
+	if err := requireAffected(result, ErrRefreshTokenInactive); err != nil {
+		return err
+	}
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("rotate refresh token: %w", err)
+	}
+	return nil
+}
+
+// RevokeFamily revokes every unrevoked token of a family
+func (r *sqliteRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`,
+		now.UTC(), familyID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("revoke refresh tokens: %w", err)
+	}
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("revoke refresh tokens: %w", err)
+	}
+	return int(revoked), nil
+}
+
+// DeleteExpired deletes tokens expired at now
+func (r *sqliteRefreshTokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at <= ?`, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("delete expired refresh tokens: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete expired refresh tokens: %w", err)
+	}
+	return int(deleted), nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insertRefreshToken stores a new token
+func insertRefreshToken(ctx context.Context, q execer, token *entity.RefreshToken) error {
+	_, err := q.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (`+refreshTokenColumns+`) VALUES (?, ?, ?, ?, ?, ?, NULL, NULL)`,
+		token.ID, token.FamilyID, token.UserID, token.SecretHash, token.ExpiresAt.UTC(), token.CreatedAt.UTC(),
+	)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return ErrRefreshTokenExists
+		}
+		return fmt.Errorf("create refresh token: %w", err)
+	}
+	return nil
+}
+
+// scanRefreshToken reads a single token from row
+func scanRefreshToken(row rowScanner) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	var rotatedAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&token.ID, &token.FamilyID, &token.UserID, &token.SecretHash,
+		&token.ExpiresAt, &token.CreatedAt, &rotatedAt, &revokedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan refresh token: %w", err)
+	}
+	if rotatedAt.Valid {
+		token.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return &token, nil
+}
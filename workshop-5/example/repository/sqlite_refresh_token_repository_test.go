@@ -0,0 +1,21 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteRefreshTokenRepository(t *testing.T) {
+	repositorytest.RunRefreshTokenRepositoryTests(t, func(t *testing.T) repository.RefreshTokenRepository {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "refresh_tokens.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repository.NewSQLiteRefreshTokenRepository(db)
+	})
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/mike/entity"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteTransferRepository implements TransferRepository using a SQLite
+// database shared with the ledger and QR requests, so a transfer, its
+// ledger posting and the QR request it pays are written in the same
+// transaction
+type sqliteTransferRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteTransferRepository creates a new SQLite-backed transfer
+// repository. The database must already be migrated, see OpenSQLite.
+func NewSQLiteTransferRepository(db *sql.DB) TransferRepository {
+	return &sqliteTransferRepository{
+		db: db,
+	}
+}
+
+const transferColumns = `id, from_user_id, to_user_id, amount, memo, qr_request_id, status, failure_reason, created_at, updated_at`
+
+// Create stores a new pending transfer
+func (r *sqliteTransferRepository) Create(ctx context.Context, transfer *entity.Transfer) error {
+	if err := validateTransfer(transfer); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO transfers (`+transferColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		transfer.ID, transfer.FromUserID, transfer.ToUserID, transfer.Amount, transfer.Memo, transfer.QRRequestID,
+		string(transfer.Status), transfer.FailureReason, transfer.CreatedAt.UTC(), transfer.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return ErrTransferExists
+		}
+		return fmt.Errorf("create transfer: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a transfer by ID
+func (r *sqliteTransferRepository) GetByID(ctx context.Context, id string) (*entity.Transfer, error) {
+	return getTransfer(ctx, r.db, id)
+}
+
+// Complete posts the transfer's ledger entries, marks it posted and marks
+// the QR request it pays, if any, paid in one transaction
+func (r *sqliteTransferRepository) Complete(ctx context.Context, id string) (*entity.Transfer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("complete transfer: %w", err)
+	}
+	defer tx.Rollback()
+
+	transfer, err := getTransfer(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Status != entity.TransferPending {
+		return nil, ErrTransferNotPending
+	}
+
+	if err := postEntries(ctx, tx, transfer.Posting()); err != nil {
+		return nil, err
+	}
+
+	transfer.Status = entity.TransferPosted
+	transfer.UpdatedAt = time.Now()
+	if err := setTransferStatus(ctx, tx, transfer); err != nil {
+		return nil, err
+	}
+	if transfer.QRRequestID != "" {
+		if _, err := transitionQRRequest(ctx, tx, transfer.QRRequestID, paidBy(transfer.FromUserID, transfer.ID)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("complete transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// Fail marks a pending transfer as failed
+func (r *sqliteTransferRepository) Fail(ctx context.Context, id, reason string) (*entity.Transfer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail transfer: %w", err)
+	}
+	defer tx.Rollback()
+
+	transfer, err := getTransfer(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.Status != entity.TransferPending {
+		return nil, ErrTransferNotPending
+	}
+
+	transfer.Status = entity.TransferFailed
+	transfer.FailureReason = reason
+	transfer.UpdatedAt = time.Now()
+	if err := setTransferStatus(ctx, tx, transfer); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("fail transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// PostedTotalSince sums a member's posted outgoing transfers since a time
+func (r *sqliteTransferRepository) PostedTotalSince(ctx context.Context, fromUserID string, since time.Time) (int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM transfers
+		WHERE from_user_id = ? AND status = 'posted' AND created_at >= ?`,
+		fromUserID, since.UTC(),
+	).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("sum transfers: %w", err)
+	}
+	return total, nil
+}
+
+// getTransfer reads a single transfer
+func getTransfer(ctx context.Context, q querier, id string) (*entity.Transfer, error) {
+	var transfer entity.Transfer
+	var status string
+	err := q.QueryRowContext(ctx, `SELECT `+transferColumns+` FROM transfers WHERE id = ?`, id).Scan(
+		&transfer.ID, &transfer.FromUserID, &transfer.ToUserID, &transfer.Amount, &transfer.Memo, &transfer.QRRequestID,
+		&status, &transfer.FailureReason, &transfer.CreatedAt, &transfer.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get transfer: %w", err)
+	}
+	transfer.Status = entity.TransferStatus(status)
+	return &transfer, nil
+}
+
+// setTransferStatus persists the status fields of a transfer inside tx
+func setTransferStatus(ctx context.Context, tx *sql.Tx, transfer *entity.Transfer) error {
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE transfers SET status = ?, failure_reason = ?, updated_at = ? WHERE id = ?`,
+		string(transfer.Status), transfer.FailureReason, transfer.UpdatedAt.UTC(), transfer.ID,
+	); err != nil {
+		return fmt.Errorf("update transfer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteTransferRepository(t *testing.T) {
+	repositorytest.RunTransferRepositoryTests(t, func(t *testing.T) (repository.TransferRepository, repository.LedgerRepository, repository.QRRequestRepository) {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "transfers.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repository.NewSQLiteTransferRepository(db), repository.NewSQLiteLedgerRepository(db), repository.NewSQLiteQRRequestRepository(db)
+	})
+}
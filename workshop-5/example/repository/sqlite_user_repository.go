@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/mike/entity"
+
+	"modernc.org/sqlite" // also registers the "sqlite" database/sql driver
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// OpenSQLite opens a SQLite database at the given path and applies all
+// pending migrations
+func OpenSQLite(path string) (*sql.DB, error) {
+	// _time_format=sqlite stores timestamps as "YYYY-MM-DD HH:MM:SS.fff+00:00",
+	// which SQLite date functions understand and which sorts correctly;
+	// migration 0018 rewrites timestamps stored before it was set
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_time_format=sqlite", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+
+	// SQLite allows a single writer; serialising access through one
+	// connection avoids SQLITE_BUSY errors under concurrent requests.
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+
+	if err := Migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// sqliteUserRepository implements UserRepository using a SQLite database
+type sqliteUserRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteUserRepository creates a new SQLite-backed user repository.
+// The database must already be migrated, see OpenSQLite.
+func NewSQLiteUserRepository(db *sql.DB) UserRepository {
+	return &sqliteUserRepository{
+		db: db,
+	}
+}
+
+const userColumns = `id, member_id, first_name, last_name, phone, email, membership_level, role, registered_at`
+
+// Create creates a new user
+func (r *sqliteUserRepository) Create(ctx context.Context, user *entity.User) error {
+	if user == nil {
+		return ErrNilUser
+	}
+
+	if user.ID == "" {
+		return ErrEmptyUserID
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO users (`+userColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.ID, user.MemberID, user.FirstName, user.LastName, user.Phone, user.Email,
+		user.MembershipLevel, string(user.Role), user.RegisteredAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("create user: %w", translateConstraintError(err))
+	}
+	return nil
+}
+
+// GetByID retrieves a user by ID
+func (r *sqliteUserRepository) GetByID(ctx context.Context, id string) (*entity.User, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id)
+	return scanUser(row)
+}
+
+// GetByEmail retrieves a user by email
+func (r *sqliteUserRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, email)
+	return scanUser(row)
+}
+
+// GetByPhone retrieves a user by phone number
+func (r *sqliteUserRepository) GetByPhone(ctx context.Context, phone string) (*entity.User, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE phone = ?`, phone)
+	return scanUser(row)
+}
+
+// GetAll retrieves all users
+func (r *sqliteUserRepository) GetAll(ctx context.Context) ([]*entity.User, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+userColumns+` FROM users ORDER BY registered_at, member_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	userList := make([]*entity.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		userList = append(userList, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	return userList, nil
+}
+
+// Update updates an existing user
+func (r *sqliteUserRepository) Update(ctx context.Context, user *entity.User) error {
+	if user == nil {
+		return ErrNilUser
+	}
+
+	if user.ID == "" {
+		return ErrEmptyUserID
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE users SET member_id = ?, first_name = ?, last_name = ?, phone = ?, email = ?,
+			membership_level = ?, role = ?, registered_at = ?
+		WHERE id = ?`,
+		user.MemberID, user.FirstName, user.LastName, user.Phone, user.Email,
+		user.MembershipLevel, string(user.Role), user.RegisteredAt.UTC(), user.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update user: %w", translateConstraintError(err))
+	}
+
+	return requireAffected(result, ErrUserNotFound)
+}
+
+// Delete deletes a user by ID
+func (r *sqliteUserRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+
+	return requireAffected(result, ErrUserNotFound)
+}
+
+// Query retrieves a filtered, sorted page of users. Filters and ordering
+// are pushed into SQL so the membership_level and registered_at indexes
+// can be used.
+func (r *sqliteUserRepository) Query(ctx context.Context, q UserQuery) (*UserPage, error) {
+	q, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	var where []string
+	var args []any
+	if q.MembershipLevel != "" {
+		where = append(where, "membership_level = ?")
+		args = append(args, q.MembershipLevel)
+	}
+	if !q.RegisteredFrom.IsZero() {
+		where = append(where, "registered_at >= ?")
+		args = append(args, q.RegisteredFrom.UTC())
+	}
+	if !q.RegisteredTo.IsZero() {
+		where = append(where, "registered_at < ?")
+		args = append(args, q.RegisteredTo.UTC())
+	}
+	if q.NamePrefix != "" {
+		where = append(where, `(first_name LIKE ? ESCAPE '\' OR last_name LIKE ? ESCAPE '\')`)
+		args = append(args, likePrefix(q.NamePrefix), likePrefix(q.NamePrefix))
+	}
+	if q.EmailPrefix != "" {
+		where = append(where, `email LIKE ? ESCAPE '\'`)
+		args = append(args, likePrefix(q.EmailPrefix))
+	}
+	if q.PhonePrefix != "" {
+		// A range scan on the unique phone index instead of LIKE
+		where = append(where, "phone >= ? AND phone < ?")
+		args = append(args, q.PhonePrefix, q.PhonePrefix+"\U0010FFFF")
+	}
+
+	column := string(q.SortBy)
+	op, direction := ">", "ASC"
+	if q.Descending {
+		op, direction = "<", "DESC"
+	}
+	if q.After != nil {
+		where = append(where, fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", column, op, column, op))
+		value := cursorArg(q.SortBy, q.After.Value)
+		args = append(args, value, value, q.After.ID)
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", column, direction, direction)
+	args = append(args, q.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*entity.User, 0, q.Limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query users: %w", err)
+	}
+
+	page := &UserPage{Users: users}
+	if len(users) > q.Limit {
+		page.Users = users[:q.Limit]
+		page.Next = CursorFor(page.Users[q.Limit-1], q.SortBy)
+	}
+	return page, nil
+}
+
+// likePrefix builds a LIKE pattern matching values starting with prefix
+func likePrefix(prefix string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+	return escaped + "%"
+}
+
+// cursorArg converts a cursor value to the column's query argument type
+func cursorArg(sortBy UserSortField, value string) any {
+	if sortBy == SortByRegisteredAt {
+		// Already validated by UserQuery.normalize
+		t, _ := time.Parse(cursorTimeFormat, value)
+		return t.UTC()
+	}
+	return value
+}
+
+// NextMemberID reserves the next member ID from the member_id sequence
+func (r *sqliteUserRepository) NextMemberID(ctx context.Context) (string, error) {
+	var seq int64
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE sequences SET value = value + 1 WHERE name = 'member_id' RETURNING value`,
+	).Scan(&seq)
+	if err != nil {
+		return "", fmt.Errorf("next member ID: %w", err)
+	}
+	return entity.FormatMemberID(seq), nil
+}
+
+// translateConstraintError maps SQLite uniqueness violations on the users
+// table to the repository's uniqueness errors
+func translateConstraintError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return ErrUserExists
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		msg := sqliteErr.Error()
+		switch {
+		case strings.Contains(msg, "users.id"):
+			return ErrUserExists
+		case strings.Contains(msg, "users.email"):
+			return ErrEmailTaken
+		case strings.Contains(msg, "users.phone"):
+			return ErrPhoneTaken
+		case strings.Contains(msg, "users.member_id"):
+			return ErrMemberIDTaken
+		}
+	}
+	return err
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUser reads a single users row into an entity
+func scanUser(row rowScanner) (*entity.User, error) {
+	var user entity.User
+	var role string
+	err := row.Scan(
+		&user.ID, &user.MemberID, &user.FirstName, &user.LastName, &user.Phone, &user.Email,
+		&user.MembershipLevel, &role, &user.RegisteredAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan user: %w", err)
+	}
+	user.Role = entity.Role(role)
+	return &user, nil
+}
+
+// requireAffected reports notFound when a write touched no rows
+func requireAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/repository/repositorytest"
+)
+
+func TestSQLiteUserRepository(t *testing.T) {
+	repositorytest.RunUserRepositoryTests(t, func(t *testing.T) repository.UserRepository {
+		db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "users.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repository.NewSQLiteUserRepository(db)
+	})
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "users.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	defer db.Close()
+
+	if err := repository.Migrate(db); err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+
+	var applied int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatalf("count schema_migrations: %v", err)
+	}
+	if applied == 0 {
+		t.Fatal("expected applied migrations to be recorded")
+	}
+}
+
+func TestSQLiteUsersHaveOneUniqueIndexPerColumn(t *testing.T) {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "users.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT il.name, ii.name FROM pragma_index_list('users') AS il
+		JOIN pragma_index_info(il.name) AS ii WHERE il."unique" AND il.origin != 'pk'`)
+	if err != nil {
+		t.Fatalf("list indexes: %v", err)
+	}
+	defer rows.Close()
+
+	indexes := map[string][]string{}
+	for rows.Next() {
+		var index, column string
+		if err := rows.Scan(&index, &column); err != nil {
+			t.Fatalf("scan index: %v", err)
+		}
+		indexes[column] = append(indexes[column], index)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("list indexes: %v", err)
+	}
+
+	for _, column := range []string{"member_id", "phone", "email"} {
+		if len(indexes[column]) != 1 {
+			t.Errorf("%s: expected one unique index, got %v", column, indexes[column])
+		}
+	}
+	columns := make([]string, 0, len(indexes))
+	for column := range indexes {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+	if !slices.Equal(columns, []string{"email", "member_id", "phone"}) {
+		t.Errorf("unexpected unique columns: %v", columns)
+	}
+}
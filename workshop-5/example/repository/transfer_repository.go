@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every TransferRepository implementation
+var (
+	// ErrNilTransfer is returned when Create receives a nil transfer
+	ErrNilTransfer = apperror.Validation("transfer cannot be nil")
+
+	// ErrInvalidTransfer is returned for transfers without ID, parties or a
+	// positive amount
+	ErrInvalidTransfer = apperror.Validation("invalid transfer")
+
+	// ErrTransferExists is returned when a transfer with the same ID exists
+	ErrTransferExists = apperror.Conflict("transfer already exists")
+
+	// ErrTransferNotFound is returned when no transfer matches the lookup
+	ErrTransferNotFound = apperror.NotFound("transfer not found")
+
+	// ErrTransferNotPending is returned when completing or failing a
+	// transfer that is already posted or failed
+	ErrTransferNotPending = apperror.Conflict("transfer is not pending")
+)
+
+// TransferRepository stores transfers and settles them against the ledger
+type TransferRepository interface {
+	// Create stores a new pending transfer
+	Create(ctx context.Context, transfer *entity.Transfer) error
+
+	// GetByID retrieves a transfer by ID
+	GetByID(ctx context.Context, id string) (*entity.Transfer, error)
+
+	// Complete writes the transfer's ledger posting and marks it posted in
+	// one atomic step. A transfer paying a QR request marks the request
+	// paid in the same step, and fails with ErrQRRequestNotPending unless
+	// it is pending. On error nothing is written and the transfer stays
+	// pending; ledger errors such as ErrInsufficientBalance are returned
+	// as is.
+	Complete(ctx context.Context, id string) (*entity.Transfer, error)
+
+	// Fail marks a pending transfer as failed with the given reason
+	Fail(ctx context.Context, id, reason string) (*entity.Transfer, error)
+
+	// PostedTotalSince sums the amounts of a member's posted outgoing
+	// transfers created at or after since
+	PostedTotalSince(ctx context.Context, fromUserID string, since time.Time) (int, error)
+}
+
+// validateTransfer checks the invariants shared by every implementation
+func validateTransfer(transfer *entity.Transfer) error {
+	if transfer == nil {
+		return ErrNilTransfer
+	}
+	if transfer.ID == "" || transfer.FromUserID == "" || transfer.ToUserID == "" ||
+		transfer.FromUserID == transfer.ToUserID || transfer.Amount <= 0 ||
+		transfer.Status != entity.TransferPending {
+		return ErrInvalidTransfer
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// UserSortField is a column users can be ordered by. Every sort is made
+// stable by breaking ties on the user ID.
+type UserSortField string
+
+// Supported sort fields
+const (
+	SortByRegisteredAt UserSortField = "registered_at"
+	SortByMemberID     UserSortField = "member_id"
+	SortByFirstName    UserSortField = "first_name"
+	SortByLastName     UserSortField = "last_name"
+	SortByEmail        UserSortField = "email"
+)
+
+// Valid reports whether f is a supported sort field
+func (f UserSortField) Valid() bool {
+	switch f {
+	case SortByRegisteredAt, SortByMemberID, SortByFirstName, SortByLastName, SortByEmail:
+		return true
+	}
+	return false
+}
+
+// UserQuery describes a filtered, sorted page of users. Zero values mean
+// "no filter".
+type UserQuery struct {
+	// MembershipLevel matches the level exactly
+	MembershipLevel string
+
+	// RegisteredFrom is inclusive, RegisteredTo is exclusive
+	RegisteredFrom time.Time
+	RegisteredTo   time.Time
+
+	// NamePrefix matches the start of the first or last name, ignoring the
+	// case of ASCII letters
+	NamePrefix string
+
+	// EmailPrefix matches the start of the email, ignoring the case of
+	// ASCII letters
+	EmailPrefix string
+
+	// PhonePrefix matches the start of the phone number exactly
+	PhonePrefix string
+
+	// SortBy defaults to SortByRegisteredAt
+	SortBy     UserSortField
+	Descending bool
+
+	// Limit is the maximum number of users returned; it must be positive
+	Limit int
+
+	// After resumes the listing right after the given position
+	After *UserCursor
+}
+
+// UserCursor is a keyset position in a sorted user listing: the sort value
+// and ID of the last user on the previous page
+type UserCursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// UserPage is one page of a user listing
+type UserPage struct {
+	Users []*entity.User
+
+	// Next is the cursor for the following page, nil on the last page
+	Next *UserCursor
+}
+
+// CursorFor returns the cursor positioned at user for the given sort field
+func CursorFor(user *entity.User, sortBy UserSortField) *UserCursor {
+	return &UserCursor{Value: sortValue(user, sortBy), ID: user.ID}
+}
+
+// sortValue returns the value user is ordered by. Timestamps are rendered
+// in UTC with a fixed width so they compare correctly as strings.
+func sortValue(user *entity.User, sortBy UserSortField) string {
+	switch sortBy {
+	case SortByMemberID:
+		return user.MemberID
+	case SortByFirstName:
+		return user.FirstName
+	case SortByLastName:
+		return user.LastName
+	case SortByEmail:
+		return user.Email
+	default:
+		return user.RegisteredAt.UTC().Format(cursorTimeFormat)
+	}
+}
+
+// cursorTimeFormat is a fixed-width RFC 3339 layout
+const cursorTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// normalize fills defaults and rejects malformed queries
+func (q UserQuery) normalize() (UserQuery, error) {
+	if q.SortBy == "" {
+		q.SortBy = SortByRegisteredAt
+	}
+	if !q.SortBy.Valid() {
+		return q, apperror.Validation(fmt.Sprintf("unsupported sort field %q", q.SortBy))
+	}
+	if q.Limit <= 0 {
+		return q, apperror.Validation(fmt.Sprintf("limit must be positive, got %d", q.Limit))
+	}
+	if q.After != nil && q.SortBy == SortByRegisteredAt {
+		if _, err := time.Parse(cursorTimeFormat, q.After.Value); err != nil {
+			return q, apperror.Validation("invalid cursor")
+		}
+	}
+	return q, nil
+}
+
+// matches reports whether user passes every filter of q
+func (q UserQuery) matches(user *entity.User) bool {
+	if q.MembershipLevel != "" && user.MembershipLevel != q.MembershipLevel {
+		return false
+	}
+	if !q.RegisteredFrom.IsZero() && user.RegisteredAt.Before(q.RegisteredFrom) {
+		return false
+	}
+	if !q.RegisteredTo.IsZero() && !user.RegisteredAt.Before(q.RegisteredTo) {
+		return false
+	}
+	if q.NamePrefix != "" &&
+		!hasPrefixFold(user.FirstName, q.NamePrefix) &&
+		!hasPrefixFold(user.LastName, q.NamePrefix) {
+		return false
+	}
+	if q.EmailPrefix != "" && !hasPrefixFold(user.Email, q.EmailPrefix) {
+		return false
+	}
+	if q.PhonePrefix != "" && !strings.HasPrefix(user.Phone, q.PhonePrefix) {
+		return false
+	}
+	return true
+}
+
+// less orders two users by the query's sort field, then by ID
+func (q UserQuery) less(a, b *entity.User) bool {
+	return q.compare(sortValue(a, q.SortBy), a.ID, sortValue(b, q.SortBy), b.ID) < 0
+}
+
+// afterCursor reports whether user sorts strictly after the query cursor
+func (q UserQuery) afterCursor(user *entity.User) bool {
+	if q.After == nil {
+		return true
+	}
+	return q.compare(sortValue(user, q.SortBy), user.ID, q.After.Value, q.After.ID) > 0
+}
+
+// compare orders (value, id) pairs honouring the sort direction
+func (q UserQuery) compare(aValue, aID, bValue, bID string) int {
+	c := strings.Compare(aValue, bValue)
+	if c == 0 {
+		c = strings.Compare(aID, bID)
+	}
+	if q.Descending {
+		return -c
+	}
+	return c
+}
+
+// hasPrefixFold is strings.HasPrefix ignoring the case of ASCII letters
+// only, like SQLite's LIKE, so the backends match the same users. Other
+// bytes, including every byte of a multi-byte character, must be equal.
+func hasPrefixFold(s, prefix string) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if lowerASCII(s[i]) != lowerASCII(prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerASCII lower-cases an ASCII letter and returns any other byte as is
+func lowerASCII(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
@@ -1,24 +1,78 @@
 package repository
 
-import "example.com/mike/entity"
+import (
+	"context"
 
-// UserRepository defines the interface for user data operations
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Errors returned by every UserRepository implementation. They are
+// classified with apperror, so callers may match either the specific error
+// or its kind, e.g. errors.Is(err, apperror.ErrConflict).
+var (
+	// ErrUserNotFound is returned when no user matches the lookup
+	ErrUserNotFound = apperror.NotFound("user not found")
+
+	// ErrNilUser is returned when Create or Update receives a nil user
+	ErrNilUser = apperror.Validation("user cannot be nil")
+
+	// ErrEmptyUserID is returned when Create or Update receives a user without ID
+	ErrEmptyUserID = apperror.Validation("user ID cannot be empty")
+)
+
+// Uniqueness errors returned by Create and Update. Implementations must
+// enforce these atomically with the write so concurrent callers cannot both
+// succeed.
+var (
+	// ErrUserExists is returned when a user with the same ID already exists
+	ErrUserExists = apperror.Conflict("user already exists")
+
+	// ErrEmailTaken is returned when the email belongs to another user
+	ErrEmailTaken = apperror.Conflict("email already registered")
+
+	// ErrPhoneTaken is returned when the phone number belongs to another user
+	ErrPhoneTaken = apperror.Conflict("phone already registered")
+
+	// ErrMemberIDTaken is returned when the member ID belongs to another user
+	ErrMemberIDTaken = apperror.Conflict("member ID already exists")
+)
+
+// UserRepository defines the interface for user data operations. User.Points
+// is a projection of the points ledger and is not stored; users are always
+// returned with zero points.
+//
+// Every method takes the caller's context. Once it is cancelled or past its
+// deadline, methods give up and return ctx.Err(), possibly wrapped, so
+// callers can match context.Canceled and context.DeadlineExceeded.
 type UserRepository interface {
 	// Create creates a new user
-	Create(user *entity.User) error
+	Create(ctx context.Context, user *entity.User) error
 
 	// GetByID retrieves a user by ID
-	GetByID(id string) (*entity.User, error)
+	GetByID(ctx context.Context, id string) (*entity.User, error)
 
 	// GetByEmail retrieves a user by email
-	GetByEmail(email string) (*entity.User, error)
+	GetByEmail(ctx context.Context, email string) (*entity.User, error)
+
+	// GetByPhone retrieves a user by phone number in E.164 format
+	GetByPhone(ctx context.Context, phone string) (*entity.User, error)
 
 	// GetAll retrieves all users
-	GetAll() ([]*entity.User, error)
+	GetAll(ctx context.Context) ([]*entity.User, error)
+
+	// Query retrieves a filtered, sorted page of users using keyset
+	// pagination; see UserQuery
+	Query(ctx context.Context, q UserQuery) (*UserPage, error)
 
 	// Update updates an existing user
-	Update(user *entity.User) error
+	Update(ctx context.Context, user *entity.User) error
 
 	// Delete deletes a user by ID
-	Delete(id string) error
+	Delete(ctx context.Context, id string) error
+
+	// NextMemberID reserves the next member ID from a monotonic sequence.
+	// IDs are never handed out twice, even after the user holding one is
+	// deleted; a reserved ID that is never used leaves a gap.
+	NextMemberID(ctx context.Context) (string, error)
 }
@@ -0,0 +1,5 @@
+#!/bin/bash
+
+# regenerate docs/docs.go from the swag annotations in handler/
+# install swag with: go install github.com/swaggo/swag/cmd/swag@v1.16.3
+cd "$(dirname "$0")/.." && swag init -g handler/http_handler.go --outputTypes go
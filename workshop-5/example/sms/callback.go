@@ -0,0 +1,83 @@
+package sms
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Delivery report headers. The signature is "sha256=" followed by the hex
+// HMAC-SHA256 of "<timestamp>.<body>" made with the shared webhook secret;
+// the timestamp is in Unix seconds.
+const (
+	SignatureHeader = "X-SMS-Signature"
+	TimestampHeader = "X-SMS-Timestamp"
+)
+
+// MaxCallbackAge is how far a delivery report's timestamp may be from the
+// receiver's clock, which bounds how long a captured report can be replayed
+const MaxCallbackAge = 5 * time.Minute
+
+// Errors returned by VerifyCallback
+var (
+	ErrBadSignature  = errors.New("invalid SMS callback signature")
+	ErrStaleCallback = errors.New("SMS callback timestamp is too old or in the future")
+)
+
+// DeliveryStatus is the final outcome a provider reports for a message
+type DeliveryStatus string
+
+// Delivery statuses
+const (
+	Delivered DeliveryStatus = "delivered"
+	Failed    DeliveryStatus = "failed"
+)
+
+// DeliveryReport is the body of a provider's delivery callback
+type DeliveryReport struct {
+	MessageID string         `json:"message_id" example:"msg-8f14e45f"`
+	Status    DeliveryStatus `json:"status" example:"delivered"`
+	Error     string         `json:"error,omitempty" example:""`
+}
+
+// SignCallback returns the signature header value of body sent at timestamp
+func SignCallback(secret []byte, timestamp time.Time, body []byte) string {
+	return "sha256=" + hex.EncodeToString(callbackMAC(secret, strconv.FormatInt(timestamp.Unix(), 10), body))
+}
+
+// VerifyCallback checks the signature and timestamp headers of a delivery
+// report received at now
+func VerifyCallback(secret []byte, timestamp, signature string, body []byte, now time.Time) error {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return ErrBadSignature
+	}
+	mac, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, callbackMAC(secret, timestamp, body)) {
+		return ErrBadSignature
+	}
+
+	// The timestamp is checked after the signature, so only reports we
+	// signed ourselves are ever told they are stale
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > MaxCallbackAge || age < -MaxCallbackAge {
+		return ErrStaleCallback
+	}
+	return nil
+}
+
+// callbackMAC is the HMAC-SHA256 of "<timestamp>.<body>"
+func callbackMAC(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,115 @@
+package sms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultHTTPTimeout bounds a provider call when HTTPConfig has no client
+const DefaultHTTPTimeout = 10 * time.Second
+
+// HTTPConfig configures an HTTPSender
+type HTTPConfig struct {
+	// URL receives one POST per message
+	URL string
+
+	// Token is sent as a bearer token when set
+	Token string
+
+	// From is the sender name or number shown to the recipient
+	From string
+
+	// Client defaults to a client with DefaultHTTPTimeout
+	Client *http.Client
+}
+
+// HTTPSender sends messages through a provider with a JSON API. Each
+// message is POSTed to the configured URL as
+//
+//	{"from": "...", "to": "+66812345678", "body": "...", "encoding": "UCS-2"}
+//
+// and any 2xx response with a body of {"message_id": "..."} accepts it.
+type HTTPSender struct {
+	config HTTPConfig
+}
+
+// httpRequest is the body POSTed to the provider
+type httpRequest struct {
+	From     string   `json:"from,omitempty"`
+	To       string   `json:"to"`
+	Body     string   `json:"body"`
+	Encoding Encoding `json:"encoding"`
+}
+
+// httpResponse is the body of an accepted message
+type httpResponse struct {
+	MessageID string `json:"message_id"`
+}
+
+// NewHTTPSender creates a sender for the provider at config.URL
+func NewHTTPSender(config HTTPConfig) *HTTPSender {
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	return &HTTPSender{config: config}
+}
+
+// Send posts msg to the provider and returns the provider's message ID
+func (s *HTTPSender) Send(msg Message) (string, error) {
+	if err := msg.validate(); err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(httpRequest{
+		From:     s.config.From,
+		To:       msg.To,
+		Body:     msg.Body,
+		Encoding: CountSegments(msg.Body).Encoding,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode SMS request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("create SMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	}
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("send SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Provider error bodies are only kept for the error message, so a
+	// misbehaving provider cannot make us read an unbounded response
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", fmt.Errorf("read SMS response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("%w: HTTP %d: %s", ErrRejected, resp.StatusCode, truncate(string(data), 200))
+	}
+
+	var accepted httpResponse
+	if err := json.Unmarshal(data, &accepted); err != nil || accepted.MessageID == "" {
+		return "", fmt.Errorf("%w: response has no message_id", ErrRejected)
+	}
+	return accepted.MessageID, nil
+}
+
+// truncate shortens s to at most n bytes for error messages
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
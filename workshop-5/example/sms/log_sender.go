@@ -0,0 +1,73 @@
+package sms
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LogSender is a fake provider that writes every message to w as a JSON
+// line instead of sending it. It accepts every valid message and keeps
+// them for tests to inspect.
+type LogSender struct {
+	mu   sync.Mutex
+	w    io.Writer
+	sent []Message
+}
+
+// logLine is a message as written by LogSender
+type logLine struct {
+	Ref      string    `json:"ref"`
+	To       string    `json:"to"`
+	Body     string    `json:"body"`
+	Encoding Encoding  `json:"encoding"`
+	Segments int       `json:"segments"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// NewLogSender creates a fake sender writing to w
+func NewLogSender(w io.Writer) *LogSender {
+	return &LogSender{w: w}
+}
+
+// Send writes msg to the log and returns its generated reference
+func (s *LogSender) Send(msg Message) (string, error) {
+	if err := msg.validate(); err != nil {
+		return "", err
+	}
+
+	count := CountSegments(msg.Body)
+	line := logLine{
+		Ref:      "log-" + uuid.New().String(),
+		To:       msg.To,
+		Body:     msg.Body,
+		Encoding: count.Encoding,
+		Segments: count.Segments,
+		SentAt:   time.Now().UTC(),
+	}
+	data, err := json.Marshal(line)
+	if err != nil {
+		return "", fmt.Errorf("encode SMS log line: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		return "", fmt.Errorf("write SMS log line: %w", err)
+	}
+	s.sent = append(s.sent, msg)
+	return line.Ref, nil
+}
+
+// Sent returns the messages sent so far, oldest first
+func (s *LogSender) Sent() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.sent...)
+}
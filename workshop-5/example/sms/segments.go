@@ -0,0 +1,89 @@
+package sms
+
+import "strings"
+
+// Encoding is the character encoding a message is sent in
+type Encoding string
+
+// Message encodings
+const (
+	GSM7 Encoding = "GSM-7"
+	UCS2 Encoding = "UCS-2"
+)
+
+// Segment sizes in characters (GSM-7 septets or UCS-2 code units). Split
+// messages carry a header in every segment, leaving less room for text.
+const (
+	gsm7Single    = 160
+	gsm7Multipart = 153
+	ucs2Single    = 70
+	ucs2Multipart = 67
+)
+
+// gsm7Basic is the GSM 03.38 basic character set
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extension holds the characters that take an escape plus a septet
+const gsm7Extension = "\f^{}\\[~]|€"
+
+// Count describes how a message is billed
+type Count struct {
+	Encoding Encoding `json:"encoding"`
+
+	// Units is the length in GSM-7 septets or UCS-2 code units
+	Units int `json:"units"`
+
+	// Segments is the number of messages the provider sends and bills
+	Segments int `json:"segments"`
+}
+
+// CountSegments works out the encoding and number of segments of body. An
+// empty body still takes one segment. A character is never split across
+// segments, so an extension character or a surrogate pair that does not
+// fit moves whole into the next one.
+func CountSegments(body string) Count {
+	encoding := GSM7
+	for _, r := range body {
+		if !strings.ContainsRune(gsm7Basic, r) && !strings.ContainsRune(gsm7Extension, r) {
+			encoding = UCS2
+			break
+		}
+	}
+
+	single, multipart := gsm7Single, gsm7Multipart
+	if encoding == UCS2 {
+		single, multipart = ucs2Single, ucs2Multipart
+	}
+
+	units := 0
+	for _, r := range body {
+		units += width(r, encoding)
+	}
+	if units <= single {
+		return Count{Encoding: encoding, Units: units, Segments: 1}
+	}
+
+	segments, used := 1, 0
+	for _, r := range body {
+		w := width(r, encoding)
+		if used+w > multipart {
+			segments++
+			used = 0
+		}
+		used += w
+	}
+	return Count{Encoding: encoding, Units: units, Segments: segments}
+}
+
+// width is the number of units r takes in encoding
+func width(r rune, encoding Encoding) int {
+	switch {
+	case encoding == GSM7 && strings.ContainsRune(gsm7Extension, r):
+		return 2
+	case encoding == UCS2 && r > 0xFFFF:
+		return 2
+	default:
+		return 1
+	}
+}
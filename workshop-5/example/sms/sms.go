@@ -0,0 +1,42 @@
+// Package sms sends text messages and works out how providers bill them.
+//
+// Messages that only use the GSM 03.38 alphabet are sent as GSM-7: 160
+// characters fit in one segment, or 153 per segment once a message is
+// split. Anything else, including all Thai text, is sent as UCS-2: 70
+// UTF-16 code units in one segment, or 67 per segment once split.
+// Providers bill per segment, so templates should be written with these
+// limits in mind.
+//
+// Two senders are provided: LogSender, a fake that writes each message as
+// a JSON line for local development and tests, and HTTPSender, an adapter
+// for providers that accept a JSON POST.
+//
+// Providers report the final outcome of a message with a DeliveryReport
+// posted to our webhook and signed with a shared secret, see
+// VerifyCallback. StandInProvider plays the provider's side locally.
+package sms
+
+import "errors"
+
+// Errors returned by the senders
+var (
+	// ErrInvalidMessage is returned for messages without a recipient or body
+	ErrInvalidMessage = errors.New("SMS message needs a recipient and a body")
+
+	// ErrRejected is returned when the provider does not accept a message
+	ErrRejected = errors.New("SMS provider rejected the message")
+)
+
+// Message is a text message to a single phone number in E.164 format
+type Message struct {
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+// validate checks the fields every provider needs
+func (m Message) validate() error {
+	if m.To == "" || m.Body == "" {
+		return ErrInvalidMessage
+	}
+	return nil
+}
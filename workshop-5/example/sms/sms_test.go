@@ -0,0 +1,186 @@
+package sms_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/mike/sms"
+)
+
+func TestCountSegments(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want sms.Count
+	}{
+		{"empty", "", sms.Count{Encoding: sms.GSM7, Units: 0, Segments: 1}},
+		{"plain ASCII", "Order #A1B2 paid", sms.Count{Encoding: sms.GSM7, Units: 16, Segments: 1}},
+		{"GSM-7 at the limit", strings.Repeat("a", 160), sms.Count{Encoding: sms.GSM7, Units: 160, Segments: 1}},
+		{"GSM-7 split", strings.Repeat("a", 161), sms.Count{Encoding: sms.GSM7, Units: 161, Segments: 2}},
+		{"extension characters count twice", strings.Repeat("€", 80), sms.Count{Encoding: sms.GSM7, Units: 160, Segments: 1}},
+		{"extension character is not split", strings.Repeat("a", 152) + "€" + strings.Repeat("a", 10), sms.Count{Encoding: sms.GSM7, Units: 164, Segments: 2}},
+		{"Thai", "ขอบคุณค่ะ", sms.Count{Encoding: sms.UCS2, Units: 9, Segments: 1}},
+		{"UCS-2 at the limit", strings.Repeat("ก", 70), sms.Count{Encoding: sms.UCS2, Units: 70, Segments: 1}},
+		{"UCS-2 split", strings.Repeat("ก", 71), sms.Count{Encoding: sms.UCS2, Units: 71, Segments: 2}},
+		{"UCS-2 three segments", strings.Repeat("ก", 135), sms.Count{Encoding: sms.UCS2, Units: 135, Segments: 3}},
+		{"one Thai character switches encoding", strings.Repeat("a", 100) + "ก", sms.Count{Encoding: sms.UCS2, Units: 101, Segments: 2}},
+		{"emoji take a surrogate pair", strings.Repeat("☕", 2) + "😀", sms.Count{Encoding: sms.UCS2, Units: 4, Segments: 1}},
+		{"surrogate pair is not split", strings.Repeat("ก", 66) + "😀" + strings.Repeat("ก", 10), sms.Count{Encoding: sms.UCS2, Units: 78, Segments: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sms.CountSegments(tt.body); got != tt.want {
+				t.Fatalf("CountSegments = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogSender(t *testing.T) {
+	var buf bytes.Buffer
+	sender := sms.NewLogSender(&buf)
+
+	ref, err := sender.Send(sms.Message{To: "+66812345678", Body: "ขอบคุณค่ะ"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if !strings.HasPrefix(ref, "log-") {
+		t.Fatalf("unexpected ref %q", ref)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if line["ref"] != ref || line["to"] != "+66812345678" || line["encoding"] != "UCS-2" || line["segments"] != 1.0 {
+		t.Fatalf("unexpected log line: %v", line)
+	}
+
+	if _, err := sender.Send(sms.Message{To: "+66812345678"}); !errors.Is(err, sms.ErrInvalidMessage) {
+		t.Fatalf("expected %v, got %v", sms.ErrInvalidMessage, err)
+	}
+	if sent := sender.Sent(); len(sent) != 1 || sent[0].Body != "ขอบคุณค่ะ" {
+		t.Fatalf("unexpected sent messages: %+v", sent)
+	}
+}
+
+func TestHTTPSender(t *testing.T) {
+	var got map[string]string
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if got["to"] == "+66800000000" {
+			http.Error(w, `{"error":"blocked number"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		w.Write([]byte(`{"message_id":"msg-42"}`))
+	}))
+	defer provider.Close()
+
+	sender := sms.NewHTTPSender(sms.HTTPConfig{URL: provider.URL, Token: "secret", From: "LBK"})
+	ref, err := sender.Send(sms.Message{To: "+66812345678", Body: "ขอบคุณค่ะ"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if ref != "msg-42" || got["from"] != "LBK" || got["body"] != "ขอบคุณค่ะ" || got["encoding"] != "UCS-2" {
+		t.Fatalf("unexpected ref %q or request %v", ref, got)
+	}
+
+	_, err = sender.Send(sms.Message{To: "+66800000000", Body: "Hi"})
+	if !errors.Is(err, sms.ErrRejected) || !strings.Contains(err.Error(), "blocked number") {
+		t.Fatalf("expected a rejection with the provider's reason, got %v", err)
+	}
+
+	unauthorized := sms.NewHTTPSender(sms.HTTPConfig{URL: provider.URL})
+	if _, err := unauthorized.Send(sms.Message{To: "+66812345678", Body: "Hi"}); !errors.Is(err, sms.ErrRejected) {
+		t.Fatalf("expected %v, got %v", sms.ErrRejected, err)
+	}
+}
+
+func TestVerifyCallback(t *testing.T) {
+	secret := []byte("webhook-secret")
+	body := []byte(`{"message_id":"msg-42","status":"delivered"}`)
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := sms.SignCallback(secret, now, body)
+
+	if err := sms.VerifyCallback(secret, timestamp, signature, body, now); err != nil {
+		t.Fatalf("VerifyCallback: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    []byte
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		want      error
+	}{
+		{"edited body", secret, timestamp, signature, []byte(`{"message_id":"msg-42","status":"failed"}`), now, sms.ErrBadSignature},
+		{"wrong secret", []byte("other"), timestamp, signature, body, now, sms.ErrBadSignature},
+		{"edited timestamp", secret, strconv.FormatInt(now.Unix()+1, 10), signature, body, now, sms.ErrBadSignature},
+		{"no scheme", secret, timestamp, strings.TrimPrefix(signature, "sha256="), body, now, sms.ErrBadSignature},
+		{"replayed later", secret, timestamp, signature, body, now.Add(sms.MaxCallbackAge + time.Second), sms.ErrStaleCallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := sms.VerifyCallback(tt.secret, tt.timestamp, tt.signature, tt.body, tt.now); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestStandInProvider(t *testing.T) {
+	secret := []byte("webhook-secret")
+	var reports []sms.DeliveryReport
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if sms.VerifyCallback(secret, r.Header.Get(sms.TimestampHeader), r.Header.Get(sms.SignatureHeader), body, time.Now()) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var report sms.DeliveryReport
+		json.Unmarshal(body, &report)
+		reports = append(reports, report)
+	}))
+	defer receiver.Close()
+
+	provider := sms.NewStandInProvider(sms.StandInConfig{CallbackURL: receiver.URL, Secret: secret})
+	server := httptest.NewServer(provider)
+	defer server.Close()
+
+	ref, err := sms.NewHTTPSender(sms.HTTPConfig{URL: server.URL}).Send(sms.Message{To: "+66812345678", Body: "Hi"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if msg := provider.Messages()[ref]; msg.To != "+66812345678" || msg.Body != "Hi" {
+		t.Fatalf("message %q not recorded: %+v", ref, provider.Messages())
+	}
+
+	status, err := provider.Report(ref, sms.Failed, "handset off")
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Report: status %d, err %v", status, err)
+	}
+	if len(reports) != 1 || reports[0] != (sms.DeliveryReport{MessageID: ref, Status: sms.Failed, Error: "handset off"}) {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+	if _, err := provider.Report("missing", sms.Delivered, ""); !errors.Is(err, sms.ErrUnknownMessage) {
+		t.Fatalf("expected %v, got %v", sms.ErrUnknownMessage, err)
+	}
+}
@@ -0,0 +1,121 @@
+package sms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrUnknownMessage is returned when reporting on a message the stand-in
+// provider never accepted
+var ErrUnknownMessage = errors.New("unknown SMS message")
+
+// StandInConfig configures a StandInProvider
+type StandInConfig struct {
+	// CallbackURL receives the signed delivery reports
+	CallbackURL string
+
+	// Secret signs the delivery reports
+	Secret []byte
+
+	// Client posts the delivery reports; it defaults to a client with
+	// DefaultHTTPTimeout
+	Client *http.Client
+}
+
+// StandInProvider is a local stand-in for an SMS provider. It serves the
+// API HTTPSender talks to, accepting every message, and posts signed
+// delivery reports to the callback URL when told to, so the whole
+// send-and-deliver flow runs without a real provider.
+type StandInProvider struct {
+	config StandInConfig
+
+	mu       sync.Mutex
+	next     int
+	messages map[string]Message
+}
+
+// NewStandInProvider creates a stand-in provider reporting to
+// config.CallbackURL
+func NewStandInProvider(config StandInConfig) *StandInProvider {
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	return &StandInProvider{
+		config:   config,
+		messages: make(map[string]Message),
+	}
+}
+
+// ServeHTTP accepts a message posted by HTTPSender and answers with its
+// message ID
+func (p *StandInProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req httpRequest
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+		http.Error(w, `{"error":"expected a JSON message"}`, http.StatusBadRequest)
+		return
+	}
+	msg := Message{To: req.To, Body: req.Body}
+	if msg.validate() != nil {
+		http.Error(w, `{"error":"to and body are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	p.next++
+	id := "standin-" + strconv.Itoa(p.next)
+	p.messages[id] = msg
+	p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(httpResponse{MessageID: id})
+}
+
+// Messages returns the accepted messages by message ID
+func (p *StandInProvider) Messages() map[string]Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	messages := make(map[string]Message, len(p.messages))
+	for id, msg := range p.messages {
+		messages[id] = msg
+	}
+	return messages
+}
+
+// Report posts a signed delivery report for an accepted message and
+// returns the receiver's HTTP status
+func (p *StandInProvider) Report(messageID string, status DeliveryStatus, reason string) (int, error) {
+	p.mu.Lock()
+	_, ok := p.messages[messageID]
+	p.mu.Unlock()
+	if !ok {
+		return 0, ErrUnknownMessage
+	}
+
+	body, err := json.Marshal(DeliveryReport{MessageID: messageID, Status: status, Error: reason})
+	if err != nil {
+		return 0, fmt.Errorf("encode delivery report: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.config.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create delivery report: %w", err)
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, SignCallback(p.config.Secret, now, body))
+
+	resp, err := p.config.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("post delivery report: %w", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"slices"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+)
+
+// Operation names a usecase operation guarded by the access policy
+type Operation string
+
+// Guarded operations. Reading a receipt counts as reading its order.
+const (
+	OpGetUser     Operation = "user.get"
+	OpUpdateUser  Operation = "user.update"
+	OpDeleteUser  Operation = "user.delete"
+	OpListUsers   Operation = "user.list"
+	OpSetUserRole Operation = "user.set_role"
+
+	OpReadLedger         Operation = "ledger.read"
+	OpPostLedgerEntry    Operation = "ledger.post"
+	OpReverseLedgerEntry Operation = "ledger.reverse"
+
+	OpManageCart Operation = "cart.manage"
+	OpCheckout   Operation = "cart.checkout"
+
+	OpGetOrder     Operation = "order.get"
+	OpFulfillOrder Operation = "order.fulfill"
+	OpVoidOrder    Operation = "order.void"
+	OpRefundOrder  Operation = "order.refund"
+
+	OpCreateTransfer Operation = "transfer.create"
+	OpGetTransfer    Operation = "transfer.get"
+
+	OpCreateQRRequest Operation = "qr.create"
+	OpPayQRRequest    Operation = "qr.pay"
+	OpGetQRRequest    Operation = "qr.get"
+
+	OpManageProducts Operation = "product.manage"
+	OpManageOutbox   Operation = "outbox.manage"
+	OpManageAPIKeys  Operation = "api_key.manage"
+)
+
+// Permission says who may perform an operation: users with at least role
+// Any on every resource, and, when Own is set, every user on resources
+// they own. An empty Any lets nobody act on other users' resources.
+type Permission struct {
+	Any entity.Role
+	Own bool
+}
+
+// AccessPolicy maps each guarded operation to its permission. Operations
+// missing from the policy are denied to everyone.
+type AccessPolicy map[Operation]Permission
+
+// DefaultAccessPolicy lets members act on their own account, staff serve
+// customers and fulfil orders, and admins do everything else. Nobody moves
+// points out of another member's account.
+var DefaultAccessPolicy = AccessPolicy{
+	OpGetUser:     {Any: entity.RoleStaff, Own: true},
+	OpUpdateUser:  {Any: entity.RoleAdmin, Own: true},
+	OpDeleteUser:  {Any: entity.RoleAdmin, Own: true},
+	OpListUsers:   {Any: entity.RoleAdmin},
+	OpSetUserRole: {Any: entity.RoleAdmin},
+
+	OpReadLedger:         {Any: entity.RoleStaff, Own: true},
+	OpPostLedgerEntry:    {Any: entity.RoleStaff},
+	OpReverseLedgerEntry: {Any: entity.RoleAdmin},
+
+	OpManageCart: {Any: entity.RoleStaff, Own: true},
+	OpCheckout:   {Any: entity.RoleStaff, Own: true},
+
+	OpGetOrder:     {Any: entity.RoleStaff, Own: true},
+	OpFulfillOrder: {Any: entity.RoleStaff},
+	OpVoidOrder:    {Any: entity.RoleStaff},
+	OpRefundOrder:  {Any: entity.RoleStaff},
+
+	OpCreateTransfer: {Own: true},
+	OpGetTransfer:    {Any: entity.RoleStaff, Own: true},
+
+	OpCreateQRRequest: {Own: true},
+	OpPayQRRequest:    {Own: true},
+	OpGetQRRequest:    {Any: entity.RoleMember},
+
+	OpManageProducts: {Any: entity.RoleAdmin},
+	OpManageOutbox:   {Any: entity.RoleAdmin},
+	OpManageAPIKeys:  {Any: entity.RoleAdmin},
+}
+
+// ErrForbidden is returned when the policy denies an operation
+var ErrForbidden = apperror.Forbidden("You are not allowed to perform this operation")
+
+// Authorize reports whether user may perform op on a resource owned by
+// ownerIDs, e.g. both parties of a transfer. Operations on resources
+// without an owner pass none.
+func (p AccessPolicy) Authorize(user *entity.User, op Operation, ownerIDs ...string) error {
+	perm, ok := p[op]
+	if !ok || user == nil {
+		return ErrForbidden
+	}
+	if perm.Any != "" && user.Role.AtLeast(perm.Any) {
+		return nil
+	}
+	if perm.Own && slices.Contains(ownerIDs, user.ID) {
+		return nil
+	}
+	return ErrForbidden
+}
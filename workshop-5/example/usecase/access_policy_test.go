@@ -0,0 +1,53 @@
+package usecase_test
+
+import (
+	"errors"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/usecase"
+)
+
+func TestAccessPolicyAuthorize(t *testing.T) {
+	member := &entity.User{ID: "u1", Role: entity.RoleMember}
+	staff := &entity.User{ID: "u2", Role: entity.RoleStaff}
+	admin := &entity.User{ID: "u3", Role: entity.RoleAdmin}
+	unknown := &entity.User{ID: "u4", Role: "owner"}
+
+	tests := []struct {
+		name     string
+		user     *entity.User
+		op       usecase.Operation
+		ownerIDs []string
+		allowed  bool
+	}{
+		{"member reads own account", member, usecase.OpGetUser, []string{"u1"}, true},
+		{"member reads another account", member, usecase.OpGetUser, []string{"u9"}, false},
+		{"staff reads any account", staff, usecase.OpGetUser, []string{"u9"}, true},
+		{"staff updates another account", staff, usecase.OpUpdateUser, []string{"u9"}, false},
+		{"admin lists users", admin, usecase.OpListUsers, nil, true},
+		{"staff lists users", staff, usecase.OpListUsers, nil, false},
+		{"staff checks out for a customer", staff, usecase.OpCheckout, []string{"u9"}, true},
+		{"admin reverses an entry", admin, usecase.OpReverseLedgerEntry, nil, true},
+		{"staff reverses an entry", staff, usecase.OpReverseLedgerEntry, nil, false},
+		{"recipient reads a transfer", member, usecase.OpGetTransfer, []string{"u9", "u1"}, true},
+		{"admin sends another member's points", admin, usecase.OpCreateTransfer, []string{"u9"}, false},
+		{"admin manages API keys", admin, usecase.OpManageAPIKeys, nil, true},
+		{"staff manages API keys", staff, usecase.OpManageAPIKeys, nil, false},
+		{"unknown role", unknown, usecase.OpGetQRRequest, nil, false},
+		{"no user", nil, usecase.OpGetQRRequest, nil, false},
+		{"unknown operation", admin, "user.impersonate", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := usecase.DefaultAccessPolicy.Authorize(tt.user, tt.op, tt.ownerIDs...)
+			if tt.allowed && err != nil {
+				t.Fatalf("expected access, got %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, usecase.ErrForbidden) {
+				t.Fatalf("expected %v, got %v", usecase.ErrForbidden, err)
+			}
+		})
+	}
+}
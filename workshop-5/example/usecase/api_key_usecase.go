@@ -0,0 +1,307 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"slices"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/auth"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/validation"
+)
+
+// APIKeyPolicy holds the defaults and lifetimes of partner API keys
+type APIKeyPolicy struct {
+	// DefaultRateLimit is the requests per minute of keys created without
+	// a rate limit
+	DefaultRateLimit int
+
+	// RotationGrace is how long the previous secret of a rotated key keeps
+	// working, so the partner can deploy the new one without downtime
+	RotationGrace time.Duration
+}
+
+// DefaultAPIKeyPolicy is the API key policy used by the server
+var DefaultAPIKeyPolicy = APIKeyPolicy{
+	DefaultRateLimit: 60,
+	RotationGrace:    24 * time.Hour,
+}
+
+// CreateAPIKeyRequest represents an admin creating a partner API key
+type CreateAPIKeyRequest struct {
+	Name      string            `json:"name" validate:"required,max=100" example:"Lumpini branch POS"`
+	Scopes    []entity.APIScope `json:"scopes" validate:"required,min=1,dive,oneof=points:read points:award" example:"points:award"`
+	RateLimit int               `json:"rate_limit" validate:"min=0,max=6000" example:"60"` // requests per minute; 0 uses the default
+}
+
+// APIKeyResponse represents a single API key, without its secret
+type APIKeyResponse struct {
+	Success bool           `json:"success" example:"true"`
+	Key     *entity.APIKey `json:"key"`
+}
+
+// APIKeySecretResponse represents a created or rotated API key with its
+// secret. The secret is not stored and cannot be shown again.
+type APIKeySecretResponse struct {
+	Success bool           `json:"success" example:"true"`
+	Key     *entity.APIKey `json:"key"`
+	Secret  string         `json:"secret" example:"sk_8Jq2vX0mYk1pL9sT2wZ8aB4cD6eF7gH0jK1lM2nO3pQ"`
+}
+
+// ListAPIKeysResponse represents every API key, oldest first
+type ListAPIKeysResponse struct {
+	Success bool             `json:"success" example:"true"`
+	Keys    []*entity.APIKey `json:"keys"`
+	Count   int              `json:"count" example:"1"`
+}
+
+// Errors returned by APIKeyUsecase
+var (
+	// ErrAPIKeyNotFound is returned when managing a key that does not
+	// exist
+	ErrAPIKeyNotFound = apperror.NotFound("API key not found")
+
+	// ErrAPIKeyRevoked is returned when rotating a revoked key
+	ErrAPIKeyRevoked = apperror.Conflict("API key is revoked")
+
+	// ErrInvalidAPIKeySignature is returned for signed requests with a
+	// missing, unknown or revoked key, missing headers or a wrong
+	// signature. Unknown and revoked keys are not told apart, so the
+	// response cannot be used to probe for keys.
+	ErrInvalidAPIKeySignature = apperror.Unauthorized("Invalid API key or request signature")
+
+	// ErrInvalidNonce is returned for nonces that are not 16 to 128
+	// letters, digits, '-' or '_'
+	ErrInvalidNonce = apperror.Unauthorized("Nonce must be 16 to 128 letters, digits, '-' or '_'")
+
+	// ErrStaleSignedRequest is returned for correctly signed requests
+	// whose timestamp is more than auth.MaxRequestAge from the server's
+	// clock
+	ErrStaleSignedRequest = apperror.Unauthorized("Request timestamp is too old or in the future; check the clock")
+
+	// ErrNonceReused is returned when a key sends a nonce it used before,
+	// which is how a replayed request looks
+	ErrNonceReused = apperror.Unauthorized("Nonce was already used")
+
+	// ErrMissingScope is returned when a key calls an operation it was
+	// not granted
+	ErrMissingScope = apperror.Forbidden("API key lacks the scope for this operation")
+)
+
+// nonceFormat is the format of signed request nonces
+var nonceFormat = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
+// APIKeyUsecase defines partner API key management and the
+// authentication of signed partner requests
+type APIKeyUsecase interface {
+	// CreateKey creates a key on behalf of admin actorID and returns it
+	// with its secret
+	CreateKey(ctx context.Context, actorID string, req CreateAPIKeyRequest) (*APIKeySecretResponse, error)
+
+	// ListKeys returns every key, revoked ones included
+	ListKeys(ctx context.Context) (*ListAPIKeysResponse, error)
+
+	// RevokeKey revokes a key; its requests are refused from then on
+	RevokeKey(ctx context.Context, id string) (*APIKeyResponse, error)
+
+	// RotateKey gives a key a new secret and returns it. The previous
+	// secret keeps working for the policy's rotation grace.
+	RotateKey(ctx context.Context, id string) (*APIKeySecretResponse, error)
+
+	// Authenticate verifies a signed request made with key keyID and
+	// returns the key. Each nonce is accepted once per key.
+	Authenticate(ctx context.Context, keyID string, req auth.SignedRequest, signature string) (*entity.APIKey, error)
+
+	// PurgeExpiredNonces deletes the nonces of requests no longer fresh
+	// at now and returns how many were deleted
+	PurgeExpiredNonces(ctx context.Context, now time.Time) (int, error)
+}
+
+// apiKeyUsecase implements the APIKeyUsecase interface
+type apiKeyUsecase struct {
+	apiKeyRepo repository.APIKeyRepository
+	signer     *auth.Signer
+	policy     APIKeyPolicy
+}
+
+// NewAPIKeyUsecase creates a new API key usecase. Key secrets are derived
+// from each key's salt with signer, so replacing signer's secret
+// invalidates every key.
+func NewAPIKeyUsecase(apiKeyRepo repository.APIKeyRepository, signer *auth.Signer, policy APIKeyPolicy) APIKeyUsecase {
+	return &apiKeyUsecase{
+		apiKeyRepo: apiKeyRepo,
+		signer:     signer,
+		policy:     policy,
+	}
+}
+
+// CreateKey creates a key with a random ID and salt
+func (u *apiKeyUsecase) CreateKey(ctx context.Context, actorID string, req CreateAPIKeyRequest) (*APIKeySecretResponse, error) {
+	trimSpace(&req.Name)
+	if fields := validation.Struct(req); len(fields) > 0 {
+		return nil, apperror.Validation("Invalid API key", fields...)
+	}
+	if req.RateLimit == 0 {
+		req.RateLimit = u.policy.DefaultRateLimit
+	}
+
+	id, err := randomHex(12)
+	if err != nil {
+		return nil, apperror.Internal("Failed to generate API key", err)
+	}
+	salt, err := randomHex(16)
+	if err != nil {
+		return nil, apperror.Internal("Failed to generate API key", err)
+	}
+
+	key := entity.NewAPIKey("pk_"+id, req.Name, compactScopes(req.Scopes), req.RateLimit, salt, actorID)
+	if err := u.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, internalError(ctx, "Failed to create API key", err)
+	}
+
+	return &APIKeySecretResponse{
+		Success: true,
+		Key:     key,
+		Secret:  u.secret(key.ID, key.Salt),
+	}, nil
+}
+
+// ListKeys returns every key, oldest first
+func (u *apiKeyUsecase) ListKeys(ctx context.Context) (*ListAPIKeysResponse, error) {
+	keys, err := u.apiKeyRepo.List(ctx)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to list API keys", err)
+	}
+
+	return &ListAPIKeysResponse{
+		Success: true,
+		Keys:    keys,
+		Count:   len(keys),
+	}, nil
+}
+
+// RevokeKey revokes a key. Revoking a revoked key returns it unchanged.
+func (u *apiKeyUsecase) RevokeKey(ctx context.Context, id string) (*APIKeyResponse, error) {
+	key, err := u.apiKeyRepo.Revoke(ctx, id, time.Now())
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to revoke API key", err)
+	}
+
+	return &APIKeyResponse{
+		Success: true,
+		Key:     key,
+	}, nil
+}
+
+// RotateKey gives a key a new random salt
+func (u *apiKeyUsecase) RotateKey(ctx context.Context, id string) (*APIKeySecretResponse, error) {
+	salt, err := randomHex(16)
+	if err != nil {
+		return nil, apperror.Internal("Failed to generate API key", err)
+	}
+
+	now := time.Now()
+	key, err := u.apiKeyRepo.Rotate(ctx, id, salt, now.Add(u.policy.RotationGrace), now)
+	switch {
+	case errors.Is(err, repository.ErrAPIKeyNotFound):
+		return nil, ErrAPIKeyNotFound
+	case errors.Is(err, repository.ErrAPIKeyRevoked):
+		return nil, ErrAPIKeyRevoked
+	case err != nil:
+		return nil, internalError(ctx, "Failed to rotate API key", err)
+	}
+
+	return &APIKeySecretResponse{
+		Success: true,
+		Key:     key,
+		Secret:  u.secret(key.ID, key.Salt),
+	}, nil
+}
+
+// Authenticate checks the signature against the key's current secret and,
+// during a rotation's grace, its previous one. The nonce is only recorded
+// for correctly signed, fresh requests, and is kept until the request
+// would be refused as stale anyway.
+func (u *apiKeyUsecase) Authenticate(ctx context.Context, keyID string, req auth.SignedRequest, signature string) (*entity.APIKey, error) {
+	if keyID == "" || signature == "" || req.Timestamp == "" {
+		return nil, ErrInvalidAPIKeySignature
+	}
+	if !nonceFormat.MatchString(req.Nonce) {
+		return nil, ErrInvalidNonce
+	}
+
+	key, err := u.apiKeyRepo.GetByID(ctx, keyID)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKeySignature
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get API key", err)
+	}
+	if !key.Active() {
+		return nil, ErrInvalidAPIKeySignature
+	}
+
+	now := time.Now()
+	freshUntil, err := auth.VerifyRequest([]byte(u.secret(key.ID, key.Salt)), req, signature, now)
+	if errors.Is(err, auth.ErrBadRequestSignature) && key.PreviousSaltActive(now) {
+		freshUntil, err = auth.VerifyRequest([]byte(u.secret(key.ID, key.PreviousSalt)), req, signature, now)
+	}
+	switch {
+	case errors.Is(err, auth.ErrStaleRequest):
+		return nil, ErrStaleSignedRequest
+	case err != nil:
+		return nil, ErrInvalidAPIKeySignature
+	}
+
+	if err := u.apiKeyRepo.UseNonce(ctx, key.ID, req.Nonce, freshUntil); err != nil {
+		if errors.Is(err, repository.ErrNonceUsed) {
+			return nil, ErrNonceReused
+		}
+		return nil, internalError(ctx, "Failed to record nonce", err)
+	}
+	return key, nil
+}
+
+// PurgeExpiredNonces deletes nonces expired at now
+func (u *apiKeyUsecase) PurgeExpiredNonces(ctx context.Context, now time.Time) (int, error) {
+	deleted, err := u.apiKeyRepo.DeleteExpiredNonces(ctx, now)
+	if err != nil {
+		return 0, internalError(ctx, "Failed to purge nonces", err)
+	}
+	return deleted, nil
+}
+
+// secret derives the signing secret of key id from salt. Binding the ID
+// stops a salt from being moved to another key.
+func (u *apiKeyUsecase) secret(id, salt string) string {
+	return "sk_" + u.signer.MAC("api-key:"+id+":"+salt)
+}
+
+// compactScopes drops repeated scopes, keeping the first of each
+func compactScopes(scopes []entity.APIScope) []entity.APIScope {
+	var compact []entity.APIScope
+	for _, scope := range scopes {
+		if !slices.Contains(compact, scope) {
+			compact = append(compact, scope)
+		}
+	}
+	return compact
+}
+
+// randomHex returns n random bytes, hex-encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,211 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/auth"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+)
+
+// signedRequest signs a partner request made now with secret
+func signedRequest(secret, nonce string, at time.Time) (auth.SignedRequest, string) {
+	req := auth.SignedRequest{
+		Method:    "POST",
+		Path:      "/partner/users/u1/points",
+		Timestamp: strconv.FormatInt(at.Unix(), 10),
+		Nonce:     nonce,
+		Body:      []byte(`{"amount":100}`),
+	}
+	return req, auth.SignRequest([]byte(secret), req)
+}
+
+func newAPIKeyUsecase(t *testing.T, policy usecase.APIKeyPolicy) usecase.APIKeyUsecase {
+	t.Helper()
+	return usecase.NewAPIKeyUsecase(repository.NewMemoryAPIKeyRepository(), newTokenSigner(t), policy)
+}
+
+func createAPIKey(t *testing.T, uc usecase.APIKeyUsecase, scopes ...entity.APIScope) *usecase.APIKeySecretResponse {
+	t.Helper()
+	created, err := uc.CreateKey(context.Background(), "admin", usecase.CreateAPIKeyRequest{Name: " Lumpini POS ", Scopes: scopes})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	return created
+}
+
+func TestCreateAndListAPIKeys(t *testing.T) {
+	uc := newAPIKeyUsecase(t, usecase.DefaultAPIKeyPolicy)
+
+	created := createAPIKey(t, uc, entity.ScopePointsAward, entity.ScopePointsAward)
+	key := created.Key
+	if key.Name != "Lumpini POS" || key.RateLimit != usecase.DefaultAPIKeyPolicy.DefaultRateLimit ||
+		len(key.Scopes) != 1 || key.CreatedBy != "admin" || created.Secret == "" {
+		t.Fatalf("unexpected key: %+v", created)
+	}
+
+	// Each key gets its own secret
+	other := createAPIKey(t, uc, entity.ScopePointsRead)
+	if other.Key.ID == key.ID || other.Secret == created.Secret {
+		t.Fatalf("keys share an ID or secret: %+v %+v", created, other)
+	}
+
+	list, err := uc.ListKeys(context.Background())
+	if err != nil || list.Count != 2 || list.Keys[0].ID != key.ID {
+		t.Fatalf("ListKeys = %+v, %v", list, err)
+	}
+
+	tests := []struct {
+		name  string
+		req   usecase.CreateAPIKeyRequest
+		field string
+	}{
+		{"no name", usecase.CreateAPIKeyRequest{Name: " ", Scopes: []entity.APIScope{entity.ScopePointsRead}}, "name"},
+		{"no scopes", usecase.CreateAPIKeyRequest{Name: "POS"}, "scopes"},
+		{"unknown scope", usecase.CreateAPIKeyRequest{Name: "POS", Scopes: []entity.APIScope{"points:delete"}}, "scopes[0]"},
+		{"rate limit", usecase.CreateAPIKeyRequest{Name: "POS", Scopes: []entity.APIScope{entity.ScopePointsRead}, RateLimit: -1}, "rate_limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := uc.CreateKey(context.Background(), "admin", tt.req)
+			var appErr *apperror.Error
+			if !errors.As(err, &appErr) || appErr.Kind != apperror.KindValidation ||
+				len(appErr.Fields) != 1 || appErr.Fields[0].Field != tt.field {
+				t.Fatalf("expected a %s field error, got %v", tt.field, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateSignedRequest(t *testing.T) {
+	uc := newAPIKeyUsecase(t, usecase.DefaultAPIKeyPolicy)
+	created := createAPIKey(t, uc, entity.ScopePointsAward)
+	now := time.Now()
+
+	req, signature := signedRequest(created.Secret, "nonce-0000000001", now)
+	key, err := uc.Authenticate(context.Background(), created.Key.ID, req, signature)
+	if err != nil || key.ID != created.Key.ID {
+		t.Fatalf("Authenticate = %+v, %v", key, err)
+	}
+
+	// The same request again is a replay
+	if _, err := uc.Authenticate(context.Background(), created.Key.ID, req, signature); !errors.Is(err, usecase.ErrNonceReused) {
+		t.Fatalf("replay: expected %v, got %v", usecase.ErrNonceReused, err)
+	}
+
+	tampered, _ := signedRequest(created.Secret, "nonce-0000000002", now)
+	tampered.Body = []byte(`{"amount":100000}`)
+	_, tamperedSig := signedRequest(created.Secret, "nonce-0000000002", now)
+
+	stale, staleSig := signedRequest(created.Secret, "nonce-0000000003", now.Add(-auth.MaxRequestAge-time.Minute))
+	wrongKey, wrongSig := signedRequest("sk_other", "nonce-0000000004", now)
+	short, shortSig := signedRequest(created.Secret, "short", now)
+
+	tests := []struct {
+		name      string
+		keyID     string
+		req       auth.SignedRequest
+		signature string
+		want      error
+	}{
+		{"tampered body", created.Key.ID, tampered, tamperedSig, usecase.ErrInvalidAPIKeySignature},
+		{"wrong secret", created.Key.ID, wrongKey, wrongSig, usecase.ErrInvalidAPIKeySignature},
+		{"unknown key", "pk_missing", wrongKey, wrongSig, usecase.ErrInvalidAPIKeySignature},
+		{"no signature", created.Key.ID, wrongKey, "", usecase.ErrInvalidAPIKeySignature},
+		{"stale", created.Key.ID, stale, staleSig, usecase.ErrStaleSignedRequest},
+		{"short nonce", created.Key.ID, short, shortSig, usecase.ErrInvalidNonce},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := uc.Authenticate(context.Background(), tt.keyID, tt.req, tt.signature); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// A refused request does not use up its nonce
+	req, signature = signedRequest(created.Secret, "nonce-0000000002", now)
+	if _, err := uc.Authenticate(context.Background(), created.Key.ID, req, signature); err != nil {
+		t.Fatalf("nonce of a refused request: %v", err)
+	}
+}
+
+func TestRotateAndRevokeAPIKey(t *testing.T) {
+	uc := newAPIKeyUsecase(t, usecase.DefaultAPIKeyPolicy)
+	created := createAPIKey(t, uc, entity.ScopePointsRead)
+	id := created.Key.ID
+
+	rotated, err := uc.RotateKey(context.Background(), id)
+	if err != nil {
+		t.Fatalf("RotateKey: %v", err)
+	}
+	if rotated.Secret == created.Secret || rotated.Key.RotatedAt == nil || rotated.Key.PreviousExpiresAt == nil {
+		t.Fatalf("unexpected rotation: %+v", rotated)
+	}
+
+	// Both secrets work during the grace period
+	for i, secret := range []string{rotated.Secret, created.Secret} {
+		req, signature := signedRequest(secret, "rotation-nonce-00"+strconv.Itoa(i), time.Now())
+		if _, err := uc.Authenticate(context.Background(), id, req, signature); err != nil {
+			t.Fatalf("secret %d after rotation: %v", i, err)
+		}
+	}
+
+	revoked, err := uc.RevokeKey(context.Background(), id)
+	if err != nil || revoked.Key.Active() {
+		t.Fatalf("RevokeKey = %+v, %v", revoked, err)
+	}
+	req, signature := signedRequest(rotated.Secret, "revoked-nonce-0001", time.Now())
+	if _, err := uc.Authenticate(context.Background(), id, req, signature); !errors.Is(err, usecase.ErrInvalidAPIKeySignature) {
+		t.Fatalf("revoked key: expected %v, got %v", usecase.ErrInvalidAPIKeySignature, err)
+	}
+	if _, err := uc.RotateKey(context.Background(), id); !errors.Is(err, usecase.ErrAPIKeyRevoked) {
+		t.Fatalf("rotate revoked: expected %v, got %v", usecase.ErrAPIKeyRevoked, err)
+	}
+	for _, err := range []error{errOf(uc.RotateKey(context.Background(), "pk_missing")), errOf(uc.RevokeKey(context.Background(), "pk_missing"))} {
+		if !errors.Is(err, usecase.ErrAPIKeyNotFound) {
+			t.Fatalf("missing key: expected %v, got %v", usecase.ErrAPIKeyNotFound, err)
+		}
+	}
+}
+
+func TestPreviousSecretExpiresAfterGrace(t *testing.T) {
+	uc := newAPIKeyUsecase(t, usecase.APIKeyPolicy{DefaultRateLimit: 60, RotationGrace: time.Nanosecond})
+	created := createAPIKey(t, uc, entity.ScopePointsRead)
+	if _, err := uc.RotateKey(context.Background(), created.Key.ID); err != nil {
+		t.Fatalf("RotateKey: %v", err)
+	}
+
+	req, signature := signedRequest(created.Secret, "expired-nonce-0001", time.Now())
+	if _, err := uc.Authenticate(context.Background(), created.Key.ID, req, signature); !errors.Is(err, usecase.ErrInvalidAPIKeySignature) {
+		t.Fatalf("expected %v, got %v", usecase.ErrInvalidAPIKeySignature, err)
+	}
+}
+
+func TestPurgeExpiredNonces(t *testing.T) {
+	uc := newAPIKeyUsecase(t, usecase.DefaultAPIKeyPolicy)
+	created := createAPIKey(t, uc, entity.ScopePointsRead)
+	now := time.Now()
+	req, signature := signedRequest(created.Secret, "purged-nonce-0001", now)
+	if _, err := uc.Authenticate(context.Background(), created.Key.ID, req, signature); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	if deleted, err := uc.PurgeExpiredNonces(context.Background(), now); err != nil || deleted != 0 {
+		t.Fatalf("PurgeExpiredNonces while fresh = %d, %v", deleted, err)
+	}
+	if deleted, err := uc.PurgeExpiredNonces(context.Background(), now.Add(auth.MaxRequestAge)); err != nil || deleted != 1 {
+		t.Fatalf("PurgeExpiredNonces = %d, %v, want 1", deleted, err)
+	}
+}
+
+// errOf returns the error of a call returning a value and an error
+func errOf[T any](_ T, err error) error {
+	return err
+}
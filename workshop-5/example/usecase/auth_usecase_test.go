@@ -0,0 +1,433 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/auth"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/sms"
+	"example.com/mike/usecase"
+)
+
+// authFixture wires the login usecase to in-memory storage with one
+// registered user and a fake code sender
+type authFixture struct {
+	auth     usecase.AuthUsecase
+	users    usecase.UserUsecase
+	otps     repository.OTPRepository
+	refresh  repository.RefreshTokenRepository
+	sender   *auth.LogCodeSender
+	signer   *auth.Signer
+	user     *entity.User
+	userRepo repository.UserRepository
+}
+
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+	userRepo, ledgerRepo := repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository()
+	f := &authFixture{
+		users:    usecase.NewUserUsecase(userRepo, ledgerRepo),
+		otps:     repository.NewMemoryOTPRepository(),
+		refresh:  repository.NewMemoryRefreshTokenRepository(),
+		sender:   auth.NewLogCodeSender(&bytes.Buffer{}),
+		signer:   newTokenSigner(t),
+		userRepo: userRepo,
+	}
+	f.auth = usecase.NewAuthUsecase(userRepo, f.otps, f.refresh, f.sender, f.signer, usecase.DefaultAuthPolicy)
+	f.user = registerUser(t, f.users, 1).User
+	return f
+}
+
+// newTokenSigner returns a signer with a random secret
+func newTokenSigner(t *testing.T) *auth.Signer {
+	t.Helper()
+	signer, err := auth.NewSigner(bytes.Repeat([]byte("s"), auth.MinSecretLength))
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	return signer
+}
+
+// requestCode asks for a code by phone and returns the challenge ID with
+// the code that was sent
+func (f *authFixture) requestCode(t *testing.T) (string, string) {
+	t.Helper()
+	resp, err := f.auth.RequestCode(context.Background(), usecase.RequestCodeRequest{Phone: "081-000-0001"})
+	if err != nil {
+		t.Fatalf("RequestCode: %v", err)
+	}
+	sent := f.sender.Sent()
+	if len(sent) == 0 {
+		t.Fatal("no code was sent")
+	}
+	return resp.ChallengeID, sent[len(sent)-1].Code
+}
+
+// login requests and verifies a code
+func (f *authFixture) login(t *testing.T) *usecase.TokenResponse {
+	t.Helper()
+	challengeID, code := f.requestCode(t)
+	tokens, err := f.auth.VerifyCode(context.Background(), usecase.VerifyCodeRequest{ChallengeID: challengeID, Code: code})
+	if err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+	return tokens
+}
+
+func TestLoginWithPhoneCode(t *testing.T) {
+	f := newAuthFixture(t)
+
+	resp, err := f.auth.RequestCode(context.Background(), usecase.RequestCodeRequest{Phone: "081-000-0001"})
+	if err != nil {
+		t.Fatalf("RequestCode: %v", err)
+	}
+	if resp.ChallengeID == "" || resp.ExpiresIn != 300 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	sent := f.sender.Sent()
+	if len(sent) != 1 || sent[0].Channel != entity.OTPChannelPhone || sent[0].To != f.user.Phone || len(sent[0].Code) != 6 {
+		t.Fatalf("unexpected codes sent: %+v", sent)
+	}
+	challenge, err := f.otps.GetByID(context.Background(), resp.ChallengeID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if challenge.UserID != f.user.ID || challenge.CodeHash == sent[0].Code {
+		t.Fatalf("unexpected challenge: %+v", challenge)
+	}
+
+	tokens, err := f.auth.VerifyCode(context.Background(), usecase.VerifyCodeRequest{ChallengeID: resp.ChallengeID, Code: sent[0].Code})
+	if err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+	if tokens.UserID != f.user.ID || tokens.TokenType != "Bearer" || tokens.ExpiresIn != 900 || tokens.RefreshToken == "" {
+		t.Fatalf("unexpected tokens: %+v", tokens)
+	}
+
+	user, err := f.auth.Authenticate(context.Background(), tokens.AccessToken)
+	if err != nil || user.ID != f.user.ID {
+		t.Fatalf("Authenticate = %+v, %v", user, err)
+	}
+
+	// A code logs in once
+	if _, err := f.auth.VerifyCode(context.Background(), usecase.VerifyCodeRequest{ChallengeID: resp.ChallengeID, Code: sent[0].Code}); !errors.Is(err, usecase.ErrInvalidLoginCode) {
+		t.Fatalf("VerifyCode twice: expected %v, got %v", usecase.ErrInvalidLoginCode, err)
+	}
+}
+
+func TestLoginWithEmailCode(t *testing.T) {
+	f := newAuthFixture(t)
+
+	resp, err := f.auth.RequestCode(context.Background(), usecase.RequestCodeRequest{Email: " " + f.user.Email + " "})
+	if err != nil {
+		t.Fatalf("RequestCode: %v", err)
+	}
+	sent := f.sender.Sent()
+	if len(sent) != 1 || sent[0].Channel != entity.OTPChannelEmail || sent[0].To != f.user.Email {
+		t.Fatalf("unexpected codes sent: %+v", sent)
+	}
+	if _, err := f.auth.VerifyCode(context.Background(), usecase.VerifyCodeRequest{ChallengeID: resp.ChallengeID, Code: sent[0].Code}); err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+}
+
+func TestRequestCodeValidation(t *testing.T) {
+	f := newAuthFixture(t)
+
+	tests := []struct {
+		name string
+		req  usecase.RequestCodeRequest
+		want string
+	}{
+		{"neither", usecase.RequestCodeRequest{}, "phone"},
+		{"both", usecase.RequestCodeRequest{Phone: "0810000001", Email: "user1@example.com"}, "phone"},
+		{"bad phone", usecase.RequestCodeRequest{Phone: "12"}, "phone"},
+		{"bad email", usecase.RequestCodeRequest{Email: "not-an-email"}, "email"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.auth.RequestCode(context.Background(), tt.req)
+			if fields := fieldErrors(t, err); !fields[tt.want] {
+				t.Fatalf("expected a %s error, got %v", tt.want, fields)
+			}
+		})
+	}
+	if len(f.sender.Sent()) != 0 {
+		t.Fatal("a code was sent for an invalid request")
+	}
+}
+
+func TestRequestCodeDoesNotRevealUnknownAccounts(t *testing.T) {
+	f := newAuthFixture(t)
+
+	resp, err := f.auth.RequestCode(context.Background(), usecase.RequestCodeRequest{Phone: "+66899999999"})
+	if err != nil {
+		t.Fatalf("RequestCode: %v", err)
+	}
+	if !resp.Success || resp.ChallengeID == "" || resp.ExpiresIn != 300 {
+		t.Fatalf("unknown account got a different response: %+v", resp)
+	}
+	if len(f.sender.Sent()) != 0 {
+		t.Fatal("a code was sent to an unknown account")
+	}
+	if _, err := f.auth.VerifyCode(context.Background(), usecase.VerifyCodeRequest{ChallengeID: resp.ChallengeID, Code: "000000"}); !errors.Is(err, usecase.ErrInvalidLoginCode) {
+		t.Fatalf("VerifyCode: expected %v, got %v", usecase.ErrInvalidLoginCode, err)
+	}
+}
+
+func TestRequestCodeRejectsUnsupportedChannel(t *testing.T) {
+	f := newAuthFixture(t)
+	texts := sms.NewLogSender(&bytes.Buffer{})
+	uc := usecase.NewAuthUsecase(f.userRepo, f.otps, f.refresh, auth.NewSMSCodeSender(texts), f.signer, usecase.DefaultAuthPolicy)
+
+	// Rejected before the lookup, so the answer is the same for everyone
+	for _, email := range []string{f.user.Email, "nobody@example.com"} {
+		if _, err := uc.RequestCode(context.Background(), usecase.RequestCodeRequest{Email: email}); !errors.Is(err, usecase.ErrLoginChannelUnsupported) {
+			t.Fatalf("RequestCode(%s): expected %v, got %v", email, usecase.ErrLoginChannelUnsupported, err)
+		}
+	}
+	if _, err := uc.RequestCode(context.Background(), usecase.RequestCodeRequest{Phone: f.user.Phone}); err != nil || len(texts.Sent()) != 1 {
+		t.Fatalf("RequestCode by phone: %v, %d texts sent", err, len(texts.Sent()))
+	}
+}
+
+func TestVerifyCodeLimitsAttempts(t *testing.T) {
+	f := newAuthFixture(t)
+	challengeID, code := f.requestCode(t)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	for i := 0; i < usecase.DefaultAuthPolicy.MaxCodeAttempts; i++ {
+		if _, err := f.auth.VerifyCode(context.Background(), usecase.VerifyCodeRequest{ChallengeID: challengeID, Code: wrong}); !errors.Is(err, usecase.ErrInvalidLoginCode) {
+			t.Fatalf("attempt %d: expected %v, got %v", i+1, usecase.ErrInvalidLoginCode, err)
+		}
+	}
+	if _, err := f.auth.VerifyCode(context.Background(), usecase.VerifyCodeRequest{ChallengeID: challengeID, Code: code}); !errors.Is(err, usecase.ErrInvalidLoginCode) {
+		t.Fatalf("right code after the limit: expected %v, got %v", usecase.ErrInvalidLoginCode, err)
+	}
+	if fields := fieldErrors(t, func() error {
+		_, err := f.auth.VerifyCode(context.Background(), usecase.VerifyCodeRequest{})
+		return err
+	}()); !fields["challenge_id"] || !fields["code"] {
+		t.Fatalf("expected challenge_id and code errors, got %v", fields)
+	}
+}
+
+func TestVerifyCodeConcurrentlyLogsInOnce(t *testing.T) {
+	f := newAuthFixture(t)
+	challengeID, code := f.requestCode(t)
+
+	const workers = 5
+	var wg sync.WaitGroup
+	results := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f.auth.VerifyCode(context.Background(), usecase.VerifyCodeRequest{ChallengeID: challengeID, Code: code})
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+		} else if !errors.Is(err, usecase.ErrInvalidLoginCode) {
+			t.Errorf("VerifyCode: expected nil or %v, got %v", usecase.ErrInvalidLoginCode, err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one login, got %d", succeeded)
+	}
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	f := newAuthFixture(t)
+	first := f.login(t)
+
+	second, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == "" || second.UserID != f.user.ID {
+		t.Fatalf("unexpected tokens: %+v", second)
+	}
+	third, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{RefreshToken: second.RefreshToken})
+	if err != nil {
+		t.Fatalf("Refresh the successor: %v", err)
+	}
+
+	// Replaying a rotated token revokes the whole login
+	if _, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{RefreshToken: first.RefreshToken}); !errors.Is(err, usecase.ErrRefreshTokenReused) {
+		t.Fatalf("Refresh a rotated token: expected %v, got %v", usecase.ErrRefreshTokenReused, err)
+	}
+	if _, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{RefreshToken: third.RefreshToken}); !errors.Is(err, usecase.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh after reuse: expected %v, got %v", usecase.ErrInvalidRefreshToken, err)
+	}
+}
+
+func TestRefreshRejectsInvalidTokens(t *testing.T) {
+	f := newAuthFixture(t)
+	tokens := f.login(t)
+	id := tokens.RefreshToken[:36]
+
+	for _, token := range []string{"garbage", id + ".wrong-secret", "missing." + tokens.RefreshToken[37:]} {
+		if _, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{RefreshToken: token}); !errors.Is(err, usecase.ErrInvalidRefreshToken) {
+			t.Fatalf("Refresh(%q): expected %v, got %v", token, usecase.ErrInvalidRefreshToken, err)
+		}
+	}
+	if _, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{}); !errors.Is(err, apperror.ErrValidation) {
+		t.Fatalf("Refresh without a token: expected a validation error, got %v", err)
+	}
+
+	// A wrong secret does not revoke the real token
+	if _, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{RefreshToken: tokens.RefreshToken}); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+}
+
+func TestLogoutRevokesRefreshTokens(t *testing.T) {
+	f := newAuthFixture(t)
+	first := f.login(t)
+	second, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	other := f.login(t)
+
+	if err := f.auth.Logout(context.Background(), usecase.RefreshRequest{RefreshToken: second.RefreshToken}); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if err := f.auth.Logout(context.Background(), usecase.RefreshRequest{RefreshToken: second.RefreshToken}); err != nil {
+		t.Fatalf("Logout twice: %v", err)
+	}
+	if _, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{RefreshToken: second.RefreshToken}); !errors.Is(err, usecase.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh after logout: expected %v, got %v", usecase.ErrInvalidRefreshToken, err)
+	}
+	if _, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{RefreshToken: other.RefreshToken}); err != nil {
+		t.Fatalf("other login was logged out: %v", err)
+	}
+}
+
+func TestAuthenticateRejectsInvalidTokens(t *testing.T) {
+	f := newAuthFixture(t)
+	tokens := f.login(t)
+
+	expired, err := f.signer.Sign(auth.Claims{
+		Subject:   f.user.ID,
+		ID:        "t1",
+		IssuedAt:  time.Now().Add(-time.Hour),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	other, _ := auth.NewSigner(bytes.Repeat([]byte("o"), auth.MinSecretLength))
+	forged, _ := other.Sign(auth.Claims{Subject: f.user.ID, ID: "t2", IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)})
+
+	for _, token := range []string{"", "garbage", expired, forged, tokens.RefreshToken} {
+		if _, err := f.auth.Authenticate(context.Background(), token); !errors.Is(err, usecase.ErrInvalidAccessToken) {
+			t.Fatalf("Authenticate(%q): expected %v, got %v", token, usecase.ErrInvalidAccessToken, err)
+		}
+	}
+
+	// Tokens of deleted users stop working
+	if err := f.users.DeleteUser(context.Background(), f.user.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := f.auth.Authenticate(context.Background(), tokens.AccessToken); !errors.Is(err, usecase.ErrInvalidAccessToken) {
+		t.Fatalf("Authenticate a deleted user: expected %v, got %v", usecase.ErrInvalidAccessToken, err)
+	}
+	if _, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{RefreshToken: tokens.RefreshToken}); !errors.Is(err, usecase.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh for a deleted user: expected %v, got %v", usecase.ErrInvalidRefreshToken, err)
+	}
+}
+
+func TestCancelledLoginsAreUnavailable(t *testing.T) {
+	f := newAuthFixture(t)
+	tokens := f.login(t)
+	challengeID, code := f.requestCode(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := []struct {
+		name string
+		err  error
+	}{
+		{"RequestCode", errOf(f.auth.RequestCode(ctx, usecase.RequestCodeRequest{Phone: "081-000-0001"}))},
+		{"Refresh", errOf(f.auth.Refresh(ctx, usecase.RefreshRequest{RefreshToken: tokens.RefreshToken}))},
+		{"Authenticate", errOf(f.auth.Authenticate(ctx, tokens.AccessToken))},
+	}
+	for _, call := range calls {
+		if !errors.Is(call.err, apperror.ErrUnavailable) {
+			t.Errorf("%s: expected an unavailable error, got %v", call.name, call.err)
+		}
+	}
+
+	// The refresh token was not rotated, so it still works
+	if _, err := f.auth.Refresh(context.Background(), usecase.RefreshRequest{RefreshToken: tokens.RefreshToken}); err != nil {
+		t.Fatalf("Refresh after a cancelled refresh: %v", err)
+	}
+
+	// VerifyCode only reads the user to promote admins
+	policy := usecase.DefaultAuthPolicy
+	policy.AdminEmails = []string{"admin@example.com"}
+	f.auth = usecase.NewAuthUsecase(f.userRepo, f.otps, f.refresh, f.sender, f.signer, policy)
+	if _, err := f.auth.VerifyCode(ctx, usecase.VerifyCodeRequest{ChallengeID: challengeID, Code: code}); !errors.Is(err, apperror.ErrUnavailable) {
+		t.Fatalf("VerifyCode: expected an unavailable error, got %v", err)
+	}
+}
+
+func TestPurgeExpiredLogins(t *testing.T) {
+	f := newAuthFixture(t)
+	f.login(t)
+	f.requestCode(t)
+
+	purged, err := f.auth.PurgeExpired(context.Background(), time.Now().Add(usecase.DefaultAuthPolicy.RefreshTTL+time.Minute))
+	if err != nil || purged != 3 {
+		t.Fatalf("PurgeExpired = %d, %v, want 3", purged, err)
+	}
+}
+
+func TestVerifyCodePromotesAdminEmails(t *testing.T) {
+	f := newAuthFixture(t)
+	policy := usecase.DefaultAuthPolicy
+	policy.AdminEmails = []string{"USER1@example.com"}
+	f.auth = usecase.NewAuthUsecase(f.userRepo, f.otps, f.refresh, f.sender, f.signer, policy)
+
+	f.login(t)
+	user, err := f.userRepo.GetByID(context.Background(), f.user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if user.Role != entity.RoleAdmin {
+		t.Fatalf("Role = %q, want admin", user.Role)
+	}
+
+	// Other users log in with their role unchanged
+	other := registerUser(t, f.users, 2).User
+	resp, err := f.auth.RequestCode(context.Background(), usecase.RequestCodeRequest{Email: other.Email})
+	if err != nil {
+		t.Fatalf("RequestCode: %v", err)
+	}
+	sent := f.sender.Sent()
+	if _, err := f.auth.VerifyCode(context.Background(), usecase.VerifyCodeRequest{ChallengeID: resp.ChallengeID, Code: sent[len(sent)-1].Code}); err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+	if user, err := f.userRepo.GetByID(context.Background(), other.ID); err != nil || user.Role != entity.RoleMember {
+		t.Fatalf("other user = %+v, %v", user, err)
+	}
+}
@@ -0,0 +1,448 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/validation"
+	"github.com/google/uuid"
+)
+
+// MaxCartQuantity bounds the quantity of a single product in a cart
+const MaxCartQuantity = 99
+
+// CartItemRequest represents a product to add to a cart. Adding a product
+// already in the cart increases its quantity.
+type CartItemRequest struct {
+	ProductID string `json:"product_id" validate:"required" example:"3e4f5a6b-7c8d-4e9f-a0b1-c2d3e4f5a6b7"`
+	Quantity  int    `json:"quantity" validate:"required,min=1,max=99" example:"2"`
+}
+
+// UpdateCartItemRequest represents the new quantity of a product in a cart
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" validate:"required,min=1,max=99" example:"3"`
+}
+
+// CartCustomerRequest represents the customer an order is placed for,
+// copied onto the order at checkout. The phone number may be in E.164 or
+// Thai national format.
+type CartCustomerRequest struct {
+	Name  string `json:"name" validate:"required,max=100,person_name" example:"Somchai Jaidee"`
+	Phone string `json:"phone" validate:"required,phone" example:"081-234-5678"`
+}
+
+// CheckoutRequest represents a request to buy everything in a member's
+// cart. Language selects the SMS receipt template, Thai by default.
+type CheckoutRequest struct {
+	UserID   string `json:"user_id" validate:"required" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Language string `json:"language" validate:"omitempty,oneof=th en" example:"th"`
+
+	// Actor is who places the order: the member, or a staff member serving
+	// them. It is set by the caller, never read from the body, and defaults
+	// to the member.
+	Actor string `json:"-"`
+}
+
+// CartLine is a cart item priced at the current catalog price. Available
+// is false when the product was removed, taken off sale or has too little
+// stock; such lines must be changed before checkout.
+type CartLine struct {
+	ProductID string `json:"product_id" example:"3e4f5a6b-7c8d-4e9f-a0b1-c2d3e4f5a6b7"`
+	Name      string `json:"name" example:"Iced Latte"`
+	UnitPrice int    `json:"unit_price" example:"120"`
+	Quantity  int    `json:"quantity" example:"2"`
+	LineTotal int    `json:"line_total" example:"240"`
+	Available bool   `json:"available" example:"true"`
+}
+
+// CartResponse represents a member's cart with its subtotal in points
+type CartResponse struct {
+	Success       bool       `json:"success" example:"true"`
+	Message       string     `json:"message" example:"Cart found"`
+	UserID        string     `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Items         []CartLine `json:"items"`
+	Subtotal      int        `json:"subtotal" example:"240"`
+	CustomerName  string     `json:"customer_name,omitempty" example:"Somchai Jaidee"`
+	CustomerPhone string     `json:"customer_phone,omitempty" example:"+66812345678"`
+}
+
+// Errors returned by CartUsecase
+var (
+	// ErrCartProductNotFound is returned when adding a product that does
+	// not exist
+	ErrCartProductNotFound = apperror.Validation("Invalid cart item",
+		apperror.FieldError{Field: "product_id", Message: "product not found"})
+
+	// ErrCartItemNotFound is returned when changing a product that is not
+	// in the cart
+	ErrCartItemNotFound = apperror.NotFound("Cart item not found")
+
+	// ErrCartQuantityTooLarge is returned when adding would take a product
+	// over MaxCartQuantity
+	ErrCartQuantityTooLarge = apperror.Validation("Invalid cart item",
+		apperror.FieldError{Field: "quantity", Message: "quantity in cart must be at most 99"})
+
+	// ErrCartEmpty is returned when checking out an empty cart
+	ErrCartEmpty = apperror.Conflict("Cart is empty")
+
+	// ErrProductInactive is returned when a product in the request or cart
+	// has been taken off sale or removed from the catalog
+	ErrProductInactive = apperror.Conflict("Product is no longer available")
+
+	// ErrOutOfStock is returned when a product has fewer units in stock
+	// than requested
+	ErrOutOfStock = apperror.Conflict("Not enough stock")
+)
+
+// CartUsecase defines the cart and checkout operations. Carts are kept on
+// the server, one per member, and priced from the catalog when read.
+type CartUsecase interface {
+	// GetCart retrieves a member's cart
+	GetCart(ctx context.Context, userID string) (*CartResponse, error)
+
+	// AddItem adds a product to a member's cart
+	AddItem(ctx context.Context, userID string, req CartItemRequest) (*CartResponse, error)
+
+	// UpdateItem sets the quantity of a product in a member's cart
+	UpdateItem(ctx context.Context, userID, productID string, req UpdateCartItemRequest) (*CartResponse, error)
+
+	// RemoveItem removes a product from a member's cart
+	RemoveItem(ctx context.Context, userID, productID string) (*CartResponse, error)
+
+	// SetCustomer attaches the customer's name and phone number to a
+	// member's cart
+	SetCustomer(ctx context.Context, userID string, req CartCustomerRequest) (*CartResponse, error)
+
+	// Checkout buys everything in a member's cart with points and texts
+	// the customer a receipt
+	Checkout(ctx context.Context, req CheckoutRequest) (*OrderResponse, error)
+}
+
+// cartUsecase implements the CartUsecase interface
+type cartUsecase struct {
+	userRepo    repository.UserRepository
+	productRepo repository.ProductRepository
+	cartRepo    repository.CartRepository
+	orderRepo   repository.OrderRepository
+	ledgerRepo  repository.LedgerRepository
+	receipts    ReceiptUsecase
+	outbox      OutboxUsecase
+
+	// carts serialises changes per member so concurrent edits and
+	// checkouts of the same cart cannot interleave
+	carts keyedMutex
+}
+
+// NewCartUsecase creates a new cart and checkout usecase
+func NewCartUsecase(
+	userRepo repository.UserRepository,
+	productRepo repository.ProductRepository,
+	cartRepo repository.CartRepository,
+	orderRepo repository.OrderRepository,
+	ledgerRepo repository.LedgerRepository,
+	receipts ReceiptUsecase,
+	outbox OutboxUsecase,
+) CartUsecase {
+	return &cartUsecase{
+		userRepo:    userRepo,
+		productRepo: productRepo,
+		cartRepo:    cartRepo,
+		orderRepo:   orderRepo,
+		ledgerRepo:  ledgerRepo,
+		receipts:    receipts,
+		outbox:      outbox,
+	}
+}
+
+// GetCart retrieves a member's cart
+func (u *cartUsecase) GetCart(ctx context.Context, userID string) (*CartResponse, error) {
+	cart, err := u.getCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return u.respond(ctx, cart, "Cart found")
+}
+
+// AddItem adds a product to a member's cart, checking that it is on sale
+// and in stock
+func (u *cartUsecase) AddItem(ctx context.Context, userID string, req CartItemRequest) (*CartResponse, error) {
+	trimSpace(&req.ProductID)
+	if fields := validation.Struct(req); len(fields) > 0 {
+		return nil, apperror.Validation("Invalid cart item", fields...)
+	}
+
+	unlock := u.carts.Lock(userID)
+	defer unlock()
+
+	cart, err := u.getCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity := req.Quantity
+	if item := cart.Item(req.ProductID); item != nil {
+		quantity += item.Quantity
+	}
+	if quantity > MaxCartQuantity {
+		return nil, ErrCartQuantityTooLarge
+	}
+	if err := u.checkAvailable(ctx, req.ProductID, quantity); err != nil {
+		return nil, err
+	}
+
+	cart.SetQuantity(req.ProductID, quantity)
+	return u.save(ctx, cart, "Item added")
+}
+
+// UpdateItem sets the quantity of a product already in a member's cart
+func (u *cartUsecase) UpdateItem(ctx context.Context, userID, productID string, req UpdateCartItemRequest) (*CartResponse, error) {
+	if fields := validation.Struct(req); len(fields) > 0 {
+		return nil, apperror.Validation("Invalid cart item", fields...)
+	}
+
+	unlock := u.carts.Lock(userID)
+	defer unlock()
+
+	cart, err := u.getCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Item(productID) == nil {
+		return nil, ErrCartItemNotFound
+	}
+	if err := u.checkAvailable(ctx, productID, req.Quantity); err != nil {
+		return nil, err
+	}
+
+	cart.SetQuantity(productID, req.Quantity)
+	return u.save(ctx, cart, "Item updated")
+}
+
+// RemoveItem removes a product from a member's cart
+func (u *cartUsecase) RemoveItem(ctx context.Context, userID, productID string) (*CartResponse, error) {
+	unlock := u.carts.Lock(userID)
+	defer unlock()
+
+	cart, err := u.getCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Item(productID) == nil {
+		return nil, ErrCartItemNotFound
+	}
+
+	cart.SetQuantity(productID, 0)
+	return u.save(ctx, cart, "Item removed")
+}
+
+// SetCustomer attaches the customer's name and phone number, stored in
+// E.164, to a member's cart
+func (u *cartUsecase) SetCustomer(ctx context.Context, userID string, req CartCustomerRequest) (*CartResponse, error) {
+	trimSpace(&req.Name, &req.Phone)
+	if fields := validation.Struct(req); len(fields) > 0 {
+		return nil, apperror.Validation("Invalid customer details", fields...)
+	}
+	phone, _ := validation.NormalizePhone(req.Phone) // validated above
+
+	unlock := u.carts.Lock(userID)
+	defer unlock()
+
+	cart, err := u.getCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cart.CustomerName = req.Name
+	cart.CustomerPhone = phone
+	return u.save(ctx, cart, "Customer updated")
+}
+
+// Checkout prices the cart at the current catalog prices and places the
+// order. Stock, the points payment, the order, the emptied cart and the
+// outbox message that sends the SMS receipt are written atomically by the
+// order repository; if any of them fails, the cart is left as it was. The
+// message is dispatched once the order is placed, and left to the outbox
+// dispatcher to retry if that fails.
+func (u *cartUsecase) Checkout(ctx context.Context, req CheckoutRequest) (*OrderResponse, error) {
+	trimSpace(&req.UserID, &req.Language)
+	if fields := validation.Struct(req); len(fields) > 0 {
+		return nil, apperror.Validation("Invalid checkout", fields...)
+	}
+
+	response, messageID, err := u.placeOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The order stands whatever happens to its receipt, and the SMS is sent
+	// after the cart is unlocked so a slow provider never blocks the member
+	if _, err := u.outbox.Dispatch(ctx, messageID); err != nil {
+		log.Printf("Failed to dispatch receipt for order %s: %v", response.Order.ID, err)
+	}
+	receipt, err := u.receipts.GetReceipt(ctx, response.Order.ID)
+	switch {
+	case err == nil:
+		response.Receipt = receipt.Receipt
+	case !errors.Is(err, ErrReceiptNotFound):
+		log.Printf("Failed to get receipt for order %s: %v", response.Order.ID, err)
+	}
+	return response, nil
+}
+
+// placeOrder prices the cart and places the order, with the outbox message
+// for its receipt, under the member's lock and returns the message ID
+func (u *cartUsecase) placeOrder(ctx context.Context, req CheckoutRequest) (*OrderResponse, string, error) {
+	unlock := u.carts.Lock(req.UserID)
+	defer unlock()
+
+	cart, err := u.getCart(ctx, req.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(cart.Items) == 0 {
+		return nil, "", ErrCartEmpty
+	}
+
+	items := make([]entity.OrderItem, len(cart.Items))
+	for i, item := range cart.Items {
+		product, err := u.productRepo.GetByID(ctx, item.ProductID)
+		if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+			return nil, "", internalError(ctx, "Failed to get product", err)
+		}
+		if err := availability(product, item.Quantity); err != nil {
+			return nil, "", err
+		}
+		items[i] = entity.OrderItem{
+			ProductID: product.ID,
+			Name:      product.Name,
+			UnitPrice: product.PricePoints,
+			Quantity:  item.Quantity,
+			LineTotal: product.PricePoints * item.Quantity,
+		}
+	}
+
+	actor := req.Actor
+	if actor == "" {
+		actor = req.UserID
+	}
+	order := entity.NewOrder(uuid.New().String(), req.UserID, items, cart.CustomerName, cart.CustomerPhone)
+	order.Events[0].Actor = actor
+	order.Apply(entity.OrderPaid, actor, "Paid with points")
+	payment := entity.NewPointsPayment(uuid.New().String(), order)
+
+	message, err := newOutboxMessage(TopicSMSReceipt, ReceiptMessage{OrderID: order.ID, Language: req.Language})
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The catalog may change between pricing and placing, so stock and
+	// status are checked again inside the repository
+	err = u.orderRepo.Place(ctx, order, payment, message)
+	switch {
+	case errors.Is(err, repository.ErrInsufficientBalance):
+		return nil, "", ErrInsufficientBalance
+	case errors.Is(err, repository.ErrOutOfStock):
+		return nil, "", ErrOutOfStock
+	case errors.Is(err, repository.ErrProductInactive), errors.Is(err, repository.ErrProductNotFound):
+		return nil, "", ErrProductInactive
+	case err != nil:
+		return nil, "", internalError(ctx, "Failed to place order", err)
+	}
+
+	// The order is placed; report it even if the request is gone by now,
+	// so a retry does not buy the cart twice
+	balance, err := u.ledgerRepo.Balance(context.WithoutCancel(ctx), req.UserID)
+	if err != nil {
+		return nil, "", apperror.Internal("Failed to get balance", err)
+	}
+
+	return &OrderResponse{
+		Success: true,
+		Message: "Order placed",
+		Order:   order,
+		Payment: payment,
+		Balance: balance,
+	}, message.ID, nil
+}
+
+// getCart loads the cart of an existing member
+func (u *cartUsecase) getCart(ctx context.Context, userID string) (*entity.Cart, error) {
+	if _, err := u.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, internalError(ctx, "Failed to get user", err)
+	}
+
+	cart, err := u.cartRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get cart", err)
+	}
+	return cart, nil
+}
+
+// checkAvailable checks that quantity units of a product can be bought
+func (u *cartUsecase) checkAvailable(ctx context.Context, productID string, quantity int) error {
+	product, err := u.productRepo.GetByID(ctx, productID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return ErrCartProductNotFound
+	}
+	if err != nil {
+		return internalError(ctx, "Failed to get product", err)
+	}
+	return availability(product, quantity)
+}
+
+// save stores the cart and returns it priced
+func (u *cartUsecase) save(ctx context.Context, cart *entity.Cart, message string) (*CartResponse, error) {
+	cart.UpdatedAt = time.Now()
+	if err := u.cartRepo.Save(ctx, cart); err != nil {
+		return nil, internalError(ctx, "Failed to save cart", err)
+	}
+	return u.respond(ctx, cart, message)
+}
+
+// respond prices every cart item at the current catalog price
+func (u *cartUsecase) respond(ctx context.Context, cart *entity.Cart, message string) (*CartResponse, error) {
+	response := &CartResponse{
+		Success:       true,
+		Message:       message,
+		UserID:        cart.UserID,
+		Items:         make([]CartLine, len(cart.Items)),
+		CustomerName:  cart.CustomerName,
+		CustomerPhone: cart.CustomerPhone,
+	}
+	for i, item := range cart.Items {
+		line := CartLine{ProductID: item.ProductID, Quantity: item.Quantity}
+		product, err := u.productRepo.GetByID(ctx, item.ProductID)
+		switch {
+		case err == nil:
+			line.Name = product.Name
+			line.UnitPrice = product.PricePoints
+			line.LineTotal = product.PricePoints * item.Quantity
+			line.Available = availability(product, item.Quantity) == nil
+		case !errors.Is(err, apperror.ErrNotFound):
+			return nil, internalError(ctx, "Failed to get product", err)
+		}
+		response.Items[i] = line
+		response.Subtotal += line.LineTotal
+	}
+	return response, nil
+}
+
+// availability reports why quantity units of product, nil if it was not
+// found, cannot be bought
+func availability(product *entity.Product, quantity int) error {
+	switch {
+	case product == nil || !product.Active:
+		return ErrProductInactive
+	case product.Stock < quantity:
+		return ErrOutOfStock
+	}
+	return nil
+}
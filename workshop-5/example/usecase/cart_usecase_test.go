@@ -0,0 +1,382 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/analytics"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/sms"
+	"example.com/mike/usecase"
+)
+
+// cartFixture wires the cart and order usecases to in-memory storage with
+// a registered member and two products
+type cartFixture struct {
+	carts    usecase.CartUsecase
+	orders   usecase.OrderUsecase
+	receipts usecase.ReceiptUsecase
+	products usecase.ProductUsecase
+	ledger   usecase.LedgerUsecase
+	users    usecase.UserUsecase
+	outbox   usecase.OutboxUsecase
+	sms      *sms.LogSender
+	events   *analytics.LogEmitter
+	member   string
+	latte    string
+	mocha    string
+}
+
+func newCartFixture(t *testing.T, balance int) *cartFixture {
+	t.Helper()
+	return newCartFixtureWithSender(t, balance, nil)
+}
+
+// newCartFixtureWithSender sends receipts with sender instead of the
+// fixture's log sender when it is not nil
+func newCartFixtureWithSender(t *testing.T, balance int, sender usecase.SMSSender) *cartFixture {
+	t.Helper()
+	userRepo, ledgerRepo := repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository()
+	productRepo, cartRepo := repository.NewMemoryProductRepository(), repository.NewMemoryCartRepository()
+	outboxRepo := repository.NewMemoryOutboxRepository()
+	orderRepo := repository.NewMemoryOrderRepository(productRepo, ledgerRepo, cartRepo, outboxRepo)
+	logSender := sms.NewLogSender(io.Discard)
+	if sender == nil {
+		sender = logSender
+	}
+	events := analytics.NewLogEmitter(io.Discard)
+	receiptRepo := repository.NewMemoryReceiptRepository(outboxRepo)
+	receipts := usecase.NewReceiptUsecase(userRepo, orderRepo, ledgerRepo, receiptRepo, sender, webhookSecret)
+	outbox := usecase.NewOutboxUsecase(outboxRepo, map[string]usecase.OutboxHandler{
+		usecase.TopicSMSReceipt:     usecase.ReceiptOutboxHandler(receipts),
+		usecase.TopicAnalyticsEvent: usecase.AnalyticsOutboxHandler(events),
+	}, usecase.DefaultOutboxPolicy)
+
+	f := &cartFixture{
+		carts:    usecase.NewCartUsecase(userRepo, productRepo, cartRepo, orderRepo, ledgerRepo, receipts, outbox),
+		receipts: receipts,
+		orders:   usecase.NewOrderUsecase(orderRepo),
+		products: usecase.NewProductUsecase(productRepo),
+		ledger:   usecase.NewLedgerUsecase(userRepo, ledgerRepo),
+		users:    usecase.NewUserUsecase(userRepo, ledgerRepo),
+		outbox:   outbox,
+		sms:      logSender,
+		events:   events,
+	}
+	f.member = registerUser(t, f.users, 1).User.ID
+	f.latte = createProduct(t, f.products, usecase.ProductRequest{Name: "Iced Latte", Category: "drinks", PricePoints: 120, Stock: 5})
+	f.mocha = createProduct(t, f.products, usecase.ProductRequest{Name: "Mocha", Category: "drinks", PricePoints: 80, Stock: 1})
+	if balance > 0 {
+		if _, err := f.ledger.PostEntry(context.Background(), f.member, usecase.PostEntryRequest{Type: "earn", Amount: balance}); err != nil {
+			t.Fatalf("PostEntry: %v", err)
+		}
+	}
+	return f
+}
+
+func (f *cartFixture) add(t *testing.T, productID string, quantity int) *usecase.CartResponse {
+	t.Helper()
+	resp, err := f.carts.AddItem(context.Background(), f.member, usecase.CartItemRequest{ProductID: productID, Quantity: quantity})
+	if err != nil {
+		t.Fatalf("AddItem(%s): %v", productID, err)
+	}
+	return resp
+}
+
+// dispatch delivers every outbox message that is due, such as analytics
+// events
+func (f *cartFixture) dispatch(t *testing.T) {
+	t.Helper()
+	if _, err := f.outbox.DispatchDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+}
+
+func (f *cartFixture) stock(t *testing.T, productID string) int {
+	t.Helper()
+	resp, err := f.products.GetProduct(context.Background(), productID, true)
+	if err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	return resp.Product.Stock
+}
+
+func TestCartItems(t *testing.T) {
+	f := newCartFixture(t, 0)
+
+	f.add(t, f.latte, 1)
+	f.add(t, f.mocha, 1)
+	resp := f.add(t, f.latte, 1)
+	if len(resp.Items) != 2 || resp.Items[0].ProductID != f.latte || resp.Items[0].Quantity != 2 ||
+		resp.Items[0].LineTotal != 240 || !resp.Items[0].Available || resp.Subtotal != 320 {
+		t.Fatalf("unexpected cart: %+v", resp)
+	}
+
+	resp, err := f.carts.UpdateItem(context.Background(), f.member, f.latte, usecase.UpdateCartItemRequest{Quantity: 4})
+	if err != nil {
+		t.Fatalf("UpdateItem: %v", err)
+	}
+	if resp.Items[0].Quantity != 4 || resp.Subtotal != 560 {
+		t.Fatalf("unexpected cart after update: %+v", resp)
+	}
+
+	resp, err = f.carts.RemoveItem(context.Background(), f.member, f.mocha)
+	if err != nil {
+		t.Fatalf("RemoveItem: %v", err)
+	}
+	if len(resp.Items) != 1 || resp.Subtotal != 480 {
+		t.Fatalf("unexpected cart after remove: %+v", resp)
+	}
+
+	// Prices come from the catalog, so a price change shows up in the cart
+	if _, err := f.products.PatchProduct(context.Background(), f.latte, []byte(`{"price_points": 100}`)); err != nil {
+		t.Fatalf("PatchProduct: %v", err)
+	}
+	resp, err = f.carts.GetCart(context.Background(), f.member)
+	if err != nil {
+		t.Fatalf("GetCart: %v", err)
+	}
+	if resp.Subtotal != 400 {
+		t.Fatalf("subtotal = %d, want 400", resp.Subtotal)
+	}
+
+	resp, err = f.carts.SetCustomer(context.Background(), f.member, usecase.CartCustomerRequest{Name: " Somchai Jaidee ", Phone: "081-234-5678"})
+	if err != nil {
+		t.Fatalf("SetCustomer: %v", err)
+	}
+	if resp.CustomerName != "Somchai Jaidee" || resp.CustomerPhone != "+66812345678" || len(resp.Items) != 1 {
+		t.Fatalf("unexpected cart after setting the customer: %+v", resp)
+	}
+}
+
+func TestCartItemFailures(t *testing.T) {
+	f := newCartFixture(t, 0)
+	f.add(t, f.latte, 1)
+	if _, err := f.products.PatchProduct(context.Background(), f.mocha, []byte(`{"active": false}`)); err != nil {
+		t.Fatalf("PatchProduct: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"missing product", func() error {
+			_, err := f.carts.AddItem(context.Background(), f.member, usecase.CartItemRequest{ProductID: "missing", Quantity: 1})
+			return err
+		}, usecase.ErrCartProductNotFound},
+		{"inactive product", func() error {
+			_, err := f.carts.AddItem(context.Background(), f.member, usecase.CartItemRequest{ProductID: f.mocha, Quantity: 1})
+			return err
+		}, usecase.ErrProductInactive},
+		{"more than in stock", func() error {
+			_, err := f.carts.AddItem(context.Background(), f.member, usecase.CartItemRequest{ProductID: f.latte, Quantity: 5})
+			return err
+		}, usecase.ErrOutOfStock},
+		{"over the cart limit", func() error {
+			_, err := f.carts.UpdateItem(context.Background(), f.member, f.latte, usecase.UpdateCartItemRequest{Quantity: 100})
+			return err
+		}, nil},
+		{"update item not in cart", func() error {
+			_, err := f.carts.UpdateItem(context.Background(), f.member, f.mocha, usecase.UpdateCartItemRequest{Quantity: 1})
+			return err
+		}, usecase.ErrCartItemNotFound},
+		{"remove item not in cart", func() error {
+			_, err := f.carts.RemoveItem(context.Background(), f.member, f.mocha)
+			return err
+		}, usecase.ErrCartItemNotFound},
+		{"unknown member", func() error {
+			_, err := f.carts.GetCart(context.Background(), "missing")
+			return err
+		}, usecase.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if tt.want == nil {
+				if fields := fieldErrors(t, err); !fields["quantity"] {
+					t.Fatalf("expected a quantity field error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	_, err := f.carts.SetCustomer(context.Background(), f.member, usecase.CartCustomerRequest{Name: "R2-D2 <script>", Phone: "12"})
+	if fields := fieldErrors(t, err); !fields["name"] || !fields["phone"] {
+		t.Fatalf("expected name and phone errors, got %v", err)
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	f := newCartFixture(t, 1000)
+	f.add(t, f.latte, 2)
+	f.add(t, f.mocha, 1)
+	if _, err := f.carts.SetCustomer(context.Background(), f.member, usecase.CartCustomerRequest{Name: "Somchai Jaidee", Phone: "0812345678"}); err != nil {
+		t.Fatalf("SetCustomer: %v", err)
+	}
+
+	resp, err := f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: f.member})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	order := resp.Order
+	if order.Total != 320 || order.Status != entity.OrderPaid || len(order.Items) != 2 ||
+		order.Items[0] != (entity.OrderItem{ProductID: f.latte, Name: "Iced Latte", UnitPrice: 120, Quantity: 2, LineTotal: 240}) ||
+		order.CustomerName != "Somchai Jaidee" || order.CustomerPhone != "+66812345678" {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+	if resp.Payment.Amount != 320 || resp.Payment.OrderID != order.ID || resp.Payment.Method != entity.PaymentPoints ||
+		resp.Balance != 680 {
+		t.Fatalf("unexpected payment: %+v, balance %d", resp.Payment, resp.Balance)
+	}
+	if f.stock(t, f.latte) != 3 || f.stock(t, f.mocha) != 0 {
+		t.Fatalf("stock not reserved: latte %d, mocha %d", f.stock(t, f.latte), f.stock(t, f.mocha))
+	}
+
+	cart, err := f.carts.GetCart(context.Background(), f.member)
+	if err != nil {
+		t.Fatalf("GetCart: %v", err)
+	}
+	if len(cart.Items) != 0 || cart.CustomerName != "" {
+		t.Fatalf("expected an empty cart after checkout, got %+v", cart)
+	}
+
+	got, err := f.orders.GetOrder(context.Background(), order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if got.Order.Total != 320 || got.Payment.ID != resp.Payment.ID {
+		t.Fatalf("unexpected stored order: %+v", got)
+	}
+	if _, err := f.orders.GetOrder(context.Background(), "missing"); !errors.Is(err, usecase.ErrOrderNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrOrderNotFound, err)
+	}
+
+	entries, err := f.ledger.ListEntries(context.Background(), f.member, usecase.ListEntriesRequest{Type: "spend"})
+	if err != nil {
+		t.Fatalf("ListEntries: %v", err)
+	}
+	if len(entries.Entries) != 1 || entries.Entries[0].OrderID != order.ID || entries.Entries[0].Amount != -320 {
+		t.Fatalf("unexpected spend entries: %+v", entries.Entries)
+	}
+}
+
+func TestCheckoutFailuresAreDistinct(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance int
+		prepare func(t *testing.T, f *cartFixture)
+		want    error
+	}{
+		{"empty cart", 1000, func(t *testing.T, f *cartFixture) {}, usecase.ErrCartEmpty},
+		{"insufficient balance", 100, func(t *testing.T, f *cartFixture) {
+			f.add(t, f.latte, 1)
+		}, usecase.ErrInsufficientBalance},
+		{"out of stock", 1000, func(t *testing.T, f *cartFixture) {
+			f.add(t, f.latte, 3)
+			if _, err := f.products.PatchProduct(context.Background(), f.latte, []byte(`{"stock": 2}`)); err != nil {
+				t.Fatalf("PatchProduct: %v", err)
+			}
+		}, usecase.ErrOutOfStock},
+		{"inactive product", 1000, func(t *testing.T, f *cartFixture) {
+			f.add(t, f.latte, 1)
+			if _, err := f.products.PatchProduct(context.Background(), f.latte, []byte(`{"active": false}`)); err != nil {
+				t.Fatalf("PatchProduct: %v", err)
+			}
+		}, usecase.ErrProductInactive},
+		{"deleted product", 1000, func(t *testing.T, f *cartFixture) {
+			f.add(t, f.latte, 1)
+			if err := f.products.DeleteProduct(context.Background(), f.latte); err != nil {
+				t.Fatalf("DeleteProduct: %v", err)
+			}
+		}, usecase.ErrProductInactive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCartFixture(t, tt.balance)
+			tt.prepare(t, f)
+			before, err := f.carts.GetCart(context.Background(), f.member)
+			if err != nil {
+				t.Fatalf("GetCart: %v", err)
+			}
+
+			if _, err := f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: f.member}); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+
+			// Nothing was taken: the cart, balance and stock are unchanged
+			after, err := f.carts.GetCart(context.Background(), f.member)
+			if err != nil {
+				t.Fatalf("GetCart: %v", err)
+			}
+			if len(after.Items) != len(before.Items) {
+				t.Fatalf("cart changed: before %+v, after %+v", before.Items, after.Items)
+			}
+			balance, err := f.ledger.GetBalance(context.Background(), f.member)
+			if err != nil {
+				t.Fatalf("GetBalance: %v", err)
+			}
+			if balance.Balance != tt.balance {
+				t.Fatalf("balance = %d, want %d", balance.Balance, tt.balance)
+			}
+			if f.stock(t, f.mocha) != 1 {
+				t.Fatal("stock changed")
+			}
+		})
+	}
+
+	f := newCartFixture(t, 0)
+	if _, err := f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: "missing"}); !errors.Is(err, usecase.ErrUserNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrUserNotFound, err)
+	}
+}
+
+func TestConcurrentCheckoutsNeverOversell(t *testing.T) {
+	f := newCartFixture(t, 0)
+
+	// Several members race for the single Mocha
+	const buyers = 8
+	members := make([]string, buyers)
+	for i := range members {
+		members[i] = registerUser(t, f.users, i+2).User.ID
+		if _, err := f.ledger.PostEntry(context.Background(), members[i], usecase.PostEntryRequest{Type: "earn", Amount: 100}); err != nil {
+			t.Fatalf("PostEntry: %v", err)
+		}
+		if _, err := f.carts.AddItem(context.Background(), members[i], usecase.CartItemRequest{ProductID: f.mocha, Quantity: 1}); err != nil {
+			t.Fatalf("AddItem: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	placed := 0
+	for _, member := range members {
+		wg.Add(1)
+		go func(member string) {
+			defer wg.Done()
+			_, err := f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: member})
+			switch {
+			case err == nil:
+				mu.Lock()
+				placed++
+				mu.Unlock()
+			case !errors.Is(err, usecase.ErrOutOfStock):
+				t.Errorf("Checkout: unexpected error: %v", err)
+			}
+		}(member)
+	}
+	wg.Wait()
+
+	if placed != 1 || f.stock(t, f.mocha) != 0 {
+		t.Fatalf("placed %d orders, stock %d; want 1 order and no stock", placed, f.stock(t, f.mocha))
+	}
+}
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// keyedMutex serialises work per key, e.g. per account, using a fixed set
+// of striped locks so memory use does not grow with the number of keys.
+// Distinct keys may share a stripe, which only costs some concurrency.
+type keyedMutex struct {
+	stripes [64]sync.Mutex
+}
+
+// Lock locks the stripe for key and returns the matching unlock function
+func (m *keyedMutex) Lock(key string) (unlock func()) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	mu := &m.stripes[h.Sum32()%uint32(len(m.stripes))]
+	mu.Lock()
+	return mu.Unlock
+}
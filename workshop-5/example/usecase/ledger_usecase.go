@@ -0,0 +1,348 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/validation"
+	"github.com/google/uuid"
+)
+
+// PostEntryRequest records points earned or spent by a member. Transfers
+// between members are posted by the transfer flow, not through this request.
+type PostEntryRequest struct {
+	Type    string `json:"type" validate:"required,oneof=earn spend" example:"earn"`
+	Amount  int    `json:"amount" validate:"required,min=1,max=1000000" example:"100"`
+	Memo    string `json:"memo" validate:"max=200" example:"Welcome bonus"`
+	OrderID string `json:"order_id" validate:"max=64" example:""`
+}
+
+// AwardPointsRequest represents a partner awarding points to a member.
+// It is posted as an earn entry.
+type AwardPointsRequest struct {
+	Amount int    `json:"amount" validate:"required,min=1,max=1000000" example:"100"`
+	Memo   string `json:"memo" validate:"max=200" example:"Purchase at Lumpini branch"`
+}
+
+// ReverseEntryRequest represents an admin undoing a ledger entry
+type ReverseEntryRequest struct {
+	Reason string `json:"reason" validate:"required,max=200" example:"Points credited twice"`
+}
+
+// LedgerEntryResponse represents a single ledger entry and the member's
+// balance
+type LedgerEntryResponse struct {
+	Success bool                `json:"success" example:"true"`
+	Entry   *entity.LedgerEntry `json:"entry"`
+	Balance int                 `json:"balance" example:"100"`
+}
+
+// BalanceResponse represents a member's current points balance
+type BalanceResponse struct {
+	Success bool   `json:"success" example:"true"`
+	UserID  string `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Balance int    `json:"balance" example:"15420"`
+}
+
+// ListEntriesRequest represents the filter and page position of a ledger
+// history listing
+type ListEntriesRequest struct {
+	// Type is earn, spend, transfer (both directions), transfer_in,
+	// transfer_out or reversal; empty lists everything
+	Type string `query:"type" example:"transfer"`
+
+	// Limit is the page size, 1 to MaxListLimit
+	Limit int `query:"limit" example:"20"`
+
+	// Cursor is the next_cursor of the previous page
+	Cursor string `query:"cursor"`
+}
+
+// ListEntriesResponse represents one page of ledger entries, newest first
+type ListEntriesResponse struct {
+	Success    bool                  `json:"success" example:"true"`
+	Entries    []*entity.LedgerEntry `json:"entries"`
+	Count      int                   `json:"count" example:"20"`
+	NextCursor string                `json:"next_cursor,omitempty" example:"eyJ0IjoiMjAyNC0wMS0wMVQwMDowMDowMFoifQ"`
+}
+
+// Errors returned by LedgerUsecase
+var (
+	// ErrInsufficientBalance is returned when a member spends more points
+	// than they hold
+	ErrInsufficientBalance = apperror.Conflict("Insufficient balance")
+
+	// ErrLedgerEntryNotFound is returned when the entry does not exist or
+	// belongs to another member
+	ErrLedgerEntryNotFound = apperror.NotFound("Ledger entry not found")
+
+	// ErrLedgerEntryReversed is returned when reversing an entry whose
+	// posting was already reversed
+	ErrLedgerEntryReversed = apperror.Conflict("Ledger entry already reversed")
+
+	// ErrLedgerEntryNotReversible is returned when reversing a reversal, an
+	// order payment, which is refunded through the order instead, or a
+	// transfer, which stays posted once completed
+	ErrLedgerEntryNotReversible = apperror.Unprocessable("Ledger entry cannot be reversed; order payments are refunded through the order and transfers are final")
+)
+
+// entryTypeFilters maps the history filters shown in the wallet UI to
+// entry types
+var entryTypeFilters = map[string][]entity.EntryType{
+	"earn":         {entity.EntryEarn},
+	"spend":        {entity.EntrySpend},
+	"transfer":     {entity.EntryTransferIn, entity.EntryTransferOut},
+	"transfer_in":  {entity.EntryTransferIn},
+	"transfer_out": {entity.EntryTransferOut},
+	"reversal":     {entity.EntryReversal},
+}
+
+// LedgerUsecase defines the points ledger operations. Ledger entries are
+// immutable; a member's balance is always the sum of their entries.
+type LedgerUsecase interface {
+	// PostEntry records points earned or spent by a member
+	PostEntry(ctx context.Context, userID string, req PostEntryRequest) (*LedgerEntryResponse, error)
+
+	// GetBalance returns a member's current balance
+	GetBalance(ctx context.Context, userID string) (*BalanceResponse, error)
+
+	// GetEntry retrieves one of a member's ledger entries
+	GetEntry(ctx context.Context, userID, entryID string) (*LedgerEntryResponse, error)
+
+	// ListEntries retrieves a page of a member's ledger history
+	ListEntries(ctx context.Context, userID string, req ListEntriesRequest) (*ListEntriesResponse, error)
+
+	// ReverseEntry undoes the posting of one of a member's ledger entries
+	ReverseEntry(ctx context.Context, userID, entryID string, req ReverseEntryRequest) (*LedgerEntryResponse, error)
+}
+
+// ledgerUsecase implements the LedgerUsecase interface
+type ledgerUsecase struct {
+	userRepo   repository.UserRepository
+	ledgerRepo repository.LedgerRepository
+}
+
+// NewLedgerUsecase creates a new ledger usecase
+func NewLedgerUsecase(userRepo repository.UserRepository, ledgerRepo repository.LedgerRepository) LedgerUsecase {
+	return &ledgerUsecase{
+		userRepo:   userRepo,
+		ledgerRepo: ledgerRepo,
+	}
+}
+
+// PostEntry records points earned or spent by a member. Spending is
+// checked against the balance atomically by the repository.
+func (u *ledgerUsecase) PostEntry(ctx context.Context, userID string, req PostEntryRequest) (*LedgerEntryResponse, error) {
+	if fields := validation.Struct(req); len(fields) > 0 {
+		return nil, apperror.Validation("Invalid ledger entry", fields...)
+	}
+	if err := u.requireUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	var posting *entity.Posting
+	if entity.EntryType(req.Type) == entity.EntryEarn {
+		posting = entity.NewEarnPosting(id, userID, req.Amount, req.Memo)
+	} else {
+		posting = entity.NewSpendPosting(id, userID, req.Amount, req.Memo, req.OrderID)
+	}
+
+	if err := u.ledgerRepo.Post(ctx, posting); err != nil {
+		if errors.Is(err, repository.ErrInsufficientBalance) {
+			return nil, ErrInsufficientBalance
+		}
+		return nil, internalError(ctx, "Failed to post ledger entry", err)
+	}
+
+	// The entry is posted; report it even if the request is gone by now
+	balance, err := u.ledgerRepo.Balance(context.WithoutCancel(ctx), userID)
+	if err != nil {
+		return nil, apperror.Internal("Failed to get balance", err)
+	}
+
+	return &LedgerEntryResponse{
+		Success: true,
+		Entry:   posting.EntryFor(userID),
+		Balance: balance,
+	}, nil
+}
+
+// GetBalance returns a member's current balance
+func (u *ledgerUsecase) GetBalance(ctx context.Context, userID string) (*BalanceResponse, error) {
+	if err := u.requireUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	balance, err := u.ledgerRepo.Balance(ctx, userID)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get balance", err)
+	}
+
+	return &BalanceResponse{
+		Success: true,
+		UserID:  userID,
+		Balance: balance,
+	}, nil
+}
+
+// GetEntry retrieves one of a member's ledger entries
+func (u *ledgerUsecase) GetEntry(ctx context.Context, userID, entryID string) (*LedgerEntryResponse, error) {
+	if err := u.requireUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	entry, err := u.ledgerRepo.GetEntry(ctx, entryID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrLedgerEntryNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get ledger entry", err)
+	}
+	if entry.AccountID != userID {
+		return nil, ErrLedgerEntryNotFound
+	}
+
+	balance, err := u.ledgerRepo.Balance(ctx, userID)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get balance", err)
+	}
+
+	return &LedgerEntryResponse{
+		Success: true,
+		Entry:   entry,
+		Balance: balance,
+	}, nil
+}
+
+// ListEntries retrieves a page of a member's ledger history, newest first
+func (u *ledgerUsecase) ListEntries(ctx context.Context, userID string, req ListEntriesRequest) (*ListEntriesResponse, error) {
+	query := repository.LedgerQuery{AccountID: userID, Limit: req.Limit}
+
+	var fields []apperror.FieldError
+	if req.Type != "" {
+		types, ok := entryTypeFilters[req.Type]
+		if !ok {
+			fields = append(fields, apperror.FieldError{
+				Field:   "type",
+				Message: "type must be one of earn, spend, transfer, transfer_in, transfer_out, reversal",
+			})
+		}
+		query.Types = types
+	}
+	switch {
+	case req.Limit == 0:
+		query.Limit = DefaultListLimit
+	case req.Limit < 0 || req.Limit > MaxListLimit:
+		fields = append(fields, apperror.FieldError{Field: "limit", Message: "limit must be between 1 and 100"})
+	}
+	if req.Cursor != "" {
+		var cursor repository.LedgerCursor
+		if err := decodeCursor(req.Cursor, &cursor); err != nil || cursor.ID == "" {
+			fields = append(fields, apperror.FieldError{Field: "cursor", Message: "cursor is malformed"})
+		} else {
+			query.After = &cursor
+		}
+	}
+	if len(fields) > 0 {
+		return nil, apperror.Validation("Invalid list parameters", fields...)
+	}
+
+	if err := u.requireUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	page, err := u.ledgerRepo.Entries(ctx, query)
+	if errors.Is(err, apperror.ErrValidation) {
+		return nil, apperror.Validation("Invalid list parameters",
+			apperror.FieldError{Field: "cursor", Message: "cursor is malformed"})
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to list ledger entries", err)
+	}
+
+	response := &ListEntriesResponse{
+		Success: true,
+		Entries: page.Entries,
+		Count:   len(page.Entries),
+	}
+	if page.Next != nil {
+		response.NextCursor = encodeCursor(page.Next)
+	}
+	return response, nil
+}
+
+// ReverseEntry undoes the whole posting an entry belongs to with a new
+// posting of opposite amounts; the ledger itself is never edited. The
+// reversal ID is derived from the posting ID, like order refunds, so a
+// posting can be reversed only once.
+func (u *ledgerUsecase) ReverseEntry(ctx context.Context, userID, entryID string, req ReverseEntryRequest) (*LedgerEntryResponse, error) {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if fields := validation.Struct(req); len(fields) > 0 {
+		return nil, apperror.Validation("Invalid reversal", fields...)
+	}
+	if err := u.requireUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	entry, err := u.ledgerRepo.GetEntry(ctx, entryID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrLedgerEntryNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get ledger entry", err)
+	}
+	if entry.AccountID != userID {
+		return nil, ErrLedgerEntryNotFound
+	}
+	if entry.Type == entity.EntryReversal || entry.OrderID != "" || entry.TransferID != "" {
+		return nil, ErrLedgerEntryNotReversible
+	}
+
+	posting, err := u.ledgerRepo.GetPosting(ctx, entry.PostingID)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get posting", err)
+	}
+	reversal := posting.Reversal(reversalPrefix+posting.ID, req.Reason)
+
+	err = u.ledgerRepo.Post(ctx, reversal)
+	switch {
+	case errors.Is(err, repository.ErrPostingExists):
+		return nil, ErrLedgerEntryReversed
+	case errors.Is(err, repository.ErrInsufficientBalance):
+		return nil, ErrInsufficientBalance
+	case err != nil:
+		return nil, internalError(ctx, "Failed to post reversal", err)
+	}
+
+	// The reversal is posted; report it even if the request is gone by now
+	balance, err := u.ledgerRepo.Balance(context.WithoutCancel(ctx), userID)
+	if err != nil {
+		return nil, apperror.Internal("Failed to get balance", err)
+	}
+
+	return &LedgerEntryResponse{
+		Success: true,
+		Entry:   reversal.EntryFor(userID),
+		Balance: balance,
+	}, nil
+}
+
+// reversalPrefix starts the ID of every reversal posting
+const reversalPrefix = "reversal-"
+
+// requireUser reports ErrUserNotFound unless the member exists
+func (u *ledgerUsecase) requireUser(ctx context.Context, userID string) error {
+	_, err := u.userRepo.GetByID(ctx, userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return internalError(ctx, "Failed to get user", err)
+	}
+	return nil
+}
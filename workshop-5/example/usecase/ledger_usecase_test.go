@@ -0,0 +1,213 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+)
+
+// newUsecases wires user and ledger usecases to shared in-memory storage
+func newUsecases() (usecase.UserUsecase, usecase.LedgerUsecase) {
+	userRepo, ledgerRepo := repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository()
+	return usecase.NewUserUsecase(userRepo, ledgerRepo), usecase.NewLedgerUsecase(userRepo, ledgerRepo)
+}
+
+func TestPostEntryUpdatesBalanceAndUserPoints(t *testing.T) {
+	users, ledger := newUsecases()
+	user := registerUser(t, users, 1).User
+
+	earned, err := ledger.PostEntry(context.Background(), user.ID, usecase.PostEntryRequest{Type: "earn", Amount: 500, Memo: "welcome"})
+	if err != nil {
+		t.Fatalf("PostEntry earn: %v", err)
+	}
+	if earned.Balance != 500 || earned.Entry.Type != entity.EntryEarn || earned.Entry.Amount != 500 {
+		t.Fatalf("unexpected earn response: %+v %+v", earned, earned.Entry)
+	}
+
+	spent, err := ledger.PostEntry(context.Background(), user.ID, usecase.PostEntryRequest{Type: "spend", Amount: 120, OrderID: "order-1"})
+	if err != nil {
+		t.Fatalf("PostEntry spend: %v", err)
+	}
+	if spent.Balance != 380 || spent.Entry.Amount != -120 || spent.Entry.OrderID != "order-1" {
+		t.Fatalf("unexpected spend response: %+v %+v", spent, spent.Entry)
+	}
+
+	got, err := users.GetUser(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if got.User.Points != 380 {
+		t.Fatalf("User.Points = %d, want the ledger balance 380", got.User.Points)
+	}
+
+	list, err := users.ListUsers(context.Background(), usecase.ListUsersRequest{})
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if list.Users[0].Points != 380 {
+		t.Fatalf("listed User.Points = %d, want 380", list.Users[0].Points)
+	}
+}
+
+func TestPostEntryRejectsOverspend(t *testing.T) {
+	users, ledger := newUsecases()
+	user := registerUser(t, users, 1).User
+
+	_, err := ledger.PostEntry(context.Background(), user.ID, usecase.PostEntryRequest{Type: "spend", Amount: 1})
+	if !errors.Is(err, usecase.ErrInsufficientBalance) {
+		t.Fatalf("expected %v, got %v", usecase.ErrInsufficientBalance, err)
+	}
+}
+
+func TestPostEntryValidation(t *testing.T) {
+	users, ledger := newUsecases()
+	user := registerUser(t, users, 1).User
+
+	_, err := ledger.PostEntry(context.Background(), user.ID, usecase.PostEntryRequest{Type: "transfer_in", Amount: -5})
+	fields := fieldErrors(t, err)
+	if !fields["type"] || !fields["amount"] {
+		t.Fatalf("expected type and amount errors, got %v", fields)
+	}
+
+	if _, err := ledger.PostEntry(context.Background(), "missing", usecase.PostEntryRequest{Type: "earn", Amount: 1}); !errors.Is(err, usecase.ErrUserNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrUserNotFound, err)
+	}
+}
+
+func TestUpdateUserCannotChangePoints(t *testing.T) {
+	users, ledger := newUsecases()
+	user := registerUser(t, users, 1).User
+	if _, err := ledger.PostEntry(context.Background(), user.ID, usecase.PostEntryRequest{Type: "earn", Amount: 100}); err != nil {
+		t.Fatalf("PostEntry: %v", err)
+	}
+
+	_, err := users.PatchUser(context.Background(), user.ID, []byte(`{"points": 999999}`))
+	if fields := fieldErrors(t, err); !fields["points"] {
+		t.Fatalf("expected a points field error, got %v", fields)
+	}
+
+	// Echoing the current balance back is fine
+	resp, err := users.PatchUser(context.Background(), user.ID, []byte(`{"points": 100, "first_name": "Jane"}`))
+	if err != nil {
+		t.Fatalf("PatchUser: %v", err)
+	}
+	if resp.User.Points != 100 {
+		t.Fatalf("User.Points = %d, want 100", resp.User.Points)
+	}
+}
+
+func TestListEntries(t *testing.T) {
+	users, ledger := newUsecases()
+	user := registerUser(t, users, 1).User
+	for i := 0; i < 5; i++ {
+		if _, err := ledger.PostEntry(context.Background(), user.ID, usecase.PostEntryRequest{Type: "earn", Amount: 10}); err != nil {
+			t.Fatalf("PostEntry: %v", err)
+		}
+	}
+	if _, err := ledger.PostEntry(context.Background(), user.ID, usecase.PostEntryRequest{Type: "spend", Amount: 5}); err != nil {
+		t.Fatalf("PostEntry: %v", err)
+	}
+
+	seen := 0
+	req := usecase.ListEntriesRequest{Type: "earn", Limit: 2}
+	for {
+		resp, err := ledger.ListEntries(context.Background(), user.ID, req)
+		if err != nil {
+			t.Fatalf("ListEntries: %v", err)
+		}
+		for _, entry := range resp.Entries {
+			if entry.Type != entity.EntryEarn {
+				t.Fatalf("unexpected entry type %q", entry.Type)
+			}
+		}
+		seen += resp.Count
+		if resp.NextCursor == "" {
+			break
+		}
+		req.Cursor = resp.NextCursor
+	}
+	if seen != 5 {
+		t.Fatalf("expected 5 earn entries, got %d", seen)
+	}
+
+	_, err := ledger.ListEntries(context.Background(), user.ID, usecase.ListEntriesRequest{Type: "bonus", Limit: 500, Cursor: "!!"})
+	fields := fieldErrors(t, err)
+	if !fields["type"] || !fields["limit"] || !fields["cursor"] {
+		t.Fatalf("expected type, limit and cursor errors, got %v", fields)
+	}
+}
+
+func TestGetEntryOfAnotherUserIsNotFound(t *testing.T) {
+	users, ledger := newUsecases()
+	alice := registerUser(t, users, 1).User
+	bob := registerUser(t, users, 2).User
+
+	posted, err := ledger.PostEntry(context.Background(), alice.ID, usecase.PostEntryRequest{Type: "earn", Amount: 10})
+	if err != nil {
+		t.Fatalf("PostEntry: %v", err)
+	}
+
+	if _, err := ledger.GetEntry(context.Background(), alice.ID, posted.Entry.ID); err != nil {
+		t.Fatalf("GetEntry: %v", err)
+	}
+	if _, err := ledger.GetEntry(context.Background(), bob.ID, posted.Entry.ID); !errors.Is(err, usecase.ErrLedgerEntryNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrLedgerEntryNotFound, err)
+	}
+}
+
+func TestReverseEntry(t *testing.T) {
+	users, ledger := newUsecases()
+	user := registerUser(t, users, 1).User
+
+	earned, err := ledger.PostEntry(context.Background(), user.ID, usecase.PostEntryRequest{Type: "earn", Amount: 300})
+	if err != nil {
+		t.Fatalf("PostEntry: %v", err)
+	}
+	paid, err := ledger.PostEntry(context.Background(), user.ID, usecase.PostEntryRequest{Type: "spend", Amount: 100, OrderID: "order-1"})
+	if err != nil {
+		t.Fatalf("PostEntry: %v", err)
+	}
+
+	// 100 of the 300 points are spent, so the credit cannot be taken back yet
+	_, err = ledger.ReverseEntry(context.Background(), user.ID, earned.Entry.ID, usecase.ReverseEntryRequest{Reason: "Credited twice"})
+	if !errors.Is(err, usecase.ErrInsufficientBalance) {
+		t.Fatalf("reversing spent points: expected %v, got %v", usecase.ErrInsufficientBalance, err)
+	}
+	if _, err := ledger.ReverseEntry(context.Background(), user.ID, paid.Entry.ID, usecase.ReverseEntryRequest{Reason: "Wrong order"}); !errors.Is(err, usecase.ErrLedgerEntryNotReversible) {
+		t.Fatalf("reversing an order payment: expected %v, got %v", usecase.ErrLedgerEntryNotReversible, err)
+	}
+
+	if _, err := ledger.PostEntry(context.Background(), user.ID, usecase.PostEntryRequest{Type: "earn", Amount: 100}); err != nil {
+		t.Fatalf("PostEntry: %v", err)
+	}
+	reversed, err := ledger.ReverseEntry(context.Background(), user.ID, earned.Entry.ID, usecase.ReverseEntryRequest{Reason: "Credited twice"})
+	if err != nil {
+		t.Fatalf("ReverseEntry: %v", err)
+	}
+	if reversed.Entry.Type != entity.EntryReversal || reversed.Entry.Amount != -300 || reversed.Entry.Memo != "Credited twice" || reversed.Balance != 0 {
+		t.Fatalf("unexpected reversal: %+v %+v", reversed, reversed.Entry)
+	}
+
+	// The reversal is listed under its own filter, not as a new earn
+	for filter, want := range map[string]int{"earn": 2, "reversal": 1} {
+		entries, err := ledger.ListEntries(context.Background(), user.ID, usecase.ListEntriesRequest{Type: filter})
+		if err != nil || entries.Count != want {
+			t.Fatalf("ListEntries(%s) = %+v, %v; want %d entries", filter, entries, err, want)
+		}
+	}
+
+	if _, err := ledger.ReverseEntry(context.Background(), user.ID, earned.Entry.ID, usecase.ReverseEntryRequest{Reason: "Again"}); !errors.Is(err, usecase.ErrLedgerEntryReversed) {
+		t.Fatalf("second reversal: expected %v, got %v", usecase.ErrLedgerEntryReversed, err)
+	}
+	if _, err := ledger.ReverseEntry(context.Background(), user.ID, reversed.Entry.ID, usecase.ReverseEntryRequest{Reason: "Undo"}); !errors.Is(err, usecase.ErrLedgerEntryNotReversible) {
+		t.Fatalf("reversing a reversal: expected %v, got %v", usecase.ErrLedgerEntryNotReversible, err)
+	}
+	_, err = ledger.ReverseEntry(context.Background(), user.ID, earned.Entry.ID, usecase.ReverseEntryRequest{})
+	if fields := fieldErrors(t, err); !fields["reason"] {
+		t.Fatalf("expected a reason error, got %v", fields)
+	}
+}
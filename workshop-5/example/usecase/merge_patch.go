@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"example.com/mike/apperror"
+)
+
+// decodeStrict decodes a single JSON document into v, rejecting unknown
+// fields so typos never silently drop an update
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			field = strings.Trim(field, `"`)
+			return apperror.Validation("Invalid request format",
+				apperror.FieldError{Field: field, Message: field + " is not a known field"})
+		}
+		return apperror.Validation("Invalid request format")
+	}
+	if decoder.More() {
+		return apperror.Validation("Invalid request format")
+	}
+	return nil
+}
+
+// applyMergePatch applies an RFC 7396 JSON Merge Patch to a JSON document
+func applyMergePatch(document, patch []byte) ([]byte, error) {
+	var target, changes interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergeValue(target, changes))
+}
+
+// mergeValue implements the MergePatch algorithm from RFC 7396 section 2:
+// objects are merged member by member, null removes a member and any other
+// value replaces the target outright.
+func mergeValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergeValue(targetObject[name], value)
+	}
+	return targetObject
+}
@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Test cases from RFC 7396 Appendix A
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		original, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		got, err := applyMergePatch([]byte(tt.original), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("applyMergePatch(%s, %s): %v", tt.original, tt.patch, err)
+		}
+
+		var gotValue, wantValue interface{}
+		_ = json.Unmarshal(got, &gotValue)
+		_ = json.Unmarshal([]byte(tt.want), &wantValue)
+		if !reflect.DeepEqual(gotValue, wantValue) {
+			t.Errorf("applyMergePatch(%s, %s) = %s, want %s", tt.original, tt.patch, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/validation"
+)
+
+// OrderResponse represents an order and its payment. Balance is the
+// member's balance after checkout and is only set by Checkout, as is the
+// SMS receipt when it was sent before Checkout returned.
+type OrderResponse struct {
+	Success bool            `json:"success" example:"true"`
+	Message string          `json:"message" example:"Order placed"`
+	Order   *entity.Order   `json:"order"`
+	Payment *entity.Payment `json:"payment,omitempty"`
+	Balance int             `json:"balance,omitempty" example:"14920"`
+	Receipt *entity.Receipt `json:"receipt,omitempty"`
+}
+
+// OrderActionRequest represents a staff member changing an order's
+// status. A reason is required to void or refund an order. Actor is the
+// ID of the staff member; it is set by the caller, never read from the
+// body.
+type OrderActionRequest struct {
+	Actor  string `json:"-" validate:"required,max=64"`
+	Reason string `json:"reason" validate:"max=200" example:"Customer changed their mind"`
+}
+
+// orderTransitions lists the statuses each status may move to. Orders are
+// paid at checkout; voided and refunded orders are final.
+var orderTransitions = map[entity.OrderStatus][]entity.OrderStatus{
+	entity.OrderCreated:   {entity.OrderPaid, entity.OrderVoided},
+	entity.OrderPaid:      {entity.OrderFulfilled, entity.OrderVoided},
+	entity.OrderFulfilled: {entity.OrderRefunded},
+}
+
+// Errors returned by OrderUsecase
+var (
+	// ErrOrderNotFound is returned when the requested order does not exist
+	ErrOrderNotFound = apperror.NotFound("Order not found")
+
+	// ErrOrderTransitionNotAllowed is returned when the order's current
+	// status cannot move to the requested one
+	ErrOrderTransitionNotAllowed = apperror.Conflict("Order cannot move to the requested status")
+)
+
+// OrderUsecase defines the operations on placed orders. Orders are placed
+// by CartUsecase.Checkout.
+type OrderUsecase interface {
+	// GetOrder retrieves an order and its payment by ID
+	GetOrder(ctx context.Context, id string) (*OrderResponse, error)
+
+	// FulfillOrder marks a paid order as handed over to the customer
+	FulfillOrder(ctx context.Context, id string, req OrderActionRequest) (*OrderResponse, error)
+
+	// VoidOrder cancels an order before fulfilment, refunding its payment
+	// and restocking its items
+	VoidOrder(ctx context.Context, id string, req OrderActionRequest) (*OrderResponse, error)
+
+	// RefundOrder reverses a fulfilled order, refunding its payment and
+	// restocking its items
+	RefundOrder(ctx context.Context, id string, req OrderActionRequest) (*OrderResponse, error)
+}
+
+// orderUsecase implements the OrderUsecase interface
+type orderUsecase struct {
+	orderRepo repository.OrderRepository
+	orders    keyedMutex
+}
+
+// NewOrderUsecase creates a new order usecase
+func NewOrderUsecase(orderRepo repository.OrderRepository) OrderUsecase {
+	return &orderUsecase{
+		orderRepo: orderRepo,
+	}
+}
+
+// GetOrder retrieves an order and its payment by ID
+func (u *orderUsecase) GetOrder(ctx context.Context, id string) (*OrderResponse, error) {
+	order, err := u.orderRepo.GetByID(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get order", err)
+	}
+
+	payment, err := u.orderRepo.GetPayment(ctx, id)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get payment", err)
+	}
+
+	return &OrderResponse{
+		Success: true,
+		Message: "Order found",
+		Order:   order,
+		Payment: payment,
+	}, nil
+}
+
+// FulfillOrder marks a paid order as fulfilled
+func (u *orderUsecase) FulfillOrder(ctx context.Context, id string, req OrderActionRequest) (*OrderResponse, error) {
+	return u.transition(ctx, id, entity.OrderFulfilled, req, "Order fulfilled")
+}
+
+// VoidOrder voids an order that has not been fulfilled
+func (u *orderUsecase) VoidOrder(ctx context.Context, id string, req OrderActionRequest) (*OrderResponse, error) {
+	return u.transition(ctx, id, entity.OrderVoided, req, "Order voided")
+}
+
+// RefundOrder refunds a fulfilled order
+func (u *orderUsecase) RefundOrder(ctx context.Context, id string, req OrderActionRequest) (*OrderResponse, error) {
+	return u.transition(ctx, id, entity.OrderRefunded, req, "Order refunded")
+}
+
+// transition moves an order to status to if orderTransitions allows it.
+// Voiding or refunding a paid order posts a reversal of its payment, and
+// both restock the order's items, in the same write as the status change.
+func (u *orderUsecase) transition(ctx context.Context, id string, to entity.OrderStatus, req OrderActionRequest, message string) (*OrderResponse, error) {
+	trimSpace(&req.Actor, &req.Reason)
+	fields := validation.Struct(req)
+	if req.Reason == "" && (to == entity.OrderVoided || to == entity.OrderRefunded) {
+		fields = append(fields, apperror.FieldError{Field: "reason", Message: "reason is required"})
+	}
+	if len(fields) > 0 {
+		return nil, apperror.Validation("Invalid order action", fields...)
+	}
+
+	unlock := u.orders.Lock(id)
+	defer unlock()
+
+	order, err := u.orderRepo.GetByID(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get order", err)
+	}
+	if !slices.Contains(orderTransitions[order.Status], to) {
+		return nil, ErrOrderTransitionNotAllowed
+	}
+
+	final := to == entity.OrderVoided || to == entity.OrderRefunded
+	event := order.Clone().Apply(to, req.Actor, req.Reason)
+	order, err = u.orderRepo.Transition(ctx, repository.OrderTransition{
+		OrderID:       id,
+		Event:         event,
+		RefundPayment: final && (event.From == entity.OrderPaid || event.From == entity.OrderFulfilled),
+		Restock:       final,
+	})
+	switch {
+	case errors.Is(err, repository.ErrOrderStatusChanged):
+		return nil, ErrOrderTransitionNotAllowed
+	case errors.Is(err, apperror.ErrNotFound):
+		return nil, ErrOrderNotFound
+	case err != nil:
+		return nil, internalError(ctx, "Failed to update order", err)
+	}
+
+	payment, err := u.orderRepo.GetPayment(ctx, id)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get payment", err)
+	}
+
+	return &OrderResponse{
+		Success: true,
+		Message: message,
+		Order:   order,
+		Payment: payment,
+	}, nil
+}
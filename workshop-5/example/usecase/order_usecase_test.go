@@ -0,0 +1,132 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/mike/entity"
+	"example.com/mike/usecase"
+)
+
+// checkout places an order for quantity lattes
+func (f *cartFixture) checkout(t *testing.T, quantity int) *entity.Order {
+	t.Helper()
+	f.add(t, f.latte, quantity)
+	resp, err := f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: f.member})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	return resp.Order
+}
+
+func (f *cartFixture) balance(t *testing.T) int {
+	t.Helper()
+	resp, err := f.ledger.GetBalance(context.Background(), f.member)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	return resp.Balance
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	f := newCartFixture(t, 1000)
+	order := f.checkout(t, 2)
+	if len(order.Events) != 2 || order.Events[0].To != entity.OrderCreated || order.Events[1].To != entity.OrderPaid {
+		t.Fatalf("unexpected checkout events: %+v", order.Events)
+	}
+
+	resp, err := f.orders.FulfillOrder(context.Background(), order.ID, usecase.OrderActionRequest{Actor: " staff-1 "})
+	if err != nil {
+		t.Fatalf("FulfillOrder: %v", err)
+	}
+	fulfilled := resp.Order.Events[2]
+	if resp.Order.Status != entity.OrderFulfilled || fulfilled.From != entity.OrderPaid || fulfilled.Actor != "staff-1" {
+		t.Fatalf("unexpected fulfilled order: %+v", resp.Order)
+	}
+	if f.balance(t) != 760 || f.stock(t, f.latte) != 3 {
+		t.Fatalf("fulfilment changed balance %d or stock %d", f.balance(t), f.stock(t, f.latte))
+	}
+
+	resp, err = f.orders.RefundOrder(context.Background(), order.ID, usecase.OrderActionRequest{Actor: "staff-2", Reason: "Spilled"})
+	if err != nil {
+		t.Fatalf("RefundOrder: %v", err)
+	}
+	refunded := resp.Order.Events[3]
+	if resp.Order.Status != entity.OrderRefunded || refunded.Actor != "staff-2" || refunded.Reason != "Spilled" ||
+		resp.Payment.Status != entity.PaymentRefunded {
+		t.Fatalf("unexpected refunded order: %+v, payment %+v", resp.Order, resp.Payment)
+	}
+	if f.balance(t) != 1000 || f.stock(t, f.latte) != 5 {
+		t.Fatalf("refund not reversed: balance %d, stock %d", f.balance(t), f.stock(t, f.latte))
+	}
+
+	got, err := f.orders.GetOrder(context.Background(), order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if len(got.Order.Events) != 4 {
+		t.Fatalf("expected 4 events, got %+v", got.Order.Events)
+	}
+}
+
+func TestVoidOrder(t *testing.T) {
+	f := newCartFixture(t, 1000)
+	order := f.checkout(t, 1)
+
+	resp, err := f.orders.VoidOrder(context.Background(), order.ID, usecase.OrderActionRequest{Actor: "staff-1", Reason: "Out of milk"})
+	if err != nil {
+		t.Fatalf("VoidOrder: %v", err)
+	}
+	if resp.Order.Status != entity.OrderVoided || resp.Payment.Status != entity.PaymentRefunded {
+		t.Fatalf("unexpected voided order: %+v", resp)
+	}
+	if f.balance(t) != 1000 || f.stock(t, f.latte) != 5 {
+		t.Fatalf("void not reversed: balance %d, stock %d", f.balance(t), f.stock(t, f.latte))
+	}
+
+	// The refund is listed as a reversal, not as another spend
+	for _, filter := range []string{"spend", "reversal"} {
+		entries, err := f.ledger.ListEntries(context.Background(), f.member, usecase.ListEntriesRequest{Type: filter})
+		if err != nil {
+			t.Fatalf("ListEntries: %v", err)
+		}
+		if len(entries.Entries) != 1 || entries.Entries[0].OrderID != order.ID {
+			t.Fatalf("expected one %s entry for the order, got %+v", filter, entries.Entries)
+		}
+	}
+}
+
+func TestOrderTransitionFailures(t *testing.T) {
+	f := newCartFixture(t, 1000)
+	order := f.checkout(t, 1)
+	staff := usecase.OrderActionRequest{Actor: "staff-1", Reason: "Requested"}
+
+	if _, err := f.orders.RefundOrder(context.Background(), order.ID, staff); !errors.Is(err, usecase.ErrOrderTransitionNotAllowed) {
+		t.Fatalf("refund before fulfilment: expected %v, got %v", usecase.ErrOrderTransitionNotAllowed, err)
+	}
+	if _, err := f.orders.FulfillOrder(context.Background(), "missing", staff); !errors.Is(err, usecase.ErrOrderNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrOrderNotFound, err)
+	}
+
+	_, err := f.orders.VoidOrder(context.Background(), order.ID, usecase.OrderActionRequest{})
+	if fields := fieldErrors(t, err); !fields["Actor"] || !fields["reason"] {
+		t.Fatalf("expected actor and reason errors, got %v", fields)
+	}
+
+	if _, err := f.orders.VoidOrder(context.Background(), order.ID, staff); err != nil {
+		t.Fatalf("VoidOrder: %v", err)
+	}
+	for name, action := range map[string]func(context.Context, string, usecase.OrderActionRequest) (*usecase.OrderResponse, error){
+		"fulfil": f.orders.FulfillOrder,
+		"void":   f.orders.VoidOrder,
+		"refund": f.orders.RefundOrder,
+	} {
+		if _, err := action(context.Background(), order.ID, staff); !errors.Is(err, usecase.ErrOrderTransitionNotAllowed) {
+			t.Fatalf("%s after void: expected %v, got %v", name, usecase.ErrOrderTransitionNotAllowed, err)
+		}
+	}
+	if f.balance(t) != 1000 || f.stock(t, f.latte) != 5 {
+		t.Fatalf("void applied twice: balance %d, stock %d", f.balance(t), f.stock(t, f.latte))
+	}
+}
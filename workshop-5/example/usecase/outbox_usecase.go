@@ -0,0 +1,326 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"example.com/mike/analytics"
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"github.com/google/uuid"
+)
+
+// Outbox topics
+const (
+	// TopicSMSReceipt texts the receipt of a placed order, see
+	// ReceiptOutboxHandler
+	TopicSMSReceipt = "sms.receipt"
+
+	// TopicAnalyticsEvent records an analytics.Event, see
+	// AnalyticsOutboxHandler
+	TopicAnalyticsEvent = "analytics.event"
+)
+
+// OutboxHandler delivers the messages of one topic. A handler may be called
+// more than once for the same message, e.g. when the process dies before
+// the delivery is recorded, so it must tolerate repeats. Internal errors
+// are retried; any other kind of apperror.Error dead-letters the message at
+// once, since retrying cannot fix it.
+type OutboxHandler func(ctx context.Context, message *entity.OutboxMessage) error
+
+// OutboxPolicy controls how outbox messages are retried
+type OutboxPolicy struct {
+	// MaxAttempts is how many times a message is tried before it is
+	// dead-lettered
+	MaxAttempts int
+
+	// BaseDelay is the wait after the first failed attempt; it doubles
+	// after every further failure up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Lease is how long a claimed message is left to its dispatcher before
+	// it is tried again
+	Lease time.Duration
+
+	// BatchSize is how many due messages DispatchDue claims at a time
+	BatchSize int
+}
+
+// DefaultOutboxPolicy gives up after ten attempts spread over about a
+// quarter of an hour
+var DefaultOutboxPolicy = OutboxPolicy{
+	MaxAttempts: 10,
+	BaseDelay:   2 * time.Second,
+	MaxDelay:    10 * time.Minute,
+	Lease:       time.Minute,
+	BatchSize:   50,
+}
+
+// Backoff returns the wait after the given number of failed attempts
+func (p OutboxPolicy) Backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// ListOutboxRequest represents the filters of an outbox listing. Messages
+// are listed oldest first.
+type ListOutboxRequest struct {
+	// Status is pending, delivered or dead; empty lists every message
+	Status string `query:"status" example:"dead"`
+
+	// Limit is the number of messages, 1 to MaxListLimit
+	Limit int `query:"limit" example:"20"`
+}
+
+// ListOutboxResponse represents a list of outbox messages
+type ListOutboxResponse struct {
+	Success  bool                    `json:"success" example:"true"`
+	Messages []*entity.OutboxMessage `json:"messages"`
+	Count    int                     `json:"count" example:"1"`
+}
+
+// OutboxMessageResponse represents a single outbox message
+type OutboxMessageResponse struct {
+	Success       bool                  `json:"success" example:"true"`
+	Message       string                `json:"message" example:"Outbox message replayed"`
+	OutboxMessage *entity.OutboxMessage `json:"outbox_message"`
+}
+
+// Errors returned by OutboxUsecase
+var (
+	// ErrOutboxMessageNotFound is returned when the message does not exist
+	ErrOutboxMessageNotFound = apperror.NotFound("Outbox message not found")
+
+	// ErrOutboxMessageDelivered is returned when replaying a message that
+	// was already delivered
+	ErrOutboxMessageDelivered = apperror.Conflict("Outbox message was already delivered")
+)
+
+// OutboxUsecase dispatches outbox messages to the handler of their topic
+// and lets staff inspect and replay them
+type OutboxUsecase interface {
+	// Dispatch delivers a single message if it is due, e.g. right after
+	// the transaction that wrote it commits, and returns it with the
+	// outcome. Messages another dispatcher has claimed are left alone and
+	// returned as they are.
+	Dispatch(ctx context.Context, id string) (*entity.OutboxMessage, error)
+
+	// DispatchDue delivers every message due at now and returns how many
+	// were attempted
+	DispatchDue(ctx context.Context, now time.Time) (int, error)
+
+	// ListMessages retrieves messages by status
+	ListMessages(ctx context.Context, req ListOutboxRequest) (*ListOutboxResponse, error)
+
+	// GetMessage retrieves a message by ID
+	GetMessage(ctx context.Context, id string) (*OutboxMessageResponse, error)
+
+	// ReplayMessage resets the attempts of a pending or dead message and
+	// dispatches it at once
+	ReplayMessage(ctx context.Context, id string) (*OutboxMessageResponse, error)
+}
+
+// outboxUsecase implements the OutboxUsecase interface
+type outboxUsecase struct {
+	outboxRepo repository.OutboxRepository
+	handlers   map[string]OutboxHandler
+	policy     OutboxPolicy
+}
+
+// NewOutboxUsecase creates a new outbox usecase delivering each topic with
+// its handler. Messages for topics without a handler are dead-lettered.
+func NewOutboxUsecase(
+	outboxRepo repository.OutboxRepository,
+	handlers map[string]OutboxHandler,
+	policy OutboxPolicy,
+) OutboxUsecase {
+	return &outboxUsecase{
+		outboxRepo: outboxRepo,
+		handlers:   handlers,
+		policy:     policy,
+	}
+}
+
+// Dispatch claims and delivers a single message
+func (u *outboxUsecase) Dispatch(ctx context.Context, id string) (*entity.OutboxMessage, error) {
+	now := time.Now()
+	message, err := u.outboxRepo.Claim(ctx, id, now, u.policy.Lease)
+	switch {
+	case errors.Is(err, repository.ErrOutboxMessageNotDue):
+		return u.getMessage(ctx, id)
+	case errors.Is(err, apperror.ErrNotFound):
+		return nil, ErrOutboxMessageNotFound
+	case err != nil:
+		return nil, internalError(ctx, "Failed to claim outbox message", err)
+	}
+
+	if err := u.deliver(ctx, message, now); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// DispatchDue claims due messages in batches and delivers them one by one
+func (u *outboxUsecase) DispatchDue(ctx context.Context, now time.Time) (int, error) {
+	attempted := 0
+	for {
+		messages, err := u.outboxRepo.ClaimDue(ctx, now, u.policy.BatchSize, u.policy.Lease)
+		if err != nil {
+			return attempted, internalError(ctx, "Failed to claim outbox messages", err)
+		}
+		for _, message := range messages {
+			if err := u.deliver(ctx, message, now); err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+		if len(messages) == 0 || len(messages) < u.policy.BatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// ListMessages retrieves messages by status, oldest first
+func (u *outboxUsecase) ListMessages(ctx context.Context, req ListOutboxRequest) (*ListOutboxResponse, error) {
+	status := entity.OutboxStatus(req.Status)
+	var fields []apperror.FieldError
+	switch status {
+	case "", entity.OutboxPending, entity.OutboxDelivered, entity.OutboxDead:
+	default:
+		fields = append(fields, apperror.FieldError{Field: "status", Message: "status must be one of: pending delivered dead"})
+	}
+	limit := req.Limit
+	switch {
+	case limit == 0:
+		limit = DefaultListLimit
+	case limit < 0 || limit > MaxListLimit:
+		fields = append(fields, apperror.FieldError{Field: "limit", Message: "limit must be between 1 and 100"})
+	}
+	if len(fields) > 0 {
+		return nil, apperror.Validation("Invalid list parameters", fields...)
+	}
+
+	messages, err := u.outboxRepo.List(ctx, status, limit)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to list outbox messages", err)
+	}
+
+	return &ListOutboxResponse{
+		Success:  true,
+		Messages: messages,
+		Count:    len(messages),
+	}, nil
+}
+
+// GetMessage retrieves a message by ID
+func (u *outboxUsecase) GetMessage(ctx context.Context, id string) (*OutboxMessageResponse, error) {
+	message, err := u.getMessage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxMessageResponse{
+		Success:       true,
+		Message:       "Outbox message found",
+		OutboxMessage: message,
+	}, nil
+}
+
+// ReplayMessage moves a message back to pending and dispatches it
+func (u *outboxUsecase) ReplayMessage(ctx context.Context, id string) (*OutboxMessageResponse, error) {
+	_, err := u.outboxRepo.Replay(ctx, id, time.Now())
+	switch {
+	case errors.Is(err, repository.ErrOutboxMessageDelivered):
+		return nil, ErrOutboxMessageDelivered
+	case errors.Is(err, apperror.ErrNotFound):
+		return nil, ErrOutboxMessageNotFound
+	case err != nil:
+		return nil, internalError(ctx, "Failed to replay outbox message", err)
+	}
+
+	message, err := u.Dispatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxMessageResponse{
+		Success:       true,
+		Message:       "Outbox message replayed",
+		OutboxMessage: message,
+	}, nil
+}
+
+// deliver runs the handler of a claimed message and records the outcome:
+// delivered, retried after a backoff, or dead once retrying cannot help or
+// the attempts run out. The recorded error includes the cause internal
+// errors hide from clients, since only staff see it.
+func (u *outboxUsecase) deliver(ctx context.Context, message *entity.OutboxMessage, now time.Time) error {
+	handler, ok := u.handlers[message.Topic]
+	var err error
+	if ok {
+		err = handler(ctx, message)
+	} else {
+		err = apperror.Unprocessable(fmt.Sprintf("No handler for topic %q", message.Topic))
+	}
+
+	switch {
+	case err == nil:
+		message.MarkDelivered(now)
+	case apperror.KindOf(err) != apperror.KindInternal || message.Attempts >= u.policy.MaxAttempts:
+		log.Printf("Dead-lettering outbox message %s (%s) after %d attempts: %v", message.ID, message.Topic, message.Attempts, err)
+		message.MarkDead(err.Error(), now)
+	default:
+		message.MarkRetry(err.Error(), now.Add(u.policy.Backoff(message.Attempts)), now)
+	}
+
+	// Record the outcome even if ctx ends meanwhile, so a delivered message
+	// is not delivered again
+	if err := u.outboxRepo.Update(context.WithoutCancel(ctx), message); err != nil {
+		return apperror.Internal("Failed to update outbox message", err)
+	}
+	return nil
+}
+
+// getMessage loads a message, mapping a missing one to
+// ErrOutboxMessageNotFound
+func (u *outboxUsecase) getMessage(ctx context.Context, id string) (*entity.OutboxMessage, error) {
+	message, err := u.outboxRepo.GetByID(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrOutboxMessageNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get outbox message", err)
+	}
+	return message, nil
+}
+
+// newOutboxMessage creates a message for topic with a new ID
+func newOutboxMessage(topic string, payload any) (*entity.OutboxMessage, error) {
+	message, err := entity.NewOutboxMessage(uuid.New().String(), topic, payload)
+	if err != nil {
+		return nil, apperror.Internal("Failed to create outbox message", err)
+	}
+	return message, nil
+}
+
+// AnalyticsOutboxHandler records TopicAnalyticsEvent messages with events
+func AnalyticsOutboxHandler(events AnalyticsEmitter) OutboxHandler {
+	return func(ctx context.Context, message *entity.OutboxMessage) error {
+		var event analytics.Event
+		if err := message.Decode(&event); err != nil {
+			return apperror.Unprocessable(err.Error())
+		}
+		if err := events.Emit(event); err != nil {
+			return apperror.Internal("Failed to emit analytics event", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,226 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+)
+
+// testOutboxPolicy retries quickly so tests can step through every attempt
+var testOutboxPolicy = usecase.OutboxPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+	Lease:       time.Minute,
+	BatchSize:   2,
+}
+
+// outboxFixture dispatches the "test" topic with a handler whose outcome the
+// test controls
+type outboxFixture struct {
+	repo   repository.OutboxRepository
+	outbox usecase.OutboxUsecase
+	err    error
+	calls  int
+}
+
+func newOutboxFixture(t *testing.T) *outboxFixture {
+	t.Helper()
+	f := &outboxFixture{repo: repository.NewMemoryOutboxRepository()}
+	f.outbox = usecase.NewOutboxUsecase(f.repo, map[string]usecase.OutboxHandler{
+		"test": func(context.Context, *entity.OutboxMessage) error {
+			f.calls++
+			return f.err
+		},
+	}, testOutboxPolicy)
+	return f
+}
+
+// enqueue stores a message for topic that is due now
+func (f *outboxFixture) enqueue(t *testing.T, id, topic string) {
+	t.Helper()
+	message, err := entity.NewOutboxMessage(id, topic, map[string]string{"id": id})
+	if err != nil {
+		t.Fatalf("NewOutboxMessage: %v", err)
+	}
+	if err := f.repo.Enqueue(context.Background(), message); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+}
+
+func (f *outboxFixture) get(t *testing.T, id string) *entity.OutboxMessage {
+	t.Helper()
+	resp, err := f.outbox.GetMessage(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	return resp.OutboxMessage
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	policy := usecase.OutboxPolicy{BaseDelay: 2 * time.Second, MaxDelay: 10 * time.Second}
+	want := map[int]time.Duration{1: 2 * time.Second, 2: 4 * time.Second, 3: 8 * time.Second, 4: 10 * time.Second, 50: 10 * time.Second}
+	for attempts, delay := range want {
+		if got := policy.Backoff(attempts); got != delay {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, delay)
+		}
+	}
+}
+
+func TestOutboxDispatchDue(t *testing.T) {
+	f := newOutboxFixture(t)
+	for _, id := range []string{"m1", "m2", "m3"} {
+		f.enqueue(t, id, "test")
+	}
+
+	// Messages are claimed in batches until none are due
+	attempted, err := f.outbox.DispatchDue(context.Background(), time.Now())
+	if err != nil || attempted != 3 || f.calls != 3 {
+		t.Fatalf("DispatchDue = %d, %v after %d calls", attempted, err, f.calls)
+	}
+	message := f.get(t, "m1")
+	if message.Status != entity.OutboxDelivered || message.Attempts != 1 || message.LastError != "" {
+		t.Fatalf("unexpected message: %+v", message)
+	}
+
+	attempted, err = f.outbox.DispatchDue(context.Background(), time.Now())
+	if err != nil || attempted != 0 {
+		t.Fatalf("DispatchDue = %d, %v, want nothing left", attempted, err)
+	}
+}
+
+func TestOutboxRetriesThenDeadLetters(t *testing.T) {
+	f := newOutboxFixture(t)
+	f.enqueue(t, "m1", "test")
+	f.err = apperror.Internal("Failed to send", errors.New("provider unreachable"))
+
+	now := time.Now()
+	for attempt, wait := range []time.Duration{time.Second, 2 * time.Second} {
+		if _, err := f.outbox.DispatchDue(context.Background(), now); err != nil {
+			t.Fatalf("DispatchDue: %v", err)
+		}
+		message := f.get(t, "m1")
+		if message.Status != entity.OutboxPending || message.Attempts != attempt+1 ||
+			!message.NextAttemptAt.Equal(now.Add(wait)) || !strings.Contains(message.LastError, "provider unreachable") {
+			t.Fatalf("attempt %d: unexpected message %+v", attempt+1, message)
+		}
+
+		// Nothing is tried again before the backoff has passed
+		if attempted, _ := f.outbox.DispatchDue(context.Background(), now.Add(wait-time.Millisecond)); attempted != 0 {
+			t.Fatalf("attempt %d: retried before the backoff", attempt+1)
+		}
+		now = now.Add(wait)
+	}
+
+	if _, err := f.outbox.DispatchDue(context.Background(), now); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+	if message := f.get(t, "m1"); message.Status != entity.OutboxDead || message.Attempts != testOutboxPolicy.MaxAttempts {
+		t.Fatalf("expected a dead message, got %+v", message)
+	}
+	if attempted, _ := f.outbox.DispatchDue(context.Background(), now.Add(time.Hour)); attempted != 0 {
+		t.Fatal("dead message was retried")
+	}
+}
+
+func TestOutboxDeadLettersPermanentFailures(t *testing.T) {
+	f := newOutboxFixture(t)
+	f.enqueue(t, "bad", "test")
+	f.enqueue(t, "unknown", "nobody.listens")
+	f.err = apperror.Unprocessable("Malformed payload")
+
+	if _, err := f.outbox.DispatchDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+	for id, reason := range map[string]string{"bad": "Malformed payload", "unknown": "No handler"} {
+		message := f.get(t, id)
+		if message.Status != entity.OutboxDead || message.Attempts != 1 || !strings.Contains(message.LastError, reason) {
+			t.Fatalf("%s: unexpected message %+v", id, message)
+		}
+	}
+}
+
+func TestOutboxDispatch(t *testing.T) {
+	f := newOutboxFixture(t)
+	f.enqueue(t, "m1", "test")
+
+	// A message claimed by the dispatcher is left alone
+	if _, err := f.repo.Claim(context.Background(), "m1", time.Now(), time.Minute); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	message, err := f.outbox.Dispatch(context.Background(), "m1")
+	if err != nil || message.Status != entity.OutboxPending || message.Attempts != 1 || f.calls != 0 {
+		t.Fatalf("Dispatch = %+v, %v after %d calls", message, err, f.calls)
+	}
+
+	if _, err := f.outbox.Dispatch(context.Background(), "missing"); !errors.Is(err, usecase.ErrOutboxMessageNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrOutboxMessageNotFound, err)
+	}
+}
+
+func TestOutboxReplay(t *testing.T) {
+	f := newOutboxFixture(t)
+	f.enqueue(t, "m1", "test")
+	f.err = apperror.Unprocessable("Malformed payload")
+	if _, err := f.outbox.DispatchDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+
+	// Once the cause is fixed a dead message can be replayed
+	f.err = nil
+	resp, err := f.outbox.ReplayMessage(context.Background(), "m1")
+	if err != nil {
+		t.Fatalf("ReplayMessage: %v", err)
+	}
+	if message := resp.OutboxMessage; message.Status != entity.OutboxDelivered || message.Attempts != 1 || f.calls != 2 {
+		t.Fatalf("unexpected message: %+v", message)
+	}
+
+	tests := []struct {
+		id   string
+		want error
+	}{
+		{"m1", usecase.ErrOutboxMessageDelivered},
+		{"missing", usecase.ErrOutboxMessageNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := f.outbox.ReplayMessage(context.Background(), tt.id); !errors.Is(err, tt.want) {
+			t.Errorf("ReplayMessage(%s): expected %v, got %v", tt.id, tt.want, err)
+		}
+	}
+}
+
+func TestListOutboxMessages(t *testing.T) {
+	f := newOutboxFixture(t)
+	f.enqueue(t, "m1", "test")
+	f.enqueue(t, "m2", "nobody.listens")
+	if _, err := f.outbox.DispatchDue(context.Background(), time.Now()); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+
+	resp, err := f.outbox.ListMessages(context.Background(), usecase.ListOutboxRequest{Status: "dead"})
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	if resp.Count != 1 || resp.Messages[0].ID != "m2" {
+		t.Fatalf("unexpected messages: %+v", resp.Messages)
+	}
+	if resp, _ := f.outbox.ListMessages(context.Background(), usecase.ListOutboxRequest{}); resp.Count != 2 {
+		t.Fatalf("expected every message, got %d", resp.Count)
+	}
+
+	_, err = f.outbox.ListMessages(context.Background(), usecase.ListOutboxRequest{Status: "stuck", Limit: 101})
+	if fields := fieldErrors(t, err); !fields["status"] || !fields["limit"] {
+		t.Fatalf("expected status and limit errors, got %v", fields)
+	}
+	if _, err := f.outbox.GetMessage(context.Background(), "missing"); !errors.Is(err, usecase.ErrOutboxMessageNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrOutboxMessageNotFound, err)
+	}
+}
@@ -0,0 +1,301 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/validation"
+	"github.com/google/uuid"
+)
+
+// ProductRequest represents the editable fields of a product. Categories
+// are stored in lower case. Active defaults to true when a product is
+// created and is left unchanged when omitted from an update.
+type ProductRequest struct {
+	Name        string `json:"name" validate:"required,max=100" example:"Iced Latte"`
+	Description string `json:"description" validate:"max=1000" example:"Double shot, oat milk"`
+	Category    string `json:"category" validate:"required,max=50" example:"drinks"`
+	PricePoints int    `json:"price_points" validate:"required,min=1,max=1000000" example:"120"`
+	Stock       int    `json:"stock" validate:"min=0" example:"40"`
+	Active      *bool  `json:"active,omitempty" example:"true"`
+}
+
+// ProductResponse represents a single product
+type ProductResponse struct {
+	Success bool            `json:"success" example:"true"`
+	Message string          `json:"message" example:"Product found"`
+	Product *entity.Product `json:"product,omitempty"`
+}
+
+// ListProductsRequest represents the filters and page position of a
+// product listing. Products are listed by name.
+type ListProductsRequest struct {
+	// Category matches the category exactly, ignoring case
+	Category string `query:"category" example:"drinks"`
+
+	// Q searches the name and description
+	Q string `query:"q" example:"latte"`
+
+	// Limit is the page size, 1 to MaxListLimit
+	Limit int `query:"limit" example:"20"`
+
+	// Cursor is the next_cursor of the previous page
+	Cursor string `query:"cursor"`
+}
+
+// ListProductsResponse represents one page of products
+type ListProductsResponse struct {
+	Success    bool              `json:"success" example:"true"`
+	Products   []*entity.Product `json:"products"`
+	Count      int               `json:"count" example:"20"`
+	NextCursor string            `json:"next_cursor,omitempty" example:"eyJuIjoiSWNlZCBMYXR0ZSJ9"`
+}
+
+// Errors returned by ProductUsecase
+var (
+	// ErrProductNotFound is returned when the product does not exist or,
+	// in the public catalog, is inactive
+	ErrProductNotFound = apperror.NotFound("Product not found")
+)
+
+// DecodeProductRequest strictly decodes a JSON product body. Unknown fields
+// are rejected so typos never silently drop a change.
+func DecodeProductRequest(data []byte) (ProductRequest, error) {
+	var req ProductRequest
+	err := decodeStrict(data, &req)
+	return req, err
+}
+
+// ProductUsecase defines the product catalog operations. The public
+// catalog only shows active products; admin operations see every product.
+type ProductUsecase interface {
+	// CreateProduct adds a product to the catalog
+	CreateProduct(ctx context.Context, req ProductRequest) (*ProductResponse, error)
+
+	// GetProduct retrieves a product by ID. Inactive products are only
+	// returned when includeInactive is set.
+	GetProduct(ctx context.Context, id string, includeInactive bool) (*ProductResponse, error)
+
+	// ListProducts retrieves a filtered page of products. Inactive
+	// products are only listed when includeInactive is set.
+	ListProducts(ctx context.Context, req ListProductsRequest, includeInactive bool) (*ListProductsResponse, error)
+
+	// UpdateProduct replaces the editable fields of a product
+	UpdateProduct(ctx context.Context, id string, req ProductRequest) (*ProductResponse, error)
+
+	// PatchProduct applies an RFC 7396 JSON Merge Patch to a product
+	PatchProduct(ctx context.Context, id string, patch []byte) (*ProductResponse, error)
+
+	// DeleteProduct removes a product from the catalog
+	DeleteProduct(ctx context.Context, id string) error
+}
+
+// productUsecase implements the ProductUsecase interface
+type productUsecase struct {
+	productRepo repository.ProductRepository
+}
+
+// NewProductUsecase creates a new product catalog usecase
+func NewProductUsecase(productRepo repository.ProductRepository) ProductUsecase {
+	return &productUsecase{
+		productRepo: productRepo,
+	}
+}
+
+// CreateProduct adds a product to the catalog
+func (u *productUsecase) CreateProduct(ctx context.Context, req ProductRequest) (*ProductResponse, error) {
+	req, err := normalizeProductRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	active := req.Active == nil || *req.Active
+	product := entity.NewProduct(uuid.New().String(), req.Name, req.Description, req.Category, req.PricePoints, req.Stock, active)
+	if err := u.productRepo.Create(ctx, product); err != nil {
+		return nil, internalError(ctx, "Failed to create product", err)
+	}
+
+	return &ProductResponse{
+		Success: true,
+		Message: "Product created",
+		Product: product,
+	}, nil
+}
+
+// GetProduct retrieves a product by ID
+func (u *productUsecase) GetProduct(ctx context.Context, id string, includeInactive bool) (*ProductResponse, error) {
+	product, err := u.getProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !product.Active && !includeInactive {
+		return nil, ErrProductNotFound
+	}
+
+	return &ProductResponse{
+		Success: true,
+		Message: "Product found",
+		Product: product,
+	}, nil
+}
+
+// ListProducts retrieves a filtered page of products ordered by name
+func (u *productUsecase) ListProducts(ctx context.Context, req ListProductsRequest, includeInactive bool) (*ListProductsResponse, error) {
+	query := repository.ProductQuery{
+		Category:   strings.ToLower(strings.TrimSpace(req.Category)),
+		Search:     strings.TrimSpace(req.Q),
+		ActiveOnly: !includeInactive,
+		Limit:      req.Limit,
+	}
+
+	var fields []apperror.FieldError
+	if len(query.Search) > 100 {
+		fields = append(fields, apperror.FieldError{Field: "q", Message: "q must be at most 100 characters"})
+	}
+	switch {
+	case req.Limit == 0:
+		query.Limit = DefaultListLimit
+	case req.Limit < 0 || req.Limit > MaxListLimit:
+		fields = append(fields, apperror.FieldError{Field: "limit", Message: "limit must be between 1 and 100"})
+	}
+	if req.Cursor != "" {
+		var cursor repository.ProductCursor
+		if err := decodeCursor(req.Cursor, &cursor); err != nil || cursor.ID == "" {
+			fields = append(fields, apperror.FieldError{Field: "cursor", Message: "cursor is malformed"})
+		} else {
+			query.After = &cursor
+		}
+	}
+	if len(fields) > 0 {
+		return nil, apperror.Validation("Invalid list parameters", fields...)
+	}
+
+	page, err := u.productRepo.Query(ctx, query)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to list products", err)
+	}
+
+	response := &ListProductsResponse{
+		Success:  true,
+		Products: page.Products,
+		Count:    len(page.Products),
+	}
+	if page.Next != nil {
+		response.NextCursor = encodeCursor(page.Next)
+	}
+	return response, nil
+}
+
+// UpdateProduct replaces the editable fields of a product
+func (u *productUsecase) UpdateProduct(ctx context.Context, id string, req ProductRequest) (*ProductResponse, error) {
+	product, err := u.getProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.applyUpdate(ctx, product, req)
+}
+
+// PatchProduct applies an RFC 7396 JSON Merge Patch to a product. The patch
+// is applied to the product's current editable fields, then validated
+// exactly like a full update.
+func (u *productUsecase) PatchProduct(ctx context.Context, id string, patch []byte) (*ProductResponse, error) {
+	product, err := u.getProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := json.Marshal(ProductRequest{
+		Name:        product.Name,
+		Description: product.Description,
+		Category:    product.Category,
+		PricePoints: product.PricePoints,
+		Stock:       product.Stock,
+		Active:      &product.Active,
+	})
+	if err != nil {
+		return nil, apperror.Internal("Failed to encode product", err)
+	}
+
+	merged, err := applyMergePatch(current, patch)
+	if err != nil {
+		return nil, apperror.Validation("Invalid merge patch document")
+	}
+
+	req, err := DecodeProductRequest(merged)
+	if err != nil {
+		return nil, err
+	}
+	return u.applyUpdate(ctx, product, req)
+}
+
+// DeleteProduct removes a product from the catalog
+func (u *productUsecase) DeleteProduct(ctx context.Context, id string) error {
+	err := u.productRepo.Delete(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return internalError(ctx, "Failed to delete product", err)
+	}
+	return nil
+}
+
+// getProduct loads a product, mapping repository errors
+func (u *productUsecase) getProduct(ctx context.Context, id string) (*entity.Product, error) {
+	product, err := u.productRepo.GetByID(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get product", err)
+	}
+	return product, nil
+}
+
+// applyUpdate validates req and stores it over product
+func (u *productUsecase) applyUpdate(ctx context.Context, product *entity.Product, req ProductRequest) (*ProductResponse, error) {
+	req, err := normalizeProductRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Name = req.Name
+	product.Description = req.Description
+	product.Category = req.Category
+	product.PricePoints = req.PricePoints
+	product.Stock = req.Stock
+	if req.Active != nil {
+		product.Active = *req.Active
+	}
+
+	if err := u.productRepo.Update(ctx, product); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, internalError(ctx, "Failed to update product", err)
+	}
+
+	return &ProductResponse{
+		Success: true,
+		Message: "Product updated",
+		Product: product,
+	}, nil
+}
+
+// normalizeProductRequest validates req, reporting every invalid field at
+// once, and returns it trimmed with the category in lower case
+func normalizeProductRequest(req ProductRequest) (ProductRequest, error) {
+	trimSpace(&req.Name, &req.Description, &req.Category)
+	if fields := validation.Struct(req); len(fields) > 0 {
+		return req, apperror.Validation("Invalid product data", fields...)
+	}
+
+	req.Category = strings.ToLower(req.Category)
+	return req, nil
+}
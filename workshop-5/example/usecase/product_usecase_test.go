@@ -0,0 +1,145 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func createProduct(t *testing.T, uc usecase.ProductUsecase, req usecase.ProductRequest) string {
+	t.Helper()
+	resp, err := uc.CreateProduct(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateProduct(%s): %v", req.Name, err)
+	}
+	return resp.Product.ID
+}
+
+func TestCreateProduct(t *testing.T) {
+	uc := usecase.NewProductUsecase(repository.NewMemoryProductRepository())
+
+	resp, err := uc.CreateProduct(context.Background(), usecase.ProductRequest{Name: " Iced Latte ", Category: " Drinks ", PricePoints: 120, Stock: 40})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	product := resp.Product
+	if product.ID == "" || product.Name != "Iced Latte" || product.Category != "drinks" || !product.Active || product.Stock != 40 {
+		t.Fatalf("unexpected product: %+v", product)
+	}
+
+	resp, err = uc.CreateProduct(context.Background(), usecase.ProductRequest{Name: "Draft", Category: "drinks", PricePoints: 1, Active: boolPtr(false)})
+	if err != nil || resp.Product.Active {
+		t.Fatalf("CreateProduct(active false) = %+v, %v", resp, err)
+	}
+}
+
+func TestCreateProductValidation(t *testing.T) {
+	uc := usecase.NewProductUsecase(repository.NewMemoryProductRepository())
+
+	_, err := uc.CreateProduct(context.Background(), usecase.ProductRequest{Description: strings.Repeat("x", 1001), PricePoints: -1, Stock: -1})
+	fields := fieldErrors(t, err)
+	for _, field := range []string{"name", "description", "category", "price_points", "stock"} {
+		if !fields[field] {
+			t.Errorf("expected a %s field error, got %v", field, fields)
+		}
+	}
+}
+
+func TestProductVisibility(t *testing.T) {
+	uc := usecase.NewProductUsecase(repository.NewMemoryProductRepository())
+	hidden := createProduct(t, uc, usecase.ProductRequest{Name: "Hidden", Category: "drinks", PricePoints: 1, Active: boolPtr(false)})
+	createProduct(t, uc, usecase.ProductRequest{Name: "Shown", Category: "drinks", PricePoints: 1})
+
+	if _, err := uc.GetProduct(context.Background(), hidden, false); !errors.Is(err, usecase.ErrProductNotFound) {
+		t.Fatalf("public GetProduct of an inactive product: expected %v, got %v", usecase.ErrProductNotFound, err)
+	}
+	if resp, err := uc.GetProduct(context.Background(), hidden, true); err != nil || resp.Product.ID != hidden {
+		t.Fatalf("admin GetProduct = %+v, %v", resp, err)
+	}
+
+	public, err := uc.ListProducts(context.Background(), usecase.ListProductsRequest{Category: "DRINKS"}, false)
+	if err != nil || public.Count != 1 || public.Products[0].Name != "Shown" {
+		t.Fatalf("public ListProducts = %+v, %v", public, err)
+	}
+	all, err := uc.ListProducts(context.Background(), usecase.ListProductsRequest{}, true)
+	if err != nil || all.Count != 2 {
+		t.Fatalf("admin ListProducts = %+v, %v", all, err)
+	}
+}
+
+func TestListProductsPagination(t *testing.T) {
+	uc := usecase.NewProductUsecase(repository.NewMemoryProductRepository())
+	for _, name := range []string{"Americano", "Bagel", "Croissant"} {
+		createProduct(t, uc, usecase.ProductRequest{Name: name, Category: "cafe", PricePoints: 50})
+	}
+
+	first, err := uc.ListProducts(context.Background(), usecase.ListProductsRequest{Limit: 2}, false)
+	if err != nil || first.Count != 2 || first.NextCursor == "" {
+		t.Fatalf("first page = %+v, %v", first, err)
+	}
+	second, err := uc.ListProducts(context.Background(), usecase.ListProductsRequest{Limit: 2, Cursor: first.NextCursor}, false)
+	if err != nil || second.Count != 1 || second.Products[0].Name != "Croissant" || second.NextCursor != "" {
+		t.Fatalf("second page = %+v, %v", second, err)
+	}
+
+	_, err = uc.ListProducts(context.Background(), usecase.ListProductsRequest{Limit: 101, Cursor: "not-a-cursor"}, false)
+	if fields := fieldErrors(t, err); !fields["limit"] || !fields["cursor"] {
+		t.Fatalf("expected limit and cursor field errors, got %v", err)
+	}
+}
+
+func TestUpdateAndPatchProduct(t *testing.T) {
+	uc := usecase.NewProductUsecase(repository.NewMemoryProductRepository())
+	id := createProduct(t, uc, usecase.ProductRequest{Name: "Latte", Description: "Hot", Category: "drinks", PricePoints: 100, Stock: 5})
+
+	resp, err := uc.UpdateProduct(context.Background(), id, usecase.ProductRequest{Name: "Iced Latte", Category: "Cold", PricePoints: 120, Stock: 10})
+	if err != nil {
+		t.Fatalf("UpdateProduct: %v", err)
+	}
+	if p := resp.Product; p.Name != "Iced Latte" || p.Description != "" || p.Category != "cold" || p.PricePoints != 120 || !p.Active {
+		t.Fatalf("unexpected product after update: %+v", p)
+	}
+
+	resp, err = uc.PatchProduct(context.Background(), id, []byte(`{"stock": 0, "active": false}`))
+	if err != nil {
+		t.Fatalf("PatchProduct: %v", err)
+	}
+	if p := resp.Product; p.Stock != 0 || p.Active || p.Name != "Iced Latte" || p.PricePoints != 120 {
+		t.Fatalf("unexpected product after patch: %+v", p)
+	}
+
+	if fields := fieldErrors(t, func() error {
+		_, err := uc.PatchProduct(context.Background(), id, []byte(`{"name": null, "colour": "red"}`))
+		return err
+	}()); !fields["colour"] {
+		t.Fatalf("expected an unknown field error, got %v", fields)
+	}
+	if fields := fieldErrors(t, func() error {
+		_, err := uc.PatchProduct(context.Background(), id, []byte(`{"price_points": 0}`))
+		return err
+	}()); !fields["price_points"] {
+		t.Fatalf("expected a price_points field error, got %v", fields)
+	}
+
+	if _, err := uc.UpdateProduct(context.Background(), "missing", usecase.ProductRequest{Name: "X", Category: "x", PricePoints: 1}); !errors.Is(err, usecase.ErrProductNotFound) {
+		t.Fatalf("UpdateProduct missing: expected %v, got %v", usecase.ErrProductNotFound, err)
+	}
+}
+
+func TestDeleteProduct(t *testing.T) {
+	uc := usecase.NewProductUsecase(repository.NewMemoryProductRepository())
+	id := createProduct(t, uc, usecase.ProductRequest{Name: "Latte", Category: "drinks", PricePoints: 100})
+
+	if err := uc.DeleteProduct(context.Background(), id); err != nil {
+		t.Fatalf("DeleteProduct: %v", err)
+	}
+	if err := uc.DeleteProduct(context.Background(), id); !errors.Is(err, usecase.ErrProductNotFound) {
+		t.Fatalf("DeleteProduct twice: expected %v, got %v", usecase.ErrProductNotFound, err)
+	}
+}
@@ -0,0 +1,372 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/qrpayload"
+	"example.com/mike/repository"
+	"example.com/mike/validation"
+	"github.com/google/uuid"
+)
+
+// QRPolicy holds the limits applied to QR payment requests
+type QRPolicy struct {
+	// MinAmount and MaxAmount bound the requested amount
+	MinAmount int
+	MaxAmount int
+
+	// DefaultTTL applies when the request sets no expiry; MaxTTL bounds it
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+// DefaultQRPolicy matches the transfer limits, since paying a request
+// posts a transfer
+var DefaultQRPolicy = QRPolicy{
+	MinAmount:  DefaultTransferPolicy.MinAmount,
+	MaxAmount:  DefaultTransferPolicy.MaxAmount,
+	DefaultTTL: 15 * time.Minute,
+	MaxTTL:     24 * time.Hour,
+}
+
+// qrSweepBatch is how many due requests ExpireDue loads at a time
+const qrSweepBatch = 100
+
+// CreateQRRequest represents a request for a payment shown as a QR code
+type CreateQRRequest struct {
+	RecipientID string `json:"recipient_id" validate:"required" example:"6ba7b810-9dad-11d1-80b4-00c04fd430c8"`
+	Amount      int    `json:"amount" validate:"required" example:"250"`
+	Memo        string `json:"memo" validate:"max=200" example:"Coffee"`
+	ExpiresIn   int    `json:"expires_in" validate:"min=0" example:"900"` // seconds, 0 for the default
+}
+
+// PayQRRequest represents a payer settling a QR payment request with the
+// payload scanned from its QR code
+type PayQRRequest struct {
+	Payload string `json:"payload" validate:"required" example:"LBK1.k1.8d3c2b1a-4e5f-4a6b-9c7d-0e1f2a3b4c5d.6ba7b810-9dad-11d1-80b4-00c04fd430c8.6y.sbd9hs.Lw2yq1Xh3s0p4b9Jm7Q1Kg"`
+	PayerID string `json:"payer_id" validate:"required" example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
+// QRRequestResponse represents a QR payment request and, once paid, the
+// transfer that settled it
+type QRRequestResponse struct {
+	Success  bool              `json:"success" example:"true"`
+	Message  string            `json:"message" example:"QR request created"`
+	Request  *entity.QRRequest `json:"request"`
+	Payload  string            `json:"payload,omitempty" example:"LBK1.k1.8d3c2b1a-4e5f-4a6b-9c7d-0e1f2a3b4c5d.6ba7b810-9dad-11d1-80b4-00c04fd430c8.6y.sbd9hs.Lw2yq1Xh3s0p4b9Jm7Q1Kg"`
+	Transfer *entity.Transfer  `json:"transfer,omitempty"`
+	Balance  int               `json:"balance,omitempty" example:"14750"`
+}
+
+// Errors returned by QRUsecase
+var (
+	// ErrQRRequestNotFound is returned when the requested QR request does
+	// not exist
+	ErrQRRequestNotFound = apperror.NotFound("QR request not found")
+
+	// ErrQRRequestExpired is returned when paying an expired request
+	ErrQRRequestExpired = apperror.Conflict("QR request has expired")
+
+	// ErrQRRequestAlreadyPaid is returned when a payload is replayed for a
+	// request that was already paid
+	ErrQRRequestAlreadyPaid = apperror.Conflict("QR request has already been paid")
+
+	// ErrQRRecipientNotFound is returned when the recipient does not exist
+	ErrQRRecipientNotFound = apperror.Validation("Invalid QR request",
+		apperror.FieldError{Field: "recipient_id", Message: "recipient not found"})
+)
+
+// QRUsecase defines the QR payment request operations
+type QRUsecase interface {
+	// CreateRequest creates a pending payment request
+	CreateRequest(ctx context.Context, req CreateQRRequest) (*QRRequestResponse, error)
+
+	// GetRequest retrieves a payment request by ID
+	GetRequest(ctx context.Context, id string) (*QRRequestResponse, error)
+
+	// PayRequest verifies a scanned payload and settles its pending
+	// request with a transfer from the payer
+	PayRequest(ctx context.Context, req PayQRRequest) (*QRRequestResponse, error)
+
+	// ExpireDue expires every pending request past its expiry at now and
+	// returns how many were expired
+	ExpireDue(ctx context.Context, now time.Time) (int, error)
+}
+
+// qrUsecase implements the QRUsecase interface
+type qrUsecase struct {
+	userRepo  repository.UserRepository
+	qrRepo    repository.QRRequestRepository
+	transfers TransferUsecase
+	keyring   *qrpayload.Keyring
+	policy    QRPolicy
+
+	// requests serialises payment and expiry per request, so a request is
+	// never both paid and expired
+	requests keyedMutex
+}
+
+// NewQRUsecase creates a new QR payment request usecase. Payloads are
+// signed with keyring, and payments are posted through transfers, so they
+// obey the transfer limits as well.
+func NewQRUsecase(
+	userRepo repository.UserRepository,
+	qrRepo repository.QRRequestRepository,
+	transfers TransferUsecase,
+	keyring *qrpayload.Keyring,
+	policy QRPolicy,
+) QRUsecase {
+	return &qrUsecase{
+		userRepo:  userRepo,
+		qrRepo:    qrRepo,
+		transfers: transfers,
+		keyring:   keyring,
+		policy:    policy,
+	}
+}
+
+// CreateRequest creates a pending payment request
+func (u *qrUsecase) CreateRequest(ctx context.Context, req CreateQRRequest) (*QRRequestResponse, error) {
+	trimSpace(&req.RecipientID, &req.Memo)
+
+	fields := validation.Struct(req)
+	if req.Amount != 0 && (req.Amount < u.policy.MinAmount || req.Amount > u.policy.MaxAmount) {
+		fields = append(fields, apperror.FieldError{
+			Field:   "amount",
+			Message: fmt.Sprintf("amount must be between %d and %d", u.policy.MinAmount, u.policy.MaxAmount),
+		})
+	}
+	ttl := time.Duration(req.ExpiresIn) * time.Second
+	if ttl > u.policy.MaxTTL {
+		fields = append(fields, apperror.FieldError{
+			Field:   "expires_in",
+			Message: fmt.Sprintf("expires_in must be at most %d seconds", int(u.policy.MaxTTL.Seconds())),
+		})
+	}
+	if len(fields) > 0 {
+		return nil, apperror.Validation("Invalid QR request", fields...)
+	}
+	if ttl == 0 {
+		ttl = u.policy.DefaultTTL
+	}
+
+	if _, err := u.userRepo.GetByID(ctx, req.RecipientID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, ErrQRRecipientNotFound
+		}
+		return nil, internalError(ctx, "Failed to get recipient", err)
+	}
+
+	request := entity.NewQRRequest(uuid.New().String(), req.RecipientID, req.Amount, req.Memo, ttl)
+	payload, err := u.sign(request)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.qrRepo.Create(ctx, request); err != nil {
+		return nil, internalError(ctx, "Failed to create QR request", err)
+	}
+
+	return &QRRequestResponse{
+		Success: true,
+		Message: "QR request created",
+		Request: request,
+		Payload: payload,
+	}, nil
+}
+
+// GetRequest retrieves a payment request by ID. A pending request past its
+// expiry is expired first, so it never shows as payable.
+func (u *qrUsecase) GetRequest(ctx context.Context, id string) (*QRRequestResponse, error) {
+	request, err := u.getRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Due(time.Now()) {
+		unlock := u.requests.Lock(id)
+		request, err = u.expire(ctx, id)
+		unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	response := &QRRequestResponse{
+		Success: true,
+		Message: "QR request found",
+		Request: request,
+	}
+	if request.Status == entity.QRRequestPending {
+		if response.Payload, err = u.sign(request); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// PayRequest verifies the payload's signature and expiry, checks it against
+// the stored request, then settles the request with a transfer from the
+// payer to the recipient. The transfer only posts while the request is
+// still pending, and marks it paid atomically, so a payload pays at most
+// once; the request lock just keeps this instance from racing itself.
+func (u *qrUsecase) PayRequest(ctx context.Context, req PayQRRequest) (*QRRequestResponse, error) {
+	trimSpace(&req.Payload, &req.PayerID)
+	if fields := validation.Struct(req); len(fields) > 0 {
+		return nil, apperror.Validation("Invalid payment", fields...)
+	}
+
+	now := time.Now()
+	claims, err := u.keyring.Verify(req.Payload, now)
+	if err != nil && !errors.Is(err, qrpayload.ErrExpired) {
+		return nil, invalidPayload(err.Error())
+	}
+	id := claims.RequestID
+
+	unlock := u.requests.Lock(id)
+	defer unlock()
+
+	request, err := u.getRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.RecipientID != claims.RecipientID || request.Amount != claims.Amount ||
+		request.ExpiresAt.Unix() != claims.ExpiresAt.Unix() {
+		return nil, invalidPayload("QR payload does not match the request")
+	}
+	if request.Due(now) {
+		if _, err := u.expire(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrQRRequestExpired
+	}
+	switch request.Status {
+	case entity.QRRequestPaid:
+		return nil, ErrQRRequestAlreadyPaid
+	case entity.QRRequestExpired:
+		return nil, ErrQRRequestExpired
+	}
+	if req.PayerID == request.RecipientID {
+		return nil, apperror.Validation("Invalid payment",
+			apperror.FieldError{Field: "payer_id", Message: "cannot pay your own request"})
+	}
+
+	// The transfer marks the request paid in the same transaction as its
+	// posting, so the payer is never debited for a request left pending
+	transfer, err := u.transfers.Transfer(ctx, TransferRequest{
+		FromUserID:  req.PayerID,
+		ToUserID:    request.RecipientID,
+		Amount:      request.Amount,
+		Memo:        request.Memo,
+		QRRequestID: id,
+	})
+	if errors.Is(err, repository.ErrQRRequestNotPending) {
+		// Paid or expired by another instance since the status check
+		return nil, u.notPending(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	paid, err := u.getRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QRRequestResponse{
+		Success:  true,
+		Message:  "QR request paid",
+		Request:  paid,
+		Transfer: transfer.Transfer,
+		Balance:  transfer.Balance,
+	}, nil
+}
+
+// ExpireDue expires every pending request past its expiry at now
+func (u *qrUsecase) ExpireDue(ctx context.Context, now time.Time) (int, error) {
+	expired := 0
+	for {
+		due, err := u.qrRepo.ListDue(ctx, now, qrSweepBatch)
+		if err != nil {
+			return expired, internalError(ctx, "Failed to list due QR requests", err)
+		}
+
+		for _, request := range due {
+			unlock := u.requests.Lock(request.ID)
+			_, err := u.qrRepo.MarkExpired(ctx, request.ID)
+			unlock()
+
+			// Paid while waiting for the lock
+			if errors.Is(err, repository.ErrQRRequestNotPending) {
+				continue
+			}
+			if err != nil {
+				return expired, internalError(ctx, "Failed to expire QR request", err)
+			}
+			expired++
+		}
+
+		if len(due) < qrSweepBatch {
+			return expired, nil
+		}
+	}
+}
+
+// sign returns the signed payload of a request
+func (u *qrUsecase) sign(request *entity.QRRequest) (string, error) {
+	payload, err := u.keyring.Sign(qrpayload.Claims{
+		RequestID:   request.ID,
+		RecipientID: request.RecipientID,
+		Amount:      request.Amount,
+		ExpiresAt:   request.ExpiresAt,
+	})
+	if err != nil {
+		return "", apperror.Internal("Failed to sign QR payload", err)
+	}
+	return payload, nil
+}
+
+// invalidPayload reports a payload that failed verification
+func invalidPayload(reason string) error {
+	return apperror.Validation("Invalid QR payload", apperror.FieldError{Field: "payload", Message: reason})
+}
+
+// getRequest loads a request, mapping repository errors
+func (u *qrUsecase) getRequest(ctx context.Context, id string) (*entity.QRRequest, error) {
+	request, err := u.qrRepo.GetByID(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrQRRequestNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get QR request", err)
+	}
+	return request, nil
+}
+
+// notPending reports why a request that was pending can no longer be paid
+func (u *qrUsecase) notPending(ctx context.Context, id string) error {
+	request, err := u.getRequest(ctx, id)
+	if err != nil {
+		return err
+	}
+	if request.Status == entity.QRRequestPaid {
+		return ErrQRRequestAlreadyPaid
+	}
+	return ErrQRRequestExpired
+}
+
+// expire marks a due request expired; the caller holds its lock. A request
+// paid in the meantime is returned as is.
+func (u *qrUsecase) expire(ctx context.Context, id string) (*entity.QRRequest, error) {
+	request, err := u.qrRepo.MarkExpired(ctx, id)
+	if errors.Is(err, repository.ErrQRRequestNotPending) {
+		return u.getRequest(ctx, id)
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to expire QR request", err)
+	}
+	return request, nil
+}
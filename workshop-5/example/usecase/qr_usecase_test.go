@@ -0,0 +1,405 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/qrpayload"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+)
+
+// qrFixture wires the QR usecase to in-memory storage with a recipient and
+// two funded payers
+type qrFixture struct {
+	qr                           usecase.QRUsecase
+	qrRepo                       repository.QRRequestRepository
+	keyring                      *qrpayload.Keyring
+	ledger                       usecase.LedgerUsecase
+	recipient, payer, otherPayer string
+}
+
+func newQRFixture(t *testing.T) *qrFixture {
+	t.Helper()
+	return newQRFixtureWithRepo(t, repository.NewMemoryQRRequestRepository())
+}
+
+// newQRFixtureWithRepo builds the fixture on qrRepo, e.g. to inject failures
+func newQRFixtureWithRepo(t *testing.T, qrRepo repository.QRRequestRepository) *qrFixture {
+	t.Helper()
+	userRepo, ledgerRepo := repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository()
+	users := usecase.NewUserUsecase(userRepo, ledgerRepo)
+	transfers := usecase.NewTransferUsecase(userRepo, repository.NewMemoryTransferRepository(ledgerRepo, qrRepo), ledgerRepo, usecase.DefaultTransferPolicy)
+	keyring := newKeyring(t, "k1")
+
+	f := &qrFixture{
+		qr:         usecase.NewQRUsecase(userRepo, qrRepo, transfers, keyring, usecase.DefaultQRPolicy),
+		qrRepo:     qrRepo,
+		keyring:    keyring,
+		ledger:     usecase.NewLedgerUsecase(userRepo, ledgerRepo),
+		recipient:  registerUser(t, users, 1).User.ID,
+		payer:      registerUser(t, users, 2).User.ID,
+		otherPayer: registerUser(t, users, 3).User.ID,
+	}
+	for _, id := range []string{f.payer, f.otherPayer} {
+		if _, err := f.ledger.PostEntry(context.Background(), id, usecase.PostEntryRequest{Type: "earn", Amount: 1000}); err != nil {
+			t.Fatalf("PostEntry: %v", err)
+		}
+	}
+	return f
+}
+
+// newKeyring returns a keyring with a single random key
+func newKeyring(t *testing.T, id string) *qrpayload.Keyring {
+	t.Helper()
+	key, err := qrpayload.GenerateKey(id)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	keyring, err := qrpayload.NewKeyring(key)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return keyring
+}
+
+// create creates a request and returns it with its signed payload
+func (f *qrFixture) create(t *testing.T, amount int) (*entity.QRRequest, string) {
+	t.Helper()
+	resp, err := f.qr.CreateRequest(context.Background(), usecase.CreateQRRequest{RecipientID: f.recipient, Amount: amount, Memo: "Coffee"})
+	if err != nil {
+		t.Fatalf("CreateRequest: %v", err)
+	}
+	return resp.Request, resp.Payload
+}
+
+// store saves a request directly, bypassing the usecase, and signs it
+func (f *qrFixture) store(t *testing.T, request *entity.QRRequest) string {
+	t.Helper()
+	if err := f.qrRepo.Create(context.Background(), request); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return sign(t, f.keyring, request.ID, request.RecipientID, request.Amount, request.ExpiresAt)
+}
+
+func sign(t *testing.T, keyring *qrpayload.Keyring, id, recipientID string, amount int, expiresAt time.Time) string {
+	t.Helper()
+	payload, err := keyring.Sign(qrpayload.Claims{RequestID: id, RecipientID: recipientID, Amount: amount, ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return payload
+}
+
+func (f *qrFixture) balance(t *testing.T, userID string) int {
+	t.Helper()
+	resp, err := f.ledger.GetBalance(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	return resp.Balance
+}
+
+func TestCreateQRRequest(t *testing.T) {
+	f := newQRFixture(t)
+
+	before := time.Now()
+	resp, err := f.qr.CreateRequest(context.Background(), usecase.CreateQRRequest{RecipientID: f.recipient, Amount: 250, Memo: " Coffee "})
+	if err != nil {
+		t.Fatalf("CreateRequest: %v", err)
+	}
+	request := resp.Request
+	if request.Status != entity.QRRequestPending || request.Memo != "Coffee" || !strings.HasPrefix(resp.Payload, qrpayload.Version+".k1.") {
+		t.Fatalf("unexpected response: %+v %+v", resp, request)
+	}
+	claims, err := f.keyring.Verify(resp.Payload, time.Now())
+	if err != nil || claims.RequestID != request.ID || claims.RecipientID != f.recipient || claims.Amount != 250 {
+		t.Fatalf("Verify(payload) = %+v, %v", claims, err)
+	}
+	if ttl := request.ExpiresAt.Sub(before); ttl < usecase.DefaultQRPolicy.DefaultTTL || ttl > usecase.DefaultQRPolicy.DefaultTTL+time.Minute {
+		t.Fatalf("expected the default expiry, got %s", ttl)
+	}
+
+	resp, err = f.qr.CreateRequest(context.Background(), usecase.CreateQRRequest{RecipientID: f.recipient, Amount: 1, ExpiresIn: 60})
+	if err != nil || resp.Request.ExpiresAt.Sub(resp.Request.CreatedAt) != time.Minute {
+		t.Fatalf("CreateRequest(expires_in 60) = %+v, %v", resp, err)
+	}
+}
+
+func TestCreateQRRequestValidation(t *testing.T) {
+	f := newQRFixture(t)
+
+	tests := []struct {
+		name  string
+		req   usecase.CreateQRRequest
+		field string
+	}{
+		{"missing recipient", usecase.CreateQRRequest{Amount: 1}, "recipient_id"},
+		{"unknown recipient", usecase.CreateQRRequest{RecipientID: "missing", Amount: 1}, "recipient_id"},
+		{"zero amount", usecase.CreateQRRequest{RecipientID: f.recipient}, "amount"},
+		{"above maximum", usecase.CreateQRRequest{RecipientID: f.recipient, Amount: 50001}, "amount"},
+		{"negative expiry", usecase.CreateQRRequest{RecipientID: f.recipient, Amount: 1, ExpiresIn: -1}, "expires_in"},
+		{"expiry too long", usecase.CreateQRRequest{RecipientID: f.recipient, Amount: 1, ExpiresIn: 86401}, "expires_in"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.qr.CreateRequest(context.Background(), tt.req)
+			if fields := fieldErrors(t, err); !fields[tt.field] {
+				t.Fatalf("expected a %s field error, got %v", tt.field, err)
+			}
+		})
+	}
+}
+
+func TestPayQRRequest(t *testing.T) {
+	f := newQRFixture(t)
+	request, payload := f.create(t, 250)
+
+	resp, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: " " + payload + " ", PayerID: f.payer})
+	if err != nil {
+		t.Fatalf("PayRequest: %v", err)
+	}
+	if resp.Request.Status != entity.QRRequestPaid || resp.Request.PayerID != f.payer ||
+		resp.Request.TransferID != resp.Transfer.ID || resp.Transfer.Memo != "Coffee" || resp.Balance != 750 {
+		t.Fatalf("unexpected response: %+v %+v %+v", resp, resp.Request, resp.Transfer)
+	}
+	if got := f.balance(t, f.recipient); got != 250 {
+		t.Fatalf("recipient balance = %d, want 250", got)
+	}
+
+	got, err := f.qr.GetRequest(context.Background(), request.ID)
+	if err != nil || got.Request.Status != entity.QRRequestPaid || got.Payload != "" {
+		t.Fatalf("GetRequest after payment = %+v, %v", got, err)
+	}
+
+	// Replaying the payload does not pay again
+	if _, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: payload, PayerID: f.otherPayer}); !errors.Is(err, usecase.ErrQRRequestAlreadyPaid) {
+		t.Fatalf("second payment: expected %v, got %v", usecase.ErrQRRequestAlreadyPaid, err)
+	}
+	if got := f.balance(t, f.otherPayer); got != 1000 {
+		t.Fatalf("second payer was charged: balance %d", got)
+	}
+}
+
+func TestPayQRRequestFailures(t *testing.T) {
+	f := newQRFixture(t)
+
+	missing := sign(t, f.keyring, "missing", f.recipient, 100, time.Now().Add(time.Minute))
+	if _, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: missing, PayerID: f.payer}); !errors.Is(err, usecase.ErrQRRequestNotFound) {
+		t.Fatalf("unknown request: expected %v, got %v", usecase.ErrQRRequestNotFound, err)
+	}
+
+	_, payload := f.create(t, 250)
+	if fields := fieldErrors(t, func() error {
+		_, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{PayerID: f.payer})
+		return err
+	}()); !fields["payload"] {
+		t.Fatal("expected a payload field error without a payload")
+	}
+	if fields := fieldErrors(t, func() error {
+		_, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: payload, PayerID: f.recipient})
+		return err
+	}()); !fields["payer_id"] {
+		t.Fatal("expected a payer_id field error when paying your own request")
+	}
+	if _, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: payload, PayerID: "missing"}); !errors.Is(err, usecase.ErrUserNotFound) {
+		t.Fatalf("unknown payer: expected %v, got %v", usecase.ErrUserNotFound, err)
+	}
+
+	// A failed transfer leaves the request payable
+	expensive, payload := f.create(t, 1001)
+	if _, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: payload, PayerID: f.payer}); !errors.Is(err, usecase.ErrInsufficientBalance) {
+		t.Fatalf("expected %v, got %v", usecase.ErrInsufficientBalance, err)
+	}
+	got, err := f.qr.GetRequest(context.Background(), expensive.ID)
+	if err != nil || got.Request.Status != entity.QRRequestPending {
+		t.Fatalf("request after failed payment = %+v, %v; want pending", got, err)
+	}
+}
+
+// markPaidHookRepository runs beforeMarkPaid, when set, before marking a
+// request paid and fails with its error
+type markPaidHookRepository struct {
+	repository.QRRequestRepository
+	beforeMarkPaid func(id string) error
+}
+
+func (r *markPaidHookRepository) MarkPaid(ctx context.Context, id, payerID, transferID string) (*entity.QRRequest, error) {
+	if r.beforeMarkPaid != nil {
+		if err := r.beforeMarkPaid(id); err != nil {
+			return nil, err
+		}
+	}
+	return r.QRRequestRepository.MarkPaid(ctx, id, payerID, transferID)
+}
+
+func TestPayQRRequestRollsBackWhenMarkPaidFails(t *testing.T) {
+	qrRepo := &markPaidHookRepository{QRRequestRepository: repository.NewMemoryQRRequestRepository()}
+	f := newQRFixtureWithRepo(t, qrRepo)
+	request, payload := f.create(t, 250)
+
+	qrRepo.beforeMarkPaid = func(string) error { return errors.New("disk full") }
+	if _, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: payload, PayerID: f.payer}); !errors.Is(err, apperror.ErrInternal) {
+		t.Fatalf("expected an internal error, got %v", err)
+	}
+	if payer, recipient := f.balance(t, f.payer), f.balance(t, f.recipient); payer != 1000 || recipient != 0 {
+		t.Fatalf("balances after failed payment = %d, %d; want 1000, 0", payer, recipient)
+	}
+	got, err := f.qr.GetRequest(context.Background(), request.ID)
+	if err != nil || got.Request.Status != entity.QRRequestPending {
+		t.Fatalf("request after failed payment = %+v, %v; want pending", got, err)
+	}
+
+	// The same payload pays the request once it can be marked paid
+	qrRepo.beforeMarkPaid = nil
+	resp, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: payload, PayerID: f.payer})
+	if err != nil || resp.Request.Status != entity.QRRequestPaid || resp.Balance != 750 {
+		t.Fatalf("retried payment = %+v, %v", resp, err)
+	}
+	if _, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: payload, PayerID: f.payer}); !errors.Is(err, usecase.ErrQRRequestAlreadyPaid) {
+		t.Fatalf("third payment: expected %v, got %v", usecase.ErrQRRequestAlreadyPaid, err)
+	}
+	if got := f.balance(t, f.payer); got != 750 {
+		t.Fatalf("payer balance = %d, want 750", got)
+	}
+}
+
+func TestPayQRRequestExpiredByAnotherInstance(t *testing.T) {
+	qrRepo := &markPaidHookRepository{QRRequestRepository: repository.NewMemoryQRRequestRepository()}
+	f := newQRFixtureWithRepo(t, qrRepo)
+	_, payload := f.create(t, 250)
+
+	// Another instance's sweeper expires the request while it is being paid
+	qrRepo.beforeMarkPaid = func(id string) error {
+		_, err := qrRepo.MarkExpired(context.Background(), id)
+		return err
+	}
+	if _, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: payload, PayerID: f.payer}); !errors.Is(err, usecase.ErrQRRequestExpired) {
+		t.Fatalf("expected %v, got %v", usecase.ErrQRRequestExpired, err)
+	}
+	if payer, recipient := f.balance(t, f.payer), f.balance(t, f.recipient); payer != 1000 || recipient != 0 {
+		t.Fatalf("balances after expired payment = %d, %d; want 1000, 0", payer, recipient)
+	}
+}
+
+func TestPayQRRequestRejectsBadPayloads(t *testing.T) {
+	f := newQRFixture(t)
+	request, payload := f.create(t, 250)
+	expiresAt := request.ExpiresAt
+
+	cut := strings.LastIndex(payload, ".")
+	forged := payload[:cut+1] + strings.Repeat("A", len(payload)-cut-1)
+	if forged == payload {
+		forged = payload[:cut+1] + strings.Repeat("B", len(payload)-cut-1)
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		message string
+	}{
+		{"not a payload", "lbkpay:" + request.ID, "malformed QR payload"},
+		{"future version", "LBK9" + strings.TrimPrefix(payload, qrpayload.Version), "unsupported QR payload version"},
+		{"forged signature", forged, "invalid QR payload signature"},
+		{"edited amount", strings.Replace(payload, ".6y.", ".1.", 1), "invalid QR payload signature"},
+		{"unknown key", sign(t, newKeyring(t, "other"), request.ID, f.recipient, 250, expiresAt), "unknown QR signing key"},
+		{"same key id, other secret", sign(t, newKeyring(t, "k1"), request.ID, f.recipient, 250, expiresAt), "invalid QR payload signature"},
+		{"other amount", sign(t, f.keyring, request.ID, f.recipient, 1, expiresAt), "QR payload does not match the request"},
+		{"other recipient", sign(t, f.keyring, request.ID, f.payer, 250, expiresAt), "QR payload does not match the request"},
+		{"other expiry", sign(t, f.keyring, request.ID, f.recipient, 250, expiresAt.Add(time.Hour)), "QR payload does not match the request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: tt.payload, PayerID: f.payer})
+			var appErr *apperror.Error
+			if !errors.As(err, &appErr) || appErr.Kind != apperror.KindValidation ||
+				len(appErr.Fields) != 1 || appErr.Fields[0].Field != "payload" || appErr.Fields[0].Message != tt.message {
+				t.Fatalf("expected a payload field error %q, got %v", tt.message, err)
+			}
+		})
+	}
+
+	if got := f.balance(t, f.payer); got != 1000 {
+		t.Fatalf("payer was charged for a rejected payload: balance %d", got)
+	}
+	if _, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: payload, PayerID: f.payer}); err != nil {
+		t.Fatalf("the genuine payload should still pay: %v", err)
+	}
+}
+
+func TestQRRequestExpiry(t *testing.T) {
+	f := newQRFixture(t)
+
+	// Past its expiry but not swept yet
+	stale := f.store(t, entity.NewQRRequest("stale", f.recipient, 100, "", -time.Second))
+	if _, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: stale, PayerID: f.payer}); !errors.Is(err, usecase.ErrQRRequestExpired) {
+		t.Fatalf("paying a stale request: expected %v, got %v", usecase.ErrQRRequestExpired, err)
+	}
+	if got := f.balance(t, f.payer); got != 1000 {
+		t.Fatalf("payer was charged for an expired request: balance %d", got)
+	}
+
+	unswept := entity.NewQRRequest("unswept", f.recipient, 100, "", -time.Second)
+	if err := f.qrRepo.Create(context.Background(), unswept); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	got, err := f.qr.GetRequest(context.Background(), "unswept")
+	if err != nil || got.Request.Status != entity.QRRequestExpired || got.Payload != "" {
+		t.Fatalf("GetRequest of a due request = %+v, %v; want expired", got, err)
+	}
+
+	// The sweeper expires pending requests only
+	_, payload := f.create(t, 100)
+	paid, paidPayload := f.create(t, 100)
+	if _, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: paidPayload, PayerID: f.payer}); err != nil {
+		t.Fatalf("PayRequest: %v", err)
+	}
+	expired, err := f.qr.ExpireDue(context.Background(), time.Now().Add(time.Hour))
+	if err != nil || expired != 1 {
+		t.Fatalf("ExpireDue = %d, %v; want 1", expired, err)
+	}
+	if _, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: payload, PayerID: f.payer}); !errors.Is(err, usecase.ErrQRRequestExpired) {
+		t.Fatalf("paying a swept request: expected %v, got %v", usecase.ErrQRRequestExpired, err)
+	}
+	if got, err := f.qr.GetRequest(context.Background(), paid.ID); err != nil || got.Request.Status != entity.QRRequestPaid {
+		t.Fatalf("paid request after sweep = %+v, %v", got, err)
+	}
+}
+
+func TestConcurrentQRPaymentsPayOnce(t *testing.T) {
+	f := newQRFixture(t)
+	_, payload := f.create(t, 100)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	paid := 0
+	for _, payer := range []string{f.payer, f.otherPayer, f.payer, f.otherPayer} {
+		wg.Add(1)
+		go func(payer string) {
+			defer wg.Done()
+			_, err := f.qr.PayRequest(context.Background(), usecase.PayQRRequest{Payload: payload, PayerID: payer})
+			if err != nil && !errors.Is(err, usecase.ErrQRRequestAlreadyPaid) {
+				t.Errorf("PayRequest: unexpected error: %v", err)
+			}
+			if err == nil {
+				mu.Lock()
+				paid++
+				mu.Unlock()
+			}
+		}(payer)
+	}
+	wg.Wait()
+
+	if paid != 1 {
+		t.Fatalf("expected one payment, got %d", paid)
+	}
+	if got := f.balance(t, f.recipient); got != 100 {
+		t.Fatalf("recipient balance = %d, want 100", got)
+	}
+}
@@ -0,0 +1,341 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"example.com/mike/analytics"
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/sms"
+	"github.com/google/uuid"
+)
+
+// Receipt languages. Thai is the default.
+const (
+	LanguageThai    = "th"
+	LanguageEnglish = "en"
+)
+
+// SMSSender sends text messages through an SMS provider, returning the
+// provider's reference for the message. sms.LogSender and sms.HTTPSender
+// implement it.
+type SMSSender interface {
+	Send(msg sms.Message) (providerRef string, err error)
+}
+
+// Analytics events recorded through the outbox once a receipt's outcome is
+// known
+const (
+	// EventSMSSent is emitted when the provider reports a receipt delivered
+	EventSMSSent = "sms_sent"
+
+	// EventSMSFailed is emitted when the provider rejects a receipt or
+	// reports that it could not be delivered
+	EventSMSFailed = "sms_failed"
+)
+
+// AnalyticsEmitter records analytics events. analytics.LogEmitter
+// implements it.
+type AnalyticsEmitter interface {
+	Emit(event analytics.Event) error
+}
+
+// ReceiptMessage is the payload of a TopicSMSReceipt message, enqueued at
+// checkout in the same transaction as the order
+type ReceiptMessage struct {
+	OrderID  string `json:"order_id"`
+	Language string `json:"language"`
+}
+
+// receiptTemplates are the receipt texts by language. The Thai text is sent
+// as UCS-2, so it is kept short enough for two segments.
+var receiptTemplates = map[string]*template.Template{
+	LanguageThai: template.Must(template.New(LanguageThai).Parse(
+		"ขอบคุณที่ใช้บริการ คำสั่งซื้อ #{{.Ref}} ชำระ {{.Total}} พอยต์ คงเหลือ {{.Balance}} พอยต์")),
+	LanguageEnglish: template.Must(template.New(LanguageEnglish).Parse(
+		"Thank you! Order #{{.Ref}}: {{.Total}} points paid, {{.Balance}} points left.")),
+}
+
+// receiptData fills a receipt template
+type receiptData struct {
+	Ref     string
+	Total   int
+	Balance int
+}
+
+// ReceiptResponse represents the SMS receipt of an order
+type ReceiptResponse struct {
+	Success bool            `json:"success" example:"true"`
+	Receipt *entity.Receipt `json:"receipt"`
+}
+
+// DeliveryReportResponse represents the receipt a delivery report was
+// applied to. Duplicate is set when the receipt already had its outcome,
+// e.g. for a report the provider retried, and nothing was changed.
+type DeliveryReportResponse struct {
+	Success   bool            `json:"success" example:"true"`
+	Duplicate bool            `json:"duplicate" example:"false"`
+	Receipt   *entity.Receipt `json:"receipt"`
+}
+
+// Errors returned by ReceiptUsecase
+var (
+	// ErrReceiptNotFound is returned when the order has no receipt, or no
+	// receipt was sent with a delivery report's message ID
+	ErrReceiptNotFound = apperror.NotFound("Receipt not found")
+
+	// ErrInvalidWebhookSignature is returned for delivery reports that are
+	// unsigned, signed with another secret or too old
+	ErrInvalidWebhookSignature = apperror.Unauthorized("Invalid webhook signature")
+)
+
+// ReceiptUsecase defines the SMS receipt operations
+type ReceiptUsecase interface {
+	// SendReceipt texts the receipt of a placed order, with the member's
+	// balance, to the customer's phone number or the member's when the
+	// order has none. The receipt is recorded as sent, or as failed when
+	// the provider rejects it. When the provider cannot be reached the
+	// receipt stays pending and an internal error is returned, so the
+	// outbox retries it; receipts that are no longer pending are returned
+	// as they are without sending them again.
+	SendReceipt(ctx context.Context, orderID, language string) (*entity.Receipt, error)
+
+	// GetReceipt retrieves the receipt of an order
+	GetReceipt(ctx context.Context, orderID string) (*ReceiptResponse, error)
+
+	// ReceiveDeliveryReport applies a provider's signed delivery report
+	// to the receipt sent with its message ID. Reports are idempotent on
+	// the message ID: once a receipt is delivered or failed, later reports
+	// for it change nothing.
+	ReceiveDeliveryReport(ctx context.Context, body []byte, timestamp, signature string) (*DeliveryReportResponse, error)
+}
+
+// receiptUsecase implements the ReceiptUsecase interface
+type receiptUsecase struct {
+	userRepo    repository.UserRepository
+	orderRepo   repository.OrderRepository
+	ledgerRepo  repository.LedgerRepository
+	receiptRepo repository.ReceiptRepository
+	sender      SMSSender
+
+	// webhookSecret is shared with the provider to sign delivery reports
+	webhookSecret []byte
+}
+
+// NewReceiptUsecase creates a new SMS receipt usecase. webhookSecret
+// verifies the provider's delivery reports.
+func NewReceiptUsecase(
+	userRepo repository.UserRepository,
+	orderRepo repository.OrderRepository,
+	ledgerRepo repository.LedgerRepository,
+	receiptRepo repository.ReceiptRepository,
+	sender SMSSender,
+	webhookSecret []byte,
+) ReceiptUsecase {
+	return &receiptUsecase{
+		userRepo:      userRepo,
+		orderRepo:     orderRepo,
+		ledgerRepo:    ledgerRepo,
+		receiptRepo:   receiptRepo,
+		sender:        sender,
+		webhookSecret: webhookSecret,
+	}
+}
+
+// SendReceipt records a pending receipt unless one exists, sends it and
+// records the outcome
+func (u *receiptUsecase) SendReceipt(ctx context.Context, orderID, language string) (*entity.Receipt, error) {
+	receipt, err := u.receiptRepo.GetByOrderID(ctx, orderID)
+	switch {
+	case err == nil && receipt.Status != entity.ReceiptPending:
+		return receipt, nil
+	case errors.Is(err, apperror.ErrNotFound):
+		if receipt, err = u.createReceipt(ctx, orderID, language); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, internalError(ctx, "Failed to get receipt", err)
+	}
+
+	// A pending receipt found above was left by an attempt that could not
+	// reach the provider, or died before recording the outcome, so it is
+	// sent again
+	var messages []*entity.OutboxMessage
+	ref, err := u.sender.Send(sms.Message{To: receipt.Phone, Body: receipt.Body})
+	switch {
+	case errors.Is(err, sms.ErrRejected), errors.Is(err, sms.ErrInvalidMessage):
+		receipt.MarkFailed(err.Error())
+		message, err := u.eventMessage(EventSMSFailed, receipt)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	case err != nil:
+		return nil, apperror.Internal("Failed to send receipt", err)
+	default:
+		receipt.MarkSent(ref)
+	}
+	if err := u.receiptRepo.Update(ctx, receipt, messages...); err != nil {
+		return nil, internalError(ctx, "Failed to update receipt", err)
+	}
+	return receipt, nil
+}
+
+// createReceipt renders and stores a pending receipt for an order
+func (u *receiptUsecase) createReceipt(ctx context.Context, orderID, language string) (*entity.Receipt, error) {
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get order", err)
+	}
+	balance, err := u.ledgerRepo.Balance(ctx, order.UserID)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get balance", err)
+	}
+
+	tmpl, ok := receiptTemplates[language]
+	if !ok {
+		language, tmpl = LanguageThai, receiptTemplates[LanguageThai]
+	}
+
+	var body strings.Builder
+	data := receiptData{Ref: strings.ToUpper(order.ID[:min(8, len(order.ID))]), Total: order.Total, Balance: balance}
+	if err := tmpl.Execute(&body, data); err != nil {
+		return nil, apperror.Internal("Failed to render receipt", err)
+	}
+
+	phone := order.CustomerPhone
+	if phone == "" {
+		user, err := u.userRepo.GetByID(ctx, order.UserID)
+		if err != nil {
+			return nil, internalError(ctx, "Failed to get user", err)
+		}
+		phone = user.Phone
+	}
+
+	receipt := entity.NewReceipt(uuid.New().String(), order.ID, order.UserID, phone, language, body.String())
+	count := sms.CountSegments(receipt.Body)
+	receipt.Encoding, receipt.Segments = string(count.Encoding), count.Segments
+	if err := u.receiptRepo.Create(ctx, receipt); err != nil {
+		return nil, internalError(ctx, "Failed to create receipt", err)
+	}
+	return receipt, nil
+}
+
+// GetReceipt retrieves the receipt of an order
+func (u *receiptUsecase) GetReceipt(ctx context.Context, orderID string) (*ReceiptResponse, error) {
+	receipt, err := u.receiptRepo.GetByOrderID(ctx, orderID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrReceiptNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get receipt", err)
+	}
+
+	return &ReceiptResponse{
+		Success: true,
+		Receipt: receipt,
+	}, nil
+}
+
+// ReceiveDeliveryReport verifies a delivery report and applies it once
+func (u *receiptUsecase) ReceiveDeliveryReport(ctx context.Context, body []byte, timestamp, signature string) (*DeliveryReportResponse, error) {
+	if err := sms.VerifyCallback(u.webhookSecret, timestamp, signature, body, time.Now()); err != nil {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	var report sms.DeliveryReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		return nil, apperror.Validation("Invalid delivery report")
+	}
+	var fields []apperror.FieldError
+	if report.MessageID == "" {
+		fields = append(fields, apperror.FieldError{Field: "message_id", Message: "message_id is required"})
+	}
+	status, ok := map[sms.DeliveryStatus]entity.ReceiptStatus{
+		sms.Delivered: entity.ReceiptDelivered,
+		sms.Failed:    entity.ReceiptFailed,
+	}[report.Status]
+	if !ok {
+		fields = append(fields, apperror.FieldError{Field: "status", Message: "status must be one of: delivered failed"})
+	}
+	if len(fields) > 0 {
+		return nil, apperror.Validation("Invalid delivery report", fields...)
+	}
+
+	// The event is built from the receipt as it will be once the report is
+	// applied, and only enqueued if it is
+	receipt, err := u.receiptRepo.GetByProviderRef(ctx, report.MessageID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrReceiptNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get receipt", err)
+	}
+	outcome := receipt.Clone()
+	outcome.Status, outcome.Error = status, report.Error
+	name := EventSMSSent
+	if status == entity.ReceiptFailed {
+		name = EventSMSFailed
+	}
+	message, err := u.eventMessage(name, outcome)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, err = u.receiptRepo.MarkDelivery(ctx, report.MessageID, status, report.Error, message)
+	switch {
+	case errors.Is(err, repository.ErrReceiptNotSent):
+		receipt, err = u.receiptRepo.GetByProviderRef(ctx, report.MessageID)
+		if err != nil {
+			return nil, internalError(ctx, "Failed to get receipt", err)
+		}
+		return &DeliveryReportResponse{Success: true, Duplicate: true, Receipt: receipt}, nil
+	case errors.Is(err, apperror.ErrNotFound):
+		return nil, ErrReceiptNotFound
+	case err != nil:
+		return nil, internalError(ctx, "Failed to update receipt", err)
+	}
+
+	return &DeliveryReportResponse{Success: true, Receipt: receipt}, nil
+}
+
+// eventMessage creates the outbox message recording an analytics event
+// about a receipt
+func (u *receiptUsecase) eventMessage(name string, receipt *entity.Receipt) (*entity.OutboxMessage, error) {
+	properties := map[string]string{
+		"receipt_id": receipt.ID,
+		"order_id":   receipt.OrderID,
+		"language":   receipt.Language,
+		"segments":   strconv.Itoa(receipt.Segments),
+	}
+	if receipt.ProviderRef != "" {
+		properties["provider_ref"] = receipt.ProviderRef
+	}
+	if receipt.Error != "" {
+		properties["error"] = receipt.Error
+	}
+	return newOutboxMessage(TopicAnalyticsEvent, analytics.NewEvent(name, properties))
+}
+
+// ReceiptOutboxHandler sends the receipts of TopicSMSReceipt messages with
+// receipts under the dispatcher's context
+func ReceiptOutboxHandler(receipts ReceiptUsecase) OutboxHandler {
+	return func(ctx context.Context, message *entity.OutboxMessage) error {
+		var payload ReceiptMessage
+		if err := message.Decode(&payload); err != nil {
+			return apperror.Unprocessable(err.Error())
+		}
+		_, err := receipts.SendReceipt(ctx, payload.OrderID, payload.Language)
+		return err
+	}
+}
@@ -0,0 +1,242 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/mike/entity"
+	"example.com/mike/sms"
+	"example.com/mike/usecase"
+)
+
+// webhookSecret signs the delivery reports in these tests
+var webhookSecret = []byte("test-sms-webhook-secret")
+
+// signedReport encodes a delivery report and signs it now
+func signedReport(t *testing.T, report sms.DeliveryReport) (body []byte, timestamp, signature string) {
+	t.Helper()
+	body, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("encode report: %v", err)
+	}
+	now := time.Now()
+	return body, strconv.FormatInt(now.Unix(), 10), sms.SignCallback(webhookSecret, now, body)
+}
+
+// receiveReport signs a delivery report now and hands it to receipts
+func receiveReport(t *testing.T, receipts usecase.ReceiptUsecase, report sms.DeliveryReport) (*usecase.DeliveryReportResponse, error) {
+	t.Helper()
+	body, timestamp, signature := signedReport(t, report)
+	return receipts.ReceiveDeliveryReport(context.Background(), body, timestamp, signature)
+}
+
+// failingSender is an SMS provider that rejects every message
+type failingSender struct{}
+
+func (failingSender) Send(sms.Message) (string, error) {
+	return "", sms.ErrRejected
+}
+
+func TestCheckoutSendsReceipt(t *testing.T) {
+	f := newCartFixture(t, 1000)
+	f.add(t, f.latte, 2)
+	if _, err := f.carts.SetCustomer(context.Background(), f.member, usecase.CartCustomerRequest{Name: "Somchai Jaidee", Phone: "0812345678"}); err != nil {
+		t.Fatalf("SetCustomer: %v", err)
+	}
+
+	resp, err := f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: f.member})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	receipt := resp.Receipt
+	ref := strings.ToUpper(resp.Order.ID[:8])
+	if receipt == nil || receipt.Status != entity.ReceiptSent || !strings.HasPrefix(receipt.ProviderRef, "log-") ||
+		receipt.Phone != "+66812345678" || receipt.Language != usecase.LanguageThai || receipt.Encoding != "UCS-2" ||
+		receipt.Body != "ขอบคุณที่ใช้บริการ คำสั่งซื้อ #"+ref+" ชำระ 240 พอยต์ คงเหลือ 760 พอยต์" {
+		t.Fatalf("unexpected receipt: %+v", receipt)
+	}
+	if receipt.Segments != sms.CountSegments(receipt.Body).Segments || receipt.Segments > 2 {
+		t.Fatalf("receipt takes %d segments", receipt.Segments)
+	}
+
+	sent := f.sms.Sent()
+	if len(sent) != 1 || sent[0].To != "+66812345678" || sent[0].Body != receipt.Body {
+		t.Fatalf("unexpected messages: %+v", sent)
+	}
+
+	got, err := f.receipts.GetReceipt(context.Background(), resp.Order.ID)
+	if err != nil {
+		t.Fatalf("GetReceipt: %v", err)
+	}
+	if got.Receipt.ID != receipt.ID || got.Receipt.ProviderRef != receipt.ProviderRef {
+		t.Fatalf("unexpected stored receipt: %+v", got.Receipt)
+	}
+	if _, err := f.receipts.GetReceipt(context.Background(), "missing"); !errors.Is(err, usecase.ErrReceiptNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrReceiptNotFound, err)
+	}
+}
+
+func TestCheckoutReceiptInEnglishToMember(t *testing.T) {
+	f := newCartFixture(t, 1000)
+	f.add(t, f.mocha, 1)
+
+	resp, err := f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: f.member, Language: "en"})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	member, err := f.users.GetUser(context.Background(), f.member)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	receipt := resp.Receipt
+	if receipt.Phone != member.User.Phone || receipt.Encoding != "GSM-7" || receipt.Segments != 1 ||
+		receipt.Body != "Thank you! Order #"+strings.ToUpper(resp.Order.ID[:8])+": 80 points paid, 920 points left." {
+		t.Fatalf("unexpected receipt: %+v", receipt)
+	}
+
+	_, err = f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: f.member, Language: "fr"})
+	if fields := fieldErrors(t, err); !fields["language"] {
+		t.Fatalf("expected a language error, got %v", fields)
+	}
+}
+
+func TestReceiptFailureIsRecorded(t *testing.T) {
+	f := newCartFixtureWithSender(t, 1000, failingSender{})
+	f.add(t, f.latte, 1)
+
+	resp, err := f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: f.member})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	receipt := resp.Receipt
+	if receipt == nil || receipt.Status != entity.ReceiptFailed || receipt.ProviderRef != "" || !strings.Contains(receipt.Error, "rejected") {
+		t.Fatalf("unexpected receipt: %+v", receipt)
+	}
+
+	got, err := f.receipts.GetReceipt(context.Background(), resp.Order.ID)
+	if err != nil {
+		t.Fatalf("GetReceipt: %v", err)
+	}
+	if got.Receipt.Status != entity.ReceiptFailed || got.Receipt.Error != receipt.Error {
+		t.Fatalf("failure not recorded: %+v", got.Receipt)
+	}
+
+	// The event is written with the receipt and recorded by the dispatcher
+	if emitted := f.events.Events(); len(emitted) != 0 {
+		t.Fatalf("event emitted before dispatch: %+v", emitted)
+	}
+	f.dispatch(t)
+	if emitted := f.events.Events(); len(emitted) != 1 || emitted[0].Name != usecase.EventSMSFailed ||
+		emitted[0].Properties["order_id"] != resp.Order.ID {
+		t.Fatalf("unexpected events: %+v", emitted)
+	}
+
+	// A receipt that is no longer pending is not sent again
+	again, err := f.receipts.SendReceipt(context.Background(), resp.Order.ID, usecase.LanguageEnglish)
+	if err != nil || again.ID != receipt.ID || again.Language != usecase.LanguageThai {
+		t.Fatalf("SendReceipt: %+v, %v", again, err)
+	}
+	if _, err := f.receipts.SendReceipt(context.Background(), "missing", usecase.LanguageThai); !errors.Is(err, usecase.ErrOrderNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrOrderNotFound, err)
+	}
+}
+
+func TestDeliveryReports(t *testing.T) {
+	f := newCartFixture(t, 1000)
+	f.add(t, f.latte, 1)
+	delivered, err := f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: f.member})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	f.add(t, f.latte, 1)
+	failed, err := f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: f.member})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	resp, err := receiveReport(t, f.receipts, sms.DeliveryReport{MessageID: delivered.Receipt.ProviderRef, Status: sms.Delivered})
+	if err != nil {
+		t.Fatalf("ReceiveDeliveryReport: %v", err)
+	}
+	if resp.Duplicate || resp.Receipt.Status != entity.ReceiptDelivered || resp.Receipt.OrderID != delivered.Order.ID {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	resp, err = receiveReport(t, f.receipts, sms.DeliveryReport{MessageID: failed.Receipt.ProviderRef, Status: sms.Failed, Error: "handset off"})
+	if err != nil {
+		t.Fatalf("ReceiveDeliveryReport: %v", err)
+	}
+	if resp.Receipt.Status != entity.ReceiptFailed || resp.Receipt.Error != "handset off" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	// A retried report, or a late one contradicting it, changes nothing
+	for _, status := range []sms.DeliveryStatus{sms.Delivered, sms.Failed} {
+		resp, err = receiveReport(t, f.receipts, sms.DeliveryReport{MessageID: delivered.Receipt.ProviderRef, Status: status})
+		if err != nil {
+			t.Fatalf("ReceiveDeliveryReport: %v", err)
+		}
+		if !resp.Duplicate || resp.Receipt.Status != entity.ReceiptDelivered {
+			t.Fatalf("repeat report %s: unexpected response %+v", status, resp)
+		}
+	}
+
+	f.dispatch(t)
+	emitted := f.events.Events()
+	if len(emitted) != 2 || emitted[0].Name != usecase.EventSMSSent || emitted[1].Name != usecase.EventSMSFailed ||
+		emitted[0].Properties["provider_ref"] != delivered.Receipt.ProviderRef || emitted[1].Properties["error"] != "handset off" {
+		t.Fatalf("unexpected events: %+v", emitted)
+	}
+	if got, _ := f.receipts.GetReceipt(context.Background(), delivered.Order.ID); got.Receipt.Status != entity.ReceiptDelivered {
+		t.Fatalf("delivery not stored: %+v", got.Receipt)
+	}
+}
+
+func TestDeliveryReportFailures(t *testing.T) {
+	f := newCartFixture(t, 1000)
+	f.add(t, f.latte, 1)
+	placed, err := f.carts.Checkout(context.Background(), usecase.CheckoutRequest{UserID: f.member})
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	body, timestamp, signature := signedReport(t, sms.DeliveryReport{MessageID: placed.Receipt.ProviderRef, Status: sms.Delivered})
+	stale := time.Now().Add(-sms.MaxCallbackAge - time.Minute)
+	tests := []struct {
+		name      string
+		body      []byte
+		timestamp string
+		signature string
+		want      error
+	}{
+		{"unsigned", body, timestamp, "", usecase.ErrInvalidWebhookSignature},
+		{"edited body", []byte(strings.Replace(string(body), "delivered", "failed", 1)), timestamp, signature, usecase.ErrInvalidWebhookSignature},
+		{"stale", body, strconv.FormatInt(stale.Unix(), 10), sms.SignCallback(webhookSecret, stale, body), usecase.ErrInvalidWebhookSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.receipts.ReceiveDeliveryReport(context.Background(), tt.body, tt.timestamp, tt.signature); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	_, err = receiveReport(t, f.receipts, sms.DeliveryReport{MessageID: "unknown", Status: sms.Delivered})
+	if !errors.Is(err, usecase.ErrReceiptNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrReceiptNotFound, err)
+	}
+	_, err = receiveReport(t, f.receipts, sms.DeliveryReport{Status: "queued"})
+	if fields := fieldErrors(t, err); !fields["message_id"] || !fields["status"] {
+		t.Fatalf("expected message_id and status errors, got %v", fields)
+	}
+
+	f.dispatch(t)
+	if got, _ := f.receipts.GetReceipt(context.Background(), placed.Order.ID); got.Receipt.Status != entity.ReceiptSent || len(f.events.Events()) != 0 {
+		t.Fatalf("rejected reports changed the receipt: %+v", got.Receipt)
+	}
+}
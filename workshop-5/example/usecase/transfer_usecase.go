@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/validation"
+	"github.com/google/uuid"
+)
+
+// TransferPolicy holds the limits applied to every transfer
+type TransferPolicy struct {
+	// MinAmount and MaxAmount bound a single transfer
+	MinAmount int
+	MaxAmount int
+
+	// DailyCap bounds the total a member may send per calendar day
+	DailyCap int
+
+	// Location defines where calendar days start
+	Location *time.Location
+}
+
+// DefaultTransferPolicy applies the limits from the PRD, with days in Thai
+// time (UTC+7, no daylight saving)
+var DefaultTransferPolicy = TransferPolicy{
+	MinAmount: 1,
+	MaxAmount: 50000,
+	DailyCap:  100000,
+	Location:  time.FixedZone("ICT", 7*60*60),
+}
+
+// TransferRequest represents a request to send points to another member
+type TransferRequest struct {
+	FromUserID string `json:"from_user_id" validate:"required" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ToUserID   string `json:"to_user_id" validate:"required" example:"6ba7b810-9dad-11d1-80b4-00c04fd430c8"`
+	Amount     int    `json:"amount" validate:"required" example:"500"`
+	Memo       string `json:"memo" validate:"max=200" example:"Lunch"`
+
+	// QRRequestID is set by QRUsecase when the transfer pays a QR request,
+	// which is then marked paid together with the posting
+	QRRequestID string `json:"-" swaggerignore:"true"`
+}
+
+// TransferResponse represents a transfer and the sender's new balance
+type TransferResponse struct {
+	Success  bool             `json:"success" example:"true"`
+	Message  string           `json:"message" example:"Transfer posted"`
+	Transfer *entity.Transfer `json:"transfer"`
+	Balance  int              `json:"balance,omitempty" example:"14920"`
+}
+
+// Reasons recorded on failed transfers
+const (
+	FailureInsufficientBalance = "insufficient_balance"
+	FailureDailyLimitExceeded  = "daily_limit_exceeded"
+	FailureQRRequestNotPending = "qr_request_not_pending"
+	FailureInternal            = "internal_error"
+)
+
+// Errors returned by TransferUsecase
+var (
+	// ErrRecipientNotFound is returned when the recipient does not exist
+	ErrRecipientNotFound = apperror.Validation("Invalid transfer",
+		apperror.FieldError{Field: "to_user_id", Message: "recipient not found"})
+
+	// ErrDailyLimitExceeded is returned when a transfer would take the
+	// sender over the daily cap
+	ErrDailyLimitExceeded = apperror.Conflict("Daily transfer limit exceeded")
+
+	// ErrTransferNotFound is returned when the requested transfer does not exist
+	ErrTransferNotFound = apperror.NotFound("Transfer not found")
+)
+
+// TransferUsecase defines the peer-to-peer transfer operations
+type TransferUsecase interface {
+	// Transfer sends points from one member to another
+	Transfer(ctx context.Context, req TransferRequest) (*TransferResponse, error)
+
+	// GetTransfer retrieves a transfer by ID
+	GetTransfer(ctx context.Context, id string) (*TransferResponse, error)
+}
+
+// transferUsecase implements the TransferUsecase interface
+type transferUsecase struct {
+	userRepo     repository.UserRepository
+	transferRepo repository.TransferRepository
+	ledgerRepo   repository.LedgerRepository
+	policy       TransferPolicy
+
+	// senders serialises transfers per sender so the daily cap check and
+	// the posting cannot interleave
+	senders keyedMutex
+}
+
+// NewTransferUsecase creates a new transfer usecase
+func NewTransferUsecase(
+	userRepo repository.UserRepository,
+	transferRepo repository.TransferRepository,
+	ledgerRepo repository.LedgerRepository,
+	policy TransferPolicy,
+) TransferUsecase {
+	return &transferUsecase{
+		userRepo:     userRepo,
+		transferRepo: transferRepo,
+		ledgerRepo:   ledgerRepo,
+		policy:       policy,
+	}
+}
+
+// Transfer validates the request, then records a pending transfer and
+// settles it: balance check, lock, post, unlock. Every transfer that gets
+// past validation is recorded; on failure it is marked failed and no
+// ledger entries are written.
+func (u *transferUsecase) Transfer(ctx context.Context, req TransferRequest) (*TransferResponse, error) {
+	if err := u.validate(ctx, &req); err != nil {
+		return nil, err
+	}
+
+	unlock := u.senders.Lock(req.FromUserID)
+	defer unlock()
+
+	transfer := entity.NewTransfer(uuid.New().String(), req.FromUserID, req.ToUserID, req.Amount, req.Memo)
+	transfer.QRRequestID = req.QRRequestID
+	if err := u.transferRepo.Create(ctx, transfer); err != nil {
+		return nil, internalError(ctx, "Failed to create transfer", err)
+	}
+
+	sent, err := u.transferRepo.PostedTotalSince(ctx, req.FromUserID, u.startOfDay(transfer.CreatedAt))
+	if err != nil {
+		return nil, u.fail(ctx, transfer.ID, FailureInternal, internalError(ctx, "Failed to check daily limit", err))
+	}
+	if sent+req.Amount > u.policy.DailyCap {
+		return nil, u.fail(ctx, transfer.ID, FailureDailyLimitExceeded, ErrDailyLimitExceeded)
+	}
+
+	posted, err := u.transferRepo.Complete(ctx, transfer.ID)
+	switch {
+	case errors.Is(err, repository.ErrInsufficientBalance):
+		return nil, u.fail(ctx, transfer.ID, FailureInsufficientBalance, ErrInsufficientBalance)
+	case errors.Is(err, repository.ErrQRRequestNotPending):
+		return nil, u.fail(ctx, transfer.ID, FailureQRRequestNotPending, err)
+	case err != nil:
+		return nil, u.fail(ctx, transfer.ID, FailureInternal, internalError(ctx, "Failed to post transfer", err))
+	}
+
+	// The transfer is posted; report it even if the request is gone by now,
+	// so a retry does not send the points twice
+	balance, err := u.ledgerRepo.Balance(context.WithoutCancel(ctx), req.FromUserID)
+	if err != nil {
+		return nil, apperror.Internal("Failed to get balance", err)
+	}
+
+	return &TransferResponse{
+		Success:  true,
+		Message:  "Transfer posted",
+		Transfer: posted,
+		Balance:  balance,
+	}, nil
+}
+
+// GetTransfer retrieves a transfer by ID
+func (u *transferUsecase) GetTransfer(ctx context.Context, id string) (*TransferResponse, error) {
+	transfer, err := u.transferRepo.GetByID(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get transfer", err)
+	}
+
+	return &TransferResponse{
+		Success:  true,
+		Message:  "Transfer found",
+		Transfer: transfer,
+	}, nil
+}
+
+// validate checks the request and both parties, reporting every invalid
+// field at once
+func (u *transferUsecase) validate(ctx context.Context, req *TransferRequest) error {
+	trimSpace(&req.FromUserID, &req.ToUserID, &req.Memo)
+
+	fields := validation.Struct(*req)
+	if req.Amount != 0 && (req.Amount < u.policy.MinAmount || req.Amount > u.policy.MaxAmount) {
+		fields = append(fields, apperror.FieldError{
+			Field:   "amount",
+			Message: fmt.Sprintf("amount must be between %d and %d", u.policy.MinAmount, u.policy.MaxAmount),
+		})
+	}
+	if req.FromUserID != "" && req.FromUserID == req.ToUserID {
+		fields = append(fields, apperror.FieldError{Field: "to_user_id", Message: "cannot transfer to yourself"})
+	}
+	if len(fields) > 0 {
+		return apperror.Validation("Invalid transfer", fields...)
+	}
+
+	if _, err := u.userRepo.GetByID(ctx, req.FromUserID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return internalError(ctx, "Failed to get sender", err)
+	}
+	if _, err := u.userRepo.GetByID(ctx, req.ToUserID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return ErrRecipientNotFound
+		}
+		return internalError(ctx, "Failed to get recipient", err)
+	}
+	return nil
+}
+
+// fail marks a transfer failed and returns cause. A failure to record the
+// status is reported instead, since the transfer would stay pending. The
+// status is recorded even if the request has been cancelled meanwhile.
+func (u *transferUsecase) fail(ctx context.Context, id, reason string, cause error) error {
+	if _, err := u.transferRepo.Fail(context.WithoutCancel(ctx), id, reason); err != nil {
+		return apperror.Internal("Failed to record failed transfer", err)
+	}
+	return cause
+}
+
+// startOfDay returns midnight of t's calendar day in the policy location
+func (u *transferUsecase) startOfDay(t time.Time) time.Time {
+	local := t.In(u.policy.Location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, u.policy.Location)
+}
@@ -0,0 +1,287 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+)
+
+// transferFixture wires the transfer usecase to in-memory storage with two
+// registered members
+type transferFixture struct {
+	transfers  usecase.TransferUsecase
+	ledger     usecase.LedgerUsecase
+	alice, bob string
+}
+
+func newTransferFixture(t *testing.T, policy usecase.TransferPolicy, aliceBalance int) *transferFixture {
+	t.Helper()
+	userRepo, ledgerRepo := repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository()
+	users := usecase.NewUserUsecase(userRepo, ledgerRepo)
+	f := &transferFixture{
+		transfers: usecase.NewTransferUsecase(userRepo, repository.NewMemoryTransferRepository(ledgerRepo, repository.NewMemoryQRRequestRepository()), ledgerRepo, policy),
+		ledger:    usecase.NewLedgerUsecase(userRepo, ledgerRepo),
+		alice:     registerUser(t, users, 1).User.ID,
+		bob:       registerUser(t, users, 2).User.ID,
+	}
+	if aliceBalance > 0 {
+		if _, err := f.ledger.PostEntry(context.Background(), f.alice, usecase.PostEntryRequest{Type: "earn", Amount: aliceBalance}); err != nil {
+			t.Fatalf("PostEntry: %v", err)
+		}
+	}
+	return f
+}
+
+func (f *transferFixture) assertBalance(t *testing.T, userID string, want int) {
+	t.Helper()
+	got, err := f.ledger.GetBalance(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if got.Balance != want {
+		t.Fatalf("balance of %s = %d, want %d", userID, got.Balance, want)
+	}
+}
+
+func TestTransferPostsBothLegs(t *testing.T) {
+	f := newTransferFixture(t, usecase.DefaultTransferPolicy, 1000)
+
+	resp, err := f.transfers.Transfer(context.Background(), usecase.TransferRequest{FromUserID: f.alice, ToUserID: f.bob, Amount: 300, Memo: " Lunch "})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	if resp.Transfer.Status != entity.TransferPosted || resp.Transfer.Memo != "Lunch" || resp.Balance != 700 {
+		t.Fatalf("unexpected response: %+v %+v", resp, resp.Transfer)
+	}
+	f.assertBalance(t, f.alice, 700)
+	f.assertBalance(t, f.bob, 300)
+
+	got, err := f.transfers.GetTransfer(context.Background(), resp.Transfer.ID)
+	if err != nil || got.Transfer.Status != entity.TransferPosted {
+		t.Fatalf("GetTransfer = %+v, %v", got, err)
+	}
+	if _, err := f.transfers.GetTransfer(context.Background(), "missing"); !errors.Is(err, usecase.ErrTransferNotFound) {
+		t.Fatalf("GetTransfer missing: expected %v, got %v", usecase.ErrTransferNotFound, err)
+	}
+}
+
+func TestTransferEntriesCannotBeReversed(t *testing.T) {
+	f := newTransferFixture(t, usecase.DefaultTransferPolicy, 1000)
+
+	resp, err := f.transfers.Transfer(context.Background(), usecase.TransferRequest{FromUserID: f.alice, ToUserID: f.bob, Amount: 300})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	received, err := f.ledger.ListEntries(context.Background(), f.bob, usecase.ListEntriesRequest{Type: "transfer_in"})
+	if err != nil || len(received.Entries) != 1 {
+		t.Fatalf("ListEntries = %+v, %v", received, err)
+	}
+
+	// Reversing either leg would leave the transfer reported as posted
+	_, err = f.ledger.ReverseEntry(context.Background(), f.bob, received.Entries[0].ID, usecase.ReverseEntryRequest{Reason: "Sent by mistake"})
+	if !errors.Is(err, usecase.ErrLedgerEntryNotReversible) {
+		t.Fatalf("expected %v, got %v", usecase.ErrLedgerEntryNotReversible, err)
+	}
+	f.assertBalance(t, f.alice, 700)
+	f.assertBalance(t, f.bob, 300)
+
+	got, err := f.transfers.GetTransfer(context.Background(), resp.Transfer.ID)
+	if err != nil || got.Transfer.Status != entity.TransferPosted {
+		t.Fatalf("GetTransfer = %+v, %v", got, err)
+	}
+}
+
+// recordingTransferRepository remembers the IDs of created transfers and
+// runs afterCreate, when set, once a transfer is stored
+type recordingTransferRepository struct {
+	repository.TransferRepository
+	created     []string
+	afterCreate func()
+}
+
+func (r *recordingTransferRepository) Create(ctx context.Context, transfer *entity.Transfer) error {
+	if err := r.TransferRepository.Create(ctx, transfer); err != nil {
+		return err
+	}
+	r.created = append(r.created, transfer.ID)
+	if r.afterCreate != nil {
+		r.afterCreate()
+	}
+	return nil
+}
+
+func TestTransferFailureIsRecordedAndRolledBack(t *testing.T) {
+	policy := usecase.DefaultTransferPolicy
+	policy.DailyCap = 500
+
+	tests := []struct {
+		name    string
+		seed    int
+		amounts []int
+		want    error
+		reason  string
+	}{
+		{"insufficient balance", 100, []int{101}, usecase.ErrInsufficientBalance, usecase.FailureInsufficientBalance},
+		{"daily cap", 1000, []int{400, 101}, usecase.ErrDailyLimitExceeded, usecase.FailureDailyLimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo, ledgerRepo := repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository()
+			transferRepo := &recordingTransferRepository{TransferRepository: repository.NewMemoryTransferRepository(ledgerRepo, repository.NewMemoryQRRequestRepository())}
+			users := usecase.NewUserUsecase(userRepo, ledgerRepo)
+			ledger := usecase.NewLedgerUsecase(userRepo, ledgerRepo)
+			transfers := usecase.NewTransferUsecase(userRepo, transferRepo, ledgerRepo, policy)
+			alice, bob := registerUser(t, users, 1).User.ID, registerUser(t, users, 2).User.ID
+			if _, err := ledger.PostEntry(context.Background(), alice, usecase.PostEntryRequest{Type: "earn", Amount: tt.seed}); err != nil {
+				t.Fatalf("PostEntry: %v", err)
+			}
+
+			var err error
+			spent := 0
+			for _, amount := range tt.amounts {
+				if _, err = transfers.Transfer(context.Background(), usecase.TransferRequest{FromUserID: alice, ToUserID: bob, Amount: amount}); err == nil {
+					spent += amount
+				}
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+
+			failed, getErr := transfers.GetTransfer(context.Background(), transferRepo.created[len(transferRepo.created)-1])
+			if getErr != nil {
+				t.Fatalf("GetTransfer: %v", getErr)
+			}
+			if failed.Transfer.Status != entity.TransferFailed || failed.Transfer.FailureReason != tt.reason {
+				t.Fatalf("expected a failed transfer with reason %q, got %+v", tt.reason, failed.Transfer)
+			}
+
+			for id, want := range map[string]int{alice: tt.seed - spent, bob: spent} {
+				got, err := ledger.GetBalance(context.Background(), id)
+				if err != nil || got.Balance != want {
+					t.Fatalf("balance = %+v, %v; want %d", got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTransferValidation(t *testing.T) {
+	f := newTransferFixture(t, usecase.DefaultTransferPolicy, 100000)
+
+	tests := []struct {
+		name  string
+		req   usecase.TransferRequest
+		field string
+	}{
+		{"missing recipient", usecase.TransferRequest{FromUserID: f.alice, Amount: 1}, "to_user_id"},
+		{"to self", usecase.TransferRequest{FromUserID: f.alice, ToUserID: f.alice, Amount: 1}, "to_user_id"},
+		{"zero amount", usecase.TransferRequest{FromUserID: f.alice, ToUserID: f.bob}, "amount"},
+		{"negative amount", usecase.TransferRequest{FromUserID: f.alice, ToUserID: f.bob, Amount: -5}, "amount"},
+		{"above maximum", usecase.TransferRequest{FromUserID: f.alice, ToUserID: f.bob, Amount: 50001}, "amount"},
+		{"unknown recipient", usecase.TransferRequest{FromUserID: f.alice, ToUserID: "missing", Amount: 1}, "to_user_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.transfers.Transfer(context.Background(), tt.req)
+			if fields := fieldErrors(t, err); !fields[tt.field] {
+				t.Fatalf("expected a %s field error, got %v", tt.field, err)
+			}
+		})
+	}
+
+	_, err := f.transfers.Transfer(context.Background(), usecase.TransferRequest{FromUserID: "missing", ToUserID: f.bob, Amount: 1})
+	if !errors.Is(err, usecase.ErrUserNotFound) {
+		t.Fatalf("unknown sender: expected %v, got %v", usecase.ErrUserNotFound, err)
+	}
+	f.assertBalance(t, f.alice, 100000)
+	f.assertBalance(t, f.bob, 0)
+}
+
+func TestCancelledTransfersAreUnavailable(t *testing.T) {
+	f := newTransferFixture(t, usecase.DefaultTransferPolicy, 1000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := []struct {
+		name string
+		err  error
+	}{
+		{"Transfer", errOf(f.transfers.Transfer(ctx, usecase.TransferRequest{FromUserID: f.alice, ToUserID: f.bob, Amount: 300}))},
+		{"GetBalance", errOf(f.ledger.GetBalance(ctx, f.alice))},
+		{"PostEntry", errOf(f.ledger.PostEntry(ctx, f.alice, usecase.PostEntryRequest{Type: "earn", Amount: 1}))},
+	}
+	for _, call := range calls {
+		if !errors.Is(call.err, apperror.ErrUnavailable) {
+			t.Errorf("%s: expected an unavailable error, got %v", call.name, call.err)
+		}
+	}
+
+	// Nothing was posted
+	f.assertBalance(t, f.alice, 1000)
+	f.assertBalance(t, f.bob, 0)
+}
+
+func TestTransferCancelledAfterCreateIsRecordedAsFailed(t *testing.T) {
+	userRepo, ledgerRepo := repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository()
+	transferRepo := &recordingTransferRepository{TransferRepository: repository.NewMemoryTransferRepository(ledgerRepo, repository.NewMemoryQRRequestRepository())}
+	users := usecase.NewUserUsecase(userRepo, ledgerRepo)
+	ledger := usecase.NewLedgerUsecase(userRepo, ledgerRepo)
+	transfers := usecase.NewTransferUsecase(userRepo, transferRepo, ledgerRepo, usecase.DefaultTransferPolicy)
+	alice, bob := registerUser(t, users, 1).User.ID, registerUser(t, users, 2).User.ID
+	if _, err := ledger.PostEntry(context.Background(), alice, usecase.PostEntryRequest{Type: "earn", Amount: 1000}); err != nil {
+		t.Fatalf("PostEntry: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	transferRepo.afterCreate = cancel
+
+	_, err := transfers.Transfer(ctx, usecase.TransferRequest{FromUserID: alice, ToUserID: bob, Amount: 300})
+	if !errors.Is(err, apperror.ErrUnavailable) {
+		t.Fatalf("expected an unavailable error, got %v", err)
+	}
+
+	// The pending transfer is closed out and nothing was posted
+	stored, err := transferRepo.GetByID(context.Background(), transferRepo.created[0])
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if stored.Status != entity.TransferFailed || stored.FailureReason != usecase.FailureInternal {
+		t.Fatalf("expected a failed transfer with reason %q, got %+v", usecase.FailureInternal, stored)
+	}
+	for id, want := range map[string]int{alice: 1000, bob: 0} {
+		got, err := ledger.GetBalance(context.Background(), id)
+		if err != nil || got.Balance != want {
+			t.Fatalf("balance = %+v, %v; want %d", got, err, want)
+		}
+	}
+}
+
+func TestConcurrentTransfersRespectDailyCap(t *testing.T) {
+	policy := usecase.DefaultTransferPolicy
+	policy.DailyCap = 1000
+	f := newTransferFixture(t, policy, 5000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f.transfers.Transfer(context.Background(), usecase.TransferRequest{FromUserID: f.alice, ToUserID: f.bob, Amount: 100})
+			if err != nil && !errors.Is(err, usecase.ErrDailyLimitExceeded) {
+				t.Errorf("Transfer: unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	f.assertBalance(t, f.alice, 4000)
+	f.assertBalance(t, f.bob, 1000)
+}
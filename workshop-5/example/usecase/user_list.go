@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/validation"
+)
+
+// Page size limits for ListUsers
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// ListUsersRequest represents the filters, sort order and page position of
+// a user listing
+type ListUsersRequest struct {
+	// MembershipLevel filters by tier (Gold, Silver or Bronze)
+	MembershipLevel string `query:"membership_level" example:"Gold"`
+
+	// RegisteredFrom and RegisteredTo bound the registration time. They
+	// accept RFC 3339 timestamps or YYYY-MM-DD dates; from is inclusive,
+	// to is exclusive for timestamps and covers the whole day for dates.
+	RegisteredFrom string `query:"registered_from" example:"2024-01-01"`
+	RegisteredTo   string `query:"registered_to" example:"2024-12-31"`
+
+	// Name, Email and Phone are prefix searches. Phone accepts the formats
+	// registration does, so "081" finds numbers stored as +6681...
+	Name  string `query:"name" example:"Som"`
+	Email string `query:"email" example:"somchai@"`
+	Phone string `query:"phone" example:"+6681"`
+
+	// Sort is a sort field, prefixed with "-" for descending order
+	Sort string `query:"sort" example:"-registered_at"`
+
+	// Limit is the page size, 1 to MaxListLimit
+	Limit int `query:"limit" example:"20"`
+
+	// Cursor is the next_cursor of the previous page
+	Cursor string `query:"cursor"`
+}
+
+// ListUsersResponse represents one page of users
+type ListUsersResponse struct {
+	Success    bool           `json:"success" example:"true"`
+	Users      []*entity.User `json:"users"`
+	Count      int            `json:"count" example:"20"`
+	NextCursor string         `json:"next_cursor,omitempty" example:"eyJzIjoicmVnaXN0ZXJlZF9hdCJ9"`
+}
+
+// listCursor is the opaque cursor handed to clients. It records the sort
+// order so a cursor cannot be replayed against a different ordering.
+type listCursor struct {
+	Sort string `json:"s"`
+	repository.UserCursor
+}
+
+// ListUsers retrieves a filtered, sorted page of users
+func (u *userUsecase) ListUsers(ctx context.Context, req ListUsersRequest) (*ListUsersResponse, error) {
+	query, err := buildUserQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := u.userRepo.Query(ctx, query)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to list users", err)
+	}
+	if err := u.projectPoints(ctx, page.Users); err != nil {
+		return nil, internalError(ctx, "Failed to get balances", err)
+	}
+
+	response := &ListUsersResponse{
+		Success: true,
+		Users:   page.Users,
+		Count:   len(page.Users),
+	}
+	if page.Next != nil {
+		response.NextCursor = encodeCursor(listCursor{Sort: normalizeSort(req.Sort), UserCursor: *page.Next})
+	}
+	return response, nil
+}
+
+// buildUserQuery validates req and converts it to a repository query,
+// reporting every invalid parameter at once
+func buildUserQuery(req ListUsersRequest) (repository.UserQuery, error) {
+	var fields []apperror.FieldError
+	invalid := func(field, message string) {
+		fields = append(fields, apperror.FieldError{Field: field, Message: message})
+	}
+
+	query := repository.UserQuery{
+		MembershipLevel: req.MembershipLevel,
+		NamePrefix:      req.Name,
+		EmailPrefix:     req.Email,
+		PhonePrefix:     validation.NormalizePhonePrefix(req.Phone),
+		Limit:           req.Limit,
+	}
+
+	if req.MembershipLevel != "" && !entity.ValidMembershipLevel(req.MembershipLevel) {
+		invalid("membership_level", "membership_level must be one of Gold, Silver, Bronze")
+	}
+
+	if req.RegisteredFrom != "" {
+		from, _, err := parseTimeOrDate(req.RegisteredFrom)
+		if err != nil {
+			invalid("registered_from", "registered_from must be an RFC 3339 timestamp or YYYY-MM-DD date")
+		}
+		query.RegisteredFrom = from
+	}
+	if req.RegisteredTo != "" {
+		to, isDate, err := parseTimeOrDate(req.RegisteredTo)
+		if err != nil {
+			invalid("registered_to", "registered_to must be an RFC 3339 timestamp or YYYY-MM-DD date")
+		}
+		if isDate {
+			to = to.AddDate(0, 0, 1)
+		}
+		query.RegisteredTo = to
+	}
+	if !query.RegisteredFrom.IsZero() && !query.RegisteredTo.IsZero() && !query.RegisteredFrom.Before(query.RegisteredTo) {
+		invalid("registered_to", "registered_to must be after registered_from")
+	}
+
+	query.SortBy = repository.UserSortField(strings.TrimPrefix(req.Sort, "-"))
+	query.Descending = strings.HasPrefix(req.Sort, "-")
+	if req.Sort != "" && !query.SortBy.Valid() {
+		invalid("sort", "sort must be one of registered_at, member_id, first_name, last_name, email, optionally prefixed with -")
+	}
+
+	switch {
+	case req.Limit == 0:
+		query.Limit = DefaultListLimit
+	case req.Limit < 0 || req.Limit > MaxListLimit:
+		invalid("limit", "limit must be between 1 and 100")
+	}
+
+	if req.Cursor != "" {
+		var cursor listCursor
+		err := decodeCursor(req.Cursor, &cursor)
+		switch {
+		case err != nil || cursor.ID == "":
+			invalid("cursor", "cursor is malformed")
+		case cursor.Sort != normalizeSort(req.Sort):
+			invalid("cursor", "cursor was issued for a different sort order")
+		default:
+			query.After = &cursor.UserCursor
+		}
+	}
+
+	if len(fields) > 0 {
+		return query, apperror.Validation("Invalid list parameters", fields...)
+	}
+	return query, nil
+}
+
+// normalizeSort spells out the default sort so "" and "registered_at"
+// cursors are interchangeable
+func normalizeSort(sort string) string {
+	if sort == "" {
+		return string(repository.SortByRegisteredAt)
+	}
+	return sort
+}
+
+// parseTimeOrDate parses an RFC 3339 timestamp or a YYYY-MM-DD date (UTC)
+func parseTimeOrDate(value string) (t time.Time, isDate bool, err error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339Nano, value)
+	return t, false, err
+}
+
+// encodeCursor renders a cursor as an opaque URL-safe token
+func encodeCursor(cursor any) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses a token produced by encodeCursor into cursor
+func decodeCursor(token string, cursor any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, cursor)
+}
@@ -0,0 +1,110 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+)
+
+func TestListUsersPaginates(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	for i := 1; i <= 7; i++ {
+		registerUser(t, uc, i)
+	}
+
+	seen := map[string]bool{}
+	req := usecase.ListUsersRequest{Sort: "-member_id", Limit: 3}
+	previous := ""
+	for pages := 1; ; pages++ {
+		resp, err := uc.ListUsers(context.Background(), req)
+		if err != nil {
+			t.Fatalf("ListUsers: %v", err)
+		}
+		if resp.Count != len(resp.Users) {
+			t.Fatalf("count %d does not match %d users", resp.Count, len(resp.Users))
+		}
+		for _, user := range resp.Users {
+			if seen[user.ID] {
+				t.Fatalf("user %s listed twice", user.ID)
+			}
+			seen[user.ID] = true
+			if previous != "" && user.MemberID >= previous {
+				t.Fatalf("member IDs not descending: %s after %s", user.MemberID, previous)
+			}
+			previous = user.MemberID
+		}
+		if resp.NextCursor == "" {
+			if pages != 3 {
+				t.Fatalf("expected 3 pages, got %d", pages)
+			}
+			break
+		}
+		req.Cursor = resp.NextCursor
+	}
+	if len(seen) != 7 {
+		t.Fatalf("expected 7 users, got %d", len(seen))
+	}
+}
+
+func TestListUsersValidation(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	for i := 1; i <= 3; i++ {
+		registerUser(t, uc, i)
+	}
+
+	first, err := uc.ListUsers(context.Background(), usecase.ListUsersRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		req   usecase.ListUsersRequest
+		field string
+	}{
+		{"unknown level", usecase.ListUsersRequest{MembershipLevel: "Platinum"}, "membership_level"},
+		{"bad date", usecase.ListUsersRequest{RegisteredFrom: "yesterday"}, "registered_from"},
+		{"empty range", usecase.ListUsersRequest{RegisteredFrom: "2024-02-01", RegisteredTo: "2024-01-01"}, "registered_to"},
+		{"unknown sort", usecase.ListUsersRequest{Sort: "password"}, "sort"},
+		{"limit too large", usecase.ListUsersRequest{Limit: 1000}, "limit"},
+		{"garbage cursor", usecase.ListUsersRequest{Cursor: "!!!"}, "cursor"},
+		{"cursor for other sort", usecase.ListUsersRequest{Sort: "email", Cursor: first.NextCursor}, "cursor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := uc.ListUsers(context.Background(), tt.req)
+			if fields := fieldErrors(t, err); !fields[tt.field] {
+				t.Fatalf("expected a %s field error, got %v", tt.field, fields)
+			}
+		})
+	}
+}
+
+func TestListUsersDateRangeCoversWholeDay(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	user := registerUser(t, uc, 1).User
+	day := user.RegisteredAt.UTC().Format("2006-01-02")
+
+	resp, err := uc.ListUsers(context.Background(), usecase.ListUsersRequest{RegisteredFrom: day, RegisteredTo: day})
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if resp.Count != 1 {
+		t.Fatalf("expected the user registered on %s, got %d users", day, resp.Count)
+	}
+}
+
+func TestListUsersPhonePrefixAcceptsThaiFormat(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	registerUser(t, uc, 1)
+
+	resp, err := uc.ListUsers(context.Background(), usecase.ListUsersRequest{Phone: "081-0"})
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if resp.Count != 1 {
+		t.Fatalf("expected the +66810... user, got %d users", resp.Count)
+	}
+}
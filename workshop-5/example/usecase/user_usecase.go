@@ -1,19 +1,50 @@
 package usecase
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
 
+	"example.com/mike/apperror"
 	"example.com/mike/entity"
 	"example.com/mike/repository"
+	"example.com/mike/validation"
 	"github.com/google/uuid"
 )
 
-// RegisterRequest represents the registration request
+// RegisterRequest represents the registration request. Names may use Thai
+// or Latin script; the phone number may be in E.164 or Thai national format
+// and is stored in E.164.
 type RegisterRequest struct {
-	FirstName string `json:"first_name" validate:"required" example:"John"`
-	LastName  string `json:"last_name" validate:"required" example:"Doe"`
-	Phone     string `json:"phone" validate:"required" example:"+66812345678"`
-	Email     string `json:"email" validate:"required,email" example:"john.doe@example.com"`
+	FirstName string `json:"first_name" validate:"required,max=50,person_name" example:"John"`
+	LastName  string `json:"last_name" validate:"required,max=50,person_name" example:"Doe"`
+	Phone     string `json:"phone" validate:"required,phone" example:"081-234-5678"`
+	Email     string `json:"email" validate:"required,max=254,email" example:"john.doe@example.com"`
+}
+
+// UpdateUserRequest represents a full update of a user's editable fields.
+// The read-only fields may be sent back unchanged, e.g. when a client PUTs
+// the representation it received from GET, but any change is rejected.
+type UpdateUserRequest struct {
+	FirstName string `json:"first_name" validate:"required,max=50,person_name" example:"John"`
+	LastName  string `json:"last_name" validate:"required,max=50,person_name" example:"Doe"`
+	Phone     string `json:"phone" validate:"required,phone" example:"081-234-5678"`
+	Email     string `json:"email" validate:"required,max=254,email" example:"john.doe@example.com"`
+
+	// Read-only fields. Points is a projection of the points ledger.
+	ID              *string    `json:"id,omitempty" swaggerignore:"true"`
+	MemberID        *string    `json:"member_id,omitempty" swaggerignore:"true"`
+	MembershipLevel *string    `json:"membership_level,omitempty" swaggerignore:"true"`
+	Role            *string    `json:"role,omitempty" swaggerignore:"true"`
+	Points          *int       `json:"points,omitempty" swaggerignore:"true"`
+	RegisteredAt    *time.Time `json:"registered_at,omitempty" swaggerignore:"true"`
+}
+
+// SetRoleRequest represents an admin changing a user's role
+type SetRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=member staff admin" example:"staff"`
 }
 
 // RegisterResponse represents the registration response
@@ -23,72 +54,99 @@ type RegisterResponse struct {
 	User    *entity.User `json:"user,omitempty"`
 }
 
-// UserUsecase defines the interface for user business operations
+// Errors returned by UserUsecase. Unexpected failures are returned as
+// apperror internal errors, and failures caused by a cancelled or timed out
+// context as apperror unavailable errors.
+var (
+	// ErrEmailAlreadyRegistered is returned when registering a taken email
+	ErrEmailAlreadyRegistered = apperror.Conflict("Email already registered")
+
+	// ErrPhoneAlreadyRegistered is returned when registering a taken phone number
+	ErrPhoneAlreadyRegistered = apperror.Conflict("Phone already registered")
+
+	// ErrUserNotFound is returned when the requested user does not exist
+	ErrUserNotFound = apperror.NotFound("User not found")
+)
+
+// DecodeUpdateUserRequest strictly decodes a JSON update body. Unknown
+// fields are rejected so typos never silently drop an update.
+func DecodeUpdateUserRequest(data []byte) (UpdateUserRequest, error) {
+	var req UpdateUserRequest
+	err := decodeStrict(data, &req)
+	return req, err
+}
+
+// UserUsecase defines the interface for user business operations. Every
+// method takes the request's context and passes it on to the repository,
+// so a cancelled or timed out request stops at the next storage call.
 type UserUsecase interface {
 	// Register registers a new user
-	Register(req RegisterRequest) (*RegisterResponse, error)
+	Register(ctx context.Context, req RegisterRequest) (*RegisterResponse, error)
 
 	// GetUser retrieves a user by ID
-	GetUser(id string) (*RegisterResponse, error)
+	GetUser(ctx context.Context, id string) (*RegisterResponse, error)
+
+	// ListUsers retrieves a filtered, sorted page of users
+	ListUsers(ctx context.Context, req ListUsersRequest) (*ListUsersResponse, error)
+
+	// UpdateUser replaces the editable fields of a user
+	UpdateUser(ctx context.Context, id string, req UpdateUserRequest) (*RegisterResponse, error)
 
-	// GetAllUsers retrieves all users
-	GetAllUsers() ([]*entity.User, error)
+	// PatchUser applies an RFC 7396 JSON Merge Patch to a user
+	PatchUser(ctx context.Context, id string, patch []byte) (*RegisterResponse, error)
+
+	// DeleteUser deletes a user by ID
+	DeleteUser(ctx context.Context, id string) error
+
+	// SetRole changes the role of a user
+	SetRole(ctx context.Context, id string, req SetRoleRequest) (*RegisterResponse, error)
 }
 
-// userUsecase implements the UserUsecase interface
+// userUsecase implements the UserUsecase interface. User.Points is filled
+// in from the points ledger on every read.
 type userUsecase struct {
-	userRepo repository.UserRepository
+	userRepo   repository.UserRepository
+	ledgerRepo repository.LedgerRepository
 }
 
 // NewUserUsecase creates a new user usecase
-func NewUserUsecase(userRepo repository.UserRepository) UserUsecase {
+func NewUserUsecase(userRepo repository.UserRepository, ledgerRepo repository.LedgerRepository) UserUsecase {
 	return &userUsecase{
-		userRepo: userRepo,
+		userRepo:   userRepo,
+		ledgerRepo: ledgerRepo,
 	}
 }
 
 // Register registers a new user
-func (u *userUsecase) Register(req RegisterRequest) (*RegisterResponse, error) {
-	// Validate required fields
-	if err := u.validateRegisterRequest(req); err != nil {
-		return &RegisterResponse{
-			Success: false,
-			Message: err.Error(),
-		}, nil
-	}
-
-	// Check if email already exists
-	existingUser, err := u.userRepo.GetByEmail(req.Email)
-	if err == nil && existingUser != nil {
-		return &RegisterResponse{
-			Success: false,
-			Message: "Email already registered",
-		}, nil
+func (u *userUsecase) Register(ctx context.Context, req RegisterRequest) (*RegisterResponse, error) {
+	// Validate and normalize the input
+	req, err := normalizeRegisterRequest(req)
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate unique ID and member ID
+	// Generate unique ID and reserve the next member ID. The repository owns
+	// the member ID sequence, so concurrent registrations never share one.
 	id := uuid.New().String()
 
-	// Get current user count to generate member ID
-	users, err := u.userRepo.GetAll()
+	memberID, err := u.userRepo.NextMemberID(ctx)
 	if err != nil {
-		return &RegisterResponse{
-			Success: false,
-			Message: "Failed to generate member ID",
-		}, err
+		return nil, internalError(ctx, "Failed to generate member ID", err)
 	}
 
-	memberID := fmt.Sprintf("LBK%06d", len(users)+1)
-
 	// Create new user
 	user := entity.NewUser(id, memberID, req.FirstName, req.LastName, req.Phone, req.Email)
 
-	// Save user
-	if err := u.userRepo.Create(user); err != nil {
-		return &RegisterResponse{
-			Success: false,
-			Message: "Failed to create user",
-		}, err
+	// Save user. Email and phone uniqueness is enforced atomically by the
+	// repository, so there is no check-then-create window.
+	if err := u.userRepo.Create(ctx, user); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEmailTaken):
+			return nil, ErrEmailAlreadyRegistered
+		case errors.Is(err, repository.ErrPhoneTaken):
+			return nil, ErrPhoneAlreadyRegistered
+		}
+		return nil, internalError(ctx, "Failed to create user", err)
 	}
 
 	return &RegisterResponse{
@@ -99,13 +157,10 @@ func (u *userUsecase) Register(req RegisterRequest) (*RegisterResponse, error) {
 }
 
 // GetUser retrieves a user by ID
-func (u *userUsecase) GetUser(id string) (*RegisterResponse, error) {
-	user, err := u.userRepo.GetByID(id)
+func (u *userUsecase) GetUser(ctx context.Context, id string) (*RegisterResponse, error) {
+	user, err := u.getUser(ctx, id)
 	if err != nil {
-		return &RegisterResponse{
-			Success: false,
-			Message: "User not found",
-		}, err
+		return nil, err
 	}
 
 	return &RegisterResponse{
@@ -115,24 +170,228 @@ func (u *userUsecase) GetUser(id string) (*RegisterResponse, error) {
 	}, nil
 }
 
-// GetAllUsers retrieves all users
-func (u *userUsecase) GetAllUsers() ([]*entity.User, error) {
-	return u.userRepo.GetAll()
+// UpdateUser replaces the editable fields of a user
+func (u *userUsecase) UpdateUser(ctx context.Context, id string, req UpdateUserRequest) (*RegisterResponse, error) {
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.applyUpdate(ctx, user, req)
+}
+
+// PatchUser applies an RFC 7396 JSON Merge Patch to a user. The patch is
+// applied to the user's current editable representation, then validated
+// exactly like a full update.
+func (u *userUsecase) PatchUser(ctx context.Context, id string, patch []byte) (*RegisterResponse, error) {
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := json.Marshal(user)
+	if err != nil {
+		return nil, apperror.Internal("Failed to encode user", err)
+	}
+
+	merged, err := applyMergePatch(current, patch)
+	if err != nil {
+		return nil, apperror.Validation("Invalid merge patch document")
+	}
+
+	req, err := DecodeUpdateUserRequest(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	// A merge patch removes members set to null; read-only fields must
+	// survive the patch untouched.
+	var removed []apperror.FieldError
+	if req.ID == nil {
+		removed = append(removed, readOnlyFieldError("id"))
+	}
+	if req.MemberID == nil {
+		removed = append(removed, readOnlyFieldError("member_id"))
+	}
+	if req.MembershipLevel == nil {
+		removed = append(removed, readOnlyFieldError("membership_level"))
+	}
+	if req.Role == nil {
+		removed = append(removed, readOnlyFieldError("role"))
+	}
+	if req.Points == nil {
+		removed = append(removed, readOnlyFieldError("points"))
+	}
+	if req.RegisteredAt == nil {
+		removed = append(removed, readOnlyFieldError("registered_at"))
+	}
+	if len(removed) > 0 {
+		return nil, apperror.Validation("Invalid user data", removed...)
+	}
+
+	return u.applyUpdate(ctx, user, req)
+}
+
+// DeleteUser deletes a user by ID
+func (u *userUsecase) DeleteUser(ctx context.Context, id string) error {
+	err := u.userRepo.Delete(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return internalError(ctx, "Failed to delete user", err)
+	}
+	return nil
+}
+
+// SetRole changes the role of a user. Roles are not editable through
+// UpdateUser or PatchUser, so only callers allowed to manage roles reach
+// this.
+func (u *userUsecase) SetRole(ctx context.Context, id string, req SetRoleRequest) (*RegisterResponse, error) {
+	req.Role = strings.TrimSpace(req.Role)
+	if fields := validation.Struct(req); len(fields) > 0 {
+		return nil, apperror.Validation("Invalid role", fields...)
+	}
+
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Role = entity.Role(req.Role)
+	err = u.userRepo.Update(ctx, user)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Failed to update user", err)
+	}
+
+	return &RegisterResponse{
+		Success: true,
+		Message: "Role updated successfully",
+		User:    user,
+	}, nil
 }
 
-// validateRegisterRequest validates the registration request
-func (u *userUsecase) validateRegisterRequest(req RegisterRequest) error {
-	if req.FirstName == "" {
-		return fmt.Errorf("first name is required")
+// getUser loads a user with their points balance, translating repository
+// errors
+func (u *userUsecase) getUser(ctx context.Context, id string) (*entity.User, error) {
+	user, err := u.userRepo.GetByID(ctx, id)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, ErrUserNotFound
 	}
-	if req.LastName == "" {
-		return fmt.Errorf("last name is required")
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get user", err)
 	}
-	if req.Phone == "" {
-		return fmt.Errorf("phone number is required")
+
+	user.Points, err = u.ledgerRepo.Balance(ctx, id)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to get balance", err)
 	}
-	if req.Email == "" {
-		return fmt.Errorf("email is required")
+	return user, nil
+}
+
+// projectPoints fills in the points balance of each user
+func (u *userUsecase) projectPoints(ctx context.Context, users []*entity.User) error {
+	ids := make([]string, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+
+	balances, err := u.ledgerRepo.Balances(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		user.Points = balances[user.ID]
 	}
 	return nil
 }
+
+// applyUpdate validates req against user and stores the result. Read-only
+// fields may be echoed back but not changed.
+func (u *userUsecase) applyUpdate(ctx context.Context, user *entity.User, req UpdateUserRequest) (*RegisterResponse, error) {
+	trimSpace(&req.FirstName, &req.LastName, &req.Phone, &req.Email)
+	fields := validation.Struct(req)
+
+	if req.ID != nil && *req.ID != user.ID {
+		fields = append(fields, readOnlyFieldError("id"))
+	}
+	if req.MemberID != nil && *req.MemberID != user.MemberID {
+		fields = append(fields, readOnlyFieldError("member_id"))
+	}
+	if req.MembershipLevel != nil && *req.MembershipLevel != user.MembershipLevel {
+		fields = append(fields, readOnlyFieldError("membership_level"))
+	}
+	if req.Role != nil && *req.Role != string(user.Role) {
+		fields = append(fields, readOnlyFieldError("role"))
+	}
+	if req.Points != nil && *req.Points != user.Points {
+		fields = append(fields, readOnlyFieldError("points"))
+	}
+	if req.RegisteredAt != nil && !req.RegisteredAt.Equal(user.RegisteredAt) {
+		fields = append(fields, readOnlyFieldError("registered_at"))
+	}
+	if len(fields) > 0 {
+		return nil, apperror.Validation("Invalid user data", fields...)
+	}
+
+	user.FirstName = req.FirstName
+	user.LastName = req.LastName
+	user.Phone, _ = validation.NormalizePhone(req.Phone) // validated above
+	user.Email = req.Email
+
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEmailTaken):
+			return nil, ErrEmailAlreadyRegistered
+		case errors.Is(err, repository.ErrPhoneTaken):
+			return nil, ErrPhoneAlreadyRegistered
+		case errors.Is(err, apperror.ErrNotFound):
+			return nil, ErrUserNotFound
+		}
+		return nil, internalError(ctx, "Failed to update user", err)
+	}
+
+	return &RegisterResponse{
+		Success: true,
+		Message: "User updated successfully",
+		User:    user,
+	}, nil
+}
+
+// internalError wraps an unexpected failure. Failures while ctx is
+// cancelled or past its deadline are expected; they are returned as
+// apperror.Unavailable so clients learn the request may be retried.
+func internalError(ctx context.Context, message string, err error) error {
+	if ctx.Err() != nil {
+		return apperror.Unavailable("Request was cancelled or timed out", err)
+	}
+	return apperror.Internal(message, err)
+}
+
+// readOnlyFieldError reports an attempt to change a read-only field
+func readOnlyFieldError(field string) apperror.FieldError {
+	return apperror.FieldError{Field: field, Message: field + " cannot be changed"}
+}
+
+// normalizeRegisterRequest validates the registration request, reporting
+// every invalid field at once, and returns it with surrounding whitespace
+// trimmed and the phone number in E.164
+func normalizeRegisterRequest(req RegisterRequest) (RegisterRequest, error) {
+	trimSpace(&req.FirstName, &req.LastName, &req.Phone, &req.Email)
+	if fields := validation.Struct(req); len(fields) > 0 {
+		return req, apperror.Validation("Invalid registration data", fields...)
+	}
+
+	req.Phone, _ = validation.NormalizePhone(req.Phone) // validated above
+	return req, nil
+}
+
+// trimSpace trims surrounding whitespace from each string in place
+func trimSpace(values ...*string) {
+	for _, v := range values {
+		*v = strings.TrimSpace(*v)
+	}
+}
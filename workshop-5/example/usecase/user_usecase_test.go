@@ -0,0 +1,440 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mike/apperror"
+	"example.com/mike/entity"
+	"example.com/mike/repository"
+	"example.com/mike/usecase"
+)
+
+func newRegisterRequest(n int) usecase.RegisterRequest {
+	return usecase.RegisterRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Phone:     fmt.Sprintf("+6681%07d", n),
+		Email:     fmt.Sprintf("user%d@example.com", n),
+	}
+}
+
+func TestRegisterConcurrentSameEmail(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+
+	const attempts = 20
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := newRegisterRequest(i + 1)
+			req.Email = "same@example.com"
+			_, err := uc.Register(context.Background(), req)
+			results <- err
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+		} else if !errors.Is(err, usecase.ErrEmailAlreadyRegistered) {
+			t.Errorf("Register: expected nil or %v, got %v", usecase.ErrEmailAlreadyRegistered, err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one successful registration, got %d", succeeded)
+	}
+}
+
+func TestRegisterConcurrentUniqueMemberIDs(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+
+	const attempts = 50
+	var wg sync.WaitGroup
+	memberIDs := make(chan string, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := uc.Register(context.Background(), newRegisterRequest(i+1))
+			if err != nil {
+				t.Errorf("Register: unexpected error: %v", err)
+				return
+			}
+			memberIDs <- resp.User.MemberID
+		}(i)
+	}
+	wg.Wait()
+	close(memberIDs)
+
+	seen := make(map[string]bool)
+	for memberID := range memberIDs {
+		if seen[memberID] {
+			t.Fatalf("member ID %s assigned twice", memberID)
+		}
+		seen[memberID] = true
+	}
+}
+
+func TestRegisterDoesNotReuseMemberIDAfterDelete(t *testing.T) {
+	repo := repository.NewMemoryUserRepository()
+	uc := usecase.NewUserUsecase(repo, repository.NewMemoryLedgerRepository())
+
+	first, err := uc.Register(context.Background(), newRegisterRequest(1))
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	second, err := uc.Register(context.Background(), newRegisterRequest(2))
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	if err := repo.Delete(context.Background(), first.User.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	third, err := uc.Register(context.Background(), newRegisterRequest(3))
+	if err != nil {
+		t.Fatalf("Register after delete: %v", err)
+	}
+	if third.User.MemberID == second.User.MemberID {
+		t.Fatalf("member ID %s collides with an existing user", third.User.MemberID)
+	}
+}
+
+func TestRegisterRejectsDuplicatePhone(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+
+	if _, err := uc.Register(context.Background(), newRegisterRequest(1)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	req := newRegisterRequest(2)
+	req.Phone = newRegisterRequest(1).Phone
+	if _, err := uc.Register(context.Background(), req); !errors.Is(err, usecase.ErrPhoneAlreadyRegistered) {
+		t.Fatalf("Register with taken phone: expected %v, got %v", usecase.ErrPhoneAlreadyRegistered, err)
+	}
+}
+
+func TestRegisterReportsAllMissingFields(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+
+	_, err := uc.Register(context.Background(), usecase.RegisterRequest{FirstName: "John"})
+	if !errors.Is(err, apperror.ErrValidation) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected *apperror.Error, got %T", err)
+	}
+	got := map[string]bool{}
+	for _, f := range appErr.Fields {
+		got[f.Field] = true
+	}
+	for _, field := range []string{"last_name", "phone", "email"} {
+		if !got[field] {
+			t.Errorf("missing field error for %q in %+v", field, appErr.Fields)
+		}
+	}
+	if got["first_name"] {
+		t.Errorf("unexpected field error for first_name")
+	}
+}
+
+func TestGetUserNotFound(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+
+	_, err := uc.GetUser(context.Background(), "does-not-exist")
+	if !errors.Is(err, usecase.ErrUserNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrUserNotFound, err)
+	}
+	if !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("expected a not-found error, got %v", err)
+	}
+}
+
+func registerUser(t *testing.T, uc usecase.UserUsecase, n int) *usecase.RegisterResponse {
+	t.Helper()
+	resp, err := uc.Register(context.Background(), newRegisterRequest(n))
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return resp
+}
+
+// fieldErrors returns the fields named in a validation error
+func fieldErrors(t *testing.T, err error) map[string]bool {
+	t.Helper()
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Kind != apperror.KindValidation {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	fields := map[string]bool{}
+	for _, f := range appErr.Fields {
+		fields[f.Field] = true
+	}
+	return fields
+}
+
+func TestUpdateUser(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	user := registerUser(t, uc, 1).User
+
+	resp, err := uc.UpdateUser(context.Background(), user.ID, usecase.UpdateUserRequest{
+		FirstName: "Jane",
+		LastName:  "Roe",
+		Phone:     "+66899999999",
+		Email:     "jane@example.com",
+		MemberID:  &user.MemberID,
+	})
+	if err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if resp.User.FirstName != "Jane" || resp.User.Email != "jane@example.com" {
+		t.Fatalf("fields not updated: %+v", resp.User)
+	}
+	if resp.User.MemberID != user.MemberID || !resp.User.RegisteredAt.Equal(user.RegisteredAt) {
+		t.Fatalf("read-only fields changed: %+v", resp.User)
+	}
+}
+
+func TestUpdateUserRejectsReadOnlyChanges(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	user := registerUser(t, uc, 1).User
+
+	memberID := "LBK999999"
+	registeredAt := user.RegisteredAt.Add(-time.Hour)
+	_, err := uc.UpdateUser(context.Background(), user.ID, usecase.UpdateUserRequest{
+		FirstName:    "Jane",
+		LastName:     "Roe",
+		Phone:        user.Phone,
+		Email:        user.Email,
+		MemberID:     &memberID,
+		RegisteredAt: &registeredAt,
+	})
+	fields := fieldErrors(t, err)
+	if !fields["member_id"] || !fields["registered_at"] {
+		t.Fatalf("expected member_id and registered_at errors, got %v", fields)
+	}
+}
+
+func TestUpdateUserUniqueness(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	first := registerUser(t, uc, 1).User
+	second := registerUser(t, uc, 2).User
+
+	_, err := uc.UpdateUser(context.Background(), second.ID, usecase.UpdateUserRequest{
+		FirstName: second.FirstName,
+		LastName:  second.LastName,
+		Phone:     second.Phone,
+		Email:     first.Email,
+	})
+	if !errors.Is(err, usecase.ErrEmailAlreadyRegistered) {
+		t.Fatalf("expected %v, got %v", usecase.ErrEmailAlreadyRegistered, err)
+	}
+}
+
+func TestUpdateUserNotFound(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+
+	_, err := uc.UpdateUser(context.Background(), "does-not-exist", usecase.UpdateUserRequest{
+		FirstName: "Jane", LastName: "Roe", Phone: "+66899999999", Email: "jane@example.com",
+	})
+	if !errors.Is(err, usecase.ErrUserNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrUserNotFound, err)
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	user := registerUser(t, uc, 1).User
+
+	resp, err := uc.PatchUser(context.Background(), user.ID, []byte(`{"phone": "+66898765432"}`))
+	if err != nil {
+		t.Fatalf("PatchUser: %v", err)
+	}
+	if resp.User.Phone != "+66898765432" {
+		t.Fatalf("phone not patched: %+v", resp.User)
+	}
+	if resp.User.Email != user.Email || resp.User.FirstName != user.FirstName {
+		t.Fatalf("untouched fields changed: %+v", resp.User)
+	}
+}
+
+func TestPatchUserRejections(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		field string
+	}{
+		{"change member_id", `{"member_id": "LBK999999"}`, "member_id"},
+		{"remove registered_at", `{"registered_at": null}`, "registered_at"},
+		{"remove required field", `{"email": null}`, "email"},
+		{"unknown field", `{"nickname": "JD"}`, "nickname"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+			user := registerUser(t, uc, 1).User
+
+			_, err := uc.PatchUser(context.Background(), user.ID, []byte(tt.patch))
+			if fields := fieldErrors(t, err); !fields[tt.field] {
+				t.Fatalf("expected a %s field error, got %v", tt.field, fields)
+			}
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	user := registerUser(t, uc, 1).User
+
+	if err := uc.DeleteUser(context.Background(), user.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := uc.GetUser(context.Background(), user.ID); !errors.Is(err, usecase.ErrUserNotFound) {
+		t.Fatalf("GetUser after delete: expected %v, got %v", usecase.ErrUserNotFound, err)
+	}
+	if err := uc.DeleteUser(context.Background(), user.ID); !errors.Is(err, usecase.ErrUserNotFound) {
+		t.Fatalf("second DeleteUser: expected %v, got %v", usecase.ErrUserNotFound, err)
+	}
+}
+
+func TestRegisterNormalizesInput(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+
+	resp, err := uc.Register(context.Background(), usecase.RegisterRequest{
+		FirstName: "  สมชาย ",
+		LastName:  "ใจดี",
+		Phone:     "081-234-5678",
+		Email:     " somchai@example.com ",
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if resp.User.Phone != "+66812345678" {
+		t.Errorf("phone not normalized to E.164: %q", resp.User.Phone)
+	}
+	if resp.User.FirstName != "สมชาย" || resp.User.Email != "somchai@example.com" {
+		t.Errorf("whitespace not trimmed: %+v", resp.User)
+	}
+
+	// The same number in another format is still a duplicate
+	_, err = uc.Register(context.Background(), usecase.RegisterRequest{
+		FirstName: "Somchai", LastName: "Jaidee", Phone: "+66 81 234 5678", Email: "other@example.com",
+	})
+	if !errors.Is(err, usecase.ErrPhoneAlreadyRegistered) {
+		t.Fatalf("expected %v, got %v", usecase.ErrPhoneAlreadyRegistered, err)
+	}
+}
+
+func TestRegisterReportsAllInvalidFields(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+
+	_, err := uc.Register(context.Background(), usecase.RegisterRequest{
+		FirstName: "J0hn",
+		LastName:  "Doe!",
+		Phone:     "12345",
+		Email:     "john@",
+	})
+	fields := fieldErrors(t, err)
+	for _, field := range []string{"first_name", "last_name", "phone", "email"} {
+		if !fields[field] {
+			t.Errorf("missing field error for %q in %v", field, fields)
+		}
+	}
+}
+
+func TestUpdateUserNormalizesPhone(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	user := registerUser(t, uc, 1).User
+
+	resp, err := uc.PatchUser(context.Background(), user.ID, []byte(`{"phone": "089-876-5432"}`))
+	if err != nil {
+		t.Fatalf("PatchUser: %v", err)
+	}
+	if resp.User.Phone != "+66898765432" {
+		t.Fatalf("phone not normalized to E.164: %q", resp.User.Phone)
+	}
+
+	_, err = uc.PatchUser(context.Background(), user.ID, []byte(`{"email": "not-an-email"}`))
+	if fields := fieldErrors(t, err); !fields["email"] {
+		t.Fatalf("expected an email field error, got %v", fields)
+	}
+}
+
+func TestSetRole(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	user := registerUser(t, uc, 1).User
+	if user.Role != entity.RoleMember {
+		t.Fatalf("new users are members, got %q", user.Role)
+	}
+
+	resp, err := uc.SetRole(context.Background(), user.ID, usecase.SetRoleRequest{Role: " staff "})
+	if err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	if resp.User.Role != entity.RoleStaff {
+		t.Fatalf("Role = %q, want staff", resp.User.Role)
+	}
+
+	_, err = uc.SetRole(context.Background(), user.ID, usecase.SetRoleRequest{Role: "owner"})
+	if fields := fieldErrors(t, err); !fields["role"] {
+		t.Fatalf("expected a role error, got %v", fields)
+	}
+	if _, err := uc.SetRole(context.Background(), "missing", usecase.SetRoleRequest{Role: "admin"}); !errors.Is(err, usecase.ErrUserNotFound) {
+		t.Fatalf("expected %v, got %v", usecase.ErrUserNotFound, err)
+	}
+
+	// The role is read-only through updates
+	role := "admin"
+	_, err = uc.UpdateUser(context.Background(), user.ID, usecase.UpdateUserRequest{
+		FirstName: user.FirstName, LastName: user.LastName, Phone: user.Phone, Email: user.Email, Role: &role,
+	})
+	if fields := fieldErrors(t, err); !fields["role"] {
+		t.Fatalf("expected a role error, got %v", fields)
+	}
+}
+
+func TestCancelledRequestsAreUnavailable(t *testing.T) {
+	uc := usecase.NewUserUsecase(repository.NewMemoryUserRepository(), repository.NewMemoryLedgerRepository())
+	user := registerUser(t, uc, 1).User
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := []struct {
+		name string
+		err  error
+	}{
+		{"Register", errOf(uc.Register(ctx, newRegisterRequest(2)))},
+		{"GetUser", errOf(uc.GetUser(ctx, user.ID))},
+		{"ListUsers", errOf(uc.ListUsers(ctx, usecase.ListUsersRequest{}))},
+		{"UpdateUser", errOf(uc.UpdateUser(ctx, user.ID, usecase.UpdateUserRequest{FirstName: "Jane", LastName: "Doe", Phone: user.Phone, Email: user.Email}))},
+		{"PatchUser", errOf(uc.PatchUser(ctx, user.ID, []byte(`{"first_name":"Jane"}`)))},
+		{"DeleteUser", uc.DeleteUser(ctx, user.ID)},
+		{"SetRole", errOf(uc.SetRole(ctx, user.ID, usecase.SetRoleRequest{Role: "staff"}))},
+	}
+	for _, call := range calls {
+		if !errors.Is(call.err, apperror.ErrUnavailable) || !errors.Is(call.err, context.Canceled) {
+			t.Errorf("%s: expected an unavailable error caused by %v, got %v", call.name, context.Canceled, call.err)
+		}
+	}
+
+	// Nothing changed
+	got, err := uc.GetUser(context.Background(), user.ID)
+	if err != nil || got.User.FirstName != user.FirstName || got.User.Role != user.Role {
+		t.Fatalf("GetUser = %+v, %v", got, err)
+	}
+}
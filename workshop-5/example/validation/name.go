@@ -0,0 +1,54 @@
+package validation
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the maximum length of a first or last name in characters
+const MaxNameLength = 50
+
+// ValidName reports whether name is a plausible personal name: Thai or
+// Latin letters (including Thai vowel and tone marks) separated by single
+// spaces, hyphens, apostrophes or periods
+func ValidName(name string) bool {
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return false
+	}
+
+	previousLetter := false
+	for i, r := range name {
+		switch {
+		case isNameLetter(r):
+			previousLetter = true
+		case isNameMark(r):
+			// Combining marks must follow a letter, e.g. the tone mark in "น้ำ"
+			if i == 0 || !previousLetter {
+				return false
+			}
+		case r == ' ' || r == '-' || r == '\'' || r == '.':
+			// Separators may not lead or repeat
+			if !previousLetter {
+				return false
+			}
+			previousLetter = false
+		default:
+			return false
+		}
+	}
+
+	last, _ := utf8.DecodeLastRuneInString(name)
+	return last != ' ' && last != '-' && last != '\''
+}
+
+// isNameLetter reports whether r is a Thai or Latin letter. Thai vowels
+// written as spacing characters (e.g. า, เ) are letters too.
+func isNameLetter(r rune) bool {
+	return unicode.IsLetter(r) && (unicode.Is(unicode.Latin, r) || unicode.Is(unicode.Thai, r))
+}
+
+// isNameMark reports whether r is a combining mark used in names, such as
+// Thai vowel signs and tone marks or Latin diacritics in decomposed form
+func isNameMark(r rune) bool {
+	return unicode.Is(unicode.Mn, r) && (unicode.Is(unicode.Thai, r) || unicode.In(r, unicode.Inherited))
+}
@@ -0,0 +1,101 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+)
+
+// ThaiCountryCode is the calling code prepended to Thai national numbers
+const ThaiCountryCode = "66"
+
+// ErrInvalidPhone is returned for numbers that cannot be normalized to E.164
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// NormalizePhone converts a phone number to E.164, e.g. +66812345678.
+//
+// It accepts international numbers starting with + or 00, and Thai national
+// numbers with the trunk prefix 0 such as 081-234-5678 or 02 123 4567.
+// Spaces, dashes, dots and parentheses are ignored.
+func NormalizePhone(raw string) (string, error) {
+	digits, international, ok := phoneDigits(raw)
+	if !ok {
+		return "", ErrInvalidPhone
+	}
+
+	if !international {
+		// Thai national format: trunk prefix 0 followed by the subscriber number
+		national, found := strings.CutPrefix(digits, "0")
+		if !found {
+			return "", ErrInvalidPhone
+		}
+		digits = ThaiCountryCode + national
+	}
+
+	// E.164 allows at most 15 digits and country codes never start with 0
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	if national, thai := strings.CutPrefix(digits, ThaiCountryCode); thai && !validThaiNumber(national) {
+		return "", ErrInvalidPhone
+	}
+	return "+" + digits, nil
+}
+
+// NormalizePhonePrefix rewrites the start of a phone number the way
+// NormalizePhone would, so "081-2" matches numbers stored as +66812...
+// Prefixes that cannot be normalized are returned unchanged.
+func NormalizePhonePrefix(prefix string) string {
+	digits, international, ok := phoneDigits(prefix)
+	if !ok || digits == "" {
+		return prefix
+	}
+	if international {
+		return "+" + digits
+	}
+	if national, found := strings.CutPrefix(digits, "0"); found {
+		return "+" + ThaiCountryCode + national
+	}
+	return prefix
+}
+
+// phoneDigits strips formatting from raw and reports whether the number
+// carries an international prefix (+ or 00)
+func phoneDigits(raw string) (digits string, international, ok bool) {
+	raw = strings.TrimSpace(raw)
+	if rest, found := strings.CutPrefix(raw, "+"); found {
+		raw, international = rest, true
+	}
+
+	var b strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", false, false
+		}
+	}
+
+	digits = b.String()
+	if rest, found := strings.CutPrefix(digits, "00"); found && !international {
+		digits, international = rest, true
+	}
+	return digits, international, true
+}
+
+// validThaiNumber checks a Thai number without its country code: mobile
+// numbers are 9 digits starting with 6, 8 or 9, fixed lines 8 digits
+// starting with 2 to 7
+func validThaiNumber(national string) bool {
+	if national == "" {
+		return false
+	}
+	switch national[0] {
+	case '6', '8', '9':
+		return len(national) == 9
+	case '2', '3', '4', '5', '7':
+		return len(national) == 8
+	}
+	return false
+}
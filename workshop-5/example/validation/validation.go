@@ -0,0 +1,105 @@
+// Package validation checks request structs against their validate struct
+// tags and provides the phone and name rules those tags refer to.
+//
+// Besides the standard go-playground/validator tags, requests may use:
+//
+//	phone        the value can be normalized to E.164 by NormalizePhone
+//	person_name  the value passes ValidName
+//
+// Field errors are reported under their JSON names so clients can map them
+// back to the request body.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"example.com/mike/apperror"
+	"github.com/go-playground/validator/v10"
+)
+
+// validate is safe for concurrent use and caches struct metadata, so a
+// single instance is shared
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	// The tags only apply to strings, so registration cannot fail
+	must(v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		_, err := NormalizePhone(fl.Field().String())
+		return err == nil
+	}))
+	must(v.RegisterValidation("person_name", func(fl validator.FieldLevel) bool {
+		return ValidName(fl.Field().String())
+	}))
+	return v
+}
+
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Struct validates s against its validate tags and returns one error per
+// invalid field, in field order. It returns nil when s is valid.
+func Struct(s any) []apperror.FieldError {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		// Only reachable when s is not a struct, which is a programming error
+		panic(fmt.Sprintf("validation: %v", err))
+	}
+
+	fields := make([]apperror.FieldError, len(invalid))
+	for i, fe := range invalid {
+		fields[i] = apperror.FieldError{Field: fe.Field(), Message: message(fe)}
+	}
+	return fields
+}
+
+// message renders a readable message for a failed tag
+func message(fe validator.FieldError) string {
+	label := strings.ReplaceAll(fe.Field(), "_", " ")
+	switch fe.Tag() {
+	case "required":
+		return label + " is required"
+	case "email":
+		return label + " must be a valid email address"
+	case "phone":
+		return label + " must be a valid phone number, e.g. +66812345678 or 081-234-5678"
+	case "person_name":
+		return label + " may only contain Thai or Latin letters, spaces, hyphens, apostrophes and periods"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", label, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("%s must be at most %s characters", label, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", label, fe.Param())
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("%s must be at least %s characters", label, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", label, fe.Param())
+	}
+	return fmt.Sprintf("%s is invalid (%s)", label, fe.Tag())
+}
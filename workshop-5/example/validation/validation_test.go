@@ -0,0 +1,134 @@
+package validation_test
+
+import (
+	"testing"
+
+	"example.com/mike/validation"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"+66812345678", "+66812345678"},
+		{"081-234-5678", "+66812345678"},
+		{"081 234 5678", "+66812345678"},
+		{"(081) 234.5678", "+66812345678"},
+		{" 0812345678 ", "+66812345678"},
+		{"+66 81 234 5678", "+66812345678"},
+		{"0066812345678", "+66812345678"},
+		{"02-123-4567", "+6621234567"},
+		{"+1 415 555 2671", "+14155552671"},
+		{"+44 20 7946 0958", "+442079460958"},
+	}
+	for _, tt := range tests {
+		got, err := validation.NormalizePhone(tt.raw)
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizePhone(%q) = %q, %v; want %q", tt.raw, got, err, tt.want)
+		}
+	}
+
+	for _, raw := range []string{
+		"",
+		"812345678",     // no trunk prefix or country code
+		"081-234-567",   // mobile number too short
+		"081-234-56789", // mobile number too long
+		"012345678",     // no such Thai area code
+		"+66 081 234 5678",
+		"+0812345678",
+		"+1234",
+		"+1234567890123456",
+		"081-234-5678 ext 1",
+		"๐๘๑๒๓๔๕๖๗๘",
+	} {
+		if got, err := validation.NormalizePhone(raw); err == nil {
+			t.Errorf("NormalizePhone(%q) = %q, want an error", raw, got)
+		}
+	}
+}
+
+func TestNormalizePhonePrefix(t *testing.T) {
+	tests := map[string]string{
+		"081":   "+6681",
+		"081-2": "+66812",
+		"+66 8": "+668",
+		"0066":  "+66",
+		"812":   "812",
+		"abc":   "abc",
+		"":      "",
+	}
+	for prefix, want := range tests {
+		if got := validation.NormalizePhonePrefix(prefix); got != want {
+			t.Errorf("NormalizePhonePrefix(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for _, name := range []string{
+		"John",
+		"Mary-Jane",
+		"O'Brien",
+		"Jr.",
+		"Van der Berg",
+		"José",
+		"สมชาย",
+		"น้ำฝน",
+		"ศรีสุข",
+	} {
+		if !validation.ValidName(name) {
+			t.Errorf("ValidName(%q) = false, want true", name)
+		}
+	}
+
+	for _, name := range []string{
+		"",
+		" John",
+		"John ",
+		"John  Doe",
+		"John--Doe",
+		"-John",
+		"John3",
+		"John!",
+		"สมชาย๑",
+		"้สมชาย", // tone mark without a base letter
+		"Иван",   // Cyrillic
+		"太郎",
+		"John😀",
+		"Abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyz",
+	} {
+		if validation.ValidName(name) {
+			t.Errorf("ValidName(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestStructReportsEveryFieldByJSONName(t *testing.T) {
+	type request struct {
+		FirstName string `json:"first_name" validate:"required,max=50,person_name"`
+		LastName  string `json:"last_name" validate:"required,max=50,person_name"`
+		Phone     string `json:"phone" validate:"required,phone"`
+		Email     string `json:"email" validate:"required,email"`
+	}
+
+	if fields := validation.Struct(request{
+		FirstName: "สมชาย", LastName: "Doe", Phone: "081-234-5678", Email: "somchai@example.com",
+	}); fields != nil {
+		t.Fatalf("valid request: unexpected errors %v", fields)
+	}
+
+	fields := validation.Struct(request{FirstName: "John3", Phone: "12345", Email: "not-an-email"})
+	want := []string{"first_name", "last_name", "phone", "email"}
+	if len(fields) != len(want) {
+		t.Fatalf("expected %d field errors, got %v", len(want), fields)
+	}
+	for i, field := range want {
+		if fields[i].Field != field || fields[i].Message == "" {
+			t.Errorf("field error %d = %+v, want one for %s", i, fields[i], field)
+		}
+	}
+	if fields[1].Message != "last name is required" {
+		t.Errorf("unexpected message %q", fields[1].Message)
+	}
+}